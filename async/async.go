@@ -0,0 +1,209 @@
+package async
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Defaults for a Runner.
+const (
+	DefaultWorkers   = 1
+	DefaultQueueSize = 100
+)
+
+var (
+	// ErrQueueFull is returned by the submit endpoint when the queue of
+	// pending jobs is full.
+	ErrQueueFull = errors.New("job queue full")
+
+	// ErrClosed is returned by the submit endpoint after the Runner is closed.
+	ErrClosed = errors.New("runner closed")
+)
+
+// Status is the state of a job.
+type Status string
+
+// Statuses of a job, in the order they occur.
+const (
+	Pending   Status = "pending"
+	Running   Status = "running"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+// Finished returns true if the job has succeeded or failed.
+func (s Status) Finished() bool {
+	return s == Succeeded || s == Failed
+}
+
+// Ticket identifies a submitted job. It's the response of the submit
+// endpoint, and the request of the status endpoint.
+type Ticket struct {
+	ID string `json:"id"`
+}
+
+// Job is the state of a submitted job, as recorded in the Store. Errors are
+// recorded by their message, so that stores may serialize jobs.
+type Job struct {
+	ID       string      `json:"id"`
+	Status   Status      `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Runner invokes an endpoint asynchronously, for requests submitted through
+// its submit endpoint.
+type Runner struct {
+	next      endpoint.Endpoint
+	store     Store
+	workers   int
+	queueSize int
+	logger    log.Logger
+
+	mtx    sync.Mutex
+	queue  chan job
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type job struct {
+	id      string
+	request interface{}
+}
+
+// Option sets an optional parameter for a Runner.
+type Option func(*Runner)
+
+// Workers sets the number of jobs run concurrently. By default,
+// DefaultWorkers is used.
+func Workers(n int) Option {
+	return func(r *Runner) { r.workers = n }
+}
+
+// QueueSize sets the number of pending jobs which may wait for a worker.
+// Beyond that, submissions fail with ErrQueueFull. By default,
+// DefaultQueueSize is used.
+func QueueSize(n int) Option {
+	return func(r *Runner) { r.queueSize = n }
+}
+
+// Logger sets the logger for errors from the store. By default, they're
+// discarded.
+func Logger(logger log.Logger) Option {
+	return func(r *Runner) { r.logger = logger }
+}
+
+// NewRunner returns a Runner for the endpoint, recording jobs in the store,
+// and starts its workers. Callers should Close the Runner when it's no longer
+// needed.
+func NewRunner(next endpoint.Endpoint, store Store, options ...Option) *Runner {
+	r := &Runner{
+		next:      next,
+		store:     store,
+		workers:   DefaultWorkers,
+		queueSize: DefaultQueueSize,
+		logger:    log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if r.workers < 1 {
+		r.workers = 1
+	}
+	if r.queueSize < 1 {
+		r.queueSize = 1
+	}
+	r.queue = make(chan job, r.queueSize)
+	r.wg.Add(r.workers)
+	for i := 0; i < r.workers; i++ {
+		go r.work()
+	}
+	return r
+}
+
+// SubmitEndpoint returns an endpoint which queues its request as a job, and
+// returns its Ticket without waiting for it to run. Jobs run with a
+// background context, since they outlive the submission.
+func (r *Runner) SubmitEndpoint() endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		if r.closed {
+			return nil, ErrClosed
+		}
+		if len(r.queue) >= r.queueSize {
+			return nil, ErrQueueFull
+		}
+		if err := r.store.Set(Job{ID: id, Status: Pending}); err != nil {
+			return nil, err
+		}
+		r.queue <- job{id: id, request: request} // can't block, as submissions are serialized
+		return Ticket{ID: id}, nil
+	}
+}
+
+// StatusEndpoint returns an endpoint which takes a Ticket, or a pointer to
+// one, and returns the Job it identifies, from the store.
+func (r *Runner) StatusEndpoint() endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		var id string
+		switch t := request.(type) {
+		case Ticket:
+			id = t.ID
+		case *Ticket:
+			id = t.ID
+		default:
+			return nil, ErrNotFound
+		}
+		return r.store.Get(id)
+	}
+}
+
+// Close stops accepting submissions, and waits for the workers to finish the
+// jobs already queued.
+func (r *Runner) Close() {
+	r.mtx.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mtx.Unlock()
+	r.wg.Wait()
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for j := range r.queue {
+		r.set(Job{ID: j.id, Status: Running})
+		response, err := r.next(context.Background(), j.request)
+		if err != nil {
+			r.set(Job{ID: j.id, Status: Failed, Error: err.Error()})
+			continue
+		}
+		r.set(Job{ID: j.id, Status: Succeeded, Response: response})
+	}
+}
+
+func (r *Runner) set(j Job) {
+	if err := r.store.Set(j); err != nil {
+		r.logger.Log("during", "Set", "job", j.ID, "err", err)
+	}
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,86 @@
+package async_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/async"
+)
+
+func TestRunner(t *testing.T) {
+	release := make(chan struct{})
+	r := async.NewRunner(func(_ context.Context, request interface{}) (interface{}, error) {
+		<-release
+		if request == "fail" {
+			return nil, errors.New("dang")
+		}
+		return request.(string) + "!", nil
+	}, async.NewMemoryStore(time.Minute))
+
+	submit, status := r.SubmitEndpoint(), r.StatusEndpoint()
+	ok, err := submit(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail, err := submit(context.Background(), "fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := status(context.Background(), ok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := job.(async.Job).Status; s != async.Pending && s != async.Running {
+		t.Errorf("want pending or running, have %s", s)
+	}
+
+	close(release)
+	r.Close()
+
+	job, _ = status(context.Background(), ok)
+	if want, have := (async.Job{ID: ok.(async.Ticket).ID, Status: async.Succeeded, Response: "hello!"}), job; want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	job, _ = status(context.Background(), fail)
+	if want, have := (async.Job{ID: fail.(async.Ticket).ID, Status: async.Failed, Error: "dang"}), job; want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+
+	if _, err := status(context.Background(), async.Ticket{ID: "nope"}); err != async.ErrNotFound {
+		t.Errorf("want %v, have %v", async.ErrNotFound, err)
+	}
+	if _, err := submit(context.Background(), "late"); err != async.ErrClosed {
+		t.Errorf("want %v, have %v", async.ErrClosed, err)
+	}
+}
+
+func TestRunnerQueueFull(t *testing.T) {
+	var (
+		started = make(chan struct{}, 3)
+		release = make(chan struct{})
+	)
+	r := async.NewRunner(func(context.Context, interface{}) (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}, async.NewMemoryStore(time.Minute), async.Workers(1), async.QueueSize(2))
+	defer r.Close()
+	defer close(release)
+
+	submit := r.SubmitEndpoint()
+	if _, err := submit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	<-started // the worker is busy
+	for i := 0; i < 2; i++ {
+		if _, err := submit(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := submit(context.Background(), nil); err != async.ErrQueueFull {
+		t.Errorf("want %v, have %v", async.ErrQueueFull, err)
+	}
+}
@@ -0,0 +1,12 @@
+// Package async turns a synchronous endpoint into an asynchronous job queue.
+// Submitting a request returns a Ticket immediately; a bounded pool of
+// workers invokes the endpoint in the background, and records the outcome in
+// a pluggable Store, from which clients fetch it with the ticket.
+//
+//	r := async.NewRunner(reportEndpoint, async.NewMemoryStore(time.Hour), async.Workers(4))
+//	defer r.Close()
+//	submit, status := r.SubmitEndpoint(), r.StatusEndpoint()
+//
+// The submit and status endpoints may be served by any transport, like other
+// endpoints.
+package async
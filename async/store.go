@@ -0,0 +1,73 @@
+package async
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by stores, and the status endpoint, for unknown
+// tickets.
+var ErrNotFound = errors.New("job not found")
+
+// Store records the state of jobs. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Set records the job, replacing any previous state with the same ID.
+	Set(job Job) error
+
+	// Get returns the job with the ID, or ErrNotFound.
+	Get(id string) (Job, error)
+}
+
+// MemoryStore is an in-memory Store. Finished jobs are forgotten once they've
+// been unchanged for its retention period.
+type MemoryStore struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mtx     sync.Mutex
+	jobs    map[string]Job
+	expires map[string]time.Time
+}
+
+// NewMemoryStore returns a MemoryStore which retains finished jobs for the
+// given duration.
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention: retention,
+		now:       time.Now,
+		jobs:      map[string]Job{},
+		expires:   map[string]time.Time{},
+	}
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(job Job) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := s.now()
+	for id, t := range s.expires {
+		if !now.Before(t) {
+			delete(s.jobs, id)
+			delete(s.expires, id)
+		}
+	}
+	s.jobs[job.ID] = job
+	delete(s.expires, job.ID)
+	if job.Status.Finished() {
+		s.expires[job.ID] = now.Add(s.retention)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(id string) (Job, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	job, ok := s.jobs[id]
+	if t, expiring := s.expires[id]; !ok || (expiring && !s.now().Before(t)) {
+		return Job{}, ErrNotFound
+	}
+	return job, nil
+}
@@ -0,0 +1,27 @@
+package async
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRetention(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore(time.Minute)
+	s.now = func() time.Time { return now }
+
+	s.Set(Job{ID: "a", Status: Running})
+	s.Set(Job{ID: "b", Status: Succeeded})
+	now = now.Add(2 * time.Minute)
+
+	if _, err := s.Get("a"); err != nil {
+		t.Errorf("unfinished job: want no error, have %v", err)
+	}
+	if _, err := s.Get("b"); err != ErrNotFound {
+		t.Errorf("finished job: want %v, have %v", ErrNotFound, err)
+	}
+	s.Set(Job{ID: "c", Status: Pending})
+	if want, have := 2, len(s.jobs); want != have {
+		t.Errorf("want %d jobs after purge, have %d", want, have)
+	}
+}
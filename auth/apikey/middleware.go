@@ -0,0 +1,152 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/endpoint"
+)
+
+type contextKey string
+
+const (
+	// KeyContextKey holds the key used to store an API key in the context.
+	KeyContextKey contextKey = "APIKey"
+
+	// PrincipalContextKey holds the key used to store the *Principal of a
+	// valid API key in the context.
+	PrincipalContextKey contextKey = "APIKeyPrincipal"
+)
+
+// Errors of validation. Those of keys are of kind
+// endpoint.KindUnauthenticated, and that of scopes of kind
+// endpoint.KindPermissionDenied, so package transport/http responds with 401
+// Unauthorized and 403 Forbidden, respectively.
+var (
+	// ErrKeyMissing denotes a key was not passed into the validating
+	// middleware's context.
+	ErrKeyMissing = endpoint.Unauthenticated(errors.New("API key is missing"))
+
+	// ErrKeyInvalid denotes a key the Store doesn't know, e.g. as it was
+	// revoked. Stores return it for such keys.
+	ErrKeyInvalid = endpoint.Unauthenticated(errors.New("API key is invalid"))
+
+	// ErrInsufficientScope denotes a valid key without all of the required
+	// scopes.
+	ErrInsufficientScope = endpoint.PermissionDenied(errors.New("API key has insufficient scope"))
+)
+
+// Principal is the client an API key was issued to.
+type Principal struct {
+	ID     string
+	Scopes []string
+}
+
+// HasScope reports whether the principal has the scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Store looks up the principals of API keys, e.g. in a database.
+// Implementations must be safe for concurrent use, and should compare keys
+// in constant time, or look them up by a hash, so that their lookups don't
+// leak them by timing.
+type Store interface {
+	// Lookup returns the principal of the key, or ErrKeyInvalid if there's
+	// none.
+	Lookup(ctx context.Context, key string) (*Principal, error)
+}
+
+// Option sets an optional parameter for the validating middleware.
+type Option func(*validator)
+
+// Cache sets the store in which the principals of valid keys are cached,
+// keyed by a hash of the key, and for how long, so that the Store isn't
+// queried for each request. Revoked keys stay valid until their principals
+// expire. By default, principals aren't cached.
+func Cache(store cache.Store, ttl time.Duration) Option {
+	return func(v *validator) { v.cache, v.ttl = store, ttl }
+}
+
+// RequireScopes sets the scopes which the principals of keys must all have.
+func RequireScopes(scopes ...string) Option {
+	return func(v *validator) { v.scopes = append(v.scopes, scopes...) }
+}
+
+type validator struct {
+	store  Store
+	cache  cache.Store
+	ttl    time.Duration
+	scopes []string
+}
+
+// NewValidator creates a new API key validating middleware, which looks up
+// the key passed into the context via KeyContextKey in the Store. It adds the
+// principal of a valid key to the context via PrincipalContextKey, or returns
+// an error. Particularly useful for servers.
+func NewValidator(store Store, options ...Option) endpoint.Middleware {
+	v := &validator{store: store}
+	for _, option := range options {
+		option(v)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			// key is stored in the context from the transport handlers.
+			key, ok := ctx.Value(KeyContextKey).(string)
+			if !ok || key == "" {
+				return nil, ErrKeyMissing
+			}
+
+			principal, err := v.lookup(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			for _, scope := range v.scopes {
+				if !principal.HasScope(scope) {
+					return nil, ErrInsufficientScope
+				}
+			}
+
+			ctx = context.WithValue(ctx, PrincipalContextKey, principal)
+
+			return next(ctx, request)
+		}
+	}
+}
+
+func (v *validator) lookup(ctx context.Context, key string) (*Principal, error) {
+	if v.cache == nil || v.ttl <= 0 {
+		return v.lookupStore(ctx, key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	if principal, ok := v.cache.Get(hash); ok {
+		return principal.(*Principal), nil
+	}
+	principal, err := v.lookupStore(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	v.cache.Set(hash, principal, v.ttl)
+	return principal, nil
+}
+
+func (v *validator) lookupStore(ctx context.Context, key string) (*Principal, error) {
+	principal, err := v.store.Lookup(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, ErrKeyInvalid
+	}
+	return principal, nil
+}
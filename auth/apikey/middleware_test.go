@@ -0,0 +1,95 @@
+package apikey_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/auth/apikey"
+	"github.com/go-kit/kit/cache"
+)
+
+type countingStore struct {
+	apikey.Store
+	mtx     sync.Mutex
+	lookups int
+}
+
+func (s *countingStore) Lookup(ctx context.Context, key string) (*apikey.Principal, error) {
+	s.mtx.Lock()
+	s.lookups++
+	s.mtx.Unlock()
+	return s.Store.Lookup(ctx, key)
+}
+
+func TestNewValidator(t *testing.T) {
+	store := &countingStore{Store: apikey.StaticKeys(map[string]apikey.Principal{
+		"reader-key": {ID: "reader", Scopes: []string{"read"}},
+		"writer-key": {ID: "writer", Scopes: []string{"read", "write"}},
+	})}
+
+	var principal *apikey.Principal
+	e := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		principal = ctx.Value(apikey.PrincipalContextKey).(*apikey.Principal)
+		return nil, nil
+	}
+	validated := apikey.NewValidator(store,
+		apikey.RequireScopes("write"),
+		apikey.Cache(cache.NewLRU(10), time.Minute),
+	)(e)
+
+	for _, test := range []struct {
+		key string
+		err error
+	}{
+		{"", apikey.ErrKeyMissing},
+		{"unknown-key", apikey.ErrKeyInvalid},
+		{"reader-key", apikey.ErrInsufficientScope},
+		{"writer-key", nil},
+		{"writer-key", nil},
+	} {
+		ctx := context.Background()
+		if test.key != "" {
+			ctx = context.WithValue(ctx, apikey.KeyContextKey, test.key)
+		}
+		if _, err := validated(ctx, struct{}{}); test.err != err {
+			t.Errorf("%q: want %v, have %v", test.key, test.err, err)
+		}
+	}
+	if want, have := "writer", principal.ID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Only valid keys are cached.
+	if want, have := 3, store.lookups; want != have {
+		t.Errorf("want %d lookups, have %d", want, have)
+	}
+}
+
+func TestToHTTPContext(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options []apikey.HTTPOption
+		header  string
+		url     string
+		want    string
+	}{
+		{"default header", nil, apikey.DefaultHeader, "/", "key"},
+		{"custom header", []apikey.HTTPOption{apikey.Header("X-Token")}, "X-Token", "/", "key"},
+		{"no query by default", nil, "", "/?api_key=key", ""},
+		{"query", []apikey.HTTPOption{apikey.Query("api_key")}, "", "/?api_key=key", "key"},
+		{"header before query", []apikey.HTTPOption{apikey.Query("api_key")}, apikey.DefaultHeader, "/?api_key=other", "key"},
+	} {
+		r := httptest.NewRequest("GET", test.url, nil)
+		if test.header != "" {
+			r.Header.Set(test.header, "key")
+		}
+		ctx := apikey.ToHTTPContext(test.options...)(context.Background(), r)
+		have, _ := ctx.Value(apikey.KeyContextKey).(string)
+		if test.want != have {
+			t.Errorf("%s: want %q, have %q", test.name, test.want, have)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// StaticKeys returns a Store of the principals of the keys in the map, e.g.
+// loaded from configuration. Keys are compared in constant time, regardless
+// of which key matches.
+func StaticKeys(keys map[string]Principal) Store {
+	s := make(staticKeys, 0, len(keys))
+	for key, principal := range keys {
+		principal := principal
+		s = append(s, staticKey{hash: sha256.Sum256([]byte(key)), principal: &principal})
+	}
+	return s
+}
+
+type staticKeys []staticKey
+
+type staticKey struct {
+	hash      [sha256.Size]byte
+	principal *Principal
+}
+
+func (s staticKeys) Lookup(_ context.Context, key string) (*Principal, error) {
+	// Hashes are compared, as they have the same length, and all of them
+	// are, so that the time doesn't depend on the position of the match.
+	hash := sha256.Sum256([]byte(key))
+	var principal *Principal
+	for _, k := range s {
+		if subtle.ConstantTimeCompare(hash[:], k.hash[:]) == 1 {
+			principal = k.principal
+		}
+	}
+	if principal == nil {
+		return nil, ErrKeyInvalid
+	}
+	return principal, nil
+}
@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"context"
+	stdhttp "net/http"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/http"
+)
+
+// Default locations of API keys in requests.
+const (
+	DefaultHeader      = "X-API-Key"
+	DefaultMetadataKey = "x-api-key" // capital "Key" is illegal in HTTP/2.
+)
+
+// HTTPOption sets where ToHTTPContext looks for API keys.
+type HTTPOption func(*httpExtractor)
+
+// Header sets the header which holds API keys. By default, it's
+// DefaultHeader. An empty name disables headers.
+func Header(name string) HTTPOption {
+	return func(e *httpExtractor) { e.header = name }
+}
+
+// Query sets the query parameter which holds API keys, if the header
+// doesn't. As URLs are commonly logged, and cached by browsers and proxies,
+// keys in query parameters are easily leaked; prefer headers. By default,
+// query parameters aren't used.
+func Query(param string) HTTPOption {
+	return func(e *httpExtractor) { e.query = param }
+}
+
+type httpExtractor struct {
+	header string
+	query  string
+}
+
+// ToHTTPContext moves the API key from the request's header, or its query
+// parameter, to context. Particularly useful for servers.
+func ToHTTPContext(options ...HTTPOption) http.RequestFunc {
+	e := &httpExtractor{header: DefaultHeader}
+	for _, option := range options {
+		option(e)
+	}
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		var key string
+		if e.header != "" {
+			key = r.Header.Get(e.header)
+		}
+		if key == "" && e.query != "" {
+			key = r.URL.Query().Get(e.query)
+		}
+		if key == "" {
+			return ctx
+		}
+		return context.WithValue(ctx, KeyContextKey, key)
+	}
+}
+
+// FromHTTPContext moves the API key from context to the request's
+// DefaultHeader. Particularly useful for clients.
+func FromHTTPContext() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if key, ok := ctx.Value(KeyContextKey).(string); ok {
+			r.Header.Set(DefaultHeader, key)
+		}
+		return ctx
+	}
+}
+
+// ToGRPCContext moves the API key from the DefaultMetadataKey of grpc
+// metadata to context. Particularly useful for servers.
+func ToGRPCContext() grpc.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		values, ok := md[DefaultMetadataKey]
+		if !ok || len(values) == 0 || values[0] == "" {
+			return ctx
+		}
+		return context.WithValue(ctx, KeyContextKey, values[0])
+	}
+}
+
+// FromGRPCContext moves the API key from context to the DefaultMetadataKey
+// of grpc metadata. Particularly useful for clients.
+func FromGRPCContext() grpc.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if key, ok := ctx.Value(KeyContextKey).(string); ok {
+			(*md)[DefaultMetadataKey] = []string{key}
+		}
+		return ctx
+	}
+}
@@ -0,0 +1,183 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ErrUnauthenticated denotes a request without an authenticated Subject in
+// its context, e.g. as it wasn't passed through an authentication middleware.
+// It's of kind endpoint.KindUnauthenticated, so package transport/http
+// responds with 401 Unauthorized.
+var ErrUnauthenticated = endpoint.Unauthenticated(errors.New("request has no authenticated subject"))
+
+// ForbiddenError is the error of a request whose Subject doesn't meet the
+// Requirement of the endpoint. It's of kind endpoint.KindPermissionDenied, so
+// package transport/http responds with 403 Forbidden, and package
+// transport/grpc with code PermissionDenied.
+type ForbiddenError struct {
+	// Subject is the ID of the subject.
+	Subject string
+
+	// Roles are the roles of which the subject has none, if any.
+	Roles []string
+
+	// Scopes are the scopes which the subject is missing, if any.
+	Scopes []string
+}
+
+// Error implements error.
+func (e *ForbiddenError) Error() string {
+	var missing []string
+	if len(e.Roles) > 0 {
+		missing = append(missing, "one of roles "+strings.Join(e.Roles, ", "))
+	}
+	if len(e.Scopes) > 0 {
+		missing = append(missing, "scopes "+strings.Join(e.Scopes, ", "))
+	}
+	if len(missing) == 0 {
+		return "permission denied"
+	}
+	return fmt.Sprintf("permission denied: requires %s", strings.Join(missing, " and "))
+}
+
+// Kind returns endpoint.KindPermissionDenied.
+func (e *ForbiddenError) Kind() endpoint.Kind { return endpoint.KindPermissionDenied }
+
+// StatusCode returns 403 Forbidden.
+func (e *ForbiddenError) StatusCode() int { return e.Kind().StatusCode() }
+
+// Requirement is what an endpoint requires of the subjects of its requests.
+type Requirement struct {
+	// Roles are the roles of which the subject must have at least one. If
+	// empty, no role is required.
+	Roles []string
+
+	// Scopes are the scopes which the subject must all have.
+	Scopes []string
+}
+
+// Policy decides whether subjects meet requirements.
+type Policy interface {
+	// Authorize returns nil if the subject meets the requirement, or else
+	// why it doesn't, typically a *ForbiddenError. Errors whose kind isn't
+	// known are returned as endpoint.KindPermissionDenied.
+	Authorize(ctx context.Context, subject *Subject, requirement Requirement) error
+}
+
+// PolicyFunc is an adapter to allow the use of an ordinary function as a
+// Policy.
+type PolicyFunc func(ctx context.Context, subject *Subject, requirement Requirement) error
+
+// Authorize implements Policy.
+func (f PolicyFunc) Authorize(ctx context.Context, subject *Subject, requirement Requirement) error {
+	return f(ctx, subject, requirement)
+}
+
+// RBAC returns the role-based Policy, which requires the subject to have one
+// of the roles of the requirement, and all of its scopes. Roles may include
+// others, e.g. so that admins have the permissions of editors, which is
+// declared as
+//
+//	authz.RBAC(map[string][]string{"admin": {"editor"}, "editor": {"viewer"}})
+//
+// A nil map declares no inclusions.
+func RBAC(includes map[string][]string) Policy {
+	return PolicyFunc(func(_ context.Context, subject *Subject, requirement Requirement) error {
+		var err ForbiddenError
+		if len(requirement.Roles) > 0 && !hasAnyRole(subject.Roles, requirement.Roles, includes) {
+			err.Roles = requirement.Roles
+		}
+		for _, scope := range requirement.Scopes {
+			if !subject.HasScope(scope) {
+				err.Scopes = append(err.Scopes, scope)
+			}
+		}
+		if err.Roles == nil && err.Scopes == nil {
+			return nil
+		}
+		err.Subject = subject.ID
+		return &err
+	})
+}
+
+// hasAnyRole reports whether the roles, or those they include, transitively,
+// contain any of the wanted ones.
+func hasAnyRole(roles, wanted []string, includes map[string][]string) bool {
+	var (
+		queue = append([]string(nil), roles...)
+		seen  = map[string]bool{}
+	)
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+		for _, w := range wanted {
+			if role == w {
+				return true
+			}
+		}
+		queue = append(queue, includes[role]...)
+	}
+	return false
+}
+
+// Option sets an optional parameter for authorizers.
+type Option func(*authorizer)
+
+// PolicyEngine sets the Policy which decides whether subjects meet the
+// requirement, e.g. one backed by an external policy engine. By default,
+// it's RBAC(nil).
+func PolicyEngine(p Policy) Option {
+	return func(a *authorizer) { a.policy = p }
+}
+
+// SubjectFrom sets the function which finds the Subject of a request in its
+// context. By default, it's SubjectFromContext.
+func SubjectFrom(f SubjectFunc) Option {
+	return func(a *authorizer) { a.subject = f }
+}
+
+type authorizer struct {
+	policy  Policy
+	subject SubjectFunc
+}
+
+// NewAuthorizer returns a middleware which requires the Subject of requests,
+// as placed in the context by the authentication middlewares of package
+// auth, to meet the requirement of the endpoint, e.g.
+//
+//	e = authz.NewAuthorizer(authz.Requirement{Roles: []string{"admin"}})(e)
+//	e = jwt.NewParser(keys, stdjwt.SigningMethodRS256, jwt.MapClaimsFactory)(e)
+//
+// Requests without a Subject fail with ErrUnauthenticated, and those whose
+// Subject doesn't meet the requirement with the error of the Policy.
+// Particularly useful for servers.
+func NewAuthorizer(requirement Requirement, options ...Option) endpoint.Middleware {
+	a := &authorizer{policy: RBAC(nil), subject: SubjectFromContext}
+	for _, option := range options {
+		option(a)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			subject, ok := a.subject(ctx)
+			if !ok {
+				return nil, ErrUnauthenticated
+			}
+			if err := a.policy.Authorize(ctx, subject, requirement); err != nil {
+				if endpoint.KindOf(err) == endpoint.KindUnknown {
+					err = endpoint.PermissionDenied(err)
+				}
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
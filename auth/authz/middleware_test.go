@@ -0,0 +1,80 @@
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	stdjwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/go-kit/kit/auth/apikey"
+	"github.com/go-kit/kit/auth/authz"
+	"github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestNewAuthorizer(t *testing.T) {
+	jwtContext := func(claims stdjwt.MapClaims) context.Context {
+		return context.WithValue(context.Background(), jwt.JWTClaimsContextKey, claims)
+	}
+	policy := authz.RBAC(map[string][]string{"admin": {"editor"}})
+	requirement := authz.Requirement{Roles: []string{"editor"}, Scopes: []string{"orders:write"}}
+	authorized := authz.NewAuthorizer(requirement, authz.PolicyEngine(policy))(endpoint.Nop)
+
+	for _, test := range []struct {
+		name string
+		ctx  context.Context
+		kind endpoint.Kind
+	}{
+		{"no subject", context.Background(), endpoint.KindUnauthenticated},
+		{"editor", jwtContext(stdjwt.MapClaims{"sub": "alice", "roles": []interface{}{"editor"}, "scope": "orders:write"}), endpoint.KindUnknown},
+		{"admin", jwtContext(stdjwt.MapClaims{"sub": "bob", "roles": "admin", "scope": "orders:read orders:write"}), endpoint.KindUnknown},
+		{"viewer", jwtContext(stdjwt.MapClaims{"sub": "carol", "roles": []interface{}{"viewer"}, "scope": "orders:write"}), endpoint.KindPermissionDenied},
+		{"missing scope", jwtContext(stdjwt.MapClaims{"sub": "alice", "roles": []interface{}{"editor"}}), endpoint.KindPermissionDenied},
+	} {
+		_, err := authorized(test.ctx, struct{}{})
+		if test.kind == endpoint.KindUnknown {
+			if err != nil {
+				t.Errorf("%s: want no error, have %v", test.name, err)
+			}
+			continue
+		}
+		if want, have := test.kind, endpoint.KindOf(err); want != have {
+			t.Errorf("%s: want %v, have %v (%v)", test.name, want, have, err)
+		}
+	}
+}
+
+func TestForbiddenError(t *testing.T) {
+	ctx := context.WithValue(context.Background(), apikey.PrincipalContextKey, &apikey.Principal{ID: "ci", Scopes: []string{"read"}})
+	requirement := authz.Requirement{Roles: []string{"deployer"}, Scopes: []string{"read", "deploy"}}
+	_, err := authz.NewAuthorizer(requirement)(endpoint.Nop)(ctx, struct{}{})
+
+	forbidden, ok := err.(*authz.ForbiddenError)
+	if !ok {
+		t.Fatalf("want *ForbiddenError, have %T", err)
+	}
+	if want, have := "ci", forbidden.Subject; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := []string{"deploy"}, forbidden.Scopes; len(have) != 1 || want[0] != have[0] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "permission denied: requires one of roles deployer and scopes deploy", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 403, forbidden.StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestPolicyFunc(t *testing.T) {
+	ctx := context.WithValue(context.Background(), apikey.PrincipalContextKey, &apikey.Principal{ID: "ci"})
+	policy := authz.PolicyFunc(func(context.Context, *authz.Subject, authz.Requirement) error {
+		return errors.New("outside business hours")
+	})
+	_, err := authz.NewAuthorizer(authz.Requirement{}, authz.PolicyEngine(policy))(endpoint.Nop)(ctx, struct{}{})
+	if want, have := endpoint.KindPermissionDenied, endpoint.KindOf(err); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
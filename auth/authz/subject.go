@@ -0,0 +1,109 @@
+package authz
+
+import (
+	"context"
+	"strings"
+
+	stdjwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/go-kit/kit/auth/apikey"
+	"github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/auth/mtls"
+	"github.com/go-kit/kit/auth/oauth2"
+)
+
+// Subject is the authenticated client of a request.
+type Subject struct {
+	// ID identifies the subject, e.g. the subject claim of a token.
+	ID string
+
+	// Roles are the roles of the subject.
+	Roles []string
+
+	// Scopes are the scopes granted to the subject.
+	Scopes []string
+}
+
+// HasRole reports whether the subject has the role.
+func (s *Subject) HasRole(role string) bool {
+	return contains(s.Roles, role)
+}
+
+// HasScope reports whether the subject has the scope.
+func (s *Subject) HasScope(scope string) bool {
+	return contains(s.Scopes, scope)
+}
+
+// SubjectFunc returns the Subject of a request from its context, if it was
+// authenticated.
+type SubjectFunc func(ctx context.Context) (*Subject, bool)
+
+// Claims of tokens, from which SubjectFromContext takes roles and scopes.
+const (
+	RolesClaim = "roles"
+	ScopeClaim = "scope"
+)
+
+// SubjectFromContext is the default SubjectFunc. It returns the Subject of
+// the first of these which is found in the context:
+//
+//   - the principal of an API key, from package auth/apikey, with its ID and
+//     scopes;
+//   - the claims of an OAuth 2.0 token, from package auth/oauth2, with its
+//     subject, scopes, and roles from RolesClaim;
+//   - the claims of a JWT, from package auth/jwt, if they're a
+//     jwt.MapClaims or a *jwt.StandardClaims, with its subject, and roles
+//     and scopes from RolesClaim and ScopeClaim;
+//   - the identity of a client certificate, from package auth/mtls, whose ID
+//     is its SPIFFE ID, or else its common name.
+func SubjectFromContext(ctx context.Context) (*Subject, bool) {
+	if p, ok := ctx.Value(apikey.PrincipalContextKey).(*apikey.Principal); ok {
+		return &Subject{ID: p.ID, Scopes: p.Scopes}, true
+	}
+	if c, ok := ctx.Value(oauth2.ClaimsContextKey).(*oauth2.Claims); ok {
+		return &Subject{ID: c.Subject, Roles: stringsClaim(c.Extra[RolesClaim]), Scopes: c.Scopes()}, true
+	}
+	switch c := ctx.Value(jwt.JWTClaimsContextKey).(type) {
+	case stdjwt.MapClaims:
+		id, _ := c["sub"].(string)
+		return &Subject{ID: id, Roles: stringsClaim(c[RolesClaim]), Scopes: stringsClaim(c[ScopeClaim])}, true
+	case *stdjwt.StandardClaims:
+		return &Subject{ID: c.Subject}, true
+	}
+	if id, ok := mtls.IdentityFromContext(ctx); ok {
+		if id.SPIFFEID != "" {
+			return &Subject{ID: id.SPIFFEID}, true
+		}
+		return &Subject{ID: id.CommonName}, true
+	}
+	return nil, false
+}
+
+// stringsClaim returns the strings of a claim, which is either a
+// space-separated string or an array of strings.
+func stringsClaim(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		s := make([]string, 0, len(v))
+		for _, e := range v {
+			if e, ok := e.(string); ok {
+				s = append(s, e)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package hmac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type contextKey string
+
+const (
+	// KeyIDContextKey holds the key used to store the ID of the key which
+	// signed a verified request in the context.
+	KeyIDContextKey contextKey = "HMACKeyID"
+
+	// errorContextKey holds the key used to store why a request failed
+	// verification in the context.
+	errorContextKey contextKey = "HMACError"
+)
+
+// Errors of verification. They're of kind endpoint.KindUnauthenticated, so
+// package transport/http responds with 401 Unauthorized, and package
+// transport/grpc with code Unauthenticated.
+var (
+	// ErrSignatureMissing denotes a request without a signature, or which
+	// wasn't passed through Verify.
+	ErrSignatureMissing = endpoint.Unauthenticated(errors.New("request signature is missing"))
+
+	// ErrSignatureInvalid denotes a signature which doesn't match the request.
+	ErrSignatureInvalid = endpoint.Unauthenticated(errors.New("request signature is invalid"))
+
+	// ErrSignatureExpired denotes a signature whose timestamp is outside the
+	// allowed clock skew.
+	ErrSignatureExpired = endpoint.Unauthenticated(errors.New("request signature is expired"))
+
+	// ErrSignatureReplayed denotes a signature whose nonce was already used.
+	ErrSignatureReplayed = endpoint.Unauthenticated(errors.New("request signature was already used"))
+
+	// ErrUnknownKey denotes a signature by a key the KeyFunc doesn't know.
+	ErrUnknownKey = endpoint.Unauthenticated(errors.New("request signed by unknown key"))
+)
+
+// NewVerified returns a middleware which rejects requests that weren't
+// verified by Verify, with the reason they failed. Particularly useful for
+// servers.
+func NewVerified() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if _, ok := ctx.Value(KeyIDContextKey).(string); ok {
+				return next(ctx, request)
+			}
+			if err, ok := ctx.Value(errorContextKey).(error); ok {
+				return nil, err
+			}
+			return nil, ErrSignatureMissing
+		}
+	}
+}
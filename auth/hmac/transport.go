@@ -0,0 +1,224 @@
+package hmac
+
+import (
+	"bytes"
+	"context"
+	stdhmac "crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	stdhttp "net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/transport/http"
+)
+
+// Headers carrying the signature of a request.
+const (
+	KeyIDHeader     = "X-Signature-Key-Id"
+	TimestampHeader = "X-Signature-Timestamp"
+	NonceHeader     = "X-Signature-Nonce"
+	SignatureHeader = "X-Signature"
+)
+
+// Defaults for Verify.
+const (
+	DefaultMaxSkew       = 5 * time.Minute
+	DefaultMaxBodySize   = 10 << 20 // bytes
+	DefaultNonceCapacity = 100000
+)
+
+// KeyFunc returns the secret key with the ID, or an error if there's none.
+type KeyFunc func(keyID string) ([]byte, error)
+
+// StaticKeys returns a KeyFunc looking up keys in the map.
+func StaticKeys(keys map[string][]byte) KeyFunc {
+	return func(keyID string) ([]byte, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return key, nil
+	}
+}
+
+// Sign returns a client middleware which signs requests with the key,
+// identified by keyID, setting the signature headers. The signature is an
+// HMAC-SHA256 of the method, path and query, timestamp, a random nonce, and a
+// SHA-256 hash of the body, which is read and replaced. As a middleware, set
+// with ClientWrap, it signs each attempt of ClientRetry with a fresh nonce,
+// so that retries aren't rejected as replays. It should be the last of the
+// client's middlewares, so that it signs the final request. Particularly
+// useful for clients.
+func Sign(keyID string, key []byte) http.ClientMiddleware {
+	return func(next http.Doer) http.Doer {
+		return http.DoerFunc(func(r *stdhttp.Request) (*stdhttp.Response, error) {
+			// If the request can't be signed, it's sent unsigned, for the
+			// server to reject.
+			sign(keyID, key, r)
+			return next.Do(r)
+		})
+	}
+}
+
+// sign sets the signature headers of the request.
+func sign(keyID string, key []byte, r *stdhttp.Request) {
+	body, err := readBody(r, -1)
+	if err != nil {
+		return
+	}
+	var b [16]byte
+	if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(b[:])
+	r.Header.Set(KeyIDHeader, keyID)
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, hex.EncodeToString(signature(key, r, timestamp, nonce, body)))
+}
+
+// VerifyOption sets an optional parameter for Verify.
+type VerifyOption func(*verifier)
+
+// VerifyMaxSkew sets how far the timestamp of a signature may be from the
+// server's clock. By default, DefaultMaxSkew is used.
+func VerifyMaxSkew(d time.Duration) VerifyOption {
+	return func(v *verifier) { v.maxSkew = d }
+}
+
+// VerifyMaxBodySize sets the maximum size of request bodies, which are
+// buffered to be hashed. Larger requests fail verification. By default,
+// DefaultMaxBodySize is used.
+func VerifyMaxBodySize(n int64) VerifyOption {
+	return func(v *verifier) { v.maxBodySize = n }
+}
+
+// VerifyNonceStore sets the store in which used nonces are remembered until
+// their signatures expire, e.g. one shared by replicas of the service. By
+// default, an LRU of DefaultNonceCapacity nonces is used, which protects
+// against replays unless more than that many requests arrive within the
+// allowed skew.
+func VerifyNonceStore(store cache.Store) VerifyOption {
+	return func(v *verifier) { v.nonces = store }
+}
+
+type verifier struct {
+	keys        KeyFunc
+	maxSkew     time.Duration
+	maxBodySize int64
+	nonces      cache.Store
+	mtx         sync.Mutex // serializes checking and storing nonces
+	now         func() time.Time
+}
+
+// Verify verifies the signatures of requests, set by Sign, with the keys. The
+// ID of the key of a verified request is stored in the context under
+// KeyIDContextKey; otherwise, the reason it failed is stored, for NewVerified
+// to return. The body of the request is read and replaced. Particularly
+// useful for servers.
+func Verify(keys KeyFunc, options ...VerifyOption) http.RequestFunc {
+	v := &verifier{
+		keys:        keys,
+		maxSkew:     DefaultMaxSkew,
+		maxBodySize: DefaultMaxBodySize,
+		now:         time.Now,
+	}
+	for _, option := range options {
+		option(v)
+	}
+	if v.nonces == nil {
+		v.nonces = cache.NewLRU(DefaultNonceCapacity)
+	}
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		keyID, err := v.verify(r)
+		if err != nil {
+			return context.WithValue(ctx, errorContextKey, err)
+		}
+		return context.WithValue(ctx, KeyIDContextKey, keyID)
+	}
+}
+
+func (v *verifier) verify(r *stdhttp.Request) (string, error) {
+	var (
+		keyID     = r.Header.Get(KeyIDHeader)
+		timestamp = r.Header.Get(TimestampHeader)
+		nonce     = r.Header.Get(NonceHeader)
+		sig       = r.Header.Get(SignatureHeader)
+	)
+	if keyID == "" || timestamp == "" || nonce == "" || sig == "" {
+		return "", ErrSignatureMissing
+	}
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	if skew := v.now().Sub(time.Unix(unix, 0)); skew > v.maxSkew || skew < -v.maxSkew {
+		return "", ErrSignatureExpired
+	}
+	key, err := v.keys(keyID)
+	if err != nil {
+		return "", ErrUnknownKey
+	}
+	body, err := readBody(r, v.maxBodySize)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	if !stdhmac.Equal(want, signature(key, r, timestamp, nonce, body)) {
+		return "", ErrSignatureInvalid
+	}
+
+	// Only remember nonces of valid signatures, so that forged requests can't
+	// burn the nonces of genuine ones.
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	nonceKey := keyID + ":" + nonce
+	if _, seen := v.nonces.Get(nonceKey); seen {
+		return "", ErrSignatureReplayed
+	}
+	v.nonces.Set(nonceKey, struct{}{}, 2*v.maxSkew)
+	return keyID, nil
+}
+
+// signature returns the HMAC-SHA256 of the canonical form of the request.
+func signature(key []byte, r *stdhttp.Request, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := stdhmac.New(sha256.New, key)
+	io.WriteString(mac, r.Method+"\n"+r.URL.RequestURI()+"\n"+timestamp+"\n"+nonce+"\n")
+	io.WriteString(mac, hex.EncodeToString(bodyHash[:]))
+	return mac.Sum(nil)
+}
+
+// readBody reads the body of the request, up to max bytes if max isn't
+// negative, and replaces it with a reader of what was read.
+func readBody(r *stdhttp.Request, max int64) ([]byte, error) {
+	if r.Body == nil || r.Body == stdhttp.NoBody {
+		return nil, nil
+	}
+	var src io.Reader = r.Body
+	if max >= 0 {
+		src = io.LimitReader(r.Body, max+1)
+	}
+	body, err := ioutil.ReadAll(src)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if max >= 0 && int64(len(body)) > max {
+		return nil, ErrSignatureInvalid
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) { return ioutil.NopCloser(bytes.NewReader(body)), nil }
+	return body, nil
+}
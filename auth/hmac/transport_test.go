@@ -0,0 +1,155 @@
+package hmac_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/auth/hmac"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestSignVerify(t *testing.T) {
+	var (
+		key    = []byte("secret")
+		keys   = hmac.StaticKeys(map[string][]byte{"svc": key})
+		verify = hmac.Verify(keys)
+		check  = hmac.NewVerified()(func(ctx context.Context, request interface{}) (interface{}, error) {
+			return ctx.Value(hmac.KeyIDContextKey), nil
+		})
+	)
+	newRequest := func(body string) *http.Request {
+		r := httptest.NewRequest("POST", "http://example.com/orders?id=1", strings.NewReader(body))
+		r.Header = http.Header{}
+		return r
+	}
+	verified := func(r *http.Request) (interface{}, error) {
+		return check(verify(context.Background(), r), nil)
+	}
+	sign := func(r *http.Request) {
+		hmac.Sign("svc", key)(httptransport.DoerFunc(func(*http.Request) (*http.Response, error) {
+			return nil, nil
+		})).Do(r)
+	}
+
+	// A signed request verifies, and its body is intact.
+	r := newRequest("hello")
+	sign(r)
+	signed := r.Header
+	if keyID, err := verified(r); err != nil || keyID != "svc" {
+		t.Fatalf("want svc, have %v, %v", keyID, err)
+	}
+	if body, _ := ioutil.ReadAll(r.Body); string(body) != "hello" {
+		t.Errorf("body: want %q, have %q", "hello", body)
+	}
+
+	// Replaying it fails.
+	r = newRequest("hello")
+	r.Header = signed
+	if _, err := verified(r); err != hmac.ErrSignatureReplayed {
+		t.Errorf("replay: want %v, have %v", hmac.ErrSignatureReplayed, err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(*http.Request)
+		want   error
+	}{
+		{"unsigned", func(r *http.Request) { r.Header = http.Header{} }, hmac.ErrSignatureMissing},
+		{"body", func(r *http.Request) { r.Body = ioutil.NopCloser(strings.NewReader("HELLO")) }, hmac.ErrSignatureInvalid},
+		{"path", func(r *http.Request) { r.URL.RawQuery = "id=2" }, hmac.ErrSignatureInvalid},
+		{"key", func(r *http.Request) { r.Header.Set(hmac.KeyIDHeader, "other") }, hmac.ErrUnknownKey},
+		{"expired", func(r *http.Request) {
+			r.Header.Set(hmac.TimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		}, hmac.ErrSignatureExpired},
+	} {
+		r := newRequest("hello")
+		sign(r)
+		tc.tamper(r)
+		if _, err := verified(r); err != tc.want {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestSignVerifyHTTP(t *testing.T) {
+	key := []byte("secret")
+	server := httptest.NewServer(httptransport.NewServer(
+		hmac.NewVerified()(endpoint.Nop),
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerBefore(hmac.Verify(hmac.StaticKeys(map[string][]byte{"svc": key}))),
+	))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/orders")
+	for _, tc := range []struct {
+		options []httptransport.ClientOption
+		want    int
+	}{
+		{[]httptransport.ClientOption{httptransport.ClientWrap(hmac.Sign("svc", key))}, http.StatusOK},
+		{[]httptransport.ClientOption{httptransport.ClientWrap(hmac.Sign("svc", []byte("wrong")))}, http.StatusUnauthorized},
+		{nil, http.StatusUnauthorized},
+	} {
+		client := httptransport.NewClient(
+			"POST", u,
+			httptransport.EncodeJSONRequest,
+			func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+			tc.options...,
+		)
+		have, err := client.Endpoint()(context.Background(), map[string]string{"a": "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tc.want; want != have {
+			t.Errorf("want %d, have %v", want, have)
+		}
+	}
+}
+
+func TestSignRetry(t *testing.T) {
+	var (
+		key      = []byte("secret")
+		attempts int
+	)
+	server := httptest.NewServer(httptransport.NewServer(
+		hmac.NewVerified()(func(context.Context, interface{}) (interface{}, error) {
+			if attempts++; attempts == 1 {
+				return nil, endpoint.Unavailable(errors.New("overloaded"))
+			}
+			return struct{}{}, nil
+		}),
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerBefore(hmac.Verify(hmac.StaticKeys(map[string][]byte{"svc": key}))),
+	))
+	defer server.Close()
+
+	// The retry after the 503 is signed anew, rather than replayed.
+	u, _ := url.Parse(server.URL + "/orders")
+	client := httptransport.NewClient(
+		"PUT", u,
+		httptransport.EncodeJSONRequest,
+		func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+		httptransport.ClientRetry(httptransport.ClientRetryBackoff(time.Millisecond, time.Millisecond)),
+		httptransport.ClientWrap(hmac.Sign("svc", key)),
+	)
+	have, err := client.Endpoint()(context.Background(), map[string]string{"a": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := http.StatusOK; want != have {
+		t.Errorf("want %d, have %v", want, have)
+	}
+	if want, have := 2, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
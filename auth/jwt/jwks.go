@@ -0,0 +1,254 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// ErrKeyNotFound denotes the JWKS has no key for the key ID header (kid) of
+// a token, even after it was fetched again.
+var ErrKeyNotFound = errors.New("no key found for the JWT Token's key ID")
+
+// JWKS is a JSON Web Key Set fetched from an endpoint, e.g. that of an OAuth
+// 2.0 authorization server, whose Keyfunc selects the keys of tokens by their
+// key ID header (kid), so that the keys can be rotated without restarts.
+//
+// The set is fetched with the first token, and cached until it expires, after
+// the refresh interval with a jitter of up to 10%, so that instances don't
+// fetch it in lockstep. Once it expired, or if a token has an unknown key ID,
+// e.g. as a new key was published, it's fetched again, at most once per
+// minimum refresh interval. If a fetch fails, the last set which was fetched
+// is kept.
+type JWKS struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	minInterval     time.Duration
+	now             func() time.Time
+
+	mtx     sync.Mutex
+	rand    *rand.Rand
+	keys    map[string]interface{} // by kid; nil until fetched
+	expires time.Time
+	fetched time.Time // the last attempt
+	err     error     // of the last attempt
+}
+
+// JWKSOption sets an optional parameter for JWKSs.
+type JWKSOption func(*JWKS)
+
+// JWKSClient sets the http.Client with which the set is fetched. By default,
+// it's a client with a timeout of 10 seconds.
+func JWKSClient(client *http.Client) JWKSOption {
+	return func(j *JWKS) { j.client = client }
+}
+
+// JWKSRefreshInterval sets how long the set is cached. By default, it's an
+// hour.
+func JWKSRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) { j.refreshInterval = d }
+}
+
+// JWKSMinRefreshInterval sets the minimum interval between fetches of the
+// set, so that tokens with unknown key IDs, or an unavailable endpoint, don't
+// cause a fetch per request. By default, it's a minute.
+func JWKSMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) { j.minInterval = d }
+}
+
+// NewJWKS returns a JWKS fetched from the url, e.g.
+//
+//	jwks := jwt.NewJWKS("https://example.com/.well-known/jwks.json")
+//	ep = jwt.NewParser(jwks.Keyfunc, stdjwt.SigningMethodRS256, jwt.StandardClaimsFactory)(ep)
+func NewJWKS(url string, options ...JWKSOption) *JWKS {
+	j := &JWKS{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: time.Hour,
+		minInterval:     time.Minute,
+		now:             time.Now,
+		rand:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, option := range options {
+		option(j)
+	}
+	return j
+}
+
+// Keyfunc implements jwt.Keyfunc. It returns the key of the set whose key ID
+// is that of the token, or the only key of the set if the token has none.
+// The signing method of the token is checked by NewParser.
+func (j *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	keys, err := j.get(kid)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Refresh fetches the set now, e.g. to warm the cache on startup. If it
+// fails, the last set which was fetched is kept.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	return j.refresh(ctx)
+}
+
+// get returns the cached keys, fetched again if they expired or have no key
+// of the kid, unless they were fetched within the minimum interval.
+func (j *JWKS) get(kid string) (map[string]interface{}, error) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	now := j.now()
+	if !j.fetched.IsZero() && now.Sub(j.fetched) < j.minInterval {
+		if j.keys == nil {
+			return nil, j.err
+		}
+		return j.keys, nil
+	}
+	if _, ok := j.keys[kid]; ok && now.Before(j.expires) {
+		return j.keys, nil
+	}
+	if err := j.refresh(context.Background()); err != nil && j.keys == nil {
+		return nil, err
+	}
+	return j.keys, nil
+}
+
+// refresh fetches the set, and keeps the last one if it fails. The lock must
+// be held, so that concurrent requests wait for a single fetch.
+func (j *JWKS) refresh(ctx context.Context) error {
+	keys, err := j.fetch(ctx)
+	now := j.now()
+	j.fetched, j.err = now, err
+	if err != nil {
+		return err
+	}
+	jitter := time.Duration(j.rand.Int63n(int64(j.refreshInterval)/10 + 1))
+	j.keys, j.expires = keys, now.Add(j.refreshInterval-jitter)
+	return nil
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequest("GET", j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS: %s", resp.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding JWKS: %v", err)
+	}
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, so that they don't
+		// invalidate the others.
+		if key, err := k.key(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// jsonWebKey is a JSON Web Key, as defined by RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// key returns the key of the type which jwt-go verifies with: an
+// *rsa.PublicKey, an *ecdsa.PublicKey or a []byte.
+func (k jsonWebKey) key() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
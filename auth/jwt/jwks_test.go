@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+type jwksServer struct {
+	mtx     sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+	fail    bool
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.fetches++
+	if s.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (s *jwksServer) set(f func()) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	f()
+}
+
+func signRS256(t *testing.T, kid string, key *rsa.PrivateKey) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"user": "go-kit"})
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWKS(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{"1": key1}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	now := time.Now()
+	jwks := NewJWKS(server.URL, JWKSRefreshInterval(time.Hour), JWKSMinRefreshInterval(time.Minute))
+	jwks.now = func() time.Time { return now }
+
+	e := func(ctx context.Context, i interface{}) (interface{}, error) { return ctx, nil }
+	parser := NewParser(jwks.Keyfunc, jwt.SigningMethodRS256, MapClaimsFactory)(e)
+	parse := func(token string) error {
+		_, err := parser(context.WithValue(context.Background(), JWTTokenContextKey, token), struct{}{})
+		return err
+	}
+	fetches := func() int {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		return s.fetches
+	}
+
+	// The set is fetched with the first token, and cached.
+	for i := 0; i < 2; i++ {
+		if err := parse(signRS256(t, "1", key1)); err != nil {
+			t.Fatalf("want no error, have %v", err)
+		}
+	}
+	if want, have := 1, fetches(); want != have {
+		t.Errorf("want %d fetches, have %d", want, have)
+	}
+
+	// A rotated key is fetched by its unknown kid, once the minimum interval
+	// elapsed.
+	s.set(func() { s.keys["2"] = key2 })
+	if err := parse(signRS256(t, "2", key2)); err != ErrKeyNotFound {
+		t.Errorf("want %v, have %v", ErrKeyNotFound, err)
+	}
+	now = now.Add(time.Minute)
+	if err := parse(signRS256(t, "2", key2)); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+	if want, have := 2, fetches(); want != have {
+		t.Errorf("want %d fetches, have %d", want, have)
+	}
+
+	// The last set is kept when a fetch of an expired one fails.
+	s.set(func() { s.fail = true })
+	now = now.Add(2 * time.Hour)
+	if err := parse(signRS256(t, "1", key1)); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+	if err := parse(signRS256(t, "2", key2)); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+	if want, have := 3, fetches(); want != have {
+		t.Errorf("want %d fetches, have %d", want, have)
+	}
+}
+
+func TestJWKSUnavailable(t *testing.T) {
+	s := &jwksServer{fail: true}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL)
+	if err := jwks.Refresh(context.Background()); err == nil {
+		t.Error("want an error")
+	}
+	token := &jwt.Token{Header: map[string]interface{}{"kid": "1"}}
+	if _, err := jwks.Keyfunc(token); err == nil {
+		t.Error("want an error")
+	}
+	if want, have := 1, s.fetches; want != have {
+		t.Errorf("want %d fetches, have %d", want, have)
+	}
+}
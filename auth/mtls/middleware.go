@@ -0,0 +1,194 @@
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type contextKey string
+
+const (
+	// IdentityContextKey holds the key used to store the *Identity of the
+	// verified client certificate of a request in the context.
+	IdentityContextKey contextKey = "MTLSIdentity"
+
+	// errorContextKey holds the key used to store why a request wasn't
+	// authenticated in the context.
+	errorContextKey contextKey = "MTLSError"
+)
+
+// Errors of authentication. ErrCertificateMissing is of kind
+// endpoint.KindUnauthenticated, and ErrIdentityNotAllowed of kind
+// endpoint.KindPermissionDenied, so package transport/http responds with 401
+// Unauthorized and 403 Forbidden, respectively.
+var (
+	// ErrCertificateMissing denotes a request without a verified client
+	// certificate, or which wasn't passed through the transport helpers.
+	ErrCertificateMissing = endpoint.Unauthenticated(errors.New("verified client certificate is missing"))
+
+	// ErrIdentityNotAllowed denotes a client certificate whose identity
+	// isn't in the allow lists.
+	ErrIdentityNotAllowed = endpoint.PermissionDenied(errors.New("client certificate identity is not allowed"))
+)
+
+// Identity is the identity of a client, from its verified certificate.
+type Identity struct {
+	// Certificate is the verified leaf certificate of the client.
+	Certificate *x509.Certificate
+
+	// CommonName is the common name of the certificate's subject.
+	CommonName string
+
+	// DNSNames are the DNS names of the certificate's subject alternative
+	// names.
+	DNSNames []string
+
+	// SPIFFEID is the SPIFFE ID of the certificate, its URI subject
+	// alternative name with the spiffe scheme, e.g.
+	// "spiffe://example.org/ns/prod/sa/billing", if it has one.
+	SPIFFEID string
+}
+
+// NewIdentity returns the Identity of the certificate.
+func NewIdentity(cert *x509.Certificate) *Identity {
+	id := &Identity{
+		Certificate: cert,
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			id.SPIFFEID = uri.String()
+			break
+		}
+	}
+	return id
+}
+
+// TrustDomain returns the trust domain of the SPIFFE ID, e.g. "example.org",
+// or an empty string if there's none.
+func (id *Identity) TrustDomain() string {
+	domain := strings.TrimPrefix(id.SPIFFEID, "spiffe://")
+	if domain == id.SPIFFEID {
+		return ""
+	}
+	if i := strings.IndexByte(domain, '/'); i >= 0 {
+		domain = domain[:i]
+	}
+	return domain
+}
+
+// IdentityFromContext returns the Identity of the verified client
+// certificate stored in the context, if any.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(IdentityContextKey).(*Identity)
+	return id, ok
+}
+
+// NewAuthenticated returns a middleware which rejects requests without an
+// allowed Identity in the context, stored by the transport helpers, with the
+// reason they failed. Particularly useful for servers.
+func NewAuthenticated() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if _, ok := IdentityFromContext(ctx); ok {
+				return next(ctx, request)
+			}
+			if err, ok := ctx.Value(errorContextKey).(error); ok {
+				return nil, err
+			}
+			return nil, ErrCertificateMissing
+		}
+	}
+}
+
+// Option sets an optional parameter for the transport helpers.
+type Option func(*policy)
+
+// AllowDNSNames allows clients whose certificates have any of the DNS names
+// in their subject alternative names, compared case-insensitively.
+func AllowDNSNames(names ...string) Option {
+	return func(p *policy) { p.dnsNames = append(p.dnsNames, names...) }
+}
+
+// AllowSPIFFEIDs allows clients whose certificates have any of the SPIFFE
+// IDs.
+func AllowSPIFFEIDs(ids ...string) Option {
+	return func(p *policy) { p.spiffeIDs = append(p.spiffeIDs, ids...) }
+}
+
+// AllowTrustDomains allows clients whose certificates have a SPIFFE ID in
+// any of the trust domains, e.g. "example.org".
+func AllowTrustDomains(domains ...string) Option {
+	return func(p *policy) { p.trustDomains = append(p.trustDomains, domains...) }
+}
+
+// AllowIf allows clients whose identities satisfy the predicate.
+func AllowIf(f func(*Identity) bool) Option {
+	return func(p *policy) { p.predicates = append(p.predicates, f) }
+}
+
+// policy holds the allow lists. A client is allowed if its identity is in
+// any of them, or if there are none.
+type policy struct {
+	dnsNames     []string
+	spiffeIDs    []string
+	trustDomains []string
+	predicates   []func(*Identity) bool
+}
+
+func newPolicy(options []Option) *policy {
+	p := &policy{}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// authenticate stores the identity of the verified chains in the context, if
+// it's allowed, or else why it isn't.
+func (p *policy) authenticate(ctx context.Context, chains [][]*x509.Certificate) context.Context {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return context.WithValue(ctx, errorContextKey, ErrCertificateMissing)
+	}
+	id := NewIdentity(chains[0][0])
+	if !p.allows(id) {
+		return context.WithValue(ctx, errorContextKey, ErrIdentityNotAllowed)
+	}
+	return context.WithValue(ctx, IdentityContextKey, id)
+}
+
+func (p *policy) allows(id *Identity) bool {
+	if len(p.dnsNames) == 0 && len(p.spiffeIDs) == 0 && len(p.trustDomains) == 0 && len(p.predicates) == 0 {
+		return true
+	}
+	for _, name := range id.DNSNames {
+		for _, allowed := range p.dnsNames {
+			if strings.EqualFold(name, allowed) {
+				return true
+			}
+		}
+	}
+	if id.SPIFFEID != "" && (contains(p.spiffeIDs, id.SPIFFEID) || contains(p.trustDomains, id.TrustDomain())) {
+		return true
+	}
+	for _, f := range p.predicates {
+		if f(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
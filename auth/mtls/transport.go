@@ -0,0 +1,48 @@
+package mtls
+
+import (
+	"context"
+	stdhttp "net/http"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/http"
+)
+
+// ToHTTPContext stores the Identity of the request's verified client
+// certificate in the context, if it's allowed by the options; otherwise, the
+// reason it isn't is stored, for NewAuthenticated to return. The server's
+// tls.Config must verify client certificates, e.g. with ClientAuth set to
+// tls.RequireAndVerifyClientCert, as unverified certificates are ignored.
+// Particularly useful for servers.
+func ToHTTPContext(options ...Option) http.RequestFunc {
+	p := newPolicy(options)
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if r.TLS == nil {
+			return p.authenticate(ctx, nil)
+		}
+		return p.authenticate(ctx, r.TLS.VerifiedChains)
+	}
+}
+
+// ToGRPCContext stores the Identity of the verified client certificate of
+// the gRPC peer in the context, as ToHTTPContext does. The server must use
+// TLS transport credentials which verify client certificates. Particularly
+// useful for servers.
+func ToGRPCContext(options ...Option) grpc.ServerRequestFunc {
+	p := newPolicy(options)
+	return func(ctx context.Context, _ metadata.MD) context.Context {
+		pr, ok := peer.FromContext(ctx)
+		if !ok {
+			return p.authenticate(ctx, nil)
+		}
+		info, ok := pr.AuthInfo.(credentials.TLSInfo)
+		if !ok {
+			return p.authenticate(ctx, nil)
+		}
+		return p.authenticate(ctx, info.State.VerifiedChains)
+	}
+}
@@ -0,0 +1,94 @@
+package mtls_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/auth/mtls"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func newCert(cn, dnsName, uri string) *x509.Certificate {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	if dnsName != "" {
+		cert.DNSNames = []string{dnsName}
+	}
+	if uri != "" {
+		u, _ := url.Parse(uri)
+		cert.URIs = []*url.URL{u}
+	}
+	return cert
+}
+
+func TestToHTTPContext(t *testing.T) {
+	var (
+		billing = newCert("billing", "billing.internal", "spiffe://example.org/ns/prod/sa/billing")
+		orders  = newCert("orders", "orders.internal", "spiffe://example.org/ns/prod/sa/orders")
+		partner = newCert("partner", "", "spiffe://partner.com/api")
+		web     = newCert("web", "WEB.internal", "")
+	)
+	var identity *mtls.Identity
+	e := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		identity, _ = mtls.IdentityFromContext(ctx)
+		return nil, nil
+	}
+	authenticated := mtls.NewAuthenticated()(e)
+
+	for _, test := range []struct {
+		name    string
+		options []mtls.Option
+		cert    *x509.Certificate
+		err     error
+	}{
+		{"no certificate", nil, nil, mtls.ErrCertificateMissing},
+		{"any certificate", nil, billing, nil},
+		{"SPIFFE ID", []mtls.Option{mtls.AllowSPIFFEIDs(billing.URIs[0].String())}, billing, nil},
+		{"other SPIFFE ID", []mtls.Option{mtls.AllowSPIFFEIDs(billing.URIs[0].String())}, orders, mtls.ErrIdentityNotAllowed},
+		{"trust domain", []mtls.Option{mtls.AllowTrustDomains("example.org")}, orders, nil},
+		{"other trust domain", []mtls.Option{mtls.AllowTrustDomains("example.org")}, partner, mtls.ErrIdentityNotAllowed},
+		{"DNS name", []mtls.Option{mtls.AllowDNSNames("web.internal")}, web, nil},
+		{"predicate", []mtls.Option{mtls.AllowIf(func(id *mtls.Identity) bool { return id.CommonName == "partner" })}, partner, nil},
+	} {
+		identity = nil
+		r := httptest.NewRequest("GET", "https://example.com/", nil)
+		if test.cert != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{test.cert}}}
+		}
+		ctx := mtls.ToHTTPContext(test.options...)(context.Background(), r)
+		_, err := authenticated(ctx, struct{}{})
+		if want, have := test.err, err; want != have {
+			t.Errorf("%s: want %v, have %v", test.name, want, have)
+			continue
+		}
+		if err == nil && identity.Certificate != test.cert {
+			t.Errorf("%s: want the identity of the certificate", test.name)
+		}
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	id := mtls.NewIdentity(newCert("billing", "billing.internal", "spiffe://example.org/ns/prod/sa/billing"))
+	if want, have := "spiffe://example.org/ns/prod/sa/billing", id.SPIFFEID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "example.org", id.TrustDomain(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "", mtls.NewIdentity(newCert("web", "web.internal", "")).TrustDomain(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestNewAuthenticatedKinds(t *testing.T) {
+	if want, have := endpoint.KindUnauthenticated, endpoint.KindOf(mtls.ErrCertificateMissing); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := endpoint.KindPermissionDenied, endpoint.KindOf(mtls.ErrIdentityNotAllowed); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,192 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Defaults for introspectors.
+const (
+	DefaultCacheCapacity = 10000
+	DefaultCacheTTL      = time.Minute
+)
+
+// Claims are the claims of a token, as returned by an introspection endpoint
+// (RFC 7662, section 2.2). Only Active is required; the other claims are set
+// if the authorization server returns them.
+type Claims struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+
+	// Extra holds all of the members of the response, including those of
+	// extensions, by name.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// Scopes returns the space-separated scopes of the token.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the token has the scope.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// audience is the audience claim, which is either a string or an array of
+// strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// Introspector validates opaque tokens with the introspection endpoint of an
+// OAuth 2.0 authorization server (RFC 7662). The claims of tokens are cached,
+// so that the endpoint isn't called for each request; those of active tokens
+// no longer than until they expire.
+type Introspector struct {
+	url          string
+	client       *http.Client
+	clientID     string
+	clientSecret string
+	cache        cache.Store
+	ttl          time.Duration
+	now          func() time.Time
+}
+
+// IntrospectorOption sets an optional parameter for introspectors.
+type IntrospectorOption func(*Introspector)
+
+// IntrospectorClient sets the http.Client with which the endpoint is called.
+// By default, it's a client with a timeout of 10 seconds.
+func IntrospectorClient(client *http.Client) IntrospectorOption {
+	return func(i *Introspector) { i.client = client }
+}
+
+// IntrospectorCredentials sets the credentials with which the protected
+// resource authenticates to the endpoint, with HTTP Basic authentication.
+// By default, requests aren't authenticated.
+func IntrospectorCredentials(clientID, clientSecret string) IntrospectorOption {
+	return func(i *Introspector) { i.clientID, i.clientSecret = clientID, clientSecret }
+}
+
+// IntrospectorCache sets the store in which the claims of tokens are cached,
+// keyed by a hash of the token, and for how long, e.g. a store shared by
+// replicas of the service. A ttl of zero disables caching. By default, an LRU
+// of DefaultCacheCapacity tokens is used, with a ttl of DefaultCacheTTL.
+func IntrospectorCache(store cache.Store, ttl time.Duration) IntrospectorOption {
+	return func(i *Introspector) { i.cache, i.ttl = store, ttl }
+}
+
+// NewIntrospector returns an Introspector calling the introspection endpoint
+// at the url.
+func NewIntrospector(url string, options ...IntrospectorOption) *Introspector {
+	i := &Introspector{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		ttl:    DefaultCacheTTL,
+		now:    time.Now,
+	}
+	for _, option := range options {
+		option(i)
+	}
+	if i.cache == nil {
+		i.cache = cache.NewLRU(DefaultCacheCapacity)
+	}
+	return i
+}
+
+// Introspect returns the claims of the token, whether it's active or not.
+// Errors of the endpoint are of kind endpoint.KindUnavailable. The returned
+// claims may be cached, so they mustn't be modified.
+func (i *Introspector) Introspect(ctx context.Context, token string) (*Claims, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if i.ttl > 0 {
+		if claims, ok := i.cache.Get(key); ok {
+			return claims.(*Claims), nil
+		}
+	}
+
+	claims, err := i.introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := i.ttl
+	if claims.Active && claims.ExpiresAt != 0 {
+		if d := time.Unix(claims.ExpiresAt, 0).Sub(i.now()); d < ttl {
+			ttl = d
+		}
+	}
+	if ttl > 0 {
+		i.cache.Set(key, claims, ttl)
+	}
+	return claims, nil
+}
+
+func (i *Introspector) introspect(ctx context.Context, token string) (*Claims, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequest("POST", i.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.clientID), url.QueryEscape(i.clientSecret))
+	}
+
+	resp, err := i.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, endpoint.Unavailable(fmt.Errorf("introspecting OAuth2 token: %v", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, endpoint.Unavailable(fmt.Errorf("introspecting OAuth2 token: %s", resp.Status))
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, endpoint.Unavailable(fmt.Errorf("decoding OAuth2 introspection response: %v", err))
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(raw, claims); err != nil {
+		return nil, endpoint.Unavailable(fmt.Errorf("decoding OAuth2 introspection response: %v", err))
+	}
+	if err := json.Unmarshal(raw, &claims.Extra); err != nil {
+		return nil, endpoint.Unavailable(fmt.Errorf("decoding OAuth2 introspection response: %v", err))
+	}
+	return claims, nil
+}
@@ -0,0 +1,72 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type contextKey string
+
+const (
+	// TokenContextKey holds the key used to store an OAuth 2.0 bearer token
+	// in the context.
+	TokenContextKey contextKey = "OAuth2Token"
+
+	// ClaimsContextKey holds the key used to store the *Claims of an active
+	// token in the context.
+	ClaimsContextKey contextKey = "OAuth2Claims"
+)
+
+// Errors of introspected requests. Those of tokens are of kind
+// endpoint.KindUnauthenticated, and that of scopes of kind
+// endpoint.KindPermissionDenied, so package transport/http responds with 401
+// Unauthorized and 403 Forbidden, respectively.
+var (
+	// ErrTokenContextMissing denotes a token was not passed into the
+	// introspecting middleware's context.
+	ErrTokenContextMissing = endpoint.Unauthenticated(errors.New("token up for introspection was not passed through the context"))
+
+	// ErrTokenInactive denotes a token the authorization server doesn't
+	// consider active, e.g. as it expired or was revoked.
+	ErrTokenInactive = endpoint.Unauthenticated(errors.New("OAuth2 token is not active"))
+
+	// ErrInsufficientScope denotes an active token without all of the
+	// required scopes.
+	ErrInsufficientScope = endpoint.PermissionDenied(errors.New("OAuth2 token has insufficient scope"))
+)
+
+// NewIntrospection creates a new token introspection middleware, which
+// validates the opaque token passed into the context via TokenContextKey
+// with the Introspector, and requires it to have all of the scopes, if any.
+// It adds the claims of an active token to the context via ClaimsContextKey,
+// or returns an error. Particularly useful for servers.
+func NewIntrospection(introspector *Introspector, scopes ...string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			// token is stored in the context from the transport handlers.
+			token, ok := ctx.Value(TokenContextKey).(string)
+			if !ok {
+				return nil, ErrTokenContextMissing
+			}
+
+			claims, err := introspector.Introspect(ctx, token)
+			if err != nil {
+				return nil, err
+			}
+			if !claims.Active {
+				return nil, ErrTokenInactive
+			}
+			for _, scope := range scopes {
+				if !claims.HasScope(scope) {
+					return nil, ErrInsufficientScope
+				}
+			}
+
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+
+			return next(ctx, request)
+		}
+	}
+}
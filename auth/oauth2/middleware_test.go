@@ -0,0 +1,137 @@
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/auth/oauth2"
+	"github.com/go-kit/kit/endpoint"
+)
+
+type introspectionServer struct {
+	mtx    sync.Mutex
+	tokens map[string]map[string]interface{}
+	calls  int
+}
+
+func (s *introspectionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.calls++
+	if id, secret, ok := r.BasicAuth(); !ok || id != "api" || secret != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	response, ok := s.tokens[r.PostFormValue("token")]
+	if !ok {
+		response = map[string]interface{}{"active": false}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestNewIntrospection(t *testing.T) {
+	s := &introspectionServer{tokens: map[string]map[string]interface{}{
+		"reader": {"active": true, "scope": "read", "sub": "alice", "aud": "api", "tenant": "acme"},
+		"writer": {"active": true, "scope": "read write", "aud": []string{"api", "web"}},
+	}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	introspector := oauth2.NewIntrospector(server.URL, oauth2.IntrospectorCredentials("api", "secret"))
+	var claims *oauth2.Claims
+	e := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		claims = ctx.Value(oauth2.ClaimsContextKey).(*oauth2.Claims)
+		return nil, nil
+	}
+	introspected := oauth2.NewIntrospection(introspector, "write")(e)
+	call := func(token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = context.WithValue(ctx, oauth2.TokenContextKey, token)
+		}
+		_, err := introspected(ctx, struct{}{})
+		return err
+	}
+
+	for _, test := range []struct {
+		token string
+		err   error
+	}{
+		{"", oauth2.ErrTokenContextMissing},
+		{"unknown", oauth2.ErrTokenInactive},
+		{"reader", oauth2.ErrInsufficientScope},
+		{"writer", nil},
+	} {
+		if want, have := test.err, call(test.token); want != have {
+			t.Errorf("%q: want %v, have %v", test.token, want, have)
+		}
+	}
+	if want, have := []string{"read", "write"}, claims.Scopes(); len(want) != len(have) || want[1] != have[1] {
+		t.Errorf("want scopes %v, have %v", want, have)
+	}
+	if want, have := 2, len(claims.Audience); want != have {
+		t.Errorf("want %d audiences, have %d", want, have)
+	}
+	if want, have := http.StatusForbidden, endpoint.KindOf(oauth2.ErrInsufficientScope).StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	// Responses are cached, whether the tokens are active or not.
+	for _, token := range []string{"unknown", "reader", "writer"} {
+		call(token)
+	}
+	if want, have := 3, s.calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestIntrospectorClaims(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	s := &introspectionServer{tokens: map[string]map[string]interface{}{
+		"token": {"active": true, "sub": "alice", "aud": "api", "exp": exp, "tenant": "acme"},
+	}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	introspector := oauth2.NewIntrospector(server.URL,
+		oauth2.IntrospectorCredentials("api", "secret"),
+		oauth2.IntrospectorCache(nil, 0),
+	)
+	claims, err := introspector.Introspect(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claims.Active || claims.Subject != "alice" || claims.ExpiresAt != exp {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if want, have := []string{"api"}, claims.Audience; len(have) != 1 || want[0] != have[0] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "acme", claims.Extra["tenant"]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Caching is disabled.
+	introspector.Introspect(context.Background(), "token")
+	if want, have := 2, s.calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestIntrospectorUnavailable(t *testing.T) {
+	s := &introspectionServer{}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	// The server rejects the missing credentials.
+	introspector := oauth2.NewIntrospector(server.URL)
+	_, err := introspector.Introspect(context.Background(), "token")
+	if want, have := endpoint.KindUnavailable, endpoint.KindOf(err); want != have {
+		t.Errorf("want %v, have %v (%v)", want, have, err)
+	}
+}
@@ -0,0 +1,75 @@
+package oauth2
+
+import (
+	"context"
+	stdhttp "net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/http"
+)
+
+const bearer = "Bearer "
+
+// ToHTTPContext moves the bearer token from the request's Authorization
+// header to context. Particularly useful for servers.
+func ToHTTPContext() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		token, ok := extractToken(r.Header.Get("Authorization"))
+		if !ok {
+			return ctx
+		}
+		return context.WithValue(ctx, TokenContextKey, token)
+	}
+}
+
+// FromHTTPContext moves the bearer token from context to the request's
+// Authorization header. Particularly useful for clients.
+func FromHTTPContext() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if token, ok := ctx.Value(TokenContextKey).(string); ok {
+			r.Header.Set("Authorization", bearer+token)
+		}
+		return ctx
+	}
+}
+
+// ToGRPCContext moves the bearer token from grpc metadata to context.
+// Particularly useful for servers.
+func ToGRPCContext() grpc.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		// capital "Key" is illegal in HTTP/2.
+		values, ok := md["authorization"]
+		if !ok || len(values) == 0 {
+			return ctx
+		}
+		token, ok := extractToken(values[0])
+		if !ok {
+			return ctx
+		}
+		return context.WithValue(ctx, TokenContextKey, token)
+	}
+}
+
+// FromGRPCContext moves the bearer token from context to grpc metadata.
+// Particularly useful for clients.
+func FromGRPCContext() grpc.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if token, ok := ctx.Value(TokenContextKey).(string); ok {
+			// capital "Key" is illegal in HTTP/2.
+			(*md)["authorization"] = []string{bearer + token}
+		}
+		return ctx
+	}
+}
+
+// extractToken returns the token of a bearer Authorization header, whose
+// scheme is case-insensitive.
+func extractToken(header string) (string, bool) {
+	if len(header) <= len(bearer) || !strings.EqualFold(header[:len(bearer)], bearer) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearer):]), true
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/internal/singleflight"
+)
+
+// Store holds cached responses by key. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the response stored under the key, if it exists and hasn't
+	// expired.
+	Get(key string) (response interface{}, ok bool)
+
+	// Set stores the response under the key, to expire after ttl.
+	Set(key string, response interface{}, ttl time.Duration)
+}
+
+// KeyFunc derives the cache key of a request. Requests for which it returns
+// false aren't cached.
+type KeyFunc func(ctx context.Context, request interface{}) (key string, ok bool)
+
+// JSONKey is the default KeyFunc. The key is the dynamic type of the request
+// followed by its JSON encoding, so requests are equal if their exported
+// fields are. Requests which can't be encoded aren't cached.
+func JSONKey(_ context.Context, request interface{}) (string, bool) {
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%T:%s", request, buf), true
+}
+
+// Option sets an optional parameter for the middleware.
+type Option func(*cache)
+
+// Key sets the function which derives cache keys from requests. By default,
+// JSONKey is used.
+func Key(f KeyFunc) Option {
+	return func(c *cache) { c.key = f }
+}
+
+// CacheIf sets the predicate which decides whether a response is cached. By
+// default, every response without an error is cached.
+func CacheIf(f func(response interface{}, err error) bool) Option {
+	return func(c *cache) { c.cacheable = f }
+}
+
+// Middleware returns an endpoint.Middleware which serves responses from the
+// store when possible. On a miss, the endpoint is invoked and its response is
+// stored for ttl. Concurrent misses for the same key are collapsed into a
+// single invocation, whose result is shared by all of them; a caller whose
+// context is done gives up waiting with the context's error. The shared
+// invocation uses the context of the first caller.
+//
+// Responses are returned as stored, so they should be treated as immutable.
+func Middleware(store Store, ttl time.Duration, options ...Option) endpoint.Middleware {
+	c := &cache{
+		store:     store,
+		ttl:       ttl,
+		key:       JSONKey,
+		cacheable: func(_ interface{}, err error) bool { return err == nil },
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			key, ok := c.key(ctx, request)
+			if !ok {
+				return next(ctx, request)
+			}
+			if response, ok := c.store.Get(key); ok {
+				return response, nil
+			}
+			return c.calls.Do(ctx, key, func() (interface{}, error) {
+				response, err := next(ctx, request)
+				if c.cacheable(response, err) {
+					c.store.Set(key, response, c.ttl)
+				}
+				return response, err
+			})
+		}
+	}
+}
+
+type cache struct {
+	store     Store
+	ttl       time.Duration
+	key       KeyFunc
+	cacheable func(interface{}, error) bool
+
+	calls singleflight.Group
+}
@@ -0,0 +1,127 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/cache"
+)
+
+type request struct {
+	ID   int
+	Name string
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		calls int32
+		e     = func(_ context.Context, req interface{}) (interface{}, error) {
+			n := atomic.AddInt32(&calls, 1)
+			if req.(request).Name == "fail" {
+				return nil, errors.New("fail")
+			}
+			return n, nil
+		}
+		cached = cache.Middleware(cache.NewLRU(10), time.Minute)(e)
+		ctx    = context.Background()
+	)
+	for i, tc := range []struct {
+		req       request
+		wantCalls int32
+	}{
+		{request{1, "a"}, 1},
+		{request{1, "a"}, 1}, // hit
+		{request{2, "a"}, 2},
+		{request{1, "b"}, 3},
+		{request{1, "fail"}, 4},
+		{request{1, "fail"}, 5}, // errors aren't cached
+		{request{2, "a"}, 5},
+	} {
+		cached(ctx, tc.req)
+		if want, have := tc.wantCalls, atomic.LoadInt32(&calls); want != have {
+			t.Errorf("request %d: want %d calls, have %d", i, want, have)
+		}
+	}
+}
+
+func TestKeyAndCacheIf(t *testing.T) {
+	var (
+		calls int32
+		e     = func(context.Context, interface{}) (interface{}, error) {
+			return atomic.AddInt32(&calls, 1), nil
+		}
+		key = func(_ context.Context, req interface{}) (string, bool) {
+			r := req.(request)
+			return r.Name, r.ID > 0
+		}
+		cached = cache.Middleware(cache.NewLRU(10), time.Minute,
+			cache.Key(key),
+			cache.CacheIf(func(response interface{}, _ error) bool { return response.(int32) != 2 }),
+		)(e)
+		ctx = context.Background()
+	)
+	cached(ctx, request{0, "a"}) // uncacheable key
+	cached(ctx, request{0, "a"})
+	cached(ctx, request{2, "a"}) // response 3 is cached by name
+	cached(ctx, request{3, "a"})
+	if want, have := int32(3), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestSingleFlight(t *testing.T) {
+	var (
+		calls   int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+		e       = func(context.Context, interface{}) (interface{}, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+			}
+			<-release
+			return "ok", nil
+		}
+		cached = cache.Middleware(cache.NewLRU(10), time.Minute)(e)
+		wg     sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cached(context.Background(), request{1, "a"})
+	}()
+	<-started
+
+	responses := make(chan interface{}, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, _ := cached(context.Background(), request{1, "a"})
+			responses <- response
+		}()
+	}
+
+	// A waiter gives up when its context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cached(ctx, request{1, "a"}); err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(responses)
+	for response := range responses {
+		if want, have := "ok", response; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+	if want, have := int32(1), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
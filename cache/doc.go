@@ -0,0 +1,4 @@
+// Package cache provides an endpoint middleware which caches responses, for
+// expensive idempotent endpoints. Responses are stored in a pluggable Store;
+// an in-memory LRU is provided.
+package cache
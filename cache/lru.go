@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is an in-memory Store holding a bounded number of responses. When it's
+// full, the least recently used response is evicted. Expired responses are
+// removed when they're next looked up, or evicted.
+type LRU struct {
+	mtx     sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // most recently used at the front
+	now     func() time.Time
+}
+
+type entry struct {
+	key      string
+	response interface{}
+	expires  time.Time
+}
+
+// NewLRU returns an LRU holding up to size responses.
+func NewLRU(size int) *LRU {
+	if size < 1 {
+		size = 1
+	}
+	return &LRU{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get implements Store.
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if ent := e.Value.(*entry); !c.now().Before(ent.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*entry).response, true
+}
+
+// Set implements Store.
+func (c *LRU) Set(key string, response interface{}, ttl time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	expires := c.now().Add(ttl)
+	if e, ok := c.entries[key]; ok {
+		ent := e.Value.(*entry)
+		ent.response, ent.expires = response, expires
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, response: response, expires: expires})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of responses held, including any which have expired
+// but not yet been removed.
+func (c *LRU) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*entry).key)
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	var (
+		now = time.Now()
+		c   = NewLRU(2)
+	)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Second)
+	if _, ok := c.Get("a"); !ok { // a is now most recently used
+		t.Error("a: want hit, have miss")
+	}
+	c.Set("c", 3, time.Minute) // evicts b
+	if _, ok := c.Get("b"); ok {
+		t.Error("b: want eviction, have hit")
+	}
+	if want, have := 2, c.Len(); want != have {
+		t.Errorf("want %d entries, have %d", want, have)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a: want expiry, have hit")
+	}
+	if want, have := 1, c.Len(); want != have {
+		t.Errorf("want %d entries, have %d", want, have)
+	}
+
+	c.Set("c", 4, time.Minute)
+	if response, ok := c.Get("c"); !ok || response != 4 {
+		t.Errorf("c: want 4, have %v (%v)", response, ok)
+	}
+}
@@ -0,0 +1,179 @@
+package circuitbreaker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// LimitAlgorithm adjusts the limit of an AdaptiveLimiter as requests
+// complete. It's called with the lock of the limiter held, so it needn't be
+// safe for concurrent use, but it mustn't be shared by limiters.
+type LimitAlgorithm interface {
+	// Update returns the new limit, given the current one, the latency of a
+	// completed request, the number of requests in flight when it began,
+	// and whether it failed, e.g. as it timed out.
+	Update(limit float64, rtt time.Duration, inFlight int, failed bool) float64
+}
+
+// AIMD returns a LimitAlgorithm which increases the limit additively, by 1
+// per request which succeeds within timeout while the limit is in use, and
+// decreases it multiplicatively, by backoff, e.g. 0.9, per request which
+// fails or exceeds timeout.
+func AIMD(backoff float64, timeout time.Duration) LimitAlgorithm {
+	return &aimd{backoff: backoff, timeout: timeout}
+}
+
+type aimd struct {
+	backoff float64
+	timeout time.Duration
+}
+
+func (a *aimd) Update(limit float64, rtt time.Duration, inFlight int, failed bool) float64 {
+	if failed || rtt > a.timeout {
+		return limit * a.backoff
+	}
+	if float64(inFlight)*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// Gradient returns a LimitAlgorithm which adjusts the limit by the gradient
+// of the long-term average latency, a proxy of the latency of the downstream
+// without load, to the latency of each request: as requests queue
+// downstream, their latency grows and the limit shrinks. The latency may
+// exceed the average by a factor of tolerance, e.g. 2, before the limit
+// shrinks. Each new limit is smoothed, e.g. by 0.2, into the current one.
+func Gradient(tolerance, smoothing float64) LimitAlgorithm {
+	return &gradient{tolerance: tolerance, smoothing: smoothing}
+}
+
+type gradient struct {
+	tolerance float64
+	smoothing float64
+	long      float64 // the average latency, in seconds, over ~600 requests
+}
+
+func (g *gradient) Update(limit float64, rtt time.Duration, inFlight int, failed bool) float64 {
+	sample := rtt.Seconds()
+	if g.long == 0 {
+		g.long = sample
+	} else {
+		g.long += (sample - g.long) * 2 / 601
+	}
+	if float64(inFlight)*2 < limit || sample <= 0 {
+		return limit // the limit isn't in use, so latency says nothing of it
+	}
+	gradient := math.Max(0.5, math.Min(1, g.tolerance*g.long/sample))
+	queue := math.Sqrt(limit)
+	return limit*(1-g.smoothing) + (limit*gradient+queue)*g.smoothing
+}
+
+// AdaptiveLimiter limits the requests in flight to a limit which adapts to
+// the observed latency of the requests, with a LimitAlgorithm, so that
+// excess load is shed before the downstream collapses, without configuring
+// a static limit.
+type AdaptiveLimiter struct {
+	algorithm LimitAlgorithm
+	min, max  float64
+	failure   func(context.Context, error) bool
+
+	mtx      sync.Mutex
+	limit    float64
+	inFlight int
+}
+
+// AdaptiveOption sets an optional parameter for adaptive limiters.
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// AdaptiveLimits sets the initial, minimum, and maximum limits. By default,
+// they're 20, 1, and 1000. The minimum is at least 1, so that the limiter
+// always admits a request whose result can raise the limit; the maximum is
+// at least the minimum, and the initial limit is between them.
+func AdaptiveLimits(initial, min, max int) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.min = math.Max(1, float64(min))
+		l.max = math.Max(l.min, float64(max))
+		l.limit = math.Max(l.min, math.Min(l.max, float64(initial)))
+	}
+}
+
+// AdaptiveFailureIf sets the predicate which reports whether an error of a
+// request is a failure, which the algorithm may decrease the limit for. By
+// default, it's DefaultFailure.
+func AdaptiveFailureIf(failure func(ctx context.Context, err error) bool) AdaptiveOption {
+	return func(l *AdaptiveLimiter) { l.failure = failure }
+}
+
+// NewAdaptiveLimiter returns an AdaptiveLimiter whose limit is adjusted by
+// the algorithm.
+func NewAdaptiveLimiter(algorithm LimitAlgorithm, options ...AdaptiveOption) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		algorithm: algorithm,
+		limit:     20,
+		min:       1,
+		max:       1000,
+		failure:   DefaultFailure,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Limit returns the current limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests in flight.
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.inFlight
+}
+
+// acquire returns the number of requests in flight, including this one, or
+// false if the limit is reached.
+func (l *AdaptiveLimiter) acquire() (int, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if float64(l.inFlight) >= math.Floor(l.limit) {
+		return 0, false
+	}
+	l.inFlight++
+	return l.inFlight, true
+}
+
+func (l *AdaptiveLimiter) release(rtt time.Duration, inFlight int, failed bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.inFlight--
+	limit := l.algorithm.Update(l.limit, rtt, inFlight, failed)
+	l.limit = math.Max(l.min, math.Min(l.max, limit))
+}
+
+// AdaptiveConcurrency returns an endpoint.Middleware which limits the
+// requests in flight with the AdaptiveLimiter. Requests beyond the limit are
+// rejected immediately with endpoint.ErrConcurrencyLimit. The limit is
+// shared by every endpoint wrapped by the limiter.
+func AdaptiveConcurrency(l *AdaptiveLimiter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			inFlight, ok := l.acquire()
+			if !ok {
+				return nil, endpoint.ErrConcurrencyLimit
+			}
+			defer func(begin time.Time) {
+				l.release(time.Since(begin), inFlight, l.failure(ctx, err))
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestAIMD(t *testing.T) {
+	a := circuitbreaker.AIMD(0.5, time.Second)
+	for _, tc := range []struct {
+		rtt      time.Duration
+		inFlight int
+		failed   bool
+		want     float64
+	}{
+		{time.Millisecond, 5, false, 11}, // in use
+		{time.Millisecond, 4, false, 10}, // not in use
+		{time.Millisecond, 5, true, 5},
+		{2 * time.Second, 5, false, 5},
+	} {
+		if have := a.Update(10, tc.rtt, tc.inFlight, tc.failed); tc.want != have {
+			t.Errorf("%+v: want %v, have %v", tc, tc.want, have)
+		}
+	}
+}
+
+func TestGradient(t *testing.T) {
+	g := circuitbreaker.Gradient(1, 1)
+	if want, have := 110.0, g.Update(100, 10*time.Millisecond, 100, false); want != have {
+		t.Errorf("at the average latency: want %v, have %v", want, have)
+	}
+	// Queueing doubles the latency, which halves the limit, plus a queue.
+	if have := g.Update(100, 20*time.Millisecond, 100, false); have >= 100*0.51+10 {
+		t.Errorf("at twice the average latency: want about %v, have %v", 100*0.5+10, have)
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	var (
+		limiter = circuitbreaker.NewAdaptiveLimiter(
+			circuitbreaker.AIMD(0.5, time.Second),
+			circuitbreaker.AdaptiveLimits(2, 1, 10),
+		)
+		release = make(chan struct{})
+		errDown = errors.New("down")
+		e       = circuitbreaker.AdaptiveConcurrency(limiter)(func(_ context.Context, request interface{}) (interface{}, error) {
+			if request == "fail" {
+				return nil, errDown
+			}
+			<-release
+			return struct{}{}, nil
+		})
+		wg sync.WaitGroup
+	)
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() { defer wg.Done(); e(context.Background(), "block") }()
+	}
+	for limiter.InFlight() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := e(context.Background(), "block"); err != endpoint.ErrConcurrencyLimit {
+		t.Fatalf("want %v, have %v", endpoint.ErrConcurrencyLimit, err)
+	}
+	close(release)
+	wg.Wait()
+	limit := limiter.Limit()
+	if limit <= 2 {
+		t.Errorf("want limit above 2 after the limit was used, have %d", limit)
+	}
+
+	e(context.Background(), "fail")
+	if have := limiter.Limit(); have >= limit {
+		t.Errorf("want limit below %d after a failure, have %d", limit, have)
+	}
+}
+
+func TestAdaptiveLimitsClamped(t *testing.T) {
+	for _, tc := range []struct {
+		initial, min, max int
+		want              int
+	}{
+		{0, 0, 0, 1},
+		{5, -3, 10, 5},
+		{20, 4, 2, 4},
+		{50, 1, 10, 10},
+	} {
+		limiter := circuitbreaker.NewAdaptiveLimiter(
+			circuitbreaker.AIMD(0.5, time.Second),
+			circuitbreaker.AdaptiveLimits(tc.initial, tc.min, tc.max),
+		)
+		if have := limiter.Limit(); tc.want != have {
+			t.Errorf("%+v: want %d, have %d", tc, tc.want, have)
+		}
+	}
+}
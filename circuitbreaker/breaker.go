@@ -0,0 +1,302 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ErrCircuitOpen is returned by the endpoints of a Breaker whose circuit is
+// open, or half-open with all of its probes in flight.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State is the state of the circuit of a Breaker.
+type State int
+
+const (
+	// StateClosed is the state in which requests are allowed, and their
+	// failures are counted.
+	StateClosed State = iota
+
+	// StateOpen is the state in which requests fail fast with
+	// ErrCircuitOpen, until the open duration elapses.
+	StateOpen
+
+	// StateHalfOpen is the state in which a limited number of probe
+	// requests are allowed, to find whether the endpoint has recovered.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so that states are encoded
+// by their names.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Breaker is a circuit breaker without dependencies. Its circuit opens after
+// a number of consecutive failures, or once the rate of failures over a
+// sliding window reaches a threshold, once it has seen a minimum number of
+// requests. Once the open duration elapses, it's
+// half-open: a number of probe requests are allowed, and it closes if they
+// all succeed, or opens again as soon as one of them fails.
+//
+// A Breaker may be shared by several endpoints, so that they trip together.
+type Breaker struct {
+	failures       int
+	minRequests    int
+	openDuration   time.Duration
+	halfOpenProbes int
+	rate           float64
+	window         window // nil unless the circuit opens on the rate of failures
+	now            func() time.Time
+
+	mtx          sync.Mutex
+	state        State
+	forced       bool   // the state is held until reset
+	generation   uint64 // incremented on each change of state
+	requests     int
+	consecutive  int
+	probes       int // allowed while half-open
+	successes    int // of the probes
+	openedAt     time.Time
+	transitioned time.Time
+}
+
+// BreakerOption sets an optional parameter for breakers.
+type BreakerOption func(*Breaker)
+
+// BreakerFailures sets the number of consecutive failures which open the
+// circuit. By default, it's 5.
+func BreakerFailures(n int) BreakerOption {
+	return func(b *Breaker) { b.failures = n }
+}
+
+// BreakerMinRequests sets the minimum number of requests since the circuit
+// last closed, or in the window of BreakerFailureRate, before it may open, so
+// that a few failures of a cold endpoint don't open it. By default, there's
+// no minimum.
+func BreakerMinRequests(n int) BreakerOption {
+	return func(b *Breaker) { b.minRequests = n }
+}
+
+// BreakerOpenDuration sets how long the circuit stays open before it's
+// half-open. By default, it's 10 seconds.
+func BreakerOpenDuration(d time.Duration) BreakerOption {
+	return func(b *Breaker) { b.openDuration = d }
+}
+
+// BreakerHalfOpenProbes sets the number of probe requests allowed while the
+// circuit is half-open, all of which must succeed for it to close. By
+// default, it's 1.
+func BreakerHalfOpenProbes(n int) BreakerOption {
+	return func(b *Breaker) { b.halfOpenProbes = n }
+}
+
+// NewBreaker returns a Breaker whose circuit is closed.
+func NewBreaker(options ...BreakerOption) *Breaker {
+	b := &Breaker{
+		failures:       5,
+		openDuration:   10 * time.Second,
+		halfOpenProbes: 1,
+		now:            time.Now,
+	}
+	for _, option := range options {
+		option(b)
+	}
+	b.transitioned = b.now()
+	return b
+}
+
+// State returns the current state of the circuit.
+func (b *Breaker) State() State {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.expire()
+	return b.state
+}
+
+// allow returns the generation of the state in which the request is allowed,
+// or ErrCircuitOpen.
+func (b *Breaker) allow() (uint64, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.expire()
+	switch b.state {
+	case StateOpen:
+		return 0, ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenProbes {
+			return 0, ErrCircuitOpen
+		}
+		b.probes++
+	case StateClosed:
+		b.requests++
+	}
+	return b.generation, nil
+}
+
+// done records the outcome of a request allowed in the generation. Outcomes
+// of requests allowed before the last change of state are ignored, as are
+// those which are ignored, except that they release their probe while the
+// circuit is half-open.
+func (b *Breaker) done(generation uint64, result outcome) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.expire()
+	if generation != b.generation || b.forced {
+		return
+	}
+	failed := result == failure
+	switch b.state {
+	case StateClosed:
+		if result == ignored {
+			b.requests--
+			return
+		}
+		if b.window != nil {
+			b.window.record(b.now(), failed)
+			requests, failures := b.window.counts(b.now())
+			if failed && requests >= b.minRequests && float64(failures) >= b.rate*float64(requests) {
+				b.setState(StateOpen)
+			}
+			return
+		}
+		if !failed {
+			b.consecutive = 0
+			return
+		}
+		b.consecutive++
+		if b.consecutive >= b.failures && b.requests >= b.minRequests {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		switch result {
+		case ignored:
+			b.probes--
+		case failure:
+			b.setState(StateOpen)
+		default:
+			b.successes++
+			if b.successes >= b.halfOpenProbes {
+				b.setState(StateClosed)
+			}
+		}
+	}
+}
+
+// expire makes an open circuit half-open once the open duration elapsed.
+func (b *Breaker) expire() {
+	if b.state == StateOpen && !b.forced && b.now().Sub(b.openedAt) >= b.openDuration {
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.generation++
+	b.requests, b.consecutive, b.probes, b.successes = 0, 0, 0, 0
+	if b.window != nil {
+		b.window.reset()
+	}
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	b.transitioned = b.now()
+}
+
+// Stats are the statistics of a Breaker.
+type Stats struct {
+	State  State     `json:"state"`
+	Forced bool      `json:"forced"`
+	Since  time.Time `json:"since"` // the last change of state
+
+	// Requests and Failures are those of the sliding window of
+	// BreakerFailureRate, or else the requests since the circuit last
+	// closed and the consecutive failures.
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+}
+
+// Stats returns the current statistics of the Breaker.
+func (b *Breaker) Stats() Stats {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.expire()
+	s := Stats{
+		State:    b.state,
+		Forced:   b.forced,
+		Since:    b.transitioned,
+		Requests: b.requests,
+		Failures: b.consecutive,
+	}
+	if b.window != nil {
+		s.Requests, s.Failures = b.window.counts(b.now())
+	}
+	return s
+}
+
+// ForceOpen opens the circuit, and holds it open until Reset, e.g. to shed
+// the load of a struggling dependency during an incident.
+func (b *Breaker) ForceOpen() {
+	b.force(StateOpen, true)
+}
+
+// ForceClose closes the circuit, and holds it closed until Reset, regardless
+// of failures.
+func (b *Breaker) ForceClose() {
+	b.force(StateClosed, true)
+}
+
+// Reset closes the circuit, and releases it if it was forced.
+func (b *Breaker) Reset() {
+	b.force(StateClosed, false)
+}
+
+func (b *Breaker) force(state State, forced bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.setState(state)
+	b.forced = forced
+}
+
+// Middleware returns an endpoint.Middleware that implements the circuit
+// breaker pattern using the Breaker. Only errors returned by the wrapped
+// endpoint which are failures, as FailureIf classifies them, count against
+// the circuit breaker's error count; other errors don't count at all.
+// Requests whose context is done fail with its error, without counting.
+func Middleware(b *Breaker, options ...Option) endpoint.Middleware {
+	o := newObserver(options)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			generation, err := b.allow()
+			o.observe(b.State)
+			if err != nil {
+				o.shortCircuit()
+				return o.fallBack(ctx, request, nil, err, true)
+			}
+			response, err := next(ctx, request)
+			b.done(generation, o.outcome(ctx, err))
+			o.observe(b.State)
+			return o.fallBack(ctx, request, response, err, false)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/circuitbreaker"
+)
+
+func TestBreaker(t *testing.T) {
+	var (
+		breaker          = circuitbreaker.Middleware(circuitbreaker.NewBreaker())
+		primeWith        = 100
+		shouldPass       = func(n int) bool { return n < 5 }
+		circuitOpenError = circuitbreaker.ErrCircuitOpen.Error()
+	)
+	testFailingEndpoint(t, breaker, primeWith, shouldPass, 0, circuitOpenError)
+}
+
+func TestBreakerMinRequests(t *testing.T) {
+	var (
+		b = circuitbreaker.NewBreaker(circuitbreaker.BreakerFailures(1), circuitbreaker.BreakerMinRequests(3))
+		m = mock{err: errors.New("cold")}
+		e = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+	for i := 0; i < 3; i++ {
+		if _, err := e(context.Background(), struct{}{}); err != m.err {
+			t.Fatalf("request %d: want %v, have %v", i, m.err, err)
+		}
+	}
+	if want, have := circuitbreaker.StateOpen, b.State(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	var (
+		b = circuitbreaker.NewBreaker(
+			circuitbreaker.BreakerFailures(1),
+			circuitbreaker.BreakerOpenDuration(10*time.Millisecond),
+			circuitbreaker.BreakerHalfOpenProbes(2),
+		)
+		m = mock{err: errors.New("down")}
+		e = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+	expect := func(want circuitbreaker.State) {
+		t.Helper()
+		if have := b.State(); want != have {
+			t.Fatalf("want %s, have %s", want, have)
+		}
+	}
+
+	e(context.Background(), struct{}{})
+	expect(circuitbreaker.StateOpen)
+	time.Sleep(10 * time.Millisecond)
+	expect(circuitbreaker.StateHalfOpen)
+
+	// A failed probe opens the circuit again.
+	if _, err := e(context.Background(), struct{}{}); err != m.err {
+		t.Fatalf("want %v, have %v", m.err, err)
+	}
+	expect(circuitbreaker.StateOpen)
+	time.Sleep(10 * time.Millisecond)
+
+	// It closes once all probes succeed.
+	m.err = nil
+	for i := 0; i < 2; i++ {
+		expect(circuitbreaker.StateHalfOpen)
+		if _, err := e(context.Background(), struct{}{}); err != nil {
+			t.Fatalf("probe %d: %v", i, err)
+		}
+	}
+	expect(circuitbreaker.StateClosed)
+}
+
+func TestBreakerHalfOpenCanceled(t *testing.T) {
+	var (
+		b = circuitbreaker.NewBreaker(
+			circuitbreaker.BreakerFailures(1),
+			circuitbreaker.BreakerOpenDuration(10*time.Millisecond),
+		)
+		m = mock{err: errors.New("down")}
+		e = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+	e(context.Background(), struct{}{})
+	time.Sleep(10 * time.Millisecond)
+	if want, have := circuitbreaker.StateHalfOpen, b.State(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+
+	// The client of the probe goes away while the endpoint is still down.
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := circuitbreaker.Middleware(b)(func(context.Context, interface{}) (interface{}, error) {
+		cancel()
+		return nil, context.Canceled
+	})
+	if _, err := canceled(ctx, struct{}{}); err != context.Canceled {
+		t.Fatalf("want %v, have %v", context.Canceled, err)
+	}
+	if want, have := circuitbreaker.StateHalfOpen, b.State(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+
+	// Its probe is released, and the next one finds the endpoint down.
+	if _, err := e(context.Background(), struct{}{}); err != m.err {
+		t.Fatalf("want %v, have %v", m.err, err)
+	}
+	if want, have := circuitbreaker.StateOpen, b.State(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
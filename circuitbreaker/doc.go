@@ -6,5 +6,10 @@
 //
 // We provide several implementations in this package, but if you're looking
 // for guidance, Gobreaker is probably the best place to start.  It has a
-// simple and intuitive API, and is well-tested.
+// simple and intuitive API, and is well-tested. Breaker is similar, and has
+// no dependencies.
+//
+// AdaptiveConcurrency complements circuit breakers by shedding excess load
+// before a downstream fails: it limits the requests in flight to a limit
+// which adapts to their latency.
 package circuitbreaker
@@ -0,0 +1,65 @@
+package circuitbreaker
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// FailureIf sets the predicate which reports whether an error returned by
+// the wrapped endpoint counts as a failure of the service. Errors which
+// aren't failures are returned, and never close a half-open circuit. With
+// Breaker, they don't count at all: they neither reset the count of
+// failures, nor use up the probe of a half-open circuit, which is released
+// for another request. HandyBreaker reports them as neither successes nor
+// failures. Gobreaker and Hystrix count every request, so with them, such
+// errors count as successes while the circuit is closed, which resets the
+// count of consecutive failures, and as failures of probes. By default, it's
+// DefaultFailure.
+func FailureIf(failure func(ctx context.Context, err error) bool) Option {
+	return func(o *observer) { o.failure = failure }
+}
+
+// DefaultFailure reports whether the error is a failure of the service.
+// Errors of requests whose context was canceled, e.g. as the client went
+// away, aren't, nor are errors whose kinds are the fault of the caller, such
+// as endpoint.KindInvalidArgument and endpoint.KindNotFound. Business errors
+// carried by responses implementing endpoint.Failer aren't returned by the
+// endpoint, so they're never failures.
+func DefaultFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() == context.Canceled {
+		return false
+	}
+	switch endpoint.KindOf(err) {
+	case endpoint.KindCanceled,
+		endpoint.KindInvalidArgument,
+		endpoint.KindNotFound,
+		endpoint.KindAlreadyExists,
+		endpoint.KindPermissionDenied,
+		endpoint.KindUnauthenticated,
+		endpoint.KindFailedPrecondition:
+		return false
+	}
+	return true
+}
+
+// outcome is how the result of a request counts against the circuit.
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	ignored // as it says nothing of the health of the service
+)
+
+// outcome classifies the result of a request by its error.
+func (o *observer) outcome(ctx context.Context, err error) outcome {
+	switch {
+	case err == nil:
+		return success
+	case o.failure(ctx, err):
+		return failure
+	default:
+		return ignored
+	}
+}
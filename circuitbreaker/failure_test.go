@@ -0,0 +1,69 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestDefaultFailure(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tc := range []struct {
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{context.Background(), nil, false},
+		{context.Background(), errors.New("unavailable"), true},
+		{context.Background(), context.DeadlineExceeded, true},
+		{context.Background(), endpoint.NotFound(errors.New("no such user")), false},
+		{context.Background(), context.Canceled, false},
+		{canceled, errors.New("transport: context canceled"), false},
+	} {
+		if have := circuitbreaker.DefaultFailure(tc.ctx, tc.err); tc.want != have {
+			t.Errorf("%v: want %v, have %v", tc.err, tc.want, have)
+		}
+	}
+}
+
+func TestFailureIf(t *testing.T) {
+	var (
+		b        = circuitbreaker.NewBreaker(circuitbreaker.BreakerFailures(1))
+		notFound = endpoint.NotFound(errors.New("no such user"))
+		m        = mock{err: notFound}
+		e        = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+
+	// Errors which are the fault of the caller don't trip the circuit.
+	for i := 0; i < 3; i++ {
+		if _, err := e(context.Background(), struct{}{}); err != notFound {
+			t.Fatalf("want %v, have %v", notFound, err)
+		}
+	}
+	if want, have := circuitbreaker.StateClosed, b.State(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+
+	// Requests whose context is done aren't made.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e(ctx, struct{}{}); err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+	if want, have := 3, m.through; want != have {
+		t.Errorf("want %d requests, have %d", want, have)
+	}
+
+	// Unless the predicate says otherwise.
+	e = circuitbreaker.Middleware(b, circuitbreaker.FailureIf(func(_ context.Context, err error) bool {
+		return err != nil
+	}))(m.endpoint)
+	e(context.Background(), struct{}{})
+	if want, have := circuitbreaker.StateOpen, b.State(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
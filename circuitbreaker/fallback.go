@@ -0,0 +1,15 @@
+package circuitbreaker
+
+import (
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Fallback sets an endpoint which serves the requests which are
+// short-circuited, as the circuit is open, or which fail, e.g. with cached or
+// default responses, so that the service degrades rather than fails. Errors
+// which aren't failures, as FailureIf classifies them, are returned as is.
+// Requests served by it are counted by the FallbackSuccesses and
+// FallbackFailures metrics.
+func Fallback(fallback endpoint.Endpoint) Option {
+	return func(o *observer) { o.fallback = fallback }
+}
@@ -0,0 +1,65 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestFallback(t *testing.T) {
+	var (
+		m = circuitbreaker.Metrics{
+			FallbackSuccesses: generic.NewCounter("fallback_successes"),
+			FallbackFailures:  generic.NewCounter("fallback_failures"),
+		}
+		cached      = "cached"
+		errNoCache  = errors.New("not cached")
+		fallbackErr error
+		fallback    = func(context.Context, interface{}) (interface{}, error) {
+			if fallbackErr != nil {
+				return nil, fallbackErr
+			}
+			return cached, nil
+		}
+		b        = circuitbreaker.NewBreaker(circuitbreaker.BreakerFailures(2))
+		breaker  = circuitbreaker.Middleware(b, circuitbreaker.Fallback(fallback), circuitbreaker.Instrument(m))
+		notFound = endpoint.NotFound(errors.New("no such user"))
+		mock     = mock{err: notFound}
+		e        = breaker(mock.endpoint)
+	)
+
+	// Errors which aren't failures are returned as is.
+	if _, err := e(context.Background(), struct{}{}); err != notFound {
+		t.Fatalf("want %v, have %v", notFound, err)
+	}
+
+	// Failures are served by the fallback, and so are requests while the
+	// circuit is open.
+	mock.err = errors.New("down")
+	for i := 0; i < 3; i++ {
+		if response, err := e(context.Background(), struct{}{}); err != nil || response != cached {
+			t.Fatalf("request %d: want %v, have %v (%v)", i, cached, response, err)
+		}
+	}
+	if want, have := circuitbreaker.StateOpen, b.State(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 2, mock.through-1; want != have {
+		t.Errorf("want %d failed requests, have %d", want, have)
+	}
+
+	fallbackErr = errNoCache
+	if _, err := e(context.Background(), struct{}{}); err != errNoCache {
+		t.Errorf("want %v, have %v", errNoCache, err)
+	}
+	if want, have := 3.0, m.FallbackSuccesses.(*generic.Counter).Value(); want != have {
+		t.Errorf("want %v fallback successes, have %v", want, have)
+	}
+	if want, have := 1.0, m.FallbackFailures.(*generic.Counter).Value(); want != have {
+		t.Errorf("want %v fallback failures, have %v", want, have)
+	}
+}
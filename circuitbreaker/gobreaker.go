@@ -10,13 +10,51 @@ import (
 
 // Gobreaker returns an endpoint.Middleware that implements the circuit
 // breaker pattern using the sony/gobreaker package. Only errors returned by
-// the wrapped endpoint count against the circuit breaker's error count.
+// the wrapped endpoint which are failures, as FailureIf classifies them,
+// count against the circuit breaker's error count. Requests whose context is
+// done fail with its error, without counting. As gobreaker counts every
+// request, other errors count as successes while the circuit is closed, but
+// as failures of probes while it's half-open, so that they never close it.
 //
 // See http://godoc.org/github.com/sony/gobreaker for more information.
-func Gobreaker(cb *gobreaker.CircuitBreaker) endpoint.Middleware {
+func Gobreaker(cb *gobreaker.CircuitBreaker, options ...Option) endpoint.Middleware {
+	o := newObserver(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			return cb.Execute(func() (interface{}, error) { return next(ctx, request) })
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			var (
+				probe   = cb.State() == gobreaker.StateHalfOpen
+				nextErr error // which isn't a failure
+			)
+			response, err := cb.Execute(func() (interface{}, error) {
+				response, err := next(ctx, request)
+				if o.outcome(ctx, err) == ignored && !probe {
+					nextErr, err = err, nil
+				}
+				return response, err
+			})
+			if err == nil {
+				err = nextErr
+			}
+			open := err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests
+			if open {
+				o.shortCircuit()
+			}
+			o.observe(func() State { return gobreakerState(cb.State()) })
+			return o.fallBack(ctx, request, response, err, open)
 		}
 	}
 }
+
+func gobreakerState(s gobreaker.State) State {
+	switch s {
+	case gobreaker.StateOpen:
+		return StateOpen
+	case gobreaker.StateHalfOpen:
+		return StateHalfOpen
+	default:
+		return StateClosed
+	}
+}
@@ -1,7 +1,10 @@
 package circuitbreaker_test
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/sony/gobreaker"
 
@@ -17,3 +20,31 @@ func TestGobreaker(t *testing.T) {
 	)
 	testFailingEndpoint(t, breaker, primeWith, shouldPass, 0, circuitOpenError)
 }
+
+func TestGobreakerHalfOpenCanceled(t *testing.T) {
+	var (
+		cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Timeout:     10 * time.Millisecond,
+			ReadyToTrip: func(gobreaker.Counts) bool { return true },
+		})
+		m = mock{err: errors.New("down")}
+	)
+	circuitbreaker.Gobreaker(cb)(m.endpoint)(context.Background(), struct{}{})
+	time.Sleep(10 * time.Millisecond)
+	if want, have := gobreaker.StateHalfOpen, cb.State(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+
+	// A probe whose client goes away doesn't close the circuit.
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := circuitbreaker.Gobreaker(cb)(func(context.Context, interface{}) (interface{}, error) {
+		cancel()
+		return nil, context.Canceled
+	})
+	if _, err := canceled(ctx, struct{}{}); err != context.Canceled {
+		t.Fatalf("want %v, have %v", context.Canceled, err)
+	}
+	if have := cb.State(); have == gobreaker.StateClosed {
+		t.Errorf("want the circuit not to be closed, have %s", have)
+	}
+}
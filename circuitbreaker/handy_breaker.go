@@ -11,28 +11,41 @@ import (
 
 // HandyBreaker returns an endpoint.Middleware that implements the circuit
 // breaker pattern using the streadway/handy/breaker package. Only errors
-// returned by the wrapped endpoint count against the circuit breaker's error
-// count.
+// returned by the wrapped endpoint which are failures, as FailureIf
+// classifies them, count against the circuit breaker's error count. Other
+// errors are reported as neither successes nor failures. Requests whose
+// context is done fail with its error, without counting.
 //
 // See http://godoc.org/github.com/streadway/handy/breaker for more
 // information.
-func HandyBreaker(cb breaker.Breaker) endpoint.Middleware {
+func HandyBreaker(cb breaker.Breaker, options ...Option) endpoint.Middleware {
+	o := newObserver(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
-		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			if !cb.Allow() {
-				return nil, breaker.ErrCircuitOpen
+				o.observe(openState)
+				o.shortCircuit()
+				return o.fallBack(ctx, request, nil, breaker.ErrCircuitOpen, true)
 			}
+			o.observe(closedState)
 
-			defer func(begin time.Time) {
-				if err == nil {
-					cb.Success(time.Since(begin))
-				} else {
-					cb.Failure(time.Since(begin))
-				}
-			}(time.Now())
-
-			response, err = next(ctx, request)
-			return
+			begin := time.Now()
+			response, err := next(ctx, request)
+			switch o.outcome(ctx, err) {
+			case success:
+				cb.Success(time.Since(begin))
+			case failure:
+				cb.Failure(time.Since(begin))
+			}
+			return o.fallBack(ctx, request, response, err, false)
 		}
 	}
 }
+
+// The states of a handy breaker are approximated by whether it allows
+// requests.
+func openState() State   { return StateOpen }
+func closedState() State { return StateClosed }
@@ -9,23 +9,56 @@ import (
 )
 
 // Hystrix returns an endpoint.Middleware that implements the circuit
-// breaker pattern using the afex/hystrix-go package.
+// breaker pattern using the afex/hystrix-go package. Only errors returned by
+// the wrapped endpoint which are failures, as FailureIf classifies them,
+// count against the circuit breaker's error count. Requests whose context is
+// done while they wait for the command fail with its error. As hystrix counts
+// every request, other errors count as successes while the circuit is
+// closed, but as failures of the single test requests allowed while it's
+// open, so that they never close it.
 //
 // When using this circuit breaker, please configure your commands separately.
 //
 // See https://godoc.org/github.com/afex/hystrix-go/hystrix for more
 // information.
-func Hystrix(commandName string) endpoint.Middleware {
+func Hystrix(commandName string, options ...Option) endpoint.Middleware {
+	o := newObserver(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-			var resp interface{}
-			if err := hystrix.Do(commandName, func() (err error) {
+			var (
+				resp    interface{}
+				nextErr error // which isn't a failure
+				probe   bool
+			)
+			if circuit, _, cerr := hystrix.GetCircuit(commandName); cerr == nil {
+				probe = circuit.IsOpen()
+			}
+			err = hystrix.DoC(ctx, commandName, func(ctx context.Context) (err error) {
 				resp, err = next(ctx, request)
+				if o.outcome(ctx, err) == ignored && !probe {
+					nextErr, err = err, nil
+				}
 				return err
-			}, nil); err != nil {
-				return nil, err
+			}, nil)
+			if err == nil {
+				err = nextErr
+			}
+			open := err == hystrix.ErrCircuitOpen
+			if open {
+				o.shortCircuit()
+			}
+			if circuit, _, cerr := hystrix.GetCircuit(commandName); cerr == nil {
+				o.observe(func() State {
+					if circuit.IsOpen() {
+						return StateOpen
+					}
+					return StateClosed
+				})
+			}
+			if err != nil {
+				resp = nil
 			}
-			return resp, nil
+			return o.fallBack(ctx, request, resp, err, open)
 		}
 	}
 }
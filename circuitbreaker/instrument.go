@@ -0,0 +1,122 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Option sets an optional parameter for the middlewares of circuit breakers.
+type Option func(*observer)
+
+// StateChange sets a function which is called with the previous and current
+// states of the circuit each time it changes, e.g. to log that it opened.
+// It's called synchronously with the request which observed the change, and
+// mustn't block.
+//
+// The states of breakers which don't expose them are approximated: the
+// circuit of a HandyBreaker is open while it disallows requests, and that of
+// a Hystrix command is never half-open. Changes are observed as requests are
+// made, e.g. an open circuit is found half-open by the first request after
+// its open duration elapsed.
+func StateChange(f func(from, to State)) Option {
+	return func(o *observer) { o.onChange = f }
+}
+
+// Metrics are the metrics of a circuit breaker, so that operations can alert
+// on open circuits. Metrics which are nil aren't reported.
+type Metrics struct {
+	// State is set to the state of the circuit: 0 while it's closed, 1
+	// while it's open, and 2 while it's half-open.
+	State metrics.Gauge
+
+	// Trips counts the times the circuit opened.
+	Trips metrics.Counter
+
+	// ShortCircuits counts the requests which failed fast, as the circuit
+	// was open.
+	ShortCircuits metrics.Counter
+
+	// FallbackSuccesses and FallbackFailures count the requests served by
+	// the fallback endpoint, by whether it succeeded.
+	FallbackSuccesses metrics.Counter
+	FallbackFailures  metrics.Counter
+}
+
+// Instrument reports the metrics of the circuit breaker.
+func Instrument(m Metrics) Option {
+	return func(o *observer) { o.metrics = m }
+}
+
+// observer classifies the errors of the requests made through the
+// middleware of a circuit breaker, and tracks the states of the circuit as
+// they observe them, and reports their changes.
+type observer struct {
+	onChange func(from, to State)
+	metrics  Metrics
+	failure  func(context.Context, error) bool
+	fallback endpoint.Endpoint
+
+	mtx   sync.Mutex
+	state State
+}
+
+func newObserver(options []Option) *observer {
+	o := &observer{onChange: func(State, State) {}, failure: DefaultFailure}
+	for _, option := range options {
+		option(o)
+	}
+	if o.metrics.State != nil {
+		o.metrics.State.Set(float64(StateClosed))
+	}
+	return o
+}
+
+// observe records the current state of the circuit. It's read while the
+// lock is held, so that concurrent requests observe the states in order.
+func (o *observer) observe(current func() State) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	state := current()
+	if state == o.state {
+		return
+	}
+	from := o.state
+	o.state = state
+	if o.metrics.State != nil {
+		o.metrics.State.Set(float64(state))
+	}
+	if state == StateOpen {
+		add(o.metrics.Trips)
+	}
+	o.onChange(from, state)
+}
+
+// shortCircuit records a request which failed fast, as the circuit was open.
+func (o *observer) shortCircuit() {
+	add(o.metrics.ShortCircuits)
+}
+
+// fallBack returns the result of the fallback endpoint for a request which
+// was short-circuited, as the circuit was open, or failed, or else the
+// result of the request.
+func (o *observer) fallBack(ctx context.Context, request, response interface{}, err error, open bool) (interface{}, error) {
+	if o.fallback == nil || err == nil || (!open && !o.failure(ctx, err)) {
+		return response, err
+	}
+	response, err = o.fallback(ctx, request)
+	if err != nil {
+		add(o.metrics.FallbackFailures)
+	} else {
+		add(o.metrics.FallbackSuccesses)
+	}
+	return response, err
+}
+
+func add(c metrics.Counter) {
+	if c != nil {
+		c.Add(1)
+	}
+}
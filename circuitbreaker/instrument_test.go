@@ -0,0 +1,59 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestInstrument(t *testing.T) {
+	var (
+		changes []string
+		m       = circuitbreaker.Metrics{
+			State:         generic.NewGauge("state"),
+			Trips:         generic.NewCounter("trips"),
+			ShortCircuits: generic.NewCounter("short_circuits"),
+		}
+		b = circuitbreaker.NewBreaker(
+			circuitbreaker.BreakerFailures(1),
+			circuitbreaker.BreakerOpenDuration(10*time.Millisecond),
+		)
+		breaker = circuitbreaker.Middleware(b,
+			circuitbreaker.StateChange(func(from, to circuitbreaker.State) {
+				changes = append(changes, from.String()+"->"+to.String())
+			}),
+			circuitbreaker.Instrument(m),
+		)
+		mock = mock{err: errors.New("down")}
+		e    = breaker(mock.endpoint)
+	)
+
+	e(context.Background(), struct{}{}) // trips
+	e(context.Background(), struct{}{}) // short-circuited
+	e(context.Background(), struct{}{}) // short-circuited
+	if want, have := float64(circuitbreaker.StateOpen), m.State.(*generic.Gauge).Value(); want != have {
+		t.Errorf("want state %v, have %v", want, have)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mock.err = nil
+	e(context.Background(), struct{}{}) // probe
+
+	if want, have := []string{"closed->open", "open->half-open", "half-open->closed"}, changes; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := float64(circuitbreaker.StateClosed), m.State.(*generic.Gauge).Value(); want != have {
+		t.Errorf("want state %v, have %v", want, have)
+	}
+	if want, have := 1.0, m.Trips.(*generic.Counter).Value(); want != have {
+		t.Errorf("want %v trips, have %v", want, have)
+	}
+	if want, have := 2.0, m.ShortCircuits.(*generic.Counter).Value(); want != have {
+		t.Errorf("want %v short circuits, have %v", want, have)
+	}
+}
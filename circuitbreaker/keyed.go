@@ -0,0 +1,75 @@
+package circuitbreaker
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// KeyFunc returns the key of the breaker of a request, e.g. the downstream
+// host or method, from the context or the request.
+type KeyFunc func(ctx context.Context, request interface{}) string
+
+// Keyed returns an endpoint.Middleware that keeps a separate circuit breaker
+// per key of the requests, so that the failures of one downstream host or
+// method don't open the circuit of the others. The breaker of a key is
+// created by newBreaker on its first request, e.g.
+//
+//	circuitbreaker.Keyed(key, 1000, func(string) endpoint.Middleware {
+//	    return circuitbreaker.Middleware(circuitbreaker.NewBreaker())
+//	})
+//
+// At most size breakers are kept; the breaker of the least recently used key
+// is evicted to make room for a new one, and is created anew if the key is
+// used again.
+func Keyed(key KeyFunc, size int, newBreaker func(key string) endpoint.Middleware) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		breakers := &keyedBreakers{
+			next:       next,
+			newBreaker: newBreaker,
+			size:       size,
+			lru:        list.New(),
+			elements:   map[string]*list.Element{},
+		}
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return breakers.get(key(ctx, request))(ctx, request)
+		}
+	}
+}
+
+type keyedBreakers struct {
+	next       endpoint.Endpoint
+	newBreaker func(string) endpoint.Middleware
+	size       int
+
+	mtx      sync.Mutex
+	lru      *list.List // of *keyedBreaker, most recently used first
+	elements map[string]*list.Element
+}
+
+type keyedBreaker struct {
+	key      string
+	endpoint endpoint.Endpoint
+}
+
+// get returns the endpoint wrapped by the breaker of the key, and marks the
+// key as most recently used.
+func (k *keyedBreakers) get(key string) endpoint.Endpoint {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	if e, ok := k.elements[key]; ok {
+		k.lru.MoveToFront(e)
+		return e.Value.(*keyedBreaker).endpoint
+	}
+	if k.lru.Len() >= k.size {
+		if e := k.lru.Back(); e != nil {
+			k.lru.Remove(e)
+			delete(k.elements, e.Value.(*keyedBreaker).key)
+		}
+	}
+	b := &keyedBreaker{key: key, endpoint: k.newBreaker(key)(k.next)}
+	k.elements[key] = k.lru.PushFront(b)
+	return b.endpoint
+}
@@ -0,0 +1,53 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestKeyed(t *testing.T) {
+	var (
+		created = map[string]int{}
+		key     = func(_ context.Context, request interface{}) string { return request.(string) }
+		breaker = circuitbreaker.Keyed(key, 2, func(key string) endpoint.Middleware {
+			created[key]++
+			return circuitbreaker.Middleware(circuitbreaker.NewBreaker(circuitbreaker.BreakerFailures(1)))
+		})
+		errDown = errors.New("down")
+		e       = breaker(func(_ context.Context, request interface{}) (interface{}, error) {
+			if request == "a" {
+				return nil, errDown
+			}
+			return struct{}{}, nil
+		})
+	)
+
+	// The failures of a don't open the circuit of b.
+	if _, err := e(context.Background(), "a"); err != errDown {
+		t.Fatalf("want %v, have %v", errDown, err)
+	}
+	if _, err := e(context.Background(), "a"); err != circuitbreaker.ErrCircuitOpen {
+		t.Fatalf("want %v, have %v", circuitbreaker.ErrCircuitOpen, err)
+	}
+	if _, err := e(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// c evicts the breaker of a, the least recently used key.
+	if _, err := e(context.Background(), "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e(context.Background(), "a"); err != errDown {
+		t.Fatalf("want %v, have %v", errDown, err)
+	}
+	if want, have := 2, created["a"]; want != have {
+		t.Errorf("want %d breakers of a, have %d", want, have)
+	}
+	if want, have := 1, created["b"]; want != have {
+		t.Errorf("want %d breaker of b, have %d", want, have)
+	}
+}
@@ -0,0 +1,89 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Registry names Breakers, so that their states are inspected and controlled
+// by operators with its Handler.
+type Registry struct {
+	mtx      sync.RWMutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{breakers: map[string]*Breaker{}}
+}
+
+// Register adds the Breaker to the Registry by name, replacing any Breaker
+// of the same name.
+func (r *Registry) Register(name string, b *Breaker) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.breakers[name] = b
+}
+
+// Deregister removes the Breaker of the name from the Registry.
+func (r *Registry) Deregister(name string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.breakers, name)
+}
+
+// Stats returns the statistics of the registered Breakers, by name.
+func (r *Registry) Stats() map[string]Stats {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	stats := make(map[string]Stats, len(r.breakers))
+	for name, b := range r.breakers {
+		stats[name] = b.Stats()
+	}
+	return stats
+}
+
+// Handler returns an administrative handler of the Registry. GET requests
+// are responded to with the Stats of the Breakers as a JSON object, by name.
+// POST requests with the form values name and action, which is one of open,
+// close, and reset, force the Breaker of the name open or closed, or reset
+// it, and are responded to with its Stats. The handler should be served only
+// to operators, e.g. on an internal port.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(w, r.Stats())
+		case http.MethodPost:
+			name := req.FormValue("name")
+			r.mtx.RLock()
+			b, ok := r.breakers[name]
+			r.mtx.RUnlock()
+			if !ok {
+				http.Error(w, "unknown breaker "+name, http.StatusNotFound)
+				return
+			}
+			switch action := req.FormValue("action"); action {
+			case "open":
+				b.ForceOpen()
+			case "close":
+				b.ForceClose()
+			case "reset":
+				b.Reset()
+			default:
+				http.Error(w, "unknown action "+action, http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, b.Stats())
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
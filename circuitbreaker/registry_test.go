@@ -0,0 +1,72 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/circuitbreaker"
+)
+
+func TestRegistry(t *testing.T) {
+	var (
+		b        = circuitbreaker.NewBreaker()
+		registry = circuitbreaker.NewRegistry()
+		server   = httptest.NewServer(registry.Handler())
+		m        = mock{}
+		e        = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+	defer server.Close()
+	registry.Register("users", b)
+	e(context.Background(), struct{}{})
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats map[string]struct {
+		State    string
+		Forced   bool
+		Requests int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := "closed", stats["users"].State; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 1, stats["users"].Requests; want != have {
+		t.Errorf("want %d requests, have %d", want, have)
+	}
+
+	force := func(name, action string, want int) {
+		t.Helper()
+		resp, err := http.PostForm(server.URL, url.Values{"name": {name}, "action": {action}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if have := resp.StatusCode; want != have {
+			t.Errorf("%s %s: want %d, have %d", action, name, want, have)
+		}
+	}
+	force("orders", "open", http.StatusNotFound)
+	force("users", "trip", http.StatusBadRequest)
+
+	force("users", "open", http.StatusOK)
+	if _, err := e(context.Background(), struct{}{}); err != circuitbreaker.ErrCircuitOpen {
+		t.Errorf("want %v, have %v", circuitbreaker.ErrCircuitOpen, err)
+	}
+	if s := b.Stats(); s.State != circuitbreaker.StateOpen || !s.Forced {
+		t.Errorf("want forced open, have %+v", s)
+	}
+
+	force("users", "reset", http.StatusOK)
+	if s := b.Stats(); s.State != circuitbreaker.StateClosed || s.Forced {
+		t.Errorf("want closed, have %+v", s)
+	}
+}
@@ -0,0 +1,112 @@
+package circuitbreaker
+
+import (
+	"time"
+)
+
+// BreakerFailureRate sets the circuit to open once the rate of failures of
+// the last size requests reaches rate, e.g. 0.5, instead of after a number of
+// consecutive failures, which behaves better when failures are interleaved
+// with successes. Set BreakerMinRequests, e.g. to size, so that a few
+// failures of the first requests don't open it.
+func BreakerFailureRate(rate float64, size int) BreakerOption {
+	return func(b *Breaker) {
+		b.rate = rate
+		b.window = &countWindow{results: make([]bool, size)}
+	}
+}
+
+// BreakerFailureRateOver is like BreakerFailureRate, but the rate is that of
+// the requests of the last period d, in 10 buckets of d/10.
+func BreakerFailureRateOver(rate float64, d time.Duration) BreakerOption {
+	width := d / 10
+	if width <= 0 {
+		width = 1
+	}
+	return func(b *Breaker) {
+		b.rate = rate
+		b.window = &timeWindow{width: width, buckets: make([]bucket, 10)}
+	}
+}
+
+// window is a sliding window of the results of requests.
+type window interface {
+	record(now time.Time, failed bool)
+	counts(now time.Time) (requests, failures int)
+	reset()
+}
+
+// countWindow is the window of the last requests.
+type countWindow struct {
+	results  []bool // ring of whether each request failed
+	next     int
+	n        int
+	failures int
+}
+
+func (w *countWindow) record(_ time.Time, failed bool) {
+	if len(w.results) == 0 {
+		return
+	}
+	if w.n == len(w.results) {
+		if w.results[w.next] {
+			w.failures--
+		}
+	} else {
+		w.n++
+	}
+	w.results[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.results)
+}
+
+func (w *countWindow) counts(time.Time) (int, int) {
+	return w.n, w.failures
+}
+
+func (w *countWindow) reset() {
+	w.next, w.n, w.failures = 0, 0, 0
+}
+
+// timeWindow is the window of the requests of the last period, in buckets.
+type timeWindow struct {
+	width   time.Duration // of each bucket
+	buckets []bucket
+}
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
+func (w *timeWindow) record(now time.Time, failed bool) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[int(start.UnixNano()/int64(w.width))%len(w.buckets)]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start} // expired
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *timeWindow) counts(now time.Time) (requests, failures int) {
+	d := w.width * time.Duration(len(w.buckets))
+	for _, b := range w.buckets {
+		if now.Sub(b.start) < d {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	return requests, failures
+}
+
+func (w *timeWindow) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
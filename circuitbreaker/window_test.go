@@ -0,0 +1,71 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/circuitbreaker"
+)
+
+func TestBreakerFailureRate(t *testing.T) {
+	var (
+		b = circuitbreaker.NewBreaker(
+			circuitbreaker.BreakerFailureRate(0.5, 4),
+			circuitbreaker.BreakerMinRequests(4),
+		)
+		m = mock{}
+		e = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+	request := func(failed bool, want circuitbreaker.State) {
+		t.Helper()
+		m.err = nil
+		if failed {
+			m.err = errors.New("down")
+		}
+		e(context.Background(), struct{}{})
+		if have := b.State(); want != have {
+			t.Fatalf("want %s, have %s", want, have)
+		}
+	}
+
+	// Failures interleaved with successes, which never fail consecutively,
+	// open the circuit once they're half of the window.
+	request(true, circuitbreaker.StateClosed)
+	request(false, circuitbreaker.StateClosed)
+	request(false, circuitbreaker.StateClosed)
+	request(false, circuitbreaker.StateClosed) // 1 of 4
+	request(true, circuitbreaker.StateClosed)  // 1 of 4, as the first slid out
+	request(false, circuitbreaker.StateClosed) // 1 of 4
+	request(true, circuitbreaker.StateOpen)    // 2 of 4
+}
+
+func TestBreakerFailureRateOver(t *testing.T) {
+	var (
+		b = circuitbreaker.NewBreaker(
+			circuitbreaker.BreakerFailureRateOver(0.5, 50*time.Millisecond),
+			circuitbreaker.BreakerMinRequests(3),
+		)
+		m = mock{err: errors.New("down")}
+		e = circuitbreaker.Middleware(b)(m.endpoint)
+	)
+	e(context.Background(), struct{}{})
+	e(context.Background(), struct{}{})
+
+	// The failures slide out of the window.
+	time.Sleep(60 * time.Millisecond)
+	m.err = nil
+	e(context.Background(), struct{}{})
+	e(context.Background(), struct{}{})
+	m.err = errors.New("down")
+	e(context.Background(), struct{}{})
+	if want, have := circuitbreaker.StateClosed, b.State(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+
+	e(context.Background(), struct{}{})
+	if want, have := circuitbreaker.StateOpen, b.State(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// kitImports are imported by every generated file.
+var kitImports = []importSpec{
+	{Path: "context"},
+	{Path: "fmt"},
+	{Path: "time"},
+	{Name: "stdopentracing", Path: "github.com/opentracing/opentracing-go"},
+	{Path: "github.com/go-kit/kit/endpoint"},
+	{Path: "github.com/go-kit/kit/log"},
+	{Path: "github.com/go-kit/kit/metrics"},
+	{Path: "github.com/go-kit/kit/tracing/opentracing"},
+}
+
+// generate returns the formatted source of the endpoint layer of the service.
+func generate(s *service) ([]byte, error) {
+	endpoints := "Endpoints"
+	if s.Name != "Service" {
+		endpoints = s.Name + "Endpoints"
+	}
+	imports := append([]importSpec(nil), kitImports...)
+	seen := map[importSpec]bool{}
+	for _, i := range imports {
+		seen[i] = true
+	}
+	for _, i := range s.Imports {
+		if !seen[i] {
+			imports = append(imports, i)
+			seen[i] = true
+		}
+	}
+
+	// Group the imports as the standard library, third party packages, and
+	// Go kit.
+	groups := make([][]importSpec, 3)
+	for _, i := range imports {
+		switch {
+		case strings.HasPrefix(i.Path, "github.com/go-kit/kit/"):
+			groups[2] = append(groups[2], i)
+		case strings.Contains(strings.SplitN(i.Path, "/", 2)[0], "."):
+			groups[1] = append(groups[1], i)
+		default:
+			groups[0] = append(groups[0], i)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, struct {
+		*service
+		Endpoints string
+		Imports   [][]importSpec
+	}{s, endpoints, groups}); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"params": func(fs []field) string {
+		ps := make([]string, len(fs))
+		for i, f := range fs {
+			ps[i] = f.Param()
+		}
+		return strings.Join(append([]string{"ctx context.Context"}, ps...), ", ")
+	},
+	"results": func(fs []field) string {
+		rs := make([]string, len(fs))
+		for i, f := range fs {
+			rs[i] = f.Type
+		}
+		return "(" + strings.Join(append(rs, "error"), ", ") + ")"
+	},
+}).Parse(`// Code generated by kitgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+{{range .}}
+	{{- if .Name}}{{.Name}} {{end}}"{{.Path}}"
+{{end}}
+{{- end}}
+)
+
+// {{.Endpoints}} collects the endpoints of a {{.Name}}, one per method.
+//
+// In a server, use Make{{.Endpoints}} to construct it with the standard
+// middlewares, and serve each endpoint with a transport. In a client,
+// construct each endpoint with a transport, and use the {{.Endpoints}} as a
+// {{.Name}}.
+type {{.Endpoints}} struct {
+{{- range .Methods}}
+	{{.Name}}Endpoint endpoint.Endpoint
+{{- end}}
+}
+
+// Make{{.Endpoints}} returns {{.Endpoints}} which invoke the service. Each
+// endpoint is traced with a span named after its method, and its invocations
+// are logged, and their durations observed, with a method label and a
+// success label, false for errors of the endpoint and of the response.
+func Make{{.Endpoints}}(s {{.Name}}, logger log.Logger, duration metrics.Histogram, tracer stdopentracing.Tracer) {{.Endpoints}} {
+	wrap := func(method string, next endpoint.Endpoint) endpoint.Endpoint {
+		next = opentracing.TraceServer(tracer, method)(next)
+		logger := log.With(logger, "method", method)
+		duration := duration.With("method", method)
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func(begin time.Time) {
+				failed := err
+				if f, ok := response.(endpoint.Failer); ok && failed == nil {
+					failed = f.Failed()
+				}
+				duration.With("success", fmt.Sprint(failed == nil)).Observe(time.Since(begin).Seconds())
+				logger.Log("error", failed, "took", time.Since(begin))
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+	return {{.Endpoints}}{
+{{- range .Methods}}
+		{{.Name}}Endpoint: wrap("{{.Name}}", Make{{.Name}}Endpoint(s)),
+{{- end}}
+	}
+}
+{{range $m := .Methods}}
+// {{.Name}} implements {{$.Name}}. Primarily useful in a client.
+func (e {{$.Endpoints}}) {{.Name}}({{params .Params}}) {{results .Results}} {
+	var resp {{.Name}}Response
+	response, err := e.{{.Name}}Endpoint(ctx, {{.Name}}Request{
+{{- range .Params}}
+		{{.Field}}: {{.Name}},
+{{- end}}
+	})
+	if err != nil {
+		return {{range .Results}}resp.{{.Field}}, {{end}}err
+	}
+	resp = response.({{.Name}}Response)
+	return {{range .Results}}resp.{{.Field}}, {{end}}resp.Err
+}
+
+// Make{{.Name}}Endpoint returns an endpoint that invokes {{.Name}} on the
+// service. Primarily useful in a server.
+func Make{{.Name}}Endpoint(s {{$.Name}}) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		{{if .Params}}req := request.({{.Name}}Request){{else}}_ = request.({{.Name}}Request){{end}}
+		{{range .Results}}{{.Name}}, {{end}}err := s.{{.Name}}(ctx{{range .Params}}, {{.Arg}}{{end}})
+		return {{.Name}}Response{
+{{- range .Results}}
+			{{.Field}}: {{.Name}},
+{{- end}}
+			Err: err,
+		}, nil
+	}
+}
+
+// {{.Name}}Request collects the parameters of {{$.Name}}.{{.Name}}.
+type {{.Name}}Request struct {{if .Params}}{
+{{- range .Params}}
+	{{.Field}} {{.Type}} ` + "`" + `json:"{{.JSON}}"` + "`" + `
+{{- end}}
+}{{else}}{}{{end}}
+
+// {{.Name}}Response collects the results of {{$.Name}}.{{.Name}}.
+type {{.Name}}Response struct {
+{{- range .Results}}
+	{{.Field}} {{.Type}} ` + "`" + `json:"{{.JSON}}"` + "`" + `
+{{- end}}
+	Err error ` + "`" + `json:"-"` + "`" + `
+}
+
+// Failed implements endpoint.Failer.
+func (r {{.Name}}Response) Failed() error { return r.Err }
+{{end}}`))
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testService = `package svc
+
+import (
+	"context"
+	"io"
+	stdtime "time"
+)
+
+type Service interface {
+	Sum(ctx context.Context, a, b int) (int, error)
+	Concat(ctx context.Context, a string, rest ...string) (s string, n int, err error)
+	Ping(context.Context) error
+	Copy(ctx context.Context, r io.Reader, request string, d stdtime.Duration) (int64, error)
+}
+
+type NoContext interface {
+	Sum(a, b int) (int, error)
+}
+
+type NoError interface {
+	Sum(ctx context.Context, a, b int) int
+}
+`
+
+func TestGenerate(t *testing.T) {
+	dir := writeService(t)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "service_endpoints.go")
+	if err := run(dir, "Service", filename); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), filename, src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`stdtime "time"`,
+		`"io"`,
+		"func MakeEndpoints(s Service, logger log.Logger, duration metrics.Histogram, tracer stdopentracing.Tracer) Endpoints {",
+		"func (e Endpoints) Sum(ctx context.Context, a int, b int) (int, error) {",
+		"func (e Endpoints) Concat(ctx context.Context, a string, rest ...string) (string, int, error) {",
+		"func (e Endpoints) Copy(ctx context.Context, r io.Reader, requestArg string, d stdtime.Duration) (int64, error) {",
+		"r0, r1, err := s.Concat(ctx, req.A, req.Rest...)",
+		"type PingRequest struct{}",
+		"Rest []string `json:\"rest\"`",
+		"func (r SumResponse) Failed() error { return r.Err }",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code doesn't contain %q", want)
+		}
+	}
+
+	// The output is skipped when parsing, so it may be regenerated.
+	if err := run(dir, "Service", filename); err != nil {
+		t.Errorf("regenerating: %v", err)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := writeService(t)
+	defer os.RemoveAll(dir)
+
+	for typeName, want := range map[string]string{
+		"NoContext": "Sum must take a context.Context first",
+		"NoError":   "Sum must return an error last",
+		"Missing":   "interface Missing not found",
+	} {
+		err := run(dir, typeName, filepath.Join(dir, "out.go"))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want error containing %q, have %v", typeName, want, err)
+		}
+	}
+}
+
+func writeService(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kitgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "service.go"), []byte(testService), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
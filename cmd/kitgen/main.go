@@ -0,0 +1,61 @@
+// Command kitgen generates the endpoint layer of a Go kit service from its
+// interface: request and response types for each method, an endpoint
+// constructor for each method, an Endpoints struct which collects them and
+// implements the interface for clients, and a constructor which wires every
+// endpoint with the standard logging, metrics, and tracing middlewares, as
+// the examples do by hand.
+//
+// Every method of the interface must take a context.Context first, and
+// return an error last. Annotate the file declaring the interface with
+//
+//	//go:generate kitgen -type Service
+//
+// and run go generate. The output is written to service_endpoints.go, by
+// default, in the same package. Errors returned by the service are carried in
+// the response types, which implement endpoint.Failer, so that transports
+// encode them as errors without middlewares such as circuit breakers counting
+// them as failures.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeName = flag.String("type", "Service", "name of the service interface")
+		dir      = flag.String("dir", ".", "directory of the package declaring the interface")
+		output   = flag.String("output", "", "output file name; default <type>_endpoints.go in the package directory")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: kitgen [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	filename := *output
+	if filename == "" {
+		filename = filepath.Join(*dir, strings.ToLower(*typeName)+"_endpoints.go")
+	}
+	if err := run(*dir, *typeName, filename); err != nil {
+		fmt.Fprintf(os.Stderr, "kitgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, typeName, filename string) error {
+	s, err := parseService(dir, typeName, filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	src, err := generate(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, src, 0644)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// service is the model of a service interface, from which code is generated.
+type service struct {
+	Package string
+	Name    string
+	Imports []importSpec // used by method signatures, other than context
+	Methods []method
+}
+
+type importSpec struct {
+	Name string // explicit name, if any
+	Path string
+}
+
+type method struct {
+	Name    string
+	Params  []field // excluding the context
+	Results []field // excluding the error
+}
+
+type field struct {
+	Name     string // in the method signature
+	Field    string // in the request or response type
+	JSON     string // name of the field in JSON
+	Type     string // of the field; a slice for variadic parameters
+	Variadic bool
+}
+
+// Arg returns the argument for the field in a call of the method, from the
+// request type.
+func (f field) Arg() string {
+	if f.Variadic {
+		return "req." + f.Field + "..."
+	}
+	return "req." + f.Field
+}
+
+// Param returns the declaration of the field in the parameters of the method.
+func (f field) Param() string {
+	if f.Variadic {
+		return f.Name + " ..." + strings.TrimPrefix(f.Type, "[]")
+	}
+	return f.Name + " " + f.Type
+}
+
+// parseService parses the Go files of the package in dir, except tests and
+// the file named skip, and returns the model of the interface named typeName.
+func parseService(dir, typeName, skip string) (*service, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != skip
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, file := range pkgs[name].Files {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if ts.Name.Name != typeName {
+						continue
+					}
+					it, ok := ts.Type.(*ast.InterfaceType)
+					if !ok {
+						return nil, fmt.Errorf("%s is not an interface", typeName)
+					}
+					return newService(fset, file, typeName, it)
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("interface %s not found in %s", typeName, dir)
+}
+
+func newService(fset *token.FileSet, file *ast.File, typeName string, it *ast.InterfaceType) (*service, error) {
+	s := &service{
+		Package: file.Name.Name,
+		Name:    typeName,
+	}
+	used := map[string]bool{}
+	for _, m := range it.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok || len(m.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded interfaces aren't supported", fset.Position(m.Pos()))
+		}
+		ast.Inspect(ft, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+		method, err := newMethod(fset, m.Names[0].Name, ft)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fset.Position(m.Pos()), err)
+		}
+		s.Methods = append(s.Methods, method)
+	}
+	for _, spec := range file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		var name string
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		local := name
+		if local == "" {
+			local = path.Base(p)
+		}
+		if used[local] && p != "context" {
+			s.Imports = append(s.Imports, importSpec{Name: name, Path: p})
+		}
+	}
+	return s, nil
+}
+
+func newMethod(fset *token.FileSet, name string, ft *ast.FuncType) (method, error) {
+	m := method{Name: name}
+	params := expand(fset, ft.Params)
+	if len(params) == 0 || params[0].Type != "context.Context" {
+		return m, fmt.Errorf("%s must take a context.Context first", name)
+	}
+	results := expand(fset, ft.Results)
+	if len(results) == 0 || results[len(results)-1].Type != "error" {
+		return m, fmt.Errorf("%s must return an error last", name)
+	}
+	m.Params, m.Results = params[1:], results[:len(results)-1]
+
+	for i := range m.Params {
+		p := &m.Params[i]
+		if p.Name == "" || p.Name == "_" {
+			p.Name = fmt.Sprintf("a%d", i)
+		}
+		p.Field, p.JSON = exported(p.Name), p.Name
+		if reserved[p.Name] {
+			p.Name += "Arg"
+		}
+	}
+	for i := range m.Results {
+		r := &m.Results[i]
+		switch {
+		case r.Name != "" && r.Name != "_":
+			r.Field, r.JSON = exported(r.Name), r.Name
+		case len(m.Results) == 1:
+			r.Field, r.JSON = "V", "v"
+		default:
+			r.Field, r.JSON = fmt.Sprintf("V%d", i), fmt.Sprintf("v%d", i)
+		}
+		if r.Field == "Err" {
+			r.Field = "ErrValue"
+		}
+		r.Name = fmt.Sprintf("r%d", i)
+	}
+	return m, nil
+}
+
+// reserved are the identifiers used by the generated client methods, which
+// parameters are renamed to avoid.
+var reserved = map[string]bool{
+	"ctx": true, "e": true, "err": true, "request": true, "response": true, "resp": true,
+}
+
+// expand returns a field per name of the field list, with its type.
+func expand(fset *token.FileSet, fl *ast.FieldList) []field {
+	if fl == nil {
+		return nil
+	}
+	var fields []field
+	for _, f := range fl.List {
+		var (
+			expr     = f.Type
+			variadic bool
+		)
+		if e, ok := expr.(*ast.Ellipsis); ok {
+			expr, variadic = e.Elt, true
+		}
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, expr)
+		typ := buf.String()
+		if variadic {
+			typ = "[]" + typ
+		}
+		if len(f.Names) == 0 {
+			fields = append(fields, field{Type: typ, Variadic: variadic})
+			continue
+		}
+		for _, n := range f.Names {
+			fields = append(fields, field{Name: n.Name, Type: typ, Variadic: variadic})
+		}
+	}
+	return fields
+}
+
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,121 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Baggage is a set of items which travel with a request across services, e.g.
+// routing hints and tenant IDs. The transports propagate it in the W3C
+// baggage format: package transport/http in the baggage header, and package
+// transport/grpc in the baggage metadata.
+type Baggage map[string]string
+
+type baggageKey struct{}
+
+// ContextWithBaggage returns a context carrying the baggage, replacing any
+// baggage of ctx. It's intended to be called from a transport's request
+// function.
+func ContextWithBaggage(ctx context.Context, b Baggage) context.Context {
+	return context.WithValue(ctx, baggageKey{}, b)
+}
+
+// ContextWithBaggageItem returns a context carrying the baggage of ctx, with
+// the item set.
+func ContextWithBaggageItem(ctx context.Context, key, value string) context.Context {
+	b := Baggage{key: value}
+	for k, v := range BaggageFromContext(ctx) {
+		if k != key {
+			b[k] = v
+		}
+	}
+	return ContextWithBaggage(ctx, b)
+}
+
+// BaggageFromContext returns the baggage of the context, which mustn't be
+// modified, or nil.
+func BaggageFromContext(ctx context.Context) Baggage {
+	b, _ := ctx.Value(baggageKey{}).(Baggage)
+	return b
+}
+
+// BaggageItem returns the item of the baggage of the context, if any.
+func BaggageItem(ctx context.Context, key string) (string, bool) {
+	v, ok := BaggageFromContext(ctx)[key]
+	return v, ok
+}
+
+// BaggageKeyvals returns the items of the keys of the baggage of the context
+// as alternating keys and values, e.g. for log.With, so that logs show them.
+// Missing items have empty values. Without keys, all the items are returned,
+// sorted by key.
+func BaggageKeyvals(ctx context.Context, keys ...string) []interface{} {
+	b := BaggageFromContext(ctx)
+	if len(keys) == 0 {
+		keys = b.keys()
+	}
+	keyvals := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		keyvals = append(keyvals, k, b[k])
+	}
+	return keyvals
+}
+
+// String returns the baggage in the W3C baggage format, with the items sorted
+// by key, and the values percent-encoded.
+func (b Baggage) String() string {
+	members := make([]string, 0, len(b))
+	for _, k := range b.keys() {
+		members = append(members, k+"="+escapeBaggage(b[k]))
+	}
+	return strings.Join(members, ",")
+}
+
+func (b Baggage) keys() []string {
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseBaggage parses baggage in the W3C baggage format. The properties of
+// items are ignored, and so are invalid items.
+func ParseBaggage(s string) Baggage {
+	b := Baggage{}
+	for _, member := range strings.Split(s, ",") {
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		i := strings.IndexByte(member, '=')
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(member[:i])
+		value, err := url.PathUnescape(strings.TrimSpace(member[i+1:]))
+		if key == "" || strings.ContainsAny(key, " \t\"(),/:;<=>?@[\\]{}") || err != nil {
+			continue
+		}
+		b[key] = value
+	}
+	return b
+}
+
+// escapeBaggage percent-encodes the characters which the W3C baggage format
+// doesn't allow in values, and percent signs.
+func escapeBaggage(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c > 0x20 && c < 0x7f && c != '"' && c != ',' && c != ';' && c != '\\' && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
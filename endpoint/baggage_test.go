@@ -0,0 +1,43 @@
+package endpoint_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestBaggage(t *testing.T) {
+	ctx := endpoint.ContextWithBaggageItem(context.Background(), "tenant", "acme")
+	ctx = endpoint.ContextWithBaggageItem(ctx, "route", "canary, v2")
+
+	if v, ok := endpoint.BaggageItem(ctx, "tenant"); !ok || v != "acme" {
+		t.Errorf("want acme, have %q (%v)", v, ok)
+	}
+	if _, ok := endpoint.BaggageItem(context.Background(), "tenant"); ok {
+		t.Error("want no item")
+	}
+
+	b := endpoint.BaggageFromContext(ctx)
+	if want, have := "route=canary%2C%20v2,tenant=acme", b.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := b, endpoint.ParseBaggage(b.String()); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	if want, have := []interface{}{"route", "canary, v2", "tenant", "acme"}, endpoint.BaggageKeyvals(ctx); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []interface{}{"tenant", "acme", "user", ""}, endpoint.BaggageKeyvals(ctx, "tenant", "user"); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestParseBaggage(t *testing.T) {
+	have := endpoint.ParseBaggage(" a = 1 ;prop=x, b=%41, invalid, c d=3, =4")
+	if want := (endpoint.Baggage{"a": "1", "b": "A"}); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
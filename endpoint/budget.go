@@ -0,0 +1,32 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+)
+
+// DeadlineBudget returns a middleware for client endpoints, which shortens the
+// deadline of the request context by the local budget, i.e. the time reserved
+// for this service to finish its own work once the outgoing call returns. So,
+// timeouts cascade correctly through services that call each other: each hop
+// gets what remains of its caller's deadline, less what the caller needs. If
+// the request context has no deadline, it's passed through unchanged. If no
+// time remains after the budget, the endpoint isn't invoked, and
+// context.DeadlineExceeded is returned.
+func DeadlineBudget(local time.Duration) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				return next(ctx, request)
+			}
+			deadline = deadline.Add(-local)
+			if !time.Now().Before(deadline) {
+				return nil, context.DeadlineExceeded
+			}
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
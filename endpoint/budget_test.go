@@ -0,0 +1,46 @@
+package endpoint_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestDeadlineBudget(t *testing.T) {
+	var (
+		remaining time.Duration
+		called    bool
+		e         = endpoint.DeadlineBudget(time.Second)(func(ctx context.Context, _ interface{}) (interface{}, error) {
+			called = true
+			if deadline, ok := ctx.Deadline(); ok {
+				remaining = time.Until(deadline)
+			}
+			return nil, nil
+		})
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	e(ctx, nil)
+	if remaining > 9*time.Second || remaining < 8*time.Second {
+		t.Errorf("want about 9s remaining, have %s", remaining)
+	}
+
+	remaining = 0
+	e(context.Background(), nil)
+	if want, have := time.Duration(0), remaining; want != have {
+		t.Errorf("no deadline: want %s, have %s", want, have)
+	}
+
+	called = false
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := e(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	if called {
+		t.Error("endpoint was called with an exhausted budget")
+	}
+}
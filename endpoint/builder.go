@@ -0,0 +1,141 @@
+package endpoint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChainBuilder composes a chain of named middlewares, like Chain, but allows
+// entries to be inserted relative to others by name, included conditionally,
+// or removed, and reports the resulting order. It's useful in large services,
+// where chains are assembled by several components. The zero value is an
+// empty chain.
+//
+//	var b endpoint.ChainBuilder
+//	b.Append("logging", loggingMiddleware)
+//	b.Append("ratelimit", rateLimitMiddleware)
+//	b.InsertBefore("ratelimit", "tracing", tracingMiddleware)
+//	b.AppendIf(debug, "dump", dumpMiddleware)
+//	chain, err := b.Build() // logging -> tracing -> ratelimit
+//
+// Methods record the first error, e.g. a reference to a missing name, which
+// is returned by Build.
+type ChainBuilder struct {
+	entries []namedMiddleware
+	err     error
+}
+
+type namedMiddleware struct {
+	name string
+	m    Middleware
+}
+
+// Append adds the middleware to the inside of the chain, i.e. after every
+// other entry.
+func (b *ChainBuilder) Append(name string, m Middleware) *ChainBuilder {
+	return b.insert(len(b.entries), name, m)
+}
+
+// AppendIf is like Append, but only adds the middleware if cond is true.
+func (b *ChainBuilder) AppendIf(cond bool, name string, m Middleware) *ChainBuilder {
+	if !cond {
+		return b
+	}
+	return b.Append(name, m)
+}
+
+// Prepend adds the middleware to the outside of the chain, i.e. before every
+// other entry.
+func (b *ChainBuilder) Prepend(name string, m Middleware) *ChainBuilder {
+	return b.insert(0, name, m)
+}
+
+// InsertBefore adds the middleware immediately before, i.e. outside of, the
+// entry named target.
+func (b *ChainBuilder) InsertBefore(target, name string, m Middleware) *ChainBuilder {
+	i, ok := b.index(target)
+	if !ok {
+		return b.fail(fmt.Errorf("endpoint: no middleware named %q to insert %q before", target, name))
+	}
+	return b.insert(i, name, m)
+}
+
+// InsertAfter adds the middleware immediately after, i.e. inside of, the entry
+// named target.
+func (b *ChainBuilder) InsertAfter(target, name string, m Middleware) *ChainBuilder {
+	i, ok := b.index(target)
+	if !ok {
+		return b.fail(fmt.Errorf("endpoint: no middleware named %q to insert %q after", target, name))
+	}
+	return b.insert(i+1, name, m)
+}
+
+// Remove removes the entry with the given name.
+func (b *ChainBuilder) Remove(name string) *ChainBuilder {
+	i, ok := b.index(name)
+	if !ok {
+		return b.fail(fmt.Errorf("endpoint: no middleware named %q to remove", name))
+	}
+	b.entries = append(b.entries[:i], b.entries[i+1:]...)
+	return b
+}
+
+// Names returns the names of the entries, from the outermost to the innermost,
+// i.e. in the order requests traverse them.
+func (b *ChainBuilder) Names() []string {
+	names := make([]string, len(b.entries))
+	for i, e := range b.entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// String returns the names of the entries joined by arrows, in the order
+// requests traverse them.
+func (b *ChainBuilder) String() string {
+	return strings.Join(b.Names(), " -> ")
+}
+
+// Build returns the chain as a single middleware, or the first error
+// encountered while building it. An empty chain returns endpoints unchanged.
+func (b *ChainBuilder) Build() (Middleware, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	entries := append([]namedMiddleware(nil), b.entries...)
+	return func(next Endpoint) Endpoint {
+		for i := len(entries) - 1; i >= 0; i-- { // reverse
+			next = entries[i].m(next)
+		}
+		return next
+	}, nil
+}
+
+func (b *ChainBuilder) insert(i int, name string, m Middleware) *ChainBuilder {
+	if _, ok := b.index(name); ok {
+		return b.fail(fmt.Errorf("endpoint: duplicate middleware name %q", name))
+	}
+	if m == nil {
+		return b.fail(fmt.Errorf("endpoint: nil middleware %q", name))
+	}
+	b.entries = append(b.entries, namedMiddleware{})
+	copy(b.entries[i+1:], b.entries[i:])
+	b.entries[i] = namedMiddleware{name: name, m: m}
+	return b
+}
+
+func (b *ChainBuilder) index(name string) (int, bool) {
+	for i, e := range b.entries {
+		if e.name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (b *ChainBuilder) fail(err error) *ChainBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
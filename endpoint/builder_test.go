@@ -0,0 +1,77 @@
+package endpoint_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestChainBuilder(t *testing.T) {
+	var (
+		trace []string
+		named = func(name string) endpoint.Middleware {
+			return func(next endpoint.Endpoint) endpoint.Endpoint {
+				return func(ctx context.Context, request interface{}) (interface{}, error) {
+					trace = append(trace, name)
+					return next(ctx, request)
+				}
+			}
+		}
+		b endpoint.ChainBuilder
+	)
+	b.Append("logging", named("logging")).
+		Append("ratelimit", named("ratelimit")).
+		InsertBefore("ratelimit", "tracing", named("tracing")).
+		InsertAfter("ratelimit", "retry", named("retry")).
+		Prepend("recover", named("recover")).
+		AppendIf(false, "debug", named("debug")).
+		AppendIf(true, "validate", named("validate")).
+		Remove("retry")
+
+	want := []string{"recover", "logging", "tracing", "ratelimit", "validate"}
+	if have := b.Names(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Names: want %v, have %v", want, have)
+	}
+	if want, have := "recover -> logging -> tracing -> ratelimit -> validate", b.String(); want != have {
+		t.Errorf("String: want %q, have %q", want, have)
+	}
+
+	chain, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain(endpoint.Nop)(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if have := trace; !reflect.DeepEqual(want, have) {
+		t.Errorf("traversal: want %v, have %v", want, have)
+	}
+}
+
+func TestChainBuilderErrors(t *testing.T) {
+	nop := func(next endpoint.Endpoint) endpoint.Endpoint { return next }
+	for _, tc := range []struct {
+		name  string
+		build func(b *endpoint.ChainBuilder)
+		want  string
+	}{
+		{"missing before", func(b *endpoint.ChainBuilder) { b.InsertBefore("x", "a", nop) }, `endpoint: no middleware named "x" to insert "a" before`},
+		{"missing after", func(b *endpoint.ChainBuilder) { b.InsertAfter("x", "a", nop) }, `endpoint: no middleware named "x" to insert "a" after`},
+		{"missing remove", func(b *endpoint.ChainBuilder) { b.Remove("x") }, `endpoint: no middleware named "x" to remove`},
+		{"duplicate", func(b *endpoint.ChainBuilder) { b.Append("a", nop).Append("a", nop) }, `endpoint: duplicate middleware name "a"`},
+		{"nil", func(b *endpoint.ChainBuilder) { b.Append("a", nil) }, `endpoint: nil middleware "a"`},
+	} {
+		var b endpoint.ChainBuilder
+		tc.build(&b)
+		_, err := b.Build()
+		if err == nil {
+			t.Errorf("%s: want error, have none", tc.name)
+			continue
+		}
+		if have := err.Error(); tc.want != have {
+			t.Errorf("%s: want %q, have %q", tc.name, tc.want, have)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/internal/singleflight"
+)
+
+// Coalesce returns a middleware which collapses concurrent requests with the
+// same key into a single invocation of the endpoint, whose response and error
+// are shared by all of them. This protects cacheable endpoints from thundering
+// herds, e.g. when a popular cache entry expires. Requests for which key
+// returns false are passed through. Each endpoint wrapped by the middleware
+// coalesces its own requests.
+//
+// The shared invocation uses the context of the first request; a later
+// request whose context is done gives up waiting with the context's error.
+// Responses are shared, not copied, so they should be treated as immutable.
+func Coalesce(key func(ctx context.Context, request interface{}) (string, bool)) Middleware {
+	return func(next Endpoint) Endpoint {
+		var calls singleflight.Group
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			k, ok := key(ctx, request)
+			if !ok {
+				return next(ctx, request)
+			}
+			return calls.Do(ctx, k, func() (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package endpoint_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestCoalesce(t *testing.T) {
+	var (
+		calls   int64
+		release = make(chan struct{})
+		key     = func(_ context.Context, request interface{}) (string, bool) {
+			s, ok := request.(string)
+			return s, ok
+		}
+	)
+	e := endpoint.Coalesce(key)(func(_ context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		if _, ok := request.(string); ok {
+			<-release
+		}
+		return fmt.Sprint(request), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		request := []string{"a", "b"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := e(context.Background(), request)
+			if err != nil || response != request {
+				t.Errorf("want %s, have %v, %v", request, response, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if want, have := int64(2), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+
+	// Requests without a key aren't coalesced.
+	e(context.Background(), 1)
+	e(context.Background(), 1)
+	if want, have := int64(4), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestCoalesceContextDone(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	e := endpoint.Coalesce(func(context.Context, interface{}) (string, bool) { return "k", true })(func(context.Context, interface{}) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	go e(context.Background(), nil)
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := e(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,148 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConcurrencyLimit is returned by the MaxConcurrent middleware when a
+// request is rejected because the limit of in-flight requests is reached. Its
+// kind is KindUnavailable, so transports report it as retryable, with 503
+// Service Unavailable or the gRPC code Unavailable.
+var ErrConcurrencyLimit error = Error{Kind: KindUnavailable, Err: errors.New("concurrency limit exceeded")}
+
+// ConcurrencyOption sets an optional parameter for the MaxConcurrent
+// middleware.
+type ConcurrencyOption func(*limiter)
+
+// ConcurrencyRejections sets a counter which is incremented for every rejected
+// request, including those which gave up waiting. A metrics.Counter may be
+// used.
+func ConcurrencyRejections(c interface {
+	Add(delta float64)
+}) ConcurrencyOption {
+	return func(l *limiter) { l.rejections = c }
+}
+
+// ConcurrencyQueue bounds the number of requests waiting for a slot, if
+// MaxConcurrent waits; requests beyond it are rejected immediately, rather
+// than queued past the point of usefulness. By default, the queue is
+// unbounded.
+func ConcurrencyQueue(n int) ConcurrencyOption {
+	return func(l *limiter) { l.maxQueued = int64(n) }
+}
+
+// ConcurrencyOverload sets the kind of the errors of rejected requests, e.g.
+// KindResourceExhausted to report them with 429 Too Many Requests, and a
+// hint of when to retry them, which transports relay: package transport/http
+// sets the Retry-After header, and package transport/grpc sends RetryInfo. A
+// zero retryAfter gives no hint. Rejected requests fail with an
+// OverloadError wrapping ErrConcurrencyLimit.
+func ConcurrencyOverload(kind Kind, retryAfter time.Duration) ConcurrencyOption {
+	return func(l *limiter) { l.overload = &OverloadError{kind: kind, retryAfter: retryAfter} }
+}
+
+// OverloadError is the error of requests rejected by MaxConcurrent with
+// ConcurrencyOverload. It wraps ErrConcurrencyLimit.
+type OverloadError struct {
+	kind       Kind
+	retryAfter time.Duration
+}
+
+// Error implements error.
+func (e *OverloadError) Error() string { return ErrConcurrencyLimit.Error() }
+
+// Unwrap returns ErrConcurrencyLimit.
+func (e *OverloadError) Unwrap() error { return ErrConcurrencyLimit }
+
+// Kind returns the kind of the error.
+func (e *OverloadError) Kind() Kind { return e.kind }
+
+// StatusCode returns the HTTP status code of the kind of the error.
+func (e *OverloadError) StatusCode() int { return e.kind.StatusCode() }
+
+// RetryAfter returns the hint of when the request may be retried.
+func (e *OverloadError) RetryAfter() time.Duration { return e.retryAfter }
+
+// MaxConcurrent returns a middleware which bounds the number of requests in
+// flight through the endpoint to n, protecting it and its downstreams from
+// overload, independent of any rate limit. If wait is false, requests beyond
+// the limit are rejected immediately with ErrConcurrencyLimit. If wait is true,
+// they're queued until a slot is available, or until their context is done,
+// in which case the context's error is returned, or until the queue bounded
+// by ConcurrencyQueue is full.
+//
+// The limit is shared by every endpoint wrapped by the returned middleware,
+// which acts as a bulkhead across them. It panics if n isn't positive, as a
+// limit of zero would reject, or block, every request.
+func MaxConcurrent(n int, wait bool, options ...ConcurrencyOption) Middleware {
+	if n < 1 {
+		panic("MaxConcurrent limit must be positive")
+	}
+	l := &limiter{
+		slots: make(chan struct{}, n),
+		wait:  wait,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := l.acquire(ctx); err != nil {
+				if l.rejections != nil {
+					l.rejections.Add(1)
+				}
+				return nil, err
+			}
+			defer l.release()
+			return next(ctx, request)
+		}
+	}
+}
+
+type limiter struct {
+	queued     int64 // atomic; first, for its alignment
+	maxQueued  int64
+	slots      chan struct{}
+	wait       bool
+	overload   *OverloadError
+	rejections interface {
+		Add(delta float64)
+	}
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if !l.wait {
+		return l.reject()
+	}
+	if l.maxQueued > 0 {
+		defer atomic.AddInt64(&l.queued, -1)
+		if atomic.AddInt64(&l.queued, 1) > l.maxQueued {
+			return l.reject()
+		}
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) reject() error {
+	if l.overload != nil {
+		return l.overload
+	}
+	return ErrConcurrencyLimit
+}
+
+func (l *limiter) release() {
+	<-l.slots
+}
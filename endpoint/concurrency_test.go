@@ -0,0 +1,139 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		wait    bool
+		wantErr error
+	}{
+		{"reject", false, endpoint.ErrConcurrencyLimit},
+		{"wait", true, context.DeadlineExceeded},
+	} {
+		var (
+			rejections = generic.NewCounter("rejections")
+			started    = make(chan struct{}, 2)
+			release    = make(chan struct{})
+			block      = func(context.Context, interface{}) (interface{}, error) {
+				started <- struct{}{}
+				<-release
+				return "ok", nil
+			}
+			e  = endpoint.MaxConcurrent(2, tc.wait, endpoint.ConcurrencyRejections(rejections))(block)
+			wg sync.WaitGroup
+		)
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e(context.Background(), nil)
+			}()
+			<-started
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if _, err := e(ctx, nil); err != tc.wantErr {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.wantErr, err)
+		}
+		cancel()
+		if want, have := 1.0, rejections.Value(); want != have {
+			t.Errorf("%s: want %f rejections, have %f", tc.name, want, have)
+		}
+
+		close(release)
+		wg.Wait()
+		if _, err := e(context.Background(), nil); err != nil {
+			t.Errorf("%s: after release: %v", tc.name, err)
+		}
+	}
+}
+
+func TestMaxConcurrentWaits(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		started = make(chan struct{})
+		block   = func(context.Context, interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return "first", nil
+		}
+		limit = endpoint.MaxConcurrent(1, true)
+		done  = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		limit(block)(context.Background(), nil)
+	}()
+	<-started
+
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	// A different endpoint wrapped by the same middleware shares the limit.
+	response, err := limit(endpoint.Nop)(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := struct{}{}, response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	<-done
+}
+
+func TestMaxConcurrentQueue(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		started = make(chan struct{}, 1)
+		block   = func(context.Context, interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-release
+			return "ok", nil
+		}
+		e = endpoint.MaxConcurrent(1, true,
+			endpoint.ConcurrencyQueue(1),
+			endpoint.ConcurrencyOverload(endpoint.KindUnavailable, time.Second),
+		)(block)
+		wg sync.WaitGroup
+	)
+	wg.Add(2)
+	go func() { defer wg.Done(); e(context.Background(), nil) }()
+	<-started
+	go func() { defer wg.Done(); e(context.Background(), nil) }() // queued
+	time.Sleep(10 * time.Millisecond)
+
+	// The queue is full, so the request is rejected immediately.
+	_, err := e(context.Background(), nil)
+	if !errors.Is(err, endpoint.ErrConcurrencyLimit) {
+		t.Fatalf("want %v, have %v", endpoint.ErrConcurrencyLimit, err)
+	}
+	if want, have := endpoint.KindUnavailable, endpoint.KindOf(err); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := time.Second, err.(*endpoint.OverloadError).RetryAfter(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestMaxConcurrentInvalidLimit(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: want panic", n)
+				}
+			}()
+			endpoint.MaxConcurrent(n, false)
+		}()
+	}
+}
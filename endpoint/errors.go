@@ -0,0 +1,191 @@
+package endpoint
+
+import (
+	"context"
+)
+
+// Failer may be implemented by response types which carry a business logic
+// error, rather than returning it from the endpoint, so that middlewares such
+// as circuit breakers don't count it as a failure of the service. The servers
+// of packages transport/http and transport/grpc encode a response whose
+// Failed method returns a non-nil error as that error.
+type Failer interface {
+	Failed() error
+}
+
+// Kind is a category of error, which transports map to their own status
+// codes. The kinds mirror the gRPC status codes of the same names.
+type Kind int
+
+// Kinds of error.
+const (
+	KindUnknown Kind = iota
+	KindInvalidArgument
+	KindNotFound
+	KindAlreadyExists
+	KindPermissionDenied
+	KindUnauthenticated
+	KindFailedPrecondition
+	KindResourceExhausted
+	KindCanceled
+	KindDeadlineExceeded
+	KindUnimplemented
+	KindUnavailable
+	KindInternal
+)
+
+var kindNames = map[Kind]string{
+	KindUnknown:            "unknown",
+	KindInvalidArgument:    "invalid argument",
+	KindNotFound:           "not found",
+	KindAlreadyExists:      "already exists",
+	KindPermissionDenied:   "permission denied",
+	KindUnauthenticated:    "unauthenticated",
+	KindFailedPrecondition: "failed precondition",
+	KindResourceExhausted:  "resource exhausted",
+	KindCanceled:           "canceled",
+	KindDeadlineExceeded:   "deadline exceeded",
+	KindUnimplemented:      "unimplemented",
+	KindUnavailable:        "unavailable",
+	KindInternal:           "internal",
+}
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	if s, ok := kindNames[k]; ok {
+		return s
+	}
+	return kindNames[KindUnknown]
+}
+
+// StatusCode returns the HTTP status code corresponding to the kind.
+func (k Kind) StatusCode() int {
+	switch k {
+	case KindInvalidArgument, KindFailedPrecondition:
+		return 400 // http.StatusBadRequest
+	case KindUnauthenticated:
+		return 401 // http.StatusUnauthorized
+	case KindPermissionDenied:
+		return 403 // http.StatusForbidden
+	case KindNotFound:
+		return 404 // http.StatusNotFound
+	case KindAlreadyExists:
+		return 409 // http.StatusConflict
+	case KindResourceExhausted:
+		return 429 // http.StatusTooManyRequests
+	case KindCanceled:
+		return 499 // client closed request, by convention
+	case KindUnimplemented:
+		return 501 // http.StatusNotImplemented
+	case KindUnavailable:
+		return 503 // http.StatusServiceUnavailable
+	case KindDeadlineExceeded:
+		return 504 // http.StatusGatewayTimeout
+	}
+	return 500 // http.StatusInternalServerError
+}
+
+// Error is an error of a known kind. It implements the StatusCoder interface
+// of package transport/http, so the default error encoder responds with the
+// status code of its kind, and package transport/grpc returns it with the
+// code of its kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// NewError returns an Error of the kind, wrapping err.
+func NewError(kind Kind, err error) error {
+	return Error{Kind: kind, Err: err}
+}
+
+// InvalidArgument returns an Error of KindInvalidArgument, wrapping err.
+func InvalidArgument(err error) error { return NewError(KindInvalidArgument, err) }
+
+// NotFound returns an Error of KindNotFound, wrapping err.
+func NotFound(err error) error { return NewError(KindNotFound, err) }
+
+// AlreadyExists returns an Error of KindAlreadyExists, wrapping err.
+func AlreadyExists(err error) error { return NewError(KindAlreadyExists, err) }
+
+// PermissionDenied returns an Error of KindPermissionDenied, wrapping err.
+func PermissionDenied(err error) error { return NewError(KindPermissionDenied, err) }
+
+// Unauthenticated returns an Error of KindUnauthenticated, wrapping err.
+func Unauthenticated(err error) error { return NewError(KindUnauthenticated, err) }
+
+// FailedPrecondition returns an Error of KindFailedPrecondition, wrapping err.
+func FailedPrecondition(err error) error { return NewError(KindFailedPrecondition, err) }
+
+// ResourceExhausted returns an Error of KindResourceExhausted, wrapping err.
+func ResourceExhausted(err error) error { return NewError(KindResourceExhausted, err) }
+
+// Unimplemented returns an Error of KindUnimplemented, wrapping err.
+func Unimplemented(err error) error { return NewError(KindUnimplemented, err) }
+
+// Unavailable returns an Error of KindUnavailable, wrapping err.
+func Unavailable(err error) error { return NewError(KindUnavailable, err) }
+
+// Internal returns an Error of KindInternal, wrapping err.
+func Internal(err error) error { return NewError(KindInternal, err) }
+
+// Error implements error.
+func (e Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.String()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status code of the kind of the error.
+func (e Error) StatusCode() int {
+	return e.Kind.StatusCode()
+}
+
+// KindOf returns the kind of the error. Errors of type Error, and those with
+// a Kind method, such as TimeoutError and ValidationError, report their own
+// kind. The context errors are KindCanceled and KindDeadlineExceeded. Other
+// errors are KindUnknown.
+func KindOf(err error) Kind {
+	switch err {
+	case nil:
+		return KindUnknown
+	case context.Canceled:
+		return KindCanceled
+	case context.DeadlineExceeded:
+		return KindDeadlineExceeded
+	}
+	switch e := err.(type) {
+	case Error:
+		return e.Kind
+	case interface {
+		Kind() Kind
+	}:
+		return e.Kind()
+	}
+	return KindUnknown
+}
+
+// Classify returns a middleware which wraps errors from the endpoint in an
+// Error of the kind returned by the classifier, e.g. to map sql.ErrNoRows to
+// KindNotFound. Errors whose kind is already known, and those classified as
+// KindUnknown, are returned unchanged.
+func Classify(classifier func(error) Kind) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if err == nil || KindOf(err) != KindUnknown {
+				return response, err
+			}
+			if kind := classifier(err); kind != KindUnknown {
+				err = NewError(kind, err)
+			}
+			return response, err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package endpoint_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestKindOf(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want endpoint.Kind
+	}{
+		{nil, endpoint.KindUnknown},
+		{errors.New("dang"), endpoint.KindUnknown},
+		{endpoint.NotFound(sql.ErrNoRows), endpoint.KindNotFound},
+		{endpoint.NewError(endpoint.KindUnavailable, nil), endpoint.KindUnavailable},
+		{endpoint.TimeoutError{Duration: time.Second}, endpoint.KindDeadlineExceeded},
+		{endpoint.ValidationError{}, endpoint.KindInvalidArgument},
+		{context.Canceled, endpoint.KindCanceled},
+		{context.DeadlineExceeded, endpoint.KindDeadlineExceeded},
+	} {
+		if want, have := tc.want, endpoint.KindOf(tc.err); want != have {
+			t.Errorf("%v: want %s, have %s", tc.err, want, have)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	err := endpoint.AlreadyExists(errors.New("user exists"))
+	if want, have := "user exists", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 409, err.(endpoint.Error).StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "unavailable", endpoint.NewError(endpoint.KindUnavailable, nil).Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !errors.Is(endpoint.NotFound(sql.ErrNoRows), sql.ErrNoRows) {
+		t.Error("want the wrapped error to match")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	var fail error
+	e := endpoint.Classify(func(err error) endpoint.Kind {
+		if err == sql.ErrNoRows {
+			return endpoint.KindNotFound
+		}
+		return endpoint.KindUnknown
+	})(func(context.Context, interface{}) (interface{}, error) { return nil, fail })
+
+	for _, tc := range []struct {
+		err  error
+		want endpoint.Kind
+	}{
+		{sql.ErrNoRows, endpoint.KindNotFound},
+		{errors.New("dang"), endpoint.KindUnknown},
+		{endpoint.PermissionDenied(sql.ErrNoRows), endpoint.KindPermissionDenied},
+	} {
+		fail = tc.err
+		_, err := e(context.Background(), nil)
+		if want, have := tc.want, endpoint.KindOf(err); want != have {
+			t.Errorf("%v: want %s, have %s", tc.err, want, have)
+		}
+	}
+
+	fail = nil
+	if _, err := e(context.Background(), nil); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+}
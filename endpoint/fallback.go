@@ -0,0 +1,20 @@
+package endpoint
+
+import (
+	"context"
+)
+
+// Fallback returns an endpoint which invokes primary, and if it fails with an
+// error for which shouldFallback returns true, invokes fallback with the same
+// request and returns its result instead. It's useful for serving cached or
+// default responses when the primary endpoint, or a circuit breaker wrapping
+// it, rejects the request. A nil shouldFallback falls back on every error.
+func Fallback(primary, fallback Endpoint, shouldFallback func(error) bool) Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := primary(ctx, request)
+		if err == nil || (shouldFallback != nil && !shouldFallback(err)) {
+			return response, err
+		}
+		return fallback(ctx, request)
+	}
+}
@@ -0,0 +1,41 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestFallback(t *testing.T) {
+	var (
+		errOpen   = errors.New("circuit open")
+		errFatal  = errors.New("fatal")
+		fallback  = func(context.Context, interface{}) (interface{}, error) { return "cached", nil }
+		returning = func(response interface{}, err error) endpoint.Endpoint {
+			return func(context.Context, interface{}) (interface{}, error) { return response, err }
+		}
+		isOpen = func(err error) bool { return err == errOpen }
+	)
+	for _, tc := range []struct {
+		name           string
+		primary        endpoint.Endpoint
+		shouldFallback func(error) bool
+		wantResponse   interface{}
+		wantErr        error
+	}{
+		{"success", returning("fresh", nil), isOpen, "fresh", nil},
+		{"fallback", returning(nil, errOpen), isOpen, "cached", nil},
+		{"no fallback", returning(nil, errFatal), isOpen, nil, errFatal},
+		{"nil predicate", returning(nil, errFatal), nil, "cached", nil},
+	} {
+		response, err := endpoint.Fallback(tc.primary, fallback, tc.shouldFallback)(context.Background(), nil)
+		if want, have := tc.wantErr, err; want != have {
+			t.Errorf("%s: want error %v, have %v", tc.name, want, have)
+		}
+		if want, have := tc.wantResponse, response; want != have {
+			t.Errorf("%s: want response %v, have %v", tc.name, want, have)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package endpoint
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Defaults for the Retry middleware.
+const (
+	DefaultRetryAttempts  = 3
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+)
+
+// RetryOption sets an optional parameter for the Retry middleware.
+type RetryOption func(*retrier)
+
+// RetryAttempts sets the maximum number of times the endpoint is invoked per
+// request, including the first. By default, DefaultRetryAttempts is used.
+func RetryAttempts(n int) RetryOption {
+	return func(r *retrier) { r.attempts = n }
+}
+
+// RetryBackoff sets the delay between attempts. Before the nth retry, the
+// middleware sleeps for a random duration between zero and base * 2^(n-1),
+// capped at max, i.e. exponential backoff with full jitter. By default,
+// DefaultRetryBaseDelay and DefaultRetryMaxDelay are used.
+func RetryBackoff(base, max time.Duration) RetryOption {
+	return func(r *retrier) { r.base, r.max = base, max }
+}
+
+// RetryIf sets the predicate which decides whether an error is retryable. By
+// default, all errors are retried, except those caused by the request context
+// being canceled or timing out.
+func RetryIf(retryable func(error) bool) RetryOption {
+	return func(r *retrier) { r.retryable = retryable }
+}
+
+// RetryWithBudget limits retries by the budget, which may be shared by many
+// endpoints. By default, retries are only limited per request by attempts.
+func RetryWithBudget(b *RetryBudget) RetryOption {
+	return func(r *retrier) { r.budget = b }
+}
+
+// Retry returns a middleware which reinvokes the endpoint when it returns a
+// retryable error, with exponential backoff between attempts. Unlike lb.Retry,
+// it wraps a single endpoint, and needs no balancer. If every attempt fails,
+// the error of the last attempt is returned. If the request context is done
+// while waiting to retry, its error is returned instead.
+func Retry(options ...RetryOption) Middleware {
+	r := &retrier{
+		attempts:  DefaultRetryAttempts,
+		base:      DefaultRetryBaseDelay,
+		max:       DefaultRetryMaxDelay,
+		retryable: func(err error) bool { return err != context.Canceled && err != context.DeadlineExceeded },
+		jitter:    rand.Int63n,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if r.budget != nil {
+				r.budget.Deposit()
+			}
+			for attempt := 1; ; attempt++ {
+				response, err := next(ctx, request)
+				if err == nil ||
+					attempt >= r.attempts ||
+					!r.retryable(err) ||
+					ctx.Err() != nil ||
+					(r.budget != nil && !r.budget.Withdraw()) {
+					return response, err
+				}
+				select {
+				case <-time.After(r.delay(attempt)):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+type retrier struct {
+	attempts  int
+	base, max time.Duration
+	retryable func(error) bool
+	budget    *RetryBudget
+	jitter    func(n int64) int64
+}
+
+// delay returns the time to wait after the given attempt.
+func (r *retrier) delay(attempt int) time.Duration {
+	d := r.max
+	if shift := uint(attempt - 1); shift < 63 && r.base < r.max>>shift {
+		d = r.base << shift
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(r.jitter(int64(d) + 1))
+}
+
+// RetryBudget limits the fraction of requests which may be retried, so that
+// retries don't multiply the load on a struggling service. Every request
+// deposits ratio tokens, and every retry withdraws one, when available. The
+// balance is capped at reserve, which also allows that many retries before any
+// requests have been made.
+type RetryBudget struct {
+	mtx     sync.Mutex
+	ratio   float64
+	reserve float64
+	balance float64
+}
+
+// NewRetryBudget returns a RetryBudget allowing retries of roughly ratio of
+// requests, e.g. 0.1 for 10%, with up to reserve retries in a burst.
+func NewRetryBudget(ratio float64, reserve int) *RetryBudget {
+	if reserve < 1 {
+		reserve = 1
+	}
+	return &RetryBudget{
+		ratio:   ratio,
+		reserve: float64(reserve),
+		balance: float64(reserve),
+	}
+}
+
+// Deposit records a request, adding ratio tokens to the balance. It's called
+// by the Retry middleware, and exported for retrying in other packages, such
+// as transport/http.
+func (b *RetryBudget) Deposit() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.balance += b.ratio
+	if b.balance > b.reserve {
+		b.balance = b.reserve
+	}
+}
+
+// Withdraw takes a token for a retry, returning false if none is available.
+func (b *RetryBudget) Withdraw() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.balance < 1 {
+		return false
+	}
+	b.balance--
+	return true
+}
@@ -0,0 +1,104 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failing returns an endpoint which fails the first n calls, and counts all of
+// them.
+func failing(n int, err error, calls *int) Endpoint {
+	return func(context.Context, interface{}) (interface{}, error) {
+		*calls++
+		if *calls <= n {
+			return nil, err
+		}
+		return "ok", nil
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var (
+		errFail  = errors.New("fail")
+		errFatal = errors.New("fatal")
+	)
+	for _, tc := range []struct {
+		name      string
+		failures  int
+		err       error
+		options   []RetryOption
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", 0, errFail, nil, 1, nil},
+		{"recovers", 2, errFail, nil, 3, nil},
+		{"exhausted", 3, errFail, nil, 3, errFail},
+		{"attempts", 4, errFail, []RetryOption{RetryAttempts(5)}, 5, nil},
+		{"not retryable", 1, errFatal, []RetryOption{RetryIf(func(err error) bool { return err != errFatal })}, 1, errFatal},
+		{"canceled", 1, context.Canceled, nil, 1, context.Canceled},
+	} {
+		var calls int
+		e := Retry(append([]RetryOption{RetryBackoff(0, 0)}, tc.options...)...)(failing(tc.failures, tc.err, &calls))
+		_, err := e(context.Background(), nil)
+		if want, have := tc.wantErr, err; want != have {
+			t.Errorf("%s: want error %v, have %v", tc.name, want, have)
+		}
+		if want, have := tc.wantCalls, calls; want != have {
+			t.Errorf("%s: want %d calls, have %d", tc.name, want, have)
+		}
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var calls int
+	e := Retry(RetryBackoff(time.Hour, time.Hour))(failing(1, errors.New("fail"), &calls))
+	if _, err := e(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	if want, have := 1, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	r := &retrier{
+		base:   100 * time.Millisecond,
+		max:    time.Second,
+		jitter: func(n int64) int64 { return n - 1 }, // the longest possible delay
+	}
+	for attempt, want := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		80: time.Second,
+	} {
+		if have := r.delay(attempt); want != have {
+			t.Errorf("attempt %d: want %s, have %s", attempt, want, have)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	var (
+		b     = NewRetryBudget(0.5, 2)
+		calls int
+		e     = Retry(RetryBackoff(0, 0), RetryAttempts(10), RetryWithBudget(b))(failing(100, errors.New("fail"), &calls))
+	)
+	// The reserve allows two retries.
+	e(context.Background(), nil)
+	if want, have := 3, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+	// Each request then earns half a retry.
+	calls = 0
+	e(context.Background(), nil)
+	e(context.Background(), nil)
+	if want, have := 3, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
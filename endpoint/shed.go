@@ -0,0 +1,179 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded is returned by the LoadShed middleware for shed requests. Its
+// kind is KindUnavailable, so transports report it as retryable, with 503
+// Service Unavailable or the gRPC code Unavailable.
+var ErrOverloaded error = Error{Kind: KindUnavailable, Err: errors.New("overloaded")}
+
+// Priority is the importance of a request, for load shedding. Higher
+// priorities are shed later.
+type Priority int
+
+// Priorities of requests. Requests without a priority are PriorityNormal.
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+type priorityKey struct{}
+
+// ContextWithPriority returns a context carrying the priority of the request.
+// It's intended to be called from a transport's request function, or by a
+// client for its own requests.
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by ContextWithPriority, or
+// PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// Pressure is a signal of the load on a service, compared by LoadShed against
+// the thresholds of each priority. Its scale depends on the signal.
+type Pressure interface {
+	Pressure() float64
+}
+
+// PressureFunc is an adapter to allow the use of an ordinary function, e.g.
+// reporting CPU utilization or queue depth, as a Pressure.
+type PressureFunc func() float64
+
+// Pressure implements Pressure.
+func (f PressureFunc) Pressure() float64 { return f() }
+
+// observer is implemented by pressures which are fed by the requests that
+// LoadShed admits.
+type observer interface {
+	begin()
+	end(time.Duration)
+}
+
+// InFlight is a Pressure measuring the number of requests in flight through
+// the LoadShed middlewares using it. The zero value is ready to use.
+type InFlight struct {
+	n int64
+}
+
+// Pressure implements Pressure.
+func (f *InFlight) Pressure() float64 { return float64(atomic.LoadInt64(&f.n)) }
+
+func (f *InFlight) begin()              { atomic.AddInt64(&f.n, 1) }
+func (f *InFlight) end(_ time.Duration) { atomic.AddInt64(&f.n, -1) }
+
+// LatencyEWMA is a Pressure measuring the exponentially weighted moving
+// average latency, in seconds, of requests through the LoadShed middlewares
+// using it.
+type LatencyEWMA struct {
+	alpha float64
+
+	mtx   sync.Mutex
+	value float64
+}
+
+// NewLatencyEWMA returns a LatencyEWMA giving weight alpha, 0.0 < alpha <=
+// 1.0, to each new latency. For example, 0.1 averages over roughly the last
+// 10 requests. Values outside that range are treated as 1.0, i.e. the
+// latest latency.
+func NewLatencyEWMA(alpha float64) *LatencyEWMA {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 1
+	}
+	return &LatencyEWMA{alpha: alpha}
+}
+
+// Pressure implements Pressure.
+func (l *LatencyEWMA) Pressure() float64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.value
+}
+
+func (l *LatencyEWMA) begin() {}
+
+func (l *LatencyEWMA) end(d time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.value += l.alpha * (d.Seconds() - l.value)
+}
+
+// ShedOption sets an optional parameter for the LoadShed middleware.
+type ShedOption func(*shedder)
+
+// ShedThreshold sets the pressure at or above which requests of the priority
+// are shed. Priorities without a threshold are never shed.
+func ShedThreshold(p Priority, pressure float64) ShedOption {
+	return func(s *shedder) { s.thresholds[p] = pressure }
+}
+
+// ShedRejections sets a counter which is incremented for every shed request.
+// A metrics.Counter may be used.
+func ShedRejections(c interface {
+	Add(delta float64)
+}) ShedOption {
+	return func(s *shedder) { s.rejections = c }
+}
+
+// LoadShed returns a middleware which rejects requests with ErrOverloaded
+// while the pressure is at or above the threshold of their priority, taken
+// from the context, so that low-priority work gives way to important work
+// under load. For example, with an InFlight pressure,
+//
+//	endpoint.LoadShed(&endpoint.InFlight{},
+//	    endpoint.ShedThreshold(endpoint.PriorityLow, 50),
+//	    endpoint.ShedThreshold(endpoint.PriorityNormal, 100),
+//	)
+//
+// sheds low priority requests beyond 50 in flight, and normal priority ones
+// beyond 100, but never high or critical priority ones. InFlight and
+// LatencyEWMA are fed by the requests the middleware admits; other pressures
+// are only consulted.
+func LoadShed(pressure Pressure, options ...ShedOption) Middleware {
+	s := &shedder{
+		pressure:   pressure,
+		thresholds: map[Priority]float64{},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	o, _ := pressure.(observer)
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if threshold, ok := s.thresholds[PriorityFromContext(ctx)]; ok && s.pressure.Pressure() >= threshold {
+				if s.rejections != nil {
+					s.rejections.Add(1)
+				}
+				return nil, ErrOverloaded
+			}
+			if o == nil {
+				return next(ctx, request)
+			}
+			o.begin()
+			begin := time.Now()
+			defer func() { o.end(time.Since(begin)) }()
+			return next(ctx, request)
+		}
+	}
+}
+
+type shedder struct {
+	pressure   Pressure
+	thresholds map[Priority]float64
+	rejections interface {
+		Add(delta float64)
+	}
+}
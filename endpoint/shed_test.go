@@ -0,0 +1,92 @@
+package endpoint_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestLoadShed(t *testing.T) {
+	var (
+		pressure float64
+		counter  = generic.NewCounter("shed")
+		e        = endpoint.LoadShed(endpoint.PressureFunc(func() float64 { return pressure }),
+			endpoint.ShedThreshold(endpoint.PriorityLow, 0.5),
+			endpoint.ShedThreshold(endpoint.PriorityNormal, 0.8),
+			endpoint.ShedRejections(counter),
+		)(endpoint.Nop)
+		low      = endpoint.ContextWithPriority(context.Background(), endpoint.PriorityLow)
+		normal   = context.Background()
+		critical = endpoint.ContextWithPriority(context.Background(), endpoint.PriorityCritical)
+	)
+	for _, tc := range []struct {
+		pressure float64
+		ctx      context.Context
+		want     error
+	}{
+		{0.4, low, nil},
+		{0.5, low, endpoint.ErrOverloaded},
+		{0.5, normal, nil},
+		{0.9, normal, endpoint.ErrOverloaded},
+		{100, critical, nil},
+	} {
+		pressure = tc.pressure
+		if _, err := e(tc.ctx, nil); err != tc.want {
+			t.Errorf("pressure %v, priority %v: want %v, have %v", tc.pressure, endpoint.PriorityFromContext(tc.ctx), tc.want, err)
+		}
+	}
+	if want, have := 2.0, counter.Value(); want != have {
+		t.Errorf("want %v rejections, have %v", want, have)
+	}
+	if want, have := endpoint.KindUnavailable, endpoint.KindOf(endpoint.ErrOverloaded); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestLoadShedInFlight(t *testing.T) {
+	var (
+		inFlight = &endpoint.InFlight{}
+		release  = make(chan struct{})
+		started  = make(chan struct{})
+		shed     = endpoint.LoadShed(inFlight, endpoint.ShedThreshold(endpoint.PriorityNormal, 1))
+		blocking = shed(func(context.Context, interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	)
+	go blocking(context.Background(), nil)
+	<-started
+	if want, have := 1.0, inFlight.Pressure(); want != have {
+		t.Errorf("want %v in flight, have %v", want, have)
+	}
+	if _, err := shed(endpoint.Nop)(context.Background(), nil); err != endpoint.ErrOverloaded {
+		t.Errorf("want %v, have %v", endpoint.ErrOverloaded, err)
+	}
+	close(release)
+	for inFlight.Pressure() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := shed(endpoint.Nop)(context.Background(), nil); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+}
+
+func TestLatencyEWMA(t *testing.T) {
+	var (
+		latency = endpoint.NewLatencyEWMA(0.5)
+		e       = endpoint.LoadShed(latency)(func(context.Context, interface{}) (interface{}, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		})
+	)
+	e(context.Background(), nil)
+	e(context.Background(), nil)
+	// 0.5 * 0.02 + 0.5 * (0.02 - 0.01) = 0.015 seconds, at least
+	if have := latency.Pressure(); have < 0.015 || have > 0.5 {
+		t.Errorf("want about 0.015, have %v", have)
+	}
+}
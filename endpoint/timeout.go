@@ -0,0 +1,89 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned by the Timeout middleware when the endpoint doesn't
+// complete before its deadline. It implements the StatusCoder interface of
+// package transport/http, so the default error encoder responds with 504
+// Gateway Timeout, and package transport/grpc returns it with code
+// DeadlineExceeded.
+type TimeoutError struct {
+	Duration time.Duration // the timeout which was exceeded
+}
+
+// Error implements error.
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("endpoint timed out after %s", e.Duration)
+}
+
+// StatusCode returns http.StatusGatewayTimeout.
+func (e TimeoutError) StatusCode() int {
+	return 504
+}
+
+// Kind returns KindDeadlineExceeded.
+func (e TimeoutError) Kind() Kind {
+	return KindDeadlineExceeded
+}
+
+type timeoutKey struct{}
+
+// ContextWithTimeout returns a context which overrides the timeout of any
+// Timeout middleware the request passes through. It's intended to be called
+// from a transport's request function, e.g. with a deadline propagated by the
+// client in a header.
+func ContextWithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// TimeoutFromContext returns the timeout set by ContextWithTimeout, if any.
+func TimeoutFromContext(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(timeoutKey{}).(time.Duration)
+	return d, ok
+}
+
+// Timeout returns a middleware which bounds the time the endpoint may take to
+// d, or the timeout set by ContextWithTimeout in the request context. The
+// endpoint is invoked with a context carrying the deadline. If the deadline
+// passes before the endpoint returns, the middleware returns a TimeoutError
+// immediately, and the endpoint's eventual result is discarded. If the request
+// context is canceled, its error is returned.
+func Timeout(d time.Duration) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			timeout := d
+			if override, ok := TimeoutFromContext(ctx); ok {
+				timeout = override
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			type result struct {
+				response interface{}
+				err      error
+			}
+			c := make(chan result, 1)
+			go func() {
+				response, err := next(ctx, request)
+				c <- result{response, err}
+			}()
+
+			select {
+			case r := <-c:
+				if r.err != nil && ctx.Err() == context.DeadlineExceeded {
+					return nil, TimeoutError{Duration: timeout}
+				}
+				return r.response, r.err
+			case <-ctx.Done():
+				if ctx.Err() == context.DeadlineExceeded {
+					return nil, TimeoutError{Duration: timeout}
+				}
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestTimeout(t *testing.T) {
+	block := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	_, err := endpoint.Timeout(time.Millisecond)(block)(context.Background(), nil)
+	if want, have := (endpoint.TimeoutError{Duration: time.Millisecond}), err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 504, err.(endpoint.TimeoutError).StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestTimeoutIgnored(t *testing.T) {
+	// The middleware returns at the deadline, even if the endpoint doesn't.
+	release := make(chan struct{})
+	defer close(release)
+	stuck := func(context.Context, interface{}) (interface{}, error) {
+		<-release
+		return "late", nil
+	}
+	if _, err := endpoint.Timeout(time.Millisecond)(stuck)(context.Background(), nil); err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+func TestTimeoutOverride(t *testing.T) {
+	var deadline time.Duration
+	e := endpoint.Timeout(time.Hour)(func(ctx context.Context, _ interface{}) (interface{}, error) {
+		d, _ := ctx.Deadline()
+		deadline = time.Until(d)
+		return "ok", nil
+	})
+	ctx := endpoint.ContextWithTimeout(context.Background(), time.Minute)
+	response, err := e(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "ok", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if deadline > time.Minute || deadline < 59*time.Second {
+		t.Errorf("want deadline about a minute away, have %s", deadline)
+	}
+}
+
+func TestTimeoutErrors(t *testing.T) {
+	errFail := errors.New("fail")
+	fail := func(context.Context, interface{}) (interface{}, error) { return nil, errFail }
+	if _, err := endpoint.Timeout(time.Hour)(fail)(context.Background(), nil); err != errFail {
+		t.Errorf("want %v, have %v", errFail, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	block := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if _, err := endpoint.Timeout(time.Hour)(block)(ctx, nil); err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+}
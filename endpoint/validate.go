@@ -0,0 +1,213 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Validator may be implemented by request types to check themselves before
+// they reach the endpoint. See Validation.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError describes why a single field of a request is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by the Validation middleware for invalid
+// requests. It implements the StatusCoder interface and json.Marshaler, so the
+// default error encoder of package transport/http responds with 400 Bad
+// Request and the field details as JSON. Package transport/grpc returns it
+// with code InvalidArgument.
+type ValidationError struct {
+	Message string       // overall description, if not specific to fields
+	Fields  []FieldError // per-field details, if known
+}
+
+// Error implements error.
+func (e ValidationError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = "invalid request"
+	}
+	if len(e.Fields) == 0 {
+		return msg
+	}
+	details := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		details[i] = f.Field + ": " + f.Message
+	}
+	return msg + ": " + strings.Join(details, "; ")
+}
+
+// StatusCode returns http.StatusBadRequest.
+func (e ValidationError) StatusCode() int {
+	return 400
+}
+
+// Kind returns KindInvalidArgument.
+func (e ValidationError) Kind() Kind {
+	return KindInvalidArgument
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e ValidationError) MarshalJSON() ([]byte, error) {
+	msg := e.Message
+	if msg == "" {
+		msg = "invalid request"
+	}
+	return json.Marshal(struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields,omitempty"`
+	}{msg, e.Fields})
+}
+
+// Validation returns a middleware which validates requests before invoking the
+// endpoint. Struct requests, or pointers to them, are checked against the
+// rules in their fields' validate tags, with ValidateStruct. Then, requests
+// implementing Validator are checked by their Validate method. If either
+// fails, a ValidationError is returned. Errors from Validate which aren't
+// ValidationErrors become the message of one.
+func Validation() Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := ValidateStruct(request); err != nil {
+				return nil, err
+			}
+			if v, ok := request.(Validator); ok {
+				if err := v.Validate(); err != nil {
+					if _, ok := err.(ValidationError); !ok {
+						err = ValidationError{Message: err.Error()}
+					}
+					return nil, err
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// ValidateStruct checks the exported fields of a struct, or pointer to one,
+// against the comma-separated rules in their validate tags, and returns a
+// ValidationError listing every field which fails. Fields are named by their
+// JSON names, if they have them. Other values are always valid. The rules are
+//
+//	required   the field mustn't be its zero value, or an empty slice or map
+//	min=N      numbers must be at least N; strings, slices, and maps must have at least N elements
+//	max=N      numbers must be at most N; strings, slices, and maps must have at most N elements
+//
+// For example, `validate:"required,max=64"`. Unknown rules are reported as
+// field errors, so that typos don't pass silently.
+func ValidateStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if j := strings.Split(sf.Tag.Get("json"), ",")[0]; j != "" && j != "-" {
+			name = j
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			if msg := check(rv.Field(i), strings.TrimSpace(rule)); msg != "" {
+				fields = append(fields, FieldError{Field: name, Message: msg})
+				break
+			}
+		}
+	}
+	if len(fields) > 0 {
+		return ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// check returns a description of how the value violates the rule, or the
+// empty string if it doesn't.
+func check(v reflect.Value, rule string) string {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+	switch name {
+	case "":
+		return ""
+	case "required":
+		if isZero(v) {
+			return "is required"
+		}
+		return ""
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid rule %q", rule)
+		}
+		n, isLen, ok := measure(v)
+		if !ok {
+			return ""
+		}
+		switch {
+		case name == "min" && n < limit && isLen:
+			return fmt.Sprintf("must have at least %s elements", arg)
+		case name == "min" && n < limit:
+			return fmt.Sprintf("must be at least %s", arg)
+		case name == "max" && n > limit && isLen:
+			return fmt.Sprintf("must have at most %s elements", arg)
+		case name == "max" && n > limit:
+			return fmt.Sprintf("must be at most %s", arg)
+		}
+		return ""
+	default:
+		return fmt.Sprintf("unknown rule %q", rule)
+	}
+}
+
+// measure returns the value of numbers, or the length of strings, slices, and
+// maps, indirecting through pointers. It returns false for nil pointers and
+// other kinds.
+func measure(v reflect.Value) (n float64, isLen bool, ok bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true, true
+	}
+	return 0, false, false
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		return v.IsNil() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0)
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,91 @@
+package endpoint_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type createRequest struct {
+	Name  string   `json:"name" validate:"required,max=8"`
+	Age   int      `json:"age" validate:"min=0,max=150"`
+	Tags  []string `validate:"max=2"`
+	Notes *string  `json:"notes,omitempty" validate:"min=1"`
+	Other string
+}
+
+func (r createRequest) Validate() error {
+	if r.Name == "root" {
+		return errors.New("reserved name")
+	}
+	return nil
+}
+
+func TestValidateStruct(t *testing.T) {
+	empty := ""
+	for _, tc := range []struct {
+		name    string
+		request interface{}
+		want    []endpoint.FieldError
+	}{
+		{"valid", createRequest{Name: "alice", Age: 30}, nil},
+		{"pointer", &createRequest{Name: "alice"}, nil},
+		{"not a struct", 42, nil},
+		{"invalid", createRequest{Age: -1, Tags: []string{"a", "b", "c"}, Notes: &empty}, []endpoint.FieldError{
+			{Field: "name", Message: "is required"},
+			{Field: "age", Message: "must be at least 0"},
+			{Field: "Tags", Message: "must have at most 2 elements"},
+			{Field: "notes", Message: "must have at least 1 elements"},
+		}},
+		{"too long", createRequest{Name: "bartholomew"}, []endpoint.FieldError{
+			{Field: "name", Message: "must have at most 8 elements"},
+		}},
+		{"unknown rule", struct {
+			A int `validate:"positive"`
+		}{}, []endpoint.FieldError{{Field: "A", Message: `unknown rule "positive"`}}},
+	} {
+		err := endpoint.ValidateStruct(tc.request)
+		if tc.want == nil {
+			if err != nil {
+				t.Errorf("%s: want no error, have %v", tc.name, err)
+			}
+			continue
+		}
+		verr, ok := err.(endpoint.ValidationError)
+		if !ok {
+			t.Errorf("%s: want ValidationError, have %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.want, verr.Fields) {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, verr.Fields)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	e := endpoint.Validation()(endpoint.Nop)
+	if _, err := e(context.Background(), createRequest{Name: "alice"}); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+
+	_, err := e(context.Background(), createRequest{Name: "root"})
+	if want, have := (endpoint.ValidationError{Message: "reserved name"}), err; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	_, err = e(context.Background(), createRequest{})
+	if want, have := "invalid request: name: is required", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 400, err.(endpoint.ValidationError).StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	buf, _ := json.Marshal(err)
+	if want, have := `{"error":"invalid request","fields":[{"field":"name","message":"is required"}]}`, string(buf); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
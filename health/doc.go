@@ -0,0 +1,18 @@
+// Package health provides health checks of services. Named checks of the
+// service's dependencies are registered with a Checker, which runs them with
+// timeouts, caches their results, and aggregates them into the service's
+// liveness and readiness.
+//
+// Liveness and readiness are exposed to probes by HTTP handlers, and to
+// service discovery by Watch, which deregisters the instance while it isn't
+// ready. Given the Health of package transport/grpc as the registrar, Watch
+// keeps the gRPC health service in line with the checks too.
+//
+//	checker := health.NewChecker()
+//	checker.Register("db", db.PingContext, health.Timeout(time.Second))
+//	checker.Register("drain", health.NotDraining(drainer), health.CacheTTL(0))
+//
+//	mux.Handle("/livez", checker.LivenessHandler())
+//	mux.Handle("/readyz", checker.ReadinessHandler())
+//	go checker.Watch(ctx, registrar, 5*time.Second, logger)
+package health
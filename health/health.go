@@ -0,0 +1,223 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for checks.
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultCacheTTL = time.Second
+)
+
+// ErrTimeout is the result of checks which don't complete within their
+// timeout.
+var ErrTimeout = errors.New("health check timed out")
+
+// Check checks the health of a dependency of the service, e.g. by pinging
+// its database, and returns nil if it's healthy. It should return once the
+// context is done.
+type Check func(ctx context.Context) error
+
+// CheckOption sets an optional parameter for checks.
+type CheckOption func(*check)
+
+// Timeout sets how long the check may run before it fails with ErrTimeout.
+// By default, it's DefaultTimeout.
+func Timeout(d time.Duration) CheckOption {
+	return func(c *check) { c.timeout = d }
+}
+
+// CacheTTL sets how long the result of the check is reused, so that frequent
+// probes don't overload the dependency. A ttl of zero disables caching. By
+// default, it's DefaultCacheTTL.
+func CacheTTL(d time.Duration) CheckOption {
+	return func(c *check) { c.ttl = d }
+}
+
+// Liveness makes the check count against liveness, as well as readiness.
+// It's for checks of the process itself, e.g. for deadlocks, whose failure
+// should restart it; failures of dependencies should only fail readiness.
+func Liveness() CheckOption {
+	return func(c *check) { c.liveness = true }
+}
+
+// Checker runs the named checks of a service, and aggregates their results
+// into its liveness and readiness. The service is live if all of the checks
+// registered with Liveness pass, and ready if all of the checks pass.
+type Checker struct {
+	mtx    sync.RWMutex
+	checks map[string]*check
+}
+
+// NewChecker returns a Checker without checks, which is live and ready.
+func NewChecker() *Checker {
+	return &Checker{checks: map[string]*check{}}
+}
+
+// Register registers the check with the name, replacing any check registered
+// with the same name.
+func (c *Checker) Register(name string, f Check, options ...CheckOption) {
+	ch := &check{name: name, f: f, timeout: DefaultTimeout, ttl: DefaultCacheTTL, now: time.Now}
+	for _, option := range options {
+		option(ch)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.checks[name] = ch
+}
+
+// Deregister removes the check with the name.
+func (c *Checker) Deregister(name string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.checks, name)
+}
+
+// Live runs the checks registered with Liveness, concurrently, and reports
+// their results.
+func (c *Checker) Live(ctx context.Context) Report {
+	return c.run(ctx, true)
+}
+
+// Ready runs all of the checks, concurrently, and reports their results.
+func (c *Checker) Ready(ctx context.Context) Report {
+	return c.run(ctx, false)
+}
+
+func (c *Checker) run(ctx context.Context, liveness bool) Report {
+	c.mtx.RLock()
+	var checks []*check
+	for _, ch := range c.checks {
+		if ch.liveness || !liveness {
+			checks = append(checks, ch)
+		}
+	}
+	c.mtx.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	var (
+		results = make([]Result, len(checks))
+		wg      sync.WaitGroup
+	)
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i] = ch.result(ctx)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	report := Report{Healthy: true, Results: results}
+	for _, r := range results {
+		if r.Err != nil {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// Report is the aggregated result of checks.
+type Report struct {
+	// Healthy is true if all of the checks passed.
+	Healthy bool
+
+	// Results are those of each check, by name.
+	Results []Result
+}
+
+// MarshalJSON implements json.Marshaler. A report is encoded as
+//
+//	{"status": "fail", "checks": {"db": {"status": "fail", "error": "..."}}}
+func (r Report) MarshalJSON() ([]byte, error) {
+	checks := make(map[string]Result, len(r.Results))
+	for _, result := range r.Results {
+		checks[result.Name] = result
+	}
+	return json.Marshal(struct {
+		Status string            `json:"status"`
+		Checks map[string]Result `json:"checks,omitempty"`
+	}{status(r.Healthy), checks})
+}
+
+// Result is the result of a check.
+type Result struct {
+	Name      string
+	Err       error // nil if the check passed
+	Duration  time.Duration
+	CheckedAt time.Time
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Result) MarshalJSON() ([]byte, error) {
+	var msg string
+	if r.Err != nil {
+		msg = r.Err.Error()
+	}
+	return json.Marshal(struct {
+		Status    string    `json:"status"`
+		Error     string    `json:"error,omitempty"`
+		Duration  string    `json:"duration"`
+		CheckedAt time.Time `json:"checked_at"`
+	}{status(r.Err == nil), msg, r.Duration.String(), r.CheckedAt})
+}
+
+func status(healthy bool) string {
+	if healthy {
+		return "pass"
+	}
+	return "fail"
+}
+
+type check struct {
+	name     string
+	f        Check
+	timeout  time.Duration
+	ttl      time.Duration
+	liveness bool
+	now      func() time.Time
+
+	mtx  sync.Mutex // serializes runs, so that concurrent probes share one
+	last *Result
+}
+
+// result returns the cached result of the check, or runs it.
+func (c *check) result(ctx context.Context) Result {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.last != nil && c.now().Sub(c.last.CheckedAt) < c.ttl {
+		return *c.last
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	begin := c.now()
+	errc := make(chan error, 1)
+	go func() { errc <- c.f(ctx) }()
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		// The check ignores the context; its result is abandoned.
+		err = ctx.Err()
+	}
+
+	r := Result{Name: c.name, Err: err, Duration: c.now().Sub(begin), CheckedAt: begin}
+	if perr := parent.Err(); perr != nil {
+		// The caller gave up, which says nothing of the check.
+		r.Err = perr
+		return r
+	}
+	if err == context.DeadlineExceeded {
+		r.Err = ErrTimeout
+	}
+	c.last = &r
+	return r
+}
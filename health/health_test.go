@@ -0,0 +1,143 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/health"
+	"github.com/go-kit/kit/log"
+)
+
+func TestChecker(t *testing.T) {
+	var (
+		checker = health.NewChecker()
+		dbErr   atomic.Value
+		calls   uint64
+	)
+	dbErr.Store(errors.New("connection refused"))
+	checker.Register("db", func(context.Context) error {
+		atomic.AddUint64(&calls, 1)
+		return dbErr.Load().(error)
+	}, health.CacheTTL(time.Hour))
+	checker.Register("deadlock", func(context.Context) error { return nil }, health.Liveness())
+	checker.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, health.Timeout(time.Millisecond))
+
+	if report := checker.Live(context.Background()); !report.Healthy || len(report.Results) != 1 {
+		t.Errorf("want live with 1 check, have %+v", report)
+	}
+
+	report := checker.Ready(context.Background())
+	if report.Healthy {
+		t.Error("want not ready")
+	}
+	if want, have := 3, len(report.Results); want != have {
+		t.Fatalf("want %d results, have %d", want, have)
+	}
+	for i, want := range []error{dbErr.Load().(error), nil, health.ErrTimeout} {
+		if have := report.Results[i].Err; want != have {
+			t.Errorf("%s: want %v, have %v", report.Results[i].Name, want, have)
+		}
+	}
+
+	// The result of db is cached.
+	checker.Ready(context.Background())
+	if want, have := uint64(1), atomic.LoadUint64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("db", func(context.Context) error { return errors.New("connection refused") })
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if want, have := http.StatusServiceUnavailable, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	var body struct {
+		Status string
+		Checks map[string]struct{ Status, Error string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "fail" || body.Checks["db"].Error != "connection refused" {
+		t.Errorf("unexpected body %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/livez", nil))
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+type registrar struct {
+	mtx    sync.Mutex
+	events []string
+}
+
+func (r *registrar) Register()   { r.add("register") }
+func (r *registrar) Deregister() { r.add("deregister") }
+
+func (r *registrar) add(event string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *registrar) len() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.events)
+}
+
+type drainer struct{ draining int32 }
+
+func (d *drainer) Draining() bool { return atomic.LoadInt32(&d.draining) == 1 }
+
+func TestWatch(t *testing.T) {
+	var (
+		checker = health.NewChecker()
+		r       = &registrar{}
+		d       = &drainer{}
+	)
+	checker.Register("drain", health.NotDraining(d), health.CacheTTL(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checker.Watch(ctx, r, time.Millisecond, log.NewNopLogger())
+	}()
+
+	waitFor := func(n int) {
+		deadline := time.Now().Add(time.Second)
+		for r.len() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("want %d events, have %d", n, r.len())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(1)
+	atomic.StoreInt32(&d.draining, 1)
+	waitFor(2)
+	cancel()
+	<-done
+
+	if want, have := []string{"register", "deregister"}, r.events; len(have) != 2 || want[0] != have[0] || want[1] != have[1] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// LivenessHandler returns a handler for liveness probes, which runs the
+// liveness checks, and responds with their Report as JSON, with 200 OK if
+// they pass, and 503 Service Unavailable if any fails. The errors of checks
+// are included, so the handler shouldn't be exposed publicly.
+func (c *Checker) LivenessHandler() http.Handler {
+	return reportHandler(c.Live)
+}
+
+// ReadinessHandler returns a handler for readiness probes, which runs all of
+// the checks, and responds as LivenessHandler does.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return reportHandler(c.Ready)
+}
+
+func reportHandler(run func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := run(r.Context())
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+// ErrDraining is the result of the check of NotDraining once the server is
+// draining.
+var ErrDraining = errors.New("server is draining")
+
+// Drainer is implemented by the graceful drain helpers of the transports,
+// such as the Drainer of package transport/http.
+type Drainer interface {
+	Draining() bool
+}
+
+// NotDraining returns a Check which fails with ErrDraining once the drainer
+// begins to drain, so that the service is no longer ready, and Watch
+// withdraws it from service discovery. It should be registered with
+// CacheTTL(0), so that the drain is noticed at once.
+func NotDraining(d Drainer) Check {
+	return func(context.Context) error {
+		if d.Draining() {
+			return ErrDraining
+		}
+		return nil
+	}
+}
+
+// Watch runs the readiness checks every interval, until the context is done,
+// and keeps the registration of the instance in line with them: the instance
+// is registered once the checks pass, and deregistered while any fails, so
+// that it's withdrawn from discovery. Changes are logged. Watch blocks; the
+// instance is left as it is when it returns, for the drain to deregister it.
+//
+// Registrars which report health themselves, such as the Health of package
+// transport/grpc, which runs the gRPC health service, are kept in line with
+// the checks too.
+func (c *Checker) Watch(ctx context.Context, r sd.Registrar, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	registered := false
+	for {
+		report := c.Ready(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case report.Healthy && !registered:
+			r.Register()
+			registered = true
+			logger.Log("health", "ready", "action", "register")
+		case !report.Healthy && registered:
+			r.Deregister()
+			registered = false
+			for _, result := range report.Results {
+				if result.Err != nil {
+					logger.Log("health", "not ready", "action", "deregister", "check", result.Name, "err", result.Err)
+				}
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
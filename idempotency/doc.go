@@ -0,0 +1,14 @@
+// Package idempotency provides an endpoint middleware which makes retries of
+// a request safe, for endpoints with side effects, such as payments. Clients
+// attach a unique key to each logical request, and retries with the same key
+// receive the stored response of the first, rather than repeating its effects.
+//
+// Keys travel in the context. The transport helpers move them to and from the
+// Idempotency-Key HTTP header and gRPC metadata.
+//
+//	store := cache.NewLRU(100000)
+//	e = idempotency.Middleware(store, 24*time.Hour, idempotency.RequireKey())(e)
+//	server := httptransport.NewServer(e, dec, enc,
+//	    httptransport.ServerBefore(idempotency.ToHTTPContext()),
+//	)
+package idempotency
@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/internal/singleflight"
+)
+
+type contextKey int
+
+const keyContextKey contextKey = 0
+
+// ErrKeyRequired is returned by the middleware for requests without a key, if
+// the RequireKey option is given.
+var ErrKeyRequired = errors.New("idempotency key required")
+
+// ContextWithKey returns a context carrying the idempotency key.
+func ContextWithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContextKey, key)
+}
+
+// KeyFromContext returns the idempotency key carried by the context, if any.
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyContextKey).(string)
+	return key, ok && key != ""
+}
+
+// Option sets an optional parameter for the middleware.
+type Option func(*idempotent)
+
+// RequireKey causes requests without a key to fail with ErrKeyRequired. By
+// default, they're passed to the endpoint, without protection from replays.
+func RequireKey() Option {
+	return func(i *idempotent) { i.required = true }
+}
+
+// StoreIf sets the predicate which decides whether a response is stored, to be
+// replayed. Requests whose responses aren't stored may be retried with the
+// same key. By default, every response without an error is stored.
+func StoreIf(f func(response interface{}, err error) bool) Option {
+	return func(i *idempotent) { i.storable = f }
+}
+
+// Middleware returns an endpoint.Middleware which invokes the endpoint at
+// most once per idempotency key. The response is stored under the key for
+// ttl, and returned to every later request with the same key, without
+// invoking the endpoint. Concurrent requests with the same key wait for the
+// first to finish, and share its result.
+//
+// Keys are taken as is, so a store shouldn't be shared by endpoints whose
+// clients may reuse keys between them. Concurrent duplicates are detected
+// within the process; if duplicates may reach several instances of a
+// service, route them by key, or use a store shared by the instances, which
+// narrows the window of concurrent execution to the duration of one request.
+func Middleware(store cache.Store, ttl time.Duration, options ...Option) endpoint.Middleware {
+	i := &idempotent{
+		store:    store,
+		ttl:      ttl,
+		storable: func(_ interface{}, err error) bool { return err == nil },
+	}
+	for _, option := range options {
+		option(i)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			key, ok := KeyFromContext(ctx)
+			if !ok {
+				if i.required {
+					return nil, ErrKeyRequired
+				}
+				return next(ctx, request)
+			}
+			if response, ok := i.store.Get(key); ok {
+				return response, nil
+			}
+			return i.calls.Do(ctx, key, func() (interface{}, error) {
+				// A request with the same key may have finished since the
+				// store was checked.
+				if response, ok := i.store.Get(key); ok {
+					return response, nil
+				}
+				response, err := next(ctx, request)
+				if i.storable(response, err) {
+					i.store.Set(key, response, i.ttl)
+				}
+				return response, err
+			})
+		}
+	}
+}
+
+type idempotent struct {
+	store    cache.Store
+	ttl      time.Duration
+	required bool
+	storable func(interface{}, error) bool
+
+	calls singleflight.Group
+}
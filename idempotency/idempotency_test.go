@@ -0,0 +1,115 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/cache"
+	"github.com/go-kit/kit/idempotency"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls int
+	e := idempotency.Middleware(cache.NewLRU(10), time.Minute)(func(_ context.Context, request interface{}) (interface{}, error) {
+		calls++
+		return calls, nil
+	})
+
+	ctx := idempotency.ContextWithKey(context.Background(), "abc")
+	for i := 0; i < 3; i++ {
+		response, err := e(ctx, "charge")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 1, response.(int); want != have {
+			t.Errorf("replay %d: want %d, have %d", i, want, have)
+		}
+	}
+
+	response, _ := e(idempotency.ContextWithKey(context.Background(), "def"), "charge")
+	if want, have := 2, response.(int); want != have {
+		t.Errorf("new key: want %d, have %d", want, have)
+	}
+
+	e(context.Background(), "charge")
+	e(context.Background(), "charge")
+	if want, have := 4, calls; want != have {
+		t.Errorf("without key: want %d calls, have %d", want, have)
+	}
+}
+
+func TestMiddlewareErrorsNotStored(t *testing.T) {
+	var (
+		calls int
+		fail  = errors.New("declined")
+	)
+	e := idempotency.Middleware(cache.NewLRU(10), time.Minute)(func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, fail
+		}
+		return "ok", nil
+	})
+
+	ctx := idempotency.ContextWithKey(context.Background(), "abc")
+	if _, err := e(ctx, nil); err != fail {
+		t.Errorf("want %v, have %v", fail, err)
+	}
+	for i := 0; i < 2; i++ {
+		if response, err := e(ctx, nil); err != nil || response != "ok" {
+			t.Errorf("want ok, have %v, %v", response, err)
+		}
+	}
+	if want, have := 2, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestMiddlewareRequireKey(t *testing.T) {
+	e := idempotency.Middleware(cache.NewLRU(10), time.Minute, idempotency.RequireKey())(func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if _, err := e(context.Background(), nil); err != idempotency.ErrKeyRequired {
+		t.Errorf("want %v, have %v", idempotency.ErrKeyRequired, err)
+	}
+	if _, err := e(idempotency.ContextWithKey(context.Background(), ""), nil); err != idempotency.ErrKeyRequired {
+		t.Errorf("empty key: want %v, have %v", idempotency.ErrKeyRequired, err)
+	}
+}
+
+func TestMiddlewareConcurrentDuplicates(t *testing.T) {
+	var (
+		calls   int64
+		release = make(chan struct{})
+	)
+	e := idempotency.Middleware(cache.NewLRU(10), time.Minute)(func(context.Context, interface{}) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return "ok", nil
+	})
+
+	var (
+		ctx = idempotency.ContextWithKey(context.Background(), "abc")
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if response, err := e(ctx, nil); err != nil || response != "ok" {
+				t.Errorf("want ok, have %v, %v", response, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if want, have := int64(1), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
@@ -0,0 +1,59 @@
+package idempotency
+
+import (
+	"context"
+	stdhttp "net/http"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/http"
+)
+
+// Header is the HTTP header carrying the idempotency key. In gRPC metadata,
+// the key is lowercase.
+const Header = "Idempotency-Key"
+
+// ToHTTPContext moves the idempotency key from the request header to the
+// context. Particularly useful for servers.
+func ToHTTPContext() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if key := r.Header.Get(Header); key != "" {
+			ctx = ContextWithKey(ctx, key)
+		}
+		return ctx
+	}
+}
+
+// FromHTTPContext moves the idempotency key from the context to the request
+// header. Particularly useful for clients.
+func FromHTTPContext() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if key, ok := KeyFromContext(ctx); ok {
+			r.Header.Set(Header, key)
+		}
+		return ctx
+	}
+}
+
+// ToGRPCContext moves the idempotency key from gRPC metadata to the context.
+// Particularly useful for servers.
+func ToGRPCContext() grpc.ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		if keys := md["idempotency-key"]; len(keys) > 0 && keys[0] != "" {
+			ctx = ContextWithKey(ctx, keys[0])
+		}
+		return ctx
+	}
+}
+
+// FromGRPCContext moves the idempotency key from the context to gRPC
+// metadata. Particularly useful for clients.
+func FromGRPCContext() grpc.ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if key, ok := KeyFromContext(ctx); ok {
+			(*md)["idempotency-key"] = []string{key}
+		}
+		return ctx
+	}
+}
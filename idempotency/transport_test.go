@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHTTPContext(t *testing.T) {
+	if _, ok := KeyFromContext(ToHTTPContext()(context.Background(), &http.Request{Header: http.Header{}})); ok {
+		t.Error("context shouldn't contain a key")
+	}
+
+	r := &http.Request{Header: http.Header{}}
+	FromHTTPContext()(ContextWithKey(context.Background(), "abc"), r)
+	if want, have := "abc", r.Header.Get(Header); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	key, _ := KeyFromContext(ToHTTPContext()(context.Background(), r))
+	if want, have := "abc", key; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestGRPCContext(t *testing.T) {
+	if _, ok := KeyFromContext(ToGRPCContext()(context.Background(), metadata.MD{})); ok {
+		t.Error("context shouldn't contain a key")
+	}
+
+	md := metadata.MD{}
+	FromGRPCContext()(ContextWithKey(context.Background(), "abc"), &md)
+	key, _ := KeyFromContext(ToGRPCContext()(context.Background(), md))
+	if want, have := "abc", key; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
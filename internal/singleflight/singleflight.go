@@ -0,0 +1,53 @@
+// Package singleflight collapses concurrent invocations of a function with
+// the same key into one, whose result is shared by all callers.
+package singleflight
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is a set of in-flight invocations, by key. The zero value is ready to
+// use.
+type Group struct {
+	mtx   sync.Mutex
+	calls map[string]*call
+}
+
+// call is an in-flight invocation, shared by concurrent callers.
+type call struct {
+	done     chan struct{}
+	response interface{}
+	err      error
+}
+
+// Do invokes f, unless an invocation for the same key is in flight, in which
+// case it waits for that result. A caller whose context is done gives up
+// waiting with the context's error; the invocation itself isn't affected.
+func (g *Group) Do(ctx context.Context, key string, f func() (interface{}, error)) (interface{}, error) {
+	g.mtx.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mtx.Unlock()
+		select {
+		case <-c.done:
+			return c.response, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mtx.Unlock()
+
+	defer func() {
+		g.mtx.Lock()
+		delete(g.calls, key)
+		g.mtx.Unlock()
+		close(c.done)
+	}()
+	c.response, c.err = f()
+	return c.response, c.err
+}
@@ -0,0 +1,151 @@
+// Package cardinality protects metrics backends from unbounded label values.
+//
+// Backends like Prometheus and DogStatsD create a new time series for every
+// distinct combination of label values. If label values are derived from user
+// input, e.g. a request path, the number of time series can grow without
+// bound. A Limiter caps the number of distinct values accepted for each label
+// key, and maps any further values to a fixed overflow value.
+//
+//	limiter := cardinality.NewLimiter(100)
+//	requests := limiter.Counter(prometheus.NewCounterFrom(...))
+//	requests.With("path", r.URL.Path).Add(1) // at most 101 distinct paths
+package cardinality
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// DefaultOverflowValue replaces label values beyond a Limiter's maximum.
+const DefaultOverflowValue = "other"
+
+// Limiter enforces a maximum number of distinct values per label key. The
+// first values seen for each key are passed through unchanged; subsequent new
+// values are replaced. A Limiter may wrap several metrics, in which case they
+// share the same set of accepted values.
+type Limiter struct {
+	max      int
+	overflow string
+	buckets  int
+
+	mtx  sync.Mutex
+	seen map[string]map[string]struct{}
+}
+
+// Option sets an optional parameter for a Limiter.
+type Option func(*Limiter)
+
+// OverflowValue sets the value which replaces label values beyond the maximum.
+// By default, DefaultOverflowValue is used.
+func OverflowValue(value string) Option {
+	return func(l *Limiter) { l.overflow = value }
+}
+
+// HashOverflow replaces label values beyond the maximum with one of n values,
+// chosen by hashing the original value, rather than a single overflow value.
+// This retains some of the distribution of the overflowing values while still
+// bounding cardinality. The replacements are the overflow value suffixed with
+// the bucket, e.g. "other_3".
+func HashOverflow(n int) Option {
+	return func(l *Limiter) { l.buckets = n }
+}
+
+// NewLimiter returns a Limiter which accepts at most max distinct values for
+// each label key.
+func NewLimiter(max int, options ...Option) *Limiter {
+	l := &Limiter{
+		max:      max,
+		overflow: DefaultOverflowValue,
+		seen:     map[string]map[string]struct{}{},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Counter wraps the counter so that its label values are limited.
+func (l *Limiter) Counter(c metrics.Counter) metrics.Counter {
+	return &counter{l: l, c: c}
+}
+
+// Gauge wraps the gauge so that its label values are limited.
+func (l *Limiter) Gauge(g metrics.Gauge) metrics.Gauge {
+	return &gauge{l: l, g: g}
+}
+
+// Histogram wraps the histogram so that its label values are limited.
+func (l *Limiter) Histogram(h metrics.Histogram) metrics.Histogram {
+	return &histogram{l: l, h: h}
+}
+
+// limit returns a copy of the label values with overflowing values replaced.
+func (l *Limiter) limit(labelValues []string) []string {
+	limited := make([]string, len(labelValues))
+	copy(limited, labelValues)
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for i := 1; i < len(limited); i += 2 {
+		limited[i] = l.value(limited[i-1], limited[i])
+	}
+	return limited
+}
+
+func (l *Limiter) value(key, value string) string {
+	values, ok := l.seen[key]
+	if !ok {
+		values = map[string]struct{}{}
+		l.seen[key] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) < l.max {
+		values[value] = struct{}{}
+		return value
+	}
+	if l.buckets <= 0 {
+		return l.overflow
+	}
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return l.overflow + "_" + strconv.Itoa(int(h.Sum32()%uint32(l.buckets)))
+}
+
+type counter struct {
+	l *Limiter
+	c metrics.Counter
+}
+
+func (c *counter) With(labelValues ...string) metrics.Counter {
+	return &counter{l: c.l, c: c.c.With(c.l.limit(labelValues)...)}
+}
+
+func (c *counter) Add(delta float64) { c.c.Add(delta) }
+
+type gauge struct {
+	l *Limiter
+	g metrics.Gauge
+}
+
+func (g *gauge) With(labelValues ...string) metrics.Gauge {
+	return &gauge{l: g.l, g: g.g.With(g.l.limit(labelValues)...)}
+}
+
+func (g *gauge) Set(value float64) { g.g.Set(value) }
+
+func (g *gauge) Add(delta float64) { g.g.Add(delta) }
+
+type histogram struct {
+	l *Limiter
+	h metrics.Histogram
+}
+
+func (h *histogram) With(labelValues ...string) metrics.Histogram {
+	return &histogram{l: h.l, h: h.h.With(h.l.limit(labelValues)...)}
+}
+
+func (h *histogram) Observe(value float64) { h.h.Observe(value) }
@@ -0,0 +1,65 @@
+package cardinality
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(2)
+	for _, tc := range []struct {
+		in, want []string
+	}{
+		{[]string{"path", "/a", "code", "200"}, []string{"path", "/a", "code", "200"}},
+		{[]string{"path", "/b", "code", "404"}, []string{"path", "/b", "code", "404"}},
+		{[]string{"path", "/c", "code", "200"}, []string{"path", "other", "code", "200"}},
+		{[]string{"path", "/a", "code", "500"}, []string{"path", "/a", "code", "other"}},
+		{[]string{"path", "/b"}, []string{"path", "/b"}},
+	} {
+		if have := l.limit(tc.in); !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("%v: want %v, have %v", tc.in, tc.want, have)
+		}
+	}
+}
+
+func TestHashOverflow(t *testing.T) {
+	l := NewLimiter(1, OverflowValue("x"), HashOverflow(4))
+	l.limit([]string{"k", "first"})
+
+	seen := map[string]bool{}
+	for _, v := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "a"} {
+		have := l.limit([]string{"k", v})[1]
+		if !strings.HasPrefix(have, "x_") {
+			t.Fatalf("%s: want overflow bucket, have %q", v, have)
+		}
+		seen[have] = true
+	}
+	if len(seen) > 4 {
+		t.Errorf("want at most 4 overflow values, have %v", seen)
+	}
+	if want, have := l.limit([]string{"k", "a"}), l.limit([]string{"k", "a"}); !reflect.DeepEqual(want, have) {
+		t.Errorf("hashing isn't stable: %v, %v", want, have)
+	}
+}
+
+func TestWrappers(t *testing.T) {
+	var (
+		l     = NewLimiter(1)
+		h     = generic.NewHistogram("h", 50)
+		lh    = l.Histogram(h)
+		other = lh.With("user", "alice").With("user", "bob").(*histogram).h.(*generic.Histogram)
+	)
+	if want, have := []string{"user", "alice", "user", "other"}, other.LabelValues(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	var _ metrics.Counter = l.Counter(generic.NewCounter("c")).With("user", "carol")
+	g := l.Gauge(generic.NewGauge("g")).With("user", "dave").(*gauge).g.(*generic.Gauge)
+	if want, have := []string{"user", "other"}, g.LabelValues(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
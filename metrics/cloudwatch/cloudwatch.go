@@ -16,8 +16,48 @@ import (
 
 const (
 	maxConcurrentRequests = 20
+	maxValuesPerDatum     = 150
 )
 
+// HistogramMode determines how histogram observations are reported.
+type HistogramMode int
+
+const (
+	// Percentiles reports each histogram as one metric per quantile, suffixed
+	// with _50, _90, _95, and _99. This is the default.
+	Percentiles HistogramMode = iota
+
+	// StatisticSets reports each histogram as a single datum carrying the
+	// sample count, sum, minimum, and maximum of the observations made since
+	// the last Send. CloudWatch computes averages from these, but can't
+	// compute percentiles.
+	StatisticSets
+
+	// ValuesCounts reports each histogram as datums carrying every distinct
+	// value observed since the last Send, along with how many times it was
+	// observed. CloudWatch computes percentiles from these. Each datum holds
+	// up to 150 distinct values, so observations should be coarse, e.g.
+	// whole milliseconds, to keep the number of datums down.
+	ValuesCounts
+)
+
+// Option sets an optional parameter for the CloudWatch object.
+type Option func(*CloudWatch)
+
+// HighResolution causes all metrics to be stored with a resolution of 1
+// second, rather than the default of 1 minute. For it to be useful, Send
+// should be called more often than once per minute. High-resolution metrics
+// are billed at a higher rate.
+func HighResolution() Option {
+	return func(cw *CloudWatch) { cw.storageResolution = aws.Int64(1) }
+}
+
+// WithHistogramMode sets how histograms created by the CloudWatch object are
+// reported. By default, they're reported as Percentiles.
+func WithHistogramMode(mode HistogramMode) Option {
+	return func(cw *CloudWatch) { cw.histogramMode = mode }
+}
+
 // CloudWatch receives metrics observations and forwards them to CloudWatch.
 // Create a CloudWatch object, use it to create metrics, and pass those metrics as
 // dependencies to the components that will use them.
@@ -33,6 +73,9 @@ type CloudWatch struct {
 	gauges                map[string]*gauge
 	histograms            map[string]*histogram
 	logger                log.Logger
+	storageResolution     *int64
+	histogramMode         HistogramMode
+	deltaCounters         bool
 }
 
 // New returns a CloudWatch object that may be used to create metrics.
@@ -41,39 +84,84 @@ type CloudWatch struct {
 // A good default value is 10 and the maximum is 20.
 // Callers must ensure that regular calls to Send are performed, either
 // manually or with one of the helper methods.
-func New(namespace string, svc cloudwatchiface.CloudWatchAPI, numConcurrent int, logger log.Logger) *CloudWatch {
+func New(namespace string, svc cloudwatchiface.CloudWatchAPI, numConcurrent int, logger log.Logger, options ...Option) *CloudWatch {
 	if numConcurrent > maxConcurrentRequests {
 		numConcurrent = maxConcurrentRequests
 	}
 
-	return &CloudWatch{
+	cw := &CloudWatch{
 		sem:                   make(chan struct{}, numConcurrent),
 		namespace:             namespace,
 		numConcurrentRequests: numConcurrent,
-		svc:        svc,
-		counters:   map[string]*counter{},
-		gauges:     map[string]*gauge{},
-		histograms: map[string]*histogram{},
-		logger:     logger,
+		svc:                   svc,
+		counters:              map[string]*counter{},
+		gauges:                map[string]*gauge{},
+		histograms:            map[string]*histogram{},
+		logger:                logger,
+	}
+	for _, option := range options {
+		option(cw)
+	}
+	return cw
+}
+
+// DeltaCounters causes all counters to report the sum of values added since
+// the previous Send, rather than the cumulative total since creation. It may
+// be overridden per counter with the Delta MetricOption.
+func DeltaCounters() Option {
+	return func(cw *CloudWatch) { cw.deltaCounters = true }
+}
+
+// MetricOption sets an optional parameter for a single metric.
+type MetricOption func(*metricOptions)
+
+type metricOptions struct {
+	unit  *string
+	delta *bool
+}
+
+// Unit sets the CloudWatch unit of the metric, e.g. cloudwatch.StandardUnitSeconds
+// or "Count". By default, no unit is sent, which CloudWatch treats as None.
+func Unit(unit string) MetricOption {
+	return func(o *metricOptions) { o.unit = aws.String(unit) }
+}
+
+// Delta sets whether the counter reports the sum of values added since the
+// previous Send, rather than the cumulative total since creation. It's ignored
+// by gauges and histograms. By default, the CloudWatch object's setting is
+// used; see DeltaCounters.
+func Delta(delta bool) MetricOption {
+	return func(o *metricOptions) { o.delta = &delta }
+}
+
+func newMetricOptions(options []MetricOption) metricOptions {
+	var o metricOptions
+	for _, option := range options {
+		option(&o)
 	}
+	return o
 }
 
 // NewCounter returns a counter. Observations are aggregated and emitted once
 // per write invocation.
-func (cw *CloudWatch) NewCounter(name string) metrics.Counter {
+func (cw *CloudWatch) NewCounter(name string, options ...MetricOption) metrics.Counter {
 	cw.mtx.Lock()
 	defer cw.mtx.Unlock()
-	c := &counter{c: generic.NewCounter(name)}
+	o := newMetricOptions(options)
+	c := &counter{c: generic.NewCounter(name), unit: o.unit, delta: cw.deltaCounters}
+	if o.delta != nil {
+		c.delta = *o.delta
+	}
 	cw.counters[name] = c
 	return c
 }
 
 // NewGauge returns a gauge. Observations are aggregated and emitted once per
 // write invocation.
-func (cw *CloudWatch) NewGauge(name string) metrics.Gauge {
+func (cw *CloudWatch) NewGauge(name string, options ...MetricOption) metrics.Gauge {
 	cw.mtx.Lock()
 	defer cw.mtx.Unlock()
-	g := &gauge{g: generic.NewGauge(name)}
+	g := &gauge{g: generic.NewGauge(name), unit: newMetricOptions(options).unit}
 	cw.gauges[name] = g
 	return g
 }
@@ -81,10 +169,10 @@ func (cw *CloudWatch) NewGauge(name string) metrics.Gauge {
 // NewHistogram returns a histogram. Observations are aggregated and emitted as
 // per-quantile gauges, once per write invocation. 50 is a good default value
 // for buckets.
-func (cw *CloudWatch) NewHistogram(name string, buckets int) metrics.Histogram {
+func (cw *CloudWatch) NewHistogram(name string, buckets int, options ...MetricOption) metrics.Histogram {
 	cw.mtx.Lock()
 	defer cw.mtx.Unlock()
-	h := &histogram{h: generic.NewHistogram(name, buckets)}
+	h := &histogram{h: generic.NewHistogram(name, buckets), mode: cw.histogramMode, unit: newMetricOptions(options).unit}
 	cw.histograms[name] = h
 	return h
 }
@@ -112,23 +200,65 @@ func (cw *CloudWatch) Send() error {
 
 	for name, c := range cw.counters {
 		datums = append(datums, &cloudwatch.MetricDatum{
-			MetricName: aws.String(name),
-			Dimensions: makeDimensions(c.c.LabelValues()...),
-			Value:      aws.Float64(c.c.Value()),
-			Timestamp:  aws.Time(now),
+			MetricName:        aws.String(name),
+			Dimensions:        makeDimensions(c.c.LabelValues()...),
+			Value:             aws.Float64(c.value()),
+			Unit:              c.unit,
+			Timestamp:         aws.Time(now),
+			StorageResolution: cw.storageResolution,
 		})
 	}
 
 	for name, g := range cw.gauges {
 		datums = append(datums, &cloudwatch.MetricDatum{
-			MetricName: aws.String(name),
-			Dimensions: makeDimensions(g.g.LabelValues()...),
-			Value:      aws.Float64(g.g.Value()),
-			Timestamp:  aws.Time(now),
+			MetricName:        aws.String(name),
+			Dimensions:        makeDimensions(g.g.LabelValues()...),
+			Value:             aws.Float64(g.g.Value()),
+			Unit:              g.unit,
+			Timestamp:         aws.Time(now),
+			StorageResolution: cw.storageResolution,
 		})
 	}
 
 	for name, h := range cw.histograms {
+		switch h.mode {
+		case StatisticSets:
+			if w := h.reset(); w.count > 0 {
+				datums = append(datums, &cloudwatch.MetricDatum{
+					MetricName: aws.String(name),
+					Dimensions: makeDimensions(h.h.LabelValues()...),
+					StatisticValues: &cloudwatch.StatisticSet{
+						SampleCount: aws.Float64(w.count),
+						Sum:         aws.Float64(w.sum),
+						Minimum:     aws.Float64(w.min),
+						Maximum:     aws.Float64(w.max),
+					},
+					Unit:              h.unit,
+					Timestamp:         aws.Time(now),
+					StorageResolution: cw.storageResolution,
+				})
+			}
+			continue
+		case ValuesCounts:
+			w := h.reset()
+			for len(w.values) > 0 {
+				lim := min(len(w.values), maxValuesPerDatum)
+				datum := &cloudwatch.MetricDatum{
+					MetricName:        aws.String(name),
+					Dimensions:        makeDimensions(h.h.LabelValues()...),
+					Unit:              h.unit,
+					Timestamp:         aws.Time(now),
+					StorageResolution: cw.storageResolution,
+				}
+				for _, value := range w.values[:lim] {
+					datum.Values = append(datum.Values, aws.Float64(value))
+					datum.Counts = append(datum.Counts, aws.Float64(w.counts[value]))
+				}
+				w.values = w.values[lim:]
+				datums = append(datums, datum)
+			}
+			continue
+		}
 		for _, p := range []struct {
 			s string
 			f float64
@@ -139,10 +269,12 @@ func (cw *CloudWatch) Send() error {
 			{"99", 0.99},
 		} {
 			datums = append(datums, &cloudwatch.MetricDatum{
-				MetricName: aws.String(fmt.Sprintf("%s_%s", name, p.s)),
-				Dimensions: makeDimensions(h.h.LabelValues()...),
-				Value:      aws.Float64(h.h.Quantile(p.f)),
-				Timestamp:  aws.Time(now),
+				MetricName:        aws.String(fmt.Sprintf("%s_%s", name, p.s)),
+				Dimensions:        makeDimensions(h.h.LabelValues()...),
+				Value:             aws.Float64(h.h.Quantile(p.f)),
+				Unit:              h.unit,
+				Timestamp:         aws.Time(now),
+				StorageResolution: cw.storageResolution,
 			})
 		}
 	}
@@ -188,7 +320,9 @@ func min(a, b int) int {
 
 // counter is a CloudWatch counter metric.
 type counter struct {
-	c *generic.Counter
+	c     *generic.Counter
+	unit  *string
+	delta bool
 }
 
 // With implements counter
@@ -202,9 +336,18 @@ func (c *counter) Add(delta float64) {
 	c.c.Add(delta)
 }
 
+// value returns the value to report, resetting the counter in delta mode.
+func (c *counter) value() float64 {
+	if c.delta {
+		return c.c.ValueReset()
+	}
+	return c.c.Value()
+}
+
 // gauge is a CloudWatch gauge metric.
 type gauge struct {
-	g *generic.Gauge
+	g    *generic.Gauge
+	unit *string
 }
 
 // With implements gauge
@@ -225,7 +368,11 @@ func (g *gauge) Add(delta float64) {
 
 // histogram is a CloudWatch histogram metric
 type histogram struct {
-	h *generic.Histogram
+	h    *generic.Histogram
+	mode HistogramMode
+	unit *string
+	mtx  sync.Mutex
+	w    window
 }
 
 // With implements histogram
@@ -236,7 +383,47 @@ func (h *histogram) With(labelValues ...string) metrics.Histogram {
 
 // Observe implements histogram
 func (h *histogram) Observe(value float64) {
-	h.h.Observe(value)
+	if h.mode == Percentiles {
+		h.h.Observe(value)
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.w.observe(value)
+}
+
+// reset returns the observations made since the last reset.
+func (h *histogram) reset() window {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	w := h.w
+	h.w = window{}
+	return w
+}
+
+// window aggregates the histogram observations made between two Sends.
+type window struct {
+	count, sum, min, max float64
+	values               []float64 // distinct, in order of first observation
+	counts               map[float64]float64
+}
+
+func (w *window) observe(value float64) {
+	if w.count == 0 || value < w.min {
+		w.min = value
+	}
+	if w.count == 0 || value > w.max {
+		w.max = value
+	}
+	w.count++
+	w.sum += value
+	if w.counts == nil {
+		w.counts = map[float64]float64{}
+	}
+	if _, ok := w.counts[value]; !ok {
+		w.values = append(w.values, value)
+	}
+	w.counts[value]++
 }
 
 func makeDimensions(labelValues ...string) []*cloudwatch.Dimension {
@@ -20,12 +20,14 @@ type mockCloudWatch struct {
 	mtx                sync.RWMutex
 	valuesReceived     map[string]float64
 	dimensionsReceived map[string][]*cloudwatch.Dimension
+	datumsReceived     map[string][]*cloudwatch.MetricDatum
 }
 
 func newMockCloudWatch() *mockCloudWatch {
 	return &mockCloudWatch{
 		valuesReceived:     map[string]float64{},
 		dimensionsReceived: map[string][]*cloudwatch.Dimension{},
+		datumsReceived:     map[string][]*cloudwatch.MetricDatum{},
 	}
 }
 
@@ -33,8 +35,11 @@ func (mcw *mockCloudWatch) PutMetricData(input *cloudwatch.PutMetricDataInput) (
 	mcw.mtx.Lock()
 	defer mcw.mtx.Unlock()
 	for _, datum := range input.MetricData {
-		mcw.valuesReceived[*datum.MetricName] = *datum.Value
+		if datum.Value != nil {
+			mcw.valuesReceived[*datum.MetricName] = *datum.Value
+		}
 		mcw.dimensionsReceived[*datum.MetricName] = datum.Dimensions
+		mcw.datumsReceived[*datum.MetricName] = append(mcw.datumsReceived[*datum.MetricName], datum)
 	}
 	return nil, nil
 }
@@ -181,3 +186,130 @@ func TestHistogram(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestHighResolution(t *testing.T) {
+	svc := newMockCloudWatch()
+	cw := New("abc", svc, 10, log.NewNopLogger(), HighResolution())
+	cw.NewGauge("gauge").Set(1)
+	cw.NewHistogram("histogram", 50).Observe(1)
+	if err := cw.Send(); err != nil {
+		t.Fatal(err)
+	}
+	for name, datums := range svc.datumsReceived {
+		for _, datum := range datums {
+			if datum.StorageResolution == nil || *datum.StorageResolution != 1 {
+				t.Errorf("%s: want storage resolution 1, have %v", name, datum.StorageResolution)
+			}
+		}
+	}
+}
+
+func TestUnit(t *testing.T) {
+	svc := newMockCloudWatch()
+	cw := New("abc", svc, 10, log.NewNopLogger())
+	cw.NewCounter("requests", Unit(cloudwatch.StandardUnitCount)).Add(1)
+	cw.NewGauge("depth").Set(1)
+	cw.NewHistogram("latency", 50, Unit(cloudwatch.StandardUnitSeconds)).Observe(1)
+	if err := cw.Send(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"requests":   "Count",
+		"latency_50": "Seconds",
+		"latency_99": "Seconds",
+	} {
+		datums := svc.datumsReceived[name]
+		if len(datums) != 1 || datums[0].Unit == nil || *datums[0].Unit != want {
+			t.Errorf("%s: want unit %q, have %v", name, want, datums)
+		}
+	}
+	if datums := svc.datumsReceived["depth"]; len(datums) != 1 || datums[0].Unit != nil {
+		t.Errorf("depth: want no unit, have %v", datums)
+	}
+}
+
+func TestDeltaCounters(t *testing.T) {
+	svc := newMockCloudWatch()
+	cw := New("abc", svc, 10, log.NewNopLogger(), DeltaCounters())
+	delta := cw.NewCounter("delta")
+	total := cw.NewCounter("total", Delta(false))
+
+	for _, want := range []struct{ delta, total float64 }{{3, 3}, {4, 7}, {0, 7}} {
+		if want.delta > 0 {
+			delta.Add(want.delta)
+			total.Add(want.delta)
+		}
+		svc.datumsReceived = map[string][]*cloudwatch.MetricDatum{}
+		if err := cw.Send(); err != nil {
+			t.Fatal(err)
+		}
+		if have := *svc.datumsReceived["delta"][0].Value; want.delta != have {
+			t.Errorf("delta: want %f, have %f", want.delta, have)
+		}
+		if have := *svc.datumsReceived["total"][0].Value; want.total != have {
+			t.Errorf("total: want %f, have %f", want.total, have)
+		}
+	}
+}
+
+func TestHistogramStatisticSets(t *testing.T) {
+	svc := newMockCloudWatch()
+	cw := New("abc", svc, 10, log.NewNopLogger(), WithHistogramMode(StatisticSets))
+	histogram := cw.NewHistogram("latency", 50).With("method", "Foo")
+	for _, v := range []float64{4, 1, 7} {
+		histogram.Observe(v)
+	}
+	if err := cw.Send(); err != nil {
+		t.Fatal(err)
+	}
+
+	datums := svc.datumsReceived["latency"]
+	if want, have := 1, len(datums); want != have {
+		t.Fatalf("want %d datum, have %d", want, have)
+	}
+	s := datums[0].StatisticValues
+	if s == nil {
+		t.Fatal("want StatisticValues, have none")
+	}
+	if want, have := "3 12 1 7", fmt.Sprint(*s.SampleCount, *s.Sum, *s.Minimum, *s.Maximum); want != have {
+		t.Errorf("want count/sum/min/max %s, have %s", want, have)
+	}
+	if err := testDimensions(svc, "latency", "method", "Foo"); err != nil {
+		t.Error(err)
+	}
+
+	// Nothing was observed in the next window, so nothing is sent.
+	if err := cw.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(svc.datumsReceived["latency"]); want != have {
+		t.Errorf("want %d datum, have %d", want, have)
+	}
+}
+
+func TestHistogramValuesCounts(t *testing.T) {
+	svc := newMockCloudWatch()
+	cw := New("abc", svc, 10, log.NewNopLogger(), WithHistogramMode(ValuesCounts))
+	histogram := cw.NewHistogram("latency", 50)
+	for i := 0; i < maxValuesPerDatum+1; i++ {
+		histogram.Observe(float64(i))
+	}
+	histogram.Observe(0)
+	if err := cw.Send(); err != nil {
+		t.Fatal(err)
+	}
+
+	datums := svc.datumsReceived["latency"]
+	if want, have := 2, len(datums); want != have {
+		t.Fatalf("want %d datums, have %d", want, have)
+	}
+	if want, have := maxValuesPerDatum, len(datums[0].Values); want != have {
+		t.Errorf("want %d values in the first datum, have %d", want, have)
+	}
+	if want, have := 2.0, *datums[0].Counts[0]; want != have {
+		t.Errorf("want value 0 counted %f times, have %f", want, have)
+	}
+	if want, have := float64(maxValuesPerDatum), *datums[1].Values[0]; want != have {
+		t.Errorf("want value %f in the second datum, have %f", want, have)
+	}
+}
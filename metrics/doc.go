@@ -93,5 +93,6 @@
 //    prometheus  n    native                 native                 native
 //    pcp         1    native                 native                 native
 //    cloudwatch  n    batch push-aggregate   batch push-aggregate   synthetic, batch, push-aggregate
+//    otlp        n    n/a                    n/a                    native exponential, batch, push-aggregate
 //
 package metrics
@@ -14,10 +14,12 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/internal/emitter"
 	"github.com/go-kit/kit/metrics/internal/lv"
 	"github.com/go-kit/kit/metrics/internal/ratemap"
 	"github.com/go-kit/kit/util/conn"
@@ -29,41 +31,74 @@ import (
 //
 // All metrics are buffered until WriteTo is called. Counters and gauges are
 // aggregated into a single observation per timeseries per write. Timings and
-// histograms are buffered but not aggregated.
+// histograms are buffered but not aggregated. Sets are reduced to their unique
+// values per timeseries per write.
+//
+// Label values given to the With method of each metric are emitted as
+// DogStatsD tags, after any global tags given to New.
 //
 // To regularly report metrics to an io.Writer, use the WriteLoop helper method.
 // To send to a DogStatsD server, use the SendLoop helper method.
 type Dogstatsd struct {
 	prefix     string
+	lvs        lv.LabelValues
 	rates      *ratemap.RateMap
 	counters   *lv.Space
 	gauges     *lv.Space
 	timings    *lv.Space
 	histograms *lv.Space
+	sets       *lv.Space
 	logger     log.Logger
+
+	mtx        sync.Mutex
+	maxTagSets int
+	tagSets    map[string]map[string]struct{}
+	gaugeFuncs []gaugeFunc
+}
+
+type gaugeFunc struct {
+	name string
+	lvs  lv.LabelValues
+	f    func() float64
 }
 
 // New returns a Dogstatsd object that may be used to create metrics. Prefix is
-// applied to all created metrics. Callers must ensure that regular calls to
-// WriteTo are performed, either manually or with one of the helper methods.
-func New(prefix string, logger log.Logger) *Dogstatsd {
+// applied to all created metrics. The optional label values are global tags,
+// applied to every emission. Callers must ensure that regular calls to WriteTo
+// are performed, either manually or with one of the helper methods.
+func New(prefix string, logger log.Logger, lvs ...string) *Dogstatsd {
 	return &Dogstatsd{
 		prefix:     prefix,
+		lvs:        lv.LabelValues{}.With(lvs...),
 		rates:      ratemap.New(),
 		counters:   lv.NewSpace(),
 		gauges:     lv.NewSpace(),
 		timings:    lv.NewSpace(),
 		histograms: lv.NewSpace(),
+		sets:       lv.NewSpace(),
 		logger:     logger,
+		tagSets:    map[string]map[string]struct{}{},
 	}
 }
 
+// LimitTagSets caps the number of distinct tag sets, i.e. combinations of
+// label values passed to With, that are emitted for each metric name. Once a
+// metric has reached the limit, observations with previously unseen tag sets
+// are emitted without their per-metric tags, leaving only the global tags.
+// This protects against unbounded cardinality from e.g. user-supplied label
+// values. Zero, the default, means no limit.
+func (d *Dogstatsd) LimitTagSets(max int) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.maxTagSets = max
+}
+
 // NewCounter returns a counter, sending observations to this Dogstatsd object.
 func (d *Dogstatsd) NewCounter(name string, sampleRate float64) *Counter {
 	d.rates.Set(d.prefix+name, sampleRate)
 	return &Counter{
 		name: d.prefix + name,
-		obs:  d.counters.Observe,
+		obs:  d.limit(d.counters.Observe),
 	}
 }
 
@@ -71,18 +106,32 @@ func (d *Dogstatsd) NewCounter(name string, sampleRate float64) *Counter {
 func (d *Dogstatsd) NewGauge(name string) *Gauge {
 	return &Gauge{
 		name: d.prefix + name,
-		obs:  d.gauges.Observe,
-		add:  d.gauges.Add,
+		obs:  d.limit(d.gauges.Observe),
+		add:  d.limit(d.gauges.Add),
 	}
 }
 
+// NewGaugeFunc registers a gauge whose value is determined by calling f every
+// time the Dogstatsd object is written. It's useful for values like queue
+// depth, which are cheaper to read on demand than to track continuously. The
+// label values are emitted as tags.
+func (d *Dogstatsd) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.gaugeFuncs = append(d.gaugeFuncs, gaugeFunc{
+		name: d.prefix + name,
+		lvs:  lv.LabelValues{}.With(labelValues...),
+		f:    f,
+	})
+}
+
 // NewTiming returns a histogram whose observations are interpreted as
 // millisecond durations, and are forwarded to this Dogstatsd object.
 func (d *Dogstatsd) NewTiming(name string, sampleRate float64) *Timing {
 	d.rates.Set(d.prefix+name, sampleRate)
 	return &Timing{
 		name: d.prefix + name,
-		obs:  d.timings.Observe,
+		obs:  d.limit(d.timings.Observe),
 	}
 }
 
@@ -92,29 +141,129 @@ func (d *Dogstatsd) NewHistogram(name string, sampleRate float64) *Histogram {
 	d.rates.Set(d.prefix+name, sampleRate)
 	return &Histogram{
 		name: d.prefix + name,
-		obs:  d.histograms.Observe,
+		obs:  d.limit(d.histograms.Observe),
 	}
 }
 
+// NewSet returns a set, which counts the number of unique values observed per
+// flush, sending observations to this Dogstatsd object.
+func (d *Dogstatsd) NewSet(name string) *Set {
+	return &Set{
+		name: d.prefix + name,
+		obs:  d.limit(d.sets.Observe),
+	}
+}
+
+// limit wraps an observeFunc, enforcing the tag set limit.
+func (d *Dogstatsd) limit(obs observeFunc) observeFunc {
+	return func(name string, lvs lv.LabelValues, value float64) {
+		if len(lvs) > 0 && !d.allow(name, lvs) {
+			lvs = lv.LabelValues{}
+		}
+		obs(name, lvs, value)
+	}
+}
+
+func (d *Dogstatsd) allow(name string, lvs lv.LabelValues) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.maxTagSets <= 0 {
+		return true
+	}
+	key := strings.Join(lvs, "\x00")
+	seen, ok := d.tagSets[name]
+	if !ok {
+		seen = map[string]struct{}{}
+		d.tagSets[name] = seen
+	}
+	if _, ok := seen[key]; ok {
+		return true
+	}
+	if len(seen) >= d.maxTagSets {
+		return false
+	}
+	seen[key] = struct{}{}
+	return true
+}
+
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
-// time the passed channel fires. This method blocks until the channel is
-// closed, so clients probably want to run it in its own goroutine. For typical
-// usage, create a time.Ticker and pass its C channel to this method.
+// time the passed channel fires. If the writer has a Flush method, it is
+// called after each write. This method blocks until the channel is closed, so
+// clients probably want to run it in its own goroutine. For typical usage,
+// create a time.Ticker and pass its C channel to this method.
 func (d *Dogstatsd) WriteLoop(c <-chan time.Time, w io.Writer) {
 	for range c {
 		if _, err := d.WriteTo(w); err != nil {
 			d.logger.Log("during", "WriteTo", "err", err)
 		}
+		if f, ok := w.(interface {
+			Flush() error
+		}); ok {
+			f.Flush()
+		}
 	}
 }
 
+// SendOption sets an optional parameter for SendLoop.
+type SendOption func(*emitter.Config)
+
+// SendMaxPacketSize sets the maximum size in bytes of each packet sent to the
+// server. Observations are packed into as few packets as possible; a single
+// observation longer than the maximum is sent alone. By default, 1432 bytes
+// are used, which fits a UDP datagram within a typical Ethernet MTU.
+func SendMaxPacketSize(n int) SendOption {
+	return func(c *emitter.Config) { c.MaxPacketSize = n }
+}
+
+// SendFlushInterval bounds the time a partially filled packet is held before
+// it's sent. Packets are also sent at the end of every write, so this only
+// matters if the loop's channel fires less often. By default, one second is
+// used.
+func SendFlushInterval(d time.Duration) SendOption {
+	return func(c *emitter.Config) { c.FlushInterval = d }
+}
+
+// SendQueueSize sets the number of packets which may wait to be sent. If the
+// server is slow or unavailable and the queue fills, further packets are
+// dropped rather than blocking the loop. By default, 1024 packets are queued.
+func SendQueueSize(n int) SendOption {
+	return func(c *emitter.Config) { c.QueueSize = n }
+}
+
+// SendErrorHandler sets the function called with each error from sending a
+// packet, including emitter.ErrQueueFull when one is dropped. By default,
+// errors are logged to the Dogstatsd object's logger.
+func SendErrorHandler(f func(error)) SendOption {
+	return func(c *emitter.Config) { c.ErrorHandler = f }
+}
+
+// SendDropCounter sets a counter which is incremented by the number of
+// observations in each dropped packet.
+func SendDropCounter(counter metrics.Counter) SendOption {
+	return func(c *emitter.Config) { c.DropCounter = counter }
+}
+
 // SendLoop is a helper method that wraps WriteLoop, passing a managed
-// connection to the network and address. Like WriteLoop, this method blocks
-// until the channel is closed, so clients probably want to start it in its own
-// goroutine. For typical usage, create a time.Ticker and pass its C channel to
-// this method.
-func (d *Dogstatsd) SendLoop(c <-chan time.Time, network, address string) {
-	d.WriteLoop(c, conn.NewDefaultManager(network, address, d.logger))
+// connection to the network and address. Observations are packed into packets
+// and sent from a separate goroutine, so a slow server doesn't delay the loop;
+// see the SendOption functions for the parameters. Like WriteLoop, this method
+// blocks until the channel is closed, so clients probably want to start it in
+// its own goroutine. For typical usage, create a time.Ticker and pass its C
+// channel to this method.
+func (d *Dogstatsd) SendLoop(c <-chan time.Time, network, address string, options ...SendOption) {
+	d.sendLoop(c, conn.NewDefaultManager(network, address, d.logger), options...)
+}
+
+func (d *Dogstatsd) sendLoop(c <-chan time.Time, w io.Writer, options ...SendOption) {
+	cfg := emitter.Config{
+		ErrorHandler: func(err error) { d.logger.Log("during", "send", "err", err) },
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	e := emitter.New(w, cfg)
+	defer e.Close()
+	d.WriteLoop(c, e)
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in
@@ -125,7 +274,7 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	var n int
 
 	d.counters.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
-		n, err = fmt.Fprintf(w, "%s:%f|c%s%s\n", name, sum(values), sampling(d.rates.Get(name)), tagValues(lvs))
+		n, err = fmt.Fprintf(w, "%s:%f|c%s%s\n", name, sum(values), sampling(d.rates.Get(name)), d.tagValues(lvs))
 		if err != nil {
 			return false
 		}
@@ -136,8 +285,15 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 		return count, err
 	}
 
+	d.mtx.Lock()
+	gaugeFuncs := d.gaugeFuncs
+	d.mtx.Unlock()
+	for _, gf := range gaugeFuncs {
+		d.gauges.Observe(gf.name, gf.lvs, gf.f())
+	}
+
 	d.gauges.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
-		n, err = fmt.Fprintf(w, "%s:%f|g%s\n", name, last(values), tagValues(lvs))
+		n, err = fmt.Fprintf(w, "%s:%f|g%s\n", name, last(values), d.tagValues(lvs))
 		if err != nil {
 			return false
 		}
@@ -151,7 +307,7 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	d.timings.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
 		sampleRate := d.rates.Get(name)
 		for _, value := range values {
-			n, err = fmt.Fprintf(w, "%s:%f|ms%s%s\n", name, value, sampling(sampleRate), tagValues(lvs))
+			n, err = fmt.Fprintf(w, "%s:%f|ms%s%s\n", name, value, sampling(sampleRate), d.tagValues(lvs))
 			if err != nil {
 				return false
 			}
@@ -166,7 +322,21 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	d.histograms.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
 		sampleRate := d.rates.Get(name)
 		for _, value := range values {
-			n, err = fmt.Fprintf(w, "%s:%f|h%s%s\n", name, value, sampling(sampleRate), tagValues(lvs))
+			n, err = fmt.Fprintf(w, "%s:%f|h%s%s\n", name, value, sampling(sampleRate), d.tagValues(lvs))
+			if err != nil {
+				return false
+			}
+			count += int64(n)
+		}
+		return true
+	})
+	if err != nil {
+		return count, err
+	}
+
+	d.sets.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		for _, value := range unique(values) {
+			n, err = fmt.Fprintf(w, "%s:%f|s%s\n", name, value, d.tagValues(lvs))
 			if err != nil {
 				return false
 			}
@@ -193,6 +363,18 @@ func last(a []float64) float64 {
 	return a[len(a)-1]
 }
 
+func unique(a []float64) []float64 {
+	seen := make(map[float64]struct{}, len(a))
+	u := make([]float64, 0, len(a))
+	for _, f := range a {
+		if _, ok := seen[f]; !ok {
+			seen[f] = struct{}{}
+			u = append(u, f)
+		}
+	}
+	return u
+}
+
 func sampling(r float64) string {
 	var sv string
 	if r < 1.0 {
@@ -201,6 +383,10 @@ func sampling(r float64) string {
 	return sv
 }
 
+func (d *Dogstatsd) tagValues(labelValues []string) string {
+	return tagValues(append(d.lvs[:len(d.lvs):len(d.lvs)], labelValues...))
+}
+
 func tagValues(labelValues []string) string {
 	if len(labelValues) == 0 {
 		return ""
@@ -312,3 +498,25 @@ func (h *Histogram) With(labelValues ...string) metrics.Histogram {
 func (h *Histogram) Observe(value float64) {
 	h.obs(h.name, h.lvs, value)
 }
+
+// Set is a DogStatsD set. Observations are forwarded to a Dogstatsd object,
+// and reduced to their unique values per timeseries.
+type Set struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+}
+
+// With returns a set with the label values applied as tags.
+func (s *Set) With(labelValues ...string) *Set {
+	return &Set{
+		name: s.name,
+		lvs:  s.lvs.With(labelValues...),
+		obs:  s.obs,
+	}
+}
+
+// Add records value as a member of the set.
+func (s *Set) Add(value float64) {
+	s.obs(s.name, s.lvs, value)
+}
@@ -1,7 +1,12 @@
 package dogstatsd
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics/teststat"
@@ -88,3 +93,104 @@ func TestTimingSampled(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGlobalTags(t *testing.T) {
+	prefix, name := "global.", "counter"
+	regex := `^` + prefix + name + `:([0-9\.]+)\|c\|#env:prod,region:us,label:value$`
+	d := New(prefix, log.NewNopLogger(), "env", "prod", "region", "us")
+	counter := d.NewCounter(name, 1.0).With("label", "value")
+	valuef := teststat.SumLines(d, regex)
+	if err := teststat.TestCounter(counter, valuef); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTagSetLimit(t *testing.T) {
+	d := New("", log.NewNopLogger(), "env", "prod")
+	d.LimitTagSets(2)
+	counter := d.NewCounter("requests", 1.0)
+	counter.With("user", "a").Add(1)
+	counter.With("user", "b").Add(1)
+	counter.With("user", "c").Add(1) // over the limit
+	counter.With("user", "a").Add(1) // already seen
+	counter.With("user", "d").Add(1) // over the limit
+
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	have := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	sort.Strings(have)
+	want := []string{
+		"requests:1.000000|c|#env:prod,user:b",
+		"requests:2.000000|c|#env:prod",
+		"requests:2.000000|c|#env:prod,user:a",
+	}
+	if strings.Join(want, "\n") != strings.Join(have, "\n") {
+		t.Errorf("want\n%s\nhave\n%s", strings.Join(want, "\n"), strings.Join(have, "\n"))
+	}
+}
+
+func TestSet(t *testing.T) {
+	d := New("dogstatsd.", log.NewNopLogger())
+	set := d.NewSet("users").With("foo", "bar")
+	for _, v := range []float64{1, 2, 1, 3, 2} {
+		set.Add(v)
+	}
+
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	want := strings.Join([]string{
+		"dogstatsd.users:1.000000|s|#foo:bar",
+		"dogstatsd.users:2.000000|s|#foo:bar",
+		"dogstatsd.users:3.000000|s|#foo:bar",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	d := New("dogstatsd.", log.NewNopLogger(), "env", "prod")
+	d.NewGaugeFunc("depth", func() float64 { return 12 }, "queue", "jobs")
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	if want, have := "dogstatsd.depth:12.000000|g|#env:prod,queue:jobs\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type packetWriter struct {
+	packets []string
+}
+
+func (w *packetWriter) Write(p []byte) (int, error) {
+	w.packets = append(w.packets, string(p))
+	return len(p), nil
+}
+
+func TestSendLoop(t *testing.T) {
+	s := New("send.", log.NewNopLogger())
+	for i := 0; i < 20; i++ {
+		s.NewCounter(fmt.Sprintf("counter_%02d", i), 1.0).Add(1)
+	}
+	var (
+		w = &packetWriter{}
+		c = make(chan time.Time, 1)
+	)
+	c <- time.Now()
+	close(c)
+	s.sendLoop(c, w, SendMaxPacketSize(100))
+
+	var lines int
+	for _, p := range w.packets {
+		if len(p) > 100 {
+			t.Errorf("packet of %d bytes exceeds maximum", len(p))
+		}
+		lines += strings.Count(p, "\n")
+	}
+	if want, have := 20, lines; want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+	if len(w.packets) >= lines {
+		t.Errorf("%d lines weren't packed: %d packets", lines, len(w.packets))
+	}
+}
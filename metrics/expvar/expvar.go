@@ -4,6 +4,7 @@ package expvar
 
 import (
 	"expvar"
+	"strconv"
 	"sync"
 
 	"github.com/go-kit/kit/metrics"
@@ -53,30 +54,42 @@ func (g *Gauge) Set(value float64) { g.f.Set(value) }
 // Add implements metrics.Gauge.
 func (g *Gauge) Add(delta float64) { g.f.Add(delta) }
 
+// DefaultQuantiles are the quantiles published by a Histogram if no others
+// are specified.
+var DefaultQuantiles = []float64{0.50, 0.90, 0.95, 0.99}
+
 // Histogram implements the histogram metric with a combination of the generic
-// Histogram object and several expvar Floats, one for each of the 50th, 90th,
-// 95th, and 99th quantiles of observed values, with the quantile attached to
-// the name as a suffix. Label values are not supported.
+// Histogram object and several expvar Floats: one for each published quantile
+// of observed values, with the quantile attached to the name as a suffix, e.g.
+// name.p99, as well as name.count and name.sum. All are updated on every
+// observation. Label values are not supported.
 type Histogram struct {
-	mtx sync.Mutex
-	h   *generic.Histogram
-	p50 *expvar.Float
-	p90 *expvar.Float
-	p95 *expvar.Float
-	p99 *expvar.Float
+	mtx       sync.Mutex
+	h         *generic.Histogram
+	quantiles []float64
+	vars      []*expvar.Float // per quantile
+	count     *expvar.Float
+	sum       *expvar.Float
 }
 
 // NewHistogram returns a Histogram object with the given name and number of
 // buckets in the underlying histogram object. 50 is a good default number of
-// buckets.
-func NewHistogram(name string, buckets int) *Histogram {
-	return &Histogram{
-		h:   generic.NewHistogram(name, buckets),
-		p50: expvar.NewFloat(name + ".p50"),
-		p90: expvar.NewFloat(name + ".p90"),
-		p95: expvar.NewFloat(name + ".p95"),
-		p99: expvar.NewFloat(name + ".p99"),
+// buckets. The quantiles, 0.0 < q < 1.0, determine which are published; if
+// none are given, DefaultQuantiles are used.
+func NewHistogram(name string, buckets int, quantiles ...float64) *Histogram {
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+	h := &Histogram{
+		h:         generic.NewHistogram(name, buckets),
+		quantiles: quantiles,
+		count:     expvar.NewFloat(name + ".count"),
+		sum:       expvar.NewFloat(name + ".sum"),
+	}
+	for _, q := range quantiles {
+		h.vars = append(h.vars, expvar.NewFloat(name+"."+quantileSuffix(q)))
 	}
+	return h
 }
 
 // With is a no-op.
@@ -87,8 +100,14 @@ func (h *Histogram) Observe(value float64) {
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
 	h.h.Observe(value)
-	h.p50.Set(h.h.Quantile(0.50))
-	h.p90.Set(h.h.Quantile(0.90))
-	h.p95.Set(h.h.Quantile(0.95))
-	h.p99.Set(h.h.Quantile(0.99))
+	for i, q := range h.quantiles {
+		h.vars[i].Set(h.h.Quantile(q))
+	}
+	h.count.Set(float64(h.h.Count()))
+	h.sum.Set(h.h.Sum())
+}
+
+// quantileSuffix formats a quantile as a percentile, e.g. p99 or p99.9.
+func quantileSuffix(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
 }
@@ -1,6 +1,7 @@
 package expvar
 
 import (
+	"expvar"
 	"strconv"
 	"testing"
 
@@ -26,13 +27,41 @@ func TestGauge(t *testing.T) {
 func TestHistogram(t *testing.T) {
 	histogram := NewHistogram("expvar_histogram", 50).With("label values", "not supported").(*Histogram)
 	quantiles := func() (float64, float64, float64, float64) {
-		p50, _ := strconv.ParseFloat(histogram.p50.String(), 64)
-		p90, _ := strconv.ParseFloat(histogram.p90.String(), 64)
-		p95, _ := strconv.ParseFloat(histogram.p95.String(), 64)
-		p99, _ := strconv.ParseFloat(histogram.p99.String(), 64)
+		p50, _ := strconv.ParseFloat(expvar.Get("expvar_histogram.p50").String(), 64)
+		p90, _ := strconv.ParseFloat(expvar.Get("expvar_histogram.p90").String(), 64)
+		p95, _ := strconv.ParseFloat(expvar.Get("expvar_histogram.p95").String(), 64)
+		p99, _ := strconv.ParseFloat(expvar.Get("expvar_histogram.p99").String(), 64)
 		return p50, p90, p95, p99
 	}
 	if err := teststat.TestHistogram(histogram, quantiles, 0.01); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestHistogramQuantiles(t *testing.T) {
+	histogram := NewHistogram("expvar_histogram_quantiles", 50, 0.5, 0.999)
+	for _, v := range []float64{1, 2, 3, 4} {
+		histogram.Observe(v)
+	}
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"expvar_histogram_quantiles.count", "4"},
+		{"expvar_histogram_quantiles.sum", "10"},
+		{"expvar_histogram_quantiles.p50", "2"},
+		{"expvar_histogram_quantiles.p99.9", "4"},
+	} {
+		v := expvar.Get(tc.name)
+		if v == nil {
+			t.Errorf("%s: not published", tc.name)
+			continue
+		}
+		if want, have := tc.want, v.String(); want != have {
+			t.Errorf("%s: want %s, have %s", tc.name, want, have)
+		}
+	}
+	if v := expvar.Get("expvar_histogram_quantiles.p90"); v != nil {
+		t.Errorf("p90: want unpublished, have %s", v)
+	}
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import "time"
+
+// PollGauge is a helper function that evaluates f and sets the result on the
+// gauge every time the passed channel fires. It's useful for values like queue
+// depth, which are cheaper to read on demand than to track continuously, when
+// the backend has no native support for callback gauges. This function blocks
+// until the channel is closed, so clients probably want to run it in its own
+// goroutine. For typical usage, create a time.Ticker and pass its C channel to
+// this function.
+//
+// Backends which evaluate callbacks at scrape or flush time, like Prometheus,
+// StatsD, DogStatsD, and Graphite, provide a NewGaugeFunc method or function
+// which should be preferred.
+func PollGauge(c <-chan time.Time, g Gauge, f func() float64) {
+	for range c {
+		g.Set(f())
+	}
+}
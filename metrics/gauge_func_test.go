@@ -0,0 +1,30 @@
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestPollGauge(t *testing.T) {
+	g := generic.NewGauge("depth")
+	c := make(chan time.Time)
+	done := make(chan struct{})
+	depths, i := []float64{1, 5, 3}, 0
+	go func() {
+		metrics.PollGauge(c, g, func() float64 { i++; return depths[i-1] })
+		close(done)
+	}()
+
+	for range depths {
+		c <- time.Now()
+	}
+	close(c)
+	<-done
+
+	if want, have := 3.0, g.Value(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+}
@@ -0,0 +1,311 @@
+package generic
+
+import (
+	"math"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/internal/lv"
+)
+
+// Defaults for an ExponentialHistogram, chosen to match the OpenTelemetry SDK.
+const (
+	DefaultExponentialMaxBuckets = 160
+	DefaultExponentialMaxScale   = 20
+)
+
+// ExponentialHistogram is an in-memory implementation of a Histogram using
+// base-2 exponential buckets, as defined by OpenTelemetry. Bucket boundaries
+// are powers of base = 2^(2^-scale). Observations start at the maximum scale,
+// i.e. the finest resolution, and the scale is reduced as needed to keep the
+// number of buckets per sign within the maximum. So, the relative error of
+// any observation is bounded, regardless of the range of observed values, and
+// no bucket boundaries need to be chosen up front.
+type ExponentialHistogram struct {
+	Name string
+	lvs  lv.LabelValues
+	h    *safeExponential
+}
+
+// ExponentialOption sets an optional parameter for an ExponentialHistogram.
+type ExponentialOption func(*safeExponential)
+
+// ExponentialMaxBuckets sets the maximum number of buckets for each of the
+// positive and negative ranges. By default, DefaultExponentialMaxBuckets is
+// used.
+func ExponentialMaxBuckets(n int) ExponentialOption {
+	return func(h *safeExponential) { h.maxBuckets = n }
+}
+
+// ExponentialMaxScale sets the initial, and finest, scale, between -10 and 20.
+// By default, DefaultExponentialMaxScale is used.
+func ExponentialMaxScale(scale int32) ExponentialOption {
+	return func(h *safeExponential) { h.scale = scale }
+}
+
+// ExponentialZeroThreshold sets the magnitude at or below which observations
+// are counted as zero. By default, only zero is.
+func ExponentialZeroThreshold(t float64) ExponentialOption {
+	return func(h *safeExponential) { h.zeroThreshold = math.Abs(t) }
+}
+
+// NewExponentialHistogram returns an ExponentialHistogram with the given
+// options.
+func NewExponentialHistogram(name string, options ...ExponentialOption) *ExponentialHistogram {
+	h := &safeExponential{
+		maxBuckets: DefaultExponentialMaxBuckets,
+		scale:      DefaultExponentialMaxScale,
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if h.maxBuckets < 2 {
+		h.maxBuckets = 2
+	}
+	if h.scale > 20 {
+		h.scale = 20
+	}
+	if h.scale < -10 {
+		h.scale = -10
+	}
+	h.maxScale = h.scale
+	return &ExponentialHistogram{
+		Name: name,
+		h:    h,
+	}
+}
+
+// With implements Histogram.
+func (h *ExponentialHistogram) With(labelValues ...string) metrics.Histogram {
+	return &ExponentialHistogram{
+		Name: h.Name,
+		lvs:  h.lvs.With(labelValues...),
+		h:    h.h,
+	}
+}
+
+// Observe implements Histogram. NaN and infinite values are ignored.
+func (h *ExponentialHistogram) Observe(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	h.h.Lock()
+	defer h.h.Unlock()
+	h.h.observe(value)
+}
+
+// Reset discards all observations, and restores the initial scale. The
+// histogram's state is shared by every set of label values, so they're all
+// affected.
+func (h *ExponentialHistogram) Reset() {
+	h.h.Lock()
+	defer h.h.Unlock()
+	h.h.scale = h.h.maxScale
+	h.h.positive, h.h.negative = expBuckets{}, expBuckets{}
+	h.h.zeroCount, h.h.count, h.h.sum, h.h.min, h.h.max = 0, 0, 0, 0, 0
+}
+
+// Quantile returns an estimate of the value of the quantile q, 0.0 < q < 1.0.
+// The estimate is the geometric midpoint of the bucket containing the
+// quantile, clamped to the observed minimum and maximum. It returns 0 if there
+// are no observations.
+func (h *ExponentialHistogram) Quantile(q float64) float64 {
+	return h.Snapshot().Quantile(q)
+}
+
+// Snapshot returns a copy of the current state of the histogram.
+func (h *ExponentialHistogram) Snapshot() ExponentialSnapshot {
+	h.h.Lock()
+	defer h.h.Unlock()
+	return ExponentialSnapshot{
+		Scale:         h.h.scale,
+		ZeroThreshold: h.h.zeroThreshold,
+		ZeroCount:     h.h.zeroCount,
+		Positive:      h.h.positive.copy(),
+		Negative:      h.h.negative.copy(),
+		Count:         h.h.count,
+		Sum:           h.h.sum,
+		Min:           h.h.min,
+		Max:           h.h.max,
+	}
+}
+
+// LabelValues returns the set of label values attached to the histogram.
+func (h *ExponentialHistogram) LabelValues() []string {
+	return h.lvs
+}
+
+// ExponentialSnapshot is the state of an ExponentialHistogram at a point in
+// time. Its fields map directly to an OpenTelemetry exponential histogram data
+// point.
+type ExponentialSnapshot struct {
+	Scale         int32
+	ZeroThreshold float64
+	ZeroCount     uint64
+	Positive      ExponentialBuckets // of positive observations
+	Negative      ExponentialBuckets // of the magnitudes of negative observations
+	Count         uint64
+	Sum           float64
+	Min           float64
+	Max           float64
+}
+
+// ExponentialBuckets is a contiguous range of exponential buckets. Counts[i]
+// is the number of observations in the bucket with index Offset+i, which
+// covers magnitudes in (base^(Offset+i), base^(Offset+i+1)].
+type ExponentialBuckets struct {
+	Offset int32
+	Counts []uint64
+}
+
+// Quantile returns an estimate of the value of the quantile q, 0.0 < q < 1.0,
+// as described for ExponentialHistogram.Quantile.
+func (s ExponentialSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var (
+		base = math.Exp2(math.Exp2(-float64(s.Scale)))
+		seen uint64
+		v    float64
+	)
+	for i := len(s.Negative.Counts) - 1; i >= 0 && seen < rank; i-- {
+		seen += s.Negative.Counts[i]
+		v = -math.Pow(base, float64(s.Negative.Offset)+float64(i)+0.5)
+	}
+	if seen < rank {
+		seen += s.ZeroCount
+		v = 0
+	}
+	for i := 0; i < len(s.Positive.Counts) && seen < rank; i++ {
+		seen += s.Positive.Counts[i]
+		v = math.Pow(base, float64(s.Positive.Offset)+float64(i)+0.5)
+	}
+	return math.Max(s.Min, math.Min(s.Max, v))
+}
+
+type safeExponential struct {
+	sync.Mutex
+	maxBuckets    int
+	maxScale      int32
+	zeroThreshold float64
+	scale         int32
+
+	positive  expBuckets
+	negative  expBuckets
+	zeroCount uint64
+	count     uint64
+	sum       float64
+	min       float64
+	max       float64
+}
+
+func (h *safeExponential) observe(value float64) {
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+	if h.count == 0 || value > h.max {
+		h.max = value
+	}
+	h.count++
+	h.sum += value
+
+	magnitude := math.Abs(value)
+	if magnitude <= h.zeroThreshold {
+		h.zeroCount++
+		return
+	}
+	b, other := &h.positive, &h.negative
+	if value < 0 {
+		b, other = other, b
+	}
+	index := bucketIndex(magnitude, h.scale)
+	if change := b.scaleChange(index, h.maxBuckets); change > 0 {
+		h.scale -= change
+		b.downscale(change)
+		other.downscale(change)
+		index >>= uint(change)
+	}
+	b.increment(index)
+}
+
+// bucketIndex returns the index of the bucket containing the positive value
+// at the given scale, such that the bucket is (base^index, base^(index+1)].
+func bucketIndex(value float64, scale int32) int32 {
+	frac, exp := math.Frexp(value) // value = frac * 2^exp, 0.5 <= frac < 1
+	if scale <= 0 {
+		// Exact powers of two belong to the bucket below.
+		index := int32(exp - 1)
+		if frac == 0.5 {
+			index--
+		}
+		return index >> uint(-scale)
+	}
+	if frac == 0.5 {
+		return int32(exp-1)<<uint(scale) - 1
+	}
+	return int32(math.Ceil(math.Log(value)*math.Exp2(float64(scale))/math.Ln2)) - 1
+}
+
+type expBuckets struct {
+	offset int32
+	counts []uint64
+}
+
+// scaleChange returns the reduction in scale needed for the buckets to cover
+// index within max buckets.
+func (b *expBuckets) scaleChange(index int32, max int) int32 {
+	if len(b.counts) == 0 {
+		return 0
+	}
+	lo, hi := b.offset, b.offset+int32(len(b.counts))-1
+	if index < lo {
+		lo = index
+	}
+	if index > hi {
+		hi = index
+	}
+	var change int32
+	for int(hi>>uint(change))-int(lo>>uint(change)) >= max {
+		change++
+	}
+	return change
+}
+
+// downscale merges the buckets for a scale reduced by change.
+func (b *expBuckets) downscale(change int32) {
+	if change <= 0 || len(b.counts) == 0 {
+		return
+	}
+	offset := b.offset >> uint(change)
+	counts := make([]uint64, (b.offset+int32(len(b.counts))-1)>>uint(change)-offset+1)
+	for i, c := range b.counts {
+		counts[(b.offset+int32(i))>>uint(change)-offset] += c
+	}
+	b.offset, b.counts = offset, counts
+}
+
+func (b *expBuckets) increment(index int32) {
+	switch {
+	case len(b.counts) == 0:
+		b.offset, b.counts = index, []uint64{0}
+	case index < b.offset:
+		counts := make([]uint64, int(b.offset-index)+len(b.counts))
+		copy(counts[b.offset-index:], b.counts)
+		b.offset, b.counts = index, counts
+	case index >= b.offset+int32(len(b.counts)):
+		b.counts = append(b.counts, make([]uint64, int(index-b.offset)-len(b.counts)+1)...)
+	}
+	b.counts[index-b.offset]++
+}
+
+func (b expBuckets) copy() ExponentialBuckets {
+	return ExponentialBuckets{
+		Offset: b.offset,
+		Counts: append([]uint64(nil), b.counts...),
+	}
+}
@@ -0,0 +1,86 @@
+package generic_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestExponentialBuckets(t *testing.T) {
+	h := generic.NewExponentialHistogram("exp", generic.ExponentialMaxScale(0))
+	for _, v := range []float64{1, 2, 3, 4, 0, -3} {
+		h.Observe(v)
+	}
+	want := generic.ExponentialSnapshot{
+		Scale:     0,
+		ZeroCount: 1,
+		Positive:  generic.ExponentialBuckets{Offset: -1, Counts: []uint64{1, 1, 2}},
+		Negative:  generic.ExponentialBuckets{Offset: 1, Counts: []uint64{1}},
+		Count:     6,
+		Sum:       7,
+		Min:       -3,
+		Max:       4,
+	}
+	if have := h.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestExponentialDownscale(t *testing.T) {
+	h := generic.NewExponentialHistogram("exp", generic.ExponentialMaxScale(0), generic.ExponentialMaxBuckets(2))
+	for _, v := range []float64{1, 4, 16} {
+		h.Observe(v)
+	}
+	s := h.Snapshot()
+	if want, have := int32(-2), s.Scale; want != have {
+		t.Errorf("scale: want %d, have %d", want, have)
+	}
+	if want, have := (generic.ExponentialBuckets{Offset: -1, Counts: []uint64{1, 2}}), s.Positive; !reflect.DeepEqual(want, have) {
+		t.Errorf("positive: want %+v, have %+v", want, have)
+	}
+}
+
+func TestExponentialWideRange(t *testing.T) {
+	h := generic.NewExponentialHistogram("exp").With("label", "value").(*generic.ExponentialHistogram)
+	if want, have := []string{"label", "value"}, h.LabelValues(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	// Six orders of magnitude, which would need a long hand-tuned bucket list.
+	var values []float64
+	for v := 1e-3; v < 1e3; v *= 1.01 {
+		values = append(values, v)
+		h.Observe(v)
+	}
+	s := h.Snapshot()
+	if n := len(s.Positive.Counts); n > generic.DefaultExponentialMaxBuckets {
+		t.Errorf("%d buckets exceeds the maximum", n)
+	}
+	var total uint64
+	for _, c := range s.Positive.Counts {
+		total += c
+	}
+	if want, have := uint64(len(values)), total; want != have {
+		t.Errorf("bucket counts: want %d, have %d", want, have)
+	}
+	// The relative error is bounded by the bucket width, base - 1.
+	tolerance := math.Exp2(math.Exp2(-float64(s.Scale))) - 1
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99} {
+		want := values[int(math.Ceil(q*float64(len(values))))-1]
+		if have := h.Quantile(q); math.Abs(want-have)/want > tolerance {
+			t.Errorf("quantile %.2f: want %f, have %f (tolerance %f)", q, want, have, tolerance)
+		}
+	}
+}
+
+func TestExponentialReset(t *testing.T) {
+	h := generic.NewExponentialHistogram("exp", generic.ExponentialMaxScale(0), generic.ExponentialMaxBuckets(2))
+	for _, v := range []float64{1, 4, 16} {
+		h.Observe(v)
+	}
+	h.Reset()
+	if want, have := (generic.ExponentialSnapshot{}), h.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
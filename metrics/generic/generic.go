@@ -145,7 +145,7 @@ type Histogram struct {
 func NewHistogram(name string, buckets int) *Histogram {
 	return &Histogram{
 		Name: name,
-		h:    &safeHistogram{Histogram: gohistogram.NewHistogram(buckets)},
+		h:    &safeHistogram{Histogram: gohistogram.NewHistogram(buckets), buckets: buckets},
 	}
 }
 
@@ -163,6 +163,16 @@ func (h *Histogram) Observe(value float64) {
 	h.h.Lock()
 	defer h.h.Unlock()
 	h.h.Add(value)
+	h.h.stats.observe(value)
+}
+
+// Reset discards all observations. The histogram's state is shared by every
+// set of label values, so they're all affected.
+func (h *Histogram) Reset() {
+	h.h.Lock()
+	defer h.h.Unlock()
+	h.h.Histogram = gohistogram.NewHistogram(h.h.buckets)
+	h.h.stats = moments{}
 }
 
 // Quantile returns the value of the quantile q, 0.0 < q < 1.0.
@@ -172,6 +182,84 @@ func (h *Histogram) Quantile(q float64) float64 {
 	return h.h.Quantile(q)
 }
 
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	return h.h.stats.count
+}
+
+// Sum returns the sum of all observations.
+func (h *Histogram) Sum() float64 {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	return h.h.stats.sum
+}
+
+// Min returns the smallest observation, or 0 if there are none.
+func (h *Histogram) Min() float64 {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	return h.h.stats.min
+}
+
+// Max returns the largest observation, or 0 if there are none.
+func (h *Histogram) Max() float64 {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	return h.h.stats.max
+}
+
+// Mean returns the arithmetic mean of all observations, or 0 if there are
+// none.
+func (h *Histogram) Mean() float64 {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	return h.h.stats.mean
+}
+
+// StdDev returns the population standard deviation of all observations, or 0
+// if there are none.
+func (h *Histogram) StdDev() float64 {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	return h.h.stats.stdDev()
+}
+
+// HistogramSnapshot is a consistent, point-in-time summary of the observations
+// made by a Histogram.
+type HistogramSnapshot struct {
+	Count     uint64
+	Sum       float64
+	Min       float64
+	Max       float64
+	Mean      float64
+	StdDev    float64
+	Quantiles map[float64]float64 // quantile (0..1) to value
+}
+
+// Snapshot returns a summary of the observations so far, including the values
+// of the requested quantiles, 0.0 < q < 1.0. Unlike calling the individual
+// accessors, all values in the snapshot are computed from the same set of
+// observations.
+func (h *Histogram) Snapshot(quantiles ...float64) HistogramSnapshot {
+	h.h.RLock()
+	defer h.h.RUnlock()
+	s := HistogramSnapshot{
+		Count:     h.h.stats.count,
+		Sum:       h.h.stats.sum,
+		Min:       h.h.stats.min,
+		Max:       h.h.stats.max,
+		Mean:      h.h.stats.mean,
+		StdDev:    h.h.stats.stdDev(),
+		Quantiles: make(map[float64]float64, len(quantiles)),
+	}
+	for _, q := range quantiles {
+		s.Quantiles[q] = h.h.Quantile(q)
+	}
+	return s
+}
+
 // LabelValues returns the set of label values attached to the histogram.
 func (h *Histogram) LabelValues() []string {
 	return h.lvs
@@ -189,6 +277,38 @@ func (h *Histogram) Print(w io.Writer) {
 type safeHistogram struct {
 	sync.RWMutex
 	gohistogram.Histogram
+	buckets int
+	stats   moments
+}
+
+// moments tracks exact summary statistics of a stream of observations, which
+// gohistogram only approximates.
+type moments struct {
+	count    uint64
+	sum      float64
+	min, max float64
+	mean, m2 float64 // Welford's online algorithm
+}
+
+func (m *moments) observe(value float64) {
+	if m.count == 0 || value < m.min {
+		m.min = value
+	}
+	if m.count == 0 || value > m.max {
+		m.max = value
+	}
+	m.count++
+	m.sum += value
+	delta := value - m.mean
+	m.mean += delta / float64(m.count)
+	m.m2 += delta * (value - m.mean)
+}
+
+func (m *moments) stdDev() float64 {
+	if m.count == 0 {
+		return 0
+	}
+	return math.Sqrt(m.m2 / float64(m.count))
 }
 
 // Bucket is a range in a histogram which aggregates observations.
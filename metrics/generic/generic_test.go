@@ -7,6 +7,7 @@ package generic_test
 import (
 	"math"
 	"math/rand"
+	"reflect"
 	"sync"
 	"testing"
 
@@ -107,3 +108,55 @@ func TestSimpleHistogram(t *testing.T) {
 		t.Errorf("want %f, have %f", want, have)
 	}
 }
+
+func TestHistogramAccessors(t *testing.T) {
+	histogram := generic.NewHistogram("my_histogram", 50)
+	if want, have := (generic.HistogramSnapshot{Quantiles: map[float64]float64{}}), histogram.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("empty snapshot: want %+v, have %+v", want, have)
+	}
+
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		histogram.Observe(v)
+	}
+	for _, tc := range []struct {
+		name       string
+		want, have float64
+	}{
+		{"Count", 8, float64(histogram.Count())},
+		{"Sum", 40, histogram.Sum()},
+		{"Min", 2, histogram.Min()},
+		{"Max", 9, histogram.Max()},
+		{"Mean", 5, histogram.Mean()},
+		{"StdDev", 2, histogram.StdDev()},
+	} {
+		if tc.want != tc.have {
+			t.Errorf("%s: want %f, have %f", tc.name, tc.want, tc.have)
+		}
+	}
+
+	s := histogram.Snapshot(0.5, 0.99)
+	if want, have := uint64(8), s.Count; want != have {
+		t.Errorf("snapshot Count: want %d, have %d", want, have)
+	}
+	if want, have := 2.0, s.StdDev; want != have {
+		t.Errorf("snapshot StdDev: want %f, have %f", want, have)
+	}
+	for _, q := range []float64{0.5, 0.99} {
+		if want, have := histogram.Quantile(q), s.Quantiles[q]; want != have {
+			t.Errorf("snapshot quantile %.2f: want %f, have %f", q, want, have)
+		}
+	}
+}
+
+func TestHistogramReset(t *testing.T) {
+	histogram := generic.NewHistogram("my_histogram", 50)
+	histogram.With("label", "value").Observe(123)
+	histogram.Reset()
+	if want, have := (generic.HistogramSnapshot{Quantiles: map[float64]float64{}}), histogram.Snapshot(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	histogram.Observe(1)
+	if want, have := uint64(1), histogram.Count(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
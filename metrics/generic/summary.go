@@ -0,0 +1,259 @@
+package generic
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/internal/lv"
+)
+
+// Defaults for a Summary, chosen to match the Prometheus client.
+const (
+	DefaultMaxAge     = 10 * time.Minute
+	DefaultAgeBuckets = 5
+	DefaultMaxSamples = 1028
+)
+
+// DefaultObjectives are the quantiles reported by a Summary if no others are
+// configured.
+var DefaultObjectives = []float64{0.5, 0.9, 0.99}
+
+// Summary is an in-memory implementation of a Histogram which computes
+// quantiles over a sliding time window. The window is divided into a number of
+// age buckets; observations are recorded into the current bucket, and the
+// oldest bucket is discarded as the window slides forward. It's intended for
+// providers that emit pre-computed quantiles, and as such is not suitable for
+// aggregation.
+type Summary struct {
+	Name string
+	lvs  lv.LabelValues
+	s    *safeSummary
+}
+
+// SummaryOption sets an optional parameter for a Summary.
+type SummaryOption func(*Summary)
+
+// SummaryObjectives sets the quantiles, 0.0 < q < 1.0, reported by Quantiles.
+// By default, DefaultObjectives are used.
+func SummaryObjectives(quantiles ...float64) SummaryOption {
+	return func(s *Summary) {
+		s.s.objectives = append([]float64(nil), quantiles...)
+		sort.Float64s(s.s.objectives)
+	}
+}
+
+// SummaryMaxAge sets the duration of the sliding window. Observations older
+// than this no longer contribute to quantiles. By default, DefaultMaxAge is
+// used.
+func SummaryMaxAge(d time.Duration) SummaryOption {
+	return func(s *Summary) { s.s.maxAge = d }
+}
+
+// SummaryAgeBuckets sets the number of buckets the sliding window is divided
+// into. More buckets make the window slide more smoothly, at the cost of
+// memory. By default, DefaultAgeBuckets is used.
+func SummaryAgeBuckets(n int) SummaryOption {
+	return func(s *Summary) { s.s.ageBuckets = n }
+}
+
+// SummaryMaxSamples sets the maximum number of observations retained per age
+// bucket. Beyond this, observations are reservoir sampled. By default,
+// DefaultMaxSamples is used.
+func SummaryMaxSamples(n int) SummaryOption {
+	return func(s *Summary) { s.s.maxSamples = n }
+}
+
+// NewSummary returns a Summary with the given options.
+func NewSummary(name string, options ...SummaryOption) *Summary {
+	summary := &Summary{
+		Name: name,
+		s: &safeSummary{
+			objectives: DefaultObjectives,
+			maxAge:     DefaultMaxAge,
+			ageBuckets: DefaultAgeBuckets,
+			maxSamples: DefaultMaxSamples,
+			now:        time.Now,
+		},
+	}
+	for _, option := range options {
+		option(summary)
+	}
+	s := summary.s
+	if s.ageBuckets < 1 {
+		s.ageBuckets = 1
+	}
+	if s.maxSamples < 1 {
+		s.maxSamples = 1
+	}
+	s.buckets = make([]ageBucket, s.ageBuckets)
+	s.width = s.maxAge / time.Duration(s.ageBuckets)
+	s.start = s.now()
+	return summary
+}
+
+// With implements Histogram.
+func (s *Summary) With(labelValues ...string) metrics.Histogram {
+	return &Summary{
+		Name: s.Name,
+		lvs:  s.lvs.With(labelValues...),
+		s:    s.s,
+	}
+}
+
+// Observe implements Histogram.
+func (s *Summary) Observe(value float64) {
+	s.s.Lock()
+	defer s.s.Unlock()
+	s.s.rotate()
+	s.s.count++
+	s.s.sum += value
+	s.s.buckets[s.s.head].add(value, s.s.maxSamples)
+}
+
+// Reset discards all observations, including those counted by Count and Sum.
+// The summary's state is shared by every set of label values, so they're all
+// affected.
+func (s *Summary) Reset() {
+	s.s.Lock()
+	defer s.s.Unlock()
+	for i := range s.s.buckets {
+		s.s.buckets[i].reset()
+	}
+	s.s.count, s.s.sum = 0, 0
+}
+
+// Quantile returns the value of the quantile q, 0.0 < q < 1.0, over the
+// current window. It returns 0 if there are no observations in the window.
+func (s *Summary) Quantile(q float64) float64 {
+	s.s.Lock()
+	defer s.s.Unlock()
+	s.s.rotate()
+	return quantile(s.s.window(), q)
+}
+
+// Quantiles returns the value of each configured objective over the current
+// window.
+func (s *Summary) Quantiles() map[float64]float64 {
+	s.s.Lock()
+	defer s.s.Unlock()
+	s.s.rotate()
+	window := s.s.window()
+	quantiles := make(map[float64]float64, len(s.s.objectives))
+	for _, q := range s.s.objectives {
+		quantiles[q] = quantile(window, q)
+	}
+	return quantiles
+}
+
+// Objectives returns the configured quantiles, in ascending order.
+func (s *Summary) Objectives() []float64 {
+	return s.s.objectives
+}
+
+// Count returns the total number of observations. Like a Prometheus summary,
+// this isn't subject to the sliding window.
+func (s *Summary) Count() uint64 {
+	s.s.Lock()
+	defer s.s.Unlock()
+	return s.s.count
+}
+
+// Sum returns the sum of all observations. Like a Prometheus summary, this
+// isn't subject to the sliding window.
+func (s *Summary) Sum() float64 {
+	s.s.Lock()
+	defer s.s.Unlock()
+	return s.s.sum
+}
+
+// LabelValues returns the set of label values attached to the summary.
+func (s *Summary) LabelValues() []string {
+	return s.lvs
+}
+
+type safeSummary struct {
+	sync.Mutex
+	objectives []float64
+	maxAge     time.Duration
+	ageBuckets int
+	maxSamples int
+	now        func() time.Time
+
+	width   time.Duration
+	start   time.Time // of the head bucket
+	head    int
+	buckets []ageBucket
+	count   uint64
+	sum     float64
+}
+
+// rotate advances the head bucket, clearing expired buckets, until it covers
+// the current time.
+func (s *safeSummary) rotate() {
+	if s.width <= 0 {
+		return
+	}
+	n := int(s.now().Sub(s.start) / s.width)
+	if n <= 0 {
+		return
+	}
+	s.start = s.start.Add(time.Duration(n) * s.width)
+	if n > len(s.buckets) {
+		n = len(s.buckets)
+	}
+	for i := 0; i < n; i++ {
+		s.head = (s.head + 1) % len(s.buckets)
+		s.buckets[s.head].reset()
+	}
+}
+
+// window returns the sorted observations from every live bucket.
+func (s *safeSummary) window() []float64 {
+	var values []float64
+	for _, b := range s.buckets {
+		values = append(values, b.samples...)
+	}
+	sort.Float64s(values)
+	return values
+}
+
+type ageBucket struct {
+	samples []float64
+	seen    int
+}
+
+func (b *ageBucket) add(value float64, max int) {
+	b.seen++
+	if len(b.samples) < max {
+		b.samples = append(b.samples, value)
+		return
+	}
+	if i := rand.Intn(b.seen); i < max {
+		b.samples[i] = value
+	}
+}
+
+func (b *ageBucket) reset() {
+	b.samples = b.samples[:0]
+	b.seen = 0
+}
+
+// quantile returns the value at quantile q of the sorted values, using the
+// nearest-rank method.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
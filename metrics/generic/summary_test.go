@@ -0,0 +1,95 @@
+package generic_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/kit/metrics/teststat"
+)
+
+func TestSummary(t *testing.T) {
+	name := "my_summary"
+	summary := generic.NewSummary(name).With("label", "summary").(*generic.Summary)
+	if want, have := name, summary.Name; want != have {
+		t.Errorf("Name: want %q, have %q", want, have)
+	}
+	quantiles := func() (float64, float64, float64, float64) {
+		return summary.Quantile(0.50), summary.Quantile(0.90), summary.Quantile(0.95), summary.Quantile(0.99)
+	}
+	if err := teststat.TestHistogram(summary, quantiles, 0.01); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSummaryObjectives(t *testing.T) {
+	summary := generic.NewSummary("my_summary", generic.SummaryObjectives(0.99, 0.25, 0.75))
+	if want, have := []float64{0.25, 0.75, 0.99}, summary.Objectives(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Objectives: want %v, have %v", want, have)
+	}
+	for i := 1; i <= 100; i++ {
+		summary.Observe(float64(i))
+	}
+	if want, have := map[float64]float64{0.25: 25, 0.75: 75, 0.99: 99}, summary.Quantiles(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Quantiles: want %v, have %v", want, have)
+	}
+	if want, have := uint64(100), summary.Count(); want != have {
+		t.Errorf("Count: want %d, have %d", want, have)
+	}
+	if want, have := float64(5050), summary.Sum(); want != have {
+		t.Errorf("Sum: want %f, have %f", want, have)
+	}
+}
+
+func TestSummaryMaxAge(t *testing.T) {
+	summary := generic.NewSummary("my_summary",
+		generic.SummaryMaxAge(200*time.Millisecond),
+		generic.SummaryAgeBuckets(2),
+	)
+	summary.Observe(1000)
+	if want, have := float64(1000), summary.Quantile(0.5); want != have {
+		t.Errorf("before expiry: want %f, have %f", want, have)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	summary.Observe(1)
+	if want, have := float64(1), summary.Quantile(0.99); want != have {
+		t.Errorf("after expiry: want %f, have %f", want, have)
+	}
+	if want, have := uint64(2), summary.Count(); want != have {
+		t.Errorf("Count: want %d, have %d", want, have)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if want, have := float64(0), summary.Quantile(0.5); want != have {
+		t.Errorf("empty window: want %f, have %f", want, have)
+	}
+}
+
+func TestSummaryMaxSamples(t *testing.T) {
+	summary := generic.NewSummary("my_summary", generic.SummaryMaxSamples(10), generic.SummaryAgeBuckets(1))
+	for i := 0; i < 1000; i++ {
+		summary.Observe(float64(i))
+	}
+	if want, have := uint64(1000), summary.Count(); want != have {
+		t.Errorf("Count: want %d, have %d", want, have)
+	}
+	if have := summary.Quantile(0.5); have < 0 || have >= 1000 {
+		t.Errorf("Quantile: have %f, want a sampled observation", have)
+	}
+}
+
+func TestSummaryReset(t *testing.T) {
+	summary := generic.NewSummary("my_summary")
+	for i := 1; i <= 10; i++ {
+		summary.Observe(float64(i))
+	}
+	summary.Reset()
+	if want, have := uint64(0), summary.Count(); want != have {
+		t.Errorf("Count: want %d, have %d", want, have)
+	}
+	if want, have := 0.0, summary.Quantile(0.5); want != have {
+		t.Errorf("Quantile: want %f, have %f", want, have)
+	}
+}
@@ -1,6 +1,7 @@
 // Package graphite provides a Graphite backend for metrics. Metrics are batched
-// and emitted in the plaintext protocol. For more information, see
-// http://graphite.readthedocs.io/en/latest/feeding-carbon.html#the-plaintext-protocol
+// and emitted in the plaintext protocol, or optionally the pickle protocol. For
+// more information, see
+// http://graphite.readthedocs.io/en/latest/feeding-carbon.html
 //
 // Graphite does not have a native understanding of metric parameterization, so
 // label values not supported. Use distinct metrics for each unique combination
@@ -8,8 +9,11 @@
 package graphite
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"sync"
 	"time"
 
@@ -25,16 +29,21 @@ import (
 //
 // All metrics are buffered until WriteTo is called. Counters and gauges are
 // aggregated into a single observation per timeseries per write. Histograms are
-// exploded into per-quantile gauges and reported once per write.
+// exploded into per-quantile gauges and reported once per write. Windowed
+// histograms are additionally reduced to count, sum, min, and max, and reset
+// after every write.
 //
 // To regularly report metrics to an io.Writer, use the WriteLoop helper method.
-// To send to a Graphite server, use the SendLoop helper method.
+// To send to a Graphite server, use the SendLoop helper method. The pickle
+// protocol is supported by the corresponding WritePickleTo, WritePickleLoop,
+// and SendPickleLoop methods.
 type Graphite struct {
 	mtx        sync.RWMutex
 	prefix     string
 	counters   map[string]*Counter
 	gauges     map[string]*Gauge
 	histograms map[string]*Histogram
+	gaugeFuncs map[string]func() float64
 	logger     log.Logger
 }
 
@@ -47,6 +56,7 @@ func New(prefix string, logger log.Logger) *Graphite {
 		counters:   map[string]*Counter{},
 		gauges:     map[string]*Gauge{},
 		histograms: map[string]*Histogram{},
+		gaugeFuncs: map[string]func() float64{},
 		logger:     logger,
 	}
 }
@@ -71,6 +81,15 @@ func (g *Graphite) NewGauge(name string) *Gauge {
 	return ga
 }
 
+// NewGaugeFunc registers a gauge whose value is determined by calling f once
+// per write invocation. It's useful for values like queue depth, which are
+// cheaper to read on demand than to track continuously.
+func (g *Graphite) NewGaugeFunc(name string, f func() float64) {
+	g.mtx.Lock()
+	g.gaugeFuncs[g.prefix+name] = f
+	g.mtx.Unlock()
+}
+
 // NewHistogram returns a histogram. Observations are aggregated and emitted as
 // per-quantile gauges, once per write invocation. 50 is a good default value
 // for buckets.
@@ -82,6 +101,19 @@ func (g *Graphite) NewHistogram(name string, buckets int) *Histogram {
 	return h
 }
 
+// NewWindowedHistogram returns a histogram whose observations are aggregated
+// over each flush window, i.e. the time between writes. Every write emits the
+// count, sum, min, max, and quantiles of the observations made since the
+// previous write, as name.count, name.sum, name.min, name.max, and name.p50
+// etc. 50 is a good default value for buckets.
+func (g *Graphite) NewWindowedHistogram(name string, buckets int) *Histogram {
+	h := NewWindowedHistogram(g.prefix+name, buckets)
+	g.mtx.Lock()
+	g.histograms[g.prefix+name] = h
+	g.mtx.Unlock()
+	return h
+}
+
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
 // time the passed channel fires. This method blocks until the channel is
 // closed, so clients probably want to run it in its own goroutine. For typical
@@ -103,51 +135,150 @@ func (g *Graphite) SendLoop(c <-chan time.Time, network, address string) {
 	g.WriteLoop(c, conn.NewDefaultManager(network, address, g.logger))
 }
 
+// WritePickleLoop is like WriteLoop, but invokes WritePickleTo.
+func (g *Graphite) WritePickleLoop(c <-chan time.Time, w io.Writer) {
+	for range c {
+		if _, err := g.WritePickleTo(w); err != nil {
+			g.logger.Log("during", "WritePickleTo", "err", err)
+		}
+	}
+}
+
+// SendPickleLoop is like SendLoop, but speaks the pickle protocol. Note that
+// Carbon listens for pickled metrics on a different port than plaintext ones,
+// by default 2004.
+func (g *Graphite) SendPickleLoop(c <-chan time.Time, network, address string) {
+	g.WritePickleLoop(c, conn.NewDefaultManager(network, address, g.logger))
+}
+
 // WriteTo flushes the buffered content of the metrics to the writer, in
 // Graphite plaintext format. WriteTo abides best-effort semantics, so
 // observations are lost if there is a problem with the write. Clients should be
 // sure to call WriteTo regularly, ideally through the WriteLoop or SendLoop
 // helper methods.
 func (g *Graphite) WriteTo(w io.Writer) (count int64, err error) {
-	g.mtx.RLock()
-	defer g.mtx.RUnlock()
-	now := time.Now().Unix()
-
-	for name, c := range g.counters {
-		n, err := fmt.Fprintf(w, "%s %f %d\n", name, c.c.ValueReset(), now)
+	for _, p := range g.flush() {
+		n, err := fmt.Fprintf(w, "%s %f %d\n", p.name, p.value, p.timestamp)
 		if err != nil {
 			return count, err
 		}
 		count += int64(n)
 	}
+	return count, nil
+}
 
-	for name, ga := range g.gauges {
-		n, err := fmt.Fprintf(w, "%s %f %d\n", name, ga.g.Value(), now)
+// PickleBatchSize is the maximum number of data points sent in a single pickle
+// message. Carbon rejects overly large messages; this is well below its limit.
+const PickleBatchSize = 500
+
+// WritePickleTo flushes the buffered content of the metrics to the writer, in
+// Graphite pickle format. Data points are sent in batches of at most
+// PickleBatchSize, each with its own length header. Like WriteTo, it abides
+// best-effort semantics.
+func (g *Graphite) WritePickleTo(w io.Writer) (count int64, err error) {
+	points := g.flush()
+	for len(points) > 0 {
+		var batch []point
+		lim := PickleBatchSize
+		if len(points) < lim {
+			lim = len(points)
+		}
+		batch, points = points[:lim], points[lim:]
+		n, err := w.Write(pickle(batch))
+		count += int64(n)
 		if err != nil {
 			return count, err
 		}
-		count += int64(n)
+	}
+	return count, nil
+}
+
+// point is a single data point to be sent to Graphite.
+type point struct {
+	name      string
+	value     float64
+	timestamp int64
+}
+
+var quantiles = []struct {
+	s string
+	f float64
+}{
+	{"50", 0.50},
+	{"90", 0.90},
+	{"95", 0.95},
+	{"99", 0.99},
+}
+
+// flush collects the current data points of every metric, resetting counters
+// and windowed histograms.
+func (g *Graphite) flush() []point {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	now := time.Now().Unix()
+
+	var points []point
+	add := func(name string, value float64) {
+		points = append(points, point{name, value, now})
+	}
+
+	for name, c := range g.counters {
+		add(name, c.c.ValueReset())
+	}
+
+	for name, ga := range g.gauges {
+		add(name, ga.g.Value())
+	}
+
+	for name, f := range g.gaugeFuncs {
+		add(name, f())
 	}
 
 	for name, h := range g.histograms {
-		for _, p := range []struct {
-			s string
-			f float64
-		}{
-			{"50", 0.50},
-			{"90", 0.90},
-			{"95", 0.95},
-			{"99", 0.99},
-		} {
-			n, err := fmt.Fprintf(w, "%s.p%s %f %d\n", name, p.s, h.h.Quantile(p.f), now)
-			if err != nil {
-				return count, err
+		if !h.windowed {
+			for _, p := range quantiles {
+				add(fmt.Sprintf("%s.p%s", name, p.s), h.h.Quantile(p.f))
 			}
-			count += int64(n)
+			continue
+		}
+		w := h.reset()
+		if w.count == 0 {
+			add(name+".count", 0)
+			continue
 		}
+		add(name+".count", float64(w.count))
+		add(name+".sum", w.sum)
+		add(name+".min", w.min)
+		add(name+".max", w.max)
+		for _, p := range quantiles {
+			add(fmt.Sprintf("%s.p%s", name, p.s), w.h.Quantile(p.f))
+		}
+	}
+
+	return points
+}
+
+// pickle encodes the points as a length-prefixed list of (path, (timestamp,
+// value)) tuples, using pickle protocol 2.
+func pickle(points []point) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x80\x02") // PROTO 2
+	buf.WriteString("](")       // EMPTY_LIST, MARK
+	for _, p := range points {
+		buf.WriteByte('X') // BINUNICODE
+		binary.Write(&buf, binary.LittleEndian, uint32(len(p.name)))
+		buf.WriteString(p.name)
+		buf.WriteString("\x8a\x08") // LONG1, 8 bytes
+		binary.Write(&buf, binary.LittleEndian, p.timestamp)
+		buf.WriteByte('G') // BINFLOAT
+		binary.Write(&buf, binary.BigEndian, math.Float64bits(p.value))
+		buf.WriteString("\x86\x86") // TUPLE2, TUPLE2
 	}
+	buf.WriteString("e.") // APPENDS, STOP
 
-	return count, err
+	msg := make([]byte, 4, 4+buf.Len())
+	binary.BigEndian.PutUint32(msg, uint32(buf.Len()))
+	return append(msg, buf.Bytes()...)
 }
 
 // Counter is a Graphite counter metric.
@@ -189,15 +320,72 @@ func (g *Gauge) Add(delta float64) { g.g.Add(delta) }
 // per-quantile gauges.
 type Histogram struct {
 	h *generic.Histogram
+
+	windowed bool
+	buckets  int
+	mtx      sync.Mutex
+	w        window
 }
 
 // NewHistogram returns a new usable Histogram metric.
 func NewHistogram(name string, buckets int) *Histogram {
-	return &Histogram{generic.NewHistogram(name, buckets)}
+	return &Histogram{h: generic.NewHistogram(name, buckets)}
+}
+
+// NewWindowedHistogram returns a new usable Histogram metric, which aggregates
+// observations over each flush window.
+func NewWindowedHistogram(name string, buckets int) *Histogram {
+	return &Histogram{
+		h:        generic.NewHistogram(name, buckets),
+		windowed: true,
+		buckets:  buckets,
+		w:        newWindow(name, buckets),
+	}
 }
 
 // With is a no-op.
 func (h *Histogram) With(...string) metrics.Histogram { return h }
 
 // Observe implements histogram.
-func (h *Histogram) Observe(value float64) { h.h.Observe(value) }
+func (h *Histogram) Observe(value float64) {
+	if !h.windowed {
+		h.h.Observe(value)
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.w.observe(value)
+}
+
+// reset returns the current window, and starts a new one.
+func (h *Histogram) reset() window {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	w := h.w
+	h.w = newWindow(h.h.Name, h.buckets)
+	return w
+}
+
+// window aggregates the observations made during one flush window.
+type window struct {
+	count    int64
+	sum      float64
+	min, max float64
+	h        *generic.Histogram
+}
+
+func newWindow(name string, buckets int) window {
+	return window{h: generic.NewHistogram(name, buckets)}
+}
+
+func (w *window) observe(value float64) {
+	if w.count == 0 || value < w.min {
+		w.min = value
+	}
+	if w.count == 0 || value > w.max {
+		w.max = value
+	}
+	w.count++
+	w.sum += value
+	w.h.Observe(value)
+}
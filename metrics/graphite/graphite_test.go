@@ -2,8 +2,12 @@ package graphite
 
 import (
 	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/kit/log"
@@ -61,3 +65,145 @@ func TestHistogram(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestWindowedHistogram(t *testing.T) {
+	g := New("prefix.", log.NewNopLogger())
+	histogram := g.NewWindowedHistogram("latency", 50)
+	for _, v := range []float64{3, 1, 2} {
+		histogram.Observe(v)
+	}
+
+	values := func() map[string]float64 {
+		var buf bytes.Buffer
+		g.WriteTo(&buf)
+		m := map[string]float64{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			fields := strings.Fields(line)
+			f, _ := strconv.ParseFloat(fields[1], 64)
+			m[fields[0]] = f
+		}
+		return m
+	}
+
+	have := values()
+	for name, want := range map[string]float64{
+		"prefix.latency.count": 3,
+		"prefix.latency.sum":   6,
+		"prefix.latency.min":   1,
+		"prefix.latency.max":   3,
+		"prefix.latency.p50":   2,
+	} {
+		if have := have[name]; want != have {
+			t.Errorf("%s: want %f, have %f", name, want, have)
+		}
+	}
+
+	// The next window starts empty.
+	have = values()
+	if want, have := 1, len(have); want != have {
+		t.Errorf("want %d data point in the empty window, have %d: %v", want, have, have)
+	}
+	if want, have := 0.0, have["prefix.latency.count"]; want != have {
+		t.Errorf("want count %f, have %f", want, have)
+	}
+}
+
+func TestPickle(t *testing.T) {
+	g := New("pickle.", log.NewNopLogger())
+	g.NewCounter("requests").Add(12)
+	g.NewGauge("depth").Set(3.5)
+
+	var buf bytes.Buffer
+	if _, err := g.WritePickleTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	points := unpickle(t, buf.Bytes())
+	if want, have := 2, len(points); want != have {
+		t.Fatalf("want %d points, have %d", want, have)
+	}
+	for _, p := range points {
+		if p.timestamp == 0 {
+			t.Errorf("%s: missing timestamp", p.name)
+		}
+		switch p.name {
+		case "pickle.requests":
+			if want, have := 12.0, p.value; want != have {
+				t.Errorf("%s: want %f, have %f", p.name, want, have)
+			}
+		case "pickle.depth":
+			if want, have := 3.5, p.value; want != have {
+				t.Errorf("%s: want %f, have %f", p.name, want, have)
+			}
+		default:
+			t.Errorf("unexpected point %q", p.name)
+		}
+	}
+}
+
+func TestPickleBatches(t *testing.T) {
+	g := New("", log.NewNopLogger())
+	for i := 0; i < PickleBatchSize+1; i++ {
+		g.NewGauge(strconv.Itoa(i)).Set(float64(i))
+	}
+
+	var buf bytes.Buffer
+	g.WritePickleTo(&buf)
+
+	var sizes []int
+	for b := buf.Bytes(); len(b) > 0; {
+		n := int(binary.BigEndian.Uint32(b))
+		sizes = append(sizes, len(unpickle(t, b[:4+n])))
+		b = b[4+n:]
+	}
+	if want, have := []int{PickleBatchSize, 1}, sizes; !reflect.DeepEqual(want, have) {
+		t.Errorf("want batches of %v, have %v", want, have)
+	}
+}
+
+// unpickle decodes a single length-prefixed pickle message, as produced by
+// WritePickleTo. It only understands the opcodes which WritePickleTo uses.
+func unpickle(t *testing.T, b []byte) []point {
+	if want, have := len(b)-4, int(binary.BigEndian.Uint32(b)); want != have {
+		t.Fatalf("length header: want %d, have %d", want, have)
+	}
+	b = b[4:]
+	if !bytes.HasPrefix(b, []byte("\x80\x02](")) || !bytes.HasSuffix(b, []byte("e.")) {
+		t.Fatalf("unexpected pickle framing: %q", b)
+	}
+	b = b[4 : len(b)-2]
+
+	var points []point
+	for len(b) > 0 {
+		var p point
+		if b[0] != 'X' {
+			t.Fatalf("want BINUNICODE, have %q", b[0])
+		}
+		n := int(binary.LittleEndian.Uint32(b[1:]))
+		p.name, b = string(b[5:5+n]), b[5+n:]
+		if !bytes.HasPrefix(b, []byte("\x8a\x08")) {
+			t.Fatalf("want LONG1, have %q", b[:2])
+		}
+		p.timestamp, b = int64(binary.LittleEndian.Uint64(b[2:])), b[10:]
+		if b[0] != 'G' {
+			t.Fatalf("want BINFLOAT, have %q", b[0])
+		}
+		p.value, b = math.Float64frombits(binary.BigEndian.Uint64(b[1:])), b[9:]
+		if !bytes.HasPrefix(b, []byte("\x86\x86")) {
+			t.Fatalf("want TUPLE2 TUPLE2, have %q", b[:2])
+		}
+		b = b[2:]
+		points = append(points, p)
+	}
+	return points
+}
+
+func TestGaugeFunc(t *testing.T) {
+	g := New("graphite.", log.NewNopLogger())
+	g.NewGaugeFunc("depth", func() float64 { return 42 })
+	var buf bytes.Buffer
+	g.WriteTo(&buf)
+	if want, have := `^graphite\.depth 42\.000000 [0-9]+$`, strings.TrimSpace(buf.String()); !regexp.MustCompile(want).MatchString(have) {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
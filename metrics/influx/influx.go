@@ -1,6 +1,8 @@
 // Package influx provides an InfluxDB implementation for metrics. The model is
 // similar to other push-based instrumentation systems. Observations are
 // aggregated locally and emitted to the Influx server on regular intervals.
+// InfluxDB 1.x is supported via its client library, and 2.x via V2Writer.
+// Other systems accepting line protocol over HTTP are supported via LineWriter.
 package influx
 
 import (
@@ -0,0 +1,111 @@
+package influx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	influxdb "github.com/influxdata/influxdb/client/v2"
+)
+
+// LineWriter is a BatchPointsWriter which POSTs points in Influx line protocol
+// to an arbitrary HTTP endpoint. Many systems besides InfluxDB accept line
+// protocol, e.g. VictoriaMetrics at /write, or Telegraf's http_listener_v2
+// input, so it's useful for pushing metrics in environments without scrape
+// infrastructure. Use it with the WriteTo and WriteLoop methods of Influx.
+//
+// Timestamps are written in the precision of the batch. If the endpoint needs
+// to be told the precision, include it in the URL, e.g. ?precision=s. The
+// database, retention policy, and write consistency of the batch are ignored.
+type LineWriter struct {
+	url       string
+	client    *http.Client
+	header    http.Header
+	batchSize int
+	gzip      bool
+}
+
+// LineOption sets an optional parameter for LineWriters.
+type LineOption func(*LineWriter)
+
+// LineBatchSize sets the maximum number of points sent per request. By
+// default, batches of up to 5000 points are sent.
+func LineBatchSize(n int) LineOption {
+	return func(w *LineWriter) { w.batchSize = n }
+}
+
+// LineGzip compresses request bodies with gzip, and sets the Content-Encoding
+// header accordingly. By default, request bodies are uncompressed.
+func LineGzip() LineOption {
+	return func(w *LineWriter) { w.gzip = true }
+}
+
+// LineHeader sets a header sent with every request, e.g. Authorization.
+func LineHeader(key, value string) LineOption {
+	return func(w *LineWriter) { w.header.Set(key, value) }
+}
+
+// LineHTTPClient sets the HTTP client used to send requests. By default,
+// http.DefaultClient is used.
+func LineHTTPClient(client *http.Client) LineOption {
+	return func(w *LineWriter) { w.client = client }
+}
+
+// NewLineWriter returns a LineWriter which POSTs to the URL.
+func NewLineWriter(url string, options ...LineOption) *LineWriter {
+	w := &LineWriter{
+		url:       url,
+		client:    http.DefaultClient,
+		header:    http.Header{},
+		batchSize: 5000,
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Write implements BatchPointsWriter.
+func (w *LineWriter) Write(bp influxdb.BatchPoints) error {
+	return writeLines(bp.Points(), bp.Precision(), w.batchSize, w.send)
+}
+
+func (w *LineWriter) send(body []byte) error {
+	if w.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range w.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		buf, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("line protocol write: %s: %s", resp.Status, bytes.TrimSpace(buf))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package influx
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	influxdb "github.com/influxdata/influxdb/client/v2"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestLineWriter(t *testing.T) {
+	var (
+		bodies   []string
+		encoding []string
+		auth     []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = zr
+		}
+		buf, _ := ioutil.ReadAll(body)
+		bodies = append(bodies, string(buf))
+		encoding = append(encoding, r.Header.Get("Content-Encoding"))
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	in := New(map[string]string{"a": "b"}, influxdb.BatchPointsConfig{Precision: "s"}, log.NewNopLogger())
+	in.NewCounter("one").Add(1)
+	in.NewCounter("two").Add(2)
+	in.NewGauge("three").Set(3)
+
+	w := NewLineWriter(s.URL+"/write?precision=s", LineBatchSize(2), LineGzip(), LineHeader("Authorization", "Bearer t0ken"))
+	if err := in.WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 2, len(bodies); want != have {
+		t.Fatalf("want %d requests, have %d", want, have)
+	}
+	for i := range bodies {
+		if want, have := "gzip", encoding[i]; want != have {
+			t.Errorf("request %d: Content-Encoding: want %q, have %q", i, want, have)
+		}
+		if want, have := "Bearer t0ken", auth[i]; want != have {
+			t.Errorf("request %d: Authorization: want %q, have %q", i, want, have)
+		}
+	}
+	all := strings.Join(bodies, "")
+	for _, want := range []string{"one,a=b count=1 ", "two,a=b count=2 ", "three,a=b value=3 "} {
+		if !strings.Contains(all, want) {
+			t.Errorf("want line starting %q, have\n%s", want, all)
+		}
+	}
+	// Precision is seconds, so timestamps are 10 digits.
+	for _, line := range strings.Split(strings.TrimSpace(all), "\n") {
+		if fields := strings.Fields(line); len(fields[len(fields)-1]) != 10 {
+			t.Errorf("want a timestamp in seconds, have %q", line)
+		}
+	}
+}
+
+func TestLineWriterError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad line", http.StatusBadRequest)
+	}))
+	defer s.Close()
+
+	in := New(nil, influxdb.BatchPointsConfig{}, log.NewNopLogger())
+	in.NewCounter("one").Add(1)
+	err := in.WriteTo(NewLineWriter(s.URL))
+	if want := "line protocol write: 400 Bad Request: bad line"; err == nil || err.Error() != want {
+		t.Errorf("want %q, have %v", want, err)
+	}
+}
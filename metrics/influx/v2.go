@@ -0,0 +1,167 @@
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb/client/v2"
+)
+
+// V2Writer is a BatchPointsWriter which writes to the InfluxDB 2.x HTTP API,
+// authenticating with a token and writing to a bucket within an organization.
+// Use it in place of an InfluxDB 1.x client with the WriteTo and WriteLoop
+// methods of Influx. The database, retention policy, and write consistency of
+// the batch are ignored; only its precision is respected.
+//
+// Large batches are split into multiple requests. Requests which are rejected
+// with 429 Too Many Requests or 503 Service Unavailable are retried, honoring
+// the Retry-After header if the server provides one.
+type V2Writer struct {
+	url        string
+	token      string
+	org        string
+	bucket     string
+	client     *http.Client
+	batchSize  int
+	maxRetries int
+	backoff    time.Duration
+}
+
+// V2Option sets an optional parameter for V2Writers.
+type V2Option func(*V2Writer)
+
+// V2BatchSize sets the maximum number of points sent per write request. By
+// default, batches of up to 5000 points are sent, as recommended by InfluxData.
+func V2BatchSize(n int) V2Option {
+	return func(w *V2Writer) { w.batchSize = n }
+}
+
+// V2MaxRetries sets how many times a rate-limited request is retried before
+// the write fails. By default, requests are retried 3 times.
+func V2MaxRetries(n int) V2Option {
+	return func(w *V2Writer) { w.maxRetries = n }
+}
+
+// V2Backoff sets the initial delay between retries of a rate-limited request,
+// when the server doesn't specify one via Retry-After. The delay doubles after
+// every attempt. By default, it's 1 second.
+func V2Backoff(d time.Duration) V2Option {
+	return func(w *V2Writer) { w.backoff = d }
+}
+
+// V2HTTPClient sets the HTTP client used to send write requests. By default,
+// http.DefaultClient is used.
+func V2HTTPClient(client *http.Client) V2Option {
+	return func(w *V2Writer) { w.client = client }
+}
+
+// NewV2Writer returns a V2Writer for the InfluxDB 2.x server at the base URL,
+// e.g. http://localhost:8086.
+func NewV2Writer(url, token, org, bucket string, options ...V2Option) *V2Writer {
+	w := &V2Writer{
+		url:        strings.TrimSuffix(url, "/") + "/api/v2/write",
+		token:      token,
+		org:        org,
+		bucket:     bucket,
+		client:     http.DefaultClient,
+		batchSize:  5000,
+		maxRetries: 3,
+		backoff:    time.Second,
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Write implements BatchPointsWriter.
+func (w *V2Writer) Write(bp influxdb.BatchPoints) error {
+	precision := v2Precision(bp.Precision())
+	return writeLines(bp.Points(), precision, w.batchSize, func(body []byte) error {
+		return w.send(precision, body)
+	})
+}
+
+// writeLines encodes the points in line protocol, with timestamps in the given
+// precision, and passes them to send in batches of at most batchSize points.
+// A batchSize of zero or less sends every point in a single batch.
+func writeLines(points []*influxdb.Point, precision string, batchSize int, send func([]byte) error) error {
+	for len(points) > 0 {
+		var batch []*influxdb.Point
+		lim := len(points)
+		if batchSize > 0 && lim > batchSize {
+			lim = batchSize
+		}
+		batch, points = points[:lim], points[lim:]
+
+		var body bytes.Buffer
+		for _, p := range batch {
+			body.WriteString(p.PrecisionString(precision))
+			body.WriteByte('\n')
+		}
+		if err := send(body.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *V2Writer) send(precision string, body []byte) error {
+	params := url.Values{}
+	params.Set("org", w.org)
+	params.Set("bucket", w.bucket)
+	params.Set("precision", precision)
+
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("POST", w.url+"?"+params.Encode(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Token "+w.token)
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return err
+		}
+		buf, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode/100 == 2:
+			return nil
+		case (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < w.maxRetries:
+			delay := backoff
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+				delay = time.Duration(s) * time.Second
+			}
+			time.Sleep(delay)
+			backoff *= 2
+		default:
+			return fmt.Errorf("influx write: %s: %s", resp.Status, bytes.TrimSpace(buf))
+		}
+	}
+}
+
+// v2Precision maps an InfluxDB 1.x precision, which is a Go duration unit, to
+// one accepted by the 2.x API.
+func v2Precision(precision string) string {
+	switch precision {
+	case "ns", "us", "ms", "s":
+		return precision
+	case "u", "µ", "µs":
+		return "us"
+	case "m", "h":
+		return "s"
+	default:
+		return "ns"
+	}
+}
@@ -0,0 +1,103 @@
+package influx
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb/client/v2"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestV2Writer(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(buf))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	in := New(map[string]string{"a": "b"}, influxdb.BatchPointsConfig{Precision: "s"}, log.NewNopLogger())
+	in.NewCounter("one").Add(1)
+	in.NewCounter("two").Add(2)
+	in.NewGauge("three").Set(3)
+
+	w := NewV2Writer(s.URL, "s3cr3t", "my-org", "my-bucket", V2BatchSize(2))
+	if err := in.WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 2, len(requests); want != have {
+		t.Fatalf("want %d requests, have %d", want, have)
+	}
+	r := requests[0]
+	if want, have := "/api/v2/write", r.URL.Path; want != have {
+		t.Errorf("path: want %q, have %q", want, have)
+	}
+	if want, have := "bucket=my-bucket&org=my-org&precision=s", r.URL.RawQuery; want != have {
+		t.Errorf("query: want %q, have %q", want, have)
+	}
+	if want, have := "Token s3cr3t", r.Header.Get("Authorization"); want != have {
+		t.Errorf("Authorization: want %q, have %q", want, have)
+	}
+	if want, have := 3, strings.Count(strings.Join(bodies, ""), "\n"); want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+	for _, want := range []string{"one,a=b count=1 ", "two,a=b count=2 ", "three,a=b value=3 "} {
+		if !strings.Contains(strings.Join(bodies, ""), want) {
+			t.Errorf("want line starting %q, have\n%s", want, strings.Join(bodies, ""))
+		}
+	}
+}
+
+func TestV2WriterRetry(t *testing.T) {
+	var attempts int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	in := New(nil, influxdb.BatchPointsConfig{}, log.NewNopLogger())
+	in.NewGauge("g").Set(1)
+	if err := in.WriteTo(NewV2Writer(s.URL, "", "", "")); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
+
+func TestV2WriterRetriesExhausted(t *testing.T) {
+	var attempts int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer s.Close()
+
+	in := New(nil, influxdb.BatchPointsConfig{}, log.NewNopLogger())
+	in.NewGauge("g").Set(1)
+	err := in.WriteTo(NewV2Writer(s.URL, "", "", "", V2MaxRetries(2), V2Backoff(time.Millisecond)))
+	if err == nil {
+		t.Fatal("want error, have none")
+	}
+	if want, have := "influx write: 429 Too Many Requests: slow down", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 3, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
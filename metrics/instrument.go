@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type contextKey int
+
+const methodContextKey contextKey = iota
+
+// UnknownMethod is the method label value used by Instrument when the context
+// doesn't carry a method name.
+const UnknownMethod = "unknown"
+
+// ContextWithMethod returns a context carrying the name of the method being
+// invoked, which Instrument uses as the value of its "method" label.
+// Transports typically set it in a request function, e.g. a ServerBefore.
+func ContextWithMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodContextKey, method)
+}
+
+// MethodFromContext returns the method name stored by ContextWithMethod, if
+// any.
+func MethodFromContext(ctx context.Context) (string, bool) {
+	method, ok := ctx.Value(methodContextKey).(string)
+	return method, ok
+}
+
+// Instrument returns an endpoint middleware that records the rate, errors, and
+// duration of each invocation. Each metric is given a single label, "method",
+// taken from the context via MethodFromContext, or UnknownMethod if it's not
+// set. Requests is incremented for every invocation, errs for every invocation
+// returning a non-nil error, and duration observes the number of seconds each
+// invocation took. Any of the metrics may be nil, in which case it is skipped.
+func Instrument(requests Counter, duration Histogram, errs Counter) endpoint.Middleware {
+	return InstrumentBaggage(requests, duration, errs)
+}
+
+// InstrumentBaggage is like Instrument, but the metrics are also labeled with
+// the items of the keys of the endpoint.Baggage of the context, e.g. a tenant
+// ID, or "" for requests without them. Each combination of values is a
+// separate series, so the keys should be few, and their values bounded.
+func InstrumentBaggage(requests Counter, duration Histogram, errs Counter, keys ...string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			method, ok := MethodFromContext(ctx)
+			if !ok {
+				method = UnknownMethod
+			}
+			labelValues := []string{"method", method}
+			if len(keys) > 0 {
+				baggage := endpoint.BaggageFromContext(ctx)
+				for _, key := range keys {
+					labelValues = append(labelValues, key, baggage[key])
+				}
+			}
+			defer func(begin time.Time) {
+				if requests != nil {
+					requests.With(labelValues...).Add(1)
+				}
+				if errs != nil && err != nil {
+					errs.With(labelValues...).Add(1)
+				}
+				if duration != nil {
+					duration.With(labelValues...).Observe(time.Since(begin).Seconds())
+				}
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	var (
+		requests = newLabelCounter()
+		errs     = newLabelCounter()
+		duration = newLabelHistogram()
+		mw       = metrics.Instrument(requests, duration, errs)
+		fail     = errors.New("fail")
+		ep       = mw(func(_ context.Context, request interface{}) (interface{}, error) {
+			if request == "fail" {
+				return nil, fail
+			}
+			return request, nil
+		})
+	)
+
+	ctx := metrics.ContextWithMethod(context.Background(), "Sum")
+	for _, request := range []string{"ok", "ok", "fail"} {
+		ep(ctx, request)
+	}
+	if _, err := ep(context.Background(), "fail"); err != fail {
+		t.Errorf("want %v, have %v", fail, err)
+	}
+
+	if want, have := "[method=Sum:3 method=unknown:1]", requests.String(); want != have {
+		t.Errorf("requests: want %s, have %s", want, have)
+	}
+	if want, have := "[method=Sum:1 method=unknown:1]", errs.String(); want != have {
+		t.Errorf("errs: want %s, have %s", want, have)
+	}
+	if want, have := "[method=Sum:3 method=unknown:1]", duration.String(); want != have {
+		t.Errorf("duration: want %s, have %s", want, have)
+	}
+}
+
+func TestInstrumentBaggage(t *testing.T) {
+	var (
+		requests = newLabelCounter()
+		ep       = metrics.InstrumentBaggage(requests, nil, nil, "tenant")(endpoint.Nop)
+		ctx      = metrics.ContextWithMethod(context.Background(), "Sum")
+	)
+	ep(endpoint.ContextWithBaggageItem(ctx, "tenant", "acme"), struct{}{})
+	ep(ctx, struct{}{})
+
+	if want, have := "[method=Sumtenant=:1 method=Sumtenant=acme:1]", requests.String(); want != have {
+		t.Errorf("requests: want %s, have %s", want, have)
+	}
+}
+
+func TestInstrumentNilMetrics(t *testing.T) {
+	ep := metrics.Instrument(nil, nil, nil)(endpoint.Nop)
+	if _, err := ep(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMethodFromContext(t *testing.T) {
+	if _, ok := metrics.MethodFromContext(context.Background()); ok {
+		t.Errorf("want no method, have one")
+	}
+	method, ok := metrics.MethodFromContext(metrics.ContextWithMethod(context.Background(), "Concat"))
+	if want, have := "Concat", method; !ok || want != have {
+		t.Errorf("want %q, have %q (%v)", want, have, ok)
+	}
+}
+
+// labelCounts counts observations by label values.
+type labelCounts struct {
+	mtx    sync.Mutex
+	counts map[string]int
+}
+
+func (c *labelCounts) inc(labelValues []string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	key := ""
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		key += labelValues[i] + "=" + labelValues[i+1]
+	}
+	c.counts[key]++
+}
+
+func (c *labelCounts) String() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var s []string
+	for k, n := range c.counts {
+		s = append(s, fmt.Sprintf("%s:%d", k, n))
+	}
+	sort.Strings(s)
+	return fmt.Sprint(s)
+}
+
+type labelCounter struct {
+	*labelCounts
+	lvs []string
+}
+
+func newLabelCounter() labelCounter {
+	return labelCounter{labelCounts: &labelCounts{counts: map[string]int{}}}
+}
+
+func (c labelCounter) With(labelValues ...string) metrics.Counter {
+	return labelCounter{c.labelCounts, append(append([]string{}, c.lvs...), labelValues...)}
+}
+
+func (c labelCounter) Add(float64) { c.inc(c.lvs) }
+
+type labelHistogram struct {
+	*labelCounts
+	lvs []string
+}
+
+func newLabelHistogram() labelHistogram {
+	return labelHistogram{labelCounts: &labelCounts{counts: map[string]int{}}}
+}
+
+func (h labelHistogram) With(labelValues ...string) metrics.Histogram {
+	return labelHistogram{h.labelCounts, append(append([]string{}, h.lvs...), labelValues...)}
+}
+
+func (h labelHistogram) Observe(float64) { h.inc(h.lvs) }
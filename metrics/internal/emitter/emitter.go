@@ -0,0 +1,196 @@
+// Package emitter implements a buffered packet writer for line-oriented
+// protocols like StatsD and DogStatsD. Lines are packed into packets no larger
+// than a maximum size, and written by a background goroutine, so that a slow
+// or unavailable server doesn't block the caller.
+package emitter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// Defaults for an Emitter. The packet size keeps a UDP datagram within a
+// typical 1500 byte Ethernet MTU.
+const (
+	DefaultMaxPacketSize = 1432
+	DefaultFlushInterval = time.Second
+	DefaultQueueSize     = 1024
+)
+
+// ErrQueueFull is passed to the error handler when a packet is dropped because
+// the queue of packets waiting to be written is full.
+var ErrQueueFull = errors.New("emitter queue full; packet dropped")
+
+// ErrClosed is returned by Write after the Emitter has been closed.
+var ErrClosed = errors.New("emitter closed")
+
+// Config collects the parameters of an Emitter. Zero values are replaced with
+// the defaults.
+type Config struct {
+	MaxPacketSize int             // in bytes
+	FlushInterval time.Duration   // bounds the time a partial packet is held
+	QueueSize     int             // in packets
+	ErrorHandler  func(error)     // called with write errors and ErrQueueFull; mustn't write to the Emitter
+	DropCounter   metrics.Counter // incremented by the lines in each dropped packet
+}
+
+// Emitter is an io.Writer which packs lines into packets and writes them to
+// the underlying writer from a background goroutine.
+type Emitter struct {
+	w       io.Writer
+	cfg     Config
+	queue   chan packet
+	done    chan struct{}
+	wg      sync.WaitGroup
+	dropped uint64
+
+	mtx    sync.Mutex
+	buf    bytes.Buffer
+	lines  int
+	closed bool
+}
+
+type packet struct {
+	b     []byte
+	lines int
+}
+
+// New returns an Emitter writing to w, and starts its background goroutine.
+// Callers must Close the Emitter to release it.
+func New(w io.Writer, cfg Config) *Emitter {
+	if cfg.MaxPacketSize <= 0 {
+		cfg.MaxPacketSize = DefaultMaxPacketSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	e := &Emitter{
+		w:     w,
+		cfg:   cfg,
+		queue: make(chan packet, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+// Write implements io.Writer. The bytes are split into newline-terminated
+// lines, which are appended to the current packet. A packet is queued when the
+// next line wouldn't fit; lines longer than the maximum packet size are sent
+// in packets of their own. Write never blocks on the underlying writer.
+func (e *Emitter) Write(p []byte) (int, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.closed {
+		return 0, ErrClosed
+	}
+	n := len(p)
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+		}
+		p = p[len(line):]
+		if e.buf.Len() > 0 && e.buf.Len()+len(line) > e.cfg.MaxPacketSize {
+			e.enqueue()
+		}
+		e.buf.Write(line)
+		e.lines++
+	}
+	return n, nil
+}
+
+// Flush queues the current packet, if it's not empty.
+func (e *Emitter) Flush() error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.buf.Len() > 0 {
+		e.enqueue()
+	}
+	return nil
+}
+
+// Dropped returns the total number of lines dropped because the queue was
+// full.
+func (e *Emitter) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// Close flushes the current packet and waits for every queued packet to be
+// written. Subsequent writes return ErrClosed.
+func (e *Emitter) Close() error {
+	e.mtx.Lock()
+	if e.closed {
+		e.mtx.Unlock()
+		return nil
+	}
+	if e.buf.Len() > 0 {
+		e.enqueue()
+	}
+	e.closed = true
+	e.mtx.Unlock()
+	close(e.done)
+	e.wg.Wait()
+	return nil
+}
+
+// enqueue must be called with the mutex held.
+func (e *Emitter) enqueue() {
+	pkt := packet{b: append([]byte(nil), e.buf.Bytes()...), lines: e.lines}
+	e.buf.Reset()
+	e.lines = 0
+	select {
+	case e.queue <- pkt:
+	default:
+		atomic.AddUint64(&e.dropped, uint64(pkt.lines))
+		if e.cfg.DropCounter != nil {
+			e.cfg.DropCounter.Add(float64(pkt.lines))
+		}
+		e.fail(ErrQueueFull)
+	}
+}
+
+func (e *Emitter) loop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case pkt := <-e.queue:
+			e.write(pkt)
+		case <-ticker.C:
+			e.Flush()
+		case <-e.done:
+			for {
+				select {
+				case pkt := <-e.queue:
+					e.write(pkt)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *Emitter) write(pkt packet) {
+	if _, err := e.w.Write(pkt.b); err != nil {
+		e.fail(err)
+	}
+}
+
+func (e *Emitter) fail(err error) {
+	if e.cfg.ErrorHandler != nil {
+		e.cfg.ErrorHandler(err)
+	}
+}
@@ -0,0 +1,139 @@
+package emitter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+type packetRecorder struct {
+	mtx     sync.Mutex
+	packets []string
+	block   chan struct{}
+}
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.packets = append(r.packets, string(p))
+	return len(p), nil
+}
+
+func TestPacking(t *testing.T) {
+	r := &packetRecorder{}
+	e := New(r, Config{MaxPacketSize: 20})
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(e, "metric:%d|c\n", i) // 11 bytes
+	}
+	fmt.Fprintf(e, "a_very_long_metric_name:1|c\n")
+	e.Close()
+
+	if want, have := 11, len(r.packets); want != have {
+		t.Fatalf("want %d packets, have %d: %q", want, have, r.packets)
+	}
+	var lines int
+	for i, p := range r.packets[:10] {
+		if len(p) > 20 {
+			t.Errorf("packet %d: %d bytes exceeds maximum", i, len(p))
+		}
+		lines += strings.Count(p, "\n")
+	}
+	if want, have := 10, lines; want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+	if want, have := "a_very_long_metric_name:1|c\n", r.packets[10]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if _, err := e.Write([]byte("late:1|c\n")); err != ErrClosed {
+		t.Errorf("want %v, have %v", ErrClosed, err)
+	}
+}
+
+func TestDropOnFull(t *testing.T) {
+	var (
+		r       = &packetRecorder{block: make(chan struct{})}
+		dropped = generic.NewCounter("dropped")
+		errs    []error
+		e       = New(r, Config{
+			MaxPacketSize: 1,
+			FlushInterval: time.Hour,
+			QueueSize:     1,
+			ErrorHandler:  func(err error) { errs = append(errs, err) },
+			DropCounter:   dropped,
+		})
+	)
+	e.Write([]byte("a\n"))
+	e.Flush() // taken by the blocked writer, or queued
+	for i := 0; i < 5; i++ {
+		e.Write([]byte("b\n"))
+		e.Flush()
+	}
+	close(r.block)
+	e.Close()
+
+	written := len(r.packets)
+	if written < 1 || written > 2 {
+		t.Fatalf("want 1 or 2 packets written, have %d", written)
+	}
+	if want, have := uint64(6-written), e.Dropped(); want != have {
+		t.Errorf("Dropped: want %d, have %d", want, have)
+	}
+	if want, have := float64(6-written), dropped.Value(); want != have {
+		t.Errorf("drop counter: want %f, have %f", want, have)
+	}
+	if want, have := 6-written, len(errs); want != have {
+		t.Fatalf("want %d errors, have %d", want, have)
+	}
+	for _, err := range errs {
+		if err != ErrQueueFull {
+			t.Errorf("want %v, have %v", ErrQueueFull, err)
+		}
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("unavailable") }
+
+func TestWriteErrors(t *testing.T) {
+	var (
+		mtx  sync.Mutex
+		errs []string
+	)
+	e := New(failWriter{}, Config{ErrorHandler: func(err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		errs = append(errs, err.Error())
+	}})
+	e.Write([]byte("a:1|c\n"))
+	e.Close()
+	if want, have := []string{"unavailable"}, errs; len(have) != 1 || want[0] != have[0] {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	r := &packetRecorder{}
+	e := New(r, Config{FlushInterval: time.Millisecond})
+	defer e.Close()
+	e.Write([]byte("a:1|c\n"))
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		r.mtx.Lock()
+		n := len(r.packets)
+		r.mtx.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("partial packet wasn't flushed")
+}
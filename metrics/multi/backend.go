@@ -0,0 +1,209 @@
+package multi
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// ErrTimeout is passed to a backend's error handler when an observation
+// doesn't complete within the backend's timeout, and for every observation
+// discarded while a timed-out observation is still outstanding.
+var ErrTimeout = errors.New("metrics backend timed out")
+
+// ErrorHandler is called with the name of a backend and the error it
+// produced. Errors are either ErrTimeout, or wrap a panic raised by the
+// backend's metric.
+type ErrorHandler func(backend string, err error)
+
+// Backend isolates the metrics of a single instrumentation system from the
+// others it's combined with, so that one failing or blocking backend doesn't
+// break the rest. Metrics are wrapped individually, and the results may be
+// combined with NewCounter, NewGauge, and NewHistogram as usual.
+//
+//	prom := multi.NewBackend("prometheus")
+//	dog := multi.NewBackend("dogstatsd", multi.BackendTimeout(10*time.Millisecond))
+//	requests := multi.NewCounter(
+//	    prom.Counter(prometheus.NewCounterFrom(...)),
+//	    dog.Counter(d.NewCounter("requests", 1.0)),
+//	)
+//
+// A backend may be dropped at runtime, after which observations to any of its
+// metrics are discarded. Calls to With aren't subject to the timeout, but if
+// one panics, the resulting metric discards all observations.
+type Backend struct {
+	name         string
+	errorHandler ErrorHandler
+	timeout      time.Duration
+	dropped      int32
+	stalled      int32
+}
+
+// BackendOption sets an optional parameter for a Backend.
+type BackendOption func(*Backend)
+
+// BackendErrorHandler sets the function called when an observation to the
+// backend fails. By default, errors are ignored.
+func BackendErrorHandler(h ErrorHandler) BackendOption {
+	return func(b *Backend) { b.errorHandler = h }
+}
+
+// BackendTimeout bounds the time each observation to the backend may take. If
+// it's exceeded, the caller proceeds without waiting, and further observations
+// to the backend are discarded until the outstanding one completes. Each
+// observation is run in its own goroutine, so this should only be used for
+// backends that may block. By default, there is no timeout.
+func BackendTimeout(d time.Duration) BackendOption {
+	return func(b *Backend) { b.timeout = d }
+}
+
+// NewBackend returns a Backend with the given name, which is passed to its
+// error handler.
+func NewBackend(name string, options ...BackendOption) *Backend {
+	b := &Backend{name: name}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() string {
+	return b.name
+}
+
+// Drop causes observations to the backend's metrics to be discarded.
+func (b *Backend) Drop() {
+	atomic.StoreInt32(&b.dropped, 1)
+}
+
+// Restore undoes Drop.
+func (b *Backend) Restore() {
+	atomic.StoreInt32(&b.dropped, 0)
+}
+
+// Dropped returns true if the backend has been dropped.
+func (b *Backend) Dropped() bool {
+	return atomic.LoadInt32(&b.dropped) == 1
+}
+
+// Counter wraps the counter so that it's isolated by the backend.
+func (b *Backend) Counter(c metrics.Counter) metrics.Counter {
+	return &backendCounter{b: b, c: c}
+}
+
+// Gauge wraps the gauge so that it's isolated by the backend.
+func (b *Backend) Gauge(g metrics.Gauge) metrics.Gauge {
+	return &backendGauge{b: b, g: g}
+}
+
+// Histogram wraps the histogram so that it's isolated by the backend.
+func (b *Backend) Histogram(h metrics.Histogram) metrics.Histogram {
+	return &backendHistogram{b: b, h: h}
+}
+
+func (b *Backend) do(f func()) {
+	if b.Dropped() {
+		return
+	}
+	if b.timeout <= 0 {
+		b.call(f)
+		return
+	}
+	if atomic.LoadInt32(&b.stalled) > 0 {
+		b.fail(ErrTimeout)
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.call(f)
+	}()
+	select {
+	case <-done:
+	case <-time.After(b.timeout):
+		atomic.AddInt32(&b.stalled, 1)
+		go func() {
+			<-done
+			atomic.AddInt32(&b.stalled, -1)
+		}()
+		b.fail(ErrTimeout)
+	}
+}
+
+func (b *Backend) call(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.fail(fmt.Errorf("metrics backend panic: %v", r))
+		}
+	}()
+	f()
+}
+
+func (b *Backend) fail(err error) {
+	if b.errorHandler != nil {
+		b.errorHandler(b.name, err)
+	}
+}
+
+type backendCounter struct {
+	b *Backend
+	c metrics.Counter
+}
+
+func (c *backendCounter) With(labelValues ...string) metrics.Counter {
+	var next metrics.Counter
+	c.b.call(func() { next = c.c.With(labelValues...) })
+	if next == nil {
+		next = discard.NewCounter()
+	}
+	return &backendCounter{b: c.b, c: next}
+}
+
+func (c *backendCounter) Add(delta float64) {
+	c.b.do(func() { c.c.Add(delta) })
+}
+
+type backendGauge struct {
+	b *Backend
+	g metrics.Gauge
+}
+
+func (g *backendGauge) With(labelValues ...string) metrics.Gauge {
+	var next metrics.Gauge
+	g.b.call(func() { next = g.g.With(labelValues...) })
+	if next == nil {
+		next = discard.NewGauge()
+	}
+	return &backendGauge{b: g.b, g: next}
+}
+
+func (g *backendGauge) Set(value float64) {
+	g.b.do(func() { g.g.Set(value) })
+}
+
+func (g *backendGauge) Add(delta float64) {
+	g.b.do(func() { g.g.Add(delta) })
+}
+
+type backendHistogram struct {
+	b *Backend
+	h metrics.Histogram
+}
+
+func (h *backendHistogram) With(labelValues ...string) metrics.Histogram {
+	var next metrics.Histogram
+	h.b.call(func() { next = h.h.With(labelValues...) })
+	if next == nil {
+		next = discard.NewHistogram()
+	}
+	return &backendHistogram{b: h.b, h: next}
+}
+
+func (h *backendHistogram) Observe(value float64) {
+	h.b.do(func() { h.h.Observe(value) })
+}
@@ -0,0 +1,139 @@
+package multi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+func TestBackendPanic(t *testing.T) {
+	var (
+		mtx  sync.Mutex
+		errs []string
+	)
+	handler := func(backend string, err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		errs = append(errs, fmt.Sprintf("%s: %v", backend, err))
+	}
+	healthy := &mockCounter{}
+	b := NewBackend("broken", BackendErrorHandler(handler))
+	mc := NewCounter(b.Counter(panicCounter{}), healthy)
+
+	mc.Add(1)
+	mc.With("a", "b").Add(2)
+
+	if want, have := "[1 2]", healthy.String(); want != have {
+		t.Errorf("healthy: want %q, have %q", want, have)
+	}
+	want := []string{
+		"broken: metrics backend panic: Add",
+		"broken: metrics backend panic: With",
+	}
+	if have := errs; fmt.Sprint(want) != fmt.Sprint(have) {
+		t.Errorf("errors: want %q, have %q", want, have)
+	}
+}
+
+func TestBackendTimeout(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		timeouts int
+	)
+	handler := func(backend string, err error) {
+		if err != ErrTimeout {
+			t.Errorf("want ErrTimeout, have %v", err)
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		timeouts++
+	}
+	var (
+		release = make(chan struct{})
+		slow    = &blockingHistogram{release: release}
+		healthy = &mockHistogram{}
+		b       = NewBackend("slow", BackendTimeout(10*time.Millisecond), BackendErrorHandler(handler))
+		mh      = NewHistogram(b.Histogram(slow), healthy)
+	)
+
+	begin := time.Now()
+	mh.Observe(1) // blocks, times out
+	mh.Observe(2) // discarded, since the first is outstanding
+	if d := time.Since(begin); d > time.Second {
+		t.Errorf("observations took %s", d)
+	}
+	if want, have := "[1 2]", healthy.String(); want != have {
+		t.Errorf("healthy: want %q, have %q", want, have)
+	}
+	mtx.Lock()
+	if want, have := 2, timeouts; want != have {
+		t.Errorf("timeouts: want %d, have %d", want, have)
+	}
+	mtx.Unlock()
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		mh.Observe(3)
+		if slow.String() == "[1 3]" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slow backend never recovered: %s", slow.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBackendDrop(t *testing.T) {
+	var (
+		g1 = &mockGauge{}
+		g2 = &mockGauge{}
+		b  = NewBackend("dropped")
+		mg = NewGauge(b.Gauge(g1), g2)
+	)
+	mg.Set(1)
+	b.Drop()
+	if !b.Dropped() {
+		t.Errorf("want Dropped, have not")
+	}
+	mg.Set(2)
+	b.Restore()
+	mg.Add(3)
+
+	if want, have := "[1 4]", g1.String(); want != have {
+		t.Errorf("dropped: want %q, have %q", want, have)
+	}
+	if want, have := "[1 2 5]", g2.String(); want != have {
+		t.Errorf("other: want %q, have %q", want, have)
+	}
+}
+
+type panicCounter struct{}
+
+func (panicCounter) With(...string) metrics.Counter { panic("With") }
+func (panicCounter) Add(float64)                    { panic("Add") }
+
+type blockingHistogram struct {
+	release chan struct{}
+	mtx     sync.Mutex
+	obs     []float64
+}
+
+func (h *blockingHistogram) With(...string) metrics.Histogram { return h }
+
+func (h *blockingHistogram) Observe(value float64) {
+	<-h.release
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.obs = append(h.obs, value)
+}
+
+func (h *blockingHistogram) String() string {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return fmt.Sprintf("%v", h.obs)
+}
@@ -0,0 +1,395 @@
+// Package otlp provides an OpenTelemetry Protocol (OTLP) backend for package
+// metrics. Histograms are recorded with base-2 exponential buckets, and
+// exported as OTLP exponential histograms, which retain their accuracy over a
+// wide range of values without a hand-tuned list of bucket boundaries.
+//
+// Metrics are sent as JSON over HTTP to any OTLP/HTTP receiver, e.g. an
+// OpenTelemetry Collector at http://localhost:4318/v1/metrics, or Honeycomb.
+//
+//	o := otlp.New("https://api.honeycomb.io/v1/metrics", logger,
+//	    otlp.Header("x-honeycomb-team", apiKey),
+//	    otlp.ResourceAttribute("service.name", "addsvc"),
+//	)
+//	latency := o.NewHistogram("request_duration_seconds")
+//	go o.SendLoop(time.Tick(10 * time.Second))
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/kit/metrics/internal/lv"
+)
+
+// OTLP receives metrics observations and forwards them to an OTLP/HTTP
+// receiver. Create an OTLP object, use it to create metrics, and pass those
+// metrics as dependencies to the components that will use them.
+//
+// Histograms are aggregated in memory, and exported with cumulative
+// temporality, starting from the creation of the OTLP object. Label values
+// given to With are exported as data point attributes.
+//
+// To regularly report metrics, use the SendLoop helper method.
+type OTLP struct {
+	url      string
+	client   *http.Client
+	header   http.Header
+	resource []keyValue
+	logger   log.Logger
+	start    time.Time
+	now      func() time.Time
+
+	mtx        sync.RWMutex
+	histograms []*histogram
+}
+
+// Option sets an optional parameter for the OTLP object.
+type Option func(*OTLP)
+
+// Header sets a header sent with every request, e.g. an API key.
+func Header(key, value string) Option {
+	return func(o *OTLP) { o.header.Set(key, value) }
+}
+
+// HTTPClient sets the HTTP client used to send requests. By default,
+// http.DefaultClient is used.
+func HTTPClient(client *http.Client) Option {
+	return func(o *OTLP) { o.client = client }
+}
+
+// ResourceAttribute sets an attribute of the resource, i.e. the process,
+// which produces the metrics, e.g. service.name.
+func ResourceAttribute(key, value string) Option {
+	return func(o *OTLP) { o.resource = append(o.resource, attribute(key, value)) }
+}
+
+// New returns an OTLP object which sends to the URL. Callers must ensure that
+// regular calls to Send are performed, either manually or with the SendLoop
+// helper method.
+func New(url string, logger log.Logger, options ...Option) *OTLP {
+	o := &OTLP{
+		url:    url,
+		client: http.DefaultClient,
+		header: http.Header{},
+		logger: logger,
+		now:    time.Now,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	o.start = o.now()
+	return o
+}
+
+// NewHistogram returns a histogram with exponential buckets, configured by the
+// options. Each distinct set of label values is a separate data point.
+func (o *OTLP) NewHistogram(name string, options ...generic.ExponentialOption) *Histogram {
+	h := &histogram{
+		name:    name,
+		options: options,
+		series:  map[string]*generic.ExponentialHistogram{},
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.histograms = append(o.histograms, h)
+	return &Histogram{h: h}
+}
+
+// SendLoop is a helper method that invokes Send every time the passed channel
+// fires. This method blocks until the channel is closed, so clients probably
+// want to run it in its own goroutine. For typical usage, create a time.Ticker
+// and pass its C channel to this method.
+func (o *OTLP) SendLoop(c <-chan time.Time) {
+	for range c {
+		if err := o.Send(); err != nil {
+			o.logger.Log("during", "Send", "err", err)
+		}
+	}
+}
+
+// Send POSTs the current state of every metric to the receiver.
+func (o *OTLP) Send() error {
+	var buf bytes.Buffer
+	if _, err := o.WriteTo(&buf); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", o.url, &buf)
+	if err != nil {
+		return err
+	}
+	for k, v := range o.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp export: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// WriteTo writes the current state of every metric to the writer, as an OTLP
+// ExportMetricsServiceRequest in JSON.
+func (o *OTLP) WriteTo(w io.Writer) (int64, error) {
+	var (
+		start = strconv.FormatInt(o.start.UnixNano(), 10)
+		now   = strconv.FormatInt(o.now().UnixNano(), 10)
+		ms    []metric
+	)
+	o.mtx.RLock()
+	for _, h := range o.histograms {
+		if m, ok := h.metric(start, now); ok {
+			ms = append(ms, m)
+		}
+	}
+	o.mtx.RUnlock()
+
+	buf, err := json.Marshal(exportRequest{
+		ResourceMetrics: []resourceMetrics{{
+			Resource: resource{Attributes: o.resource},
+			ScopeMetrics: []scopeMetrics{{
+				Scope:   scope{Name: "github.com/go-kit/kit/metrics/otlp"},
+				Metrics: ms,
+			}},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// Histogram is an OTLP exponential histogram. Observations are aggregated per
+// set of label values.
+type Histogram struct {
+	h   *histogram
+	lvs lv.LabelValues
+}
+
+// With implements metrics.Histogram.
+func (h *Histogram) With(labelValues ...string) metrics.Histogram {
+	return &Histogram{
+		h:   h.h,
+		lvs: h.lvs.With(labelValues...),
+	}
+}
+
+// Observe implements metrics.Histogram.
+func (h *Histogram) Observe(value float64) {
+	h.h.get(h.lvs).Observe(value)
+}
+
+// Delete removes every data point whose attributes match the label values,
+// which are combined with any given to With, so that they're no longer
+// exported. Label values may identify a data point exactly, or only some of
+// its attributes, e.g. to remove all data points of a departed tenant. It
+// returns the number of data points removed.
+func (h *Histogram) Delete(labelValues ...string) int {
+	return h.h.delete(h.lvs.With(labelValues...))
+}
+
+// Reset removes every data point of the histogram.
+func (h *Histogram) Reset() {
+	h.h.mtx.Lock()
+	defer h.h.mtx.Unlock()
+	h.h.keys = nil
+	h.h.series = map[string]*generic.ExponentialHistogram{}
+}
+
+type histogram struct {
+	name    string
+	options []generic.ExponentialOption
+
+	mtx    sync.Mutex
+	keys   []string
+	series map[string]*generic.ExponentialHistogram
+}
+
+func (h *histogram) get(lvs lv.LabelValues) *generic.ExponentialHistogram {
+	key := strings.Join(lvs, "\x00")
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = generic.NewExponentialHistogram(h.name, h.options...).With(lvs...).(*generic.ExponentialHistogram)
+		h.keys = append(h.keys, key)
+		h.series[key] = s
+	}
+	return s
+}
+
+func (h *histogram) delete(match lv.LabelValues) int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	var (
+		keys    = h.keys[:0]
+		deleted int
+	)
+	for _, key := range h.keys {
+		if matches(h.series[key].LabelValues(), match) {
+			delete(h.series, key)
+			deleted++
+			continue
+		}
+		keys = append(keys, key)
+	}
+	h.keys = keys
+	return deleted
+}
+
+// matches returns true if every label and value pair of match is in lvs.
+func matches(lvs, match []string) bool {
+	for i := 0; i+1 < len(match); i += 2 {
+		var found bool
+		for j := 0; j+1 < len(lvs); j += 2 {
+			if lvs[j] == match[i] && lvs[j+1] == match[i+1] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *histogram) metric(start, now string) (metric, bool) {
+	h.mtx.Lock()
+	series := make([]*generic.ExponentialHistogram, len(h.keys))
+	for i, key := range h.keys {
+		series[i] = h.series[key]
+	}
+	h.mtx.Unlock()
+	if len(series) == 0 {
+		return metric{}, false
+	}
+
+	points := make([]dataPoint, len(series))
+	for i, s := range series {
+		snap := s.Snapshot()
+		lvs := s.LabelValues()
+		var attrs []keyValue
+		for j := 0; j+1 < len(lvs); j += 2 {
+			attrs = append(attrs, attribute(lvs[j], lvs[j+1]))
+		}
+		points[i] = dataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      now,
+			Count:             snap.Count,
+			Sum:               snap.Sum,
+			Scale:             snap.Scale,
+			ZeroCount:         snap.ZeroCount,
+			ZeroThreshold:     snap.ZeroThreshold,
+			Positive:          makeBuckets(snap.Positive),
+			Negative:          makeBuckets(snap.Negative),
+		}
+		if snap.Count > 0 {
+			points[i].Min, points[i].Max = &snap.Min, &snap.Max
+		}
+	}
+	return metric{
+		Name: h.name,
+		ExponentialHistogram: exponentialHistogram{
+			AggregationTemporality: aggregationTemporalityCumulative,
+			DataPoints:             points,
+		},
+	}, true
+}
+
+func makeBuckets(b generic.ExponentialBuckets) buckets {
+	counts := make([]string, len(b.Counts))
+	for i, c := range b.Counts {
+		counts[i] = strconv.FormatUint(c, 10)
+	}
+	return buckets{Offset: b.Offset, BucketCounts: counts}
+}
+
+func attribute(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: value}}
+}
+
+// The following types are the JSON encoding of the OTLP metrics protocol.
+// 64-bit integers are encoded as strings, per the protobuf JSON mapping.
+
+const aggregationTemporalityCumulative = 2
+
+type exportRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type metric struct {
+	Name                 string               `json:"name"`
+	ExponentialHistogram exponentialHistogram `json:"exponentialHistogram"`
+}
+
+type exponentialHistogram struct {
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	DataPoints             []dataPoint `json:"dataPoints"`
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             uint64     `json:"count,string"`
+	Sum               float64    `json:"sum"`
+	Scale             int32      `json:"scale"`
+	ZeroCount         uint64     `json:"zeroCount,string"`
+	ZeroThreshold     float64    `json:"zeroThreshold"`
+	Positive          buckets    `json:"positive"`
+	Negative          buckets    `json:"negative"`
+	Min               *float64   `json:"min,omitempty"`
+	Max               *float64   `json:"max,omitempty"`
+}
+
+type buckets struct {
+	Offset       int32    `json:"offset"`
+	BucketCounts []string `json:"bucketCounts"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
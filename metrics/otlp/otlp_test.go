@@ -0,0 +1,137 @@
+package otlp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestSend(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer s.Close()
+
+	o := New(s.URL, log.NewNopLogger(), Header("x-honeycomb-team", "key"), ResourceAttribute("service.name", "test"))
+	o.now = func() time.Time { return time.Unix(10, 0) }
+	o.start = time.Unix(1, 0)
+	h := o.NewHistogram("latency", generic.ExponentialMaxScale(0))
+	for _, v := range []float64{1, 2, 3, 4} {
+		h.With("method", "get").Observe(v)
+	}
+	h.With("method", "put").Observe(-3)
+	o.NewHistogram("unused")
+
+	if err := o.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "key", header.Get("x-honeycomb-team"); want != have {
+		t.Errorf("header: want %q, have %q", want, have)
+	}
+	if want, have := "application/json", header.Get("Content-Type"); want != have {
+		t.Errorf("content type: want %q, have %q", want, have)
+	}
+
+	var req exportRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	rm := req.ResourceMetrics[0]
+	if want, have := []keyValue{attribute("service.name", "test")}, rm.Resource.Attributes; !reflect.DeepEqual(want, have) {
+		t.Errorf("resource: want %v, have %v", want, have)
+	}
+	ms := rm.ScopeMetrics[0].Metrics
+	if want, have := 1, len(ms); want != have {
+		t.Fatalf("want %d metrics, have %d", want, have)
+	}
+	if want, have := "latency", ms[0].Name; want != have {
+		t.Errorf("name: want %q, have %q", want, have)
+	}
+	if want, have := aggregationTemporalityCumulative, ms[0].ExponentialHistogram.AggregationTemporality; want != have {
+		t.Errorf("temporality: want %d, have %d", want, have)
+	}
+	min, max := 1.0, 4.0
+	want := dataPoint{
+		Attributes:        []keyValue{attribute("method", "get")},
+		StartTimeUnixNano: "1000000000",
+		TimeUnixNano:      "10000000000",
+		Count:             4,
+		Sum:               10,
+		Positive:          buckets{Offset: -1, BucketCounts: []string{"1", "1", "2"}},
+		Negative:          buckets{BucketCounts: []string{}},
+		Min:               &min,
+		Max:               &max,
+	}
+	points := ms[0].ExponentialHistogram.DataPoints
+	if want, have := 2, len(points); want != have {
+		t.Fatalf("want %d data points, have %d", want, have)
+	}
+	if have := points[0]; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	if want, have := (buckets{Offset: 1, BucketCounts: []string{"1"}}), points[1].Negative; !reflect.DeepEqual(want, have) {
+		t.Errorf("negative: want %+v, have %+v", want, have)
+	}
+	if !strings.Contains(string(body), `"count":"4"`) {
+		t.Errorf("counts aren't encoded as strings: %s", body)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer s.Close()
+
+	o := New(s.URL, log.NewNopLogger())
+	o.NewHistogram("latency").Observe(1)
+	err := o.Send()
+	if err == nil {
+		t.Fatal("want error, have none")
+	}
+	if want, have := "otlp export: 401 Unauthorized: bad key", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	o := New("", log.NewNopLogger())
+	h := o.NewHistogram("latency")
+	h.With("tenant", "a", "instance", "1").Observe(1)
+	h.With("tenant", "a", "instance", "2").Observe(1)
+	h.With("tenant", "b", "instance", "1").Observe(1)
+
+	points := func() int {
+		m, _ := h.h.metric("", "")
+		return len(m.ExponentialHistogram.DataPoints)
+	}
+	if want, have := 2, h.Delete("tenant", "a"); want != have {
+		t.Errorf("partial match: want %d deleted, have %d", want, have)
+	}
+	if want, have := 1, points(); want != have {
+		t.Errorf("want %d data points, have %d", want, have)
+	}
+	if want, have := 1, h.With("tenant", "b").(*Histogram).Delete("instance", "1"); want != have {
+		t.Errorf("exact match via With: want %d deleted, have %d", want, have)
+	}
+
+	h.Observe(1)
+	h.With("tenant", "c").Observe(1)
+	h.Reset()
+	if want, have := 0, points(); want != have {
+		t.Errorf("after Reset: want %d data points, have %d", want, have)
+	}
+}
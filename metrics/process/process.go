@@ -0,0 +1,95 @@
+// Package process provides a collector which publishes statistics about the
+// current process through the generic metrics interfaces. It complements
+// package runtime, and is primarily useful with push-based backends which
+// don't collect these statistics natively.
+//
+// Not every statistic is available on every platform. On Linux, all are read
+// from /proc. On other Unix systems, CPU time and maximum file descriptors are
+// read via getrusage and getrlimit, and resident memory is the peak resident
+// set size. Elsewhere, only the start time is available. Metrics for
+// unavailable statistics are never updated.
+package process
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Collector periodically publishes process statistics. Any of its metrics may
+// be nil, in which case that statistic isn't collected.
+type Collector struct {
+	CPUSeconds     metrics.Counter // user and system CPU time
+	ResidentMemory metrics.Gauge   // bytes
+	VirtualMemory  metrics.Gauge   // bytes
+	OpenFDs        metrics.Gauge   // number of open file descriptors
+	MaxFDs         metrics.Gauge   // file descriptor limit
+	StartTime      metrics.Gauge   // seconds since the Unix epoch
+
+	cpu float64 // last observed CPU time, for computing deltas
+}
+
+// Run collects statistics every time the passed channel fires, logging any
+// errors to the logger. This method blocks until the channel is closed, so you
+// probably want to run it in its own goroutine. For typical usage, create a
+// time.Ticker and pass its C channel to this method.
+func (c *Collector) Run(ch <-chan time.Time, logger log.Logger) {
+	for range ch {
+		if err := c.Collect(); err != nil {
+			logger.Log("during", "Collect", "err", err)
+		}
+	}
+}
+
+// Collect reads the current process statistics and publishes them. It isn't
+// safe to call concurrently.
+func (c *Collector) Collect() error {
+	s, err := readStats()
+	if err != nil {
+		return err
+	}
+	if c.CPUSeconds != nil && s.cpu >= 0 {
+		if delta := s.cpu - c.cpu; delta > 0 {
+			c.CPUSeconds.Add(delta)
+		}
+		c.cpu = s.cpu
+	}
+	set := func(g metrics.Gauge, value float64) {
+		if g != nil && value >= 0 {
+			g.Set(value)
+		}
+	}
+	set(c.ResidentMemory, s.rss)
+	set(c.VirtualMemory, s.vsize)
+	set(c.OpenFDs, s.openFDs)
+	set(c.MaxFDs, s.maxFDs)
+	set(c.StartTime, s.start)
+	return nil
+}
+
+// stats are the statistics available on the current platform. Unavailable
+// statistics are negative.
+type stats struct {
+	cpu     float64
+	rss     float64
+	vsize   float64
+	openFDs float64
+	maxFDs  float64
+	start   float64
+}
+
+// processStart approximates the start time of the process on platforms which
+// don't provide it.
+var processStart = time.Now()
+
+func unavailable() stats {
+	return stats{
+		cpu:     -1,
+		rss:     -1,
+		vsize:   -1,
+		openFDs: -1,
+		maxFDs:  -1,
+		start:   float64(processStart.UnixNano()) / 1e9,
+	}
+}
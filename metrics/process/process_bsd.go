@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package process
+
+import (
+	"runtime"
+	"syscall"
+)
+
+func readStats() (stats, error) {
+	s := unavailable()
+
+	var rusage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &rusage); err != nil {
+		return s, err
+	}
+	s.cpu = float64(rusage.Utime.Nano()+rusage.Stime.Nano()) / 1e9
+	s.rss = float64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" {
+		s.rss *= 1024 // kilobytes everywhere but Darwin
+	}
+
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err == nil {
+		s.maxFDs = float64(rlimit.Cur)
+	}
+	return s, nil
+}
@@ -0,0 +1,83 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// userHZ is the unit of time in /proc/self/stat. It's fixed at 100 on all
+// common architectures, and isn't otherwise discoverable without cgo.
+const userHZ = 100
+
+func readStats() (stats, error) {
+	s := unavailable()
+
+	buf, err := ioutil.ReadFile("/proc/self/stat")
+	if err != nil {
+		return s, err
+	}
+	// The command name, field 2, is in parentheses and may contain spaces,
+	// so split after its closing parenthesis; fields are then numbered from 3.
+	i := bytes.LastIndexByte(buf, ')')
+	if i < 0 {
+		return s, fmt.Errorf("malformed /proc/self/stat")
+	}
+	fields := strings.Fields(string(buf[i+1:]))
+	field := func(n int) (float64, error) {
+		if n-3 >= len(fields) {
+			return 0, fmt.Errorf("/proc/self/stat: missing field %d", n)
+		}
+		return strconv.ParseFloat(fields[n-3], 64)
+	}
+	var utime, stime, starttime, vsize, rss float64
+	for _, f := range []struct {
+		n int
+		v *float64
+	}{
+		{14, &utime},
+		{15, &stime},
+		{22, &starttime},
+		{23, &vsize},
+		{24, &rss},
+	} {
+		if *f.v, err = field(f.n); err != nil {
+			return s, err
+		}
+	}
+	s.cpu = (utime + stime) / userHZ
+	s.vsize = vsize
+	s.rss = rss * float64(os.Getpagesize())
+
+	if boot, err := bootTime(); err == nil {
+		s.start = boot + starttime/userHZ
+	}
+
+	if fds, err := ioutil.ReadDir("/proc/self/fd"); err == nil {
+		s.openFDs = float64(len(fds))
+	}
+
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err == nil {
+		s.maxFDs = float64(rlimit.Cur)
+	}
+	return s, nil
+}
+
+// bootTime returns the system boot time, in seconds since the Unix epoch.
+func bootTime() (float64, error) {
+	buf, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			return strconv.ParseFloat(strings.TrimSpace(line[len("btime "):]), 64)
+		}
+	}
+	return 0, fmt.Errorf("/proc/stat: missing btime")
+}
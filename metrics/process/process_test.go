@@ -0,0 +1,53 @@
+package process
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestCollector(t *testing.T) {
+	c := &Collector{
+		CPUSeconds:     generic.NewCounter("cpu_seconds"),
+		ResidentMemory: generic.NewGauge("resident_memory"),
+		VirtualMemory:  generic.NewGauge("virtual_memory"),
+		OpenFDs:        generic.NewGauge("open_fds"),
+		MaxFDs:         generic.NewGauge("max_fds"),
+		StartTime:      generic.NewGauge("start_time"),
+	}
+	if err := c.Collect(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := c.StartTime.(*generic.Gauge).Value()
+	if now := float64(time.Now().Unix()); start <= 0 || start > now+1 {
+		t.Errorf("StartTime: want in (0, %f], have %f", now, start)
+	}
+	if runtime.GOOS != "linux" {
+		t.Skipf("remaining statistics aren't all available on %s", runtime.GOOS)
+	}
+
+	for name, g := range map[string]*generic.Gauge{
+		"ResidentMemory": c.ResidentMemory.(*generic.Gauge),
+		"VirtualMemory":  c.VirtualMemory.(*generic.Gauge),
+		"OpenFDs":        c.OpenFDs.(*generic.Gauge),
+		"MaxFDs":         c.MaxFDs.(*generic.Gauge),
+	} {
+		if have := g.Value(); have <= 0 {
+			t.Errorf("%s: want > 0, have %f", name, have)
+		}
+	}
+
+	// Burn some CPU, and check the counter only ever increases.
+	before := c.CPUSeconds.(*generic.Counter).Value()
+	for deadline := time.Now().Add(50 * time.Millisecond); time.Now().Before(deadline); {
+	}
+	if err := c.Collect(); err != nil {
+		t.Fatal(err)
+	}
+	if after := c.CPUSeconds.(*generic.Counter).Value(); after < before {
+		t.Errorf("CPUSeconds: want >= %f, have %f", before, after)
+	}
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ExemplarFunc extracts exemplar labels, typically a trace ID, from a context.
+// It should return nil if the context carries no exemplar.
+type ExemplarFunc func(ctx context.Context) prometheus.Labels
+
+// TraceIDExemplar returns an ExemplarFunc producing a single trace_id label
+// from the trace ID returned by f. Contexts for which f returns the empty
+// string yield no exemplar.
+func TraceIDExemplar(f func(ctx context.Context) string) ExemplarFunc {
+	return func(ctx context.Context) prometheus.Labels {
+		id := f(ctx)
+		if id == "" {
+			return nil
+		}
+		return prometheus.Labels{"trace_id": id}
+	}
+}
+
+// WithExemplarFunc returns a copy of the counter which uses f to extract
+// exemplars in AddContext.
+func (c *Counter) WithExemplarFunc(f ExemplarFunc) *Counter {
+	return &Counter{
+		cv:  c.cv,
+		lvs: c.lvs,
+		ef:  f,
+	}
+}
+
+// AddWithExemplar adds delta to the counter and attaches the exemplar labels
+// to the observation. Exemplars are only exposed when the registry is scraped
+// in the OpenMetrics format. A nil exemplar behaves like Add.
+func (c *Counter) AddWithExemplar(delta float64, exemplar prometheus.Labels) {
+	counter := c.cv.With(makeLabels(c.lvs...))
+	if ea, ok := counter.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		ea.AddWithExemplar(delta, exemplar)
+		return
+	}
+	counter.Add(delta)
+}
+
+// AddContext adds delta to the counter, attaching the exemplar extracted from
+// ctx by the counter's ExemplarFunc, if any.
+func (c *Counter) AddContext(ctx context.Context, delta float64) {
+	c.AddWithExemplar(delta, extract(ctx, c.ef))
+}
+
+// WithExemplarFunc returns a copy of the histogram which uses f to extract
+// exemplars in ObserveContext.
+func (h *Histogram) WithExemplarFunc(f ExemplarFunc) *Histogram {
+	return &Histogram{
+		hv:  h.hv,
+		lvs: h.lvs,
+		ef:  f,
+	}
+}
+
+// ObserveWithExemplar records the observation and attaches the exemplar labels
+// to the bucket it falls into. Exemplars are only exposed when the registry is
+// scraped in the OpenMetrics format. A nil exemplar behaves like Observe.
+func (h *Histogram) ObserveWithExemplar(value float64, exemplar prometheus.Labels) {
+	observer := h.hv.With(makeLabels(h.lvs...))
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(value, exemplar)
+		return
+	}
+	observer.Observe(value)
+}
+
+// ObserveContext records the observation, attaching the exemplar extracted
+// from ctx by the histogram's ExemplarFunc, if any.
+func (h *Histogram) ObserveContext(ctx context.Context, value float64) {
+	h.ObserveWithExemplar(value, extract(ctx, h.ef))
+}
+
+func extract(ctx context.Context, f ExemplarFunc) prometheus.Labels {
+	if f == nil || ctx == nil {
+		return nil
+	}
+	return f(ctx)
+}
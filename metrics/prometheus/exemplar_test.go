@@ -0,0 +1,109 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+var _ metrics.ContextObserver = (*Histogram)(nil)
+
+type traceIDKey struct{}
+
+func traceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+func TestCounterExemplar(t *testing.T) {
+	cv := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Namespace: "test",
+		Subsystem: "exemplar",
+		Name:      "counter",
+		Help:      "This is the help string.",
+	}, []string{"method"})
+	reg := stdprometheus.NewRegistry()
+	reg.MustRegister(cv)
+
+	counter := NewCounter(cv).WithExemplarFunc(TraceIDExemplar(traceID)).With("method", "Foo").(*Counter)
+	counter.AddContext(context.Background(), 1) // no exemplar
+	counter.AddContext(context.WithValue(context.Background(), traceIDKey{}, "abc123"), 2)
+
+	m := gatherOne(t, reg)
+	if want, have := 3.0, m.GetCounter().GetValue(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+	e := m.GetCounter().GetExemplar()
+	if e == nil {
+		t.Fatal("no exemplar recorded")
+	}
+	if want, have := 2.0, e.GetValue(); want != have {
+		t.Errorf("want exemplar value %f, have %f", want, have)
+	}
+	if want, have := "trace_id=abc123", labelString(e.GetLabel()); want != have {
+		t.Errorf("want exemplar labels %q, have %q", want, have)
+	}
+}
+
+func TestHistogramExemplar(t *testing.T) {
+	hv := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+		Namespace: "test",
+		Subsystem: "exemplar",
+		Name:      "histogram",
+		Help:      "This is the help string.",
+		Buckets:   []float64{1, 10},
+	}, []string{})
+	reg := stdprometheus.NewRegistry()
+	reg.MustRegister(hv)
+
+	histogram := NewHistogram(hv)
+	histogram.ObserveWithExemplar(5, stdprometheus.Labels{"trace_id": "def456"})
+	histogram.ObserveWithExemplar(0.5, nil)
+
+	m := gatherOne(t, reg)
+	if want, have := uint64(2), m.GetHistogram().GetSampleCount(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	for _, b := range m.GetHistogram().GetBucket() {
+		e := b.GetExemplar()
+		switch b.GetUpperBound() {
+		case 1:
+			if e != nil {
+				t.Errorf("bucket 1: want no exemplar, have %v", e)
+			}
+		case 10:
+			if e == nil {
+				t.Fatal("bucket 10: no exemplar recorded")
+			}
+			if want, have := "trace_id=def456", labelString(e.GetLabel()); want != have {
+				t.Errorf("want exemplar labels %q, have %q", want, have)
+			}
+		}
+	}
+}
+
+func gatherOne(t *testing.T, g stdprometheus.Gatherer) *dto.Metric {
+	mfs, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mfs) != 1 || len(mfs[0].GetMetric()) != 1 {
+		t.Fatalf("want 1 metric, have %v", mfs)
+	}
+	return mfs[0].GetMetric()[0]
+}
+
+func labelString(pairs []*dto.LabelPair) string {
+	var s string
+	for i, p := range pairs {
+		if i > 0 {
+			s += ","
+		}
+		s += p.GetName() + "=" + p.GetValue()
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNativeBucketFactor is the bucket growth factor used by native
+// histograms when no NativeBucketFactor option is given. Each bucket is at
+// most 10% wider than the previous one.
+const DefaultNativeBucketFactor = 1.1
+
+// NativeHistogramOption sets an optional parameter for native histograms.
+type NativeHistogramOption func(*prometheus.HistogramOpts)
+
+// NativeBucketFactor sets the upper bound for the growth factor from one
+// sparse bucket to the next. Values closer to 1 give better resolution at the
+// cost of more buckets. The factor must be greater than 1.
+func NativeBucketFactor(factor float64) NativeHistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.NativeHistogramBucketFactor = factor }
+}
+
+// NativeZeroThreshold sets the width of the zero bucket. Observations whose
+// absolute value is at or below the threshold are counted in the zero bucket.
+func NativeZeroThreshold(threshold float64) NativeHistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.NativeHistogramZeroThreshold = threshold }
+}
+
+// NativeMaxBucketNumber limits the number of populated sparse buckets. Once
+// the limit is exceeded, the histogram is reset or its resolution is reduced,
+// depending on NativeMinResetDuration. Zero means no limit.
+func NativeMaxBucketNumber(n uint32) NativeHistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.NativeHistogramMaxBucketNumber = n }
+}
+
+// NativeMinResetDuration sets the minimum time between resets of a histogram
+// which has exceeded its NativeMaxBucketNumber.
+func NativeMinResetDuration(d time.Duration) NativeHistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.NativeHistogramMinResetDuration = d }
+}
+
+// NewNativeHistogramFrom constructs and registers a Prometheus HistogramVec
+// using native (sparse, exponential) buckets, and returns a usable Histogram
+// object. Classic buckets are only maintained if opts.Buckets is set
+// explicitly. Native histograms are only exposed via the protobuf exposition
+// format, and require a Prometheus server with the feature enabled.
+func NewNativeHistogramFrom(opts prometheus.HistogramOpts, labelNames []string, options ...NativeHistogramOption) *Histogram {
+	return NewNativeHistogramIn(prometheus.DefaultRegisterer, opts, labelNames, options...)
+}
+
+// NewNativeHistogramIn is like NewNativeHistogramFrom, but registers the
+// HistogramVec with r.
+func NewNativeHistogramIn(r prometheus.Registerer, opts prometheus.HistogramOpts, labelNames []string, options ...NativeHistogramOption) *Histogram {
+	hv := NewNativeHistogramVec(opts, labelNames, options...)
+	r.MustRegister(hv)
+	return NewHistogram(hv)
+}
+
+// NewNativeHistogramVec constructs, but does not register, a Prometheus
+// HistogramVec using native buckets. Wrap the result with NewHistogram.
+func NewNativeHistogramVec(opts prometheus.HistogramOpts, labelNames []string, options ...NativeHistogramOption) *prometheus.HistogramVec {
+	opts.NativeHistogramBucketFactor = DefaultNativeBucketFactor
+	for _, option := range options {
+		option(&opts)
+	}
+	return prometheus.NewHistogramVec(opts, labelNames)
+}
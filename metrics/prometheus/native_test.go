@@ -0,0 +1,38 @@
+package prometheus
+
+import (
+	"testing"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/metrics/teststat"
+)
+
+func TestNativeHistogram(t *testing.T) {
+	hv := NewNativeHistogramVec(stdprometheus.HistogramOpts{
+		Namespace: "test",
+		Subsystem: "prometheus",
+		Name:      "native_histogram",
+		Help:      "This is the help string for the native histogram.",
+	}, []string{"x"}, NativeBucketFactor(1.01), NativeMaxBucketNumber(1000))
+	reg := stdprometheus.NewRegistry()
+	reg.MustRegister(hv)
+
+	histogram := NewHistogram(hv).With("x", "1")
+	teststat.PopulateNormalHistogram(histogram, 123)
+
+	h := gatherOne(t, reg).GetHistogram()
+	if want, have := uint64(teststat.Count), h.GetSampleCount(); want != have {
+		t.Errorf("sample count: want %d, have %d", want, have)
+	}
+	if len(h.GetBucket()) != 0 {
+		t.Errorf("want no classic buckets, have %d", len(h.GetBucket()))
+	}
+	// The largest factor no greater than 1.01 is 2^(2^-7), i.e. schema 7.
+	if want, have := int32(7), h.GetSchema(); want != have {
+		t.Errorf("schema: want %d, have %d", want, have)
+	}
+	if len(h.GetPositiveSpan()) == 0 {
+		t.Error("want positive spans, have none")
+	}
+}
@@ -1,7 +1,9 @@
 // Package prometheus provides Prometheus implementations for metrics.
 // Individual metrics are mapped to their Prometheus counterparts, and
 // (depending on the constructor used) may be automatically registered in the
-// global Prometheus metrics registry.
+// global Prometheus metrics registry. Constructors with the In suffix register
+// with a user-supplied Registerer instead, which is useful for binaries hosting
+// several independent registries, and for tests.
 package prometheus
 
 import (
@@ -15,13 +17,20 @@ import (
 type Counter struct {
 	cv  *prometheus.CounterVec
 	lvs lv.LabelValues
+	ef  ExemplarFunc
 }
 
 // NewCounterFrom constructs and registers a Prometheus CounterVec,
 // and returns a usable Counter object.
 func NewCounterFrom(opts prometheus.CounterOpts, labelNames []string) *Counter {
+	return NewCounterIn(prometheus.DefaultRegisterer, opts, labelNames)
+}
+
+// NewCounterIn constructs a Prometheus CounterVec, registers it with r,
+// and returns a usable Counter object.
+func NewCounterIn(r prometheus.Registerer, opts prometheus.CounterOpts, labelNames []string) *Counter {
 	cv := prometheus.NewCounterVec(opts, labelNames)
-	prometheus.MustRegister(cv)
+	r.MustRegister(cv)
 	return NewCounter(cv)
 }
 
@@ -32,11 +41,32 @@ func NewCounter(cv *prometheus.CounterVec) *Counter {
 	}
 }
 
+// Vec returns the underlying CounterVec, e.g. to unregister it, or to
+// collect it directly.
+func (c *Counter) Vec() *prometheus.CounterVec {
+	return c.cv
+}
+
+// Delete removes every timeseries whose labels match the label values, which
+// are combined with any given to With, so that they're no longer exported.
+// Label values may identify a timeseries exactly, or only some of its labels,
+// e.g. to remove all timeseries of a departed tenant. It returns the number of
+// timeseries removed.
+func (c *Counter) Delete(labelValues ...string) int {
+	return c.cv.DeletePartialMatch(makeLabels(c.lvs.With(labelValues...)...))
+}
+
+// Reset removes every timeseries of the metric.
+func (c *Counter) Reset() {
+	c.cv.Reset()
+}
+
 // With implements Counter.
 func (c *Counter) With(labelValues ...string) metrics.Counter {
 	return &Counter{
 		cv:  c.cv,
 		lvs: c.lvs.With(labelValues...),
+		ef:  c.ef,
 	}
 }
 
@@ -54,8 +84,14 @@ type Gauge struct {
 // NewGaugeFrom construts and registers a Prometheus GaugeVec,
 // and returns a usable Gauge object.
 func NewGaugeFrom(opts prometheus.GaugeOpts, labelNames []string) *Gauge {
+	return NewGaugeIn(prometheus.DefaultRegisterer, opts, labelNames)
+}
+
+// NewGaugeIn constructs a Prometheus GaugeVec, registers it with r,
+// and returns a usable Gauge object.
+func NewGaugeIn(r prometheus.Registerer, opts prometheus.GaugeOpts, labelNames []string) *Gauge {
 	gv := prometheus.NewGaugeVec(opts, labelNames)
-	prometheus.MustRegister(gv)
+	r.MustRegister(gv)
 	return NewGauge(gv)
 }
 
@@ -66,6 +102,26 @@ func NewGauge(gv *prometheus.GaugeVec) *Gauge {
 	}
 }
 
+// Vec returns the underlying GaugeVec, e.g. to unregister it, or to
+// collect it directly.
+func (g *Gauge) Vec() *prometheus.GaugeVec {
+	return g.gv
+}
+
+// Delete removes every timeseries whose labels match the label values, which
+// are combined with any given to With, so that they're no longer exported.
+// Label values may identify a timeseries exactly, or only some of its labels,
+// e.g. to remove all timeseries of a departed tenant. It returns the number of
+// timeseries removed.
+func (g *Gauge) Delete(labelValues ...string) int {
+	return g.gv.DeletePartialMatch(makeLabels(g.lvs.With(labelValues...)...))
+}
+
+// Reset removes every timeseries of the metric.
+func (g *Gauge) Reset() {
+	g.gv.Reset()
+}
+
 // With implements Gauge.
 func (g *Gauge) With(labelValues ...string) metrics.Gauge {
 	return &Gauge{
@@ -84,6 +140,21 @@ func (g *Gauge) Add(delta float64) {
 	g.gv.With(makeLabels(g.lvs...)).Add(delta)
 }
 
+// NewGaugeFuncFrom constructs and registers a Prometheus GaugeFunc, whose value
+// is determined by calling f every time the registry is scraped. It's useful
+// for values like queue depth, which are cheaper to read on demand than to
+// track continuously. f must be safe for concurrent use.
+func NewGaugeFuncFrom(opts prometheus.GaugeOpts, f func() float64) prometheus.GaugeFunc {
+	return NewGaugeFuncIn(prometheus.DefaultRegisterer, opts, f)
+}
+
+// NewGaugeFuncIn is like NewGaugeFuncFrom, but registers the GaugeFunc with r.
+func NewGaugeFuncIn(r prometheus.Registerer, opts prometheus.GaugeOpts, f func() float64) prometheus.GaugeFunc {
+	gf := prometheus.NewGaugeFunc(opts, f)
+	r.MustRegister(gf)
+	return gf
+}
+
 // Summary implements Histogram, via a Prometheus SummaryVec. The difference
 // between a Summary and a Histogram is that Summaries don't require predefined
 // quantile buckets, but cannot be statistically aggregated.
@@ -95,8 +166,14 @@ type Summary struct {
 // NewSummaryFrom constructs and registers a Prometheus SummaryVec,
 // and returns a usable Summary object.
 func NewSummaryFrom(opts prometheus.SummaryOpts, labelNames []string) *Summary {
+	return NewSummaryIn(prometheus.DefaultRegisterer, opts, labelNames)
+}
+
+// NewSummaryIn constructs a Prometheus SummaryVec, registers it with r,
+// and returns a usable Summary object.
+func NewSummaryIn(r prometheus.Registerer, opts prometheus.SummaryOpts, labelNames []string) *Summary {
 	sv := prometheus.NewSummaryVec(opts, labelNames)
-	prometheus.MustRegister(sv)
+	r.MustRegister(sv)
 	return NewSummary(sv)
 }
 
@@ -107,6 +184,26 @@ func NewSummary(sv *prometheus.SummaryVec) *Summary {
 	}
 }
 
+// Vec returns the underlying SummaryVec, e.g. to unregister it, or to
+// collect it directly.
+func (s *Summary) Vec() *prometheus.SummaryVec {
+	return s.sv
+}
+
+// Delete removes every timeseries whose labels match the label values, which
+// are combined with any given to With, so that they're no longer exported.
+// Label values may identify a timeseries exactly, or only some of its labels,
+// e.g. to remove all timeseries of a departed tenant. It returns the number of
+// timeseries removed.
+func (s *Summary) Delete(labelValues ...string) int {
+	return s.sv.DeletePartialMatch(makeLabels(s.lvs.With(labelValues...)...))
+}
+
+// Reset removes every timeseries of the metric.
+func (s *Summary) Reset() {
+	s.sv.Reset()
+}
+
 // With implements Histogram.
 func (s *Summary) With(labelValues ...string) metrics.Histogram {
 	return &Summary{
@@ -126,13 +223,20 @@ func (s *Summary) Observe(value float64) {
 type Histogram struct {
 	hv  *prometheus.HistogramVec
 	lvs lv.LabelValues
+	ef  ExemplarFunc
 }
 
 // NewHistogramFrom constructs and registers a Prometheus HistogramVec,
 // and returns a usable Histogram object.
 func NewHistogramFrom(opts prometheus.HistogramOpts, labelNames []string) *Histogram {
+	return NewHistogramIn(prometheus.DefaultRegisterer, opts, labelNames)
+}
+
+// NewHistogramIn constructs a Prometheus HistogramVec, registers it with r,
+// and returns a usable Histogram object.
+func NewHistogramIn(r prometheus.Registerer, opts prometheus.HistogramOpts, labelNames []string) *Histogram {
 	hv := prometheus.NewHistogramVec(opts, labelNames)
-	prometheus.MustRegister(hv)
+	r.MustRegister(hv)
 	return NewHistogram(hv)
 }
 
@@ -143,11 +247,32 @@ func NewHistogram(hv *prometheus.HistogramVec) *Histogram {
 	}
 }
 
+// Vec returns the underlying HistogramVec, e.g. to unregister it, or to
+// collect it directly.
+func (h *Histogram) Vec() *prometheus.HistogramVec {
+	return h.hv
+}
+
+// Delete removes every timeseries whose labels match the label values, which
+// are combined with any given to With, so that they're no longer exported.
+// Label values may identify a timeseries exactly, or only some of its labels,
+// e.g. to remove all timeseries of a departed tenant. It returns the number of
+// timeseries removed.
+func (h *Histogram) Delete(labelValues ...string) int {
+	return h.hv.DeletePartialMatch(makeLabels(h.lvs.With(labelValues...)...))
+}
+
+// Reset removes every timeseries of the metric.
+func (h *Histogram) Reset() {
+	h.hv.Reset()
+}
+
 // With implements Histogram.
 func (h *Histogram) With(labelValues ...string) metrics.Histogram {
 	return &Histogram{
 		hv:  h.hv,
 		lvs: h.lvs.With(labelValues...),
+		ef:  h.ef,
 	}
 }
 
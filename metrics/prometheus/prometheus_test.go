@@ -212,3 +212,154 @@ func TestInconsistentLabelCardinality(t *testing.T) {
 		"a", "1", "b", "2", "c", "KABOOM!",
 	).Add(123)
 }
+
+func TestGaugeFunc(t *testing.T) {
+	depth := 0.0
+	NewGaugeFuncFrom(stdprometheus.GaugeOpts{
+		Namespace: "test",
+		Subsystem: "prometheus",
+		Name:      "gauge_func",
+		Help:      "This is the help string for the gauge func.",
+	}, func() float64 { depth++; return depth })
+
+	value := func() float64 {
+		mfs, err := stdprometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() == "test_prometheus_gauge_func" {
+				return mf.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		t.Fatal("gauge func not registered")
+		return 0
+	}
+	for _, want := range []float64{1, 2} {
+		if have := value(); want != have {
+			t.Errorf("want %f, have %f", want, have)
+		}
+	}
+}
+
+func TestCustomRegisterer(t *testing.T) {
+	opts := func(name string) (string, string, string) { return "test", "registry", name }
+	for _, tc := range []struct {
+		name    string
+		observe func(r *stdprometheus.Registry)
+	}{
+		{"counter", func(r *stdprometheus.Registry) {
+			ns, ss, n := opts("counter")
+			NewCounterIn(r, stdprometheus.CounterOpts{Namespace: ns, Subsystem: ss, Name: n, Help: n}, []string{"a"}).With("a", "1").Add(1)
+		}},
+		{"gauge", func(r *stdprometheus.Registry) {
+			ns, ss, n := opts("gauge")
+			NewGaugeIn(r, stdprometheus.GaugeOpts{Namespace: ns, Subsystem: ss, Name: n, Help: n}, []string{"a"}).With("a", "1").Set(1)
+		}},
+		{"gauge_func", func(r *stdprometheus.Registry) {
+			ns, ss, n := opts("gauge_func")
+			NewGaugeFuncIn(r, stdprometheus.GaugeOpts{Namespace: ns, Subsystem: ss, Name: n, Help: n}, func() float64 { return 1 })
+		}},
+		{"summary", func(r *stdprometheus.Registry) {
+			ns, ss, n := opts("summary")
+			NewSummaryIn(r, stdprometheus.SummaryOpts{Namespace: ns, Subsystem: ss, Name: n, Help: n}, []string{"a"}).With("a", "1").Observe(1)
+		}},
+		{"histogram", func(r *stdprometheus.Registry) {
+			ns, ss, n := opts("histogram")
+			NewHistogramIn(r, stdprometheus.HistogramOpts{Namespace: ns, Subsystem: ss, Name: n, Help: n}, []string{"a"}).With("a", "1").Observe(1)
+		}},
+		{"native_histogram", func(r *stdprometheus.Registry) {
+			ns, ss, n := opts("native_histogram")
+			NewNativeHistogramIn(r, stdprometheus.HistogramOpts{Namespace: ns, Subsystem: ss, Name: n, Help: n}, []string{"a"}).With("a", "1").Observe(1)
+		}},
+	} {
+		// Each registry is independent, so registering the same metric in
+		// two of them doesn't conflict.
+		for i := 0; i < 2; i++ {
+			r := stdprometheus.NewRegistry()
+			tc.observe(r)
+			gatherOne(t, r)
+		}
+
+		mfs, err := stdprometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range mfs {
+			if mf.GetName() == "test_registry_"+tc.name {
+				t.Errorf("%s: registered with the default registry", tc.name)
+			}
+		}
+	}
+}
+
+func TestVec(t *testing.T) {
+	r := stdprometheus.NewRegistry()
+	c := NewCounterIn(r, stdprometheus.CounterOpts{Name: "vec_counter", Help: "."}, []string{"a"})
+	c.With("a", "1").Add(2)
+	if want, have := 2.0, gatherOne(t, r).GetCounter().GetValue(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+	if !r.Unregister(c.Vec()) {
+		t.Errorf("Unregister via Vec failed")
+	}
+
+	var (
+		g = NewGauge(stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{Name: "g", Help: "."}, nil))
+		s = NewSummary(stdprometheus.NewSummaryVec(stdprometheus.SummaryOpts{Name: "s", Help: "."}, nil))
+		h = NewHistogram(stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{Name: "h", Help: "."}, nil))
+	)
+	if g.Vec() == nil || s.Vec() == nil || h.Vec() == nil {
+		t.Errorf("want non-nil vecs")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	r := stdprometheus.NewRegistry()
+	c := NewCounterIn(r, stdprometheus.CounterOpts{Name: "delete_counter", Help: "."}, []string{"tenant", "instance"})
+	c.With("tenant", "a", "instance", "1").Add(1)
+	c.With("tenant", "a", "instance", "2").Add(1)
+	c.With("tenant", "b", "instance", "1").Add(1)
+
+	series := func() int {
+		mfs, err := r.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		for _, mf := range mfs {
+			n += len(mf.GetMetric())
+		}
+		return n
+	}
+	if want, have := 2, c.Delete("tenant", "a"); want != have {
+		t.Errorf("partial match: want %d deleted, have %d", want, have)
+	}
+	if want, have := 1, series(); want != have {
+		t.Errorf("want %d timeseries, have %d", want, have)
+	}
+	if want, have := 1, c.With("tenant", "b").(*Counter).Delete("instance", "1"); want != have {
+		t.Errorf("exact match via With: want %d deleted, have %d", want, have)
+	}
+	if want, have := 0, c.Delete("tenant", "c"); want != have {
+		t.Errorf("no match: want %d deleted, have %d", want, have)
+	}
+
+	g := NewGaugeIn(r, stdprometheus.GaugeOpts{Name: "delete_gauge", Help: "."}, []string{"a"})
+	s := NewSummaryIn(r, stdprometheus.SummaryOpts{Name: "delete_summary", Help: "."}, []string{"a"})
+	h := NewHistogramIn(r, stdprometheus.HistogramOpts{Name: "delete_histogram", Help: "."}, []string{"a"})
+	for _, v := range []string{"1", "2"} {
+		g.With("a", v).Set(1)
+		s.With("a", v).Observe(1)
+		h.With("a", v).Observe(1)
+	}
+	if want, have := 6, series(); want != have {
+		t.Errorf("want %d timeseries, have %d", want, have)
+	}
+	g.Reset()
+	s.Reset()
+	h.Reset()
+	if want, have := 0, series(); want != have {
+		t.Errorf("after Reset: want %d timeseries, have %d", want, have)
+	}
+}
@@ -0,0 +1,257 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Pusher pushes the current state of a set of Prometheus metrics to a remote
+// system. It's intended for batch jobs and short-lived processes, which can't
+// reliably be scraped.
+type Pusher interface {
+	Push(ctx context.Context) error
+}
+
+// PushLoop is a helper function that invokes p.Push every time the passed
+// channel fires. This function blocks until the channel is closed, so clients
+// probably want to run it in its own goroutine. For typical usage, create a
+// time.Ticker and pass its C channel to this function.
+func PushLoop(c <-chan time.Time, p Pusher, logger log.Logger) {
+	for range c {
+		if err := p.Push(context.Background()); err != nil {
+			logger.Log("during", "Push", "err", err)
+		}
+	}
+}
+
+// NewPushgateway returns a Pusher which replaces all metrics grouped under job
+// on the Pushgateway at url with the metrics gathered from g. Pass
+// prometheus.DefaultGatherer to push the metrics created by the From
+// constructors in this package.
+func NewPushgateway(url, job string, g prometheus.Gatherer) Pusher {
+	return NewPushgatewayFrom(push.New(url, job).Gatherer(g))
+}
+
+// NewPushgatewayFrom adapts a fully-configured client_golang push.Pusher, for
+// example one with grouping labels or basic auth, to the Pusher interface.
+func NewPushgatewayFrom(p *push.Pusher) Pusher {
+	return pushgateway{p}
+}
+
+type pushgateway struct{ p *push.Pusher }
+
+func (pg pushgateway) Push(ctx context.Context) error { return pg.p.PushContext(ctx) }
+
+// RemoteWriter is a Pusher which sends the metrics gathered from a Prometheus
+// gatherer to an endpoint that speaks the Prometheus remote-write protocol
+// (version 1), e.g. Prometheus itself, Cortex, Thanos, or VictoriaMetrics.
+//
+// Counters, gauges, and untyped metrics are sent as a single sample. Histograms
+// and summaries are exploded into their _bucket, _sum, and _count series, in
+// the same way as the text exposition format.
+type RemoteWriter struct {
+	url    string
+	g      prometheus.Gatherer
+	client *http.Client
+	labels map[string]string
+	now    func() time.Time
+}
+
+// RemoteWriterOption sets an optional parameter for RemoteWriters.
+type RemoteWriterOption func(*RemoteWriter)
+
+// RemoteWriterClient sets the HTTP client used to send write requests. By
+// default, http.DefaultClient is used.
+func RemoteWriterClient(client *http.Client) RemoteWriterOption {
+	return func(w *RemoteWriter) { w.client = client }
+}
+
+// RemoteWriterLabels sets external labels, typically job and instance, which
+// are attached to every series sent by the writer. Labels already present on a
+// series take precedence.
+func RemoteWriterLabels(labels map[string]string) RemoteWriterOption {
+	return func(w *RemoteWriter) { w.labels = labels }
+}
+
+// NewRemoteWriter returns a RemoteWriter which sends the metrics gathered from
+// g to the remote-write endpoint at url.
+func NewRemoteWriter(url string, g prometheus.Gatherer, options ...RemoteWriterOption) *RemoteWriter {
+	w := &RemoteWriter{
+		url:    url,
+		g:      g,
+		client: http.DefaultClient,
+		now:    time.Now,
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Push implements Pusher. It gathers the metrics, encodes them as a
+// snappy-compressed WriteRequest, and POSTs them to the endpoint.
+func (w *RemoteWriter) Push(ctx context.Context) error {
+	mfs, err := w.g.Gather()
+	if err != nil {
+		return err
+	}
+
+	body := s2.EncodeSnappy(nil, encodeWriteRequest(mfs, w.labels, w.now()))
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		buf, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write: %s: %s", resp.Status, bytes.TrimSpace(buf))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// series is a single remote-write time series with one sample.
+type series struct {
+	labels []*dto.LabelPair
+	value  float64
+}
+
+func encodeWriteRequest(mfs []*dto.MetricFamily, external map[string]string, now time.Time) []byte {
+	ts := now.UnixNano() / int64(time.Millisecond)
+	var buf []byte
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for _, s := range explode(mf, m) {
+				buf = protowire.AppendTag(buf, 1, protowire.BytesType) // WriteRequest.timeseries
+				buf = protowire.AppendBytes(buf, encodeTimeSeries(s, external, ts))
+			}
+		}
+	}
+	return buf
+}
+
+func explode(mf *dto.MetricFamily, m *dto.Metric) []series {
+	name := mf.GetName()
+	one := func(suffix string, value float64, extra ...*dto.LabelPair) series {
+		return series{labels: withName(name+suffix, m.GetLabel(), extra...), value: value}
+	}
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		return []series{one("", m.GetCounter().GetValue())}
+	case dto.MetricType_GAUGE:
+		return []series{one("", m.GetGauge().GetValue())}
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		out := make([]series, 0, len(s.GetQuantile())+2)
+		for _, q := range s.GetQuantile() {
+			out = append(out, one("", q.GetValue(), labelPair("quantile", formatFloat(q.GetQuantile()))))
+		}
+		return append(out,
+			one("_sum", s.GetSampleSum()),
+			one("_count", float64(s.GetSampleCount())),
+		)
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		h := m.GetHistogram()
+		out := make([]series, 0, len(h.GetBucket())+3)
+		for _, b := range h.GetBucket() {
+			out = append(out, one("_bucket", float64(b.GetCumulativeCount()), labelPair("le", formatFloat(b.GetUpperBound()))))
+		}
+		return append(out,
+			one("_bucket", float64(h.GetSampleCount()), labelPair("le", "+Inf")),
+			one("_sum", h.GetSampleSum()),
+			one("_count", float64(h.GetSampleCount())),
+		)
+	default:
+		return []series{one("", m.GetUntyped().GetValue())}
+	}
+}
+
+func encodeTimeSeries(s series, external map[string]string, ts int64) []byte {
+	labels := s.labels
+	for k, v := range external {
+		if !hasLabel(labels, k) {
+			labels = append(labels, labelPair(k, v))
+		}
+	}
+	sort.Sort(byName(labels)) // remote write requires sorted labels
+
+	var buf []byte
+	for _, lp := range labels {
+		var l []byte
+		l = protowire.AppendTag(l, 1, protowire.BytesType) // Label.name
+		l = protowire.AppendString(l, lp.GetName())
+		l = protowire.AppendTag(l, 2, protowire.BytesType) // Label.value
+		l = protowire.AppendString(l, lp.GetValue())
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType) // TimeSeries.labels
+		buf = protowire.AppendBytes(buf, l)
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type) // Sample.value
+	sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType) // Sample.timestamp
+	sample = protowire.AppendVarint(sample, uint64(ts))
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType) // TimeSeries.samples
+	buf = protowire.AppendBytes(buf, sample)
+	return buf
+}
+
+func withName(name string, labels []*dto.LabelPair, extra ...*dto.LabelPair) []*dto.LabelPair {
+	out := make([]*dto.LabelPair, 0, len(labels)+len(extra)+1)
+	out = append(out, labelPair("__name__", name))
+	out = append(out, labels...)
+	return append(out, extra...)
+}
+
+func hasLabel(labels []*dto.LabelPair, name string) bool {
+	for _, lp := range labels {
+		if lp.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func labelPair(name, value string) *dto.LabelPair {
+	return &dto.LabelPair{Name: &name, Value: &value}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return fmt.Sprint(f)
+	}
+}
+
+type byName []*dto.LabelPair
+
+func (a byName) Len() int           { return len(a) }
+func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byName) Less(i, j int) bool { return a[i].GetName() < a[j].GetName() }
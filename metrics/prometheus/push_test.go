@@ -0,0 +1,148 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestRemoteWriter(t *testing.T) {
+	reg := stdprometheus.NewRegistry()
+	cv := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{Name: "requests", Help: "help"}, []string{"method"})
+	hv := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{Name: "latency", Help: "help", Buckets: []float64{1}}, []string{})
+	reg.MustRegister(cv, hv)
+	NewCounter(cv).With("method", "Foo").Add(3)
+	NewHistogram(hv).Observe(0.5)
+	NewHistogram(hv).Observe(2)
+
+	var (
+		have   []string
+		header http.Header
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		compressed, _ := ioutil.ReadAll(r.Body)
+		buf, err := s2.Decode(nil, compressed)
+		if err != nil {
+			t.Errorf("snappy decode: %v", err)
+		}
+		have = decodeWriteRequest(t, buf)
+	}))
+	defer s.Close()
+
+	w := NewRemoteWriter(s.URL, reg, RemoteWriterLabels(map[string]string{"job": "batch", "method": "ignored"}))
+	w.now = func() time.Time { return time.Unix(1, 0) }
+	if err := w.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := "snappy", header.Get("Content-Encoding"); want != have {
+		t.Errorf("Content-Encoding: want %q, have %q", want, have)
+	}
+	want := []string{
+		`__name__=latency_bucket,job=batch,le=+Inf,method=ignored 2 @1000`,
+		`__name__=latency_bucket,job=batch,le=1,method=ignored 1 @1000`,
+		`__name__=latency_count,job=batch,method=ignored 2 @1000`,
+		`__name__=latency_sum,job=batch,method=ignored 2.5 @1000`,
+		`__name__=requests,job=batch,method=Foo 3 @1000`,
+	}
+	sort.Strings(have)
+	if strings.Join(want, "\n") != strings.Join(have, "\n") {
+		t.Errorf("want\n%s\nhave\n%s", strings.Join(want, "\n"), strings.Join(have, "\n"))
+	}
+}
+
+func TestRemoteWriterError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer s.Close()
+
+	err := NewRemoteWriter(s.URL, stdprometheus.NewRegistry()).Push(context.Background())
+	if err == nil {
+		t.Fatal("want error, have none")
+	}
+	if want, have := "remote write: 400 Bad Request: out of order sample", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestPushgateway(t *testing.T) {
+	var method, path string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+	}))
+	defer s.Close()
+
+	reg := stdprometheus.NewRegistry()
+	gv := stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{Name: "last_success", Help: "help"}, []string{})
+	reg.MustRegister(gv)
+	NewGauge(gv).Set(123)
+
+	if err := NewPushgateway(s.URL, "batch", reg).Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "PUT", method; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "/metrics/job/batch", path; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+// decodeWriteRequest renders each series in the encoded WriteRequest as
+// "labels value @timestamp".
+func decodeWriteRequest(t *testing.T, buf []byte) []string {
+	var out []string
+	for _, ts := range fields(t, buf, 1) {
+		var (
+			labels []string
+			sample string
+		)
+		for _, l := range fields(t, ts, 1) {
+			name, value := fields(t, l, 1), fields(t, l, 2)
+			labels = append(labels, string(name[0])+"="+string(value[0]))
+		}
+		for _, s := range fields(t, ts, 2) {
+			v, n := protowire.ConsumeFixed64(s[1:])
+			ms, _ := protowire.ConsumeVarint(s[1+n+1:])
+			sample = fmt.Sprintf("%v @%d", math.Float64frombits(v), ms)
+		}
+		out = append(out, strings.Join(labels, ",")+" "+sample)
+	}
+	return out
+}
+
+// fields returns the contents of every length-delimited field num in buf.
+func fields(t *testing.T, buf []byte, num protowire.Number) [][]byte {
+	var out [][]byte
+	for len(buf) > 0 {
+		n, typ, l := protowire.ConsumeTag(buf)
+		if l < 0 {
+			t.Fatal(protowire.ParseError(l))
+		}
+		buf = buf[l:]
+		if typ != protowire.BytesType {
+			l = protowire.ConsumeFieldValue(n, typ, buf)
+			buf = buf[l:]
+			continue
+		}
+		v, l := protowire.ConsumeBytes(buf)
+		buf = buf[l:]
+		if n == num {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/cloudwatch"
+)
+
+type cloudwatchProvider struct {
+	cw   *cloudwatch.CloudWatch
+	stop func()
+	opts options
+}
+
+// NewCloudWatchProvider wraps the given CloudWatch object and stop func and
+// returns a Provider that produces CloudWatch metrics. Units set via the Unit
+// option are applied to the corresponding metrics, and counters report deltas
+// if the DeltaCounters option is given; help strings are ignored. A
+// typical stop function would be ticker.Stop from the ticker passed to the
+// WriteLoop helper method.
+func NewCloudWatchProvider(cw *cloudwatch.CloudWatch, stop func(), options ...Option) Provider {
+	return &cloudwatchProvider{
+		cw:   cw,
+		stop: stop,
+		opts: newOptions(options),
+	}
+}
+
+// NewCounter implements Provider.
+func (p *cloudwatchProvider) NewCounter(name string) metrics.Counter {
+	options := p.metricOptions(name)
+	if p.opts.deltaCounters {
+		options = append(options, cloudwatch.Delta(true))
+	}
+	return p.cw.NewCounter(name, options...)
+}
+
+// NewGauge implements Provider.
+func (p *cloudwatchProvider) NewGauge(name string) metrics.Gauge {
+	return p.cw.NewGauge(name, p.metricOptions(name)...)
+}
+
+// NewHistogram implements Provider.
+func (p *cloudwatchProvider) NewHistogram(name string, buckets int) metrics.Histogram {
+	return p.cw.NewHistogram(name, buckets, p.metricOptions(name)...)
+}
+
+// Stop implements Provider, invoking the stop function passed at construction.
+func (p *cloudwatchProvider) Stop() {
+	p.stop()
+}
+
+func (p *cloudwatchProvider) metricOptions(name string) []cloudwatch.MetricOption {
+	if unit := p.opts.metadata[name].Unit; unit != "" {
+		return []cloudwatch.MetricOption{cloudwatch.Unit(unit)}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package provider
 
 import (
+	"sync"
+	"time"
+
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/influx"
 )
@@ -38,3 +41,57 @@ func (p *influxProvider) NewHistogram(name string, buckets int) metrics.Histogra
 func (p *influxProvider) Stop() {
 	p.stop()
 }
+
+type influxPushProvider struct {
+	influxProvider
+	w    influx.BatchPointsWriter
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewInfluxV2Provider takes the given Influx object and InfluxDB 2.x writer,
+// and returns a Provider that produces Influx metrics. Observations are written
+// every flushInterval until Stop is called, which writes one last time.
+func NewInfluxV2Provider(in *influx.Influx, w *influx.V2Writer, flushInterval time.Duration) Provider {
+	return NewInfluxPushProvider(in, w, flushInterval)
+}
+
+// NewInfluxPushProvider is like NewInfluxV2Provider, but writes to any
+// BatchPointsWriter, e.g. an influx.LineWriter pushing line protocol to
+// VictoriaMetrics or Telegraf.
+func NewInfluxPushProvider(in *influx.Influx, w influx.BatchPointsWriter, flushInterval time.Duration) Provider {
+	p := &influxPushProvider{
+		influxProvider: influxProvider{in: in},
+		w:              w,
+		done:           make(chan struct{}),
+	}
+	ticker := time.NewTicker(flushInterval)
+	c := make(chan time.Time)
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		defer ticker.Stop()
+		defer close(c)
+		for {
+			select {
+			case t := <-ticker.C:
+				c <- t
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	go func() {
+		defer p.wg.Done()
+		in.WriteLoop(c, w)
+	}()
+	return p
+}
+
+// Stop implements Provider, halting the periodic writes and then flushing all
+// remaining observations. Errors from the final write are dropped.
+func (p *influxPushProvider) Stop() {
+	close(p.done)
+	p.wg.Wait()
+	p.in.WriteTo(p.w)
+}
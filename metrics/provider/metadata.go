@@ -0,0 +1,62 @@
+package provider
+
+// Metadata describes a metric beyond its name. Providers for backends which
+// support metadata apply it to the metrics they produce; others ignore it.
+type Metadata struct {
+	Help string // human-readable description
+	Unit string // unit of the observed values, in the backend's vocabulary
+}
+
+// Option sets an optional parameter for the providers which accept it.
+type Option func(*options)
+
+type options struct {
+	metadata      map[string]Metadata
+	deltaCounters bool
+}
+
+// Help sets the description of the named metric, e.g. the Prometheus help
+// string.
+func Help(name, help string) Option {
+	return func(o *options) {
+		m := o.metadata[name]
+		m.Help = help
+		o.metadata[name] = m
+	}
+}
+
+// Unit sets the unit of the named metric, e.g. the CloudWatch unit "Seconds".
+func Unit(name, unit string) Option {
+	return func(o *options) {
+		m := o.metadata[name]
+		m.Unit = unit
+		o.metadata[name] = m
+	}
+}
+
+// DeltaCounters causes counters to report the sum of values added since the
+// previous flush, rather than the cumulative total since creation, for
+// providers whose backends would otherwise receive totals, e.g. CloudWatch.
+// StatsD, DogStatsD, Graphite, and Influx counters always report deltas, and
+// Prometheus and expvar counters are always cumulative, so their providers
+// ignore it.
+func DeltaCounters() Option {
+	return func(o *options) { o.deltaCounters = true }
+}
+
+func newOptions(opts []Option) options {
+	o := options{metadata: map[string]Metadata{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// help returns the help string of the named metric, or the name itself if
+// none is set.
+func (o options) help(name string) string {
+	if h := o.metadata[name].Help; h != "" {
+		return h
+	}
+	return name
+}
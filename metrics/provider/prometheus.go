@@ -1,6 +1,8 @@
 package provider
 
 import (
+	"context"
+
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 
 	"github.com/go-kit/kit/metrics"
@@ -8,56 +10,107 @@ import (
 )
 
 type prometheusProvider struct {
+	r         stdprometheus.Registerer
 	namespace string
 	subsystem string
+	opts      options
 }
 
 // NewPrometheusProvider returns a Provider that produces Prometheus metrics.
-// Namespace and subsystem are applied to all produced metrics.
-func NewPrometheusProvider(namespace, subsystem string) Provider {
+// Namespace and subsystem are applied to all produced metrics. Help strings
+// set via the Help option are applied to the corresponding metrics; units are
+// ignored, as Prometheus conveys them by metric name suffixes.
+func NewPrometheusProvider(namespace, subsystem string, options ...Option) Provider {
+	return NewPrometheusProviderIn(stdprometheus.DefaultRegisterer, namespace, subsystem, options...)
+}
+
+// NewPrometheusProviderIn is like NewPrometheusProvider, but the produced
+// metrics are registered with r rather than the global registry.
+func NewPrometheusProviderIn(r stdprometheus.Registerer, namespace, subsystem string, options ...Option) Provider {
 	return &prometheusProvider{
+		r:         r,
 		namespace: namespace,
 		subsystem: subsystem,
+		opts:      newOptions(options),
 	}
 }
 
-// NewCounter implements Provider via prometheus.NewCounterFrom, i.e. the
+// NewCounter implements Provider via prometheus.NewCounterIn, i.e. the
 // counter is registered. The metric's namespace and subsystem are taken from
-// the Provider. Help is set to the name of the metric, and no const label names
-// are set.
+// the Provider. Help is taken from the Help option, defaulting to the name of
+// the metric, and no const label names are set.
 func (p *prometheusProvider) NewCounter(name string) metrics.Counter {
-	return prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+	return prometheus.NewCounterIn(p.r, stdprometheus.CounterOpts{
 		Namespace: p.namespace,
 		Subsystem: p.subsystem,
 		Name:      name,
-		Help:      name,
+		Help:      p.opts.help(name),
 	}, []string{})
 }
 
-// NewGauge implements Provider via prometheus.NewGaugeFrom, i.e. the gauge is
+// NewGauge implements Provider via prometheus.NewGaugeIn, i.e. the gauge is
 // registered. The metric's namespace and subsystem are taken from the Provider.
-// Help is set to the name of the metric, and no const label names are set.
+// Help is taken from the Help option, defaulting to the name of the metric, and
+// no const label names are set.
 func (p *prometheusProvider) NewGauge(name string) metrics.Gauge {
-	return prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+	return prometheus.NewGaugeIn(p.r, stdprometheus.GaugeOpts{
 		Namespace: p.namespace,
 		Subsystem: p.subsystem,
 		Name:      name,
-		Help:      name,
+		Help:      p.opts.help(name),
 	}, []string{})
 }
 
-// NewGauge implements Provider via prometheus.NewSummaryFrom, i.e. the summary
+// NewGauge implements Provider via prometheus.NewSummaryIn, i.e. the summary
 // is registered. The metric's namespace and subsystem are taken from the
-// Provider. Help is set to the name of the metric, and no const label names are
-// set. Buckets are ignored.
+// Provider. Help is taken from the Help option, defaulting to the name of the
+// metric, and no const label names are set. Buckets are ignored.
 func (p *prometheusProvider) NewHistogram(name string, _ int) metrics.Histogram {
-	return prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+	return prometheus.NewSummaryIn(p.r, stdprometheus.SummaryOpts{
 		Namespace: p.namespace,
 		Subsystem: p.subsystem,
 		Name:      name,
-		Help:      name,
+		Help:      p.opts.help(name),
 	}, []string{})
 }
 
 // Stop implements Provider, but is a no-op.
 func (p *prometheusProvider) Stop() {}
+
+type prometheusPushProvider struct {
+	prometheusProvider
+	p    prometheus.Pusher
+	stop func()
+}
+
+// NewPrometheusPushProvider returns a Provider that produces Prometheus
+// metrics, like NewPrometheusProvider, for processes that push their metrics
+// rather than being scraped. The pusher should gather from the default
+// registry. A typical stop function would be ticker.Stop from the ticker
+// passed to the prometheus.PushLoop helper function.
+func NewPrometheusPushProvider(namespace, subsystem string, p prometheus.Pusher, stop func(), options ...Option) Provider {
+	return NewPrometheusPushProviderIn(stdprometheus.DefaultRegisterer, namespace, subsystem, p, stop, options...)
+}
+
+// NewPrometheusPushProviderIn is like NewPrometheusPushProvider, but the
+// produced metrics are registered with r, which the pusher should gather from.
+func NewPrometheusPushProviderIn(r stdprometheus.Registerer, namespace, subsystem string, p prometheus.Pusher, stop func(), options ...Option) Provider {
+	return &prometheusPushProvider{
+		prometheusProvider: prometheusProvider{
+			r:         r,
+			namespace: namespace,
+			subsystem: subsystem,
+			opts:      newOptions(options),
+		},
+		p:    p,
+		stop: stop,
+	}
+}
+
+// Stop implements Provider, invoking the stop function passed at construction
+// and then pushing one last time, so that the final state of short-lived
+// processes isn't lost. Errors from the final push are dropped.
+func (p *prometheusPushProvider) Stop() {
+	p.stop()
+	p.p.Push(context.Background())
+}
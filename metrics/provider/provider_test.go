@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"testing"
+
+	stdcloudwatch "github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/cloudwatch"
+)
+
+func TestPrometheusHelp(t *testing.T) {
+	r := stdprometheus.NewRegistry()
+	p := NewPrometheusProviderIn(r, "test", "provider", Help("requests", "Total requests served."), Unit("requests", "requests"))
+	p.NewCounter("requests").Add(1)
+	p.NewGauge("depth").Set(1)
+
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	help := map[string]string{}
+	for _, mf := range mfs {
+		help[mf.GetName()] = mf.GetHelp()
+	}
+	for name, want := range map[string]string{
+		"test_provider_requests": "Total requests served.",
+		"test_provider_depth":    "depth",
+	} {
+		if have := help[name]; want != have {
+			t.Errorf("%s: want %q, have %q", name, want, have)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	o := newOptions([]Option{Unit("latency", "Seconds"), Help("latency", "How long."), Help("other", "Other.")})
+	if want, have := (Metadata{Help: "How long.", Unit: "Seconds"}), o.metadata["latency"]; want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	if want, have := "unset", o.help("unset"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type cloudwatchValues struct {
+	cloudwatchiface.CloudWatchAPI
+	values map[string]float64
+}
+
+func (c *cloudwatchValues) PutMetricData(input *stdcloudwatch.PutMetricDataInput) (*stdcloudwatch.PutMetricDataOutput, error) {
+	for _, datum := range input.MetricData {
+		c.values[*datum.MetricName] = *datum.Value
+	}
+	return nil, nil
+}
+
+func TestCloudWatchDeltaCounters(t *testing.T) {
+	svc := &cloudwatchValues{values: map[string]float64{}}
+	cw := cloudwatch.New("test", svc, 1, log.NewNopLogger())
+	p := NewCloudWatchProvider(cw, func() {}, DeltaCounters())
+	c := p.NewCounter("requests")
+
+	for _, delta := range []float64{3, 4} {
+		c.Add(delta)
+		if err := cw.Send(); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := delta, svc.values["requests"]; want != have {
+			t.Errorf("want %f, have %f", want, have)
+		}
+	}
+}
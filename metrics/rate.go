@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRateBuckets is the number of buckets a Rate's window is divided into,
+// if not otherwise specified.
+const DefaultRateBuckets = 10
+
+// Rate measures the rate of events per second over a sliding time window. The
+// window is divided into a number of buckets; events are counted into the
+// current bucket, and the oldest bucket is discarded as the window slides
+// forward. Rate is meant for in-process adaptive logic, e.g. load shedding or
+// adaptive concurrency limits, but may also be exported by passing its Rate
+// method to PollGauge, or to a backend's NewGaugeFunc.
+//
+// Rate implements Counter, so it may be combined with other counters, e.g. via
+// package multi. Label values are not supported.
+type Rate struct {
+	mtx     sync.Mutex
+	now     func() time.Time
+	window  time.Duration
+	width   time.Duration
+	buckets []float64
+	head    int
+	start   time.Time // of the head bucket
+	created time.Time
+}
+
+// RateOption sets an optional parameter for a Rate.
+type RateOption func(*Rate)
+
+// RateBuckets sets the number of buckets the window is divided into. More
+// buckets make the window slide more smoothly, at the cost of memory. By
+// default, DefaultRateBuckets is used.
+func RateBuckets(n int) RateOption {
+	return func(r *Rate) {
+		if n > 0 {
+			r.buckets = make([]float64, n)
+		}
+	}
+}
+
+// RateClock sets the function the rate uses to read the current time. It's
+// intended for deterministic tests. By default, time.Now is used.
+func RateClock(now func() time.Time) RateOption {
+	return func(r *Rate) { r.now = now }
+}
+
+// NewRate returns a Rate measuring events over the given window.
+func NewRate(window time.Duration, options ...RateOption) *Rate {
+	r := &Rate{
+		now:     time.Now,
+		window:  window,
+		buckets: make([]float64, DefaultRateBuckets),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	r.width = window / time.Duration(len(r.buckets))
+	r.created = r.now()
+	r.start = r.created
+	return r
+}
+
+// With is a no-op.
+func (r *Rate) With(labelValues ...string) Counter { return r }
+
+// Add records delta events.
+func (r *Rate) Add(delta float64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.rotate(r.now())
+	r.buckets[r.head] += delta
+}
+
+// Rate returns the number of events per second over the window. Until a full
+// window has elapsed since the Rate was created, the rate is computed over the
+// time elapsed so far.
+func (r *Rate) Rate() float64 {
+	return r.Snapshot().Rate
+}
+
+// RateSnapshot is a consistent view of a Rate at a point in time.
+type RateSnapshot struct {
+	Count  float64       // events in the window
+	Span   time.Duration // duration the events were counted over
+	Rate   float64       // events per second
+	Window time.Duration // configured window
+}
+
+// Snapshot returns the current state of the rate.
+func (r *Rate) Snapshot() RateSnapshot {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	now := r.now()
+	r.rotate(now)
+
+	var count float64
+	for _, n := range r.buckets {
+		count += n
+	}
+	span := time.Duration(len(r.buckets)-1)*r.width + now.Sub(r.start)
+	if elapsed := now.Sub(r.created); elapsed < span {
+		span = elapsed
+	}
+	s := RateSnapshot{
+		Count:  count,
+		Span:   span,
+		Window: r.window,
+	}
+	if span > 0 {
+		s.Rate = count / span.Seconds()
+	}
+	return s
+}
+
+// rotate advances the head bucket, clearing expired buckets, until it covers
+// now.
+func (r *Rate) rotate(now time.Time) {
+	if r.width <= 0 {
+		return
+	}
+	n := int(now.Sub(r.start) / r.width)
+	if n <= 0 {
+		return
+	}
+	r.start = r.start.Add(time.Duration(n) * r.width)
+	if n > len(r.buckets) {
+		n = len(r.buckets)
+	}
+	for i := 0; i < n; i++ {
+		r.head = (r.head + 1) % len(r.buckets)
+		r.buckets[r.head] = 0
+	}
+}
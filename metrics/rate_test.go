@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+func TestRate(t *testing.T) {
+	var (
+		clock = newFakeClock()
+		rate  = metrics.NewRate(10*time.Second, metrics.RateBuckets(10), metrics.RateClock(clock.Now))
+	)
+	if want, have := 0.0, rate.Rate(); want != have {
+		t.Errorf("empty: want %f, have %f", want, have)
+	}
+
+	// 10 events per second for 10 seconds.
+	for i := 0; i < 10; i++ {
+		rate.Add(10)
+		clock.Advance(time.Second)
+	}
+	s := rate.Snapshot()
+	if want, have := 90.0, s.Count; want != have {
+		t.Errorf("Count: want %f, have %f", want, have)
+	}
+	// The oldest bucket has just expired, and the head bucket has only just
+	// begun, so the first second's events are excluded.
+	if want, have := 9*time.Second, s.Span; want != have {
+		t.Errorf("Span: want %s, have %s", want, have)
+	}
+	if want, have := 10.0, s.Rate; math.Abs(want-have) > 1e-9 {
+		t.Errorf("Rate: want %f, have %f", want, have)
+	}
+
+	// Half of the window slides out.
+	clock.Advance(5 * time.Second)
+	if want, have := 40.0/9, rate.Rate(); math.Abs(want-have) > 1e-9 {
+		t.Errorf("after 5s: want %f, have %f", want, have)
+	}
+
+	// The whole window slides out.
+	clock.Advance(time.Minute)
+	if want, have := 0.0, rate.Rate(); want != have {
+		t.Errorf("after 1m: want %f, have %f", want, have)
+	}
+}
+
+func TestRatePartialWindow(t *testing.T) {
+	var (
+		clock = newFakeClock()
+		rate  = metrics.NewRate(time.Minute, metrics.RateClock(clock.Now))
+	)
+	rate.With("label values", "not supported").Add(20)
+	clock.Advance(2 * time.Second)
+	if want, have := 10.0, rate.Rate(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+}
@@ -0,0 +1,148 @@
+// Package runtime provides a collector which publishes Go runtime statistics
+// through the generic metrics interfaces, so that they're available with any
+// backend, not just those, like Prometheus, which collect them natively.
+package runtime
+
+import (
+	"math"
+	goruntime "runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	kitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// MaxSchedLatencyObservations bounds the number of observations made to the
+// SchedLatency histogram per collection. The Go runtime records the latency of
+// every goroutine scheduling event; if more events occurred than this since the
+// last collection, the observations are scaled down proportionally, which
+// preserves their distribution.
+const MaxSchedLatencyObservations = 1000
+
+const schedLatencies = "/sched/latencies:seconds"
+
+// Collector periodically publishes Go runtime statistics. Any of its metrics
+// may be nil, in which case that statistic isn't collected.
+type Collector struct {
+	Goroutines   kitmetrics.Gauge     // number of goroutines
+	HeapAlloc    kitmetrics.Gauge     // bytes of allocated heap objects
+	HeapInuse    kitmetrics.Gauge     // bytes in in-use heap spans
+	HeapObjects  kitmetrics.Gauge     // number of allocated heap objects
+	HeapSys      kitmetrics.Gauge     // bytes of heap memory obtained from the OS
+	GCCount      kitmetrics.Counter   // completed GC cycles
+	GCPause      kitmetrics.Histogram // seconds, one observation per GC cycle
+	SchedLatency kitmetrics.Histogram // seconds goroutines spent runnable before running
+
+	mtx    sync.Mutex
+	numGC  uint32
+	sched  []uint64
+	sample []metrics.Sample
+}
+
+// Run collects statistics every time the passed channel fires. This method
+// blocks until the channel is closed, so you probably want to run it in its own
+// goroutine. For typical usage, create a time.Ticker and pass its C channel to
+// this method.
+func (c *Collector) Run(ch <-chan time.Time) {
+	for range ch {
+		c.Collect()
+	}
+}
+
+// Collect reads the current runtime statistics and publishes them.
+func (c *Collector) Collect() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.Goroutines != nil {
+		c.Goroutines.Set(float64(goruntime.NumGoroutine()))
+	}
+	if c.HeapAlloc != nil || c.HeapInuse != nil || c.HeapObjects != nil || c.HeapSys != nil || c.GCCount != nil || c.GCPause != nil {
+		c.collectMemStats()
+	}
+	if c.SchedLatency != nil {
+		c.collectSchedLatency()
+	}
+}
+
+func (c *Collector) collectMemStats() {
+	var ms goruntime.MemStats
+	goruntime.ReadMemStats(&ms)
+
+	set := func(g kitmetrics.Gauge, value uint64) {
+		if g != nil {
+			g.Set(float64(value))
+		}
+	}
+	set(c.HeapAlloc, ms.HeapAlloc)
+	set(c.HeapInuse, ms.HeapInuse)
+	set(c.HeapObjects, ms.HeapObjects)
+	set(c.HeapSys, ms.HeapSys)
+
+	cycles := ms.NumGC - c.numGC
+	if c.GCCount != nil && cycles > 0 {
+		c.GCCount.Add(float64(cycles))
+	}
+	if c.GCPause != nil {
+		// PauseNs is a circular buffer of recent pause times; older
+		// pauses have already been overwritten.
+		first := c.numGC
+		if cycles > uint32(len(ms.PauseNs)) {
+			first = ms.NumGC - uint32(len(ms.PauseNs))
+		}
+		for i := first; i < ms.NumGC; i++ {
+			c.GCPause.Observe(float64(ms.PauseNs[i%uint32(len(ms.PauseNs))]) / float64(time.Second))
+		}
+	}
+	c.numGC = ms.NumGC
+}
+
+func (c *Collector) collectSchedLatency() {
+	if c.sample == nil {
+		c.sample = []metrics.Sample{{Name: schedLatencies}}
+	}
+	metrics.Read(c.sample)
+	if c.sample[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return // unsupported by this runtime
+	}
+	h := c.sample[0].Value.Float64Histogram()
+
+	if len(c.sched) != len(h.Counts) {
+		c.sched = make([]uint64, len(h.Counts))
+	}
+	var (
+		deltas = make([]uint64, len(h.Counts))
+		total  uint64
+	)
+	for i, n := range h.Counts {
+		deltas[i] = n - c.sched[i]
+		total += deltas[i]
+		c.sched[i] = n
+	}
+	scale := 1.0
+	if total > MaxSchedLatencyObservations {
+		scale = float64(MaxSchedLatencyObservations) / float64(total)
+	}
+	for i, n := range deltas {
+		if n == 0 {
+			continue
+		}
+		value := bucketValue(h.Buckets[i], h.Buckets[i+1])
+		for j := 0; j < int(math.Round(float64(n)*scale)); j++ {
+			c.SchedLatency.Observe(value)
+		}
+	}
+}
+
+// bucketValue returns a representative value for a histogram bucket.
+func bucketValue(lo, hi float64) float64 {
+	switch {
+	case math.IsInf(lo, -1):
+		return hi
+	case math.IsInf(hi, 1):
+		return lo
+	default:
+		return (lo + hi) / 2
+	}
+}
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"math"
+	goruntime "runtime"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestCollector(t *testing.T) {
+	c := &Collector{
+		Goroutines:   generic.NewGauge("goroutines"),
+		HeapAlloc:    generic.NewGauge("heap_alloc"),
+		HeapSys:      generic.NewGauge("heap_sys"),
+		GCCount:      generic.NewCounter("gc_count"),
+		GCPause:      generic.NewHistogram("gc_pause", 50),
+		SchedLatency: generic.NewHistogram("sched_latency", 50),
+	}
+	c.Collect()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); goruntime.Gosched() }()
+	}
+	wg.Wait()
+	goruntime.GC()
+	goruntime.GC()
+	before := c.GCCount.(*generic.Counter).Value()
+	c.Collect()
+
+	if have := c.Goroutines.(*generic.Gauge).Value(); have < 1 {
+		t.Errorf("Goroutines: want > 0, have %f", have)
+	}
+	if have := c.HeapAlloc.(*generic.Gauge).Value(); have <= 0 {
+		t.Errorf("HeapAlloc: want > 0, have %f", have)
+	}
+	if alloc, sys := c.HeapAlloc.(*generic.Gauge).Value(), c.HeapSys.(*generic.Gauge).Value(); sys < alloc {
+		t.Errorf("HeapSys: want >= %f, have %f", alloc, sys)
+	}
+	if have := c.GCCount.(*generic.Counter).Value() - before; have < 2 {
+		t.Errorf("GCCount: want >= 2 new cycles, have %f", have)
+	}
+	pauses := c.GCPause.(*generic.Histogram)
+	if want, have := uint64(c.GCCount.(*generic.Counter).Value()), pauses.Count(); want != have {
+		t.Errorf("GCPause: want %d observations, have %d", want, have)
+	}
+	if have := c.SchedLatency.(*generic.Histogram).Count(); have > 2*MaxSchedLatencyObservations+10 {
+		t.Errorf("SchedLatency: want at most ~%d observations per collection, have %d", MaxSchedLatencyObservations, have)
+	}
+}
+
+func TestCollectorNilMetrics(t *testing.T) {
+	c := &Collector{}
+	c.Collect() // shouldn't panic
+}
+
+func TestBucketValue(t *testing.T) {
+	for _, tc := range []struct {
+		lo, hi, want float64
+	}{
+		{1, 3, 2},
+		{math.Inf(-1), 3, 3},
+		{1, math.Inf(1), 1},
+	} {
+		if have := bucketValue(tc.lo, tc.hi); tc.want != have {
+			t.Errorf("bucketValue(%f, %f): want %f, have %f", tc.lo, tc.hi, tc.want, have)
+		}
+	}
+}
@@ -6,15 +6,23 @@
 // remote server. This is useful even if you connect to your StatsD server over
 // UDP. Emitting one network packet per observation can quickly overwhelm even
 // the fastest internal network.
+//
+// Counters, timings, and histograms take a sample rate. Observations are
+// recorded with that probability, and emitted with the rate attached, so that
+// the server can scale them back up. High-frequency metrics should use a low
+// sample rate to avoid saturating the socket.
 package statsd
 
 import (
 	"fmt"
 	"io"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/internal/emitter"
 	"github.com/go-kit/kit/metrics/internal/lv"
 	"github.com/go-kit/kit/metrics/internal/ratemap"
 	"github.com/go-kit/kit/util/conn"
@@ -25,8 +33,8 @@ import (
 // dependencies to the components that will use them.
 //
 // All metrics are buffered until WriteTo is called. Counters and gauges are
-// aggregated into a single observation per timeseries per write. Timings are
-// buffered but not aggregated.
+// aggregated into a single observation per timeseries per write. Timings and
+// histograms are buffered but not aggregated.
 //
 // To regularly report metrics to an io.Writer, use the WriteLoop helper method.
 // To send to a StatsD server, use the SendLoop helper method.
@@ -39,11 +47,16 @@ type Statsd struct {
 	// implementation detail born purely from convenience. It would be more
 	// accurate to collect them in a map[string][]float64, but we already have
 	// this nice data structure and helper methods.
-	counters *lv.Space
-	gauges   *lv.Space
-	timings  *lv.Space
+	counters   *lv.Space
+	gauges     *lv.Space
+	timings    *lv.Space
+	histograms *lv.Space
+
+	mtx        sync.RWMutex
+	gaugeFuncs map[string]func() float64
 
 	logger log.Logger
+	rand   func() float64
 }
 
 // New returns a Statsd object that may be used to create metrics. Prefix is
@@ -51,21 +64,25 @@ type Statsd struct {
 // WriteTo are performed, either manually or with one of the helper methods.
 func New(prefix string, logger log.Logger) *Statsd {
 	return &Statsd{
-		prefix:   prefix,
-		rates:    ratemap.New(),
-		counters: lv.NewSpace(),
-		gauges:   lv.NewSpace(),
-		timings:  lv.NewSpace(),
-		logger:   logger,
+		prefix:     prefix,
+		rates:      ratemap.New(),
+		counters:   lv.NewSpace(),
+		gauges:     lv.NewSpace(),
+		timings:    lv.NewSpace(),
+		histograms: lv.NewSpace(),
+		gaugeFuncs: map[string]func() float64{},
+		logger:     logger,
+		rand:       rand.Float64,
 	}
 }
 
 // NewCounter returns a counter, sending observations to this Statsd object.
+// Each delta is recorded with probability sampleRate.
 func (s *Statsd) NewCounter(name string, sampleRate float64) *Counter {
 	s.rates.Set(s.prefix+name, sampleRate)
 	return &Counter{
 		name: s.prefix + name,
-		obs:  s.counters.Observe,
+		obs:  s.sample(sampleRate, s.counters.Observe),
 	}
 }
 
@@ -78,35 +95,130 @@ func (s *Statsd) NewGauge(name string) *Gauge {
 	}
 }
 
+// NewGaugeFunc registers a gauge whose value is determined by calling f every
+// time the Statsd object is written. It's useful for values like queue depth,
+// which are cheaper to read on demand than to track continuously.
+func (s *Statsd) NewGaugeFunc(name string, f func() float64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.gaugeFuncs[s.prefix+name] = f
+}
+
 // NewTiming returns a histogram whose observations are interpreted as
-// millisecond durations, and are forwarded to this Statsd object.
+// millisecond durations, and are forwarded to this Statsd object. They're
+// emitted with the |ms type. Each observation is recorded with probability
+// sampleRate.
 func (s *Statsd) NewTiming(name string, sampleRate float64) *Timing {
 	s.rates.Set(s.prefix+name, sampleRate)
 	return &Timing{
 		name: s.prefix + name,
-		obs:  s.timings.Observe,
+		obs:  s.sample(sampleRate, s.timings.Observe),
+	}
+}
+
+// NewHistogram returns a histogram whose observations are of an unspecified
+// unit, and are forwarded to this Statsd object. They're emitted with the |h
+// type, which is understood by many StatsD implementations, but not the
+// reference one. Each observation is recorded with probability sampleRate.
+func (s *Statsd) NewHistogram(name string, sampleRate float64) *Histogram {
+	s.rates.Set(s.prefix+name, sampleRate)
+	return &Histogram{
+		name: s.prefix + name,
+		obs:  s.sample(sampleRate, s.histograms.Observe),
+	}
+}
+
+// sample wraps an observeFunc, so that observations are only recorded with
+// probability sampleRate.
+func (s *Statsd) sample(sampleRate float64, obs observeFunc) observeFunc {
+	if sampleRate >= 1.0 {
+		return obs
+	}
+	return func(name string, lvs lv.LabelValues, value float64) {
+		if s.rand() < sampleRate {
+			obs(name, lvs, value)
+		}
 	}
 }
 
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
-// time the passed channel fires. This method blocks until the channel is
-// closed, so clients probably want to run it in its own goroutine. For typical
-// usage, create a time.Ticker and pass its C channel to this method.
+// time the passed channel fires. If the writer has a Flush method, it is
+// called after each write. This method blocks until the channel is closed, so
+// clients probably want to run it in its own goroutine. For typical usage,
+// create a time.Ticker and pass its C channel to this method.
 func (s *Statsd) WriteLoop(c <-chan time.Time, w io.Writer) {
 	for range c {
 		if _, err := s.WriteTo(w); err != nil {
 			s.logger.Log("during", "WriteTo", "err", err)
 		}
+		if f, ok := w.(interface {
+			Flush() error
+		}); ok {
+			f.Flush()
+		}
 	}
 }
 
+// SendOption sets an optional parameter for SendLoop.
+type SendOption func(*emitter.Config)
+
+// SendMaxPacketSize sets the maximum size in bytes of each packet sent to the
+// server. Observations are packed into as few packets as possible; a single
+// observation longer than the maximum is sent alone. By default, 1432 bytes
+// are used, which fits a UDP datagram within a typical Ethernet MTU.
+func SendMaxPacketSize(n int) SendOption {
+	return func(c *emitter.Config) { c.MaxPacketSize = n }
+}
+
+// SendFlushInterval bounds the time a partially filled packet is held before
+// it's sent. Packets are also sent at the end of every write, so this only
+// matters if the loop's channel fires less often. By default, one second is
+// used.
+func SendFlushInterval(d time.Duration) SendOption {
+	return func(c *emitter.Config) { c.FlushInterval = d }
+}
+
+// SendQueueSize sets the number of packets which may wait to be sent. If the
+// server is slow or unavailable and the queue fills, further packets are
+// dropped rather than blocking the loop. By default, 1024 packets are queued.
+func SendQueueSize(n int) SendOption {
+	return func(c *emitter.Config) { c.QueueSize = n }
+}
+
+// SendErrorHandler sets the function called with each error from sending a
+// packet, including emitter.ErrQueueFull when one is dropped. By default,
+// errors are logged to the Statsd object's logger.
+func SendErrorHandler(f func(error)) SendOption {
+	return func(c *emitter.Config) { c.ErrorHandler = f }
+}
+
+// SendDropCounter sets a counter which is incremented by the number of
+// observations in each dropped packet.
+func SendDropCounter(counter metrics.Counter) SendOption {
+	return func(c *emitter.Config) { c.DropCounter = counter }
+}
+
 // SendLoop is a helper method that wraps WriteLoop, passing a managed
-// connection to the network and address. Like WriteLoop, this method blocks
-// until the channel is closed, so clients probably want to start it in its own
-// goroutine. For typical usage, create a time.Ticker and pass its C channel to
-// this method.
-func (s *Statsd) SendLoop(c <-chan time.Time, network, address string) {
-	s.WriteLoop(c, conn.NewDefaultManager(network, address, s.logger))
+// connection to the network and address. Observations are packed into packets
+// and sent from a separate goroutine, so a slow server doesn't delay the loop;
+// see the SendOption functions for the parameters. Like WriteLoop, this method
+// blocks until the channel is closed, so clients probably want to start it in
+// its own goroutine. For typical usage, create a time.Ticker and pass its C
+// channel to this method.
+func (s *Statsd) SendLoop(c <-chan time.Time, network, address string, options ...SendOption) {
+	s.sendLoop(c, conn.NewDefaultManager(network, address, s.logger), options...)
+}
+
+func (s *Statsd) sendLoop(c <-chan time.Time, w io.Writer, options ...SendOption) {
+	cfg := emitter.Config{
+		ErrorHandler: func(err error) { s.logger.Log("during", "send", "err", err) },
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	e := emitter.New(w, cfg)
+	defer e.Close()
+	s.WriteLoop(c, e)
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in
@@ -128,6 +240,12 @@ func (s *Statsd) WriteTo(w io.Writer) (count int64, err error) {
 		return count, err
 	}
 
+	s.mtx.RLock()
+	for name, f := range s.gaugeFuncs {
+		s.gauges.Observe(name, lv.LabelValues{}, f())
+	}
+	s.mtx.RUnlock()
+
 	s.gauges.Reset().Walk(func(name string, _ lv.LabelValues, values []float64) bool {
 		n, err = fmt.Fprintf(w, "%s:%f|g\n", name, last(values))
 		if err != nil {
@@ -155,6 +273,21 @@ func (s *Statsd) WriteTo(w io.Writer) (count int64, err error) {
 		return count, err
 	}
 
+	s.histograms.Reset().Walk(func(name string, _ lv.LabelValues, values []float64) bool {
+		sampleRate := s.rates.Get(name)
+		for _, value := range values {
+			n, err = fmt.Fprintf(w, "%s:%f|h%s\n", name, value, sampling(sampleRate))
+			if err != nil {
+				return false
+			}
+			count += int64(n)
+		}
+		return true
+	})
+	if err != nil {
+		return count, err
+	}
+
 	return count, err
 }
 
@@ -237,3 +370,21 @@ func (t *Timing) With(...string) metrics.Histogram {
 func (t *Timing) Observe(value float64) {
 	t.obs(t.name, lv.LabelValues{}, value)
 }
+
+// Histogram is a StatsD histogram, or metrics.Histogram. Observations are
+// forwarded to a Statsd object, and collected (but not aggregated) per
+// timeseries.
+type Histogram struct {
+	name string
+	obs  observeFunc
+}
+
+// With is a no-op.
+func (h *Histogram) With(...string) metrics.Histogram {
+	return h
+}
+
+// Observe implements metrics.Histogram.
+func (h *Histogram) Observe(value float64) {
+	h.obs(h.name, lv.LabelValues{}, value)
+}
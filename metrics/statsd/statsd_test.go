@@ -1,7 +1,11 @@
 package statsd
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics/teststat"
@@ -20,10 +24,17 @@ func TestCounter(t *testing.T) {
 }
 
 func TestCounterSampled(t *testing.T) {
-	// This will involve multiplying the observed sum by the inverse of the
-	// sample rate and checking against the expected value within some
-	// tolerance.
-	t.Skip("TODO")
+	prefix, name := "abc.", "sampled"
+	regex := `^` + prefix + name + `:([0-9\.]+)\|c\|@0\.5[0]*$`
+	s := New(prefix, log.NewNopLogger())
+	s.rand = alternate()
+	counter := s.NewCounter(name, 0.5)
+	for i := 0; i < 10; i++ {
+		counter.Add(1)
+	}
+	if want, have := 5.0, teststat.SumLines(s, regex)(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
 }
 
 func TestGauge(t *testing.T) {
@@ -58,9 +69,96 @@ func TestTimingSampled(t *testing.T) {
 	label, value := "foo", "bar" // ignored
 	regex := `^` + prefix + name + `:([0-9\.]+)\|ms\|@0\.01[0]*$`
 	s := New(prefix, log.NewNopLogger())
+	s.rand = func() float64 { return 0 } // record everything, to test quantiles
 	timing := s.NewTiming(name, 0.01).With(label, value)
 	quantiles := teststat.Quantiles(s, regex, 50)
 	if err := teststat.TestHistogram(timing, quantiles, 0.02); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestTimingSampledDrops(t *testing.T) {
+	s := New("statsd.", log.NewNopLogger())
+	s.rand = alternate()
+	timing := s.NewTiming("dropped_timing_test", 0.5)
+	for i := 0; i < 10; i++ {
+		timing.Observe(float64(i))
+	}
+	var buf bytes.Buffer
+	s.WriteTo(&buf)
+	if want, have := 5, strings.Count(buf.String(), "|ms|@0.5"); want != have {
+		t.Errorf("want %d sampled timings, have %d\n%s", want, have, buf.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	prefix, name := "statsd.", "histogram_test"
+	label, value := "abc", "def" // ignored
+	regex := `^` + prefix + name + `:([0-9\.]+)\|h$`
+	s := New(prefix, log.NewNopLogger())
+	histogram := s.NewHistogram(name, 1.0).With(label, value)
+	quantiles := teststat.Quantiles(s, regex, 50) // no |@0.X
+	if err := teststat.TestHistogram(histogram, quantiles, 0.01); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// alternate returns a random source which alternates between keeping and
+// dropping observations sampled at 0.5.
+func alternate() func() float64 {
+	var i int
+	return func() float64 {
+		i++
+		return float64(i%2) * 0.9
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	s := New("statsd.", log.NewNopLogger())
+	depth := 0.0
+	s.NewGaugeFunc("depth", func() float64 { depth++; return depth })
+	for _, want := range []string{"statsd.depth:1.000000|g\n", "statsd.depth:2.000000|g\n"} {
+		var buf bytes.Buffer
+		s.WriteTo(&buf)
+		if have := buf.String(); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+type packetWriter struct {
+	packets []string
+}
+
+func (w *packetWriter) Write(p []byte) (int, error) {
+	w.packets = append(w.packets, string(p))
+	return len(p), nil
+}
+
+func TestSendLoop(t *testing.T) {
+	s := New("send.", log.NewNopLogger())
+	for i := 0; i < 20; i++ {
+		s.NewCounter(fmt.Sprintf("counter_%02d", i), 1.0).Add(1)
+	}
+	var (
+		w = &packetWriter{}
+		c = make(chan time.Time, 1)
+	)
+	c <- time.Now()
+	close(c)
+	s.sendLoop(c, w, SendMaxPacketSize(100))
+
+	var lines int
+	for _, p := range w.packets {
+		if len(p) > 100 {
+			t.Errorf("packet of %d bytes exceeds maximum", len(p))
+		}
+		lines += strings.Count(p, "\n")
+	}
+	if want, have := 20, lines; want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+	if len(w.packets) >= lines {
+		t.Errorf("%d lines weren't packed: %d packets", lines, len(w.packets))
+	}
+}
@@ -0,0 +1,217 @@
+package teststat
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// Operations recorded by a Recorder.
+const (
+	OpAdd     = "add"
+	OpSet     = "set"
+	OpObserve = "observe"
+)
+
+// Observation is a single call to Add, Set, or Observe on a metric produced by
+// a Recorder.
+type Observation struct {
+	Name        string
+	LabelValues []string
+	Op          string // OpAdd, OpSet, or OpObserve
+	Value       float64
+}
+
+// Recorder produces counters, gauges, and histograms which capture every
+// observation along with its label values, so that instrumented code, e.g.
+// middlewares, can be unit tested without a real backend. It satisfies the
+// metrics provider.Provider interface. Recorders are safe for concurrent use.
+//
+// The query and check methods select observations by metric name and label
+// values. An observation matches if it carries every given label key with the
+// given value, in any order; it may carry other labels too.
+type Recorder struct {
+	mtx sync.Mutex
+	obs []Observation
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewCounter returns a counter whose observations are recorded.
+func (r *Recorder) NewCounter(name string) metrics.Counter {
+	return recordedCounter{recorded{r: r, name: name}}
+}
+
+// NewGauge returns a gauge whose observations are recorded.
+func (r *Recorder) NewGauge(name string) metrics.Gauge {
+	return recordedGauge{recorded{r: r, name: name}}
+}
+
+// NewHistogram returns a histogram whose observations are recorded. Buckets
+// are ignored.
+func (r *Recorder) NewHistogram(name string, _ int) metrics.Histogram {
+	return recordedHistogram{recorded{r: r, name: name}}
+}
+
+// Stop is a no-op.
+func (r *Recorder) Stop() {}
+
+// Reset discards all recorded observations.
+func (r *Recorder) Reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.obs = nil
+}
+
+// Observations returns the recorded observations of the named metric with
+// matching label values, in the order they were made.
+func (r *Recorder) Observations(name string, labelValues ...string) []Observation {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	var obs []Observation
+	for _, o := range r.obs {
+		if o.Name == name && matches(o.LabelValues, labelValues) {
+			obs = append(obs, o)
+		}
+	}
+	return obs
+}
+
+// Sum returns the sum of all values added to the named counter or gauge with
+// matching label values.
+func (r *Recorder) Sum(name string, labelValues ...string) float64 {
+	var sum float64
+	for _, o := range r.Observations(name, labelValues...) {
+		if o.Op == OpAdd {
+			sum += o.Value
+		}
+	}
+	return sum
+}
+
+// Value returns the current value of the named gauge with matching label
+// values, by replaying its sets and adds in order.
+func (r *Recorder) Value(name string, labelValues ...string) float64 {
+	var value float64
+	for _, o := range r.Observations(name, labelValues...) {
+		switch o.Op {
+		case OpSet:
+			value = o.Value
+		case OpAdd:
+			value += o.Value
+		}
+	}
+	return value
+}
+
+// Values returns the values observed by the named histogram with matching
+// label values, in the order they were observed.
+func (r *Recorder) Values(name string, labelValues ...string) []float64 {
+	var values []float64
+	for _, o := range r.Observations(name, labelValues...) {
+		if o.Op == OpObserve {
+			values = append(values, o.Value)
+		}
+	}
+	return values
+}
+
+// CheckCounter returns an error unless the sum of the named counter with
+// matching label values is want.
+func (r *Recorder) CheckCounter(name string, want float64, labelValues ...string) error {
+	if have := r.Sum(name, labelValues...); want != have {
+		return fmt.Errorf("%s%v: want %f, have %f", name, labelValues, want, have)
+	}
+	return nil
+}
+
+// CheckGauge returns an error unless the current value of the named gauge with
+// matching label values is want.
+func (r *Recorder) CheckGauge(name string, want float64, labelValues ...string) error {
+	if have := r.Value(name, labelValues...); want != have {
+		return fmt.Errorf("%s%v: want %f, have %f", name, labelValues, want, have)
+	}
+	return nil
+}
+
+// CheckHistogram returns an error unless the named histogram with matching
+// label values made exactly want observations.
+func (r *Recorder) CheckHistogram(name string, want int, labelValues ...string) error {
+	if have := len(r.Values(name, labelValues...)); want != have {
+		return fmt.Errorf("%s%v: want %d observations, have %d", name, labelValues, want, have)
+	}
+	return nil
+}
+
+func (r *Recorder) record(name string, labelValues []string, op string, value float64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.obs = append(r.obs, Observation{
+		Name:        name,
+		LabelValues: labelValues,
+		Op:          op,
+		Value:       value,
+	})
+}
+
+// matches returns true if have contains every key-value pair in want.
+func matches(have, want []string) bool {
+	for i := 0; i+1 < len(want); i += 2 {
+		found := false
+		for j := 0; j+1 < len(have); j += 2 {
+			if have[j] == want[i] && have[j+1] == want[i+1] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type recorded struct {
+	r    *Recorder
+	name string
+	lvs  []string
+}
+
+func (m recorded) with(labelValues []string) recorded {
+	if len(labelValues)%2 != 0 {
+		labelValues = append(labelValues, "unknown")
+	}
+	lvs := make([]string, 0, len(m.lvs)+len(labelValues))
+	lvs = append(append(lvs, m.lvs...), labelValues...)
+	return recorded{r: m.r, name: m.name, lvs: lvs}
+}
+
+type recordedCounter struct{ recorded }
+
+func (c recordedCounter) With(labelValues ...string) metrics.Counter {
+	return recordedCounter{c.with(labelValues)}
+}
+
+func (c recordedCounter) Add(delta float64) { c.r.record(c.name, c.lvs, OpAdd, delta) }
+
+type recordedGauge struct{ recorded }
+
+func (g recordedGauge) With(labelValues ...string) metrics.Gauge {
+	return recordedGauge{g.with(labelValues)}
+}
+
+func (g recordedGauge) Set(value float64) { g.r.record(g.name, g.lvs, OpSet, value) }
+
+func (g recordedGauge) Add(delta float64) { g.r.record(g.name, g.lvs, OpAdd, delta) }
+
+type recordedHistogram struct{ recorded }
+
+func (h recordedHistogram) With(labelValues ...string) metrics.Histogram {
+	return recordedHistogram{h.with(labelValues)}
+}
+
+func (h recordedHistogram) Observe(value float64) { h.r.record(h.name, h.lvs, OpObserve, value) }
@@ -0,0 +1,60 @@
+package teststat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	requests := r.NewCounter("requests")
+	requests.With("method", "Sum", "code", "200").Add(1)
+	requests.With("method", "Sum").With("code", "500").Add(2)
+	requests.With("method", "Concat", "code", "200").Add(4)
+
+	for _, tc := range []struct {
+		labelValues []string
+		want        float64
+	}{
+		{nil, 7},
+		{[]string{"method", "Sum"}, 3},
+		{[]string{"code", "200"}, 5},
+		{[]string{"code", "500", "method", "Sum"}, 2},
+		{[]string{"method", "Nope"}, 0},
+	} {
+		if err := r.CheckCounter("requests", tc.want, tc.labelValues...); err != nil {
+			t.Error(err)
+		}
+	}
+
+	inFlight := r.NewGauge("in_flight").With("method", "Sum")
+	inFlight.Set(3)
+	inFlight.Add(1)
+	inFlight.Add(-2)
+	if err := r.CheckGauge("in_flight", 2, "method", "Sum"); err != nil {
+		t.Error(err)
+	}
+
+	duration := r.NewHistogram("duration", 50)
+	duration.With("method", "Sum").Observe(0.5)
+	duration.With("method", "Sum").Observe(1.5)
+	if err := r.CheckHistogram("duration", 2, "method", "Sum"); err != nil {
+		t.Error(err)
+	}
+	if want, have := []float64{0.5, 1.5}, r.Values("duration"); !reflect.DeepEqual(want, have) {
+		t.Errorf("Values: want %v, have %v", want, have)
+	}
+	if err := r.CheckHistogram("duration", 1); err == nil {
+		t.Errorf("want error, have none")
+	}
+
+	want := Observation{Name: "requests", LabelValues: []string{"method", "Sum", "code", "500"}, Op: OpAdd, Value: 2}
+	if have := r.Observations("requests", "code", "500"); len(have) != 1 || !reflect.DeepEqual(want, have[0]) {
+		t.Errorf("Observations: want [%v], have %v", want, have)
+	}
+
+	r.Reset()
+	if have := r.Observations("requests"); len(have) != 0 {
+		t.Errorf("after Reset: want no observations, have %v", have)
+	}
+}
@@ -1,4 +1,5 @@
-// Package teststat provides helpers for testing metrics backends.
+// Package teststat provides helpers for testing metrics backends, and a
+// Recorder for testing code which is instrumented with metrics.
 package teststat
 
 import (
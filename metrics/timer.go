@@ -1,28 +1,70 @@
 package metrics
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Timer acts as a stopwatch, sending observations to a wrapped histogram.
 // It's a bit of helpful syntax sugar for h.Observe(time.Since(x)).
 type Timer struct {
-	h Histogram
-	t time.Time
+	h   Histogram
+	t   time.Time
+	now func() time.Time
+}
+
+// TimerOption sets an optional parameter for a Timer.
+type TimerOption func(*Timer)
+
+// TimerClock sets the function the timer uses to read the current time. It's
+// intended for deterministic tests. By default, time.Now is used, whose
+// results carry a monotonic clock reading, so durations are unaffected by
+// changes to the wall clock.
+func TimerClock(now func() time.Time) TimerOption {
+	return func(t *Timer) { t.now = now }
 }
 
 // NewTimer wraps the given histogram and records the current time.
-func NewTimer(h Histogram) *Timer {
-	return &Timer{
-		h: h,
-		t: time.Now(),
+func NewTimer(h Histogram, options ...TimerOption) *Timer {
+	t := &Timer{
+		h:   h,
+		now: time.Now,
 	}
+	for _, option := range options {
+		option(t)
+	}
+	t.t = t.now()
+	return t
+}
+
+// ContextObserver is implemented by histograms which can derive additional
+// information about an observation, such as an exemplar, from a context. The
+// Prometheus Histogram is one example.
+type ContextObserver interface {
+	ObserveContext(ctx context.Context, value float64)
 }
 
 // ObserveDuration captures the number of seconds since the timer was
 // constructed, and forwards that observation to the histogram.
 func (t *Timer) ObserveDuration() {
-	d := time.Since(t.t).Seconds()
+	t.h.Observe(t.elapsed())
+}
+
+// ObserveDurationContext is like ObserveDuration, but if the histogram
+// implements ContextObserver, the observation is made with the passed
+// context, which allows it to be annotated with e.g. a trace ID exemplar.
+func (t *Timer) ObserveDurationContext(ctx context.Context) {
+	if co, ok := t.h.(ContextObserver); ok {
+		co.ObserveContext(ctx, t.elapsed())
+		return
+	}
+	t.h.Observe(t.elapsed())
+}
+
+func (t *Timer) elapsed() float64 {
+	d := t.now().Sub(t.t).Seconds()
 	if d < 0 {
 		d = 0
 	}
-	t.h.Observe(d)
+	return d
 }
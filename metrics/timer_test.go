@@ -1,6 +1,8 @@
 package metrics_test
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"testing"
 
@@ -31,3 +33,64 @@ func TestTimerSlow(t *testing.T) {
 		t.Errorf("want %.3f, have %.3f", want, have)
 	}
 }
+
+func TestTimerClock(t *testing.T) {
+	var (
+		h     = generic.NewHistogram("timer_clock", 50)
+		clock = newFakeClock()
+		timer = metrics.NewTimer(h, metrics.TimerClock(clock.Now))
+	)
+	clock.Advance(1500 * time.Millisecond)
+	timer.ObserveDuration()
+	clock.Advance(-time.Hour) // time travel is clamped
+	timer.ObserveDuration()
+
+	if want, have := 1.5, h.Max(); want != have {
+		t.Errorf("Max: want %.3f, have %.3f", want, have)
+	}
+	if want, have := 0.0, h.Min(); want != have {
+		t.Errorf("Min: want %.3f, have %.3f", want, have)
+	}
+}
+
+func TestTimerObserveDurationContext(t *testing.T) {
+	var (
+		clock = newFakeClock()
+		co    = &contextObserver{}
+		timer = metrics.NewTimer(co, metrics.TimerClock(clock.Now))
+		ctx   = context.WithValue(context.Background(), traceKey{}, "abc")
+	)
+	clock.Advance(250 * time.Millisecond)
+	timer.ObserveDurationContext(ctx)
+
+	if want, have := "abc 0.250", co.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Histograms which aren't ContextObservers are observed normally.
+	h := generic.NewHistogram("plain", 50)
+	metrics.NewTimer(h, metrics.TimerClock(clock.Now)).ObserveDurationContext(ctx)
+	if want, have := uint64(1), h.Count(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+type traceKey struct{}
+
+type fakeClock struct{ t time.Time }
+
+func newFakeClock() *fakeClock               { return &fakeClock{t: time.Unix(1000, 0)} }
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+type contextObserver struct {
+	trace interface{}
+	value float64
+}
+
+func (o *contextObserver) With(...string) metrics.Histogram { return o }
+func (o *contextObserver) Observe(value float64)            { o.value = value }
+func (o *contextObserver) ObserveContext(ctx context.Context, value float64) {
+	o.trace, o.value = ctx.Value(traceKey{}), value
+}
+func (o *contextObserver) String() string { return fmt.Sprintf("%v %.3f", o.trace, o.value) }
@@ -0,0 +1,236 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// AdaptiveLimiter is a rate limiter whose rate adapts to the feedback of the
+// downstream, so that clients tune themselves instead of being configured
+// with a static rate. The rate increases additively while requests succeed,
+// and decreases multiplicatively, at most once a second, as requests fail
+// or their average latency exceeds a target. When the downstream throttles
+// a request, e.g. with 429 Too Many Requests, the rate decreases and
+// requests are paused for its Retry-After. Feedback is reported by the
+// middleware of NewFeedback, or by calling Success, Failure, and Throttled.
+// It implements Allower and Waiter.
+type AdaptiveLimiter struct {
+	min, max float64
+	increase float64
+	backoff  float64
+	burst    int
+	target   time.Duration
+
+	mtx       sync.Mutex
+	rate      float64
+	tat       time.Time // the theoretical arrival time of the next request
+	paused    time.Time // until
+	decreased time.Time
+	latency   float64 // the average latency, in seconds
+}
+
+var (
+	_ Allower = (*AdaptiveLimiter)(nil)
+	_ Waiter  = (*AdaptiveLimiter)(nil)
+)
+
+// AdaptiveOption sets an optional parameter for adaptive limiters.
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// AdaptiveIncrease sets how much the rate increases per second while
+// requests succeed, in requests per second. By default, it's 1.
+func AdaptiveIncrease(step float64) AdaptiveOption {
+	return func(l *AdaptiveLimiter) { l.increase = step }
+}
+
+// AdaptiveBackoff sets the factor the rate is multiplied by as requests fail
+// or are throttled. By default, it's 0.7.
+func AdaptiveBackoff(factor float64) AdaptiveOption {
+	return func(l *AdaptiveLimiter) { l.backoff = factor }
+}
+
+// AdaptiveBurst sets the size of the bursts of requests allowed. By default,
+// it's 1.
+func AdaptiveBurst(n int) AdaptiveOption {
+	return func(l *AdaptiveLimiter) { l.burst = n }
+}
+
+// AdaptiveLatencyTarget sets the target of the average latency of the
+// requests, above which the rate decreases. By default, latency is ignored.
+func AdaptiveLatencyTarget(d time.Duration) AdaptiveOption {
+	return func(l *AdaptiveLimiter) { l.target = d }
+}
+
+// NewAdaptiveLimiter returns an AdaptiveLimiter which allows initial
+// requests per second, adapting between min and max.
+func NewAdaptiveLimiter(initial, min, max float64, options ...AdaptiveOption) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		min:      min,
+		max:      max,
+		increase: 1,
+		backoff:  0.7,
+		burst:    1,
+		rate:     initial,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Rate returns the current rate, in requests per second.
+func (l *AdaptiveLimiter) Rate() float64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.rate
+}
+
+// Allow implements Allower.
+func (l *AdaptiveLimiter) Allow() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.take(time.Now()) <= 0
+}
+
+// Wait implements Waiter. If the context of the request would be done before
+// it's allowed, an error is returned immediately.
+func (l *AdaptiveLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mtx.Lock()
+		now := time.Now()
+		delay := l.take(now)
+		l.mtx.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+			return context.DeadlineExceeded
+		}
+
+		// The rate may change meanwhile, so it's checked again.
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take takes a request, and returns 0, or returns how long until one may be
+// taken.
+func (l *AdaptiveLimiter) take(now time.Time) time.Duration {
+	if now.Before(l.paused) {
+		return l.paused.Sub(now)
+	}
+	interval := time.Duration(float64(time.Second) / l.rate)
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if delay := tat.Add(interval).Sub(now) - interval*time.Duration(l.burst); delay > 0 {
+		return delay
+	}
+	l.tat = tat.Add(interval)
+	return 0
+}
+
+// Success reports a request which succeeded with the latency.
+func (l *AdaptiveLimiter) Success(latency time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.latency == 0 {
+		l.latency = latency.Seconds()
+	} else {
+		l.latency += 0.1 * (latency.Seconds() - l.latency)
+	}
+	if l.target > 0 && l.latency > l.target.Seconds() {
+		l.decrease(time.Now())
+		return
+	}
+	// Each success is a fraction of a second at the rate.
+	l.rate = math.Min(l.max, l.rate+l.increase/l.rate)
+}
+
+// Failure reports a request which failed, e.g. as the downstream was
+// unavailable.
+func (l *AdaptiveLimiter) Failure() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.decrease(time.Now())
+}
+
+// Throttled reports a request which the downstream throttled, and pauses
+// requests for retryAfter, if it's positive.
+func (l *AdaptiveLimiter) Throttled(retryAfter time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := time.Now()
+	l.decrease(now)
+	if until := now.Add(retryAfter); until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// decrease decreases the rate, at most once a second, so that the failures
+// of requests in flight together count once.
+func (l *AdaptiveLimiter) decrease(now time.Time) {
+	if now.Sub(l.decreased) < time.Second {
+		return
+	}
+	l.rate = math.Max(l.min, l.rate*l.backoff)
+	l.decreased = now
+}
+
+// NewFeedback returns an endpoint.Middleware that reports the feedback of
+// the downstream to the AdaptiveLimiter. It wraps the client endpoint,
+// inside the limiter, e.g.
+//
+//	e = ratelimit.NewFeedback(l)(e)
+//	e = ratelimit.NewDelayingLimiter(l)(e)
+//
+// Errors of kind endpoint.KindResourceExhausted, with status code 429, and
+// ErrLimited are throttled requests; their RetryAfter method, if they have
+// one, e.g. that of the errors of package transport/grpc, is the pause.
+// Errors which are the fault of the caller, such as
+// endpoint.KindInvalidArgument, and those of canceled requests are ignored.
+// Other errors are failures.
+func NewFeedback(l *AdaptiveLimiter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			begin := time.Now()
+			response, err := next(ctx, request)
+			switch {
+			case err == nil:
+				l.Success(time.Since(begin))
+			case isThrottled(err):
+				var retryAfter time.Duration
+				if e, ok := err.(interface{ RetryAfter() time.Duration }); ok {
+					retryAfter = e.RetryAfter()
+				}
+				l.Throttled(retryAfter)
+			case ctx.Err() == context.Canceled:
+			default:
+				switch endpoint.KindOf(err) {
+				case endpoint.KindUnknown, endpoint.KindDeadlineExceeded, endpoint.KindUnavailable, endpoint.KindInternal:
+					l.Failure()
+				}
+			}
+			return response, err
+		}
+	}
+}
+
+func isThrottled(err error) bool {
+	if err == ErrLimited || endpoint.KindOf(err) == endpoint.KindResourceExhausted {
+		return true
+	}
+	e, ok := err.(interface{ StatusCode() int })
+	return ok && e.StatusCode() == http.StatusTooManyRequests
+}
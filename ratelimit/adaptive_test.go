@@ -0,0 +1,92 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	max := 12.0
+	l := ratelimit.NewAdaptiveLimiter(10, 1, max, ratelimit.AdaptiveIncrease(10))
+	e := ratelimit.NewFeedback(l)(nopEndpoint)
+
+	// Each success increases the rate by increase/rate, up to the max.
+	if _, err := e(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 11.0, l.Rate(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	for i := 0; i < 10; i++ {
+		e(context.Background(), struct{}{})
+	}
+	if want, have := max, l.Rate(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Failures decrease the rate once a second, down to the min.
+	failing := ratelimit.NewFeedback(l)(func(context.Context, interface{}) (interface{}, error) {
+		return nil, endpoint.Unavailable(errors.New("unavailable"))
+	})
+	failing(context.Background(), struct{}{})
+	failing(context.Background(), struct{}{})
+	if want, have := max*0.7, l.Rate(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Errors which are the fault of the caller are ignored.
+	invalid := ratelimit.NewFeedback(l)(func(context.Context, interface{}) (interface{}, error) {
+		return nil, endpoint.InvalidArgument(errors.New("invalid"))
+	})
+	invalid(context.Background(), struct{}{})
+	if want, have := max*0.7, l.Rate(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+type retryAfterError time.Duration
+
+func (e retryAfterError) Error() string             { return "too many requests" }
+func (e retryAfterError) StatusCode() int           { return 429 }
+func (e retryAfterError) RetryAfter() time.Duration { return time.Duration(e) }
+
+func TestAdaptiveLimiterThrottled(t *testing.T) {
+	l := ratelimit.NewAdaptiveLimiter(1000, 1, 1000)
+	e := ratelimit.NewFeedback(l)(func(context.Context, interface{}) (interface{}, error) {
+		return nil, retryAfterError(20 * time.Millisecond)
+	})
+	e(context.Background(), struct{}{})
+	if want, have := 700.0, l.Rate(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Requests are paused for the Retry-After.
+	if l.Allow() {
+		t.Error("want disallowed while paused")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	begin := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed < 10*time.Millisecond {
+		t.Errorf("want a wait for the Retry-After, have %s", elapsed)
+	}
+}
+
+func TestAdaptiveLimiterLatencyTarget(t *testing.T) {
+	l := ratelimit.NewAdaptiveLimiter(100, 1, 1000, ratelimit.AdaptiveLatencyTarget(time.Millisecond))
+	l.Success(10 * time.Millisecond)
+	if want, have := 70.0, l.Rate(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
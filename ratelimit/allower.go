@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Allower dictates whether a request may run, e.g. as it's within a rate
+// limit. The Limiter of golang.org/x/time/rate implements it.
+type Allower interface {
+	Allow() bool
+}
+
+// AllowerFunc is an adapter to allow the use of an ordinary function as an
+// Allower.
+type AllowerFunc func() bool
+
+// Allow implements Allower.
+func (f AllowerFunc) Allow() bool { return f() }
+
+// NewErroringLimiter returns an endpoint.Middleware that acts as a rate
+// limiter. Requests that limit doesn't allow are simply rejected with
+// ErrLimited.
+func NewErroringLimiter(limit Allower, options ...Option) endpoint.Middleware {
+	l := configure(options)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !limit.Allow() {
+				return nil, l.deny(ctx, ErrLimited)
+			}
+			l.allow(0)
+			return next(ctx, request)
+		}
+	}
+}
+
+// Waiter blocks until a request may run, e.g. as it's within a rate limit,
+// or until the context is done, in which case it returns an error. The
+// Limiter of golang.org/x/time/rate implements it.
+type Waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// WaiterFunc is an adapter to allow the use of an ordinary function as a
+// Waiter.
+type WaiterFunc func(ctx context.Context) error
+
+// Wait implements Waiter.
+func (f WaiterFunc) Wait(ctx context.Context) error { return f(ctx) }
+
+// DelayingMargin bounds the waits of requests with a deadline through
+// delaying limiters to the deadline minus the margin, so that they're left
+// the time to be served. Requests which limit wouldn't allow by then are
+// rejected with ErrLimited, as soon as limit tells. By default, there's no
+// margin.
+func DelayingMargin(d time.Duration) Option {
+	return func(l *limiter) { l.margin = d }
+}
+
+// DelayingMaxWaiters sets the maximum number of requests waiting through
+// delaying limiters; requests beyond it are rejected immediately with
+// ErrLimited, rather than queued past the point of usefulness. By default,
+// there's no maximum.
+func DelayingMaxWaiters(n int) Option {
+	return func(l *limiter) { l.maxWaiters = int64(n) }
+}
+
+// NewDelayingLimiter returns an endpoint.Middleware that acts as a request
+// throttler. Requests are delayed until limit allows them, and fail with its
+// error if it doesn't, e.g. as their context is done. Waits are bounded by
+// the deadline of the request, and requests are rejected with ErrLimited if
+// they would wait past it.
+func NewDelayingLimiter(limit Waiter, options ...Option) endpoint.Middleware {
+	l := configure(options)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			begin := time.Now()
+			if err := l.wait(ctx, limit); err != nil {
+				return nil, l.deny(ctx, err)
+			}
+			l.allow(time.Since(begin))
+			return next(ctx, request)
+		}
+	}
+}
+
+func (l *limiter) wait(ctx context.Context, limit Waiter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.maxWaiters > 0 {
+		defer atomic.AddInt64(&l.waiters, -1)
+		if atomic.AddInt64(&l.waiters, 1) > l.maxWaiters {
+			return ErrLimited
+		}
+	}
+	waitCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		deadline = deadline.Add(-l.margin)
+		if !time.Now().Before(deadline) {
+			return ErrLimited
+		}
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	if err := limit.Wait(waitCtx); err != nil {
+		// Unless the request itself is done, it failed as it would have
+		// waited past its deadline.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, ok := ctx.Deadline(); ok {
+			return ErrLimited
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jujuratelimit "github.com/juju/ratelimit"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestErroringLimiter(t *testing.T) {
+	e := func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
+	for _, n := range []int{1, 2, 100} {
+		tb := jujuratelimit.NewBucketWithRate(float64(n), int64(n))
+		limit := ratelimit.AllowerFunc(func() bool { return tb.TakeAvailable(1) == 1 })
+		testLimiter(t, ratelimit.NewErroringLimiter(limit)(e), n)
+	}
+}
+
+func TestDelayingLimiterMargin(t *testing.T) {
+	e := ratelimit.NewDelayingLimiter(
+		ratelimit.NewGCRA(10, 1),
+		ratelimit.DelayingMargin(50*time.Millisecond),
+	)(nopEndpoint)
+	if _, err := e(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next request is allowed in 100ms, past its deadline less the margin.
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if _, err := e(ctx, struct{}{}); err != ratelimit.ErrLimited {
+		t.Errorf("want %v, have %v", ratelimit.ErrLimited, err)
+	}
+	if elapsed := time.Since(begin); elapsed > 50*time.Millisecond {
+		t.Errorf("want rejected immediately, have %s", elapsed)
+	}
+	if want, have := endpoint.KindResourceExhausted, endpoint.KindOf(ratelimit.ErrLimited); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestDelayingLimiterMaxWaiters(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		waiting = make(chan struct{})
+		limit   = ratelimit.WaiterFunc(func(ctx context.Context) error {
+			waiting <- struct{}{}
+			<-release
+			return nil
+		})
+		e = ratelimit.NewDelayingLimiter(limit, ratelimit.DelayingMaxWaiters(1))(nopEndpoint)
+	)
+	errs := make(chan error)
+	go func() {
+		_, err := e(context.Background(), struct{}{})
+		errs <- err
+	}()
+	<-waiting
+	if _, err := e(context.Background(), struct{}{}); err != ratelimit.ErrLimited {
+		t.Errorf("want %v, have %v", ratelimit.ErrLimited, err)
+	}
+	close(release)
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// GCRA is a rate limiter implementing the generic cell rate algorithm. It
+// allows a rate of requests with bursts of a limited size, like a token
+// bucket, but spaces the requests beyond a burst evenly, and its state is a
+// single time. It implements Allower and Waiter.
+type GCRA struct {
+	interval  time.Duration // between requests at the rate
+	tolerance time.Duration // of bursts
+
+	mtx sync.Mutex
+	tat time.Time // the theoretical arrival time of the next request
+}
+
+var (
+	_ Allower = (*GCRA)(nil)
+	_ Waiter  = (*GCRA)(nil)
+)
+
+// NewGCRA returns a GCRA which allows rate requests per second, with bursts
+// of up to burst requests. A burst of 1 allows no bursts, so that requests
+// are spaced by 1/rate seconds.
+func NewGCRA(rate float64, burst int) *GCRA {
+	if burst < 1 {
+		burst = 1
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	return &GCRA{
+		interval:  interval,
+		tolerance: interval * time.Duration(burst),
+	}
+}
+
+// Allow implements Allower.
+func (g *GCRA) Allow() bool {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	now := time.Now()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if tat.Add(g.interval).Sub(now) > g.tolerance {
+		return false
+	}
+	g.tat = tat.Add(g.interval)
+	return true
+}
+
+// Wait implements Waiter. The request is scheduled after those waiting
+// already, and if its context would be done first, an error is returned
+// immediately.
+func (g *GCRA) Wait(ctx context.Context) error {
+	g.mtx.Lock()
+	now := time.Now()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	delay := tat.Add(g.interval).Sub(now) - g.tolerance
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		g.mtx.Unlock()
+		return context.DeadlineExceeded
+	}
+	g.tat = tat.Add(g.interval)
+	g.mtx.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Give the request's slot back.
+		g.mtx.Lock()
+		g.tat = g.tat.Add(-g.interval)
+		g.mtx.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,50 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestGCRA(t *testing.T) {
+	g := ratelimit.NewGCRA(100, 2)
+	for i, want := range []bool{true, true, false} {
+		if have := g.Allow(); want != have {
+			t.Errorf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if !g.Allow() {
+		t.Error("want allowed after the interval")
+	}
+}
+
+func TestGCRAWait(t *testing.T) {
+	var (
+		g     = ratelimit.NewGCRA(100, 1)
+		e     = ratelimit.NewDelayingLimiter(g)(nopEndpoint)
+		begin = time.Now()
+	)
+	for i := 0; i < 3; i++ {
+		if _, err := e(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(begin); elapsed < 20*time.Millisecond {
+		t.Errorf("want requests spaced by 10ms, have 3 in %s", elapsed)
+	}
+
+	// A request which would wait past its deadline fails immediately.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := g.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	if _, err := e(ctx, struct{}{}); err != ratelimit.ErrLimited {
+		t.Errorf("want %v, have %v", ratelimit.ErrLimited, err)
+	}
+}
+
+func nopEndpoint(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Option sets an optional parameter for the middlewares of rate limiters.
+type Option func(*limiter)
+
+// Metrics are the metrics of the middleware of a rate limiter, so that
+// throttling is visible. Metrics which are nil aren't reported.
+type Metrics struct {
+	// Allowed and Denied count the requests, by whether they were allowed.
+	Allowed metrics.Counter
+	Denied  metrics.Counter
+
+	// WaitTime observes the seconds each allowed request waited, through
+	// delaying limiters.
+	WaitTime metrics.Histogram
+}
+
+// Instrument reports the metrics of the middleware.
+func Instrument(m Metrics) Option {
+	return func(l *limiter) { l.metrics = m }
+}
+
+// OnDenied sets a function which is called with the context and the error of
+// each denied request, and returns the error the request fails with instead,
+// e.g. to log it, or to hint when to retry with WithRetryAfter.
+func OnDenied(f func(ctx context.Context, err error) error) Option {
+	return func(l *limiter) { l.onDenied = f }
+}
+
+// limiter holds the options of the middleware of a rate limiter.
+type limiter struct {
+	waiters    int64 // atomic; first, for its alignment
+	margin     time.Duration
+	maxWaiters int64
+	metrics    Metrics
+	onDenied   func(context.Context, error) error
+}
+
+func configure(options []Option) *limiter {
+	l := &limiter{}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// allow records a request which was allowed after the wait.
+func (l *limiter) allow(wait time.Duration) {
+	if l.metrics.Allowed != nil {
+		l.metrics.Allowed.Add(1)
+	}
+	if l.metrics.WaitTime != nil {
+		l.metrics.WaitTime.Observe(wait.Seconds())
+	}
+}
+
+// deny records a request which was denied, and returns its error.
+func (l *limiter) deny(ctx context.Context, err error) error {
+	if l.metrics.Denied != nil {
+		l.metrics.Denied.Add(1)
+	}
+	if l.onDenied != nil {
+		return l.onDenied(ctx, err)
+	}
+	return err
+}
+
+// WithRetryAfter returns an error which wraps err with a hint of when the
+// request may be retried. Transports relay it: package transport/http's
+// DefaultErrorEncoder sets the Retry-After header, and package
+// transport/grpc sends it as RetryInfo. The kind of the error is that of err.
+func WithRetryAfter(err error, d time.Duration) error {
+	return retryAfterError{err: err, retryAfter: d}
+}
+
+type retryAfterError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e retryAfterError) Error() string { return e.err.Error() }
+
+// Unwrap returns the wrapped error.
+func (e retryAfterError) Unwrap() error { return e.err }
+
+// Kind returns the kind of the wrapped error.
+func (e retryAfterError) Kind() endpoint.Kind { return endpoint.KindOf(e.err) }
+
+// StatusCode returns the HTTP status code of the kind of the wrapped error.
+func (e retryAfterError) StatusCode() int { return e.Kind().StatusCode() }
+
+// RetryAfter returns the hint of when the request may be retried.
+func (e retryAfterError) RetryAfter() time.Duration { return e.retryAfter }
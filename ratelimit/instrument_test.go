@@ -0,0 +1,69 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestInstrument(t *testing.T) {
+	var (
+		m = ratelimit.Metrics{
+			Allowed:  generic.NewCounter("allowed"),
+			Denied:   generic.NewCounter("denied"),
+			WaitTime: generic.NewHistogram("wait_time", 10),
+		}
+		e = ratelimit.NewDelayingLimiter(ratelimit.NewGCRA(100, 1), ratelimit.Instrument(m))(nopEndpoint)
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := e(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	e(ctx, struct{}{})
+
+	if want, have := 2.0, m.Allowed.(*generic.Counter).Value(); want != have {
+		t.Errorf("allowed: want %v, have %v", want, have)
+	}
+	if want, have := 1.0, m.Denied.(*generic.Counter).Value(); want != have {
+		t.Errorf("denied: want %v, have %v", want, have)
+	}
+	if want, have := uint64(2), m.WaitTime.(*generic.Histogram).Count(); want != have {
+		t.Errorf("wait time: want %d observations, have %d", want, have)
+	}
+	if max := m.WaitTime.(*generic.Histogram).Max(); max < 0.005 {
+		t.Errorf("wait time: want the second request to wait, have max %v", max)
+	}
+}
+
+func TestOnDenied(t *testing.T) {
+	var (
+		key  = struct{}{}
+		seen interface{}
+		e    = ratelimit.NewErroringLimiter(
+			ratelimit.AllowerFunc(func() bool { return false }),
+			ratelimit.OnDenied(func(ctx context.Context, err error) error {
+				seen = ctx.Value(key)
+				return ratelimit.WithRetryAfter(err, 1500*time.Millisecond)
+			}),
+		)(nopEndpoint)
+	)
+	_, err := e(context.WithValue(context.Background(), key, "client"), struct{}{})
+	if want, have := "client", seen; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// The error carries the hint for the transports.
+	if want, have := http.StatusTooManyRequests, err.(interface{ StatusCode() int }).StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := 1500*time.Millisecond, err.(interface{ RetryAfter() time.Duration }).RetryAfter(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// KeyFunc returns the key of the client of a request, e.g. its API key, user
+// ID or IP address, from the context or the request.
+type KeyFunc func(ctx context.Context, request interface{}) string
+
+// NewKeyedLimiter returns an endpoint.Middleware that acts as a rate limiter
+// per key of the requests, so that one noisy client is throttled without
+// affecting the others. The limiter of a key is created by newLimiter on its
+// first request, and is forgotten once the key is idle for expiry. Requests
+// that the limiter of their key doesn't allow are rejected with ErrLimited.
+func NewKeyedLimiter(key KeyFunc, newLimiter func(key string) Allower, expiry time.Duration, options ...Option) endpoint.Middleware {
+	l := configure(options)
+	k := &keyedLimiters{
+		newLimiter: newLimiter,
+		expiry:     expiry,
+		limiters:   map[string]*keyedLimiter{},
+		swept:      time.Now(),
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !k.get(key(ctx, request)).Allow() {
+				return nil, l.deny(ctx, ErrLimited)
+			}
+			l.allow(0)
+			return next(ctx, request)
+		}
+	}
+}
+
+type keyedLimiters struct {
+	newLimiter func(string) Allower
+	expiry     time.Duration
+
+	mtx      sync.Mutex
+	limiters map[string]*keyedLimiter
+	swept    time.Time
+}
+
+type keyedLimiter struct {
+	Allower
+	used time.Time
+}
+
+// get returns the limiter of the key. Idle limiters are swept at most once
+// per expiry.
+func (k *keyedLimiters) get(key string) Allower {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	now := time.Now()
+	if now.Sub(k.swept) >= k.expiry {
+		for key, l := range k.limiters {
+			if now.Sub(l.used) >= k.expiry {
+				delete(k.limiters, key)
+			}
+		}
+		k.swept = now
+	}
+	l, ok := k.limiters[key]
+	if !ok {
+		l = &keyedLimiter{Allower: k.newLimiter(key)}
+		k.limiters[key] = l
+	}
+	l.used = now
+	return l.Allower
+}
@@ -0,0 +1,44 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jujuratelimit "github.com/juju/ratelimit"
+
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	var (
+		created = map[string]int{}
+		key     = func(_ context.Context, request interface{}) string { return request.(string) }
+		limiter = ratelimit.NewKeyedLimiter(key, func(key string) ratelimit.Allower {
+			created[key]++
+			tb := jujuratelimit.NewBucketWithRate(0.001, 1)
+			return ratelimit.AllowerFunc(func() bool { return tb.TakeAvailable(1) == 1 })
+		}, 20*time.Millisecond)
+		e = limiter(func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil })
+	)
+
+	// The noisy client a is throttled, but not b.
+	if _, err := e(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e(context.Background(), "a"); err != ratelimit.ErrLimited {
+		t.Fatalf("want %v, have %v", ratelimit.ErrLimited, err)
+	}
+	if _, err := e(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once idle, the limiter of a is forgotten.
+	time.Sleep(30 * time.Millisecond)
+	if _, err := e(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, created["a"]; want != have {
+		t.Errorf("want %d limiters of a, have %d", want, have)
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// PriorityLimiter is a rate limiter which keeps separate budgets per priority
+// class of requests, e.g. interactive and batch. Each class may be guaranteed
+// a minimum share of the rate, which requests of other classes can't take
+// from it; beyond their shares, classes use the spare capacity, as long as
+// it isn't reserved for another class.
+type PriorityLimiter struct {
+	rate  float64
+	burst int
+
+	mtx      sync.Mutex
+	total    *bucket
+	reserved map[endpoint.Priority]*bucket
+}
+
+// PriorityOption sets an optional parameter for priority limiters.
+type PriorityOption func(*PriorityLimiter)
+
+// PriorityReserve guarantees requests of the priority a rate, in requests
+// per second, of the limiter's rate, with a share of its bursts. The rates
+// reserved for all priorities shouldn't exceed the limiter's rate. By
+// default, no rate is reserved.
+func PriorityReserve(p endpoint.Priority, rate float64) PriorityOption {
+	return func(l *PriorityLimiter) {
+		burst := math.Max(1, float64(l.burst)*rate/l.rate)
+		l.reserved[p] = newBucket(rate, burst)
+	}
+}
+
+// NewPriorityLimiter returns a PriorityLimiter which allows rate requests
+// per second, with bursts of up to burst requests, across all priorities.
+func NewPriorityLimiter(rate float64, burst int, options ...PriorityOption) *PriorityLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	l := &PriorityLimiter{
+		rate:     rate,
+		burst:    burst,
+		total:    newBucket(rate, float64(burst)),
+		reserved: map[endpoint.Priority]*bucket{},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Allow reports whether a request of the priority may run. It's allowed
+// within the rate reserved for its priority, or else if there's capacity
+// beyond that reserved for the others.
+func (l *PriorityLimiter) Allow(p endpoint.Priority) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := time.Now()
+	l.total.refill(now)
+	if b, ok := l.reserved[p]; ok {
+		b.refill(now)
+		if b.tokens >= 1 {
+			// The share of the total may be overdrawn by requests of other
+			// priorities, which it then pays back.
+			b.tokens--
+			l.total.tokens--
+			return true
+		}
+	}
+	var reserved float64
+	for q, b := range l.reserved {
+		if q != p {
+			b.refill(now)
+			reserved += math.Floor(b.tokens)
+		}
+	}
+	if l.total.tokens-reserved < 1 {
+		return false
+	}
+	l.total.tokens--
+	return true
+}
+
+// NewPriorityErroringLimiter returns an endpoint.Middleware that acts as a
+// rate limiter per priority of the requests, taken from the context by
+// endpoint.PriorityFromContext. Requests that limit doesn't allow are
+// rejected with ErrLimited.
+func NewPriorityErroringLimiter(limit *PriorityLimiter, options ...Option) endpoint.Middleware {
+	l := configure(options)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !limit.Allow(endpoint.PriorityFromContext(ctx)) {
+				return nil, l.deny(ctx, ErrLimited)
+			}
+			l.allow(0)
+			return next(ctx, request)
+		}
+	}
+}
+
+// bucket is a token bucket, refilled lazily.
+type bucket struct {
+	rate, burst float64
+	tokens      float64
+	refilled    time.Time
+}
+
+func newBucket(rate, burst float64) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, refilled: time.Now()}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.refilled).Seconds()*b.rate)
+	b.refilled = now
+}
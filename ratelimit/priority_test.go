@@ -0,0 +1,41 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestPriorityLimiter(t *testing.T) {
+	l := ratelimit.NewPriorityLimiter(100, 10,
+		ratelimit.PriorityReserve(endpoint.PriorityHigh, 40),
+	)
+
+	// Low priority requests use the capacity beyond the 4 reserved for high
+	// priority requests, which may still burst.
+	for i, want := range []bool{true, true, true, true, true, true, false} {
+		if have := l.Allow(endpoint.PriorityLow); want != have {
+			t.Errorf("low %d: want %v, have %v", i, want, have)
+		}
+	}
+	for i, want := range []bool{true, true, true, true, false} {
+		if have := l.Allow(endpoint.PriorityHigh); want != have {
+			t.Errorf("high %d: want %v, have %v", i, want, have)
+		}
+	}
+
+}
+
+func TestPriorityLimiterSpare(t *testing.T) {
+	// Without reserves, any priority uses the whole capacity.
+	l := ratelimit.NewPriorityLimiter(100, 2)
+	e := ratelimit.NewPriorityErroringLimiter(l)(nopEndpoint)
+	ctx := endpoint.ContextWithPriority(context.Background(), endpoint.PriorityLow)
+	for i, want := range []error{nil, nil, ratelimit.ErrLimited} {
+		if _, have := e(ctx, struct{}{}); want != have {
+			t.Errorf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package redis provides a rate limiter which enforces a rate limit across
+// all instances of a service, with the state of the limit kept in Redis.
+package redis
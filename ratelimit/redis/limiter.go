@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	jujuratelimit "github.com/juju/ratelimit"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+// Client is the Redis client of a Limiter. It's the subset of a client which
+// the Limiter uses, so that any client library may be adapted to it, e.g.
+// that of github.com/go-redis/redis with
+//
+//	type client struct{ *redis.Client }
+//
+//	func (c client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//	    return c.Client.Eval(ctx, script, keys, args...).Result()
+//	}
+type Client interface {
+	// Eval evaluates the Lua script with the keys and arguments, and
+	// returns its result, e.g. an int64 for a Lua number.
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// gcra implements the generic cell rate algorithm, which is equivalent to a
+// token bucket, with the theoretical arrival time of the next request kept
+// at the key, in microseconds of the clock of Redis, so that the clocks of
+// the instances don't matter. ARGV[1] is the interval between requests at
+// the rate, and ARGV[2] is the tolerance of bursts, in microseconds. Before
+// Redis 5, scripts which read the clock may only write once they replicate
+// their effects, rather than themselves, which later versions always do.
+const gcra = `
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+if new_tat - now > tolerance then
+	return 0
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+return 1
+`
+
+// Limiter is a ratelimit.Allower which enforces a rate limit across all
+// instances of a service, with the state of the limit kept in Redis. If
+// Redis is unreachable, or too slow, requests are allowed by a local
+// fallback limiter instead.
+type Limiter struct {
+	client    Client
+	key       string
+	interval  time.Duration
+	tolerance time.Duration
+	timeout   time.Duration
+	fallback  ratelimit.Allower
+	logger    log.Logger
+}
+
+var _ ratelimit.Allower = (*Limiter)(nil)
+
+// LimiterOption sets an optional parameter for limiters.
+type LimiterOption func(*Limiter)
+
+// LimiterTimeout sets the timeout of the requests to Redis, after which the
+// fallback limiter decides. By default, it's 100ms.
+func LimiterTimeout(d time.Duration) LimiterOption {
+	return func(l *Limiter) { l.timeout = d }
+}
+
+// LimiterFallback sets the limiter which decides while Redis is unreachable,
+// e.g. a local limiter of the rate divided by the number of instances. By
+// default, it's a local token bucket of the whole rate and burst.
+func LimiterFallback(a ratelimit.Allower) LimiterOption {
+	return func(l *Limiter) { l.fallback = a }
+}
+
+// LimiterLogger sets the logger of the errors of Redis. By default, they
+// aren't logged.
+func LimiterLogger(logger log.Logger) LimiterOption {
+	return func(l *Limiter) { l.logger = logger }
+}
+
+// NewLimiter returns a Limiter which allows rate requests per second, with
+// bursts of up to burst requests, across all the instances using the key. It
+// panics if the rate isn't positive, or is so low that the interval between
+// requests overflows a time.Duration.
+func NewLimiter(client Client, key string, rate float64, burst int, options ...LimiterOption) *Limiter {
+	if !(rate > 0) || float64(time.Second)/rate >= math.MaxInt64 {
+		panic("redis limiter rate out of range")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	l := &Limiter{
+		client:    client,
+		key:       key,
+		interval:  interval,
+		tolerance: interval * time.Duration(burst),
+		timeout:   100 * time.Millisecond,
+		logger:    log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	if l.fallback == nil {
+		tb := jujuratelimit.NewBucketWithRate(rate, int64(burst))
+		l.fallback = ratelimit.AllowerFunc(func() bool { return tb.TakeAvailable(1) == 1 })
+	}
+	return l
+}
+
+// Allow implements ratelimit.Allower.
+func (l *Limiter) Allow() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	result, err := l.client.Eval(ctx, gcra, []string{l.key}, micros(l.interval), micros(l.tolerance))
+	if err != nil {
+		l.logger.Log("key", l.key, "err", err)
+		return l.fallback.Allow()
+	}
+	switch result := result.(type) {
+	case int64:
+		return result == 1
+	default:
+		l.logger.Log("key", l.key, "err", fmt.Sprintf("unexpected result %v", result))
+		return l.fallback.Allow()
+	}
+}
+
+func micros(d time.Duration) int64 {
+	return int64(math.Ceil(float64(d) / float64(time.Microsecond)))
+}
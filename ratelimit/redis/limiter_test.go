@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/ratelimit"
+)
+
+// fakeRedis evaluates the GCRA script, with a clock of its own.
+type fakeRedis struct {
+	now  int64 // microseconds
+	tats map[string]int64
+	err  error
+}
+
+func (r *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if script != gcra {
+		return nil, errors.New("unknown script")
+	}
+	interval, tolerance := args[0].(int64), args[1].(int64)
+	tat, ok := r.tats[keys[0]]
+	if !ok || tat < r.now {
+		tat = r.now
+	}
+	if tat+interval-r.now > tolerance {
+		return int64(0), nil
+	}
+	r.tats[keys[0]] = tat + interval
+	return int64(1), nil
+}
+
+func TestLimiter(t *testing.T) {
+	var (
+		redis    = &fakeRedis{tats: map[string]int64{}}
+		fallback = 0
+		l        = NewLimiter(redis, "users", 10, 2, LimiterFallback(ratelimit.AllowerFunc(func() bool {
+			fallback++
+			return true
+		})))
+	)
+
+	// A burst of 2 is allowed, and the next request 100ms after.
+	for i, want := range []bool{true, true, false} {
+		if have := l.Allow(); want != have {
+			t.Errorf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+	redis.now += int64(100 * time.Millisecond / time.Microsecond)
+	for i, want := range []bool{true, false} {
+		if have := l.Allow(); want != have {
+			t.Errorf("request %d after 100ms: want %v, have %v", i, want, have)
+		}
+	}
+	if want, have := 0, fallback; want != have {
+		t.Errorf("want %d fallbacks, have %d", want, have)
+	}
+
+	// While Redis is unreachable, the fallback decides.
+	redis.err = errors.New("connection refused")
+	if !l.Allow() {
+		t.Error("want allowed by the fallback")
+	}
+	if want, have := 1, fallback; want != have {
+		t.Errorf("want %d fallbacks, have %d", want, have)
+	}
+}
+
+func TestLimiterDefaultFallback(t *testing.T) {
+	l := NewLimiter(&fakeRedis{err: errors.New("connection refused")}, "users", 1, 1)
+	for i, want := range []bool{true, false} {
+		if have := l.Allow(); want != have {
+			t.Errorf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+}
+
+func TestLimiterInvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN(), 1e-12} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("rate %v: want panic", rate)
+				}
+			}()
+			NewLimiter(&fakeRedis{}, "users", rate, 1)
+		}()
+	}
+}
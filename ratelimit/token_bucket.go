@@ -11,8 +11,10 @@ import (
 )
 
 // ErrLimited is returned in the request path when the rate limiter is
-// triggered and the request is rejected.
-var ErrLimited = errors.New("rate limit exceeded")
+// triggered and the request is rejected. Its kind is
+// endpoint.KindResourceExhausted, so transports report it with 429 Too Many
+// Requests or the gRPC code ResourceExhausted.
+var ErrLimited error = endpoint.Error{Kind: endpoint.KindResourceExhausted, Err: errors.New("rate limit exceeded")}
 
 // NewTokenBucketLimiter returns an endpoint.Middleware that acts as a rate
 // limiter based on a token-bucket algorithm. Requests that would exceed the
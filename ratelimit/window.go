@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLog is a rate limiter which allows a number of requests in
+// any period of a window, exactly, by keeping the times of the requests it
+// allowed in the last window. Unlike a token bucket, it never allows more
+// requests in a window than the limit, at the cost of memory proportional
+// to it. It implements Allower and Waiter.
+type SlidingWindowLog struct {
+	limit  int
+	window time.Duration
+
+	mtx   sync.Mutex
+	times []time.Time // ring of the times of the last limit requests
+	next  int
+	n     int
+}
+
+var (
+	_ Allower = (*SlidingWindowLog)(nil)
+	_ Waiter  = (*SlidingWindowLog)(nil)
+)
+
+// NewSlidingWindowLog returns a SlidingWindowLog which allows limit requests
+// in any period of window.
+func NewSlidingWindowLog(limit int, window time.Duration) *SlidingWindowLog {
+	return &SlidingWindowLog{
+		limit:  limit,
+		window: window,
+		times:  make([]time.Time, limit),
+	}
+}
+
+// Allow implements Allower.
+func (l *SlidingWindowLog) Allow() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := time.Now()
+	if l.delay(now) > 0 {
+		return false
+	}
+	l.record(now)
+	return true
+}
+
+// Wait implements Waiter. If the context of the request would be done before
+// it's allowed, an error is returned immediately.
+func (l *SlidingWindowLog) Wait(ctx context.Context) error {
+	for {
+		l.mtx.Lock()
+		now := time.Now()
+		delay := l.delay(now)
+		if delay <= 0 {
+			l.record(now)
+			l.mtx.Unlock()
+			return nil
+		}
+		l.mtx.Unlock()
+		if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+			return context.DeadlineExceeded
+		}
+
+		// Others may take the slot meanwhile, so it's checked again.
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// delay returns how long until a request is allowed.
+func (l *SlidingWindowLog) delay(now time.Time) time.Duration {
+	if l.limit <= 0 {
+		return l.window
+	}
+	if l.n < l.limit {
+		return 0
+	}
+	oldest := l.times[l.next]
+	return oldest.Add(l.window).Sub(now)
+}
+
+func (l *SlidingWindowLog) record(now time.Time) {
+	l.times[l.next] = now
+	l.next = (l.next + 1) % l.limit
+	if l.n < l.limit {
+		l.n++
+	}
+}
+
+// SlidingWindowCounter is a rate limiter which allows about a number of
+// requests in any period of a window, with constant memory. It counts the
+// requests of fixed windows, and estimates those of the sliding window by
+// weighting the count of the previous fixed window by its overlap with the
+// sliding window. It implements Allower.
+type SlidingWindowCounter struct {
+	limit  int
+	window time.Duration
+
+	mtx      sync.Mutex
+	start    time.Time // of the current fixed window
+	current  int
+	previous int
+}
+
+var _ Allower = (*SlidingWindowCounter)(nil)
+
+// NewSlidingWindowCounter returns a SlidingWindowCounter which allows about
+// limit requests in any period of window.
+func NewSlidingWindowCounter(limit int, window time.Duration) *SlidingWindowCounter {
+	return &SlidingWindowCounter{
+		limit:  limit,
+		window: window,
+		start:  time.Now().Truncate(window),
+	}
+}
+
+// Allow implements Allower.
+func (c *SlidingWindowCounter) Allow() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	now := time.Now()
+	switch elapsed := now.Sub(c.start); {
+	case elapsed >= 2*c.window:
+		c.start, c.previous, c.current = now.Truncate(c.window), 0, 0
+	case elapsed >= c.window:
+		c.start, c.previous, c.current = c.start.Add(c.window), c.current, 0
+	}
+	overlap := 1 - float64(now.Sub(c.start))/float64(c.window)
+	if float64(c.previous)*overlap+float64(c.current) >= float64(c.limit) {
+		return false
+	}
+	c.current++
+	return true
+}
@@ -0,0 +1,35 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/ratelimit"
+)
+
+func TestSlidingWindowLog(t *testing.T) {
+	l := ratelimit.NewSlidingWindowLog(2, 20*time.Millisecond)
+	for i, want := range []bool{true, true, false} {
+		if have := l.Allow(); want != have {
+			t.Errorf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+
+	begin := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed < 10*time.Millisecond {
+		t.Errorf("want to wait for the window to slide, have %s", elapsed)
+	}
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	c := ratelimit.NewSlidingWindowCounter(2, time.Hour)
+	for i, want := range []bool{true, true, false} {
+		if have := c.Allow(); want != have {
+			t.Errorf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+}
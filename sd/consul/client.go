@@ -16,6 +16,28 @@ type Client interface {
 	Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error)
 }
 
+// ConnectClient is a Client which also queries the instances of services
+// which are Connect-capable, i.e. Connect-native services, and the sidecar
+// proxies of other services. The Client returned by NewClient implements
+// it.
+type ConnectClient interface {
+	Client
+
+	// Connect is as Service, but for Connect-capable instances.
+	Connect(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error)
+}
+
+// TTLClient is a Client which also updates the TTL checks of the local
+// agent, which registrars of services with a TTL use to renew them. The
+// Client returned by NewClient implements it.
+type TTLClient interface {
+	Client
+
+	// UpdateTTL sets the status, e.g. consul.HealthPassing, and output of
+	// the TTL check, which renews its TTL.
+	UpdateTTL(checkID, output, status string) error
+}
+
 type client struct {
 	consul *consul.Client
 }
@@ -37,3 +59,11 @@ func (c *client) Deregister(r *consul.AgentServiceRegistration) error {
 func (c *client) Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
 	return c.consul.Health().Service(service, tag, passingOnly, queryOpts)
 }
+
+func (c *client) Connect(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
+	return c.consul.Health().Connect(service, tag, passingOnly, queryOpts)
+}
+
+func (c *client) UpdateTTL(checkID, output, status string) error {
+	return c.consul.Agent().UpdateTTL(checkID, output, status)
+}
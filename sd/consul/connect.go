@@ -0,0 +1,281 @@
+package consul
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ErrConnectUnsupported is returned by instancers querying Connect-capable
+// instances with a Client which isn't a ConnectClient.
+var ErrConnectUnsupported = errors.New("client doesn't support Connect queries")
+
+// ConnectNative marks the registration as that of a Connect-native service,
+// which serves Connect mTLS itself, e.g. with the ServerConfig of
+// ConnectTLS, rather than through a sidecar proxy, and returns it.
+func ConnectNative(r *consul.AgentServiceRegistration) *consul.AgentServiceRegistration {
+	if r.Connect == nil {
+		r.Connect = &consul.AgentServiceConnect{}
+	}
+	r.Connect.Native = true
+	return r
+}
+
+// ConnectCA is the part of the Consul agent API which issues the
+// certificates of Connect. It's implemented by *consul.Agent.
+type ConnectCA interface {
+	ConnectCARoots(q *consul.QueryOptions) (*consul.CARootList, *consul.QueryMeta, error)
+	ConnectCALeaf(service string, q *consul.QueryOptions) (*consul.LeafCert, *consul.QueryMeta, error)
+}
+
+// ConnectTLS keeps the leaf certificate of a service, and the roots of the
+// Connect CA, from the local agent, and returns TLS configs with them,
+// with which the service serves, and dials its upstreams, over Connect
+// mTLS, without a sidecar proxy. Both are watched with blocking queries, so
+// that certificates are rotated as the agent renews them, or the CA's roots
+// change, and the configs always use the latest.
+//
+// Peers are authenticated by their certificates, as issued by the CA for
+// their services. It's up to servers to authorize their clients, e.g. by
+// the intentions of Consul, with the service of ConnectPeer.
+type ConnectTLS struct {
+	ca      ConnectCA
+	service string
+	logger  log.Logger
+	quitc   chan struct{}
+
+	mtx         sync.RWMutex
+	cert        *tls.Certificate
+	roots       *x509.CertPool
+	trustDomain string
+}
+
+// NewConnectTLS returns a ConnectTLS for the service, once its leaf
+// certificate, and the CA's roots, are fetched from the agent.
+func NewConnectTLS(ca ConnectCA, service string, logger log.Logger) (*ConnectTLS, error) {
+	c := &ConnectTLS{
+		ca:      ca,
+		service: service,
+		logger:  log.With(logger, "service", service),
+		quitc:   make(chan struct{}),
+	}
+	rootsIndex, err := c.updateRoots(defaultIndex)
+	if err != nil {
+		return nil, err
+	}
+	leafIndex, err := c.updateLeaf(defaultIndex)
+	if err != nil {
+		return nil, err
+	}
+	go c.loop("roots", rootsIndex, c.updateRoots)
+	go c.loop("leaf", leafIndex, c.updateLeaf)
+	return c, nil
+}
+
+// Stop terminates the watches of the certificates.
+func (c *ConnectTLS) Stop() {
+	close(c.quitc)
+}
+
+// ServerConfig returns a TLS config for serving the service over Connect
+// mTLS. Clients must present certificates issued by the CA.
+func (c *ConnectTLS) ServerConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.certificate(), nil
+		},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: c.verify(""),
+	}
+}
+
+// ClientConfig returns a TLS config for dialing the upstream service over
+// Connect mTLS, e.g. in the dialer of the Factory of its instances, as
+// returned by an instancer with InstancerConnect. Servers must present
+// certificates issued by the CA for the upstream service.
+func (c *ConnectTLS) ClientConfig(upstream string) *tls.Config {
+	return &tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.certificate(), nil
+		},
+		// The certificates of Connect identify services by their URIs,
+		// rather than by host names, so they're verified by verify.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: c.verify(upstream),
+	}
+}
+
+// ConnectPeer returns the service of the peer of a Connect mTLS connection,
+// by the URI of its certificate, or false if it has none.
+func ConnectPeer(cs tls.ConnectionState) (string, bool) {
+	if len(cs.PeerCertificates) == 0 {
+		return "", false
+	}
+	for _, uri := range cs.PeerCertificates[0].URIs {
+		if service, ok := connectService(uri); ok {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+func (c *ConnectTLS) certificate() *tls.Certificate {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.cert
+}
+
+// verify returns a function verifying the chain of peer certificates
+// against the current roots, and that the leaf is that of the service, if
+// it's not empty, in the trust domain of the CA.
+func (c *ConnectTLS) verify(service string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no peer certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+
+		c.mtx.RLock()
+		roots, trustDomain := c.roots, c.trustDomain
+		c.mtx.RUnlock()
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err != nil {
+			return err
+		}
+		if service == "" {
+			return nil
+		}
+
+		for _, uri := range certs[0].URIs {
+			if !strings.EqualFold(uri.Host, trustDomain) {
+				continue
+			}
+			if s, ok := connectService(uri); ok && s == service {
+				return nil
+			}
+		}
+		return fmt.Errorf("peer certificate isn't that of service %q", service)
+	}
+}
+
+// connectService returns the service of a SPIFFE ID of Connect, e.g.
+// spiffe://<trust domain>/ns/default/dc/dc1/svc/web.
+func connectService(uri *url.URL) (string, bool) {
+	if uri.Scheme != "spiffe" {
+		return "", false
+	}
+	i := strings.LastIndex(uri.Path, "/svc/")
+	if i < 0 {
+		return "", false
+	}
+	return uri.Path[i+len("/svc/"):], true
+}
+
+func (c *ConnectTLS) updateRoots(lastIndex uint64) (uint64, error) {
+	list, meta, err := c.ca.ConnectCARoots(&consul.QueryOptions{WaitIndex: lastIndex})
+	if err != nil {
+		return 0, err
+	}
+	roots := x509.NewCertPool()
+	for _, root := range list.Roots {
+		if !roots.AppendCertsFromPEM([]byte(root.RootCertPEM)) {
+			return 0, fmt.Errorf("invalid root certificate %q", root.ID)
+		}
+	}
+
+	c.mtx.Lock()
+	c.roots, c.trustDomain = roots, list.TrustDomain
+	c.mtx.Unlock()
+	return meta.LastIndex, nil
+}
+
+func (c *ConnectTLS) updateLeaf(lastIndex uint64) (uint64, error) {
+	leaf, meta, err := c.ca.ConnectCALeaf(c.service, &consul.QueryOptions{WaitIndex: lastIndex})
+	if err != nil {
+		return 0, err
+	}
+	cert, err := tls.X509KeyPair([]byte(leaf.CertPEM), []byte(leaf.PrivateKeyPEM))
+	if err != nil {
+		return 0, err
+	}
+
+	c.mtx.Lock()
+	c.cert = &cert
+	c.mtx.Unlock()
+	return meta.LastIndex, nil
+}
+
+// loop watches the roots or leaf with blocking queries, until stopped. As
+// the queries can't be interrupted, the last may complete after Stop.
+func (c *ConnectTLS) loop(what string, lastIndex uint64, update func(uint64) (uint64, error)) {
+	backoff := 100 * time.Millisecond
+	for {
+		index, err := c.query(lastIndex, update)
+		switch {
+		case err == io.EOF:
+			return // stopped via quitc
+		case err != nil:
+			c.logger.Log("cert", what, "err", err)
+			d := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+			select {
+			case <-time.After(d):
+			case <-c.quitc:
+				return
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			lastIndex = defaultIndex
+		default:
+			backoff = 100 * time.Millisecond
+			if index < lastIndex {
+				index = defaultIndex
+			}
+			lastIndex = index
+		}
+	}
+}
+
+func (c *ConnectTLS) query(lastIndex uint64, update func(uint64) (uint64, error)) (uint64, error) {
+	type result struct {
+		index uint64
+		err   error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		index, err := update(lastIndex)
+		resc <- result{index, err}
+	}()
+	select {
+	case res := <-resc:
+		return res.index, res.err
+	case <-c.quitc:
+		return 0, io.EOF
+	}
+}
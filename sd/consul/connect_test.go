@@ -0,0 +1,270 @@
+package consul
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+
+	"github.com/go-kit/kit/log"
+)
+
+const testTrustDomain = "11111111-2222-3333-4444-555555555555.consul"
+
+// testCA is a Connect CA, whose queries block until their index changes,
+// as those of the agent.
+type testCA struct {
+	t      *testing.T
+	root   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+
+	mtx     sync.Mutex
+	index   uint64
+	changed chan struct{}
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Consul CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{t: t, root: root, key: key, serial: 1, index: 1, changed: make(chan struct{})}
+}
+
+// rotate changes the index, so that the leaves are issued again.
+func (ca *testCA) rotate() {
+	ca.mtx.Lock()
+	ca.index++
+	close(ca.changed)
+	ca.changed = make(chan struct{})
+	ca.mtx.Unlock()
+}
+
+func (ca *testCA) wait(lastIndex uint64) uint64 {
+	ca.mtx.Lock()
+	index, changed := ca.index, ca.changed
+	ca.mtx.Unlock()
+	if lastIndex != index {
+		return index
+	}
+	<-changed
+	ca.mtx.Lock()
+	defer ca.mtx.Unlock()
+	return ca.index
+}
+
+func (ca *testCA) ConnectCARoots(q *consul.QueryOptions) (*consul.CARootList, *consul.QueryMeta, error) {
+	index := ca.wait(q.WaitIndex)
+	root := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.root.Raw})
+	return &consul.CARootList{
+		TrustDomain: testTrustDomain,
+		Roots:       []*consul.CARoot{{ID: "root", RootCertPEM: string(root), Active: true}},
+	}, &consul.QueryMeta{LastIndex: index}, nil
+}
+
+func (ca *testCA) ConnectCALeaf(service string, q *consul.QueryOptions) (*consul.LeafCert, *consul.QueryMeta, error) {
+	index := ca.wait(q.WaitIndex)
+	leaf := ca.issue("spiffe://" + testTrustDomain + "/ns/default/dc/dc1/svc/" + service)
+	return leaf, &consul.QueryMeta{LastIndex: index}, nil
+}
+
+func (ca *testCA) issue(uri string) *consul.LeafCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	ca.mtx.Lock()
+	ca.serial++
+	serial := ca.serial
+	ca.mtx.Unlock()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{u},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.root, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return &consul.LeafCert{
+		SerialNumber:  big.NewInt(serial).String(),
+		CertPEM:       string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		ServiceURI:    uri,
+	}
+}
+
+// handshake performs the handshake of a client and server, over a pipe,
+// and returns the service of the client, as seen by the server.
+func handshake(client, server *tls.Config) (string, error) {
+	cc, sc := net.Pipe()
+	defer cc.Close()
+	defer sc.Close()
+
+	type result struct {
+		peer string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		conn := tls.Server(sc, server)
+		err := conn.Handshake()
+		peer, _ := ConnectPeer(conn.ConnectionState())
+		if err != nil {
+			sc.Close()
+		}
+		resc <- result{peer, err}
+	}()
+	if err := tls.Client(cc, client).Handshake(); err != nil {
+		cc.Close()
+		<-resc
+		return "", err
+	}
+	res := <-resc
+	return res.peer, res.err
+}
+
+func TestConnectTLS(t *testing.T) {
+	ca := newTestCA(t)
+	web, err := NewConnectTLS(ca, "web", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer web.Stop()
+	db, err := NewConnectTLS(ca, "db", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Stop()
+
+	peer, err := handshake(web.ClientConfig("db"), db.ServerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "web", peer; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if _, err := handshake(web.ClientConfig("cache"), db.ServerConfig()); err == nil {
+		t.Error("want error dialing another service, have none")
+	}
+
+	other, err := NewConnectTLS(newTestCA(t), "web", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Stop()
+	if _, err := handshake(other.ClientConfig("db"), db.ServerConfig()); err == nil {
+		t.Error("want error from a client of another CA, have none")
+	}
+}
+
+func TestConnectTLSRotation(t *testing.T) {
+	ca := newTestCA(t)
+	c, err := NewConnectTLS(ca, "web", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	before := c.certificate()
+	ca.rotate()
+	deadline := time.Now().Add(time.Second)
+	for c.certificate() == before {
+		if time.Now().After(deadline) {
+			t.Fatal("leaf certificate wasn't rotated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	db, err := NewConnectTLS(ca, "db", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Stop()
+	if _, err := handshake(c.ClientConfig("db"), db.ServerConfig()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnectNative(t *testing.T) {
+	r := ConnectNative(&consul.AgentServiceRegistration{Name: "web"})
+	if r.Connect == nil || !r.Connect.Native {
+		t.Errorf("want Connect-native registration, have %+v", r.Connect)
+	}
+}
+
+type connectClient struct {
+	Client
+	entries []*consul.ServiceEntry
+}
+
+func (c *connectClient) Connect(service, tag string, passingOnly bool, opts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
+	time.Sleep(time.Millisecond)
+	return c.entries, &consul.QueryMeta{LastIndex: opts.WaitIndex + 1}, nil
+}
+
+func TestInstancerConnect(t *testing.T) {
+	client := &connectClient{
+		Client: newTestClient(consulState),
+		entries: []*consul.ServiceEntry{
+			{
+				Node:    &consul.Node{Address: "10.0.0.0"},
+				Service: &consul.AgentService{Service: "search-sidecar-proxy", Port: 21000},
+			},
+		},
+	}
+	s := NewInstancer(client, log.NewNopLogger(), "search", nil, true, InstancerConnect())
+	defer s.Stop()
+
+	state := s.cache.State()
+	if want, have := []string{"10.0.0.0:21000"}, state.Instances; len(have) != 1 || want[0] != have[0] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	u := NewInstancer(newTestClient(consulState), log.NewNopLogger(), "search", nil, true, InstancerConnect())
+	defer u.Stop()
+	if want, have := ErrConnectUnsupported, u.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
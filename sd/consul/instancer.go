@@ -3,6 +3,8 @@ package consul
 import (
 	"fmt"
 	"io"
+	"math/rand"
+	"time"
 
 	consul "github.com/hashicorp/consul/api"
 
@@ -21,13 +23,76 @@ type Instancer struct {
 	service     string
 	tags        []string
 	passingOnly bool
+	statuses    []string
+	datacenter  string
+	namespace   string
+	filter      string
+	waitTime    time.Duration
+	connect     bool
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
 	quitc       chan struct{}
 }
 
+// InstancerOption sets an optional parameter for instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerStatus only returns instances whose checks aggregate to one of
+// the statuses, e.g. consul.HealthPassing and consul.HealthWarning, so that
+// instances with warnings are still used. As passingOnly is applied by
+// Consul, it shouldn't be set with this option. By default, instances of
+// any status are returned, unless passingOnly is set.
+func InstancerStatus(statuses ...string) InstancerOption {
+	return func(s *Instancer) { s.statuses = statuses }
+}
+
+// InstancerDatacenter queries the datacenter. By default, that of the agent
+// is queried.
+func InstancerDatacenter(dc string) InstancerOption {
+	return func(s *Instancer) { s.datacenter = dc }
+}
+
+// InstancerNamespace queries the namespace, of Consul Enterprise. By
+// default, that of the token is queried.
+func InstancerNamespace(ns string) InstancerOption {
+	return func(s *Instancer) { s.namespace = ns }
+}
+
+// InstancerFilter filters instances on the server, with the filter
+// expression, e.g. `Service.Meta.version == "2"`, in addition to the tags.
+// By default, there's no filter.
+func InstancerFilter(expr string) InstancerOption {
+	return func(s *Instancer) { s.filter = expr }
+}
+
+// InstancerWaitTime sets how long blocking queries wait for changes, at most
+// 10 minutes. By default, the agent's default of 5 minutes applies.
+func InstancerWaitTime(d time.Duration) InstancerOption {
+	return func(s *Instancer) { s.waitTime = d }
+}
+
+// InstancerConnect only returns Connect-capable instances, i.e.
+// Connect-native instances of the service, and the sidecar proxies of its
+// other instances, which are to be dialed over Connect mTLS, e.g. with the
+// ClientConfig of ConnectTLS. The client must be a ConnectClient. By
+// default, instances are returned as they're registered.
+func InstancerConnect() InstancerOption {
+	return func(s *Instancer) { s.connect = true }
+}
+
+// InstancerBackoff sets the backoff of queries after they fail, e.g. as
+// Consul is unavailable, which doubles from min to max. Each wait is
+// jittered, from half the backoff to all of it, so that instancers don't
+// query Consul in lockstep once it recovers. By default, it's from 100ms to
+// 30 seconds.
+func InstancerBackoff(min, max time.Duration) InstancerOption {
+	return func(s *Instancer) { s.minBackoff, s.maxBackoff = min, max }
+}
+
 // NewInstancer returns a Consul instancer that publishes instances for the
 // requested service. It only returns instances for which all of the passed tags
 // are present.
-func NewInstancer(client Client, logger log.Logger, service string, tags []string, passingOnly bool) *Instancer {
+func NewInstancer(client Client, logger log.Logger, service string, tags []string, passingOnly bool, options ...InstancerOption) *Instancer {
 	s := &Instancer{
 		cache:       instance.NewCache(),
 		client:      client,
@@ -35,17 +100,22 @@ func NewInstancer(client Client, logger log.Logger, service string, tags []strin
 		service:     service,
 		tags:        tags,
 		passingOnly: passingOnly,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  30 * time.Second,
 		quitc:       make(chan struct{}),
 	}
+	for _, option := range options {
+		option(s)
+	}
 
-	instances, index, err := s.getInstances(defaultIndex, nil)
+	instances, records, index, err := s.getInstances(defaultIndex, nil)
 	if err == nil {
 		s.logger.Log("instances", len(instances))
 	} else {
 		s.logger.Log("err", err)
 	}
 
-	s.cache.Update(sd.Event{Instances: instances, Err: err})
+	s.cache.Update(sd.Event{Instances: instances, Records: records, Err: err})
 	go s.loop(index)
 	return s
 }
@@ -58,23 +128,55 @@ func (s *Instancer) Stop() {
 func (s *Instancer) loop(lastIndex uint64) {
 	var (
 		instances []string
+		records   map[string]sd.Instance
+		index     uint64
 		err       error
+		backoff   = s.minBackoff
 	)
 	for {
-		instances, lastIndex, err = s.getInstances(lastIndex, s.quitc)
+		instances, records, index, err = s.getInstances(lastIndex, s.quitc)
 		switch {
 		case err == io.EOF:
 			return // stopped via quitc
 		case err != nil:
 			s.logger.Log("err", err)
 			s.cache.Update(sd.Event{Err: err})
+			if !s.wait(backoff) {
+				return
+			}
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+			lastIndex = defaultIndex
 		default:
-			s.cache.Update(sd.Event{Instances: instances})
+			s.cache.Update(sd.Event{Instances: instances, Records: records})
+			backoff = s.minBackoff
+			// Indexes going backwards, e.g. as Consul's state is restored,
+			// must be reset, or queries would block until they catch up.
+			if index < lastIndex {
+				index = defaultIndex
+			}
+			lastIndex = index
 		}
 	}
 }
 
-func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]string, uint64, error) {
+// wait waits for a jittered backoff, and returns false if the instancer is
+// stopped meanwhile.
+func (s *Instancer) wait(backoff time.Duration) bool {
+	if backoff <= 0 {
+		return true
+	}
+	d := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	select {
+	case <-time.After(d):
+		return true
+	case <-s.quitc:
+		return false
+	}
+}
+
+func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]string, map[string]sd.Instance, uint64, error) {
 	tag := ""
 	if len(s.tags) > 0 {
 		tag = s.tags[0]
@@ -88,6 +190,7 @@ func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]
 
 	type response struct {
 		instances []string
+		records   map[string]sd.Instance
 		index     uint64
 	}
 
@@ -96,9 +199,22 @@ func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]
 		resc = make(chan response, 1)
 	)
 
+	query := s.client.Service
+	if s.connect {
+		c, ok := s.client.(ConnectClient)
+		if !ok {
+			return nil, nil, 0, ErrConnectUnsupported
+		}
+		query = c.Connect
+	}
+
 	go func() {
-		entries, meta, err := s.client.Service(s.service, tag, s.passingOnly, &consul.QueryOptions{
-			WaitIndex: lastIndex,
+		entries, meta, err := query(s.service, tag, s.passingOnly, &consul.QueryOptions{
+			WaitIndex:  lastIndex,
+			WaitTime:   s.waitTime,
+			Datacenter: s.datacenter,
+			Namespace:  s.namespace,
+			Filter:     s.filter,
 		})
 		if err != nil {
 			errc <- err
@@ -107,19 +223,24 @@ func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]
 		if len(s.tags) > 1 {
 			entries = filterEntries(entries, s.tags[1:]...)
 		}
+		if len(s.statuses) > 0 {
+			entries = filterStatuses(entries, s.statuses...)
+		}
+		instances, records := makeInstances(entries)
 		resc <- response{
-			instances: makeInstances(entries),
+			instances: instances,
+			records:   records,
 			index:     meta.LastIndex,
 		}
 	}()
 
 	select {
 	case err := <-errc:
-		return nil, 0, err
+		return nil, nil, 0, err
 	case res := <-resc:
-		return res.instances, res.index, nil
+		return res.instances, res.records, res.index, nil
 	case <-interruptc:
-		return nil, 0, io.EOF
+		return nil, nil, 0, io.EOF
 	}
 }
 
@@ -154,14 +275,45 @@ ENTRIES:
 	return es
 }
 
-func makeInstances(entries []*consul.ServiceEntry) []string {
-	instances := make([]string, len(entries))
+func filterStatuses(entries []*consul.ServiceEntry, statuses ...string) []*consul.ServiceEntry {
+	var es []*consul.ServiceEntry
+	for _, entry := range entries {
+		status := entry.Checks.AggregatedStatus()
+		for _, want := range statuses {
+			if status == want {
+				es = append(es, entry)
+				break
+			}
+		}
+	}
+	return es
+}
+
+// makeInstances returns the instances of the entries, and their records,
+// with the tags, service metadata and weights of the services. The weight of
+// a service whose checks aggregate to warning is its warning weight.
+func makeInstances(entries []*consul.ServiceEntry) ([]string, map[string]sd.Instance) {
+	var (
+		instances = make([]string, len(entries))
+		records   = make(map[string]sd.Instance, len(entries))
+	)
 	for i, entry := range entries {
 		addr := entry.Node.Address
 		if entry.Service.Address != "" {
 			addr = entry.Service.Address
 		}
 		instances[i] = fmt.Sprintf("%s:%d", addr, entry.Service.Port)
+
+		weight := entry.Service.Weights.Passing
+		if entry.Checks.AggregatedStatus() == consul.HealthWarning {
+			weight = entry.Service.Weights.Warning
+		}
+		records[instances[i]] = sd.Instance{
+			Address:  instances[i],
+			Tags:     entry.Service.Tags,
+			Metadata: entry.Service.Meta,
+			Weight:   weight,
+		}
 	}
-	return instances
+	return instances, records
 }
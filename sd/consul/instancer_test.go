@@ -2,7 +2,10 @@ package consul
 
 import (
 	"context"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	consul "github.com/hashicorp/consul/api"
 
@@ -60,6 +63,16 @@ var consulState = []*consul.ServiceEntry{
 	},
 }
 
+func TestInstancerRecords(t *testing.T) {
+	s := NewInstancer(newTestClient(consulState), log.NewNopLogger(), "search", []string{"api"}, true)
+	defer s.Stop()
+
+	want := sd.Instance{Address: "10.0.0.1:8001", Tags: []string{"api", "v2"}}
+	if have := s.cache.State().Record("10.0.0.1:8001"); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
 func TestInstancer(t *testing.T) {
 	var (
 		logger = log.NewNopLogger()
@@ -131,3 +144,108 @@ func TestInstancerAddressOverride(t *testing.T) {
 		t.Errorf("want %q, have %q", want, have)
 	}
 }
+
+type queryClient struct {
+	Client
+	mtx     sync.Mutex
+	queries []consul.QueryOptions
+	err     error
+}
+
+func (c *queryClient) Service(service, tag string, passingOnly bool, opts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
+	c.mtx.Lock()
+	c.queries = append(c.queries, *opts)
+	err := c.err
+	c.mtx.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, meta, err := c.Client.Service(service, tag, passingOnly, opts)
+	if meta != nil {
+		meta.LastIndex = opts.WaitIndex + 1
+	}
+	time.Sleep(time.Millisecond)
+	return entries, meta, err
+}
+
+func (c *queryClient) recorded() []consul.QueryOptions {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]consul.QueryOptions(nil), c.queries...)
+}
+
+func TestInstancerQueryOptions(t *testing.T) {
+	client := &queryClient{Client: newTestClient(consulState)}
+	s := NewInstancer(client, log.NewNopLogger(), "search", []string{"api"}, true,
+		InstancerDatacenter("dc2"),
+		InstancerNamespace("team"),
+		InstancerFilter(`Service.Meta.version == "2"`),
+		InstancerWaitTime(time.Minute),
+	)
+	time.Sleep(10 * time.Millisecond)
+	s.Stop()
+
+	queries := client.recorded()
+	if len(queries) < 2 {
+		t.Fatalf("want at least 2 queries, have %d", len(queries))
+	}
+	for i, q := range queries[:2] {
+		want := consul.QueryOptions{
+			WaitIndex:  uint64(i),
+			WaitTime:   time.Minute,
+			Datacenter: "dc2",
+			Namespace:  "team",
+			Filter:     `Service.Meta.version == "2"`,
+		}
+		if !reflect.DeepEqual(want, q) {
+			t.Errorf("query %d: want %+v, have %+v", i, want, q)
+		}
+	}
+}
+
+func TestInstancerStatus(t *testing.T) {
+	entries := []*consul.ServiceEntry{
+		{
+			Node:    &consul.Node{Address: "10.0.0.0"},
+			Service: &consul.AgentService{Service: "search", Port: 8000},
+			Checks:  consul.HealthChecks{{Status: consul.HealthPassing}},
+		},
+		{
+			Node:    &consul.Node{Address: "10.0.0.1"},
+			Service: &consul.AgentService{Service: "search", Port: 8000},
+			Checks:  consul.HealthChecks{{Status: consul.HealthPassing}, {Status: consul.HealthWarning}},
+		},
+		{
+			Node:    &consul.Node{Address: "10.0.0.2"},
+			Service: &consul.AgentService{Service: "search", Port: 8000},
+			Checks:  consul.HealthChecks{{Status: consul.HealthCritical}},
+		},
+	}
+	s := NewInstancer(newTestClient(entries), log.NewNopLogger(), "search", nil, false,
+		InstancerStatus(consul.HealthPassing, consul.HealthWarning))
+	defer s.Stop()
+
+	if want, have := []string{"10.0.0.0:8000", "10.0.0.1:8000"}, s.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestInstancerBackoff(t *testing.T) {
+	client := &queryClient{Client: newTestClient(consulState), err: &consul.StatusError{Code: 500, Body: "dang"}}
+	s := NewInstancer(client, log.NewNopLogger(), "search", nil, true, InstancerBackoff(20*time.Millisecond, 40*time.Millisecond))
+	if s.cache.State().Err == nil {
+		t.Error("want error, have none")
+	}
+	time.Sleep(100 * time.Millisecond)
+	s.Stop()
+
+	// The first query, and retries after 10-20ms, 20-40ms, and 20-40ms.
+	if n := len(client.recorded()); n < 3 || n > 6 {
+		t.Errorf("want 3 to 6 queries, have %d", n)
+	}
+	for _, q := range client.recorded() {
+		if q.WaitIndex != 0 {
+			t.Errorf("want queries after failures not to block, have index %d", q.WaitIndex)
+		}
+	}
+}
@@ -1,32 +1,79 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 )
 
+// ErrTTLUnsupported is returned by registrars of services with a TTL with a
+// Client which isn't a TTLClient.
+var ErrTTLUnsupported = errors.New("client doesn't support TTL checks")
+
 // Registrar registers service instance liveness information to Consul.
 type Registrar struct {
 	client       Client
 	registration *stdconsul.AgentServiceRegistration
 	logger       log.Logger
+	ttl          time.Duration
+	heartbeat    []sd.HeartbeatOption
+	registrar    *sd.HeartbeatRegistrar
+}
+
+// RegistrarOption sets an optional parameter for registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarTTL adds a TTL check to the checks of the registration, which is
+// renewed by heartbeats three times per TTL, so that the service's health
+// is critical once it stops renewing it, e.g. as it crashes. The checks of
+// the registration passed to NewRegistrar are kept, and it isn't modified.
+// If the agent loses the registration, e.g. as it restarts, the service is
+// registered again. The client must be a TTLClient.
+func RegistrarTTL(ttl time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.ttl = ttl }
+}
+
+// RegistrarStateChange sets a function which is called with the state of
+// the registration of a service with a TTL each time it changes, e.g. to
+// tie readiness to the registration. By default, changes are only logged.
+func RegistrarStateChange(f func(sd.RegistrationState)) RegistrarOption {
+	return func(r *Registrar) { r.heartbeat = append(r.heartbeat, sd.HeartbeatStateChange(f)) }
 }
 
 // NewRegistrar returns a Consul Registrar acting on the provided catalog
 // registration.
-func NewRegistrar(client Client, r *stdconsul.AgentServiceRegistration, logger log.Logger) *Registrar {
-	return &Registrar{
+func NewRegistrar(client Client, r *stdconsul.AgentServiceRegistration, logger log.Logger, options ...RegistrarOption) *Registrar {
+	p := &Registrar{
 		client:       client,
 		registration: r,
 		logger:       log.With(logger, "service", r.Name, "tags", fmt.Sprint(r.Tags), "address", r.Address),
 	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.ttl > 0 {
+		reg := *r
+		reg.Checks = append(append(stdconsul.AgentServiceChecks(nil), r.Checks...), &stdconsul.AgentServiceCheck{TTL: p.ttl.String()})
+		p.registration = &reg
+		p.registrar = sd.NewHeartbeatRegistrar(&heartbeater{client: client, registration: &reg}, p.ttl/3, p.logger, p.heartbeat...)
+	}
+	return p
 }
 
-// Register implements sd.Registrar interface.
+// Register implements sd.Registrar interface. Services with a TTL are
+// renewed by heartbeats, and registered again if they're lost, until
+// they're deregistered.
 func (p *Registrar) Register() {
+	if p.registrar != nil {
+		p.registrar.Register()
+		return
+	}
 	if err := p.client.Register(p.registration); err != nil {
 		p.logger.Log("err", err)
 	} else {
@@ -36,9 +83,67 @@ func (p *Registrar) Register() {
 
 // Deregister implements sd.Registrar interface.
 func (p *Registrar) Deregister() {
+	if p.registrar != nil {
+		p.registrar.Deregister()
+		return
+	}
 	if err := p.client.Deregister(p.registration); err != nil {
 		p.logger.Log("err", err)
 	} else {
 		p.logger.Log("action", "deregister")
 	}
 }
+
+// heartbeater implements sd.Heartbeater for a registration whose last check
+// is a TTL check.
+type heartbeater struct {
+	client       Client
+	registration *stdconsul.AgentServiceRegistration
+}
+
+func (h *heartbeater) Register() error {
+	if _, ok := h.client.(TTLClient); !ok {
+		return ErrTTLUnsupported
+	}
+	if err := h.client.Register(h.registration); err != nil {
+		return err
+	}
+	// The check is critical until it's first renewed.
+	return h.Heartbeat()
+}
+
+func (h *heartbeater) Heartbeat() error {
+	err := h.client.(TTLClient).UpdateTTL(h.checkID(), "", stdconsul.HealthPassing)
+	if err != nil && isUnknownCheck(err) {
+		return sd.ErrNotRegistered
+	}
+	return err
+}
+
+func (h *heartbeater) Deregister() error {
+	return h.client.Deregister(h.registration)
+}
+
+// checkID returns the ID the agent gives the TTL check: that of the service,
+// suffixed by the check's position if the service has several checks.
+func (h *heartbeater) checkID() string {
+	id := h.registration.ID
+	if id == "" {
+		id = h.registration.Name
+	}
+	n := len(h.registration.Checks)
+	if h.registration.Check != nil {
+		n++
+	}
+	if n > 1 {
+		return fmt.Sprintf("service:%s:%d", id, n)
+	}
+	return "service:" + id
+}
+
+// isUnknownCheck reports whether the error is that of an update of a check
+// which the agent doesn't know, as it lost the registration.
+func isUnknownCheck(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Unknown check") || strings.Contains(msg, "does not have associated TTL")
+}
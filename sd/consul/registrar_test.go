@@ -1,11 +1,16 @@
 package consul
 
 import (
+	"errors"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 )
 
 func TestRegistrar(t *testing.T) {
@@ -25,3 +30,108 @@ func TestRegistrar(t *testing.T) {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
+
+// ttlClient is a TTLClient whose agent loses the registration when its
+// entries are cleared, as the agent restarts.
+type ttlClient struct {
+	mtx sync.Mutex
+	*testClient
+	updates int
+	checkID string
+}
+
+func (c *ttlClient) Register(r *stdconsul.AgentServiceRegistration) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.testClient.Register(r)
+}
+
+func (c *ttlClient) Deregister(r *stdconsul.AgentServiceRegistration) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.testClient.Deregister(r)
+}
+
+func (c *ttlClient) UpdateTTL(checkID, output, status string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.updates++
+	c.checkID = checkID
+	if len(c.entries) == 0 {
+		return errors.New(`Unexpected response code: 500 (Unknown check "` + checkID + `")`)
+	}
+	return nil
+}
+
+func TestRegistrarTTL(t *testing.T) {
+	var (
+		client       = &ttlClient{testClient: newTestClient(nil)}
+		registration = *testRegistration
+		mtx          sync.Mutex
+		states       []sd.RegistrationState
+		p            = NewRegistrar(client, &registration, log.NewNopLogger(),
+			RegistrarTTL(3*time.Millisecond),
+			RegistrarStateChange(func(s sd.RegistrationState) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				states = append(states, s)
+			}),
+		)
+	)
+	if registration.Check != nil || registration.Checks != nil {
+		t.Errorf("want the registration unchanged, have %+v", registration)
+	}
+
+	p.Register()
+	client.mtx.Lock()
+	client.entries = nil // the agent restarts
+	updates := client.updates
+	client.mtx.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		client.mtx.Lock()
+		entries, n := len(client.entries), client.updates
+		client.mtx.Unlock()
+		if entries == 1 && n > updates+1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want registered again, have %d entries after %d updates", entries, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	p.Deregister()
+	if want, have := 0, len(client.entries); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want := []sd.RegistrationState{sd.Registered, sd.Lost, sd.Registered, sd.Deregistered}; !reflect.DeepEqual(want, states) {
+		t.Errorf("want %v, have %v", want, states)
+	}
+}
+
+func TestRegistrarTTLChecks(t *testing.T) {
+	var (
+		client       = &ttlClient{testClient: newTestClient(nil)}
+		check        = &stdconsul.AgentServiceCheck{HTTP: "http://my-address:12345/health", Interval: "10s"}
+		registration = *testRegistration
+	)
+	registration.Check = check
+	p := NewRegistrar(client, &registration, log.NewNopLogger(), RegistrarTTL(time.Minute))
+	p.Register()
+	defer p.Deregister()
+
+	// The caller's check is kept, and the TTL check is the second.
+	if registration.Check != check || registration.Checks != nil {
+		t.Errorf("want the registration unchanged, have %+v", registration)
+	}
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if want, have := "service:my-id:2", client.checkID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -1,2 +1,4 @@
 // Package dnssrv provides an Instancer implementation for DNS SRV records.
+// Names are resolved on a fixed schedule, with net.LookupSRV, or, as the TTL
+// of their records expires, with a DNS client which reports it.
 package dnssrv
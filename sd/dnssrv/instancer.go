@@ -3,6 +3,8 @@ package dnssrv
 import (
 	"fmt"
 	"net"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -11,12 +13,44 @@ import (
 )
 
 // Instancer yields instances from the named DNS SRV record. The name is
-// resolved on a fixed schedule. Priorities and weights are ignored.
+// resolved on a fixed schedule, or, if it's constructed with
+// NewTTLInstancer, as the TTL of the records expires. The SRV records of
+// the instances, with their priorities and weights, are retained, and
+// available with Records. The events of the Instancer carry the records of
+// the instances, with the weights of their SRV records.
 type Instancer struct {
 	cache  *instance.Cache
 	name   string
 	logger log.Logger
 	quit   chan struct{}
+
+	minRefresh time.Duration
+	maxRefresh time.Duration
+
+	mtx     sync.RWMutex
+	records map[string]net.SRV
+	diffs   map[chan<- Diff]struct{}
+}
+
+// Diff is a change of the instances of an Instancer, from one resolution
+// of the name to the next. Changed instances are in both resolutions, with
+// different priorities or weights.
+type Diff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// InstancerOption sets an optional parameter for instancers constructed with
+// NewTTLInstancer.
+type InstancerOption func(*Instancer)
+
+// InstancerRefresh bounds the interval at which the name is resolved again,
+// which is otherwise the TTL of the records. After failures, the name is
+// resolved again at min, doubling up to max. By default, they're one second
+// and five minutes.
+func InstancerRefresh(min, max time.Duration) InstancerOption {
+	return func(p *Instancer) { p.minRefresh, p.maxRefresh = min, max }
 }
 
 // NewInstancer returns a DNS SRV instancer.
@@ -37,25 +71,60 @@ func NewInstancerDetailed(
 	lookup Lookup,
 	logger log.Logger,
 ) *Instancer {
-	p := &Instancer{
-		cache:  instance.NewCache(),
-		name:   name,
-		logger: logger,
-		quit:   make(chan struct{}),
-	}
+	p := newInstancer(name, logger)
 
-	instances, err := p.resolve(lookup)
+	event, err := p.resolve(lookup)
 	if err == nil {
-		logger.Log("name", name, "instances", len(instances))
+		logger.Log("name", name, "instances", len(event.Instances))
 	} else {
 		logger.Log("name", name, "err", err)
 	}
-	p.cache.Update(sd.Event{Instances: instances, Err: err})
+	event.Err = err
+	p.cache.Update(event)
 
 	go p.loop(refresh, lookup)
 	return p
 }
 
+// NewTTLInstancer returns a DNS SRV instancer which resolves the name again
+// as the TTL of its records expires, within the bounds of InstancerRefresh.
+// The lookup may be constructed with NewTTLLookup.
+func NewTTLInstancer(
+	name string,
+	lookup TTLLookup,
+	logger log.Logger,
+	options ...InstancerOption,
+) *Instancer {
+	p := newInstancer(name, logger)
+	p.minRefresh, p.maxRefresh = time.Second, 5*time.Minute
+	for _, option := range options {
+		option(p)
+	}
+
+	addrs, ttl, err := lookup(name)
+	if err == nil {
+		logger.Log("name", name, "instances", len(addrs), "ttl", ttl)
+		p.cache.Update(p.update(addrs))
+	} else {
+		logger.Log("name", name, "err", err)
+		p.cache.Update(sd.Event{Err: err})
+	}
+
+	go p.loopTTL(lookup, ttl, err)
+	return p
+}
+
+func newInstancer(name string, logger log.Logger) *Instancer {
+	return &Instancer{
+		cache:   instance.NewCache(),
+		name:    name,
+		logger:  logger,
+		quit:    make(chan struct{}),
+		records: map[string]net.SRV{},
+		diffs:   map[chan<- Diff]struct{}{},
+	}
+}
+
 // Stop terminates the Instancer.
 func (p *Instancer) Stop() {
 	close(p.quit)
@@ -66,30 +135,144 @@ func (p *Instancer) loop(t *time.Ticker, lookup Lookup) {
 	for {
 		select {
 		case <-t.C:
-			instances, err := p.resolve(lookup)
+			event, err := p.resolve(lookup)
 			if err != nil {
 				p.logger.Log("name", p.name, "err", err)
 				p.cache.Update(sd.Event{Err: err})
 				continue // don't replace potentially-good with bad
 			}
-			p.cache.Update(sd.Event{Instances: instances})
+			p.cache.Update(event)
+
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+func (p *Instancer) loopTTL(lookup TTLLookup, ttl time.Duration, err error) {
+	backoff := p.minRefresh
+	for {
+		next := ttl
+		if err != nil {
+			next = backoff
+			if backoff *= 2; backoff > p.maxRefresh {
+				backoff = p.maxRefresh
+			}
+		} else {
+			backoff = p.minRefresh
+		}
+		if next < p.minRefresh {
+			next = p.minRefresh
+		}
+		if next > p.maxRefresh {
+			next = p.maxRefresh
+		}
 
+		t := time.NewTimer(next)
+		select {
+		case <-t.C:
 		case <-p.quit:
+			t.Stop()
 			return
 		}
+
+		var addrs []*net.SRV
+		addrs, ttl, err = lookup(p.name)
+		if err != nil {
+			p.logger.Log("name", p.name, "err", err)
+			p.cache.Update(sd.Event{Err: err})
+			continue // don't replace potentially-good with bad
+		}
+		p.cache.Update(p.update(addrs))
 	}
 }
 
-func (p *Instancer) resolve(lookup Lookup) ([]string, error) {
+func (p *Instancer) resolve(lookup Lookup) (sd.Event, error) {
 	_, addrs, err := lookup("", "", p.name)
 	if err != nil {
-		return nil, err
+		return sd.Event{}, err
 	}
-	instances := make([]string, len(addrs))
+	return p.update(addrs), nil
+}
+
+// update records the addresses of a resolution, pushes the diff from the
+// last one, if any, and returns the event of the instances, with their
+// records.
+func (p *Instancer) update(addrs []*net.SRV) sd.Event {
+	var (
+		records   = make(map[string]net.SRV, len(addrs))
+		instances = make([]string, len(addrs))
+		event     = sd.Event{Records: make(map[string]sd.Instance, len(addrs))}
+	)
 	for i, addr := range addrs {
 		instances[i] = net.JoinHostPort(addr.Target, fmt.Sprint(addr.Port))
+		records[instances[i]] = *addr
+		event.Records[instances[i]] = sd.Instance{Address: instances[i], Weight: int(addr.Weight)}
 	}
-	return instances, nil
+	event.Instances = instances
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	var diff Diff
+	for instance, record := range records {
+		if old, ok := p.records[instance]; !ok {
+			diff.Added = append(diff.Added, instance)
+		} else if old != record {
+			diff.Changed = append(diff.Changed, instance)
+		}
+	}
+	for instance := range p.records {
+		if _, ok := records[instance]; !ok {
+			diff.Removed = append(diff.Removed, instance)
+		}
+	}
+	p.records = records
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0 {
+		sort.Strings(diff.Added)
+		sort.Strings(diff.Removed)
+		sort.Strings(diff.Changed)
+		for ch := range p.diffs {
+			ch <- diff
+		}
+	}
+	return event
+}
+
+// Records returns the SRV records of the instances, by instance, of the
+// last successful resolution of the name.
+func (p *Instancer) Records() map[string]net.SRV {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	records := make(map[string]net.SRV, len(p.records))
+	for instance, record := range p.records {
+		records[instance] = record
+	}
+	return records
+}
+
+// Weight returns the weight of the SRV record of the instance, of the last
+// successful resolution of the name, or 0 if there's none.
+func (p *Instancer) Weight(instance string) int {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return int(p.records[instance].Weight)
+}
+
+// RegisterDiffs registers the channel to receive the diff of each
+// resolution of the name which changes the instances, or their priorities
+// or weights. Unlike the events of Register, the current instances aren't
+// sent on registration; see Records.
+func (p *Instancer) RegisterDiffs(ch chan<- Diff) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.diffs[ch] = struct{}{}
+}
+
+// DeregisterDiffs deregisters a channel registered with RegisterDiffs.
+func (p *Instancer) DeregisterDiffs(ch chan<- Diff) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	delete(p.diffs, ch)
 }
 
 // Register implements Instancer.
@@ -1,7 +1,9 @@
 package dnssrv
 
 import (
+	"errors"
 	"net"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -71,3 +73,78 @@ func TestRefresh(t *testing.T) {
 type nopCloser struct{}
 
 func (nopCloser) Close() error { return nil }
+
+func TestTTLRefresh(t *testing.T) {
+	name := "some.service.internal"
+
+	type result struct {
+		addrs []*net.SRV
+		ttl   time.Duration
+		err   error
+	}
+	results := make(chan result)
+	lookups := make(chan time.Time, 16)
+	lookup := func(string) ([]*net.SRV, time.Duration, error) {
+		lookups <- time.Now()
+		r := <-results
+		return r.addrs, r.ttl, r.err
+	}
+
+	start := time.Now()
+	go func() {
+		results <- result{addrs: []*net.SRV{{Target: "1.0.0.1", Port: 1001, Weight: 10}}, ttl: 50 * time.Millisecond}
+	}()
+	instancer := NewTTLInstancer(name, lookup, log.NewNopLogger(), InstancerRefresh(20*time.Millisecond, time.Second))
+	defer instancer.Stop()
+	<-lookups
+
+	diffs := make(chan Diff, 16)
+	instancer.RegisterDiffs(diffs)
+	events := make(chan sd.Event, 16)
+	instancer.Register(events)
+	if want, have := []string{"1.0.0.1:1001"}, (<-events).Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// The name is resolved again once the TTL expires.
+	results <- result{addrs: []*net.SRV{
+		{Target: "1.0.0.1", Port: 1001, Weight: 20},
+		{Target: "1.0.0.2", Port: 1002},
+	}, ttl: time.Hour}
+	if have := <-lookups; have.Sub(start) < 50*time.Millisecond {
+		t.Errorf("resolved again after %v, before the TTL", have.Sub(start))
+	}
+	if want, have := (Diff{Added: []string{"1.0.0.2:1002"}, Changed: []string{"1.0.0.1:1001"}}), <-diffs; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	event := <-events
+	if want, have := []string{"1.0.0.1:1001", "1.0.0.2:1002"}, event.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := (sd.Instance{Address: "1.0.0.1:1001", Weight: 20}), event.Record("1.0.0.1:1001"); !reflect.DeepEqual(want, have) {
+		t.Errorf("record: want %+v, have %+v", want, have)
+	}
+	if want, have := uint16(20), instancer.Records()["1.0.0.1:1001"].Weight; want != have {
+		t.Errorf("weight: want %d, have %d", want, have)
+	}
+	if want, have := 20, instancer.Weight("1.0.0.1:1001"); want != have {
+		t.Errorf("weight: want %d, have %d", want, have)
+	}
+
+	// The TTL of an hour is bounded by the maximum refresh; failures keep
+	// the records.
+	results <- result{err: errors.New("dang")}
+	if event = <-events; event.Err == nil {
+		t.Errorf("want error, have %v", event)
+	}
+	if want, have := 2, len(instancer.Records()); want != have {
+		t.Errorf("records: want %d, have %d", want, have)
+	}
+
+	// After a failure, the name is resolved again at the minimum refresh.
+	before := time.Now()
+	results <- result{addrs: []*net.SRV{{Target: "1.0.0.2", Port: 1002}}, ttl: time.Hour}
+	if have := <-lookups; have.Sub(before) > 500*time.Millisecond {
+		t.Errorf("resolved again after %v, want the minimum refresh", have.Sub(before))
+	}
+}
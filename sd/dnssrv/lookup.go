@@ -1,7 +1,92 @@
 package dnssrv
 
-import "net"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
 
 // Lookup is a function that resolves a DNS SRV record to multiple addresses.
 // It has the same signature as net.LookupSRV.
 type Lookup func(service, proto, name string) (cname string, addrs []*net.SRV, err error)
+
+// TTLLookup is a function that resolves a DNS SRV record to multiple
+// addresses, and returns the TTL of the records, after which the name should
+// be resolved again.
+type TTLLookup func(name string) (addrs []*net.SRV, ttl time.Duration, err error)
+
+// NewTTLLookup returns a TTLLookup which queries the DNS servers at the
+// addresses, e.g. "10.0.0.2:53", in order, until one answers, or those of
+// /etc/resolv.conf, if there are none. The TTL is the least of the answers.
+func NewTTLLookup(servers ...string) (TTLLookup, error) {
+	if len(servers) == 0 {
+		conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range conf.Servers {
+			servers = append(servers, net.JoinHostPort(server, conf.Port))
+		}
+		if len(servers) == 0 {
+			return nil, errors.New("no DNS servers in /etc/resolv.conf")
+		}
+	}
+	return func(name string) ([]*net.SRV, time.Duration, error) {
+		return lookupTTL(servers, name)
+	}, nil
+}
+
+func lookupTTL(servers []string, name string) ([]*net.SRV, time.Duration, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeSRV)
+
+	var (
+		r   *dns.Msg
+		err error
+	)
+	for _, server := range servers {
+		if r, err = exchange(m, server); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, 0, fmt.Errorf("lookup %s: %s", name, dns.RcodeToString[r.Rcode])
+	}
+
+	var (
+		addrs []*net.SRV
+		ttl   uint32
+	)
+	for _, rr := range r.Answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		if len(addrs) == 0 || srv.Hdr.Ttl < ttl {
+			ttl = srv.Hdr.Ttl
+		}
+		addrs = append(addrs, &net.SRV{
+			Target:   srv.Target,
+			Port:     srv.Port,
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+		})
+	}
+	return addrs, time.Duration(ttl) * time.Second, nil
+}
+
+// exchange queries the server over UDP, and over TCP, if the answer is
+// truncated.
+func exchange(m *dns.Msg, server string) (*dns.Msg, error) {
+	r, _, err := new(dns.Client).Exchange(m, server)
+	if err == nil && r.Truncated {
+		r, _, err = (&dns.Client{Net: "tcp"}).Exchange(m, server)
+	}
+	return r, err
+}
@@ -0,0 +1,58 @@
+package dnssrv
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestTTLLookup(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name != "search.service.internal." {
+			m.Rcode = dns.RcodeNameError
+		} else {
+			for i, ttl := range []uint32{300, 60} {
+				m.Answer = append(m.Answer, &dns.SRV{
+					Hdr:      dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: ttl},
+					Priority: 1,
+					Weight:   uint16(10 * (i + 1)),
+					Port:     uint16(8080 + i),
+					Target:   "host" + string(rune('a'+i)) + ".internal.",
+				})
+			}
+		}
+		w.WriteMsg(m)
+	})}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	lookup, err := NewTTLLookup(pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, ttl, err := lookup("search.service.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 60*time.Second, ttl; want != have {
+		t.Errorf("ttl: want %v, have %v", want, have)
+	}
+	if want, have := 2, len(addrs); want != have {
+		t.Fatalf("want %d records, have %d", want, have)
+	}
+	if want, have := (net.SRV{Target: "hostb.internal.", Port: 8081, Priority: 1, Weight: 20}), *addrs[1]; want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+
+	if _, _, err := lookup("nope.internal"); err == nil {
+		t.Error("want error, have none")
+	}
+}
@@ -2,6 +2,7 @@ package sd
 
 import (
 	"io"
+	"reflect"
 	"sort"
 	"sync"
 	"time"
@@ -18,8 +19,14 @@ type endpointCache struct {
 	mtx                sync.RWMutex
 	factory            Factory
 	cache              map[string]endpointCloser
+	instances          []string
+	records            map[string]Instance
+	recreate           bool // on changes of records
+	unhealthy          map[string]bool
+	ejected            map[string]time.Time
 	err                error
 	endpoints          []endpoint.Endpoint
+	instanceEndpoints  []InstanceEndpoint
 	logger             log.Logger
 	invalidateDeadline time.Time
 	timeNow            func() time.Time
@@ -33,11 +40,13 @@ type endpointCloser struct {
 // newEndpointCache returns a new, empty endpointCache.
 func newEndpointCache(factory Factory, logger log.Logger, options endpointerOptions) *endpointCache {
 	return &endpointCache{
-		options: options,
-		factory: factory,
-		cache:   map[string]endpointCloser{},
-		logger:  logger,
-		timeNow: time.Now,
+		options:   options,
+		factory:   factory,
+		cache:     map[string]endpointCloser{},
+		unhealthy: map[string]bool{},
+		ejected:   map[string]time.Time{},
+		logger:    logger,
+		timeNow:   time.Now,
 	}
 }
 
@@ -51,13 +60,14 @@ func (c *endpointCache) Update(event Event) {
 
 	// Happy path.
 	if event.Err == nil {
-		c.updateCache(event.Instances)
+		c.updateCache(event.Instances, event.Records)
 		c.err = nil
 		return
 	}
 
 	// Sad path. Something's gone wrong in sd.
 	c.logger.Log("err", event.Err)
+	count(c.options.metrics.Errors, 1)
 	if !c.options.invalidateOnError {
 		return // keep returning the last known endpoints on error
 	}
@@ -70,15 +80,22 @@ func (c *endpointCache) Update(event Event) {
 	return
 }
 
-func (c *endpointCache) updateCache(instances []string) {
+func (c *endpointCache) updateCache(instances []string, records map[string]Instance) {
 	// Deterministic order (for later).
 	sort.Strings(instances)
 
+	// The factory may look up the records of new instances.
+	previous := c.records
+	c.records = records
+
 	// Produce the current set of services.
+	var added, removed int
 	cache := make(map[string]endpointCloser, len(instances))
 	for _, instance := range instances {
-		// If it already exists, just copy it over.
-		if sc, ok := c.cache[instance]; ok {
+		// If it already exists, just copy it over, unless the factory
+		// receives records and its record changed.
+		unchanged := !c.recreate || reflect.DeepEqual(previous[instance], records[instance])
+		if sc, ok := c.cache[instance]; ok && unchanged {
 			cache[instance] = sc
 			delete(c.cache, instance)
 			continue
@@ -88,9 +105,11 @@ func (c *endpointCache) updateCache(instances []string) {
 		service, closer, err := c.factory(instance)
 		if err != nil {
 			c.logger.Log("instance", instance, "err", err)
+			count(c.options.metrics.FactoryErrors, 1)
 			continue
 		}
 		cache[instance] = endpointCloser{service, closer}
+		added++
 	}
 
 	// Close any leftover endpoints.
@@ -98,33 +117,172 @@ func (c *endpointCache) updateCache(instances []string) {
 		if sc.Closer != nil {
 			sc.Closer.Close()
 		}
+		removed++
 	}
 
-	// Populate the slice of endpoints.
-	endpoints := make([]endpoint.Endpoint, 0, len(cache))
-	for _, instance := range instances {
-		// A bad factory may mean an instance is not present.
+	if added > 0 || removed > 0 {
+		c.logger.Log("instances", len(cache), "added", added, "removed", removed)
+	}
+	count(c.options.metrics.Added, added)
+	count(c.options.metrics.Removed, removed)
+	if g := c.options.metrics.Instances; g != nil {
+		g.Set(float64(len(cache)))
+	}
+
+	// Forget the health and ejections of removed instances.
+	for instance := range c.unhealthy {
 		if _, ok := cache[instance]; !ok {
-			continue
+			delete(c.unhealthy, instance)
+		}
+	}
+	for instance := range c.ejected {
+		if _, ok := cache[instance]; !ok {
+			delete(c.ejected, instance)
 		}
-		endpoints = append(endpoints, cache[instance].Endpoint)
 	}
 
 	// Swap and trigger GC for old copies.
-	c.endpoints = endpoints
+	c.instances = instances
 	c.cache = cache
+	c.populate()
+}
+
+// populate populates the slice of endpoints, withholding those of unhealthy
+// or ejected instances, and those of less preferred zones.
+func (c *endpointCache) populate() {
+	var (
+		instances = make([]string, 0, len(c.cache))
+		available = make(map[string]bool, len(c.cache))
+	)
+	for _, instance := range c.instances {
+		// A bad factory may mean an instance is not present.
+		if _, ok := c.cache[instance]; !ok {
+			continue
+		}
+		_, ejected := c.ejected[instance]
+		instances = append(instances, instance)
+		available[instance] = !ejected && !c.unhealthy[instance]
+	}
+	if c.options.zones != nil {
+		instances = c.options.zones.prefer(instances, available, c.record)
+	}
+
+	var (
+		endpoints         = make([]endpoint.Endpoint, 0, len(instances))
+		instanceEndpoints = make([]InstanceEndpoint, 0, len(instances))
+	)
+	for _, instance := range instances {
+		if available[instance] {
+			e := c.cache[instance].Endpoint
+			endpoints = append(endpoints, e)
+			instanceEndpoints = append(instanceEndpoints, InstanceEndpoint{c.record(instance), e})
+		}
+	}
+	c.endpoints, c.instanceEndpoints = endpoints, instanceEndpoints
+}
+
+// record returns the record of the instance. The lock must be held, as it is
+// while the factory is called.
+func (c *endpointCache) record(instance string) Instance {
+	return Event{Records: c.records}.Record(instance)
+}
+
+// Instances returns the instances which have endpoints, healthy or not.
+func (c *endpointCache) Instances() []string {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	instances := make([]string, 0, len(c.cache))
+	for _, instance := range c.instances {
+		if _, ok := c.cache[instance]; ok {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
+
+// SetHealth records the results of probes of instances, by instance. The
+// endpoints of unhealthy instances are withheld until they're healthy again.
+func (c *endpointCache) SetHealth(results map[string]error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	changed := false
+	for instance, err := range results {
+		if _, ok := c.cache[instance]; !ok || c.unhealthy[instance] == (err != nil) {
+			continue // removed since it was probed, or unchanged
+		}
+		if err == nil {
+			c.logger.Log("instance", instance, "health", "healthy")
+			delete(c.unhealthy, instance)
+		} else {
+			c.logger.Log("instance", instance, "health", "unhealthy", "err", err)
+			c.unhealthy[instance] = true
+		}
+		changed = true
+	}
+	if changed {
+		c.populate()
+	}
+}
+
+// Eject withholds the endpoint of the instance for d, unless more than
+// maxPercent of the instances would be ejected, and reports whether it was
+// ejected. One instance may always be ejected.
+func (c *endpointCache) Eject(instance string, d time.Duration, maxPercent int) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ejected := c.ejected[instance]; ejected {
+		return false
+	}
+	if _, ok := c.cache[instance]; !ok {
+		return false
+	}
+	max := len(c.cache) * maxPercent / 100
+	if max < 1 {
+		max = 1
+	}
+	if len(c.ejected) >= max {
+		c.logger.Log("instance", instance, "msg", "not ejected, as too many instances are ejected")
+		return false
+	}
+	c.logger.Log("instance", instance, "msg", "ejected", "duration", d)
+	until := c.timeNow().Add(d)
+	c.ejected[instance] = until
+	c.populate()
+	time.AfterFunc(d, func() {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		// The instance may have been removed, and ejected again since.
+		if c.ejected[instance].Equal(until) {
+			c.logger.Log("instance", instance, "msg", "readmitted")
+			delete(c.ejected, instance)
+			c.populate()
+		}
+	})
+	return true
 }
 
 // Endpoints yields the current set of (presumably identical) endpoints, ordered
 // lexicographically by the corresponding instance string.
 func (c *endpointCache) Endpoints() ([]endpoint.Endpoint, error) {
+	endpoints, _, err := c.current()
+	return endpoints, err
+}
+
+// InstanceEndpoints yields the current set of endpoints with the records of
+// their instances, in the order of Endpoints.
+func (c *endpointCache) InstanceEndpoints() ([]InstanceEndpoint, error) {
+	_, instanceEndpoints, err := c.current()
+	return instanceEndpoints, err
+}
+
+func (c *endpointCache) current() ([]endpoint.Endpoint, []InstanceEndpoint, error) {
 	// in the steady state we're going to have many goroutines calling Endpoints()
 	// concurrently, so to minimize contention we use a shared R-lock.
 	c.mtx.RLock()
 
 	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
 		defer c.mtx.RUnlock()
-		return c.endpoints, nil
+		return c.endpoints, c.instanceEndpoints, nil
 	}
 
 	c.mtx.RUnlock()
@@ -135,9 +293,9 @@ func (c *endpointCache) Endpoints() ([]endpoint.Endpoint, error) {
 
 	// re-check condition due to a race between RUnlock() and Lock().
 	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
-		return c.endpoints, nil
+		return c.endpoints, c.instanceEndpoints, nil
 	}
 
-	c.updateCache(nil) // close any remaining active endpoints
-	return nil, c.err
+	c.updateCache(nil, nil) // close any remaining active endpoints
+	return nil, nil, c.err
 }
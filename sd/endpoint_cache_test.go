@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
 )
 
 func TestEndpointCache(t *testing.T) {
@@ -149,6 +150,47 @@ func TestBadFactory(t *testing.T) {
 	assertEndpointsLen(t, cache, 0)
 }
 
+func TestEndpointCacheMetrics(t *testing.T) {
+	var (
+		m = Metrics{
+			Instances:     generic.NewGauge("instances"),
+			Added:         generic.NewCounter("added"),
+			Removed:       generic.NewCounter("removed"),
+			Errors:        generic.NewCounter("errors"),
+			FactoryErrors: generic.NewCounter("factory_errors"),
+		}
+		opts endpointerOptions
+		f    = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			if instance == "bad" {
+				return nil, nil, errors.New("bad instance")
+			}
+			return endpoint.Nop, nil, nil
+		}
+	)
+	Instrument(m)(&opts)
+	cache := newEndpointCache(f, log.NewNopLogger(), opts)
+
+	cache.Update(Event{Instances: []string{"a", "b", "bad"}})
+	cache.Update(Event{Err: errors.New("sd error")})
+	cache.Update(Event{Instances: []string{"b", "c"}})
+
+	for _, tc := range []struct {
+		name string
+		have float64
+		want float64
+	}{
+		{"instances", m.Instances.(*generic.Gauge).Value(), 2},
+		{"added", m.Added.(*generic.Counter).Value(), 3},
+		{"removed", m.Removed.(*generic.Counter).Value(), 1},
+		{"errors", m.Errors.(*generic.Counter).Value(), 1},
+		{"factory errors", m.FactoryErrors.(*generic.Counter).Value(), 1},
+	} {
+		if tc.want != tc.have {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, tc.have)
+		}
+	}
+}
+
 func assertEndpointsLen(t *testing.T, cache *endpointCache, l int) {
 	endpoints, err := cache.Endpoints()
 	if err != nil {
@@ -1,6 +1,9 @@
 package sd
 
 import (
+	"context"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
@@ -15,6 +18,22 @@ type Endpointer interface {
 	Endpoints() ([]endpoint.Endpoint, error)
 }
 
+// InstanceEndpointer is an Endpointer which also yields the instance of each
+// of its endpoints, for balancers which depend on them, e.g. to weigh or hash
+// the instances. The slice it yields is replaced, rather than modified, when
+// the endpoints change, so that callers may detect changes cheaply; it must
+// not be modified.
+type InstanceEndpointer interface {
+	Endpointer
+	InstanceEndpoints() ([]InstanceEndpoint, error)
+}
+
+// InstanceEndpoint is an endpoint with the record of its instance.
+type InstanceEndpoint struct {
+	Instance Instance
+	Endpoint endpoint.Endpoint
+}
+
 // FixedEndpointer yields a fixed set of endpoints.
 type FixedEndpointer []endpoint.Endpoint
 
@@ -26,17 +45,44 @@ func (s FixedEndpointer) Endpoints() ([]endpoint.Endpoint, error) { return s, ni
 // keeps returning previously created Endpoints assuming they are still good, unless
 // this behavior is disabled via InvalidateOnError option.
 func NewEndpointer(src Instancer, f Factory, logger log.Logger, options ...EndpointerOption) *DefaultEndpointer {
+	return newEndpointer(src, func(*endpointCache) Factory { return f }, logger, options)
+}
+
+// NewRecordEndpointer is like NewEndpointer, but factory f receives the
+// structured records of the instances, as Instancer src notifies of them.
+// The endpoint of an instance is created again when its record changes, e.g.
+// as its weight does.
+func NewRecordEndpointer(src Instancer, f RecordFactory, logger log.Logger, options ...EndpointerOption) *DefaultEndpointer {
+	return newEndpointer(src, func(c *endpointCache) Factory {
+		c.recreate = true
+		return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return f(c.record(instance))
+		}
+	}, logger, options)
+}
+
+func newEndpointer(src Instancer, factory func(*endpointCache) Factory, logger log.Logger, options []EndpointerOption) *DefaultEndpointer {
 	opts := endpointerOptions{}
 	for _, opt := range options {
 		opt(&opts)
 	}
+	cache := newEndpointCache(nil, logger, opts)
+	f := factory(cache)
+	cache.factory = f
+	if opts.outliers != nil {
+		cache.factory = opts.outliers.factory(f, cache)
+	}
 	se := &DefaultEndpointer{
-		cache:     newEndpointCache(f, logger, opts),
+		cache:     cache,
 		instancer: src,
 		ch:        make(chan Event),
+		quit:      make(chan struct{}),
 	}
 	go se.receive()
 	src.Register(se.ch)
+	if opts.probe != nil {
+		go se.check(opts.probe, opts.probeInterval)
+	}
 	return se
 }
 
@@ -57,9 +103,26 @@ func InvalidateOnError(timeout time.Duration) EndpointerOption {
 	}
 }
 
+// HealthCheck returns EndpointerOption that probes each instance with probe
+// every interval, independent of the health the discovery system reports.
+// The endpoints of instances whose last probe failed are withheld, until a
+// probe succeeds again. Each probe is canceled after the interval. Instances
+// are healthy until they're first probed.
+func HealthCheck(probe Probe, interval time.Duration) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		opts.probe = probe
+		opts.probeInterval = interval
+	}
+}
+
 type endpointerOptions struct {
 	invalidateOnError bool
 	invalidateTimeout time.Duration
+	probe             Probe
+	probeInterval     time.Duration
+	outliers          *outlierDetector
+	zones             *zonePreference
+	metrics           Metrics
 }
 
 // DefaultEndpointer implements an Endpointer interface.
@@ -70,6 +133,7 @@ type DefaultEndpointer struct {
 	cache     *endpointCache
 	instancer Instancer
 	ch        chan Event
+	quit      chan struct{}
 }
 
 func (de *DefaultEndpointer) receive() {
@@ -78,13 +142,53 @@ func (de *DefaultEndpointer) receive() {
 	}
 }
 
-// Close de-registeres DefaultEndpointer from the Instancer and stops the internal go-routine.
+// check probes the instances every interval, until the Endpointer is closed.
+func (de *DefaultEndpointer) check(probe Probe, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-de.quit:
+			return
+		}
+
+		var (
+			instances = de.cache.Instances()
+			results   = make(map[string]error, len(instances))
+			mtx       sync.Mutex
+			wg        sync.WaitGroup
+		)
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		wg.Add(len(instances))
+		for _, instance := range instances {
+			go func(instance string) {
+				defer wg.Done()
+				err := probe(ctx, instance)
+				mtx.Lock()
+				results[instance] = err
+				mtx.Unlock()
+			}(instance)
+		}
+		wg.Wait()
+		cancel()
+		de.cache.SetHealth(results)
+	}
+}
+
+// Close de-registeres DefaultEndpointer from the Instancer and stops the internal go-routines.
 func (de *DefaultEndpointer) Close() {
 	de.instancer.Deregister(de.ch)
 	close(de.ch)
+	close(de.quit)
 }
 
 // Endpoints implements Endpointer.
 func (de *DefaultEndpointer) Endpoints() ([]endpoint.Endpoint, error) {
 	return de.cache.Endpoints()
 }
+
+// InstanceEndpoints implements InstanceEndpointer.
+func (de *DefaultEndpointer) InstanceEndpoints() ([]InstanceEndpoint, error) {
+	return de.cache.InstanceEndpoints()
+}
@@ -1,7 +1,11 @@
 package sd_test
 
 import (
+	"context"
+	"errors"
 	"io"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -59,6 +63,144 @@ func TestDefaultEndpointer(t *testing.T) {
 	// and therefore does not have access to the endpointer's private members.
 }
 
+func TestHealthCheck(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		healthy = map[string]bool{"a": true, "b": true}
+		probe   = func(_ context.Context, instance string) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if !healthy[instance] {
+				return errors.New("unhealthy")
+			}
+			return nil
+		}
+		instancer = sd.FixedInstancer{"a", "b"}
+	)
+	endpointer := sd.NewEndpointer(instancer, func(string) (endpoint.Endpoint, io.Closer, error) {
+		return endpoint.Nop, nil, nil
+	}, log.NewNopLogger(), sd.HealthCheck(probe, time.Millisecond))
+	defer endpointer.Close()
+
+	expectEndpoints := func(want int) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			endpoints, err := endpointer.Endpoints()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(endpoints) == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("want %d endpoints, have %d", want, len(endpoints))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	expectEndpoints(2)
+
+	mtx.Lock()
+	healthy["b"] = false
+	mtx.Unlock()
+	expectEndpoints(1)
+
+	mtx.Lock()
+	healthy["b"] = true
+	mtx.Unlock()
+	expectEndpoints(2)
+}
+
+func TestRecordEndpointer(t *testing.T) {
+	var (
+		records = make(chan sd.Instance, 3)
+		ca      = make(closer)
+		f       = func(instance sd.Instance) (endpoint.Endpoint, io.Closer, error) {
+			records <- instance
+			if instance.Address == "a" && instance.Weight == 1 {
+				return endpoint.Nop, ca, nil
+			}
+			return endpoint.Nop, nil, nil
+		}
+		instancer = &mockInstancer{
+			cache: instance.NewCache(),
+		}
+	)
+	instancer.Update(sd.Event{
+		Instances: []string{"a", "b"},
+		Records:   map[string]sd.Instance{"a": {Address: "a", Tags: []string{"v1"}, Weight: 1}},
+	})
+
+	endpointer := sd.NewRecordEndpointer(instancer, f, log.NewNopLogger())
+	defer endpointer.Close()
+
+	expectRecord := func(want sd.Instance) {
+		select {
+		case have := <-records:
+			if !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("want %v, have none", want)
+		}
+	}
+	expectRecord(sd.Instance{Address: "a", Tags: []string{"v1"}, Weight: 1})
+	expectRecord(sd.Instance{Address: "b"}) // no record
+
+	// The endpoint of an instance whose record changes is created again.
+	instancer.Update(sd.Event{
+		Instances: []string{"a", "b"},
+		Records:   map[string]sd.Instance{"a": {Address: "a", Tags: []string{"v1"}, Weight: 2}},
+	})
+	expectRecord(sd.Instance{Address: "a", Tags: []string{"v1"}, Weight: 2})
+	select {
+	case <-ca:
+	case <-time.After(time.Second):
+		t.Errorf("didn't close the endpoint of the changed record in time")
+	}
+	select {
+	case have := <-records:
+		t.Errorf("want no more records, have %v", have)
+	default:
+	}
+}
+
+func TestInstanceEndpoints(t *testing.T) {
+	instancer := &mockInstancer{
+		cache: instance.NewCache(),
+	}
+	instancer.Update(sd.Event{
+		Instances: []string{"b", "a"},
+		Records:   map[string]sd.Instance{"a": {Address: "a", Weight: 3}},
+	})
+	endpointer := sd.NewEndpointer(instancer, func(string) (endpoint.Endpoint, io.Closer, error) {
+		return endpoint.Nop, nil, nil
+	}, log.NewNopLogger())
+	defer endpointer.Close()
+
+	var (
+		endpoints []sd.InstanceEndpoint
+		deadline  = time.Now().Add(time.Second)
+	)
+	for len(endpoints) < 2 && time.Now().Before(deadline) {
+		endpoints, _ = endpointer.InstanceEndpoints()
+		time.Sleep(time.Millisecond)
+	}
+	var have []sd.Instance
+	for _, ie := range endpoints {
+		have = append(have, ie.Instance)
+	}
+	if want := []sd.Instance{{Address: "a", Weight: 3}, {Address: "b"}}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// The slice is the same until the endpoints change.
+	again, _ := endpointer.InstanceEndpoints()
+	if &again[0] != &endpoints[0] {
+		t.Error("want the same slice while the endpoints are unchanged")
+	}
+}
+
 type mockInstancer struct {
 	cache *instance.Cache
 }
@@ -1,24 +1,23 @@
 package etcd
 
 import (
-	"sync"
 	"time"
 
 	etcd "github.com/coreos/etcd/client"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 )
 
 const minHeartBeatTime = 500 * time.Millisecond
 
 // Registrar registers service instance liveness information to etcd.
 type Registrar struct {
-	client  Client
-	service Service
-	logger  log.Logger
-
-	quitmtx sync.Mutex
-	quit    chan struct{}
+	client    Client
+	service   Service
+	logger    log.Logger
+	heartbeat []sd.HeartbeatOption
+	registrar *sd.HeartbeatRegistrar
 }
 
 // Service holds the instance identifying data you want to publish to etcd. Key
@@ -57,64 +56,70 @@ func NewTTLOption(heartbeat, ttl time.Duration) *TTLOption {
 	}
 }
 
+// RegistrarOption sets an optional parameter for registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarStateChange sets a function which is called with the state of
+// the registration of services with a TTL each time it changes, e.g. to
+// tie readiness to the registration. By default, changes are only logged.
+func RegistrarStateChange(f func(sd.RegistrationState)) RegistrarOption {
+	return func(r *Registrar) { r.heartbeat = append(r.heartbeat, sd.HeartbeatStateChange(f)) }
+}
+
 // NewRegistrar returns a etcd Registrar acting on the provided catalog
 // registration (service).
-func NewRegistrar(client Client, service Service, logger log.Logger) *Registrar {
-	return &Registrar{
+func NewRegistrar(client Client, service Service, logger log.Logger, options ...RegistrarOption) *Registrar {
+	r := &Registrar{
 		client:  client,
 		service: service,
 		logger:  log.With(logger, "key", service.Key, "value", service.Value),
 	}
+	for _, option := range options {
+		option(r)
+	}
+	if service.TTL != nil {
+		r.registrar = sd.NewHeartbeatRegistrar(heartbeater{client, service}, service.TTL.heartbeat, r.logger, r.heartbeat...)
+	}
+	return r
 }
 
 // Register implements the sd.Registrar interface. Call it when you want your
-// service to be registered in etcd, typically at startup.
+// service to be registered in etcd, typically at startup. The keys of
+// services with a TTL are refreshed by heartbeats, and, if the first
+// registration fails, registered again until it succeeds.
 func (r *Registrar) Register() {
+	if r.registrar != nil {
+		r.registrar.Register()
+		return
+	}
 	if err := r.client.Register(r.service); err != nil {
 		r.logger.Log("err", err)
 	} else {
 		r.logger.Log("action", "register")
 	}
-	if r.service.TTL != nil {
-		go r.loop()
-	}
-}
-
-func (r *Registrar) loop() {
-	r.quitmtx.Lock()
-	if r.quit != nil {
-		return // already running
-	}
-	r.quit = make(chan struct{})
-	r.quitmtx.Unlock()
-
-	tick := time.NewTicker(r.service.TTL.heartbeat)
-	defer tick.Stop()
-	for {
-		select {
-		case <-tick.C:
-			if err := r.client.Register(r.service); err != nil {
-				r.logger.Log("err", err)
-			}
-		case <-r.quit:
-			return
-		}
-	}
 }
 
 // Deregister implements the sd.Registrar interface. Call it when you want your
 // service to be deregistered from etcd, typically just prior to shutdown.
 func (r *Registrar) Deregister() {
+	if r.registrar != nil {
+		r.registrar.Deregister()
+		return
+	}
 	if err := r.client.Deregister(r.service); err != nil {
 		r.logger.Log("err", err)
 	} else {
 		r.logger.Log("action", "deregister")
 	}
+}
 
-	r.quitmtx.Lock()
-	defer r.quitmtx.Unlock()
-	if r.quit != nil {
-		close(r.quit)
-		r.quit = nil
-	}
+// heartbeater implements sd.Heartbeater for a service with a TTL, whose key
+// is set again by each heartbeat, refreshing its TTL.
+type heartbeater struct {
+	client  Client
+	service Service
 }
+
+func (h heartbeater) Register() error   { return h.client.Register(h.service) }
+func (h heartbeater) Heartbeat() error  { return h.client.Register(h.service) }
+func (h heartbeater) Deregister() error { return h.client.Deregister(h.service) }
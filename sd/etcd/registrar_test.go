@@ -3,9 +3,12 @@ package etcd
 import (
 	"bytes"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 )
 
 // testClient is a basic implementation of Client
@@ -109,3 +112,42 @@ func TestDeregister(t *testing.T) {
 		}
 	}
 }
+
+// countingClient counts the registrations of services.
+type countingClient struct {
+	testClient
+	mtx       sync.Mutex
+	registers int
+}
+
+func (c *countingClient) Register(s Service) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.registers++
+	return nil
+}
+
+func TestRegisterTTL(t *testing.T) {
+	var (
+		c       = &countingClient{}
+		service = Service{Key: "testKey", Value: "testValue", TTL: &TTLOption{heartbeat: time.Millisecond, ttl: time.Second}}
+		states  = make(chan sd.RegistrationState, 2)
+		r       = NewRegistrar(c, service, log.NewNopLogger(), RegistrarStateChange(func(s sd.RegistrationState) { states <- s }))
+	)
+	r.Register()
+	if want, have := sd.Registered, <-states; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// The key is refreshed by heartbeats.
+	time.Sleep(20 * time.Millisecond)
+	r.Deregister()
+	if want, have := sd.Deregistered, <-states; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.registers < 3 {
+		t.Errorf("want at least 3 registrations, have %d", c.registers)
+	}
+}
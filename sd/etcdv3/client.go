@@ -0,0 +1,228 @@
+package etcdv3
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+)
+
+var (
+	// ErrNoKey indicates a client method needs a key but receives none.
+	ErrNoKey = errors.New("no key provided")
+
+	// ErrNoValue indicates a client method needs a value but receives none.
+	ErrNoValue = errors.New("no value provided")
+)
+
+// Client is a wrapper around the etcd client.
+type Client interface {
+	// GetEntries queries the given prefix in etcd and returns a slice
+	// containing the values of all keys found, recursively, underneath that
+	// prefix.
+	GetEntries(prefix string) ([]string, error)
+
+	// WatchPrefix watches the given prefix in etcd for changes. When a change
+	// is detected, it will signal on the passed channel. Clients are expected
+	// to call GetEntries to update themselves with the latest set of complete
+	// values. WatchPrefix will always send an initial sentinel value on the
+	// channel after establishing the watch, to ensure that clients always
+	// receive the latest set of values. WatchPrefix will block until the
+	// context passed to the NewClient constructor is terminated.
+	WatchPrefix(prefix string, ch chan struct{})
+
+	// Register a service with etcd. If it has a TTL, its key is bound to a
+	// new lease of the TTL, which is kept alive until it's deregistered, and
+	// the returned channel is closed if the lease is lost, e.g. as it
+	// expires while etcd is unreachable, and so the key is deleted.
+	// Otherwise, the channel is nil.
+	Register(s Service) (lost <-chan struct{}, err error)
+
+	// Deregister a service with etcd, revoking its lease, if it has one.
+	Deregister(s Service) error
+}
+
+type client struct {
+	cli *clientv3.Client
+	ctx context.Context
+
+	mtx    sync.Mutex
+	leases map[string]lease // by key
+}
+
+// lease is a lease kept alive for the key of a service.
+type lease struct {
+	id     clientv3.LeaseID
+	cancel context.CancelFunc
+}
+
+// ClientOptions defines options for the etcd client. All values are optional.
+// If any duration is not specified, a default of 3 seconds will be used.
+type ClientOptions struct {
+	Cert          string
+	Key           string
+	CACert        string
+	DialTimeout   time.Duration
+	DialKeepAlive time.Duration
+	Username      string
+	Password      string
+}
+
+// NewClient returns Client with a connection to the named machines. It will
+// return an error if a connection to the cluster cannot be made. The parameter
+// machines needs to be a full URL with schemas. e.g. "http://localhost:2379"
+// will work, but "localhost:2379" will not.
+func NewClient(ctx context.Context, machines []string, options ClientOptions) (Client, error) {
+	if options.DialTimeout == 0 {
+		options.DialTimeout = 3 * time.Second
+	}
+	if options.DialKeepAlive == 0 {
+		options.DialKeepAlive = 3 * time.Second
+	}
+
+	var tlscfg *tls.Config
+	if options.Cert != "" && options.Key != "" {
+		tlsCert, err := tls.LoadX509KeyPair(options.Cert, options.Key)
+		if err != nil {
+			return nil, err
+		}
+		caCertCt, err := ioutil.ReadFile(options.CACert)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCertCt)
+		tlscfg = &tls.Config{
+			Certificates: []tls.Certificate{tlsCert},
+			RootCAs:      caCertPool,
+		}
+	}
+
+	cli, err := clientv3.New(clientv3.Config{
+		Context:           ctx,
+		Endpoints:         machines,
+		DialTimeout:       options.DialTimeout,
+		DialKeepAliveTime: options.DialKeepAlive,
+		TLS:               tlscfg,
+		Username:          options.Username,
+		Password:          options.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{
+		cli:    cli,
+		ctx:    ctx,
+		leases: map[string]lease{},
+	}, nil
+}
+
+// GetEntries implements the etcd Client interface.
+func (c *client) GetEntries(prefix string) ([]string, error) {
+	resp, err := c.cli.Get(c.ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		entries[i] = string(kv.Value)
+	}
+	return entries, nil
+}
+
+// WatchPrefix implements the etcd Client interface.
+func (c *client) WatchPrefix(prefix string, ch chan struct{}) {
+	wch := c.cli.Watch(c.ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(0))
+	ch <- struct{}{} // make sure caller invokes GetEntries
+	for wr := range wch {
+		if wr.Canceled {
+			return
+		}
+		ch <- struct{}{}
+	}
+}
+
+func (c *client) Register(s Service) (<-chan struct{}, error) {
+	if s.Key == "" {
+		return nil, ErrNoKey
+	}
+	if s.Value == "" {
+		return nil, ErrNoValue
+	}
+	if s.TTL == nil {
+		_, err := c.cli.Put(c.ctx, s.Key, s.Value)
+		return nil, err
+	}
+
+	grant, err := c.cli.Grant(c.ctx, int64(s.TTL.ttl/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.cli.Put(c.ctx, s.Key, s.Value, clientv3.WithLease(grant.ID)); err != nil {
+		c.cli.Revoke(c.ctx, grant.ID)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	kch, err := c.cli.KeepAlive(ctx, grant.ID)
+	if err != nil {
+		cancel()
+		c.cli.Revoke(c.ctx, grant.ID)
+		return nil, err
+	}
+
+	// A previous lease of the key is superseded by the new one.
+	c.mtx.Lock()
+	prev, ok := c.leases[s.Key]
+	c.leases[s.Key] = lease{id: grant.ID, cancel: cancel}
+	c.mtx.Unlock()
+	if ok {
+		prev.cancel()
+		c.cli.Revoke(c.ctx, prev.id)
+	}
+
+	lost := make(chan struct{})
+	go func() {
+		for range kch {
+			// The responses of the keepalives are only drained.
+		}
+		// The channel is also closed once the keepalive is canceled, by
+		// Deregister, or a new registration, which isn't a loss.
+		if ctx.Err() == nil {
+			close(lost)
+		}
+	}()
+	return lost, nil
+}
+
+func (c *client) Deregister(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+
+	c.mtx.Lock()
+	l, ok := c.leases[s.Key]
+	delete(c.leases, s.Key)
+	c.mtx.Unlock()
+	if ok {
+		l.cancel()
+		// Revoking the lease deletes the key, unless it's expired, and so
+		// deleted, already.
+		_, err := c.cli.Revoke(c.ctx, l.id)
+		if err == rpctypes.ErrLeaseNotFound {
+			err = nil
+		}
+		return err
+	}
+
+	_, err := c.cli.Delete(c.ctx, s.Key)
+	return err
+}
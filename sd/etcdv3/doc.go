@@ -0,0 +1,4 @@
+// Package etcdv3 provides an Instancer and Registrar implementation for etcd,
+// by its v3 API. Registrations are bound to leases, which are kept alive while
+// the instance runs, so that the keys of crashed instances expire promptly.
+package etcdv3
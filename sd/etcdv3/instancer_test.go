@@ -0,0 +1,74 @@
+package etcdv3
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+var _ sd.Instancer = &Instancer{} // API check
+
+func TestInstancer(t *testing.T) {
+	client := &fakeClient{
+		kvs: map[string]string{"/foo/1": "1:1", "/foo/2": "1:2"},
+	}
+
+	s, err := NewInstancer(client, "/foo", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	state := s.cache.State()
+	if state.Err != nil {
+		t.Fatal(state.Err)
+	}
+	if want, have := 2, len(state.Instances); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestInstancerNoPrefix(t *testing.T) {
+	client := &fakeClient{err: errors.New("unavailable")}
+
+	s, err := NewInstancer(client, "/foo", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	if s.cache.State().Err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+type fakeClient struct {
+	kvs map[string]string
+	err error
+}
+
+func (c *fakeClient) GetEntries(prefix string) ([]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var entries []string
+	for k, v := range c.kvs {
+		if strings.HasPrefix(k, prefix) {
+			entries = append(entries, v)
+		}
+	}
+	return entries, nil
+}
+
+func (c *fakeClient) WatchPrefix(prefix string, ch chan struct{}) {}
+
+func (c *fakeClient) Register(Service) (<-chan struct{}, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) Deregister(Service) error {
+	return nil
+}
@@ -0,0 +1,206 @@
+package etcdv3
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const minTTL = time.Second
+
+// Registrar registers service instance liveness information to etcd.
+type Registrar struct {
+	client     Client
+	service    Service
+	logger     log.Logger
+	state      func(State)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	quitmtx sync.Mutex
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+// Service holds the instance identifying data you want to publish to etcd. Key
+// must be unique, and value is the string returned to subscribers, typically
+// called the "instance" string in other parts of package sd.
+type Service struct {
+	Key   string // unique key, e.g. "/service/foobar/1.2.3.4:8080"
+	Value string // returned to subscribers, e.g. "http://1.2.3.4:8080"
+	TTL   *TTLOption
+}
+
+// TTLOption allows binding a key to a lease of a TTL, which is kept alive
+// while it's registered, so that it's deleted once the TTL expires after
+// the instance stops keeping it alive, e.g. as it crashes.
+type TTLOption struct {
+	ttl time.Duration // e.g. time.Second * 10
+}
+
+// NewTTLOption returns a TTLOption with the TTL of the lease, in whole
+// seconds, of at least a second. The lease is kept alive about three times
+// per TTL, by the etcd client.
+//
+// A good default value might be a 10s TTL.
+func NewTTLOption(ttl time.Duration) *TTLOption {
+	if ttl < minTTL {
+		ttl = minTTL
+	}
+	return &TTLOption{
+		ttl: ttl,
+	}
+}
+
+// State is the state of the registration of a Registrar.
+type State int
+
+const (
+	// Registered is the state once the service is registered, and each time
+	// it's registered again after its lease is lost.
+	Registered State = iota
+
+	// Lost is the state once the lease of the service is lost, e.g. as it
+	// expired while etcd was unreachable, and so its key is deleted, until
+	// it's registered again.
+	Lost
+
+	// Deregistered is the state once the service is deregistered.
+	Deregistered
+)
+
+func (s State) String() string {
+	switch s {
+	case Registered:
+		return "registered"
+	case Lost:
+		return "lost"
+	case Deregistered:
+		return "deregistered"
+	default:
+		return "unknown"
+	}
+}
+
+// RegistrarOption sets an optional parameter for registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarStateChange sets a function which is called with the state of
+// the registration each time it changes, e.g. to fail readiness checks
+// while the lease is lost. By default, changes are only logged.
+func RegistrarStateChange(f func(State)) RegistrarOption {
+	return func(r *Registrar) { r.state = f }
+}
+
+// RegistrarBackoff sets the backoff of registrations after they fail, e.g.
+// as etcd is unreachable, which doubles from min to max, for services with
+// a TTL, which are registered again until they succeed. By default, it's
+// from 100ms to 30 seconds.
+func RegistrarBackoff(min, max time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.minBackoff, r.maxBackoff = min, max }
+}
+
+// NewRegistrar returns a etcd Registrar acting on the provided catalog
+// registration (service).
+func NewRegistrar(client Client, service Service, logger log.Logger, options ...RegistrarOption) *Registrar {
+	r := &Registrar{
+		client:     client,
+		service:    service,
+		logger:     log.With(logger, "key", service.Key, "value", service.Value),
+		state:      func(State) {},
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Register implements the sd.Registrar interface. Call it when you want your
+// service to be registered in etcd, typically at startup. Services with a
+// TTL are registered again whenever their lease is lost, and, if the first
+// registration fails, until it succeeds, until they're deregistered.
+func (r *Registrar) Register() {
+	if r.service.TTL == nil {
+		if _, err := r.client.Register(r.service); err != nil {
+			r.logger.Log("err", err)
+		} else {
+			r.logger.Log("action", "register")
+			r.state(Registered)
+		}
+		return
+	}
+
+	r.quitmtx.Lock()
+	defer r.quitmtx.Unlock()
+	if r.quit != nil {
+		return // already running
+	}
+	r.quit, r.done = make(chan struct{}), make(chan struct{})
+	lost, ok := r.register()
+	go r.loop(lost, ok, r.quit, r.done)
+}
+
+// register registers the service, and returns the channel closed once its
+// lease is lost, or false if it fails.
+func (r *Registrar) register() (<-chan struct{}, bool) {
+	lost, err := r.client.Register(r.service)
+	if err != nil {
+		r.logger.Log("err", err)
+		return nil, false
+	}
+	r.logger.Log("action", "register")
+	r.state(Registered)
+	return lost, true
+}
+
+func (r *Registrar) loop(lost <-chan struct{}, ok bool, quit, done chan struct{}) {
+	defer close(done)
+	backoff := r.minBackoff
+	for {
+		if ok {
+			select {
+			case <-lost:
+				r.logger.Log("action", "lost")
+				r.state(Lost)
+				backoff = r.minBackoff
+			case <-quit:
+				return
+			}
+		} else {
+			select {
+			case <-time.After(backoff):
+			case <-quit:
+				return
+			}
+			if backoff *= 2; backoff > r.maxBackoff {
+				backoff = r.maxBackoff
+			}
+		}
+
+		lost, ok = r.register()
+	}
+}
+
+// Deregister implements the sd.Registrar interface. Call it when you want your
+// service to be deregistered from etcd, typically just prior to shutdown.
+func (r *Registrar) Deregister() {
+	// The loop is stopped first, so that it doesn't register the service
+	// again after it's deregistered.
+	r.quitmtx.Lock()
+	if r.quit != nil {
+		close(r.quit)
+		<-r.done
+		r.quit, r.done = nil, nil
+	}
+	r.quitmtx.Unlock()
+
+	if err := r.client.Deregister(r.service); err != nil {
+		r.logger.Log("err", err)
+	} else {
+		r.logger.Log("action", "deregister")
+		r.state(Deregistered)
+	}
+}
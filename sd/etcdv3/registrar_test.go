@@ -0,0 +1,167 @@
+package etcdv3
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// testClient is a basic implementation of Client, whose registrations are
+// lost as their channels are closed.
+type testClient struct {
+	mtx         sync.Mutex
+	registerRes []error // values returned by successive calls to Register
+	registers   int
+	lost        []chan struct{}
+	deregisters int
+}
+
+func (tc *testClient) GetEntries(prefix string) ([]string, error) {
+	return nil, nil
+}
+
+func (tc *testClient) WatchPrefix(prefix string, ch chan struct{}) {}
+
+func (tc *testClient) Register(s Service) (<-chan struct{}, error) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	tc.registers++
+	if len(tc.registerRes) > 0 {
+		err := tc.registerRes[0]
+		tc.registerRes = tc.registerRes[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	lost := make(chan struct{})
+	tc.lost = append(tc.lost, lost)
+	return lost, nil
+}
+
+func (tc *testClient) Deregister(s Service) error {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	tc.deregisters++
+	return nil
+}
+
+// loseLease closes the channel of the last registration.
+func (tc *testClient) loseLease() {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	close(tc.lost[len(tc.lost)-1])
+}
+
+func (tc *testClient) registrations() int {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	return len(tc.lost)
+}
+
+// stateRecorder records the states of a registration.
+type stateRecorder struct {
+	mtx    sync.Mutex
+	states []State
+}
+
+func (r *stateRecorder) record(s State) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.states = append(r.states, s)
+}
+
+func (r *stateRecorder) recorded() []State {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]State(nil), r.states...)
+}
+
+// default service used to build registrar in our tests
+var testService = Service{Key: "testKey", Value: "testValue"}
+
+func TestRegister(t *testing.T) {
+	for _, tc := range []struct {
+		registerRes error
+		log         string
+	}{
+		{errors.New("regError"), "key=testKey value=testValue err=regError\n"},
+		{nil, "key=testKey value=testValue action=register\n"},
+	} {
+		c := &testClient{registerRes: []error{tc.registerRes}}
+		buf := &bytes.Buffer{}
+		r := NewRegistrar(c, testService, log.NewLogfmtLogger(buf))
+		r.Register()
+		if want, have := tc.log, buf.String(); want != have {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+	}
+}
+
+func TestRegisterLeaseLost(t *testing.T) {
+	var (
+		c        = &testClient{}
+		states   = &stateRecorder{}
+		service  = Service{Key: "testKey", Value: "testValue", TTL: NewTTLOption(10 * time.Second)}
+		r        = NewRegistrar(c, service, log.NewNopLogger(), RegistrarStateChange(states.record))
+		deadline = time.Now().Add(time.Second)
+	)
+	r.Register()
+	r.Register() // already running
+	if want, have := 1, c.registrations(); want != have {
+		t.Fatalf("want %d registrations, have %d", want, have)
+	}
+
+	c.loseLease()
+	for c.registrations() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("service wasn't registered again")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	r.Deregister()
+	if want, have := []State{Registered, Lost, Registered, Deregistered}, states.recorded(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 1, c.deregisters; want != have {
+		t.Errorf("want %d deregistrations, have %d", want, have)
+	}
+}
+
+func TestRegisterRetry(t *testing.T) {
+	var (
+		c        = &testClient{registerRes: []error{errors.New("unavailable"), errors.New("unavailable")}}
+		service  = Service{Key: "testKey", Value: "testValue", TTL: NewTTLOption(10 * time.Second)}
+		r        = NewRegistrar(c, service, log.NewNopLogger(), RegistrarBackoff(time.Millisecond, 2*time.Millisecond))
+		deadline = time.Now().Add(time.Second)
+	)
+	r.Register()
+	defer r.Deregister()
+	for c.registrations() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("service wasn't registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if want, have := 3, c.registers; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
+
+func TestDeregister(t *testing.T) {
+	c := &testClient{}
+	buf := &bytes.Buffer{}
+	r := NewRegistrar(c, testService, log.NewLogfmtLogger(buf))
+	r.Deregister()
+	if want, have := "key=testKey value=testValue action=deregister\n", buf.String(); want != have {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+}
@@ -36,82 +36,142 @@ func (u *fargoUnsuccessfulHTTPResponse) Error() string {
 
 // Registrar maintains service instance liveness information in Eureka.
 type Registrar struct {
-	conn     fargoConnection
-	instance *fargo.Instance
-	logger   log.Logger
-	quitc    chan chan struct{}
-	sync.Mutex
+	conn            fargoConnection
+	instance        *fargo.Instance
+	logger          log.Logger
+	renewalInterval time.Duration
+	heartbeat       []sd.HeartbeatOption
+	registrar       *sd.HeartbeatRegistrar
+
+	mtx        sync.Mutex
+	registered bool
 }
 
 var _ sd.Registrar = (*Registrar)(nil)
 
-// NewRegistrar returns an Eureka Registrar acting on behalf of the provided
-// Fargo connection and instance. See the integration test for usage examples.
-func NewRegistrar(conn fargoConnection, instance *fargo.Instance, logger log.Logger) *Registrar {
-	return &Registrar{
-		conn:     conn,
-		instance: instance,
-		logger:   log.With(logger, "service", instance.App, "address", fmt.Sprintf("%s:%d", instance.IPAddr, instance.Port)),
+// RegistrarOption sets an optional parameter for registrars. Options set the
+// fields of the instance, which override those it's constructed with.
+type RegistrarOption func(*Registrar)
+
+// RegistrarMetadata sets the metadata of the instance, which is returned
+// with it to clients, e.g. its version or zone. Keys must be valid XML
+// element names.
+func RegistrarMetadata(md map[string]string) RegistrarOption {
+	return func(r *Registrar) {
+		for k, v := range md {
+			r.instance.SetMetadataString(k, v)
+		}
 	}
 }
 
-// Register implements sd.Registrar.
-func (r *Registrar) Register() {
-	r.Lock()
-	defer r.Unlock()
-
-	if r.quitc != nil {
-		return // Already in the registration loop.
+// RegistrarSecurePort enables the secure port of the instance, on which it
+// serves over TLS, in addition to its port.
+func RegistrarSecurePort(port int) RegistrarOption {
+	return func(r *Registrar) {
+		r.instance.SecurePort, r.instance.SecurePortEnabled = port, true
 	}
+}
 
-	if err := r.conn.RegisterInstance(r.instance); err != nil {
-		r.logger.Log("during", "Register", "err", err)
+// RegistrarVIPAddress sets the virtual addresses of the instance, by which
+// clients look up its application, on its port and secure port. An empty
+// address is left as the instance's.
+func RegistrarVIPAddress(vip, secureVIP string) RegistrarOption {
+	return func(r *Registrar) {
+		if vip != "" {
+			r.instance.VipAddress = vip
+		}
+		if secureVIP != "" {
+			r.instance.SecureVipAddress = secureVIP
+		}
 	}
+}
 
-	r.quitc = make(chan chan struct{})
-	go r.loop()
+// RegistrarHealthCheckURL sets the URL of the health check of the instance.
+func RegistrarHealthCheckURL(url string) RegistrarOption {
+	return func(r *Registrar) { r.instance.HealthCheckUrl = url }
 }
 
-// Deregister implements sd.Registrar.
-func (r *Registrar) Deregister() {
-	r.Lock()
-	defer r.Unlock()
+// RegistrarStatusPageURL sets the URL of the status page of the instance.
+func RegistrarStatusPageURL(url string) RegistrarOption {
+	return func(r *Registrar) { r.instance.StatusPageUrl = url }
+}
 
-	if r.quitc == nil {
-		return // Already deregistered.
+// RegistrarRenewalInterval sets the interval of the heartbeats of the
+// instance, which renew its lease. It's registered in whole seconds, of at
+// least one. By default, the renewal interval of the instance's lease info
+// is used, or 30 seconds, if it has none.
+func RegistrarRenewalInterval(d time.Duration) RegistrarOption {
+	return func(r *Registrar) {
+		r.renewalInterval = d
+		r.instance.LeaseInfo.RenewalIntervalInSecs = seconds(d)
 	}
+}
 
-	q := make(chan struct{})
-	r.quitc <- q
-	<-q
-	r.quitc = nil
+// RegistrarStateChange sets a function which is called with the state of
+// the registration each time it changes, e.g. to fail readiness checks
+// while the instance is evicted. By default, changes are only logged.
+func RegistrarStateChange(f func(sd.RegistrationState)) RegistrarOption {
+	return func(r *Registrar) { r.heartbeat = append(r.heartbeat, sd.HeartbeatStateChange(f)) }
 }
 
-func (r *Registrar) loop() {
-	var renewalInterval time.Duration
-	if r.instance.LeaseInfo.RenewalIntervalInSecs > 0 {
+// RegistrarEvictionDuration sets the duration of the lease of the instance,
+// after which Eureka evicts it, if it isn't renewed by a heartbeat. It
+// should be several renewal intervals. By default, the duration of the
+// instance's lease info is used, or Eureka's default of 90 seconds, if it
+// has none.
+func RegistrarEvictionDuration(d time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.instance.LeaseInfo.DurationInSecs = seconds(d) }
+}
+
+// seconds returns the duration in whole seconds, rounded up, of at least one.
+func seconds(d time.Duration) int32 {
+	s := int32((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
+// NewRegistrar returns an Eureka Registrar acting on behalf of the provided
+// Fargo connection and instance. See the integration test for usage examples.
+func NewRegistrar(conn fargoConnection, instance *fargo.Instance, logger log.Logger, options ...RegistrarOption) *Registrar {
+	r := &Registrar{
+		conn:     conn,
+		instance: instance,
+		logger:   log.With(logger, "service", instance.App, "address", fmt.Sprintf("%s:%d", instance.IPAddr, instance.Port)),
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	renewalInterval := defaultRenewalInterval
+	if r.renewalInterval > 0 {
+		renewalInterval = r.renewalInterval
+	} else if r.instance.LeaseInfo.RenewalIntervalInSecs > 0 {
 		renewalInterval = time.Duration(r.instance.LeaseInfo.RenewalIntervalInSecs) * time.Second
-	} else {
-		renewalInterval = defaultRenewalInterval
 	}
-	ticker := time.NewTicker(renewalInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			if err := r.heartbeat(); err != nil {
-				r.logger.Log("during", "heartbeat", "err", err)
-			}
-
-		case q := <-r.quitc:
-			if err := r.conn.DeregisterInstance(r.instance); err != nil {
-				r.logger.Log("during", "Deregister", "err", err)
-			}
-			close(q)
-			return
-		}
+	r.registrar = sd.NewHeartbeatRegistrar(&heartbeater{conn: r.conn, instance: r.instance}, renewalInterval, r.logger, r.heartbeat...)
+	return r
+}
+
+// Register implements sd.Registrar. The instance is renewed by heartbeats,
+// and registered again if it's evicted, until it's deregistered.
+func (r *Registrar) Register() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.registered = true
+	r.registrar.Register()
+}
+
+// Deregister implements sd.Registrar.
+func (r *Registrar) Deregister() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if !r.registered {
+		return // Already deregistered.
 	}
+	r.registered = false
+	r.registrar.Deregister()
 }
 
 func httpResponseStatusCode(err error) (code int, present bool) {
@@ -130,14 +190,31 @@ func isNotFound(err error) bool {
 	return ok && code == http.StatusNotFound
 }
 
-func (r *Registrar) heartbeat() error {
-	err := r.conn.HeartBeatInstance(r.instance)
-	if err == nil {
-		return nil
+// heartbeater implements sd.Heartbeater for an instance.
+type heartbeater struct {
+	conn     fargoConnection
+	instance *fargo.Instance
+	expired  bool
+}
+
+func (h *heartbeater) Register() error {
+	if h.expired {
+		return h.conn.ReregisterInstance(h.instance)
 	}
+	return h.conn.RegisterInstance(h.instance)
+}
+
+func (h *heartbeater) Heartbeat() error {
+	err := h.conn.HeartBeatInstance(h.instance)
 	if isNotFound(err) {
 		// Instance expired (e.g. network partition). Re-register.
-		return r.conn.ReregisterInstance(r.instance)
+		h.expired = true
+		return sd.ErrNotRegistered
 	}
 	return err
 }
+
+func (h *heartbeater) Deregister() error {
+	h.expired = false
+	return h.conn.DeregisterInstance(h.instance)
+}
@@ -3,6 +3,10 @@ package eureka
 import (
 	"testing"
 	"time"
+
+	"github.com/hudl/fargo"
+
+	"github.com/go-kit/kit/sd"
 )
 
 func TestRegistrar(t *testing.T) {
@@ -90,13 +94,112 @@ func TestExpiredInstance(t *testing.T) {
 		errHeartbeat: errNotFound,
 	}
 
-	registrar := NewRegistrar(connection, instanceTest1, loggerTest)
+	states := make(chan sd.RegistrationState, 3)
+	registrar := NewRegistrar(connection, instanceTest1, loggerTest, RegistrarStateChange(func(s sd.RegistrationState) {
+		select {
+		case states <- s:
+		default:
+		}
+	}))
 	registrar.Register()
+	defer registrar.Deregister()
+
+	// Wait for a heartbeat failure, after which the instance is registered
+	// again.
+	for _, want := range []sd.RegistrationState{sd.Registered, sd.Lost, sd.Registered} {
+		select {
+		case have := <-states:
+			if want != have {
+				t.Fatalf("want %s, have %s", want, have)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	connection.mu.RLock()
+	defer connection.mu.RUnlock()
+	if want, have := 1, len(connection.instances); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
 
-	// Wait for a heartbeat failure.
-	time.Sleep(1010 * time.Millisecond)
+func TestDeregisterUnregistered(t *testing.T) {
+	connection := &testConnection{
+		instances: []*fargo.Instance{instanceTest1},
+	}
 
+	// Deregistering an instance which this registrar didn't register does
+	// nothing.
+	registrar := NewRegistrar(connection, instanceTest1, loggerTest)
+	registrar.Deregister()
 	if want, have := 1, len(connection.instances); want != have {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
+
+func TestRegistrarOptions(t *testing.T) {
+	connection := &testConnection{}
+	instance := &fargo.Instance{
+		HostName: "serveregistrar3.acme.org",
+		Port:     8080,
+		App:      appNameTest,
+		IPAddr:   "192.168.0.3",
+	}
+
+	registrar := NewRegistrar(connection, instance, loggerTest,
+		RegistrarMetadata(map[string]string{"version": "2", "zone": "us-east-1a"}),
+		RegistrarSecurePort(8443),
+		RegistrarVIPAddress("go-kit", "go-kit-secure"),
+		RegistrarHealthCheckURL("http://serveregistrar3.acme.org:8080/healthz"),
+		RegistrarStatusPageURL("http://serveregistrar3.acme.org:8080/status"),
+		RegistrarRenewalInterval(10*time.Millisecond),
+		RegistrarEvictionDuration(30*time.Second),
+	)
+	registrar.Register()
+	defer registrar.Deregister()
+
+	instances := connection.instancesForApplication(appNameTest)
+	if want, have := 1, len(instances); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	have := instances[0]
+	for k, want := range map[string]string{"version": "2", "zone": "us-east-1a"} {
+		if v, err := have.Metadata.GetString(k); err != nil || v != want {
+			t.Errorf("metadata %s: want %q, have %q (%v)", k, want, v, err)
+		}
+	}
+	if want, have := 8443, have.SecurePort; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if !have.SecurePortEnabled {
+		t.Error("want secure port enabled")
+	}
+	if want, have := "go-kit", have.VipAddress; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "go-kit-secure", have.SecureVipAddress; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "http://serveregistrar3.acme.org:8080/healthz", have.HealthCheckUrl; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "http://serveregistrar3.acme.org:8080/status", have.StatusPageUrl; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := int32(1), have.LeaseInfo.RenewalIntervalInSecs; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := int32(30), have.LeaseInfo.DurationInSecs; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	// Heartbeats are at the renewal interval.
+	time.Sleep(55 * time.Millisecond)
+	connection.mu.RLock()
+	heartbeats := connection.heartbeats
+	connection.mu.RUnlock()
+	if heartbeats < 3 {
+		t.Errorf("want at least 3 heartbeats, have %d", heartbeats)
+	}
+}
@@ -12,8 +12,9 @@ import (
 )
 
 type testConnection struct {
-	mu        sync.RWMutex
-	instances []*fargo.Instance
+	mu         sync.RWMutex
+	instances  []*fargo.Instance
+	heartbeats int
 
 	errApplication error
 	errHeartbeat   error
@@ -73,6 +74,9 @@ func (c *testConnection) RegisterInstance(i *fargo.Instance) error {
 }
 
 func (c *testConnection) HeartBeatInstance(i *fargo.Instance) error {
+	c.mu.Lock()
+	c.heartbeats++
+	c.mu.Unlock()
 	return c.errHeartbeat
 }
 
@@ -15,3 +15,9 @@ import (
 // Users are expected to provide their own factory functions that assume
 // specific transports, or can deduce transports by parsing the instance string.
 type Factory func(instance string) (endpoint.Endpoint, io.Closer, error)
+
+// RecordFactory is a Factory which receives the structured record of the
+// instance, e.g. to configure the endpoint by the version in its metadata.
+// The record of an instance whose discovery system knows only its address
+// has only its Address.
+type RecordFactory func(instance Instance) (endpoint.Endpoint, io.Closer, error)
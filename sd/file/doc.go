@@ -0,0 +1,4 @@
+// Package file provides an Instancer implementation for instances listed in
+// a local file, as provisioned by configuration management rather than a
+// discovery system. The file is read again whenever it changes.
+package file
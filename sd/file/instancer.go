@@ -0,0 +1,184 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+)
+
+// Format is the format of a file of instances.
+type Format int
+
+// Formats of files of instances.
+const (
+	// FormatAuto chooses the format by the extension of the file: .json
+	// for FormatJSON, .yaml or .yml for FormatYAML, and FormatLines
+	// otherwise.
+	FormatAuto Format = iota
+
+	// FormatJSON is a JSON array of instances, e.g.
+	// ["10.0.0.1:8080", "10.0.0.2:8080"].
+	FormatJSON
+
+	// FormatYAML is a YAML sequence of instances.
+	FormatYAML
+
+	// FormatLines is hosts-style: an instance per line. Blank lines and
+	// comments, from # to the end of the line, are ignored.
+	FormatLines
+)
+
+// Instancer yields the instances listed in a file. The file is watched, and
+// read again whenever it's written, or replaced, e.g. by renaming another
+// file over it, as configuration management tools commonly do.
+type Instancer struct {
+	cache   *instance.Cache
+	path    string
+	format  Format
+	logger  log.Logger
+	watcher *fsnotify.Watcher
+	quit    chan struct{}
+}
+
+// InstancerOption sets an optional parameter for instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerFormat sets the format of the file. By default, it's FormatAuto.
+func InstancerFormat(f Format) InstancerOption {
+	return func(s *Instancer) { s.format = f }
+}
+
+// NewInstancer returns an Instancer which yields the instances listed in the
+// file at path. An error is returned only if the file can't be watched; if
+// it can't be read or parsed, the error is sent to subscribers, until it
+// can.
+func NewInstancer(path string, logger log.Logger, options ...InstancerOption) (*Instancer, error) {
+	s := &Instancer{
+		cache:  instance.NewCache(),
+		path:   filepath.Clean(path),
+		logger: logger,
+		quit:   make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if s.format == FormatAuto {
+		switch strings.ToLower(filepath.Ext(s.path)) {
+		case ".json":
+			s.format = FormatJSON
+		case ".yaml", ".yml":
+			s.format = FormatYAML
+		default:
+			s.format = FormatLines
+		}
+	}
+
+	// The directory is watched, rather than the file, so that the file is
+	// still watched after it's replaced.
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	s.watcher = watcher
+
+	s.read()
+	go s.loop()
+	return s, nil
+}
+
+func (s *Instancer) loop() {
+	for {
+		select {
+		case event := <-s.watcher.Events:
+			if filepath.Clean(event.Name) != s.path {
+				continue
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			s.read()
+
+		case err := <-s.watcher.Errors:
+			s.logger.Log("path", s.path, "err", err)
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// read reads the file, and updates the subscribers.
+func (s *Instancer) read() {
+	instances, err := s.parse()
+	if err != nil {
+		s.logger.Log("path", s.path, "err", err)
+		s.cache.Update(sd.Event{Err: err})
+		return
+	}
+	s.logger.Log("path", s.path, "instances", len(instances))
+	s.cache.Update(sd.Event{Instances: instances})
+}
+
+func (s *Instancer) parse() ([]string, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []string{}
+	switch s.format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &instances)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &instances)
+	case FormatLines:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if i := strings.IndexByte(line, '#'); i >= 0 {
+				line = line[:i]
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				instances = append(instances, line)
+			}
+		}
+		err = scanner.Err()
+	default:
+		err = fmt.Errorf("unknown format %d", s.format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	close(s.quit)
+	s.watcher.Close()
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
@@ -0,0 +1,92 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestParse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "instancer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := []string{"10.0.0.1:8080", "10.0.0.2:8080"}
+	for name, data := range map[string]string{
+		"instances.json":  `["10.0.0.1:8080", "10.0.0.2:8080"]`,
+		"instances.yaml":  "- 10.0.0.1:8080\n- 10.0.0.2:8080\n",
+		"instances.hosts": "# web\n10.0.0.1:8080\n\n10.0.0.2:8080 # canary\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		instancer, err := NewInstancer(path, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := instancer.cache.State(); have.Err != nil || !reflect.DeepEqual(want, have.Instances) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+		instancer.Stop()
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "instancer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "instances")
+	instancer, err := NewInstancer(path, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer instancer.Stop()
+
+	// The file doesn't exist yet.
+	if have := instancer.cache.State(); have.Err == nil {
+		t.Errorf("want error, have %v", have)
+	}
+
+	expect := func(want []string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			have := instancer.cache.State()
+			if have.Err == nil && reflect.DeepEqual(want, have.Instances) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("want %v, have %v", want, have)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if err := ioutil.WriteFile(path, []byte("a:80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expect([]string{"a:80"})
+
+	// Replace the file, as configuration management tools do.
+	tmp := filepath.Join(dir, "instances.tmp")
+	if err := ioutil.WriteFile(tmp, []byte("a:80\nb:80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	expect([]string{"a:80", "b:80"})
+}
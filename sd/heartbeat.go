@@ -0,0 +1,189 @@
+package sd
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ErrNotRegistered is returned by the heartbeats of a Heartbeater whose
+// registration was lost, e.g. as the service discovery system restarted, or
+// it expired while the system was unreachable, so that it's registered
+// again.
+var ErrNotRegistered = errors.New("not registered")
+
+// Heartbeater is a registration in a service discovery system which must be
+// renewed by heartbeats, e.g. that of a service with a TTL, or it expires.
+// Its methods are never called concurrently.
+type Heartbeater interface {
+	Register() error
+	Heartbeat() error
+	Deregister() error
+}
+
+// RegistrationState is the state of the registration of a
+// HeartbeatRegistrar.
+type RegistrationState int
+
+const (
+	// Registered is the state once the service is registered, and each
+	// time it's registered again after its registration is lost.
+	Registered RegistrationState = iota
+
+	// Lost is the state once a heartbeat finds the registration lost, until
+	// it's registered again.
+	Lost
+
+	// Deregistered is the state once the service is deregistered.
+	Deregistered
+)
+
+func (s RegistrationState) String() string {
+	switch s {
+	case Registered:
+		return "registered"
+	case Lost:
+		return "lost"
+	case Deregistered:
+		return "deregistered"
+	default:
+		return "unknown"
+	}
+}
+
+// HeartbeatRegistrar is a Registrar which registers a Heartbeater, renews it
+// by heartbeats on an interval, and registers it again if it's lost, until
+// it's deregistered. Backends of service discovery systems whose
+// registrations expire use it to implement their registrars.
+type HeartbeatRegistrar struct {
+	h          Heartbeater
+	interval   time.Duration
+	logger     log.Logger
+	state      func(RegistrationState)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mtx  sync.Mutex
+	quit chan struct{}
+	done chan struct{}
+}
+
+var _ Registrar = (*HeartbeatRegistrar)(nil)
+
+// HeartbeatOption sets an optional parameter for heartbeat registrars.
+type HeartbeatOption func(*HeartbeatRegistrar)
+
+// HeartbeatStateChange sets a function which is called with the state of
+// the registration each time it changes, e.g. to fail readiness checks
+// while the registration is lost. By default, changes are only logged.
+func HeartbeatStateChange(f func(RegistrationState)) HeartbeatOption {
+	return func(r *HeartbeatRegistrar) { r.state = f }
+}
+
+// HeartbeatBackoff sets the backoff of registrations after they fail, e.g.
+// as the service discovery system is unreachable, which doubles from min to
+// max. By default, it's from 100ms to 30 seconds.
+func HeartbeatBackoff(min, max time.Duration) HeartbeatOption {
+	return func(r *HeartbeatRegistrar) { r.minBackoff, r.maxBackoff = min, max }
+}
+
+// NewHeartbeatRegistrar returns a Registrar which registers h, and renews it
+// by heartbeats every interval.
+func NewHeartbeatRegistrar(h Heartbeater, interval time.Duration, logger log.Logger, options ...HeartbeatOption) *HeartbeatRegistrar {
+	r := &HeartbeatRegistrar{
+		h:          h,
+		interval:   interval,
+		logger:     logger,
+		state:      func(RegistrationState) {},
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Register implements Registrar. The service is registered, and, if it
+// fails, registered again until it succeeds. Calling it again while it's
+// registered does nothing.
+func (r *HeartbeatRegistrar) Register() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.quit != nil {
+		return // already running
+	}
+	r.quit, r.done = make(chan struct{}), make(chan struct{})
+	registered := r.register()
+	go r.loop(registered, r.quit, r.done)
+}
+
+func (r *HeartbeatRegistrar) register() bool {
+	if err := r.h.Register(); err != nil {
+		r.logger.Log("during", "register", "err", err)
+		return false
+	}
+	r.logger.Log("action", "register")
+	r.state(Registered)
+	return true
+}
+
+func (r *HeartbeatRegistrar) loop(registered bool, quit, done chan struct{}) {
+	defer close(done)
+	backoff := r.minBackoff
+	for {
+		wait := r.interval
+		if !registered {
+			wait = backoff
+			if backoff *= 2; backoff > r.maxBackoff {
+				backoff = r.maxBackoff
+			}
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-quit:
+			t.Stop()
+			return
+		}
+
+		if registered {
+			switch err := r.h.Heartbeat(); err {
+			case nil:
+				continue
+			case ErrNotRegistered:
+				r.logger.Log("action", "lost")
+				r.state(Lost)
+			default:
+				r.logger.Log("during", "heartbeat", "err", err)
+				continue
+			}
+		}
+		if registered = r.register(); registered {
+			backoff = r.minBackoff
+		}
+	}
+}
+
+// Deregister implements Registrar. Heartbeats are stopped, and the service
+// is deregistered.
+func (r *HeartbeatRegistrar) Deregister() {
+	// The loop is stopped first, so that it doesn't register the service
+	// again after it's deregistered.
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.quit != nil {
+		close(r.quit)
+		<-r.done
+		r.quit, r.done = nil, nil
+	}
+
+	if err := r.h.Deregister(); err != nil {
+		r.logger.Log("during", "deregister", "err", err)
+		return
+	}
+	r.logger.Log("action", "deregister")
+	r.state(Deregistered)
+}
@@ -0,0 +1,104 @@
+package sd
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+type fakeHeartbeater struct {
+	mtx        sync.Mutex
+	registered bool
+	registers  int
+	heartbeats int
+	errs       []error // of the next registrations
+}
+
+func (h *fakeHeartbeater) Register() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.registers++
+	if len(h.errs) > 0 {
+		err := h.errs[0]
+		h.errs = h.errs[1:]
+		return err
+	}
+	h.registered = true
+	return nil
+}
+
+func (h *fakeHeartbeater) Heartbeat() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.heartbeats++
+	if !h.registered {
+		return ErrNotRegistered
+	}
+	return nil
+}
+
+func (h *fakeHeartbeater) Deregister() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.registered = false
+	return nil
+}
+
+// expire loses the registration, as a restart of the backend does.
+func (h *fakeHeartbeater) expire(errs ...error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.registered = false
+	h.errs = errs
+}
+
+func TestHeartbeatRegistrar(t *testing.T) {
+	var (
+		h      = &fakeHeartbeater{}
+		mtx    sync.Mutex
+		states []RegistrationState
+		r      = NewHeartbeatRegistrar(h, time.Millisecond, log.NewNopLogger(),
+			HeartbeatBackoff(time.Millisecond, time.Millisecond),
+			HeartbeatStateChange(func(s RegistrationState) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				states = append(states, s)
+			}),
+		)
+	)
+	r.Register()
+	r.Register() // already running
+
+	// The registration is lost, and the first registration after fails.
+	h.expire(errors.New("unreachable"))
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.mtx.Lock()
+		registers, registered := h.registers, h.registered
+		h.mtx.Unlock()
+		if registers == 3 && registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want registered after 3 registrations, have %d (%v)", registers, registered)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	r.Deregister()
+	h.mtx.Lock()
+	if h.registered {
+		t.Error("want deregistered")
+	}
+	h.mtx.Unlock()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want := []RegistrationState{Registered, Lost, Registered, Deregistered}; !reflect.DeepEqual(want, states) {
+		t.Errorf("want %v, have %v", want, states)
+	}
+}
@@ -12,6 +12,38 @@ package sd
 type Event struct {
 	Instances []string
 	Err       error
+
+	// Records holds the structured records of the instances, by instance,
+	// for Instancers of discovery systems which know more of them than
+	// their addresses, e.g. their tags and weights. It's optional, and may
+	// lack the records of some instances.
+	Records map[string]Instance
+}
+
+// Record returns the record of the instance, or a record of only its address
+// if the event has none.
+func (e Event) Record(instance string) Instance {
+	if r, ok := e.Records[instance]; ok {
+		return r
+	}
+	return Instance{Address: instance}
+}
+
+// Instance is the structured record of a resource instance, so that
+// factories, balancers and clients use its metadata, e.g. its version or
+// zone, without parsing it from the instance string.
+type Instance struct {
+	// Address is the instance string, e.g. host:port.
+	Address string
+
+	// Tags and Metadata are the labels of the instance in the discovery
+	// system, e.g. the tags and service metadata of Consul.
+	Tags     []string
+	Metadata map[string]string
+
+	// Weight is the relative weight of the instance, or 0 if the discovery
+	// system has none.
+	Weight int
 }
 
 // Instancer listens to a service discovery system and notifies registered
@@ -0,0 +1,7 @@
+// Package kubernetes provides Instancer and Registrar implementations for
+// Kubernetes. The Instancer watches the EndpointSlices, or the Endpoints,
+// of a Service, with the informers of package k8s.io/client-go. The
+// Registrar registers workloads running outside of the cluster in an
+// EndpointSlice of a Service without a selector, so that they're
+// discovered like pods.
+package kubernetes
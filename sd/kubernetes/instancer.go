@@ -0,0 +1,232 @@
+package kubernetes
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+)
+
+// Instancer yields instances for a Service in Kubernetes, from its
+// EndpointSlices, or its Endpoints, as they change.
+type Instancer struct {
+	cache     *instance.Cache
+	logger    log.Logger
+	namespace string
+	service   string
+	port      string
+	resync    time.Duration
+	legacy    bool
+	notReady  bool
+	list      func() ([]string, error)
+	quitc     chan struct{}
+}
+
+// InstancerOption sets an optional parameter for instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerPort selects the port of instances by its name, for Services
+// with more than one. By default, the first port of each EndpointSlice, or
+// subset of the Endpoints, is used.
+func InstancerPort(name string) InstancerOption {
+	return func(s *Instancer) { s.port = name }
+}
+
+// InstancerResync sets the interval at which the informer lists the
+// EndpointSlices again, in addition to watching them. By default, it's 0,
+// and they're only watched.
+func InstancerResync(d time.Duration) InstancerOption {
+	return func(s *Instancer) { s.resync = d }
+}
+
+// InstancerEndpoints watches the Endpoints of the Service, for clusters
+// older than Kubernetes 1.21, where the EndpointSlice API of
+// discovery.k8s.io/v1 isn't available. By default, EndpointSlices are
+// watched, which scale to large Services.
+func InstancerEndpoints() InstancerOption {
+	return func(s *Instancer) { s.legacy = true }
+}
+
+// InstancerNotReady includes endpoints which aren't ready, e.g. of pods
+// failing their readiness probes, or terminating. By default, only ready
+// endpoints are instances.
+func InstancerNotReady() InstancerOption {
+	return func(s *Instancer) { s.notReady = true }
+}
+
+// NewInstancer returns a Kubernetes instancer that publishes instances for
+// the Service in the namespace. Instances are published once the informer
+// has listed the EndpointSlices, and as they change thereafter; errors of
+// the watch are published as errors, until it recovers.
+func NewInstancer(client kubernetes.Interface, logger log.Logger, namespace, service string, options ...InstancerOption) *Instancer {
+	s := &Instancer{
+		cache:     instance.NewCache(),
+		logger:    log.With(logger, "namespace", namespace, "service", service),
+		namespace: namespace,
+		service:   service,
+		quitc:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	var informer cache.SharedIndexInformer
+	if s.legacy {
+		factory := informers.NewSharedInformerFactoryWithOptions(client, s.resync,
+			informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.FieldSelector = fields.OneTermEqualSelector("metadata.name", service).String()
+			}),
+		)
+		endpoints := factory.Core().V1().Endpoints()
+		informer = endpoints.Informer()
+		s.list = func() ([]string, error) {
+			e, err := endpoints.Lister().Endpoints(namespace).Get(service)
+			if err != nil {
+				return nil, err
+			}
+			return s.endpointsInstances(e), nil
+		}
+	} else {
+		factory := informers.NewSharedInformerFactoryWithOptions(client, s.resync,
+			informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.LabelSelector = labels.Set{discoveryv1.LabelServiceName: service}.String()
+			}),
+		)
+		slices := factory.Discovery().V1().EndpointSlices()
+		informer = slices.Informer()
+		s.list = func() ([]string, error) {
+			list, err := slices.Lister().EndpointSlices(namespace).List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			return s.sliceInstances(list), nil
+		}
+	}
+
+	if err := informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		s.logger.Log("err", err)
+		s.cache.Update(sd.Event{Err: err})
+	}); err != nil {
+		s.logger.Log("err", err)
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { s.update() },
+		UpdateFunc: func(interface{}, interface{}) { s.update() },
+		DeleteFunc: func(interface{}) { s.update() },
+	})
+	go informer.Run(s.quitc)
+	go func() {
+		if cache.WaitForCacheSync(s.quitc, informer.HasSynced) {
+			s.update()
+		}
+	}()
+	return s
+}
+
+// update publishes the instances of the informer's store.
+func (s *Instancer) update() {
+	instances, err := s.list()
+	if err != nil && s.legacy && apierrors.IsNotFound(err) {
+		instances, err = nil, nil // the Service has no Endpoints yet
+	}
+	if err != nil {
+		s.logger.Log("err", err)
+		s.cache.Update(sd.Event{Err: err})
+		return
+	}
+	s.cache.Update(sd.Event{Instances: instances})
+}
+
+// Stop terminates the instancer.
+func (s *Instancer) Stop() {
+	close(s.quitc)
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+func (s *Instancer) sliceInstances(slices []*discoveryv1.EndpointSlice) []string {
+	seen := map[string]bool{}
+	var instances []string
+	for _, slice := range slices {
+		port, ok := s.slicePort(slice.Ports)
+		if !ok {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+			if !ready && !s.notReady {
+				continue
+			}
+			for _, addr := range endpoint.Addresses {
+				// Endpoints may be in more than one slice while they move.
+				if instance := net.JoinHostPort(addr, port); !seen[instance] {
+					seen[instance] = true
+					instances = append(instances, instance)
+				}
+			}
+		}
+	}
+	return instances
+}
+
+func (s *Instancer) slicePort(ports []discoveryv1.EndpointPort) (string, bool) {
+	for _, p := range ports {
+		if p.Port == nil {
+			continue
+		}
+		if s.port == "" || (p.Name != nil && *p.Name == s.port) {
+			return strconv.Itoa(int(*p.Port)), true
+		}
+	}
+	return "", false
+}
+
+func (s *Instancer) endpointsInstances(e *corev1.Endpoints) []string {
+	var instances []string
+	for _, subset := range e.Subsets {
+		port, ok := s.endpointsPort(subset.Ports)
+		if !ok {
+			continue
+		}
+		addresses := subset.Addresses
+		if s.notReady {
+			addresses = append(addresses[:len(addresses):len(addresses)], subset.NotReadyAddresses...)
+		}
+		for _, addr := range addresses {
+			instances = append(instances, net.JoinHostPort(addr.IP, port))
+		}
+	}
+	return instances
+}
+
+func (s *Instancer) endpointsPort(ports []corev1.EndpointPort) (string, bool) {
+	for _, p := range ports {
+		if s.port == "" || p.Name == s.port {
+			return strconv.Itoa(int(p.Port)), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+var _ sd.Instancer = &Instancer{} // API check
+
+func endpointSlice(name, service string, port int32, ready map[string]bool) *discoveryv1.EndpointSlice {
+	portName := "http"
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+	}
+	for addr, r := range ready {
+		r := r
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: discoveryv1.EndpointConditions{Ready: &r},
+		})
+	}
+	return slice
+}
+
+// awaitInstances waits for an event with the instances.
+func awaitInstances(t *testing.T, ch <-chan sd.Event, want []string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	var have sd.Event
+	for {
+		select {
+		case have = <-ch:
+			if have.Err == nil && (reflect.DeepEqual(want, have.Instances) || len(want) == 0 && len(have.Instances) == 0) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("want %v, have %v", want, have)
+		}
+	}
+}
+
+func TestInstancerEndpointSlices(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		endpointSlice("search-a", "search", 8080, map[string]bool{"10.0.0.1": true, "10.0.0.2": false}),
+		endpointSlice("search-b", "search", 8080, map[string]bool{"10.0.0.3": true}),
+		endpointSlice("other-a", "other", 9090, map[string]bool{"10.0.1.1": true}),
+	)
+	s := NewInstancer(client, log.NewNopLogger(), "default", "search", InstancerPort("http"))
+	defer s.Stop()
+
+	ch := make(chan sd.Event, 16)
+	s.Register(ch)
+	defer s.Deregister(ch)
+	awaitInstances(t, ch, []string{"10.0.0.1:8080", "10.0.0.3:8080"})
+
+	ctx := context.Background()
+	updated := endpointSlice("search-b", "search", 8080, map[string]bool{"10.0.0.3": true, "10.0.0.4": true})
+	if _, err := client.DiscoveryV1().EndpointSlices("default").Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	awaitInstances(t, ch, []string{"10.0.0.1:8080", "10.0.0.3:8080", "10.0.0.4:8080"})
+
+	if err := client.DiscoveryV1().EndpointSlices("default").Delete(ctx, "search-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	awaitInstances(t, ch, []string{"10.0.0.3:8080", "10.0.0.4:8080"})
+}
+
+func TestInstancerNotReady(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		endpointSlice("search-a", "search", 8080, map[string]bool{"10.0.0.1": true, "10.0.0.2": false}),
+	)
+	s := NewInstancer(client, log.NewNopLogger(), "default", "search", InstancerNotReady())
+	defer s.Stop()
+
+	ch := make(chan sd.Event, 16)
+	s.Register(ch)
+	defer s.Deregister(ch)
+	awaitInstances(t, ch, []string{"10.0.0.1:8080", "10.0.0.2:8080"})
+}
+
+func TestInstancerEndpoints(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "search", Namespace: "default"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+			NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+			Ports: []corev1.EndpointPort{
+				{Name: "metrics", Port: 9090},
+				{Name: "http", Port: 8080},
+			},
+		}},
+	})
+	s := NewInstancer(client, log.NewNopLogger(), "default", "search", InstancerEndpoints(), InstancerPort("http"))
+	defer s.Stop()
+
+	ch := make(chan sd.Event, 16)
+	s.Register(ch)
+	defer s.Deregister(ch)
+	awaitInstances(t, ch, []string{"10.0.0.1:8080"})
+}
@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ManagedBy is the value of the EndpointSlice label
+// endpointslice.kubernetes.io/managed-by of the EndpointSlices of
+// Registrars, so that the EndpointSlice controller leaves them alone.
+const ManagedBy = "go-kit.io"
+
+// Registration is an instance of a Service running outside of the cluster.
+// The Service must not have a selector, so that Kubernetes doesn't manage
+// its EndpointSlices.
+type Registration struct {
+	Name     string // unique among the instances of the Service
+	Address  string // IP address
+	Port     int32
+	PortName string // name of the port of the Service, if it has more than one
+}
+
+// Registrar registers service instance liveness information to
+// Kubernetes, as an EndpointSlice of the Service of its own, named after
+// the Service and the instance.
+type Registrar struct {
+	client       kubernetes.Interface
+	namespace    string
+	service      string
+	registration Registration
+	logger       log.Logger
+}
+
+// NewRegistrar returns a Kubernetes Registrar, registering the instance in
+// the Service in the namespace.
+func NewRegistrar(client kubernetes.Interface, namespace, service string, r Registration, logger log.Logger) *Registrar {
+	return &Registrar{
+		client:       client,
+		namespace:    namespace,
+		service:      service,
+		registration: r,
+		logger:       log.With(logger, "namespace", namespace, "service", service, "address", net.JoinHostPort(r.Address, fmt.Sprint(r.Port))),
+	}
+}
+
+// Register implements sd.Registrar interface. It creates the EndpointSlice
+// of the instance, or updates it, if it exists.
+func (p *Registrar) Register() {
+	if err := p.register(context.Background()); err != nil {
+		p.logger.Log("err", err)
+	} else {
+		p.logger.Log("action", "register")
+	}
+}
+
+// Deregister implements sd.Registrar interface. It deletes the
+// EndpointSlice of the instance.
+func (p *Registrar) Deregister() {
+	err := p.client.DiscoveryV1().EndpointSlices(p.namespace).Delete(context.Background(), p.name(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		p.logger.Log("err", err)
+	} else {
+		p.logger.Log("action", "deregister")
+	}
+}
+
+func (p *Registrar) register(ctx context.Context) error {
+	slice, err := p.endpointSlice()
+	if err != nil {
+		return err
+	}
+	slices := p.client.DiscoveryV1().EndpointSlices(p.namespace)
+	_, err = slices.Create(ctx, slice, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := slices.Get(ctx, slice.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	slice.ResourceVersion = existing.ResourceVersion
+	_, err = slices.Update(ctx, slice, metav1.UpdateOptions{})
+	return err
+}
+
+func (p *Registrar) name() string {
+	return p.service + "-" + p.registration.Name
+}
+
+func (p *Registrar) endpointSlice() (*discoveryv1.EndpointSlice, error) {
+	ip := net.ParseIP(p.registration.Address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", p.registration.Address)
+	}
+	addressType := discoveryv1.AddressTypeIPv4
+	if ip.To4() == nil {
+		addressType = discoveryv1.AddressTypeIPv6
+	}
+
+	ready := true
+	port := p.registration.Port
+	portName := p.registration.PortName
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.name(),
+			Namespace: p.namespace,
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: p.service,
+				discoveryv1.LabelManagedBy:   ManagedBy,
+			},
+		},
+		AddressType: addressType,
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{p.registration.Address},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		}},
+		Ports: []discoveryv1.EndpointPort{{
+			Name: &portName,
+			Port: &port,
+		}},
+	}, nil
+}
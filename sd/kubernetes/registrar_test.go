@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+var _ sd.Registrar = &Registrar{} // API check
+
+func TestRegistrar(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := NewInstancer(client, log.NewNopLogger(), "default", "search", InstancerPort("http"))
+	defer s.Stop()
+	ch := make(chan sd.Event, 16)
+	s.Register(ch)
+	defer s.Deregister(ch)
+
+	r := NewRegistrar(client, "default", "search", Registration{
+		Name:     "vm-1",
+		Address:  "192.168.0.10",
+		Port:     8080,
+		PortName: "http",
+	}, log.NewNopLogger())
+	r.Register()
+	awaitInstances(t, ch, []string{"192.168.0.10:8080"})
+
+	// Registering again updates the EndpointSlice.
+	r.Register()
+	slice, err := client.DiscoveryV1().EndpointSlices("default").Get(context.Background(), "search-vm-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := ManagedBy, slice.Labels["endpointslice.kubernetes.io/managed-by"]; want != have {
+		t.Errorf("managed-by: want %q, have %q", want, have)
+	}
+
+	r.Deregister()
+	awaitInstances(t, ch, nil)
+	r.Deregister() // deregistering again is harmless
+}
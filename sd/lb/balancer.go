@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
 )
 
 // Balancer yields endpoints according to some heuristic.
@@ -13,3 +14,12 @@ type Balancer interface {
 
 // ErrNoEndpoints is returned when no qualifying endpoints are available.
 var ErrNoEndpoints = errors.New("no endpoints available")
+
+// changed reports whether the instance endpoints differ from those last
+// yielded, which sd.InstanceEndpointer replaces rather than modifies.
+func changed(last, endpoints []sd.InstanceEndpoint) bool {
+	if len(last) != len(endpoints) {
+		return true
+	}
+	return len(endpoints) > 0 && &last[0] != &endpoints[0]
+}
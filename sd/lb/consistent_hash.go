@@ -0,0 +1,198 @@
+package lb
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// Defaults for ConsistentHash.
+const (
+	DefaultConsistentHashReplicas   = 100
+	DefaultConsistentHashLoadFactor = 1.25
+)
+
+// KeyFunc extracts the key of a request, e.g. the ID of the cached entity
+// it concerns, from its context or the request itself.
+type KeyFunc func(ctx context.Context, request interface{}) string
+
+// ConsistentHashOption sets an optional parameter for ConsistentHash.
+type ConsistentHashOption func(*ConsistentHash)
+
+// ConsistentHashReplicas sets the number of points of each instance on the
+// hash ring. More points spread the keys more evenly among the instances.
+// By default, DefaultConsistentHashReplicas is used.
+func ConsistentHashReplicas(n int) ConsistentHashOption {
+	return func(c *ConsistentHash) { c.replicas = n }
+}
+
+// ConsistentHashLoadFactor sets the bound of the in-flight requests of each
+// instance, as a factor of the average, c >= 1.0. A request whose instance is
+// at the bound goes to the next instance on the ring which isn't. Lower
+// factors balance the load more evenly, at the cost of moving more keys from
+// their instances. Zero disables the bound. By default,
+// DefaultConsistentHashLoadFactor is used.
+func ConsistentHashLoadFactor(c float64) ConsistentHashOption {
+	return func(ch *ConsistentHash) { ch.loadFactor = c }
+}
+
+// ConsistentHash is a load balancer which routes each request to an instance
+// by the consistent hash of its key, so that all clients route the same key
+// to the same instance, and a change of the instances moves only a few keys.
+// The load of each instance is bounded, so that hot keys don't overload it.
+type ConsistentHash struct {
+	s          sd.InstanceEndpointer
+	key        KeyFunc
+	replicas   int
+	loadFactor float64
+
+	mtx       sync.Mutex
+	endpoints []sd.InstanceEndpoint
+	peers     []*hashPeer
+	ring      []ringPoint
+	total     int
+}
+
+type hashPeer struct {
+	instance string
+	endpoint endpoint.Endpoint
+	load     int
+	removed  bool
+}
+
+type ringPoint struct {
+	hash uint64
+	peer *hashPeer
+}
+
+// NewConsistentHash returns a load balancer that routes requests to the
+// endpoints of s by the consistent hash of the keys extracted by key.
+//
+// Unlike other balancers, the endpoint yielded by Endpoint isn't that of an
+// instance; it selects the instance when it's invoked with the request,
+// among the endpoints of s as of the last call to Endpoint.
+func NewConsistentHash(s sd.InstanceEndpointer, key KeyFunc, options ...ConsistentHashOption) *ConsistentHash {
+	c := &ConsistentHash{
+		s:          s,
+		key:        key,
+		replicas:   DefaultConsistentHashReplicas,
+		loadFactor: DefaultConsistentHashLoadFactor,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.replicas < 1 {
+		c.replicas = 1
+	}
+	return c
+}
+
+// update replaces the peers and the ring with those of the endpoints. The
+// lock must be held.
+func (c *ConsistentHash) update(endpoints []sd.InstanceEndpoint) {
+	// The loads of surviving instances are retained; those of removed ones
+	// no longer count towards the total.
+	old := make(map[string]*hashPeer, len(c.peers))
+	for _, p := range c.peers {
+		old[p.instance] = p
+	}
+	peers := make([]*hashPeer, len(endpoints))
+	ring := make([]ringPoint, 0, len(endpoints)*c.replicas)
+	total := 0
+	for i, ie := range endpoints {
+		instance := ie.Instance.Address
+		p, ok := old[instance]
+		if ok {
+			delete(old, instance)
+			total += p.load
+		} else {
+			p = &hashPeer{instance: instance}
+		}
+		p.endpoint = ie.Endpoint
+		peers[i] = p
+		for j := 0; j < c.replicas; j++ {
+			ring = append(ring, ringPoint{hashKey(instance + "-" + strconv.Itoa(j)), p})
+		}
+	}
+	for _, p := range old {
+		p.removed = true
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	c.endpoints, c.peers, c.ring, c.total = endpoints, peers, ring, total
+}
+
+// Endpoint implements Balancer.
+func (c *ConsistentHash) Endpoint() (endpoint.Endpoint, error) {
+	endpoints, err := c.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if changed(c.endpoints, endpoints) {
+		c.update(endpoints)
+	}
+	if len(c.peers) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	return c.serve, nil
+}
+
+func (c *ConsistentHash) serve(ctx context.Context, request interface{}) (interface{}, error) {
+	p, e, err := c.acquire(c.key(ctx, request))
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(p)
+	return e(ctx, request)
+}
+
+// acquire returns the peer of the key and its endpoint, the first one from
+// the hash of the key on the ring whose load is within the bound, and counts
+// the request towards its load.
+func (c *ConsistentHash) acquire(key string) (*hashPeer, endpoint.Endpoint, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.ring) <= 0 {
+		return nil, nil, ErrNoEndpoints
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(c.ring), func(i int) bool { return c.ring[i].hash >= h })
+	p := c.ring[i%len(c.ring)].peer
+	if c.loadFactor > 0 {
+		bound := int(math.Ceil(c.loadFactor * float64(c.total+1) / float64(len(c.peers))))
+		for j := 0; j < len(c.ring); j++ {
+			if q := c.ring[(i+j)%len(c.ring)].peer; q.load < bound {
+				p = q
+				break
+			}
+		}
+	}
+	p.load++
+	c.total++
+	return p, p.endpoint, nil
+}
+
+func (c *ConsistentHash) release(p *hashPeer) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	p.load--
+	if !p.removed {
+		c.total--
+	}
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
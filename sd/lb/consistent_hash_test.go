@@ -0,0 +1,136 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+)
+
+type mockInstancer struct {
+	*instance.Cache
+}
+
+func requestKey(_ context.Context, request interface{}) string { return request.(string) }
+
+func route(t *testing.T, b Balancer, key string) string {
+	e, err := b.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := e(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.(string)
+}
+
+func TestConsistentHash(t *testing.T) {
+	var (
+		instancer  = mockInstancer{instance.NewCache()}
+		endpointer = sd.NewEndpointer(instancer, instanceFactory, log.NewNopLogger())
+		balancer   = NewConsistentHash(endpointer, requestKey)
+		keys       = 10000
+	)
+	defer endpointer.Close()
+
+	instancer.Update(sd.Event{Instances: []string{"a", "b", "c", "d"}})
+	waitEndpoint(t, balancer)
+
+	routes := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprint("key", i)
+		routes[key] = route(t, balancer, key)
+		counts[routes[key]]++
+		if want, have := routes[key], route(t, balancer, key); want != have {
+			t.Fatalf("%s: want %s, have %s", key, want, have)
+		}
+	}
+	for instance, count := range counts {
+		if count < keys/4/2 || count > keys/4*2 {
+			t.Errorf("%s: %d of %d keys", instance, count, keys)
+		}
+	}
+
+	// Only the keys of the removed instance move.
+	instancer.Update(sd.Event{Instances: []string{"a", "b", "c"}})
+	deadline := time.Now().Add(time.Second)
+	for key, was := range routes {
+		is := route(t, balancer, key)
+		for was == "d" && is == "d" {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: routed to removed instance", key)
+			}
+			time.Sleep(time.Millisecond)
+			is = route(t, balancer, key)
+		}
+		if was != "d" && is != was {
+			t.Errorf("%s: moved from %s to %s", key, was, is)
+		}
+	}
+}
+
+func TestConsistentHashBoundedLoad(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		mtx     sync.Mutex
+		loads   = map[string]int{}
+		f       = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				mtx.Lock()
+				loads[instance]++
+				mtx.Unlock()
+				<-release
+				return instance, nil
+			}, nil, nil
+		}
+		endpointer = sd.NewEndpointer(sd.FixedInstancer{"a", "b", "c", "d"}, f, log.NewNopLogger())
+		balancer   = NewConsistentHash(endpointer, requestKey, ConsistentHashLoadFactor(1.25))
+		n          = 8
+		wg         sync.WaitGroup
+	)
+	defer endpointer.Close()
+	waitEndpoint(t, balancer)
+
+	// Every request has the same key, but each instance takes at most
+	// ceil(1.25 * 8 / 4) = 3 of them.
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		e, _ := balancer.Endpoint()
+		go func() {
+			defer wg.Done()
+			e(context.Background(), "hot")
+		}()
+	}
+	for {
+		mtx.Lock()
+		total := 0
+		for _, load := range loads {
+			total += load
+		}
+		mtx.Unlock()
+		if total == n {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if len(loads) < 3 {
+		t.Errorf("want the load spread over at least 3 instances, have %v", loads)
+	}
+	for instance, load := range loads {
+		if load > 3 {
+			t.Errorf("%s: want at most 3 requests, have %d", instance, load)
+		}
+	}
+}
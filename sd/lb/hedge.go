@@ -0,0 +1,176 @@
+package lb
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Defaults for Hedged.
+const (
+	DefaultHedgeDelay      = 100 * time.Millisecond
+	DefaultHedgePercentile = 0.95
+	DefaultHedgeWindow     = 1000
+)
+
+// HedgeOption sets an optional parameter for Hedged.
+type HedgeOption func(*hedger)
+
+// HedgeDelay sets the delay before the second attempt, used until enough
+// latencies have been observed to compute the percentile. By default,
+// DefaultHedgeDelay is used.
+func HedgeDelay(d time.Duration) HedgeOption {
+	return func(h *hedger) { h.initial = d }
+}
+
+// HedgePercentile sets the percentile, 0.0 < p < 1.0, of recently observed
+// latencies after which the second attempt is made. For example, with 0.95,
+// roughly 5% of requests are hedged. Zero disables tracking, so the delay is
+// always that set by HedgeDelay. By default, DefaultHedgePercentile is used.
+func HedgePercentile(p float64) HedgeOption {
+	return func(h *hedger) { h.percentile = p }
+}
+
+// HedgeWindow sets the number of recent latencies from which the percentile
+// is computed. The percentile is used once a tenth of the window is filled.
+// By default, DefaultHedgeWindow is used.
+func HedgeWindow(n int) HedgeOption {
+	return func(h *hedger) { h.window = n }
+}
+
+// Hedged wraps a service load balancer and returns an endpoint oriented load
+// balancer for the specified service method. Each request is sent to an
+// endpoint from the balancer. If it hasn't responded within the hedge delay, a
+// second attempt is sent to another endpoint from the balancer, and the first
+// successful response is returned. The context of the other attempt is
+// canceled. This reduces tail latency at the cost of a few percent more
+// requests, so it's best suited to idempotent, read-mostly endpoints.
+//
+// The hedge delay tracks a percentile of recent successful latencies. Whether
+// the second endpoint differs from the first depends on the balancer; a round
+// robin balancer yields a different endpoint whenever there are several. An
+// attempt that fails doesn't trigger a hedge; if every attempt made fails,
+// the last error is returned.
+func Hedged(b Balancer, options ...HedgeOption) endpoint.Endpoint {
+	if b == nil {
+		panic("nil Balancer")
+	}
+	h := &hedger{
+		initial:    DefaultHedgeDelay,
+		percentile: DefaultHedgePercentile,
+		window:     DefaultHedgeWindow,
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if h.window < 1 {
+		h.window = 1
+	}
+	h.delay = h.initial
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			response interface{}
+			err      error
+		}
+		results := make(chan result, 2)
+		attempt := func() {
+			e, err := b.Endpoint()
+			if err != nil {
+				results <- result{nil, err}
+				return
+			}
+			begin := time.Now()
+			response, err := e(ctx, request)
+			if err == nil {
+				h.observe(time.Since(begin))
+			}
+			results <- result{response, err}
+		}
+
+		go attempt()
+		timer := time.NewTimer(h.hedgeDelay())
+		defer timer.Stop()
+		var (
+			outstanding = 1
+			hedged      bool
+		)
+		for {
+			select {
+			case <-timer.C:
+				if !hedged {
+					hedged = true
+					outstanding++
+					go attempt()
+				}
+			case r := <-results:
+				outstanding--
+				if r.err == nil || outstanding == 0 {
+					return r.response, r.err
+				}
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+type hedger struct {
+	initial    time.Duration
+	percentile float64
+	window     int
+
+	mtx       sync.Mutex
+	latencies []time.Duration // ring buffer
+	next      int
+	count     int // observations since the delay was computed
+	delay     time.Duration
+}
+
+func (h *hedger) hedgeDelay() time.Duration {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.delay
+}
+
+// observe records the latency, recomputing the delay every tenth of a window.
+func (h *hedger) observe(d time.Duration) {
+	if h.percentile <= 0 {
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if len(h.latencies) < h.window {
+		h.latencies = append(h.latencies, d)
+	} else {
+		h.latencies[h.next] = d
+	}
+	h.next = (h.next + 1) % h.window
+	h.count++
+
+	step := h.window / 10
+	if step < 1 {
+		step = 1
+	}
+	if h.count < step {
+		return
+	}
+	h.count = 0
+	sorted := append([]time.Duration(nil), h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(h.percentile*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	h.delay = sorted[i]
+}
@@ -0,0 +1,102 @@
+package lb
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestHedgedSlowFirst(t *testing.T) {
+	canceled := make(chan struct{})
+	var (
+		slow = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}
+		fast     = func(context.Context, interface{}) (interface{}, error) { return "fast", nil }
+		balancer = NewRoundRobin(sd.FixedEndpointer{slow, fast})
+		e        = Hedged(balancer, HedgeDelay(time.Millisecond), HedgePercentile(0))
+	)
+	response, err := e(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "fast", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("losing attempt wasn't canceled")
+	}
+}
+
+func TestHedgedFast(t *testing.T) {
+	var (
+		calls int32
+		fast  = func(context.Context, interface{}) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return "fast", nil
+		}
+		e = Hedged(NewRoundRobin(sd.FixedEndpointer{fast, fast}), HedgeDelay(time.Hour))
+	)
+	for i := 0; i < 10; i++ {
+		if _, err := e(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want, have := int32(10), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestHedgedErrors(t *testing.T) {
+	errFail := errors.New("fail")
+	var (
+		fail  endpoint.Endpoint = func(context.Context, interface{}) (interface{}, error) { return nil, errFail }
+		calls int32
+		count = func(context.Context, interface{}) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return "ok", nil
+		}
+	)
+	// A failure doesn't trigger a hedge.
+	e := Hedged(NewRoundRobin(sd.FixedEndpointer{fail, count}), HedgeDelay(time.Hour))
+	if _, err := e(context.Background(), nil); err != errFail {
+		t.Errorf("want %v, have %v", errFail, err)
+	}
+	if want, have := int32(0), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+
+	e = Hedged(NewRoundRobin(sd.FixedEndpointer{}))
+	if _, err := e(context.Background(), nil); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
+
+func TestHedgePercentile(t *testing.T) {
+	h := &hedger{percentile: 0.9, window: 100, delay: time.Hour}
+	for i := 1; i <= 9; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if want, have := time.Hour, h.hedgeDelay(); want != have {
+		t.Errorf("before a tenth of the window: want %s, have %s", want, have)
+	}
+	h.observe(10 * time.Millisecond)
+	if want, have := 9*time.Millisecond, h.hedgeDelay(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	for i := 0; i < 200; i++ {
+		h.observe(time.Second)
+	}
+	if want, have := time.Second, h.hedgeDelay(); want != have {
+		t.Errorf("after the window slides: want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,106 @@
+package lb
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// LeastLoaded is a load balancer that returns the endpoint of the instance
+// with the fewest requests in flight.
+type LeastLoaded struct {
+	next uint64 // atomic; first, for its alignment
+	s    sd.InstanceEndpointer
+
+	mtx       sync.RWMutex
+	endpoints []sd.InstanceEndpoint
+	peers     []*loadedPeer
+}
+
+type loadedPeer struct {
+	inflight int64 // atomic; first, for its alignment
+	instance string
+	endpoint endpoint.Endpoint
+}
+
+// NewLeastLoaded returns a load balancer that returns the endpoint of s
+// with the fewest requests in flight. It improves on round robin when the
+// costs of requests vary widely, as slow instances are given fewer of them.
+//
+// The endpoints are wrapped to count their requests in flight, so only
+// requests made with the endpoints it returns count. Ties are broken in
+// sequence.
+func NewLeastLoaded(s sd.InstanceEndpointer) *LeastLoaded {
+	return &LeastLoaded{s: s}
+}
+
+// update replaces the peers with those of the endpoints, unless another
+// caller already did.
+func (l *LeastLoaded) update(endpoints []sd.InstanceEndpoint) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if !changed(l.endpoints, endpoints) {
+		return
+	}
+
+	// The requests in flight of surviving instances still count.
+	old := make(map[string]*loadedPeer, len(l.peers))
+	for _, p := range l.peers {
+		old[p.instance] = p
+	}
+	peers := make([]*loadedPeer, len(endpoints))
+	for i, ie := range endpoints {
+		p, ok := old[ie.Instance.Address]
+		if !ok {
+			p = &loadedPeer{instance: ie.Instance.Address}
+		}
+		p.endpoint = p.wrap(ie.Endpoint)
+		peers[i] = p
+	}
+	l.endpoints, l.peers = endpoints, peers
+}
+
+// wrap returns the endpoint, counting its requests in flight.
+func (p *loadedPeer) wrap(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt64(&p.inflight, 1)
+		defer atomic.AddInt64(&p.inflight, -1)
+		return next(ctx, request)
+	}
+}
+
+// Endpoint implements Balancer.
+func (l *LeastLoaded) Endpoint() (endpoint.Endpoint, error) {
+	endpoints, err := l.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mtx.RLock()
+	if changed(l.endpoints, endpoints) {
+		l.mtx.RUnlock()
+		l.update(endpoints)
+		l.mtx.RLock()
+	}
+	defer l.mtx.RUnlock()
+
+	if len(l.peers) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	var (
+		n     = uint64(len(l.peers))
+		start = atomic.AddUint64(&l.next, 1) - 1
+		best  *loadedPeer
+		least int64
+	)
+	for i := uint64(0); i < n; i++ {
+		p := l.peers[(start+i)%n]
+		if inflight := atomic.LoadInt64(&p.inflight); best == nil || inflight < least {
+			best, least = p, inflight
+		}
+	}
+	return best.endpoint, nil
+}
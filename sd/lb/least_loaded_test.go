@@ -0,0 +1,105 @@
+package lb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestLeastLoaded(t *testing.T) {
+	var (
+		release  = map[string]chan struct{}{"a": make(chan struct{}), "b": make(chan struct{}), "c": make(chan struct{})}
+		started  = make(chan string)
+		finished = make(chan string, 3)
+		f        = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				started <- instance
+				<-release[instance]
+				return instance, nil
+			}, nil, nil
+		}
+		endpointer = sd.NewEndpointer(sd.FixedInstancer{"a", "b", "c"}, f, log.NewNopLogger())
+		balancer   = NewLeastLoaded(endpointer)
+		wg         sync.WaitGroup
+	)
+	defer endpointer.Close()
+	waitEndpoint(t, balancer)
+
+	call := func() string {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, _ := e(context.Background(), struct{}{})
+			finished <- response.(string)
+		}()
+		return <-started
+	}
+
+	// Idle instances are returned in sequence; then the one which finishes
+	// its request is the least loaded.
+	have := []string{call(), call(), call()}
+	close(release["a"])
+	<-finished
+	have = append(have, call())
+	if want := []string{"b", "c", "a", "a"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	close(release["b"])
+	close(release["c"])
+	wg.Wait()
+}
+
+func TestLeastLoadedNoEndpoints(t *testing.T) {
+	endpointer := sd.NewEndpointer(sd.FixedInstancer{}, instanceFactory, log.NewNopLogger())
+	defer endpointer.Close()
+	balancer := NewLeastLoaded(endpointer)
+	_, err := balancer.Endpoint()
+	if want, have := ErrNoEndpoints, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestLeastLoadedHealthCheck(t *testing.T) {
+	probe := func(_ context.Context, instance string) error {
+		if instance == "b" {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}
+	endpointer := sd.NewEndpointer(sd.FixedInstancer{"a", "b"}, instanceFactory, log.NewNopLogger(), sd.HealthCheck(probe, time.Millisecond))
+	defer endpointer.Close()
+	balancer := NewLeastLoaded(endpointer)
+
+	// The endpoints of unhealthy instances are withheld from the balancer.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if endpoints, _ := endpointer.Endpoints(); len(endpoints) == 1 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("unhealthy instance wasn't withheld in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response, _ := e(context.Background(), struct{}{}); response != "a" {
+			t.Errorf("want a, have %v", response)
+		}
+	}
+}
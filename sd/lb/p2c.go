@@ -0,0 +1,159 @@
+package lb
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// DefaultP2CDecay is the default decay of the latencies of P2C.
+const DefaultP2CDecay = 10 * time.Second
+
+// P2COption sets an optional parameter for P2C.
+type P2COption func(*P2C)
+
+// P2CDecay sets the time constant of the exponentially weighted moving
+// average of the latencies of each instance. Latencies observed a decay ago
+// weigh about a third as much as new ones. By default, DefaultP2CDecay is
+// used.
+func P2CDecay(d time.Duration) P2COption {
+	return func(b *P2C) { b.decay = d }
+}
+
+// P2C is a "power of two choices" load balancer. For each endpoint, it
+// samples two instances at random, and returns that of the one with the
+// lower cost: its moving average latency, scaled by its requests in flight.
+type P2C struct {
+	s     sd.InstanceEndpointer
+	decay time.Duration
+	now   func() time.Time
+
+	mtx       sync.Mutex
+	r         *rand.Rand
+	endpoints []sd.InstanceEndpoint
+	peers     []*p2cPeer
+}
+
+type p2cPeer struct {
+	inflight int64 // atomic; first, for its alignment
+	instance string
+	endpoint endpoint.Endpoint
+
+	mtx  sync.Mutex
+	ewma float64 // nanoseconds
+	last time.Time
+}
+
+// NewP2C returns a load balancer that returns the endpoints of s by the
+// power of two choices, which nearly matches the least loaded instance
+// without tracking every instance, and without herding on it. It suits large
+// backends well.
+//
+// The endpoints are wrapped to observe their latencies and count their
+// requests in flight, so only requests made with the endpoints it returns
+// count. Instances without observed latencies are preferred, so new
+// instances are tried promptly.
+func NewP2C(s sd.InstanceEndpointer, seed int64, options ...P2COption) *P2C {
+	b := &P2C{
+		s:     s,
+		decay: DefaultP2CDecay,
+		now:   time.Now,
+		r:     rand.New(rand.NewSource(seed)),
+	}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// update replaces the peers with those of the endpoints. The lock must be
+// held.
+func (b *P2C) update(endpoints []sd.InstanceEndpoint) {
+	// The latencies and requests in flight of surviving instances are
+	// retained.
+	old := make(map[string]*p2cPeer, len(b.peers))
+	for _, p := range b.peers {
+		old[p.instance] = p
+	}
+	peers := make([]*p2cPeer, len(endpoints))
+	for i, ie := range endpoints {
+		p, ok := old[ie.Instance.Address]
+		if !ok {
+			p = &p2cPeer{instance: ie.Instance.Address}
+		}
+		p.endpoint = b.wrap(p, ie.Endpoint)
+		peers[i] = p
+	}
+	b.endpoints, b.peers = endpoints, peers
+}
+
+// wrap returns the endpoint of the peer, observing its latencies and
+// counting its requests in flight.
+func (b *P2C) wrap(p *p2cPeer, next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt64(&p.inflight, 1)
+		defer atomic.AddInt64(&p.inflight, -1)
+		begin := b.now()
+		defer func() { p.observe(begin, b.now(), b.decay) }()
+		return next(ctx, request)
+	}
+}
+
+// observe folds the latency of a request into the moving average, weighing
+// the average by the time since the last observation.
+func (p *p2cPeer) observe(begin, end time.Time, decay time.Duration) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	latency := float64(end.Sub(begin))
+	if p.last.IsZero() {
+		p.ewma = latency
+	} else {
+		w := math.Exp(-float64(end.Sub(p.last)) / float64(decay))
+		p.ewma = p.ewma*w + latency*(1-w)
+	}
+	p.last = end
+}
+
+func (p *p2cPeer) cost() float64 {
+	p.mtx.Lock()
+	ewma := p.ewma
+	p.mtx.Unlock()
+	return (ewma + 1) * float64(atomic.LoadInt64(&p.inflight)+1)
+}
+
+// Endpoint implements Balancer.
+func (b *P2C) Endpoint() (endpoint.Endpoint, error) {
+	endpoints, err := b.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if changed(b.endpoints, endpoints) {
+		b.update(endpoints)
+	}
+	n := len(b.peers)
+	if n <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	if n == 1 {
+		return b.peers[0].endpoint, nil
+	}
+	i, j := b.r.Intn(n), b.r.Intn(n-1)
+	if j >= i {
+		j++
+	}
+	p, q := b.peers[i], b.peers[j]
+	if q.cost() < p.cost() {
+		p = q
+	}
+	return p.endpoint, nil
+}
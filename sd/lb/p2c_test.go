@@ -0,0 +1,76 @@
+package lb
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+func TestP2C(t *testing.T) {
+	var (
+		mtx       sync.Mutex
+		now       = time.Unix(0, 0)
+		latencies = map[string]time.Duration{"fast": time.Millisecond, "slow": 50 * time.Millisecond}
+		f         = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				mtx.Lock()
+				now = now.Add(latencies[instance])
+				mtx.Unlock()
+				return instance, nil
+			}, nil, nil
+		}
+		endpointer = sd.NewEndpointer(sd.FixedInstancer{"fast", "slow"}, f, log.NewNopLogger())
+		balancer   = NewP2C(endpointer, 12345)
+		counts     = map[string]int{}
+	)
+	defer endpointer.Close()
+	balancer.now = func() time.Time {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return now
+	}
+	waitEndpoint(t, balancer)
+
+	// Once each latency is observed, the fast instance is preferred.
+	for i := 0; i < 100; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, _ := e(context.Background(), struct{}{})
+		counts[response.(string)]++
+	}
+	if want, have := 99, counts["fast"]; have < want {
+		t.Errorf("want at least %d requests to the fast instance, have %d", want, have)
+	}
+
+	// Unless it has many more requests in flight.
+	var fast *p2cPeer
+	for _, p := range balancer.peers {
+		if p.instance == "fast" {
+			fast = p
+		}
+	}
+	atomic.StoreInt64(&fast.inflight, 100)
+	e, _ := balancer.Endpoint()
+	if response, _ := e(context.Background(), struct{}{}); response != "slow" {
+		t.Errorf("want slow, have %v", response)
+	}
+}
+
+func TestP2CNoEndpoints(t *testing.T) {
+	endpointer := sd.NewEndpointer(sd.FixedInstancer{}, instanceFactory, log.NewNopLogger())
+	defer endpointer.Close()
+	balancer := NewP2C(endpointer, 12345)
+	_, err := balancer.Endpoint()
+	if want, have := ErrNoEndpoints, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,101 @@
+package lb
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// WeightFunc returns the weight of an instance from its record, e.g. the
+// weight of its SRV record, or of its service in Consul, as with
+// RecordWeight. Weights below 1 are treated as 1.
+type WeightFunc func(instance sd.Instance) int
+
+// RecordWeight is a WeightFunc which returns the weight of the record of the
+// instance, as reported by the Instancer.
+func RecordWeight(instance sd.Instance) int {
+	return instance.Weight
+}
+
+// WeightedRoundRobin is a load balancer that returns the endpoints of the
+// instances in sequence, each in proportion to its weight.
+type WeightedRoundRobin struct {
+	s      sd.InstanceEndpointer
+	weight WeightFunc
+
+	mtx       sync.Mutex
+	endpoints []sd.InstanceEndpoint
+	peers     []*weightedPeer
+}
+
+type weightedPeer struct {
+	instance string
+	endpoint endpoint.Endpoint
+	weight   int
+	current  int
+}
+
+// NewWeightedRoundRobin returns a load balancer that returns the endpoints
+// of s in sequence, so that instances receive traffic in proportion to their
+// weights. The sequence is smooth: an instance with weight 3 among two of
+// weight 1 is returned as a, b, a, c, a rather than a, a, a, b, c.
+//
+// Weights are evaluated whenever the endpoints change, including when the
+// records of their instances do.
+func NewWeightedRoundRobin(s sd.InstanceEndpointer, weight WeightFunc) *WeightedRoundRobin {
+	return &WeightedRoundRobin{s: s, weight: weight}
+}
+
+// update replaces the peers with those of the endpoints. The lock must be
+// held.
+func (w *WeightedRoundRobin) update(endpoints []sd.InstanceEndpoint) {
+	current := make(map[string]int, len(w.peers))
+	for _, p := range w.peers {
+		current[p.instance] = p.current
+	}
+	peers := make([]*weightedPeer, len(endpoints))
+	for i, ie := range endpoints {
+		instance := ie.Instance.Address
+		weight := w.weight(ie.Instance)
+		if weight < 1 {
+			weight = 1
+		}
+		peers[i] = &weightedPeer{instance, ie.Endpoint, weight, current[instance]}
+	}
+	w.endpoints, w.peers = endpoints, peers
+}
+
+// Endpoint implements Balancer.
+func (w *WeightedRoundRobin) Endpoint() (endpoint.Endpoint, error) {
+	endpoints, err := w.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if changed(w.endpoints, endpoints) {
+		w.update(endpoints)
+	}
+	if len(w.peers) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	// Each peer gains its weight, and the one with the most is returned, and
+	// loses the total of the weights.
+	var (
+		best  *weightedPeer
+		total int
+	)
+	for _, p := range w.peers {
+		p.current += p.weight
+		total += p.weight
+		if best == nil || p.current > best.current {
+			best = p
+		}
+	}
+	best.current -= total
+	return best.endpoint, nil
+}
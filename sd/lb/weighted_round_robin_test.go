@@ -0,0 +1,77 @@
+package lb
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+)
+
+// instanceFactory returns a factory whose endpoints respond with their
+// instances.
+func instanceFactory(instance string) (endpoint.Endpoint, io.Closer, error) {
+	return func(context.Context, interface{}) (interface{}, error) { return instance, nil }, nil, nil
+}
+
+// waitEndpoint waits for the balancer to yield an endpoint, as it receives
+// the endpoints asynchronously.
+func waitEndpoint(t *testing.T, b Balancer) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := b.Endpoint(); err == nil {
+			return
+		} else if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	var (
+		instancer  = mockInstancer{instance.NewCache()}
+		endpointer = sd.NewEndpointer(instancer, instanceFactory, log.NewNopLogger())
+		balancer   = NewWeightedRoundRobin(endpointer, RecordWeight)
+	)
+	defer endpointer.Close()
+
+	instancer.Update(sd.Event{
+		Instances: []string{"c", "a", "b"},
+		Records: map[string]sd.Instance{
+			"a": {Address: "a", Weight: 3},
+			"b": {Address: "b", Weight: 1},
+		},
+	})
+
+	// Endpoint is called once more here, so the sequence starts after "a".
+	waitEndpoint(t, balancer)
+
+	var have []string
+	for i := 0; i < 10; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, _ := e(context.Background(), struct{}{})
+		have = append(have, response.(string))
+	}
+	if want := []string{"b", "a", "c", "a", "a", "b", "a", "c", "a", "a"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestWeightedRoundRobinNoEndpoints(t *testing.T) {
+	endpointer := sd.NewEndpointer(sd.FixedInstancer{}, instanceFactory, log.NewNopLogger())
+	defer endpointer.Close()
+	balancer := NewWeightedRoundRobin(endpointer, func(sd.Instance) int { return 1 })
+	_, err := balancer.Endpoint()
+	if want, have := ErrNoEndpoints, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
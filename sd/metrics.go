@@ -0,0 +1,41 @@
+package sd
+
+import (
+	"github.com/go-kit/kit/metrics"
+)
+
+// Metrics are the metrics of the instances of an Endpointer, which make the
+// churn of a service discovery system visible. Metrics which are nil aren't
+// reported.
+type Metrics struct {
+	// Instances is set to the number of instances with endpoints.
+	Instances metrics.Gauge
+
+	// Added and Removed count the instances added to and removed from the
+	// endpoints.
+	Added   metrics.Counter
+	Removed metrics.Counter
+
+	// Errors counts the errors the Instancer notifies of, e.g. failures to
+	// resolve the instances.
+	Errors metrics.Counter
+
+	// FactoryErrors counts the instances for which the factory failed to
+	// create an endpoint.
+	FactoryErrors metrics.Counter
+}
+
+// Instrument returns EndpointerOption that reports the metrics of the
+// instances of the Endpointer. Changes of the instances are logged,
+// regardless.
+func Instrument(m Metrics) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		opts.metrics = m
+	}
+}
+
+func count(c metrics.Counter, n int) {
+	if c != nil && n > 0 {
+		c.Add(float64(n))
+	}
+}
@@ -0,0 +1,133 @@
+package sd
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MultiInstancer merges the instances of several Instancers, e.g. those of a
+// discovery system and a static fallback list, so that the instances of the
+// others are still yielded when one of them fails.
+type MultiInstancer struct {
+	srcs []Instancer
+	chs  []chan Event
+	reg  *registry
+
+	mtx    sync.Mutex
+	states []Event
+	last   []Event // the last events without errors
+}
+
+// MultiInstancerError is the error of an event of a MultiInstancer whose
+// sources have all failed. It holds the error of each source, in order.
+type MultiInstancerError []error
+
+func (e MultiInstancerError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "all sources failed: " + strings.Join(msgs, "; ")
+}
+
+// NewMultiInstancer returns an Instancer which subscribes to updates from
+// each of srcs, and yields the union of their instances, without duplicates.
+// A source which notifies of an error contributes its last known instances
+// until it recovers. Only once every source has failed is the error
+// propagated, as a MultiInstancerError; the errors of each source are
+// available with Errors.
+func NewMultiInstancer(srcs ...Instancer) *MultiInstancer {
+	m := &MultiInstancer{
+		srcs:   srcs,
+		chs:    make([]chan Event, len(srcs)),
+		reg:    newRegistry(),
+		states: make([]Event, len(srcs)),
+		last:   make([]Event, len(srcs)),
+	}
+	for i, src := range srcs {
+		m.chs[i] = make(chan Event)
+		go m.receive(i)
+		src.Register(m.chs[i])
+	}
+	return m
+}
+
+func (m *MultiInstancer) receive(i int) {
+	for event := range m.chs[i] {
+		// The lock is held while notifying, so that the merged events of
+		// the sources are ordered.
+		m.mtx.Lock()
+		m.states[i] = event
+		if event.Err == nil {
+			m.last[i] = event
+		}
+		m.reg.update(m.merge())
+		m.mtx.Unlock()
+	}
+}
+
+// merge returns the merged event of the states of the sources. The record
+// of an instance is that of the first source which has one.
+func (m *MultiInstancer) merge() Event {
+	var (
+		seen      = map[string]bool{}
+		instances = []string{}
+		records   map[string]Instance
+		errs      = make(MultiInstancerError, 0, len(m.states))
+	)
+	for i, state := range m.states {
+		if state.Err != nil {
+			errs = append(errs, state.Err)
+		}
+		for _, instance := range m.last[i].Instances {
+			if !seen[instance] {
+				seen[instance] = true
+				instances = append(instances, instance)
+			}
+			if r, ok := m.last[i].Records[instance]; ok {
+				if records == nil {
+					records = map[string]Instance{}
+				}
+				if _, ok := records[instance]; !ok {
+					records[instance] = r
+				}
+			}
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(m.states) {
+		return Event{Err: errs}
+	}
+	sort.Strings(instances)
+	return Event{Instances: instances, Records: records}
+}
+
+// Errors returns the current error of each source, in order; it's nil for
+// sources which haven't failed.
+func (m *MultiInstancer) Errors() []error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	errs := make([]error, len(m.states))
+	for i, state := range m.states {
+		errs[i] = state.Err
+	}
+	return errs
+}
+
+// Register implements Instancer.
+func (m *MultiInstancer) Register(ch chan<- Event) {
+	m.reg.register(ch)
+}
+
+// Deregister implements Instancer.
+func (m *MultiInstancer) Deregister(ch chan<- Event) {
+	m.reg.deregister(ch)
+}
+
+// Stop deregisters the MultiInstancer from its sources.
+func (m *MultiInstancer) Stop() {
+	for i, src := range m.srcs {
+		src.Deregister(m.chs[i])
+		close(m.chs[i])
+	}
+}
@@ -0,0 +1,95 @@
+package sd_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+)
+
+func TestMultiInstancer(t *testing.T) {
+	var (
+		discovery = &mockInstancer{cache: instance.NewCache()}
+		static    = sd.FixedInstancer{"10.0.0.1:80", "10.0.0.9:80"}
+		ch        = make(chan sd.Event, 10)
+	)
+	discovery.Update(sd.Event{Instances: []string{"10.0.0.2:80", "10.0.0.1:80"}})
+	multi := sd.NewMultiInstancer(discovery, static)
+	defer multi.Stop()
+	multi.Register(ch)
+	defer multi.Deregister(ch)
+
+	expect := func(want sd.Event) {
+		deadline := time.After(time.Second)
+		for {
+			select {
+			case have := <-ch:
+				if reflect.DeepEqual(want, have) {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("want %v, didn't have it", want)
+			}
+		}
+	}
+
+	// The instances are merged, without duplicates.
+	expect(sd.Event{Instances: []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.9:80"}})
+
+	// A failing source contributes its last known instances.
+	errDiscovery := errors.New("discovery unavailable")
+	discovery.Update(sd.Event{Err: errDiscovery})
+	discovery.Update(sd.Event{Instances: []string{"10.0.0.3:80"}})
+	expect(sd.Event{Instances: []string{"10.0.0.1:80", "10.0.0.3:80", "10.0.0.9:80"}})
+
+	// The records of the sources are merged.
+	records := map[string]sd.Instance{"10.0.0.3:80": {Address: "10.0.0.3:80", Weight: 5}}
+	discovery.Update(sd.Event{Instances: []string{"10.0.0.3:80"}, Records: records})
+	expect(sd.Event{Instances: []string{"10.0.0.1:80", "10.0.0.3:80", "10.0.0.9:80"}, Records: records})
+
+	discovery.Update(sd.Event{Err: errDiscovery})
+	deadline := time.Now().Add(time.Second)
+	for errs := multi.Errors(); errs[0] != errDiscovery; errs = multi.Errors() {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, have %v", errDiscovery, errs[0])
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := multi.Errors()[1]; err != nil {
+		t.Errorf("want no error of the static source, have %v", err)
+	}
+}
+
+func TestMultiInstancerAllFailed(t *testing.T) {
+	var (
+		a  = &mockInstancer{cache: instance.NewCache()}
+		b  = &mockInstancer{cache: instance.NewCache()}
+		ch = make(chan sd.Event, 10)
+	)
+	multi := sd.NewMultiInstancer(a, b)
+	defer multi.Stop()
+	multi.Register(ch)
+	defer multi.Deregister(ch)
+
+	errA, errB := errors.New("a"), errors.New("b")
+	a.Update(sd.Event{Err: errA})
+	b.Update(sd.Event{Err: errB})
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case event := <-ch:
+			if event.Err == nil {
+				continue
+			}
+			if want, have := (sd.MultiInstancerError{errA, errB}), event.Err; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+			return
+		case <-deadline:
+			t.Fatal("want error once all sources failed")
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package sd
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// OutlierOption sets an optional parameter for OutlierDetection.
+type OutlierOption func(*outlierDetector)
+
+// OutlierConsecutiveErrors sets the number of consecutive failed requests
+// after which an instance is ejected. By default, it's 5.
+func OutlierConsecutiveErrors(n int) OutlierOption {
+	return func(d *outlierDetector) { d.consecutive = n }
+}
+
+// OutlierLatency sets the latency beyond which requests count as failed,
+// even if they succeed. By default, latency isn't considered.
+func OutlierLatency(latency time.Duration) OutlierOption {
+	return func(d *outlierDetector) { d.latency = latency }
+}
+
+// OutlierEjectionTime sets the time for which an instance is ejected. It
+// doubles with each consecutive ejection of the instance, up to max. An
+// instance which isn't ejected again within max after its last ejection
+// starts over at base. By default, they're 30 seconds and 5 minutes.
+func OutlierEjectionTime(base, max time.Duration) OutlierOption {
+	return func(d *outlierDetector) { d.baseEjection, d.maxEjection = base, max }
+}
+
+// OutlierMaxEjectionPercent sets the percentage of the instances which may be
+// ejected at once, so that a failure of the service itself doesn't eject all
+// of its instances. One instance may always be ejected. By default, it's 10.
+func OutlierMaxEjectionPercent(p int) OutlierOption {
+	return func(d *outlierDetector) { d.maxEjectionPercent = p }
+}
+
+// OutlierDetection returns EndpointerOption that tracks the failures of the
+// requests made with each endpoint, and temporarily ejects instances whose
+// requests fail consecutively, withholding their endpoints, as Envoy does.
+// Requests whose contexts are canceled or expired aren't counted, as their
+// failures are those of the caller.
+func OutlierDetection(options ...OutlierOption) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		d := &outlierDetector{
+			consecutive:        5,
+			baseEjection:       30 * time.Second,
+			maxEjection:        5 * time.Minute,
+			maxEjectionPercent: 10,
+			timeNow:            time.Now,
+			stats:              map[string]*outlierStats{},
+		}
+		for _, option := range options {
+			option(d)
+		}
+		opts.outliers = d
+	}
+}
+
+type outlierDetector struct {
+	consecutive        int
+	latency            time.Duration
+	baseEjection       time.Duration
+	maxEjection        time.Duration
+	maxEjectionPercent int
+	timeNow            func() time.Time
+
+	mtx   sync.Mutex
+	stats map[string]*outlierStats
+}
+
+type outlierStats struct {
+	failures     int
+	ejections    int
+	ejectedUntil time.Time
+}
+
+// factory wraps the endpoints of f to observe the outcomes of their requests,
+// and eject their instances from c.
+func (d *outlierDetector) factory(f Factory, c *endpointCache) Factory {
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		e, closer, err := f(instance)
+		if err != nil {
+			return nil, nil, err
+		}
+		tracked := func(ctx context.Context, request interface{}) (interface{}, error) {
+			begin := d.timeNow()
+			response, err := e(ctx, request)
+			if ctx.Err() != nil {
+				return response, err
+			}
+			failed := err != nil || (d.latency > 0 && d.timeNow().Sub(begin) > d.latency)
+			if ejection, ok := d.observe(instance, failed); ok && !c.Eject(instance, ejection, d.maxEjectionPercent) {
+				d.readmit(instance)
+			}
+			return response, err
+		}
+		return tracked, forgetCloser{closer, func() { d.forget(instance) }}, nil
+	}
+}
+
+// observe records the outcome of a request to the instance, and returns the
+// time for which it's to be ejected, if it is.
+func (d *outlierDetector) observe(instance string, failed bool) (time.Duration, bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	s, ok := d.stats[instance]
+	if !ok {
+		s = &outlierStats{}
+		d.stats[instance] = s
+	}
+	if !failed {
+		s.failures = 0
+		return 0, false
+	}
+	if s.failures++; s.failures < d.consecutive {
+		return 0, false
+	}
+	s.failures = 0
+
+	now := d.timeNow()
+	if now.Before(s.ejectedUntil) {
+		return 0, false // already ejected; these requests were in flight
+	}
+	if now.Sub(s.ejectedUntil) > d.maxEjection {
+		s.ejections = 0
+	}
+	ejection := d.baseEjection
+	for i := 0; i < s.ejections && ejection < d.maxEjection; i++ {
+		ejection *= 2
+	}
+	if ejection > d.maxEjection {
+		ejection = d.maxEjection
+	}
+	s.ejections++
+	s.ejectedUntil = now.Add(ejection)
+	return ejection, true
+}
+
+// readmit undoes the ejection of the instance, which exceeded the maximum
+// ejected.
+func (d *outlierDetector) readmit(instance string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if s, ok := d.stats[instance]; ok {
+		s.ejections--
+		s.ejectedUntil = time.Time{}
+	}
+}
+
+func (d *outlierDetector) forget(instance string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	delete(d.stats, instance)
+}
+
+// forgetCloser forgets the stats of an instance when its endpoint is closed.
+type forgetCloser struct {
+	io.Closer
+	forget func()
+}
+
+func (c forgetCloser) Close() error {
+	c.forget()
+	if c.Closer == nil {
+		return nil
+	}
+	return c.Closer.Close()
+}
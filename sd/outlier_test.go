@@ -0,0 +1,101 @@
+package sd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+func TestOutlierDetection(t *testing.T) {
+	var (
+		f = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				if instance == "bad" {
+					return nil, errors.New("failed")
+				}
+				return instance, nil
+			}, nil, nil
+		}
+		endpointer = NewEndpointer(FixedInstancer{"a", "b", "bad"}, f, log.NewNopLogger(), OutlierDetection(
+			OutlierConsecutiveErrors(3),
+			OutlierEjectionTime(50*time.Millisecond, time.Second),
+			OutlierMaxEjectionPercent(50),
+		))
+	)
+	defer endpointer.Close()
+
+	expectEndpoints := func(want int) []endpoint.Endpoint {
+		deadline := time.Now().Add(time.Second)
+		for {
+			endpoints, err := endpointer.Endpoints()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(endpoints) == want {
+				return endpoints
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("want %d endpoints, have %d", want, len(endpoints))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Endpoints are ordered by instance, so the last is that of "bad".
+	endpoints := expectEndpoints(3)
+	for i := 0; i < 3; i++ {
+		endpoints[0](context.Background(), struct{}{})
+		endpoints[2](context.Background(), struct{}{})
+	}
+	endpoints, _ = endpointer.Endpoints()
+	if want, have := 2, len(endpoints); want != have {
+		t.Fatalf("want %d endpoints after ejection, have %d", want, have)
+	}
+
+	// Once the ejection time passes, the instance is readmitted.
+	expectEndpoints(3)
+
+	// Requests canceled by the caller don't count.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	endpoints, _ = endpointer.Endpoints()
+	for i := 0; i < 3; i++ {
+		endpoints[2](ctx, struct{}{})
+	}
+	if endpoints, _ = endpointer.Endpoints(); len(endpoints) != 3 {
+		t.Errorf("want 3 endpoints, have %d", len(endpoints))
+	}
+}
+
+func TestOutlierEjectionTime(t *testing.T) {
+	var (
+		opts = endpointerOptions{}
+		now  = time.Unix(0, 0)
+	)
+	OutlierDetection(OutlierConsecutiveErrors(1), OutlierEjectionTime(time.Second, 3*time.Second))(&opts)
+	d := opts.outliers
+	d.timeNow = func() time.Time { return now }
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		have, ok := d.observe("a", true)
+		if !ok || want != have {
+			t.Fatalf("want ejection for %v, have %v (%v)", want, have, ok)
+		}
+		// Failures of requests in flight while ejected are ignored.
+		if _, ok := d.observe("a", true); ok {
+			t.Fatal("want no ejection while ejected")
+		}
+		now = now.Add(have)
+	}
+
+	// Once it's not ejected again within the maximum, it starts over.
+	now = now.Add(4 * time.Second)
+	if have, _ := d.observe("a", true); have != time.Second {
+		t.Errorf("want ejection for %v, have %v", time.Second, have)
+	}
+}
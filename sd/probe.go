@@ -0,0 +1,51 @@
+package sd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Probe checks the health of an instance, e.g. by connecting to it, or
+// calling its health endpoint. A nil error means the instance is healthy.
+// Probes are used with the HealthCheck option of NewEndpointer. Other
+// protocols, such as the gRPC health checking protocol, can be probed with a
+// user-supplied function.
+type Probe func(ctx context.Context, instance string) error
+
+// TCPProbe is a Probe which checks that a TCP connection can be made to the
+// instance, which must be a host:port.
+func TCPProbe(ctx context.Context, instance string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", instance)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTPProbe returns a Probe which makes a GET request for the path, e.g.
+// "/health", of the instance, which must be a host:port, with the client.
+// Responses with 2xx status codes are healthy. If client is nil,
+// http.DefaultClient is used.
+func HTTPProbe(client *http.Client, path string) Probe {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context, instance string) error {
+		req, err := http.NewRequest("GET", "http://"+instance+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("health check: %s", resp.Status)
+		}
+		return nil
+	}
+}
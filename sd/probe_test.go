@@ -0,0 +1,33 @@
+package sd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	instance := strings.TrimPrefix(server.URL, "http://")
+
+	if err := TCPProbe(context.Background(), instance); err != nil {
+		t.Errorf("TCP: %v", err)
+	}
+	if err := HTTPProbe(nil, "/health")(context.Background(), instance); err != nil {
+		t.Errorf("HTTP: %v", err)
+	}
+	if err := HTTPProbe(nil, "/ready")(context.Background(), instance); err == nil {
+		t.Error("HTTP: want error for 503, have none")
+	}
+
+	server.Close()
+	if err := TCPProbe(context.Background(), instance); err == nil {
+		t.Error("TCP: want error for closed server, have none")
+	}
+}
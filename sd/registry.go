@@ -0,0 +1,45 @@
+package sd
+
+import (
+	"reflect"
+	"sync"
+)
+
+// registry keeps the state of an Instancer which derives its instances from
+// other Instancers, and notifies the channels registered with it of changes.
+type registry struct {
+	mtx   sync.Mutex
+	state Event
+	chans map[chan<- Event]struct{}
+}
+
+func newRegistry() *registry {
+	return &registry{chans: map[chan<- Event]struct{}{}}
+}
+
+// update notifies the registered channels of the event, unless it's the
+// current state.
+func (r *registry) update(event Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if reflect.DeepEqual(r.state, event) {
+		return
+	}
+	r.state = event
+	for ch := range r.chans {
+		ch <- event
+	}
+}
+
+func (r *registry) register(ch chan<- Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.chans[ch] = struct{}{}
+	ch <- r.state
+}
+
+func (r *registry) deregister(ch chan<- Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.chans, ch)
+}
@@ -0,0 +1,105 @@
+package sd
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// SubsetInstancer yields a deterministic subset of the instances of another
+// Instancer, so that a client of a service with thousands of instances
+// doesn't maintain connections to all of them. It sits between the Instancer
+// and the Endpointer.
+type SubsetInstancer struct {
+	src  Instancer
+	id   string
+	size int
+	ch   chan Event
+	reg  *registry
+}
+
+// NewSubsetInstancer returns an Instancer which subscribes to updates from
+// Instancer src, and yields at most size of its instances, selected by the
+// ID of the client, e.g. its hostname. Each client selects the instances
+// whose hashes with its ID are highest, so that the clients are spread
+// evenly over the instances, and a change of the instances changes the
+// subsets only by the instances added or removed. Errors are passed through.
+func NewSubsetInstancer(src Instancer, id string, size int) *SubsetInstancer {
+	s := &SubsetInstancer{
+		src:  src,
+		id:   id,
+		size: size,
+		ch:   make(chan Event),
+		reg:  newRegistry(),
+	}
+	go s.receive()
+	src.Register(s.ch)
+	return s
+}
+
+func (s *SubsetInstancer) receive() {
+	for event := range s.ch {
+		if event.Err == nil {
+			event.Instances = s.subset(event.Instances)
+			event.Records = subsetRecords(event.Records, event.Instances)
+		}
+		s.reg.update(event)
+	}
+}
+
+// subset returns the instances with the highest hashes with the ID, in
+// lexicographic order.
+func (s *SubsetInstancer) subset(instances []string) []string {
+	type scored struct {
+		instance string
+		score    uint64
+	}
+	all := make([]scored, len(instances))
+	for i, instance := range instances {
+		h := fnv.New64a()
+		h.Write([]byte(s.id))
+		h.Write([]byte{0})
+		h.Write([]byte(instance))
+		all[i] = scored{instance, h.Sum64()}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].score > all[j].score })
+	if len(all) > s.size {
+		all = all[:s.size]
+	}
+
+	subset := make([]string, len(all))
+	for i, sc := range all {
+		subset[i] = sc.instance
+	}
+	sort.Strings(subset)
+	return subset
+}
+
+// subsetRecords returns the records of the instances of the subset.
+func subsetRecords(records map[string]Instance, instances []string) map[string]Instance {
+	if records == nil {
+		return nil
+	}
+	subset := make(map[string]Instance, len(instances))
+	for _, instance := range instances {
+		if r, ok := records[instance]; ok {
+			subset[instance] = r
+		}
+	}
+	return subset
+}
+
+// Register implements Instancer.
+func (s *SubsetInstancer) Register(ch chan<- Event) {
+	s.reg.register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *SubsetInstancer) Deregister(ch chan<- Event) {
+	s.reg.deregister(ch)
+}
+
+// Stop deregisters the SubsetInstancer from the Instancer it subsets.
+func (s *SubsetInstancer) Stop() {
+	s.src.Deregister(s.ch)
+	close(s.ch)
+}
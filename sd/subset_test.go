@@ -0,0 +1,95 @@
+package sd_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+)
+
+func TestSubsetInstancer(t *testing.T) {
+	var (
+		instances []string
+		src       = &mockInstancer{cache: instance.NewCache()}
+		clients   = 100
+		counts    = map[string]int{}
+		subsets   = make([][]string, clients)
+	)
+	for i := 0; i < 50; i++ {
+		instances = append(instances, fmt.Sprintf("10.0.0.%d:8080", i))
+	}
+	src.Update(sd.Event{Instances: instances})
+
+	for i := 0; i < clients; i++ {
+		subset := sd.NewSubsetInstancer(src, fmt.Sprint("client", i), 10)
+		subsets[i] = expectSubset(t, subset, 10)
+		for _, instance := range subsets[i] {
+			counts[instance]++
+		}
+
+		// The subset of a client is deterministic.
+		again := sd.NewSubsetInstancer(src, fmt.Sprint("client", i), 10)
+		if want, have := subsets[i], expectSubset(t, again, 10); !reflect.DeepEqual(want, have) {
+			t.Errorf("client%d: want %v, have %v", i, want, have)
+		}
+		again.Stop()
+		subset.Stop()
+	}
+
+	// Each instance has about 100 * 10 / 50 = 20 clients.
+	for _, instance := range instances {
+		if count := counts[instance]; count < 5 || count > 40 {
+			t.Errorf("%s: %d clients", instance, count)
+		}
+	}
+
+	// Removing an instance which isn't in the subset leaves it unchanged.
+	subset := sd.NewSubsetInstancer(src, "client0", 10)
+	defer subset.Stop()
+	expectSubset(t, subset, 10)
+	ch := make(chan sd.Event, 1)
+	subset.Register(ch)
+	<-ch
+	for i, instance := range instances {
+		if !contains(subsets[0], instance) {
+			src.Update(sd.Event{Instances: append(append([]string{}, instances[:i]...), instances[i+1:]...)})
+			break
+		}
+	}
+	select {
+	case event := <-ch:
+		t.Errorf("want no event, have %v", event)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// expectSubset waits for the subset of the instancer, as it receives the
+// instances asynchronously.
+func expectSubset(t *testing.T, instancer sd.Instancer, size int) []string {
+	deadline := time.Now().Add(time.Second)
+	for {
+		ch := make(chan sd.Event, 1)
+		instancer.Register(ch)
+		event := <-ch
+		instancer.Deregister(ch)
+		if len(event.Instances) == size {
+			return event.Instances
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %d instances, have %v", size, event)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func contains(instances []string, instance string) bool {
+	for _, i := range instances {
+		if i == instance {
+			return true
+		}
+	}
+	return false
+}
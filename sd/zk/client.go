@@ -3,6 +3,7 @@ package zk
 import (
 	"errors"
 	"net"
+	"sort"
 	"strings"
 	"time"
 
@@ -85,6 +86,23 @@ func Credentials(user, pass string) Option {
 	}
 }
 
+// DigestACL returns an Option specifying a user/password combination which
+// the client will use to authenticate itself with, as Credentials, and an
+// ACL granting the perms only to the user, e.g. zk.PermAll, for creating
+// parent nodes, and registering services, so that they're only modified
+// by clients with the credentials. Parent nodes which are to be read by
+// others should be created with an ACL also granting them zk.PermRead.
+func DigestACL(user, pass string, perms int32) Option {
+	return func(c *clientConfig) error {
+		if user == "" || pass == "" {
+			return ErrInvalidCredentials
+		}
+		c.credentials = []byte(user + ":" + pass)
+		c.acl = zk.DigestACL(perms, user, pass)
+		return nil
+	}
+}
+
 // ConnectTimeout returns an Option specifying a non-default connection timeout
 // when we try to establish a connection to a ZooKeeper server.
 func ConnectTimeout(t time.Duration) Option {
@@ -219,6 +237,10 @@ func (c *client) GetEntries(path string) ([]string, <-chan zk.Event, error) {
 		return nil, eventc, err
 	}
 
+	// Registered services are ephemeral-sequential nodes, which are ordered
+	// by their sequence numbers, in the order they were registered.
+	sort.Sort(bySequence(znodes))
+
 	var resp []string
 	for _, znode := range znodes {
 		// retrieve payload for child znode and add to response array
@@ -229,16 +251,20 @@ func (c *client) GetEntries(path string) ([]string, <-chan zk.Event, error) {
 	return resp, eventc, nil
 }
 
-// Register implements the ZooKeeper Client interface.
+// Register implements the ZooKeeper Client interface. The service is
+// registered as an ephemeral-sequential node, under its path, which is
+// deleted once the session of the client ends, and whose sequence number
+// orders it among the services of the path.
 func (c *client) Register(s *Service) error {
 	if s.Path[len(s.Path)-1] != '/' {
 		s.Path += "/"
 	}
-	path := s.Path + s.Name
-	if err := c.CreateParentNodes(path); err != nil {
-		return err
+	if parent := strings.TrimSuffix(s.Path, "/"); parent != "" {
+		if err := c.CreateParentNodes(parent); err != nil {
+			return err
+		}
 	}
-	node, err := c.CreateProtectedEphemeralSequential(path, s.Data, c.acl)
+	node, err := c.CreateProtectedEphemeralSequential(s.Path+s.Name, s.Data, c.acl)
 	if err != nil {
 		return err
 	}
@@ -251,17 +277,17 @@ func (c *client) Deregister(s *Service) error {
 	if s.node == "" {
 		return ErrNotRegistered
 	}
-	path := s.Path + s.Name
-	found, stat, err := c.Exists(path)
+	found, stat, err := c.Exists(s.node)
 	if err != nil {
 		return err
 	}
 	if !found {
 		return ErrNodeNotFound
 	}
-	if err := c.Delete(path, stat.Version); err != nil {
+	if err := c.Delete(s.node, stat.Version); err != nil {
 		return err
 	}
+	s.node = ""
 	return nil
 }
 
@@ -271,3 +297,37 @@ func (c *client) Stop() {
 	close(c.quit)
 	c.Close()
 }
+
+// bySequence sorts znodes by the sequence numbers of ephemeral-sequential
+// nodes, their last 10 digits, and other nodes after them, by name.
+type bySequence []string
+
+func (s bySequence) Len() int      { return len(s) }
+func (s bySequence) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySequence) Less(i, j int) bool {
+	si, oki := sequence(s[i])
+	sj, okj := sequence(s[j])
+	switch {
+	case oki && okj:
+		return si < sj
+	case oki != okj:
+		return oki
+	default:
+		return s[i] < s[j]
+	}
+}
+
+// sequence returns the sequence number of an ephemeral-sequential znode.
+func sequence(znode string) (string, bool) {
+	const digits = 10
+	if len(znode) < digits {
+		return "", false
+	}
+	seq := znode[len(znode)-digits:]
+	for _, r := range seq {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return seq, true
+}
@@ -85,6 +85,16 @@ func TestOptions(t *testing.T) {
 		t.Errorf("want %v, have %v", want, have)
 	}
 
+	_, err = NewClient([]string{"localhost"}, log.NewNopLogger(), DigestACL("valid", "credentials", stdzk.PermAll))
+	if err != nil && err != stdzk.ErrNoServer {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = NewClient([]string{"localhost"}, log.NewNopLogger(), DigestACL("nopass", "", stdzk.PermAll))
+	if want, have := err, ErrInvalidCredentials; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
 	_, err = NewClient([]string{"localhost"}, log.NewNopLogger(), ConnectTimeout(0))
 	if err == nil {
 		t.Errorf("expected connect timeout error")
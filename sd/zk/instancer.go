@@ -1,6 +1,9 @@
 package zk
 
 import (
+	"math/rand"
+	"time"
+
 	"github.com/samuel/go-zookeeper/zk"
 
 	"github.com/go-kit/kit/log"
@@ -11,22 +14,41 @@ import (
 // Instancer yield instances stored in a certain ZooKeeper path. Any kind of
 // change in that path is watched and will update the subscribers.
 type Instancer struct {
-	cache  *instance.Cache
-	client Client
-	path   string
-	logger log.Logger
-	quitc  chan struct{}
+	cache      *instance.Cache
+	client     Client
+	path       string
+	logger     log.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	quitc      chan struct{}
+}
+
+// InstancerOption sets an optional parameter for instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerBackoff sets the backoff of retrievals of the entries after they
+// fail, e.g. as the session expired, or ZooKeeper is unavailable, until the
+// watch is re-established. It doubles from min to max, and each wait is
+// jittered, from half the backoff to all of it. By default, it's from 100ms
+// to 30 seconds.
+func InstancerBackoff(min, max time.Duration) InstancerOption {
+	return func(s *Instancer) { s.minBackoff, s.maxBackoff = min, max }
 }
 
 // NewInstancer returns a ZooKeeper Instancer. ZooKeeper will start watching
 // the given path for changes and update the Instancer endpoints.
-func NewInstancer(c Client, path string, logger log.Logger) (*Instancer, error) {
+func NewInstancer(c Client, path string, logger log.Logger, options ...InstancerOption) (*Instancer, error) {
 	s := &Instancer{
-		cache:  instance.NewCache(),
-		client: c,
-		path:   path,
-		logger: logger,
-		quitc:  make(chan struct{}),
+		cache:      instance.NewCache(),
+		client:     c,
+		path:       path,
+		logger:     logger,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		quitc:      make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
 	}
 
 	err := s.client.CreateParentNodes(s.path)
@@ -52,25 +74,37 @@ func (s *Instancer) loop(eventc <-chan zk.Event) {
 	var (
 		instances []string
 		err       error
+		retryc    <-chan time.Time
+		backoff   = s.minBackoff
 	)
 	for {
 		select {
 		case <-eventc:
-			// We received a path update notification. Call GetEntries to
-			// retrieve child node data, and set a new watch, as ZK watches are
-			// one-time triggers.
-			instances, eventc, err = s.client.GetEntries(s.path)
-			if err != nil {
-				s.logger.Log("path", s.path, "msg", "failed to retrieve entries", "err", err)
-				s.cache.Update(sd.Event{Err: err})
-				continue
-			}
-			s.logger.Log("path", s.path, "instances", len(instances))
-			s.cache.Update(sd.Event{Instances: instances})
-
+			// We received a path update notification, or the watch was
+			// dropped, e.g. as the session expired.
+		case <-retryc:
 		case <-s.quitc:
 			return
 		}
+
+		// Call GetEntries to retrieve child node data, and set a new watch,
+		// as ZK watches are one-time triggers.
+		retryc = nil
+		instances, eventc, err = s.client.GetEntries(s.path)
+		if err != nil {
+			s.logger.Log("path", s.path, "msg", "failed to retrieve entries", "err", err)
+			s.cache.Update(sd.Event{Err: err})
+			// Without entries, there may be no watch, so they're retrieved
+			// again after a backoff, until the watch is re-established.
+			retryc = time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)))
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+			continue
+		}
+		backoff = s.minBackoff
+		s.logger.Log("path", s.path, "instances", len(instances))
+		s.cache.Update(sd.Event{Instances: instances})
 	}
 }
 
@@ -1,9 +1,14 @@
 package zk
 
 import (
+	"reflect"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/samuel/go-zookeeper/zk"
+
 	"github.com/go-kit/kit/sd"
 )
 
@@ -123,3 +128,68 @@ func TestBadInstancerCreate(t *testing.T) {
 		t.Error("expected Instancer not to be created")
 	}
 }
+
+// expiringClient fails to retrieve entries, without a watch, as clients of
+// expired sessions do, until it recovers.
+type expiringClient struct {
+	*fakeClient
+	mtx      sync.Mutex
+	failures int
+	attempts int
+}
+
+func (c *expiringClient) GetEntries(path string) ([]string, <-chan zk.Event, error) {
+	c.mtx.Lock()
+	c.attempts++
+	fail := c.attempts > 1 && c.attempts <= 1+c.failures
+	c.mtx.Unlock()
+	if fail {
+		return nil, nil, zk.ErrSessionExpired
+	}
+	return c.fakeClient.GetEntries(path)
+}
+
+func TestInstancerReestablishesWatch(t *testing.T) {
+	client := &expiringClient{fakeClient: newFakeClient(), failures: 3}
+
+	instancer, err := NewInstancer(client, path, logger, InstancerBackoff(time.Millisecond, 4*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create new Instancer: %v", err)
+	}
+	defer instancer.Stop()
+
+	// The session expires, and the watch is dropped.
+	client.AddService(path+"/instance1", "zookeeper_node_data1")
+
+	deadline := time.Now().Add(time.Second)
+	for len(instancer.state().Instances) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watch wasn't re-established: %v", instancer.state())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if want, have := 5, client.attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
+
+func TestBySequence(t *testing.T) {
+	znodes := []string{
+		"_c_b1-instance0000000010",
+		"config",
+		"_c_a2-instance0000000002",
+		"_c_c3-instance0000000007",
+	}
+	sort.Sort(bySequence(znodes))
+	want := []string{
+		"_c_a2-instance0000000002",
+		"_c_c3-instance0000000007",
+		"_c_b1-instance0000000010",
+		"config",
+	}
+	if !reflect.DeepEqual(want, znodes) {
+		t.Errorf("want %v, have %v", want, znodes)
+	}
+}
@@ -0,0 +1,93 @@
+package sd
+
+// ZoneFunc returns the zone of an instance, e.g. from a label in its
+// metadata, as by ZoneLabel, or from its address. Instancers which don't
+// notify of records yield instances of only their addresses.
+type ZoneFunc func(instance Instance) string
+
+// ZoneLabel returns a ZoneFunc which takes the zone of an instance from the
+// label of its metadata with the key, e.g. "zone". Instances without the
+// label are of no zone, and so are used last.
+func ZoneLabel(key string) ZoneFunc {
+	return func(instance Instance) string { return instance.Metadata[key] }
+}
+
+// ZoneAware returns EndpointerOption that prefers the instances of zones in
+// order, e.g. those of the local zone, then those of other zones of the
+// local region, and spills over to the next zone only when the fraction of
+// the instances of the preferred zones which are available, as they aren't
+// withheld by HealthCheck or OutlierDetection, drops below threshold, e.g.
+// 0.7. Instances of zones which aren't listed are used last, together.
+//
+// For example, with a threshold of 0.7, if 3 of the 10 instances of the
+// local zone are unhealthy, the endpoints of the 7 others are yielded; if 4
+// are, those of the next zone are yielded too.
+func ZoneAware(zone ZoneFunc, threshold float64, zones ...string) EndpointerOption {
+	priorities := make(map[string]int, len(zones))
+	for i, z := range zones {
+		if _, ok := priorities[z]; !ok {
+			priorities[z] = i
+		}
+	}
+	return func(opts *endpointerOptions) {
+		opts.zones = &zonePreference{
+			zone:       zone,
+			threshold:  threshold,
+			priorities: priorities,
+			levels:     len(zones) + 1,
+		}
+	}
+}
+
+type zonePreference struct {
+	zone       ZoneFunc
+	threshold  float64
+	priorities map[string]int
+	levels     int
+}
+
+// prefer returns the instances of the preferred zones, in the order given.
+// Zones are added in order of preference until the fraction of the
+// instances which are available is within the threshold.
+func (z *zonePreference) prefer(instances []string, available map[string]bool, record func(string) Instance) []string {
+	var (
+		byLevel = make([][]string, z.levels)
+		total   = make([]int, z.levels)
+		up      = make([]int, z.levels)
+	)
+	for _, instance := range instances {
+		level, ok := z.priorities[z.zone(record(instance))]
+		if !ok {
+			level = z.levels - 1
+		}
+		byLevel[level] = append(byLevel[level], instance)
+		total[level]++
+		if available[instance] {
+			up[level]++
+		}
+	}
+
+	var (
+		preferred = map[string]bool{}
+		sumTotal  int
+		sumUp     int
+	)
+	for level := range byLevel {
+		for _, instance := range byLevel[level] {
+			preferred[instance] = true
+		}
+		sumTotal += total[level]
+		sumUp += up[level]
+		if sumUp > 0 && float64(sumUp) >= z.threshold*float64(sumTotal) {
+			break
+		}
+	}
+
+	result := make([]string, 0, len(preferred))
+	for _, instance := range instances {
+		if preferred[instance] {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
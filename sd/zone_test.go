@@ -0,0 +1,123 @@
+package sd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+func zoneOf(instance Instance) string { return strings.SplitN(instance.Address, "/", 2)[0] }
+
+func TestZonePreference(t *testing.T) {
+	var (
+		opts      endpointerOptions
+		instances = []string{"a/1", "a/2", "a/3", "b/1", "b/2", "c/1"}
+	)
+	ZoneAware(zoneOf, 0.6, "a", "b")(&opts)
+
+	for _, tc := range []struct {
+		down []string
+		want []string
+	}{
+		{nil, []string{"a/1", "a/2", "a/3"}},
+		{[]string{"a/1"}, []string{"a/1", "a/2", "a/3"}},
+		{[]string{"a/1", "a/2"}, []string{"a/1", "a/2", "a/3", "b/1", "b/2"}},
+		{[]string{"a/1", "a/2", "a/3", "b/1", "b/2"}, instances},
+	} {
+		available := map[string]bool{}
+		for _, instance := range instances {
+			available[instance] = true
+		}
+		for _, instance := range tc.down {
+			available[instance] = false
+		}
+		record := func(instance string) Instance { return Instance{Address: instance} }
+		if have := opts.zones.prefer(instances, available, record); !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("down %v: want %v, have %v", tc.down, tc.want, have)
+		}
+	}
+}
+
+func TestZoneAwareEndpointer(t *testing.T) {
+	var (
+		down = errors.New("down")
+		f    = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) { return instance, nil }, nil, nil
+		}
+		probe = func(_ context.Context, instance string) error {
+			if instance == "a/1" {
+				return down
+			}
+			return nil
+		}
+		endpointer = NewEndpointer(FixedInstancer{"a/1", "a/2", "b/1"}, f, log.NewNopLogger(),
+			HealthCheck(probe, time.Millisecond),
+			ZoneAware(zoneOf, 0.75, "a"),
+		)
+	)
+	defer endpointer.Close()
+
+	// Once a/1 is probed, half of zone a is available, so b is used too.
+	deadline := time.Now().Add(time.Second)
+	for {
+		endpoints, err := endpointer.Endpoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, e := range endpoints {
+			response, _ := e(context.Background(), struct{}{})
+			have = append(have, response.(string))
+		}
+		if reflect.DeepEqual([]string{"a/2", "b/1"}, have) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want [a/2 b/1], have %v", have)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// recordInstancer yields a fixed event, with records.
+type recordInstancer Event
+
+func (r recordInstancer) Register(ch chan<- Event) { ch <- Event(r) }
+func (r recordInstancer) Deregister(chan<- Event)  {}
+
+func TestZoneLabel(t *testing.T) {
+	var (
+		f = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) { return instance, nil }, nil, nil
+		}
+		instancer = recordInstancer{
+			Instances: []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80"},
+			Records: map[string]Instance{
+				"10.0.0.1:80": {Address: "10.0.0.1:80", Metadata: map[string]string{"zone": "us-east-1b"}},
+				"10.0.0.2:80": {Address: "10.0.0.2:80", Metadata: map[string]string{"zone": "us-east-1a"}},
+			},
+		}
+		endpointer = NewEndpointer(instancer, f, log.NewNopLogger(), ZoneAware(ZoneLabel("zone"), 0.5, "us-east-1a"))
+	)
+	defer endpointer.Close()
+
+	endpoints, err := endpointer.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, e := range endpoints {
+		response, _ := e(context.Background(), struct{}{})
+		have = append(have, response.(string))
+	}
+	if want := []string{"10.0.0.2:80"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -4,5 +4,7 @@
 // request, as it travels through multiple services and back to the user.
 // Package tracing provides endpoints and transport helpers and middlewares to
 // capture and emit request-scoped information. We use the excellent OpenTracing
-// project to bind to concrete tracing systems.
+// project to bind to concrete tracing systems, and its successor,
+// OpenTelemetry, in package tracing/opentelemetry. The Samplers of this
+// package decide which requests their middlewares trace, per operation.
 package tracing
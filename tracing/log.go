@@ -0,0 +1,9 @@
+package tracing
+
+// Keys of the IDs of the trace and Span of a request in log records, by which
+// logs and traces are joined. The log helpers of the tracing packages use
+// them.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
@@ -0,0 +1,9 @@
+// Package opentelemetry provides Go kit integration to the OpenTelemetry
+// project. OpenTelemetry is the successor of OpenTracing and OpenCensus, and
+// the standard API for tracing which adapts to all major tracing systems.
+//
+// Endpoint middlewares create the spans of servers and clients, and transport
+// request functions propagate them across services, with a
+// propagation.TextMapPropagator, e.g. propagation.TraceContext for the W3C
+// Trace Context headers.
+package opentelemetry
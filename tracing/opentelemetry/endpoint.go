@@ -0,0 +1,127 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/tracing"
+)
+
+// TraceOption sets an optional parameter for the tracing middlewares.
+type TraceOption func(*traceOptions)
+
+type traceOptions struct {
+	attributes func(context.Context, interface{}) []attribute.KeyValue
+	sampler    tracing.Sampler
+}
+
+// TraceAttributes sets a function which returns attributes of the span of a
+// request, from the context or the request, e.g. the ID of the resource it
+// acts on.
+func TraceAttributes(f func(ctx context.Context, request interface{}) []attribute.KeyValue) TraceOption {
+	return func(o *traceOptions) { o.attributes = f }
+}
+
+// TraceSampler sets the Sampler which decides whether the middlewares start
+// Spans, unless `ctx` already has a recording Span, whose sampling decision
+// holds. Requests which aren't sampled run without a Span, and a remote
+// parent in `ctx` is marked as not sampled, so that the decision propagates.
+// Spans which are started are still subject to the sampler of the
+// TracerProvider, which should sample them, e.g. with sdktrace.AlwaysSample,
+// for this one to decide. By default, the TracerProvider decides.
+func TraceSampler(s tracing.Sampler) TraceOption {
+	return func(o *traceOptions) { o.sampler = s }
+}
+
+// TraceServer returns a Middleware that wraps the `next` Endpoint in an
+// OpenTelemetry Span called `operationName`.
+//
+// If `ctx` already has a recording Span, e.g. started by FromHTTPRequest or
+// FromGRPCRequest, it is re-used and its name is overwritten. If `ctx` does
+// not yet have one, a server Span is started here, as a child of the remote
+// Span in `ctx`, if any. The Span ends with the request; its status is set to
+// Error if the endpoint returns an error.
+func TraceServer(tracer trace.Tracer, operationName string, options ...TraceOption) endpoint.Middleware {
+	o := newTraceOptions(options)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			span := trace.SpanFromContext(ctx)
+			if span.IsRecording() {
+				span.SetName(operationName)
+			} else {
+				if !o.sample(operationName) {
+					return next(unsampled(ctx), request)
+				}
+				ctx, span = tracer.Start(ctx, operationName, trace.WithSpanKind(trace.SpanKindServer))
+			}
+			defer span.End()
+			return o.trace(ctx, span, next, request)
+		}
+	}
+}
+
+// TraceClient returns a Middleware that wraps the `next` Endpoint in an
+// OpenTelemetry client Span called `operationName`, a child of the Span in
+// `ctx`, if any. The Span ends with the request; its status is set to Error
+// if the endpoint returns an error.
+func TraceClient(tracer trace.Tracer, operationName string, options ...TraceOption) endpoint.Middleware {
+	o := newTraceOptions(options)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !trace.SpanFromContext(ctx).IsRecording() && !o.sample(operationName) {
+				return next(unsampled(ctx), request)
+			}
+			ctx, span := tracer.Start(ctx, operationName, trace.WithSpanKind(trace.SpanKindClient))
+			defer span.End()
+			return o.trace(ctx, span, next, request)
+		}
+	}
+}
+
+func newTraceOptions(options []TraceOption) *traceOptions {
+	o := &traceOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// sample reports whether the operation is sampled.
+func (o *traceOptions) sample(operationName string) bool {
+	return o.sampler == nil || o.sampler.Sample(operationName)
+}
+
+// unsampled marks the remote parent in ctx, if any, as not sampled.
+func unsampled(ctx context.Context) context.Context {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, sc.WithTraceFlags(sc.TraceFlags().WithSampled(false)))
+}
+
+// trace calls the endpoint, and records its result in the span. Errors of
+// responses which implement endpoint.Failer are recorded, but as they're
+// business logic errors, they don't set the status.
+func (o *traceOptions) trace(ctx context.Context, span trace.Span, next endpoint.Endpoint, request interface{}) (interface{}, error) {
+	if o.attributes != nil {
+		span.SetAttributes(o.attributes(ctx, request)...)
+	}
+	response, err := next(ctx, request)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if kind := endpoint.KindOf(err); kind != endpoint.KindUnknown {
+			span.SetAttributes(attribute.String("error.kind", kind.String()))
+		}
+		return response, err
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		span.RecordError(f.Failed())
+	}
+	return response, nil
+}
@@ -0,0 +1,155 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/tracing"
+	kitotel "github.com/go-kit/kit/tracing/opentelemetry"
+)
+
+func newTracer() (trace.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return provider.Tracer("test"), recorder
+}
+
+func TestTraceServer(t *testing.T) {
+	tracer, recorder := newTracer()
+
+	// Initialize the ctx with a Span, as FromHTTPRequest does.
+	ctx, span := tracer.Start(context.Background(), "untitled", trace.WithSpanKind(trace.SpanKindServer))
+
+	tracedEndpoint := kitotel.TraceServer(tracer, "testOp")(endpoint.Nop)
+	if _, err := tracedEndpoint(ctx, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Span should have been re-used, renamed, and ended.
+	ended := recorder.Ended()
+	if want, have := 1, len(ended); want != have {
+		t.Fatalf("Want %v span(s), found %v", want, have)
+	}
+	if want, have := span.SpanContext().SpanID(), ended[0].SpanContext().SpanID(); want != have {
+		t.Errorf("Want SpanID %v, have %v", want, have)
+	}
+	if want, have := "testOp", ended[0].Name(); want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+}
+
+func TestTraceServerNoContextSpan(t *testing.T) {
+	tracer, recorder := newTracer()
+
+	// Empty/background context.
+	tracedEndpoint := kitotel.TraceServer(tracer, "testOp")(endpoint.Nop)
+	if _, err := tracedEndpoint(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// tracedEndpoint created a new Span.
+	ended := recorder.Ended()
+	if want, have := 1, len(ended); want != have {
+		t.Fatalf("Want %v span(s), found %v", want, have)
+	}
+	if want, have := "testOp", ended[0].Name(); want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+	if want, have := trace.SpanKindServer, ended[0].SpanKind(); want != have {
+		t.Errorf("Want %v, have %v", want, have)
+	}
+}
+
+func TestTraceClient(t *testing.T) {
+	tracer, recorder := newTracer()
+
+	// Initialize the ctx with a parent Span.
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	defer parent.End()
+
+	failing := func(context.Context, interface{}) (interface{}, error) {
+		return nil, endpoint.Unavailable(errors.New("down"))
+	}
+	tracedEndpoint := kitotel.TraceClient(tracer, "testOp", kitotel.TraceAttributes(
+		func(context.Context, interface{}) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("id", "1")}
+		},
+	))(failing)
+	if _, err := tracedEndpoint(ctx, struct{}{}); err == nil {
+		t.Fatal("want an error")
+	}
+
+	// tracedEndpoint created a new child Span, with the error.
+	ended := recorder.Ended()
+	if want, have := 1, len(ended); want != have {
+		t.Fatalf("Want %v span(s), found %v", want, have)
+	}
+	span := ended[0]
+	if want, have := parent.SpanContext().SpanID(), span.Parent().SpanID(); want != have {
+		t.Errorf("Want ParentID %v, have %v", want, have)
+	}
+	if want, have := trace.SpanKindClient, span.SpanKind(); want != have {
+		t.Errorf("Want %v, have %v", want, have)
+	}
+	if want, have := codes.Error, span.Status().Code; want != have {
+		t.Errorf("Want status %v, have %v", want, have)
+	}
+	want := map[attribute.Key]string{"id": "1", "error.kind": "unavailable"}
+	have := map[attribute.Key]string{}
+	for _, kv := range span.Attributes() {
+		have[kv.Key] = kv.Value.AsString()
+	}
+	for k, v := range want {
+		if have[k] != v {
+			t.Errorf("Want attribute %s=%q, have %q", k, v, have[k])
+		}
+	}
+}
+
+func TestTraceSampler(t *testing.T) {
+	tracer, recorder := newTracer()
+
+	sampler := tracing.NewOperationSampler(tracing.AlwaysSample())
+	sampler.Set("/healthz", tracing.NeverSample())
+
+	// A remote parent, as FromHTTPRequest extracts it.
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+
+	var sampled bool
+	next := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		sampled = trace.SpanContextFromContext(ctx).IsSampled()
+		return nil, nil
+	}
+	for _, op := range []string{"/orders", "/healthz"} {
+		tracedEndpoint := kitotel.TraceServer(tracer, op, kitotel.TraceSampler(sampler))(next)
+		if _, err := tracedEndpoint(ctx, struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := op == "/orders", sampled; want != have {
+			t.Errorf("%s: want sampled %v, have %v", op, want, have)
+		}
+	}
+
+	// Only the sampled operation has a Span.
+	ended := recorder.Ended()
+	if want, have := 1, len(ended); want != have {
+		t.Fatalf("Want %v span(s), found %v", want, have)
+	}
+	if want, have := "/orders", ended[0].Name(); want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,63 @@
+package opentelemetry
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// ToGRPCRequest returns a grpc RequestFunc that injects the OpenTelemetry
+// Span found in `ctx`, e.g. started by TraceClient, into the grpc Metadata
+// with the propagator. If no such Span can be found, nothing is injected.
+func ToGRPCRequest(propagator propagation.TextMapPropagator) func(ctx context.Context, md *metadata.MD) context.Context {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("rpc.system", "grpc"))
+		propagator.Inject(ctx, metadataCarrier{md})
+		return ctx
+	}
+}
+
+// FromGRPCRequest returns a grpc RequestFunc that tries to join with an
+// OpenTelemetry trace extracted from `md` with the propagator, and starts a
+// new server Span called `operationName` accordingly. If no trace could be
+// found in `md`, the Span will be a trace root. The Span is incorporated in
+// the returned Context and can be retrieved with trace.SpanFromContext(ctx);
+// TraceServer ends it.
+func FromGRPCRequest(tracer trace.Tracer, operationName string, propagator propagation.TextMapPropagator) func(ctx context.Context, md metadata.MD) context.Context {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		ctx = propagator.Extract(ctx, metadataCarrier{&md})
+		ctx, _ = tracer.Start(ctx, operationName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("rpc.system", "grpc")),
+		)
+		return ctx
+	}
+}
+
+// A type that conforms to propagation.TextMapCarrier.
+type metadataCarrier struct {
+	*metadata.MD
+}
+
+func (c metadataCarrier) Get(key string) string {
+	if vals := (*c.MD)[strings.ToLower(key)]; len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, val string) {
+	(*c.MD)[strings.ToLower(key)] = []string{val}
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.MD))
+	for k := range *c.MD {
+		keys = append(keys, k)
+	}
+	return keys
+}
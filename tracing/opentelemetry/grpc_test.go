@@ -0,0 +1,44 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+
+	kitotel "github.com/go-kit/kit/tracing/opentelemetry"
+)
+
+func TestTraceGRPCRequestRoundtrip(t *testing.T) {
+	tracer, recorder := newTracer()
+	propagator := propagation.TraceContext{}
+
+	// Initialize the ctx with a Span to inject.
+	beforeCtx, beforeSpan := tracer.Start(context.Background(), "to_inject")
+	defer beforeSpan.End()
+
+	md := metadata.Pairs()
+	afterCtx := kitotel.ToGRPCRequest(propagator)(beforeCtx, &md)
+
+	// The Span should not have changed.
+	if trace.SpanFromContext(afterCtx) != beforeSpan {
+		t.Error("Should not swap in a new span")
+	}
+
+	// Use FromGRPCRequest to verify that we can join with the trace given MD.
+	joinCtx := kitotel.FromGRPCRequest(tracer, "joined", propagator)(context.Background(), md)
+	trace.SpanFromContext(joinCtx).End()
+
+	joinedSpan := recorder.Ended()[0]
+	if want, have := beforeSpan.SpanContext().TraceID(), joinedSpan.SpanContext().TraceID(); want != have {
+		t.Errorf("Want TraceID %v, have %v", want, have)
+	}
+	if want, have := beforeSpan.SpanContext().SpanID(), joinedSpan.Parent().SpanID(); want != have {
+		t.Errorf("Want ParentID %v, have %v", want, have)
+	}
+	if want, have := "joined", joinedSpan.Name(); want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+}
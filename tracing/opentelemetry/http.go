@@ -0,0 +1,73 @@
+package opentelemetry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// ToHTTPRequest returns an http RequestFunc that injects the OpenTelemetry
+// Span found in `ctx`, e.g. started by TraceClient, into the http headers
+// with the propagator, and sets the standard HTTP attributes of the Span. If
+// no such Span can be found, nothing is injected.
+func ToHTTPRequest(propagator propagation.TextMapPropagator) kithttp.RequestFunc {
+	return func(ctx context.Context, req *http.Request) context.Context {
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.String()),
+		)
+		if host, portString, err := net.SplitHostPort(req.URL.Host); err == nil {
+			span.SetAttributes(attribute.String("net.peer.name", host))
+			if port, err := strconv.Atoi(portString); err == nil {
+				span.SetAttributes(attribute.Int("net.peer.port", port))
+			}
+		} else {
+			span.SetAttributes(attribute.String("net.peer.name", req.URL.Host))
+		}
+		propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+		return ctx
+	}
+}
+
+// FromHTTPResponse returns an http ClientResponseFunc that sets the status
+// code of the response on the OpenTelemetry Span found in `ctx`, and the
+// status of the Span to Error if it's 400 or above.
+func FromHTTPResponse() kithttp.ClientResponseFunc {
+	return func(ctx context.Context, resp *http.Response) context.Context {
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+		return ctx
+	}
+}
+
+// FromHTTPRequest returns an http RequestFunc that tries to join with an
+// OpenTelemetry trace extracted from `req` with the propagator, and starts a
+// new server Span called `operationName` accordingly. If no trace could be
+// found in `req`, the Span will be a trace root. The Span is incorporated in
+// the returned Context and can be retrieved with trace.SpanFromContext(ctx);
+// TraceServer ends it.
+func FromHTTPRequest(tracer trace.Tracer, operationName string, propagator propagation.TextMapPropagator) kithttp.RequestFunc {
+	return func(ctx context.Context, req *http.Request) context.Context {
+		ctx = propagator.Extract(ctx, propagation.HeaderCarrier(req.Header))
+		ctx, _ = tracer.Start(ctx, operationName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", req.Method),
+				attribute.String("http.url", req.URL.String()),
+			),
+		)
+		return ctx
+	}
+}
@@ -0,0 +1,83 @@
+package opentelemetry_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	kitotel "github.com/go-kit/kit/tracing/opentelemetry"
+)
+
+func TestTraceHTTPRequestRoundtrip(t *testing.T) {
+	tracer, recorder := newTracer()
+	propagator := propagation.TraceContext{}
+
+	// Initialize the ctx with a Span to inject.
+	beforeCtx, beforeSpan := tracer.Start(context.Background(), "to_inject")
+	defer beforeSpan.End()
+
+	req, _ := http.NewRequest("GET", "http://test.biz:8080/path", nil)
+	afterCtx := kitotel.ToHTTPRequest(propagator)(beforeCtx, req)
+
+	// The Span should not have changed.
+	if trace.SpanFromContext(afterCtx) != beforeSpan {
+		t.Error("Should not swap in a new span")
+	}
+	if req.Header.Get("traceparent") == "" {
+		t.Error("Want a traceparent header")
+	}
+
+	// Use FromHTTPRequest to verify that we can join with the trace given a req.
+	joinCtx := kitotel.FromHTTPRequest(tracer, "joined", propagator)(context.Background(), req)
+	trace.SpanFromContext(joinCtx).End()
+
+	joinedSpan := recorder.Ended()[0]
+	if want, have := beforeSpan.SpanContext().TraceID(), joinedSpan.SpanContext().TraceID(); want != have {
+		t.Errorf("Want TraceID %v, have %v", want, have)
+	}
+	if want, have := beforeSpan.SpanContext().SpanID(), joinedSpan.Parent().SpanID(); want != have {
+		t.Errorf("Want ParentID %v, have %v", want, have)
+	}
+	if want, have := "joined", joinedSpan.Name(); want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+	if want, have := trace.SpanKindServer, joinedSpan.SpanKind(); want != have {
+		t.Errorf("Want %v, have %v", want, have)
+	}
+}
+
+func TestToHTTPRequestAttributes(t *testing.T) {
+	tracer, recorder := newTracer()
+	ctx, span := tracer.Start(context.Background(), "to_inject")
+	req, _ := http.NewRequest("GET", "http://test.biz:8080/path", nil)
+
+	kitotel.ToHTTPRequest(propagation.TraceContext{})(ctx, req)
+	kitotel.FromHTTPResponse()(ctx, &http.Response{StatusCode: http.StatusServiceUnavailable})
+	span.End()
+
+	ended := recorder.Ended()[0]
+	want := []attribute.KeyValue{
+		attribute.String("http.method", "GET"),
+		attribute.String("http.url", "http://test.biz:8080/path"),
+		attribute.String("net.peer.name", "test.biz"),
+		attribute.Int("net.peer.port", 8080),
+		attribute.Int("http.status_code", http.StatusServiceUnavailable),
+	}
+	have := map[attribute.Key]attribute.Value{}
+	for _, kv := range ended.Attributes() {
+		have[kv.Key] = kv.Value
+	}
+	for _, kv := range want {
+		if have[kv.Key] != kv.Value {
+			t.Errorf("Want attribute %s=%v, have %v", kv.Key, kv.Value.Emit(), have[kv.Key].Emit())
+		}
+	}
+	if want, have := codes.Error, ended.Status().Code; want != have {
+		t.Errorf("Want status %v, have %v", want, have)
+	}
+}
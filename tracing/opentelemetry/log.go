@@ -0,0 +1,47 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/tracing"
+)
+
+// TraceID returns a log.Valuer that returns the hex-encoded ID of the trace
+// of the OpenTelemetry Span found in `ctx`, or an empty string if there's
+// none.
+func TraceID(ctx context.Context) log.Valuer {
+	return func() interface{} {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			return sc.TraceID().String()
+		}
+		return ""
+	}
+}
+
+// SpanID returns a log.Valuer that returns the hex-encoded ID of the
+// OpenTelemetry Span found in `ctx`, or an empty string if there's none.
+func SpanID(ctx context.Context) log.Valuer {
+	return func() interface{} {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			return sc.SpanID().String()
+		}
+		return ""
+	}
+}
+
+// WithTraceIDs returns a Logger whose records have the IDs of the trace and
+// the OpenTelemetry Span found in `ctx`, under the keys tracing.TraceIDKey
+// and tracing.SpanIDKey, so that they're joined with the trace, e.g.
+//
+//	logger := opentelemetry.WithTraceIDs(ctx, s.logger)
+//
+// If no such Span can be found, the logger is returned as is.
+func WithTraceIDs(ctx context.Context, logger log.Logger) log.Logger {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return logger
+	}
+	return log.With(logger, tracing.TraceIDKey, TraceID(ctx), tracing.SpanIDKey, SpanID(ctx))
+}
@@ -0,0 +1,38 @@
+package opentelemetry_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-kit/kit/log"
+	kitotel "github.com/go-kit/kit/tracing/opentelemetry"
+)
+
+func TestWithTraceIDs(t *testing.T) {
+	tracer, _ := newTracer()
+	ctx, span := tracer.Start(context.Background(), "test")
+	defer span.End()
+
+	var buf bytes.Buffer
+	logger := kitotel.WithTraceIDs(ctx, log.NewLogfmtLogger(&buf))
+	logger.Log("msg", "hello")
+
+	sc := span.SpanContext()
+	want := "trace_id=" + sc.TraceID().String() + " span_id=" + sc.SpanID().String() + " msg=hello\n"
+	if have := buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestWithTraceIDsNoSpan(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := trace.ContextWithSpan(context.Background(), trace.SpanFromContext(context.Background()))
+	logger := kitotel.WithTraceIDs(ctx, log.NewLogfmtLogger(&buf))
+	logger.Log("msg", "hello")
+	if want, have := "msg=hello\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -7,14 +7,52 @@ import (
 	otext "github.com/opentracing/opentracing-go/ext"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/tracing"
 )
 
+// TraceOption sets an optional parameter for the tracing middlewares.
+type TraceOption func(*traceOptions)
+
+type traceOptions struct {
+	sampler tracing.Sampler
+}
+
+// TraceSampler sets the Sampler which decides whether the Spans are sampled,
+// by setting their sampling.priority tag, which tracers take as a hint:
+// TraceServer asks it for every Span, and TraceClient for Spans without a
+// parent. By default, the tracer decides.
+func TraceSampler(s tracing.Sampler) TraceOption {
+	return func(o *traceOptions) { o.sampler = s }
+}
+
+func newTraceOptions(options []TraceOption) *traceOptions {
+	o := &traceOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// sample sets the sampling priority of the span, if there's a sampler.
+func (o *traceOptions) sample(span opentracing.Span, operationName string) {
+	if o.sampler == nil {
+		return
+	}
+	var priority uint16
+	if o.sampler.Sample(operationName) {
+		priority = 1
+	}
+	otext.SamplingPriority.Set(span, priority)
+}
+
 // TraceServer returns a Middleware that wraps the `next` Endpoint in an
 // OpenTracing Span called `operationName`.
 //
 // If `ctx` already has a Span, it is re-used and the operation name is
-// overwritten. If `ctx` does not yet have a Span, one is created here.
-func TraceServer(tracer opentracing.Tracer, operationName string) endpoint.Middleware {
+// overwritten. If `ctx` does not yet have a Span, one is created here. The
+// Span is tagged with errors as TraceErrors does.
+func TraceServer(tracer opentracing.Tracer, operationName string, options ...TraceOption) endpoint.Middleware {
+	o := newTraceOptions(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
 			serverSpan := opentracing.SpanFromContext(ctx)
@@ -26,15 +64,20 @@ func TraceServer(tracer opentracing.Tracer, operationName string) endpoint.Middl
 			}
 			defer serverSpan.Finish()
 			otext.SpanKindRPCServer.Set(serverSpan)
+			o.sample(serverSpan, operationName)
 			ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-			return next(ctx, request)
+			response, err := next(ctx, request)
+			tagError(serverSpan, response, err)
+			return response, err
 		}
 	}
 }
 
 // TraceClient returns a Middleware that wraps the `next` Endpoint in an
-// OpenTracing Span called `operationName`.
-func TraceClient(tracer opentracing.Tracer, operationName string) endpoint.Middleware {
+// OpenTracing Span called `operationName`. The Span is tagged with errors as
+// TraceErrors does.
+func TraceClient(tracer opentracing.Tracer, operationName string, options ...TraceOption) endpoint.Middleware {
+	o := newTraceOptions(options)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
 			var clientSpan opentracing.Span
@@ -45,11 +88,14 @@ func TraceClient(tracer opentracing.Tracer, operationName string) endpoint.Middl
 				)
 			} else {
 				clientSpan = tracer.StartSpan(operationName)
+				o.sample(clientSpan, operationName)
 			}
 			defer clientSpan.Finish()
 			otext.SpanKindRPCClient.Set(clientSpan)
 			ctx = opentracing.ContextWithSpan(ctx, clientSpan)
-			return next(ctx, request)
+			response, err := next(ctx, request)
+			tagError(clientSpan, response, err)
+			return response, err
 		}
 	}
 }
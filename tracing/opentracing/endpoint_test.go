@@ -8,6 +8,7 @@ import (
 	"github.com/opentracing/opentracing-go/mocktracer"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/tracing"
 	kitot "github.com/go-kit/kit/tracing/opentracing"
 )
 
@@ -115,3 +116,20 @@ func TestTraceClientNoContextSpan(t *testing.T) {
 		t.Fatalf("Want %q, have %q", want, have)
 	}
 }
+
+func TestTraceSampler(t *testing.T) {
+	tracer := mocktracer.New()
+	sampler := tracing.NewOperationSampler(tracing.AlwaysSample())
+	sampler.Set("/healthz", tracing.NeverSample())
+
+	for op, want := range map[string]bool{"/healthz": false, "/orders": true} {
+		tracedEndpoint := kitot.TraceServer(tracer, op, kitot.TraceSampler(sampler))(endpoint.Nop)
+		if _, err := tracedEndpoint(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		spans := tracer.FinishedSpans()
+		if have := spans[len(spans)-1].Context().(mocktracer.MockSpanContext).Sampled; want != have {
+			t.Errorf("%s: want sampled %v, have %v", op, want, have)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package opentracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	otext "github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// TraceErrors returns a Middleware that tags the OpenTracing Span found in
+// `ctx` with the error of the `next` Endpoint, or the business logic error of
+// a response which implements endpoint.Failer, so that the Span isn't left
+// green. The Span is tagged with error=true, and logs an error event with the
+// error's message and class: its endpoint.Kind, if it's known, or else its
+// type. TraceServer and TraceClient do so for the Spans they start; this
+// middleware is for Spans started elsewhere, e.g. by FromHTTPRequest.
+func TraceErrors() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				tagError(span, response, err)
+			}
+			return response, err
+		}
+	}
+}
+
+// tagError tags the span with the error, or the business logic error of the
+// response.
+func tagError(span opentracing.Span, response interface{}, err error) {
+	business := false
+	if err == nil {
+		f, ok := response.(endpoint.Failer)
+		if !ok || f.Failed() == nil {
+			return
+		}
+		err, business = f.Failed(), true
+	}
+	otext.Error.Set(span, true)
+	class := fmt.Sprintf("%T", err)
+	if kind := endpoint.KindOf(err); kind != endpoint.KindUnknown {
+		class = kind.String()
+	}
+	span.SetTag("error.kind", class)
+	span.LogFields(
+		otlog.String("event", "error"),
+		otlog.String("error.kind", class),
+		otlog.String("message", err.Error()),
+		otlog.Bool("business", business),
+	)
+}
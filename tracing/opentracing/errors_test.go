@@ -0,0 +1,80 @@
+package opentracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/go-kit/kit/endpoint"
+	kitot "github.com/go-kit/kit/tracing/opentracing"
+)
+
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
+
+func TestTraceServerError(t *testing.T) {
+	tracer := mocktracer.New()
+
+	failing := func(context.Context, interface{}) (interface{}, error) {
+		return nil, endpoint.Unavailable(errors.New("down"))
+	}
+	tracedEndpoint := kitot.TraceServer(tracer, "testOp")(failing)
+	if _, err := tracedEndpoint(context.Background(), struct{}{}); err == nil {
+		t.Fatal("want an error")
+	}
+
+	span := tracer.FinishedSpans()[0]
+	if want, have := true, span.Tag("error"); want != have {
+		t.Errorf("Want error tag %v, have %v", want, have)
+	}
+	if want, have := "unavailable", span.Tag("error.kind"); want != have {
+		t.Errorf("Want error.kind tag %q, have %q", want, have)
+	}
+	logs := span.Logs()
+	if want, have := 1, len(logs); want != have {
+		t.Fatalf("Want %d log(s), have %d", want, have)
+	}
+	fields := map[string]string{}
+	for _, f := range logs[0].Fields {
+		fields[f.Key] = f.ValueString
+	}
+	for k, want := range map[string]string{"event": "error", "error.kind": "unavailable", "message": "down", "business": "false"} {
+		if have := fields[k]; want != have {
+			t.Errorf("Want log field %s=%q, have %q", k, want, have)
+		}
+	}
+}
+
+func TestTraceErrorsFailer(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("op")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	failed := func(context.Context, interface{}) (interface{}, error) {
+		return failedResponse{errors.New("out of stock")}, nil
+	}
+	if _, err := kitot.TraceErrors()(failed)(ctx, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	span.Finish()
+
+	finished := tracer.FinishedSpans()[0]
+	if want, have := true, finished.Tag("error"); want != have {
+		t.Errorf("Want error tag %v, have %v", want, have)
+	}
+	if want, have := "*errors.errorString", finished.Tag("error.kind"); want != have {
+		t.Errorf("Want error.kind tag %q, have %q", want, have)
+	}
+
+	// Successful requests aren't tagged.
+	span = tracer.StartSpan("ok")
+	kitot.TraceErrors()(endpoint.Nop)(opentracing.ContextWithSpan(context.Background(), span), struct{}{})
+	span.Finish()
+	if tag := tracer.FinishedSpans()[1].Tag("error"); tag != nil {
+		t.Errorf("Want no error tag, have %v", tag)
+	}
+}
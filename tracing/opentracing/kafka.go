@@ -0,0 +1,68 @@
+package opentracing
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ToKafkaRequest returns a Kafka ProducerRequestFunc that injects an
+// OpenTracing Span found in `ctx` into the headers of the record. If no such
+// Span can be found, the ProducerRequestFunc is a noop.
+func ToKafkaRequest(tracer opentracing.Tracer, logger log.Logger) func(ctx context.Context, msg *sarama.ProducerMessage) context.Context {
+	return func(ctx context.Context, msg *sarama.ProducerMessage) context.Context {
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			// There's nothing we can do with an error here.
+			if err := tracer.Inject(span.Context(), opentracing.TextMap, producerHeadersWriter{msg}); err != nil {
+				logger.Log("err", err)
+			}
+		}
+		return ctx
+	}
+}
+
+// FromKafkaRequest returns a Kafka RequestFunc that tries to join with an
+// OpenTracing trace found in the headers of the record, and starts a new
+// Span called `operationName` accordingly. If no trace could be found, the
+// Span will be a trace root. The Span is incorporated in the returned
+// Context and can be retrieved with opentracing.SpanFromContext(ctx).
+func FromKafkaRequest(tracer opentracing.Tracer, operationName string, logger log.Logger) func(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+		wireContext, err := tracer.Extract(opentracing.TextMap, consumerHeadersReader{msg})
+		if err != nil && err != opentracing.ErrSpanContextNotFound {
+			logger.Log("err", err)
+		}
+		span := tracer.StartSpan(operationName, ext.SpanKindConsumer, opentracing.FollowsFrom(wireContext))
+		return opentracing.ContextWithSpan(ctx, span)
+	}
+}
+
+// A type that conforms to opentracing.TextMapWriter.
+type producerHeadersWriter struct {
+	*sarama.ProducerMessage
+}
+
+func (w producerHeadersWriter) Set(key, val string) {
+	w.Headers = append(w.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(val)})
+}
+
+// A type that conforms to opentracing.TextMapReader.
+type consumerHeadersReader struct {
+	*sarama.ConsumerMessage
+}
+
+func (r consumerHeadersReader) ForeachKey(handler func(key, val string) error) error {
+	for _, h := range r.Headers {
+		if h == nil {
+			continue
+		}
+		if err := handler(string(h.Key), string(h.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
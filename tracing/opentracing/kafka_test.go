@@ -0,0 +1,64 @@
+package opentracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/go-kit/kit/log"
+	kitot "github.com/go-kit/kit/tracing/opentracing"
+)
+
+func TestTraceKafkaRequestRoundtrip(t *testing.T) {
+	logger := log.NewNopLogger()
+	tracer := mocktracer.New()
+
+	// Initialize the ctx with a Span to inject.
+	beforeSpan := tracer.StartSpan("to_inject").(*mocktracer.MockSpan)
+	defer beforeSpan.Finish()
+	beforeSpan.SetBaggageItem("baggage", "check")
+	beforeCtx := opentracing.ContextWithSpan(context.Background(), beforeSpan)
+
+	toKafkaFunc := kitot.ToKafkaRequest(tracer, logger)
+	produced := &sarama.ProducerMessage{}
+	afterCtx := toKafkaFunc(beforeCtx, produced)
+
+	// The Span should not have changed.
+	afterSpan := opentracing.SpanFromContext(afterCtx)
+	if beforeSpan != afterSpan {
+		t.Error("Should not swap in a new span")
+	}
+
+	// The record is consumed with the headers it was produced with.
+	consumed := &sarama.ConsumerMessage{}
+	for i := range produced.Headers {
+		consumed.Headers = append(consumed.Headers, &produced.Headers[i])
+	}
+
+	// Use FromKafkaRequest to verify that we can join with the trace given
+	// the headers.
+	fromKafkaFunc := kitot.FromKafkaRequest(tracer, "joined", logger)
+	joinCtx := fromKafkaFunc(context.Background(), consumed)
+	joinedSpan := opentracing.SpanFromContext(joinCtx).(*mocktracer.MockSpan)
+
+	joinedContext := joinedSpan.Context().(mocktracer.MockSpanContext)
+	beforeContext := beforeSpan.Context().(mocktracer.MockSpanContext)
+
+	if joinedContext.SpanID == beforeContext.SpanID {
+		t.Error("SpanID should have changed", joinedContext.SpanID, beforeContext.SpanID)
+	}
+
+	// Check that the parent/child relationship is as expected for the joined span.
+	if want, have := beforeContext.SpanID, joinedSpan.ParentID; want != have {
+		t.Errorf("Want ParentID %v, have %v", want, have)
+	}
+	if want, have := "joined", joinedSpan.OperationName; want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+	if want, have := "check", joinedSpan.BaggageItem("baggage"); want != have {
+		t.Errorf("Want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,89 @@
+package opentracing
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/tracing"
+)
+
+// IDs returns the IDs of the trace and the OpenTracing Span found in `ctx`.
+// OpenTracing doesn't specify how a SpanContext exposes them, so they're
+// found by the conventions of the common tracers: TraceID and SpanID methods,
+// as those of Jaeger and Datadog, or fields, as those of Zipkin and the mock
+// tracer. The IDs are formatted with fmt.Sprint. ok is false if there's no
+// Span, or its IDs can't be found.
+func IDs(ctx context.Context) (traceID, spanID string, ok bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return "", "", false
+	}
+	v := reflect.ValueOf(span.Context())
+	trace, ok := id(v, "TraceID")
+	if !ok {
+		return "", "", false
+	}
+	sp, ok := id(v, "SpanID")
+	if !ok {
+		return "", "", false
+	}
+	return trace, sp, true
+}
+
+// id returns the ID of the SpanContext returned by its method, or held in its
+// field, of the name.
+func id(v reflect.Value, name string) (string, bool) {
+	if m := v.MethodByName(name); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		return fmt.Sprint(m.Call(nil)[0].Interface()), true
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	if f, ok := v.Type().FieldByName(name); !ok || f.PkgPath != "" {
+		return "", false
+	}
+	return fmt.Sprint(v.FieldByName(name).Interface()), true
+}
+
+// TraceID returns a log.Valuer that returns the ID of the trace of the
+// OpenTracing Span found in `ctx`, as IDs finds it, or an empty string.
+func TraceID(ctx context.Context) log.Valuer {
+	return func() interface{} {
+		traceID, _, _ := IDs(ctx)
+		return traceID
+	}
+}
+
+// SpanID returns a log.Valuer that returns the ID of the OpenTracing Span
+// found in `ctx`, as IDs finds it, or an empty string.
+func SpanID(ctx context.Context) log.Valuer {
+	return func() interface{} {
+		_, spanID, _ := IDs(ctx)
+		return spanID
+	}
+}
+
+// WithTraceIDs returns a Logger whose records have the IDs of the trace and
+// the OpenTracing Span found in `ctx`, under the keys tracing.TraceIDKey and
+// tracing.SpanIDKey, so that they're joined with the trace, e.g.
+//
+//	logger := opentracing.WithTraceIDs(ctx, s.logger)
+//
+// If there's no such Span, or its IDs can't be found, the logger is returned
+// as is.
+func WithTraceIDs(ctx context.Context, logger log.Logger) log.Logger {
+	if _, _, ok := IDs(ctx); !ok {
+		return logger
+	}
+	return log.With(logger, tracing.TraceIDKey, TraceID(ctx), tracing.SpanIDKey, SpanID(ctx))
+}
@@ -0,0 +1,63 @@
+package opentracing_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/go-kit/kit/log"
+	kitot "github.com/go-kit/kit/tracing/opentracing"
+)
+
+func TestWithTraceIDs(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("test")
+	defer span.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	var buf bytes.Buffer
+	logger := kitot.WithTraceIDs(ctx, log.NewLogfmtLogger(&buf))
+	logger.Log("msg", "hello")
+
+	sc := span.Context().(mocktracer.MockSpanContext)
+	want := fmt.Sprintf("trace_id=%d span_id=%d msg=hello\n", sc.TraceID, sc.SpanID)
+	if have := buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestWithTraceIDsNoSpan(t *testing.T) {
+	var buf bytes.Buffer
+	logger := kitot.WithTraceIDs(context.Background(), log.NewLogfmtLogger(&buf))
+	logger.Log("msg", "hello")
+	if want, have := "msg=hello\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type methodSpanContext struct{ trace, span uint64 }
+
+func (c methodSpanContext) TraceID() uint64                           { return c.trace }
+func (c methodSpanContext) SpanID() uint64                            { return c.span }
+func (c methodSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+
+type methodSpan struct {
+	opentracing.Span
+	sc methodSpanContext
+}
+
+func (s methodSpan) Context() opentracing.SpanContext { return s.sc }
+func (s methodSpan) Tracer() opentracing.Tracer       { return opentracing.NoopTracer{} }
+
+func TestIDsMethods(t *testing.T) {
+	span := methodSpan{sc: methodSpanContext{trace: 12, span: 34}}
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	traceID, spanID, ok := kitot.IDs(ctx)
+	if !ok || traceID != "12" || spanID != "34" {
+		t.Errorf("want 12, 34, true, have %s, %s, %v", traceID, spanID, ok)
+	}
+}
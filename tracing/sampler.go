@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Sampler decides whether the trace of a request is sampled, by the name of
+// the operation. The tracing middlewares take one as an option.
+type Sampler interface {
+	Sample(operationName string) bool
+}
+
+// SamplerFunc is an adapter to allow the use of an ordinary function as a
+// Sampler.
+type SamplerFunc func(operationName string) bool
+
+// Sample implements Sampler.
+func (f SamplerFunc) Sample(operationName string) bool { return f(operationName) }
+
+// AlwaysSample returns a Sampler which samples every trace.
+func AlwaysSample() Sampler {
+	return SamplerFunc(func(string) bool { return true })
+}
+
+// NeverSample returns a Sampler which samples no trace.
+func NeverSample() Sampler {
+	return SamplerFunc(func(string) bool { return false })
+}
+
+// ProbabilitySampler returns a Sampler which samples traces with the
+// probability p, 0.0 <= p <= 1.0.
+func ProbabilitySampler(p float64) Sampler {
+	var (
+		mtx sync.Mutex
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	)
+	return SamplerFunc(func(string) bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return rnd.Float64() < p
+	})
+}
+
+// RateLimitingSampler returns a Sampler which samples up to perSecond traces
+// per second, with bursts of up to one second's worth, so that the volume of
+// traces is bounded regardless of the load.
+func RateLimitingSampler(perSecond float64) Sampler {
+	var (
+		mtx     sync.Mutex
+		burst   = perSecond
+		credits = perSecond
+		last    = time.Now()
+	)
+	if burst < 1 {
+		burst = 1
+	}
+	return SamplerFunc(func(string) bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		now := time.Now()
+		credits += now.Sub(last).Seconds() * perSecond
+		if credits > burst {
+			credits = burst
+		}
+		last = now
+		if credits < 1 {
+			return false
+		}
+		credits--
+		return true
+	})
+}
+
+// OperationSampler is a Sampler which samples the traces of each operation
+// with the Sampler set for it, if any, or else with a default Sampler, e.g.
+// to always sample /admin, and never sample /healthz. Its Samplers may be
+// changed at runtime.
+type OperationSampler struct {
+	mtx        sync.RWMutex
+	fallback   Sampler
+	operations map[string]Sampler
+}
+
+// NewOperationSampler returns an OperationSampler which samples the traces
+// of operations without a Sampler of their own with fallback.
+func NewOperationSampler(fallback Sampler) *OperationSampler {
+	return &OperationSampler{
+		fallback:   fallback,
+		operations: map[string]Sampler{},
+	}
+}
+
+// Sample implements Sampler.
+func (s *OperationSampler) Sample(operationName string) bool {
+	s.mtx.RLock()
+	sampler, ok := s.operations[operationName]
+	if !ok {
+		sampler = s.fallback
+	}
+	s.mtx.RUnlock()
+	return sampler.Sample(operationName)
+}
+
+// Set sets the Sampler of the operation.
+func (s *OperationSampler) Set(operationName string, sampler Sampler) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.operations[operationName] = sampler
+}
+
+// Delete deletes the Sampler of the operation, which is then sampled with
+// the default Sampler.
+func (s *OperationSampler) Delete(operationName string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.operations, operationName)
+}
+
+// SetDefault sets the default Sampler.
+func (s *OperationSampler) SetDefault(fallback Sampler) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.fallback = fallback
+}
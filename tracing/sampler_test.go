@@ -0,0 +1,62 @@
+package tracing_test
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/tracing"
+)
+
+func TestProbabilitySampler(t *testing.T) {
+	for _, tc := range []struct {
+		p        float64
+		min, max int
+	}{
+		{0, 0, 0},
+		{0.5, 400, 600},
+		{1, 1000, 1000},
+	} {
+		s := tracing.ProbabilitySampler(tc.p)
+		n := 0
+		for i := 0; i < 1000; i++ {
+			if s.Sample("op") {
+				n++
+			}
+		}
+		if n < tc.min || n > tc.max {
+			t.Errorf("p=%v: want between %d and %d sampled, have %d", tc.p, tc.min, tc.max, n)
+		}
+	}
+}
+
+func TestRateLimitingSampler(t *testing.T) {
+	s := tracing.RateLimitingSampler(10)
+	n := 0
+	for i := 0; i < 100; i++ {
+		if s.Sample("op") {
+			n++
+		}
+	}
+	if n < 10 || n > 11 {
+		t.Errorf("want about 10 sampled, have %d", n)
+	}
+}
+
+func TestOperationSampler(t *testing.T) {
+	s := tracing.NewOperationSampler(tracing.ProbabilitySampler(0))
+	s.Set("/admin", tracing.AlwaysSample())
+	s.Set("/healthz", tracing.NeverSample())
+
+	for op, want := range map[string]bool{"/admin": true, "/healthz": false, "/orders": false} {
+		if have := s.Sample(op); want != have {
+			t.Errorf("%s: want %v, have %v", op, want, have)
+		}
+	}
+
+	s.SetDefault(tracing.AlwaysSample())
+	s.Delete("/healthz")
+	for op, want := range map[string]bool{"/healthz": true, "/orders": true} {
+		if have := s.Sample(op); want != have {
+			t.Errorf("%s: want %v, have %v", op, want, have)
+		}
+	}
+}
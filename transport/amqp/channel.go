@@ -0,0 +1,341 @@
+package amqp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Channel is the subset of the methods of an *amqp.Channel used by
+// Subscribers and Publishers. It's implemented by *amqp.Channel, and by the
+// Channels of NewConfirmChannel and NewReconnectingChannel.
+type Channel interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+}
+
+// ConfirmableChannel is a Channel which may be put in confirm mode, such as
+// an *amqp.Channel.
+type ConfirmableChannel interface {
+	Channel
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+}
+
+// ErrNacked is returned by the Publish method of confirming channels when
+// the broker negatively acknowledges a publishing, e.g. when it couldn't
+// persist it.
+var ErrNacked = errors.New("publishing was nacked by the broker")
+
+// NewConfirmChannel puts the channel in confirm mode, and returns a Channel
+// whose Publish method waits for the broker to confirm the publishing. It
+// returns ErrNacked if the publishing is nacked, and amqp.ErrClosed if the
+// channel is closed before it's confirmed. Publishings are serialized, as
+// confirmations arrive in order.
+func NewConfirmChannel(ch ConfirmableChannel) (Channel, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+	return &confirmChannel{
+		Channel:  ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+	}, nil
+}
+
+type confirmChannel struct {
+	Channel
+	mtx      sync.Mutex
+	confirms chan amqp.Confirmation
+}
+
+func (c *confirmChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.Channel.Publish(exchange, key, mandatory, immediate, msg); err != nil {
+		return err
+	}
+	confirm, ok := <-c.confirms
+	if !ok {
+		return amqp.ErrClosed
+	}
+	if !confirm.Ack {
+		return ErrNacked
+	}
+	return nil
+}
+
+// ChannelFunc opens a channel, e.g. on a connection it dials if it's not
+// open, and declares the exchanges, queues, and bindings it uses. The
+// channel may implement NotifyClose and Close, as *amqp.Channel does, so
+// that ReconnectingChannels notice when it fails, and close it.
+type ChannelFunc func() (Channel, error)
+
+// ReconnectOption sets an optional parameter for ReconnectingChannels.
+type ReconnectOption func(*ReconnectingChannel)
+
+// ReconnectBackoff sets the backoff of the attempts to consume again after
+// a failure, which doubles from min to max. By default, it's from 100
+// milliseconds to 10 seconds.
+func ReconnectBackoff(min, max time.Duration) ReconnectOption {
+	return func(r *ReconnectingChannel) { r.minBackoff, r.maxBackoff = min, max }
+}
+
+// ReconnectConfirm puts each channel in confirm mode, with
+// NewConfirmChannel, so that publishing waits for the broker to confirm.
+// The channels must be ConfirmableChannels.
+func ReconnectConfirm() ReconnectOption {
+	return func(r *ReconnectingChannel) { r.confirm = true }
+}
+
+// ReconnectLogger is used to log the failures of channels, and of the
+// attempts to reopen them. By default, they're not logged.
+func ReconnectLogger(logger log.Logger) ReconnectOption {
+	return func(r *ReconnectingChannel) { r.logger = logger }
+}
+
+// ReconnectingChannel is a Channel which opens a new channel with its
+// ChannelFunc when the current one fails, e.g. because the connection was
+// lost, or the broker closed the channel with an exception.
+//
+// A publishing which fails because the channel is closed is published once
+// more, on a new channel. The deliveries of its consumers continue after a
+// failure, from a consumer on a new channel, with backoff between
+// attempts. Deliveries from the failed channel can't be acknowledged, so
+// the broker delivers them again, with Redelivered set.
+type ReconnectingChannel struct {
+	open       ChannelFunc
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	confirm    bool
+	logger     log.Logger
+
+	mtx    sync.Mutex
+	ch     Channel
+	raw    Channel
+	closed bool
+	done   chan struct{}
+}
+
+// NewReconnectingChannel returns a Channel which opens channels with the
+// ChannelFunc. The first is opened on first use.
+func NewReconnectingChannel(open ChannelFunc, options ...ReconnectOption) *ReconnectingChannel {
+	r := &ReconnectingChannel{
+		open:       open,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+		logger:     log.NewNopLogger(),
+		done:       make(chan struct{}),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Publish implements Channel.
+func (r *ReconnectingChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	ch, err := r.current()
+	if err != nil {
+		return err
+	}
+	err = ch.Publish(exchange, key, mandatory, immediate, msg)
+	if _, ok := err.(*amqp.Error); !ok {
+		return err
+	}
+	r.fail(ch, err)
+	if ch, err = r.current(); err != nil {
+		return err
+	}
+	return ch.Publish(exchange, key, mandatory, immediate, msg)
+}
+
+// Consume implements Channel. Errors of the first attempt to consume are
+// returned; after, the deliveries continue across failures of the channel,
+// until the ReconnectingChannel is closed.
+func (r *ReconnectingChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	consume := func(ch Channel) (<-chan amqp.Delivery, error) {
+		return ch.Consume(queue, consumer, autoAck, exclusive, noLocal, noWait, args)
+	}
+	ch, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := consume(ch)
+	if err != nil {
+		r.fail(ch, err)
+		return nil, err
+	}
+	out := make(chan amqp.Delivery)
+	go r.consume(ch, deliveries, out, consume)
+	return out, nil
+}
+
+// Close closes the current channel, and ends the deliveries of consumers.
+func (r *ReconnectingChannel) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	return r.closeRaw()
+}
+
+func (r *ReconnectingChannel) consume(
+	ch Channel,
+	deliveries <-chan amqp.Delivery,
+	out chan<- amqp.Delivery,
+	consume func(Channel) (<-chan amqp.Delivery, error),
+) {
+	defer close(out)
+	backoff := r.minBackoff
+	for {
+		forwarded, open := r.forward(deliveries, out)
+		if !open {
+			return
+		}
+		// Back off from channels which fail as soon as they're consumed.
+		if forwarded {
+			backoff = r.minBackoff
+		} else if !r.wait(&backoff) {
+			return
+		}
+
+		// The deliveries end when the channel fails, or the consumer is
+		// canceled by the broker, e.g. because its queue was deleted.
+		r.fail(ch, nil)
+		for {
+			var err error
+			if ch, err = r.current(); err == nil {
+				if deliveries, err = consume(ch); err == nil {
+					break
+				}
+				r.fail(ch, err)
+			}
+			if err == amqp.ErrClosed && r.isClosed() {
+				return
+			}
+			r.logger.Log("err", err)
+			if !r.wait(&backoff) {
+				return
+			}
+		}
+	}
+}
+
+// wait waits for the backoff, and doubles it, returning false if the
+// ReconnectingChannel is closed first.
+func (r *ReconnectingChannel) wait(backoff *time.Duration) bool {
+	select {
+	case <-time.After(*backoff):
+	case <-r.done:
+		return false
+	}
+	if *backoff *= 2; *backoff > r.maxBackoff {
+		*backoff = r.maxBackoff
+	}
+	return true
+}
+
+// forward forwards the deliveries until they end, returning whether any
+// were forwarded, and false if the ReconnectingChannel is closed first.
+func (r *ReconnectingChannel) forward(deliveries <-chan amqp.Delivery, out chan<- amqp.Delivery) (forwarded, open bool) {
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return forwarded, true
+			}
+			select {
+			case out <- d:
+				forwarded = true
+			case <-r.done:
+				return forwarded, false
+			}
+		case <-r.done:
+			return forwarded, false
+		}
+	}
+}
+
+// current returns the current channel, opening one if there's none.
+func (r *ReconnectingChannel) current() (Channel, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return nil, amqp.ErrClosed
+	}
+	if r.ch != nil {
+		return r.ch, nil
+	}
+
+	raw, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	ch := raw
+	if r.confirm {
+		c, ok := raw.(ConfirmableChannel)
+		if !ok {
+			return nil, errors.New("channel can't be put in confirm mode")
+		}
+		if ch, err = NewConfirmChannel(c); err != nil {
+			closeChannel(raw)
+			return nil, err
+		}
+	}
+	if n, ok := raw.(interface {
+		NotifyClose(chan *amqp.Error) chan *amqp.Error
+	}); ok {
+		notify := n.NotifyClose(make(chan *amqp.Error, 1))
+		go func() {
+			if err, ok := <-notify; ok {
+				r.fail(ch, err)
+			}
+		}()
+	}
+	r.ch, r.raw = ch, raw
+	return ch, nil
+}
+
+// fail discards the channel, if it's still current, so that the next use
+// opens another.
+func (r *ReconnectingChannel) fail(ch Channel, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.ch != ch {
+		return
+	}
+	if err != nil {
+		r.logger.Log("err", err)
+	}
+	r.closeRaw()
+	r.ch, r.raw = nil, nil
+}
+
+func (r *ReconnectingChannel) closeRaw() error {
+	if r.raw == nil {
+		return nil
+	}
+	return closeChannel(r.raw)
+}
+
+func (r *ReconnectingChannel) isClosed() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.closed
+}
+
+func closeChannel(ch Channel) error {
+	if c, ok := ch.(interface {
+		Close() error
+	}); ok {
+		return c.Close()
+	}
+	return nil
+}
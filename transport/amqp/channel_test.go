@@ -0,0 +1,136 @@
+package amqp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	amqptransport "github.com/go-kit/kit/transport/amqp"
+)
+
+// testChannel is a Channel which fails Publish with its error, and may be
+// put in confirm mode, confirming publishings with its ack.
+type testChannel struct {
+	publishErr error
+	published  int
+	deliveries chan amqp.Delivery
+	ack        bool
+	confirms   chan amqp.Confirmation
+	closed     bool
+}
+
+func (c *testChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published++
+	if c.confirms != nil {
+		c.confirms <- amqp.Confirmation{DeliveryTag: uint64(c.published), Ack: c.ack}
+	}
+	return nil
+}
+
+func (c *testChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return c.deliveries, nil
+}
+
+func (c *testChannel) Confirm(noWait bool) error {
+	return nil
+}
+
+func (c *testChannel) NotifyPublish(confirms chan amqp.Confirmation) chan amqp.Confirmation {
+	c.confirms = confirms
+	return confirms
+}
+
+func (c *testChannel) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestConfirmChannel(t *testing.T) {
+	for _, testcase := range []struct {
+		ack  bool
+		want error
+	}{
+		{true, nil},
+		{false, amqptransport.ErrNacked},
+	} {
+		ch, err := amqptransport.NewConfirmChannel(&testChannel{ack: testcase.ack})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := testcase.want, ch.Publish("", "q", false, false, amqp.Publishing{}); want != have {
+			t.Errorf("ack %v: want %v, have %v", testcase.ack, want, have)
+		}
+	}
+}
+
+func TestReconnectingChannelPublish(t *testing.T) {
+	channels := []*testChannel{
+		{publishErr: amqp.ErrClosed},
+		{},
+	}
+	var opened int
+	r := amqptransport.NewReconnectingChannel(func() (amqptransport.Channel, error) {
+		opened++
+		return channels[opened-1], nil
+	})
+	defer r.Close()
+
+	if err := r.Publish("", "q", false, false, amqp.Publishing{}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, opened; want != have {
+		t.Errorf("opened: want %d, have %d", want, have)
+	}
+	if !channels[0].closed {
+		t.Errorf("failed channel wasn't closed")
+	}
+	if want, have := 1, channels[1].published; want != have {
+		t.Errorf("published: want %d, have %d", want, have)
+	}
+}
+
+func TestReconnectingChannelConsume(t *testing.T) {
+	channels := make(chan *testChannel, 2)
+	r := amqptransport.NewReconnectingChannel(
+		func() (amqptransport.Channel, error) { return <-channels, nil },
+		amqptransport.ReconnectBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	first := &testChannel{deliveries: make(chan amqp.Delivery, 1)}
+	second := &testChannel{deliveries: make(chan amqp.Delivery, 1)}
+	channels <- first
+	channels <- second
+
+	deliveries, err := r.Consume("q", "", false, false, false, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.deliveries <- amqp.Delivery{Body: []byte("1")}
+	close(first.deliveries) // the channel fails
+	second.deliveries <- amqp.Delivery{Body: []byte("2")}
+
+	for _, want := range []string{"1", "2"} {
+		select {
+		case d := <-deliveries:
+			if have := string(d.Body); want != have {
+				t.Errorf("want %s, have %s", want, have)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for delivery %s", want)
+		}
+	}
+
+	r.Close()
+	select {
+	case _, ok := <-deliveries:
+		if ok {
+			t.Errorf("deliveries continued after Close")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("deliveries didn't end after Close")
+	}
+}
@@ -0,0 +1,5 @@
+// Package amqp provides an AMQP binding for endpoints, e.g. for RabbitMQ.
+// Subscribers serve endpoints with the deliveries of queues, acknowledging
+// them once they're served, and Publishers call them by publishing to
+// exchanges, optionally waiting for replies with the reply-to RPC pattern.
+package amqp
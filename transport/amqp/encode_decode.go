@@ -0,0 +1,35 @@
+package amqp
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from an AMQP
+// delivery. It's designed to be used in AMQP subscribers, for
+// subscriber-side endpoints. One straightforward DecodeRequestFunc could be
+// something that JSON decodes from the delivery body to the concrete
+// request type.
+type DecodeRequestFunc func(context.Context, *amqp.Delivery) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the AMQP
+// publishing. It's designed to be used in AMQP publishers, for
+// publisher-side endpoints. One straightforward EncodeRequestFunc could be
+// something that JSON encodes the object directly to the publishing body.
+type EncodeRequestFunc func(context.Context, *amqp.Publishing, interface{}) error
+
+// EncodeResponseFunc encodes the passed response object into the AMQP
+// publishing of the reply, which is then published to the reply-to queue
+// of the request. It's designed to be used in AMQP subscribers, for
+// subscriber-side endpoints. One straightforward EncodeResponseFunc could
+// be something that JSON encodes the object directly to the publishing
+// body.
+type EncodeResponseFunc func(context.Context, *amqp.Publishing, interface{}) error
+
+// DecodeResponseFunc extracts a user-domain response object from the AMQP
+// delivery of the reply. It's designed to be used in AMQP publishers, for
+// publisher-side endpoints. One straightforward DecodeResponseFunc could be
+// something that JSON decodes from the delivery body to the concrete
+// response type.
+type DecodeResponseFunc func(context.Context, *amqp.Delivery) (response interface{}, err error)
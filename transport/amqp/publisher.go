@@ -0,0 +1,236 @@
+package amqp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DefaultPublisherTimeout is the timeout of requests, unless it's set with
+// PublisherTimeout.
+const DefaultPublisherTimeout = 10 * time.Second
+
+// DirectReplyTo is the pseudo-queue of RabbitMQ's direct reply-to, which
+// Publishers may consume replies from without declaring a queue. It must be
+// consumed on the channel requests are published on.
+const DirectReplyTo = "amq.rabbitmq.reply-to"
+
+// Publisher wraps an exchange and routing key, and provides a method that
+// implements endpoint.Endpoint, by publishing requests, and optionally
+// waiting for their replies.
+type Publisher struct {
+	ch       Channel
+	exchange string
+	key      string
+	enc      EncodeRequestFunc
+	dec      DecodeResponseFunc
+	before   []PublisherRequestFunc
+	after    []PublisherResponseFunc
+	timeout  time.Duration
+	replyTo  string
+	replies  *replies
+}
+
+// NewPublisher constructs a usable Publisher for a single remote method,
+// which publishes requests to the exchange with the routing key. Publish to
+// the default exchange, "", with the name of a queue as the key, to publish
+// to the queue directly.
+func NewPublisher(
+	ch Channel,
+	exchange string,
+	key string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...PublisherOption,
+) *Publisher {
+	p := &Publisher{
+		ch:       ch,
+		exchange: exchange,
+		key:      key,
+		enc:      enc,
+		dec:      dec,
+		timeout:  DefaultPublisherTimeout,
+		replies:  &replies{waiting: map[string]chan amqp.Delivery{}},
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// PublisherOption sets an optional parameter for publishers.
+type PublisherOption func(*Publisher)
+
+// PublisherBefore sets the PublisherRequestFuncs that are applied to the
+// outgoing publishing before it's published.
+func PublisherBefore(before ...PublisherRequestFunc) PublisherOption {
+	return func(p *Publisher) { p.before = append(p.before, before...) }
+}
+
+// PublisherAfter sets the PublisherResponseFuncs applied to the reply
+// before it's decoded.
+func PublisherAfter(after ...PublisherResponseFunc) PublisherOption {
+	return func(p *Publisher) { p.after = append(p.after, after...) }
+}
+
+// PublisherTimeout sets how long to wait for the reply, by default
+// DefaultPublisherTimeout. The deadline of the request context applies if
+// it's sooner.
+func PublisherTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) { p.timeout = timeout }
+}
+
+// PublisherReplyTo makes the Publisher wait for replies, following the RPC
+// pattern: requests are published with the reply-to queue, and a unique
+// correlation ID, and the reply with the ID is consumed from the queue. The
+// queue may be DirectReplyTo, or an exclusive queue of the Publisher. By
+// default, the Publisher doesn't wait for replies, and its endpoint returns
+// a nil response once the request is published.
+func PublisherReplyTo(queue string) PublisherOption {
+	return func(p *Publisher) { p.replyTo = queue }
+}
+
+// Endpoint returns a usable endpoint that invokes the remote endpoint. If
+// the reply has the ErrorHeader, set by DefaultErrorEncoder, a
+// ResponseError is returned, rather than the reply being decoded. If the
+// channel is a confirming channel, of NewConfirmChannel or
+// ReconnectConfirm, the endpoint fails unless the broker confirms the
+// request.
+func (p Publisher) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+
+		pub := amqp.Publishing{Headers: amqp.Table{}}
+		if err := p.enc(ctx, &pub, request); err != nil {
+			return nil, err
+		}
+
+		for _, f := range p.before {
+			ctx = f(ctx, &pub)
+		}
+
+		if p.replyTo == "" {
+			return nil, p.ch.Publish(p.exchange, p.key, false, false, pub)
+		}
+
+		if err := p.replies.consume(p.ch, p.replyTo); err != nil {
+			return nil, err
+		}
+		pub.ReplyTo = p.replyTo
+		pub.CorrelationId = correlationID()
+		replies := p.replies.wait(pub.CorrelationId)
+		defer p.replies.cancel(pub.CorrelationId)
+
+		if err := p.ch.Publish(p.exchange, p.key, false, false, pub); err != nil {
+			return nil, err
+		}
+
+		var reply amqp.Delivery
+		select {
+		case reply = <-replies:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		for _, f := range p.after {
+			ctx = f(ctx, &reply)
+		}
+
+		if msg, ok := reply.Headers[ErrorHeader].(string); ok {
+			return nil, ResponseError{Message: msg}
+		}
+		return p.dec(ctx, &reply)
+	}
+}
+
+// replies dispatches the replies consumed from the reply-to queue to the
+// requests waiting for them, by correlation ID.
+type replies struct {
+	mtx       sync.Mutex
+	consuming bool
+	waiting   map[string]chan amqp.Delivery
+}
+
+// consume starts consuming the queue, unless it's being consumed. It's
+// consumed again after the deliveries end, e.g. because the channel failed.
+func (r *replies) consume(ch Channel, queue string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.consuming {
+		return nil
+	}
+	deliveries, err := ch.Consume(queue, "", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	r.consuming = true
+	go r.dispatch(deliveries)
+	return nil
+}
+
+func (r *replies) dispatch(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		r.mtx.Lock()
+		c, ok := r.waiting[d.CorrelationId]
+		delete(r.waiting, d.CorrelationId)
+		r.mtx.Unlock()
+		if ok {
+			c <- d
+		}
+	}
+	r.mtx.Lock()
+	r.consuming = false
+	r.mtx.Unlock()
+}
+
+func (r *replies) wait(id string) <-chan amqp.Delivery {
+	c := make(chan amqp.Delivery, 1)
+	r.mtx.Lock()
+	r.waiting[id] = c
+	r.mtx.Unlock()
+	return c
+}
+
+func (r *replies) cancel(id string) {
+	r.mtx.Lock()
+	delete(r.waiting, id)
+	r.mtx.Unlock()
+}
+
+func correlationID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ResponseError is returned by Publishers for replies encoded by
+// DefaultErrorEncoder.
+type ResponseError struct {
+	Message string
+}
+
+// Error implements error.
+func (e ResponseError) Error() string {
+	return e.Message
+}
+
+// EncodeJSONRequest is an EncodeRequestFunc that serializes the request as
+// a JSON object to the body of the publishing. Many JSON-over-AMQP services
+// can use it as a sensible default.
+func EncodeJSONRequest(_ context.Context, pub *amqp.Publishing, request interface{}) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	pub.ContentType = "application/json"
+	pub.Body = b
+	return nil
+}
@@ -0,0 +1,92 @@
+package amqp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	amqptransport "github.com/go-kit/kit/transport/amqp"
+)
+
+func TestPublisherRPC(t *testing.T) {
+	b := newBroker()
+	var header interface{}
+	s := amqptransport.NewSubscriber(sum, decodeTestRequest, amqptransport.EncodeJSONResponse,
+		amqptransport.SubscriberBefore(func(ctx context.Context, d *amqp.Delivery) context.Context {
+			header = d.Headers["x-trace"]
+			return ctx
+		}),
+	)
+	go serve(b, "sum", s)
+
+	p := amqptransport.NewPublisher(b, "", "sum", amqptransport.EncodeJSONRequest, decodeTestResponse,
+		amqptransport.PublisherReplyTo(amqptransport.DirectReplyTo),
+		amqptransport.PublisherBefore(amqptransport.SetPublishingHeader("x-trace", "abc")),
+	)
+	response, err := p.Endpoint()(context.Background(), testRequest{A: 1, B: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, response.(testResponse).Sum; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "abc", header; want != have {
+		t.Errorf("header: want %q, have %v", want, have)
+	}
+}
+
+func TestPublisherResponseError(t *testing.T) {
+	b := newBroker()
+	s := amqptransport.NewSubscriber(
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("dang") },
+		decodeTestRequest,
+		amqptransport.EncodeJSONResponse,
+	)
+	go serve(b, "sum", s)
+
+	p := amqptransport.NewPublisher(b, "", "sum", amqptransport.EncodeJSONRequest, decodeTestResponse,
+		amqptransport.PublisherReplyTo(amqptransport.DirectReplyTo),
+	)
+	_, err := p.Endpoint()(context.Background(), testRequest{A: 1, B: 2})
+	if want, have := (amqptransport.ResponseError{Message: "dang"}), err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestPublisherTimeout(t *testing.T) {
+	b := newBroker()
+	p := amqptransport.NewPublisher(b, "", "sum", amqptransport.EncodeJSONRequest, decodeTestResponse,
+		amqptransport.PublisherReplyTo(amqptransport.DirectReplyTo),
+		amqptransport.PublisherTimeout(10*time.Millisecond),
+	)
+	_, err := p.Endpoint()(context.Background(), testRequest{A: 1, B: 2})
+	if want, have := context.DeadlineExceeded, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestPublisherNoReply(t *testing.T) {
+	b := newBroker()
+	p := amqptransport.NewPublisher(b, "events", "sum", amqptransport.EncodeJSONRequest, nil)
+	response, err := p.Endpoint()(context.Background(), testRequest{A: 1, B: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != nil {
+		t.Errorf("want nil response, have %v", response)
+	}
+
+	d := <-b.queue("sum")
+	if want, have := "events", d.Exchange; want != have {
+		t.Errorf("exchange: want %q, have %q", want, have)
+	}
+	if want, have := `{"a":1,"b":2}`, string(d.Body); want != have {
+		t.Errorf("body: want %s, have %s", want, have)
+	}
+	if d.ReplyTo != "" || d.CorrelationId != "" {
+		t.Errorf("want no reply-to, have %q, %q", d.ReplyTo, d.CorrelationId)
+	}
+}
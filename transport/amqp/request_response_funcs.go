@@ -0,0 +1,54 @@
+package amqp
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// SubscriberRequestFunc may take information from an AMQP delivery and put
+// it into a request context. SubscriberRequestFuncs are executed in
+// subscribers prior to decoding the request.
+type SubscriberRequestFunc func(context.Context, *amqp.Delivery) context.Context
+
+// PublisherRequestFunc may take information from a request context and use
+// it to manipulate the publishing, e.g. its headers. PublisherRequestFuncs
+// are executed in publishers after encoding the request but prior to
+// publishing it.
+type PublisherRequestFunc func(context.Context, *amqp.Publishing) context.Context
+
+// SubscriberResponseFunc may take information from a request context and
+// use it to manipulate the reply, e.g. its headers. SubscriberResponseFuncs
+// are only executed in subscribers, after invoking the endpoint but prior
+// to publishing a reply.
+type SubscriberResponseFunc func(context.Context, *amqp.Publishing) context.Context
+
+// PublisherResponseFunc may take information from an AMQP reply and make
+// the response available for consumption. PublisherResponseFuncs are only
+// executed in publishers, after a reply has been received, but prior to it
+// being decoded.
+type PublisherResponseFunc func(context.Context, *amqp.Delivery) context.Context
+
+// SetPublishingHeader returns a PublisherRequestFunc that sets the given
+// header of the publishing.
+func SetPublishingHeader(key string, val interface{}) PublisherRequestFunc {
+	return func(ctx context.Context, pub *amqp.Publishing) context.Context {
+		if pub.Headers == nil {
+			pub.Headers = amqp.Table{}
+		}
+		pub.Headers[key] = val
+		return ctx
+	}
+}
+
+// SetReplyHeader returns a SubscriberResponseFunc that sets the given
+// header of the reply.
+func SetReplyHeader(key string, val interface{}) SubscriberResponseFunc {
+	return func(ctx context.Context, pub *amqp.Publishing) context.Context {
+		if pub.Headers == nil {
+			pub.Headers = amqp.Table{}
+		}
+		pub.Headers[key] = val
+		return ctx
+	}
+}
@@ -0,0 +1,231 @@
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/streadway/amqp"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and serves AMQP deliveries.
+type Subscriber struct {
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []SubscriberRequestFunc
+	after        []SubscriberResponseFunc
+	errorEncoder ErrorEncoder
+	finalizer    SubscriberFinalizerFunc
+	logger       log.Logger
+	autoAck      bool
+	requeue      bool
+}
+
+// NewSubscriber constructs a new subscriber, which serves deliveries with
+// the provided endpoint.
+func NewSubscriber(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the AMQP delivery before the
+// request is decoded.
+func SubscriberBefore(before ...SubscriberRequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = append(s.before, before...) }
+}
+
+// SubscriberAfter functions are executed on the AMQP reply after the
+// endpoint is invoked, but before the reply is published.
+func SubscriberAfter(after ...SubscriberResponseFunc) SubscriberOption {
+	return func(s *Subscriber) { s.after = append(s.after, after...) }
+}
+
+// SubscriberErrorEncoder is used to encode errors to the reply whenever
+// they're encountered in the processing of a request. Clients can use this
+// to provide custom error formatting. By default, errors will be encoded
+// with the DefaultErrorEncoder.
+func SubscriberErrorEncoder(ee ErrorEncoder) SubscriberOption {
+	return func(s *Subscriber) { s.errorEncoder = ee }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure. Finer-grained
+// control of error handling, including logging in more detail, should be
+// performed in a custom SubscriberErrorEncoder or SubscriberFinalizer, both
+// of which have access to the context.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// SubscriberFinalizer is executed at the end of every delivery.
+// By default, no finalizer is registered.
+func SubscriberFinalizer(f SubscriberFinalizerFunc) SubscriberOption {
+	return func(s *Subscriber) { s.finalizer = f }
+}
+
+// SubscriberRequeue requeues deliveries which fail in the endpoint, so that
+// they're delivered again, to this or another consumer of the queue. By
+// default, they're nacked without being requeued, so that they're
+// dead-lettered, if the queue has a dead letter exchange, or dropped.
+// Deliveries which can't be decoded are never requeued.
+func SubscriberRequeue() SubscriberOption {
+	return func(s *Subscriber) { s.requeue = true }
+}
+
+// SubscriberAutoAck is for deliveries of consumers with autoAck, which the
+// broker considers acknowledged once delivered, so that they're not acked
+// or nacked again. By default, deliveries are acked once they're served,
+// and nacked if they fail.
+func SubscriberAutoAck() SubscriberOption {
+	return func(s *Subscriber) { s.autoAck = true }
+}
+
+// ServeDelivery returns a function which serves the deliveries of a
+// consumer with the endpoint, publishing replies on the channel, e.g.
+//
+//	deliveries, err := ch.Consume("addsvc.sum", "", false, false, false, false, nil)
+//	handle := subscriber.ServeDelivery(ch)
+//	for d := range deliveries {
+//	    handle(&d)
+//	}
+//
+// Deliveries with a reply-to queue are replied to, with their correlation
+// ID, following the RPC pattern; others are served, but not replied to.
+// Deliveries are acked once they're served, and the reply is published, or
+// nacked if they fail. A response implementing endpoint.Failer, whose
+// Failed method returns an error, fails like an error of the endpoint.
+func (s Subscriber) ServeDelivery(ch Channel) func(deliv *amqp.Delivery) {
+	return func(deliv *amqp.Delivery) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		reply := &amqp.Publishing{CorrelationId: deliv.CorrelationId, Headers: amqp.Table{}}
+		if s.finalizer != nil {
+			defer func() { s.finalizer(ctx, deliv) }()
+		}
+
+		for _, f := range s.before {
+			ctx = f(ctx, deliv)
+		}
+
+		request, err := s.dec(ctx, deliv)
+		if err != nil {
+			s.logger.Log("err", err)
+			s.replyError(ctx, err, ch, deliv, reply, false)
+			return
+		}
+
+		response, err := s.e(ctx, request)
+		if err != nil {
+			s.logger.Log("err", err)
+			s.replyError(ctx, err, ch, deliv, reply, s.requeue)
+			return
+		}
+
+		if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+			s.replyError(ctx, f.Failed(), ch, deliv, reply, s.requeue)
+			return
+		}
+
+		for _, f := range s.after {
+			ctx = f(ctx, reply)
+		}
+
+		if err := s.enc(ctx, reply, response); err != nil {
+			s.logger.Log("err", err)
+			s.replyError(ctx, err, ch, deliv, reply, false)
+			return
+		}
+		s.publish(ch, deliv, reply)
+		if !s.autoAck {
+			if err := deliv.Ack(false); err != nil {
+				s.logger.Log("err", err)
+			}
+		}
+	}
+}
+
+func (s Subscriber) replyError(ctx context.Context, err error, ch Channel, deliv *amqp.Delivery, reply *amqp.Publishing, requeue bool) {
+	s.errorEncoder(ctx, err, reply)
+	s.publish(ch, deliv, reply)
+	if !s.autoAck {
+		if err := deliv.Nack(false, requeue); err != nil {
+			s.logger.Log("err", err)
+		}
+	}
+}
+
+func (s Subscriber) publish(ch Channel, deliv *amqp.Delivery, reply *amqp.Publishing) {
+	if deliv.ReplyTo == "" {
+		return
+	}
+	if err := ch.Publish("", deliv.ReplyTo, false, false, *reply); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+// ErrorEncoder is responsible for encoding an error to the reply. Users are
+// encouraged to use custom ErrorEncoders to encode errors to their replies,
+// and will likely want to pass and check for their own error types.
+type ErrorEncoder func(ctx context.Context, err error, reply *amqp.Publishing)
+
+// SubscriberFinalizerFunc can be used to perform work at the end of a
+// delivery, after the reply has been published, and the delivery acked or
+// nacked. The principal intended use is for request logging.
+type SubscriberFinalizerFunc func(ctx context.Context, deliv *amqp.Delivery)
+
+// EncodeJSONResponse is an EncodeResponseFunc that serializes the response
+// as a JSON object to the body of the reply. Many JSON-over-AMQP services
+// can use it as a sensible default.
+func EncodeJSONResponse(_ context.Context, reply *amqp.Publishing, response interface{}) error {
+	b, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	reply.ContentType = "application/json"
+	reply.Body = b
+	return nil
+}
+
+// ErrorHeader is the header of replies which carry an error, set by
+// DefaultErrorEncoder, and checked by Publishers.
+const ErrorHeader = "kit-error"
+
+// DefaultErrorEncoder writes the error to the reply, as a JSON object of
+// the form {"error": "..."}, and sets the ErrorHeader of the reply to the
+// plain text of the error, so that Publishers return it as a
+// ResponseError.
+func DefaultErrorEncoder(_ context.Context, err error, reply *amqp.Publishing) {
+	b, marshalErr := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	if marshalErr != nil {
+		return
+	}
+	reply.ContentType = "application/json"
+	reply.Body = b
+	if reply.Headers == nil {
+		reply.Headers = amqp.Table{}
+	}
+	reply.Headers[ErrorHeader] = err.Error()
+}
@@ -0,0 +1,205 @@
+package amqp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/streadway/amqp"
+
+	amqptransport "github.com/go-kit/kit/transport/amqp"
+)
+
+type testRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type testResponse struct {
+	Sum int `json:"sum"`
+}
+
+func sum(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(testRequest)
+	return testResponse{Sum: req.A + req.B}, nil
+}
+
+func decodeTestRequest(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	var req testRequest
+	err := json.Unmarshal(d.Body, &req)
+	return req, err
+}
+
+func decodeTestResponse(_ context.Context, d *amqp.Delivery) (interface{}, error) {
+	var resp testResponse
+	err := json.Unmarshal(d.Body, &resp)
+	return resp, err
+}
+
+// broker is an in-memory Channel, which routes publishings to the queue
+// named by their routing key, and records acknowledgements.
+type broker struct {
+	mtx    sync.Mutex
+	queues map[string]chan amqp.Delivery
+	tag    uint64
+	acks   []string
+}
+
+func newBroker() *broker {
+	return &broker{queues: map[string]chan amqp.Delivery{}}
+}
+
+func (b *broker) queue(name string) chan amqp.Delivery {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		q = make(chan amqp.Delivery, 16)
+		b.queues[name] = q
+	}
+	return q
+}
+
+func (b *broker) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	b.mtx.Lock()
+	b.tag++
+	tag := b.tag
+	b.mtx.Unlock()
+	b.queue(key) <- amqp.Delivery{
+		Acknowledger:  b,
+		DeliveryTag:   tag,
+		Exchange:      exchange,
+		RoutingKey:    key,
+		Headers:       msg.Headers,
+		ContentType:   msg.ContentType,
+		CorrelationId: msg.CorrelationId,
+		ReplyTo:       msg.ReplyTo,
+		Body:          msg.Body,
+	}
+	return nil
+}
+
+func (b *broker) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return b.queue(queue), nil
+}
+
+func (b *broker) Ack(tag uint64, multiple bool) error {
+	b.record(fmt.Sprintf("ack %d", tag))
+	return nil
+}
+
+func (b *broker) Nack(tag uint64, multiple bool, requeue bool) error {
+	b.record(fmt.Sprintf("nack %d requeue=%v", tag, requeue))
+	return nil
+}
+
+func (b *broker) Reject(tag uint64, requeue bool) error {
+	b.record(fmt.Sprintf("reject %d requeue=%v", tag, requeue))
+	return nil
+}
+
+func (b *broker) record(ack string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.acks = append(b.acks, ack)
+}
+
+func (b *broker) recorded() []string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]string(nil), b.acks...)
+}
+
+// serve serves the deliveries of the queue published to the broker.
+func serve(b *broker, queue string, s *amqptransport.Subscriber) {
+	d := <-b.queue(queue)
+	s.ServeDelivery(b)(&d)
+}
+
+func TestSubscriberReply(t *testing.T) {
+	b := newBroker()
+	s := amqptransport.NewSubscriber(sum, decodeTestRequest, amqptransport.EncodeJSONResponse,
+		amqptransport.SubscriberAfter(amqptransport.SetReplyHeader("x-served", "yes")),
+	)
+
+	b.Publish("", "sum", false, false, amqp.Publishing{
+		CorrelationId: "42",
+		ReplyTo:       "replies",
+		Body:          []byte(`{"a":1,"b":2}`),
+	})
+	serve(b, "sum", s)
+
+	reply := <-b.queue("replies")
+	if want, have := "42", reply.CorrelationId; want != have {
+		t.Errorf("correlation ID: want %q, have %q", want, have)
+	}
+	if want, have := "yes", reply.Headers["x-served"]; want != have {
+		t.Errorf("header: want %q, have %v", want, have)
+	}
+	if want, have := `{"sum":3}`, string(reply.Body); want != have {
+		t.Errorf("body: want %s, have %s", want, have)
+	}
+	if want, have := []string{"ack 1"}, b.recorded(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestSubscriberNoReplyTo(t *testing.T) {
+	b := newBroker()
+	var served int
+	s := amqptransport.NewSubscriber(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			served++
+			return sum(ctx, request)
+		},
+		decodeTestRequest,
+		amqptransport.EncodeJSONResponse,
+	)
+
+	b.Publish("", "sum", false, false, amqp.Publishing{Body: []byte(`{"a":1,"b":2}`)})
+	serve(b, "sum", s)
+
+	if want, have := 1, served; want != have {
+		t.Errorf("served: want %d, have %d", want, have)
+	}
+	if want, have := []string{"ack 1"}, b.recorded(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestSubscriberNack(t *testing.T) {
+	failing := func(context.Context, interface{}) (interface{}, error) {
+		return nil, errors.New("dang")
+	}
+	for _, testcase := range []struct {
+		name     string
+		body     string
+		options  []amqptransport.SubscriberOption
+		wantAcks []string
+	}{
+		{"endpoint error", `{}`, nil, []string{"nack 1 requeue=false"}},
+		{"endpoint error requeued", `{}`, []amqptransport.SubscriberOption{amqptransport.SubscriberRequeue()}, []string{"nack 1 requeue=true"}},
+		{"decode error", `x`, []amqptransport.SubscriberOption{amqptransport.SubscriberRequeue()}, []string{"nack 1 requeue=false"}},
+		{"auto ack", `{}`, []amqptransport.SubscriberOption{amqptransport.SubscriberAutoAck()}, nil},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			b := newBroker()
+			s := amqptransport.NewSubscriber(failing, decodeTestRequest, amqptransport.EncodeJSONResponse, testcase.options...)
+
+			b.Publish("", "sum", false, false, amqp.Publishing{ReplyTo: "replies", Body: []byte(testcase.body)})
+			serve(b, "sum", s)
+
+			reply := <-b.queue("replies")
+			if _, ok := reply.Headers[amqptransport.ErrorHeader].(string); !ok {
+				t.Errorf("reply without %s header", amqptransport.ErrorHeader)
+			}
+			if want, have := testcase.wantAcks, b.recorded(); !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
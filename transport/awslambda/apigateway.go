@@ -0,0 +1,205 @@
+package awslambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type contextKey int
+
+const (
+	// ContextKeyProxyRequest is populated in the context of the requests of
+	// HTTPHandlers with the *events.APIGatewayProxyRequest of the event, for
+	// REST APIs, and HTTP APIs of payload format 1.0.
+	ContextKeyProxyRequest contextKey = iota
+
+	// ContextKeyV2HTTPRequest is populated in the context of the requests of
+	// HTTPHandlers with the *events.APIGatewayV2HTTPRequest of the event, for
+	// HTTP APIs of payload format 2.0.
+	ContextKeyV2HTTPRequest
+)
+
+// HTTPHandler adapts an http.Handler, such as a Server of package
+// transport/http, to the events of API Gateway, and implements
+// lambda.Handler of package github.com/aws/aws-lambda-go/lambda. It serves
+// the events of REST APIs, with the Lambda proxy integration, and of HTTP
+// APIs, of payload formats 1.0 and 2.0, as HTTP requests, and returns the
+// HTTP responses as the responses API Gateway expects.
+//
+// The context of the requests has the event, by ContextKeyProxyRequest or
+// ContextKeyV2HTTPRequest. Bodies which aren't valid UTF-8 are base64
+// encoded in the responses; so that API Gateway decodes them, binary media
+// types must be enabled for REST APIs.
+type HTTPHandler struct {
+	h http.Handler
+}
+
+// NewHTTPHandler returns an HTTPHandler serving API Gateway events with h.
+func NewHTTPHandler(h http.Handler) *HTTPHandler {
+	return &HTTPHandler{h: h}
+}
+
+// Invoke implements lambda.Handler.
+func (h *HTTPHandler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(payload, &version); err != nil {
+		return nil, err
+	}
+	if version.Version == "2.0" {
+		return h.invokeV2(ctx, payload)
+	}
+	return h.invokeProxy(ctx, payload)
+}
+
+func (h *HTTPHandler) invokeProxy(ctx context.Context, payload []byte) ([]byte, error) {
+	var event events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for k, vs := range event.MultiValueQueryStringParameters {
+		query[k] = vs
+	}
+	for k, v := range event.QueryStringParameters {
+		if _, ok := query[k]; !ok {
+			query.Set(k, v)
+		}
+	}
+	header := http.Header{}
+	for k, vs := range event.MultiValueHeaders {
+		for _, v := range vs {
+			header.Add(k, v)
+		}
+	}
+	for k, v := range event.Headers {
+		if _, ok := header[http.CanonicalHeaderKey(k)]; !ok {
+			header.Set(k, v)
+		}
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyProxyRequest, &event)
+	r, err := newRequest(ctx, event.HTTPMethod, event.Path, query.Encode(), header, event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = event.RequestContext.Identity.SourceIP
+
+	w := newResponseWriter()
+	h.h.ServeHTTP(w, r)
+
+	body, isBase64 := w.encodedBody()
+	return json.Marshal(events.APIGatewayProxyResponse{
+		StatusCode:        w.status,
+		MultiValueHeaders: w.header,
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+	})
+}
+
+func (h *HTTPHandler) invokeV2(ctx context.Context, payload []byte) ([]byte, error) {
+	var event events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	for k, v := range event.Headers {
+		header.Set(k, v)
+	}
+	if len(event.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyV2HTTPRequest, &event)
+	r, err := newRequest(ctx, event.RequestContext.HTTP.Method, event.RawPath, event.RawQueryString, header, event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = event.RequestContext.HTTP.SourceIP
+
+	w := newResponseWriter()
+	h.h.ServeHTTP(w, r)
+
+	// Cookies are returned separately, as header values are joined.
+	cookies := w.header["Set-Cookie"]
+	delete(w.header, "Set-Cookie")
+	headers := make(map[string]string, len(w.header))
+	for k, vs := range w.header {
+		headers[k] = strings.Join(vs, ",")
+	}
+	body, isBase64 := w.encodedBody()
+	return json.Marshal(events.APIGatewayV2HTTPResponse{
+		StatusCode:      w.status,
+		Headers:         headers,
+		Body:            body,
+		IsBase64Encoded: isBase64,
+		Cookies:         cookies,
+	})
+}
+
+// newRequest returns the HTTP request of an event.
+func newRequest(ctx context.Context, method, path, rawQuery string, header http.Header, body string, isBase64 bool) (*http.Request, error) {
+	b := []byte(body)
+	if isBase64 {
+		var err error
+		if b, err = base64.StdEncoding.DecodeString(body); err != nil {
+			return nil, err
+		}
+	}
+	u := &url.URL{Path: path, RawQuery: rawQuery}
+	r, err := http.NewRequest(method, u.String(), ioutil.NopCloser(bytes.NewReader(b)))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header
+	r.Host = header.Get("Host")
+	r.RequestURI = u.RequestURI()
+	r.ContentLength = int64(len(b))
+	return r.WithContext(ctx), nil
+}
+
+// responseWriter is an http.ResponseWriter recording the response.
+type responseWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{header: http.Header{}, status: http.StatusOK}
+}
+
+func (w *responseWriter) Header() http.Header { return w.header }
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status, w.wroteHeader = status, true
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// encodedBody returns the body, base64 encoded if it isn't valid UTF-8.
+func (w *responseWriter) encodedBody() (string, bool) {
+	if utf8.Valid(w.body.Bytes()) {
+		return w.body.String(), false
+	}
+	return base64.StdEncoding.EncodeToString(w.body.Bytes()), true
+}
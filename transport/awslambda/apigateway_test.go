@@ -0,0 +1,102 @@
+package awslambda_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/go-kit/kit/transport/awslambda"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// newSumServer returns a Server of package transport/http as it could run
+// on a server, taking the addends from the query.
+func newSumServer() http.Handler {
+	return httptransport.NewServer(
+		sum,
+		func(_ context.Context, r *http.Request) (interface{}, error) {
+			var req testRequest
+			err := json.Unmarshal([]byte(r.URL.Query().Get("q")), &req)
+			return req, err
+		},
+		func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+			w.Header().Set("Set-Cookie", "a=1")
+			w.Header().Add("Set-Cookie", "b=2")
+			return httptransport.EncodeJSONResponse(ctx, w, response)
+		},
+	)
+}
+
+func TestHTTPHandlerProxy(t *testing.T) {
+	h := awslambda.NewHTTPHandler(newSumServer())
+	payload, _ := json.Marshal(events.APIGatewayProxyRequest{
+		HTTPMethod:            "GET",
+		Path:                  "/sum",
+		QueryStringParameters: map[string]string{"q": `{"a":1,"b":2}`},
+		Headers:               map[string]string{"Accept": "application/json"},
+	})
+	resp, err := h.Invoke(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response events.APIGatewayProxyResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := http.StatusOK, response.StatusCode; want != have {
+		t.Errorf("status: want %d, have %d", want, have)
+	}
+	if want, have := `{"sum":3}`+"\n", response.Body; want != have {
+		t.Errorf("body: want %q, have %q", want, have)
+	}
+	if want, have := []string{"a=1", "b=2"}, response.MultiValueHeaders["Set-Cookie"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("cookies: want %v, have %v", want, have)
+	}
+}
+
+func TestHTTPHandlerV2(t *testing.T) {
+	h := awslambda.NewHTTPHandler(newSumServer())
+	event := events.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RawPath:        "/sum",
+		RawQueryString: `q={"a":-1}`,
+	}
+	event.RequestContext.HTTP.Method = "GET"
+	payload, _ := json.Marshal(event)
+	resp, err := h.Invoke(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response events.APIGatewayV2HTTPResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := http.StatusInternalServerError, response.StatusCode; want != have {
+		t.Errorf("status: want %d, have %d", want, have)
+	}
+	if want, have := "negative", response.Body; want != have {
+		t.Errorf("body: want %q, have %q", want, have)
+	}
+
+	event.RawQueryString = `q={"a":1,"b":2}`
+	payload, _ = json.Marshal(event)
+	if resp, err = h.Invoke(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	response = events.APIGatewayV2HTTPResponse{}
+	if err := json.Unmarshal(resp, &response); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"a=1", "b=2"}, response.Cookies; !reflect.DeepEqual(want, have) {
+		t.Errorf("cookies: want %v, have %v", want, have)
+	}
+	if want, have := "application/json; charset=utf-8", response.Headers["Content-Type"]; want != have {
+		t.Errorf("content type: want %q, have %q", want, have)
+	}
+}
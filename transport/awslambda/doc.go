@@ -0,0 +1,6 @@
+// Package awslambda provides an AWS Lambda binding for endpoints. Handlers
+// serve endpoints with the payloads of direct invocations, and HTTPHandlers
+// serve the events of API Gateway with an http.Handler, such as a Server of
+// package transport/http, so that the same endpoints and middlewares run
+// serverless, or on a server.
+package awslambda
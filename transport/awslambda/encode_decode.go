@@ -0,0 +1,25 @@
+package awslambda
+
+import (
+	"context"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from the payload
+// of an AWS Lambda invocation. It's designed to be used in Handlers, for
+// server-side endpoints. One straightforward DecodeRequestFunc could be
+// something that JSON decodes the payload to the concrete request type.
+type DecodeRequestFunc func(context.Context, []byte) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the payload of
+// the response of an AWS Lambda invocation. It's designed to be used in
+// Handlers, for server-side endpoints. One straightforward
+// EncodeResponseFunc could be something that JSON encodes the object
+// directly.
+type EncodeResponseFunc func(context.Context, interface{}) (response []byte, err error)
+
+// ErrorEncoder is responsible for encoding an error to the payload of the
+// response of an AWS Lambda invocation, or returning it, so that the
+// invocation fails with a function error. Users are encouraged to use
+// custom ErrorEncoders to encode errors to their responses, and will likely
+// want to pass and check for their own error types.
+type ErrorEncoder func(ctx context.Context, err error) (response []byte, returnErr error)
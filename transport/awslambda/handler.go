@@ -0,0 +1,127 @@
+package awslambda
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Handler wraps an endpoint, and implements lambda.Handler of package
+// github.com/aws/aws-lambda-go/lambda, so that it serves the payloads of
+// direct invocations, e.g. with lambda.StartHandler(handler).
+type Handler struct {
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []HandlerRequestFunc
+	after        []HandlerResponseFunc
+	errorEncoder ErrorEncoder
+	finalizer    HandlerFinalizerFunc
+	logger       log.Logger
+}
+
+// NewHandler constructs a new handler, which implements lambda.Handler and
+// wraps the provided endpoint.
+func NewHandler(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...HandlerOption,
+) *Handler {
+	h := &Handler{
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// HandlerOption sets an optional parameter for handlers.
+type HandlerOption func(*Handler)
+
+// HandlerBefore functions are executed on the payload of the invocation
+// before the request is decoded.
+func HandlerBefore(before ...HandlerRequestFunc) HandlerOption {
+	return func(h *Handler) { h.before = append(h.before, before...) }
+}
+
+// HandlerAfter functions are executed on the response after the endpoint is
+// invoked, but before it's encoded.
+func HandlerAfter(after ...HandlerResponseFunc) HandlerOption {
+	return func(h *Handler) { h.after = append(h.after, after...) }
+}
+
+// HandlerErrorEncoder is used to encode errors to the response whenever
+// they're encountered in the processing of an invocation. Clients can use
+// this to provide custom error formatting. By default, errors are returned
+// by the DefaultErrorEncoder, so that the invocation fails.
+func HandlerErrorEncoder(ee ErrorEncoder) HandlerOption {
+	return func(h *Handler) { h.errorEncoder = ee }
+}
+
+// HandlerErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure. Finer-grained
+// control of error handling, including logging in more detail, should be
+// performed in a custom HandlerErrorEncoder or HandlerFinalizer, both of
+// which have access to the context.
+func HandlerErrorLogger(logger log.Logger) HandlerOption {
+	return func(h *Handler) { h.logger = logger }
+}
+
+// HandlerFinalizer is executed at the end of every invocation. By default,
+// no finalizer is registered.
+func HandlerFinalizer(f HandlerFinalizerFunc) HandlerOption {
+	return func(h *Handler) { h.finalizer = f }
+}
+
+// Invoke implements lambda.Handler. A response implementing
+// endpoint.Failer, whose Failed method returns an error, is encoded with
+// the error encoder.
+func (h *Handler) Invoke(ctx context.Context, payload []byte) (resp []byte, err error) {
+	if h.finalizer != nil {
+		defer func() { h.finalizer(ctx, resp, err) }()
+	}
+
+	for _, f := range h.before {
+		ctx = f(ctx, payload)
+	}
+
+	request, err := h.dec(ctx, payload)
+	if err != nil {
+		h.logger.Log("err", err)
+		return h.errorEncoder(ctx, err)
+	}
+
+	response, err := h.e(ctx, request)
+	if err != nil {
+		h.logger.Log("err", err)
+		return h.errorEncoder(ctx, err)
+	}
+
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return h.errorEncoder(ctx, f.Failed())
+	}
+
+	for _, f := range h.after {
+		ctx = f(ctx, response)
+	}
+
+	if resp, err = h.enc(ctx, response); err != nil {
+		h.logger.Log("err", err)
+		return h.errorEncoder(ctx, err)
+	}
+	return resp, nil
+}
+
+// DefaultErrorEncoder returns the error, so that the invocation fails with
+// a function error, which AWS Lambda reports to the invoker with the
+// message and type of the error.
+func DefaultErrorEncoder(_ context.Context, err error) ([]byte, error) {
+	return nil, err
+}
@@ -0,0 +1,79 @@
+package awslambda_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/transport/awslambda"
+)
+
+type testRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type testResponse struct {
+	Sum int `json:"sum"`
+}
+
+func sum(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(testRequest)
+	if req.A < 0 {
+		return nil, errors.New("negative")
+	}
+	return testResponse{Sum: req.A + req.B}, nil
+}
+
+func decodeTestRequest(_ context.Context, payload []byte) (interface{}, error) {
+	var req testRequest
+	err := json.Unmarshal(payload, &req)
+	return req, err
+}
+
+func encodeJSONResponse(_ context.Context, response interface{}) ([]byte, error) {
+	return json.Marshal(response)
+}
+
+func TestHandler(t *testing.T) {
+	var finalized []string
+	h := awslambda.NewHandler(sum, decodeTestRequest, encodeJSONResponse,
+		awslambda.HandlerFinalizer(func(_ context.Context, resp []byte, err error) {
+			finalized = append(finalized, string(resp))
+		}),
+	)
+	resp, err := h.Invoke(context.Background(), []byte(`{"a":1,"b":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"sum":3}`, string(resp); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 1, len(finalized); want != have {
+		t.Errorf("finalized: want %d, have %d", want, have)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := awslambda.NewHandler(sum, decodeTestRequest, encodeJSONResponse)
+	if _, err := h.Invoke(context.Background(), []byte(`{"a":-1}`)); err == nil || err.Error() != "negative" {
+		t.Errorf("want negative error, have %v", err)
+	}
+	if _, err := h.Invoke(context.Background(), []byte(`x`)); err == nil {
+		t.Error("want decode error, have none")
+	}
+
+	h = awslambda.NewHandler(sum, decodeTestRequest, encodeJSONResponse,
+		awslambda.HandlerErrorEncoder(func(_ context.Context, err error) ([]byte, error) {
+			return json.Marshal(map[string]string{"error": err.Error()})
+		}),
+	)
+	resp, err := h.Invoke(context.Background(), []byte(`{"a":-1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"error":"negative"}`, string(resp); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
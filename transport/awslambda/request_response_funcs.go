@@ -0,0 +1,20 @@
+package awslambda
+
+import (
+	"context"
+)
+
+// HandlerRequestFunc may take information from the payload of an AWS Lambda
+// invocation and put it into a request context. HandlerRequestFuncs are
+// executed prior to decoding the request.
+type HandlerRequestFunc func(ctx context.Context, payload []byte) context.Context
+
+// HandlerResponseFunc may take information from a request context and use
+// it to manipulate the response, before it's encoded. HandlerResponseFuncs
+// are executed after invoking the endpoint.
+type HandlerResponseFunc func(ctx context.Context, response interface{}) context.Context
+
+// HandlerFinalizerFunc can be used to perform work at the end of an AWS
+// Lambda invocation, given its response and error. The principal intended
+// use is for request logging.
+type HandlerFinalizerFunc func(ctx context.Context, resp []byte, err error)
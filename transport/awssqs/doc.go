@@ -0,0 +1,4 @@
+// Package awssqs provides an Amazon SQS binding for endpoints. Subscribers
+// serve endpoints with the messages of queues, deleting them once they're
+// served.
+package awssqs
@@ -0,0 +1,14 @@
+package awssqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from an SQS
+// message. It's designed to be used in SQS subscribers, for
+// subscriber-side endpoints. One straightforward DecodeRequestFunc could be
+// something that JSON decodes from the message body to the concrete
+// request type.
+type DecodeRequestFunc func(context.Context, *sqs.Message) (request interface{}, err error)
@@ -0,0 +1,21 @@
+package awssqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// RequestFunc may take information from an SQS message and put it into a
+// request context, e.g. from its message attributes. RequestFuncs are
+// executed prior to decoding the request.
+type RequestFunc func(context.Context, *sqs.Message) context.Context
+
+// MessageAttribute returns the string value of the given message attribute
+// of the message, or "".
+func MessageAttribute(msg *sqs.Message, name string) string {
+	if v, ok := msg.MessageAttributes[name]; ok && v != nil && v.StringValue != nil {
+		return *v.StringValue
+	}
+	return ""
+}
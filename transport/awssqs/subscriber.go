@@ -0,0 +1,303 @@
+package awssqs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	// DefaultSubscriberWaitTime is how long receives wait for messages,
+	// unless it's set with SubscriberWaitTime. It's the maximum SQS allows.
+	DefaultSubscriberWaitTime = 20 * time.Second
+
+	// DefaultSubscriberMaxMessages is how many messages are received at
+	// most at once, unless it's set with SubscriberMaxMessages. It's the
+	// maximum SQS allows.
+	DefaultSubscriberMaxMessages = 10
+)
+
+// Subscriber wraps an endpoint, and serves the messages of an SQS queue.
+type Subscriber struct {
+	svc         sqsiface.SQSAPI
+	queueURL    string
+	e           endpoint.Endpoint
+	dec         DecodeRequestFunc
+	before      []RequestFunc
+	finalizer   SubscriberFinalizerFunc
+	logger      log.Logger
+	concurrency int
+	waitTime    time.Duration
+	maxMessages int64
+	visibility  []time.Duration
+	dlqURL      string
+	maxReceives int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewSubscriber constructs a new subscriber, which serves the messages of
+// the queue with the provided endpoint.
+func NewSubscriber(
+	svc sqsiface.SQSAPI,
+	queueURL string,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		svc:         svc,
+		queueURL:    queueURL,
+		e:           e,
+		dec:         dec,
+		logger:      log.NewNopLogger(),
+		concurrency: 1,
+		waitTime:    DefaultSubscriberWaitTime,
+		maxMessages: DefaultSubscriberMaxMessages,
+		minBackoff:  time.Second,
+		maxBackoff:  time.Minute,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the SQS message before the
+// request is decoded.
+func SubscriberBefore(before ...RequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = append(s.before, before...) }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure. Finer-grained
+// control of error handling, including logging in more detail, should be
+// performed in a custom SubscriberFinalizer, which has access to the
+// context.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// SubscriberFinalizer is executed at the end of every message, with the
+// error with which it failed, if any. By default, no finalizer is
+// registered.
+func SubscriberFinalizer(f SubscriberFinalizerFunc) SubscriberOption {
+	return func(s *Subscriber) { s.finalizer = f }
+}
+
+// SubscriberConcurrency sets how many messages are served concurrently. By
+// default, they're served one at a time.
+func SubscriberConcurrency(n int) SubscriberOption {
+	return func(s *Subscriber) { s.concurrency = n }
+}
+
+// SubscriberWaitTime sets how long receives wait for messages, at most 20
+// seconds. By default, it's DefaultSubscriberWaitTime.
+func SubscriberWaitTime(d time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.waitTime = d }
+}
+
+// SubscriberMaxMessages sets how many messages are received at most at
+// once, at most 10. By default, it's DefaultSubscriberMaxMessages.
+func SubscriberMaxMessages(n int64) SubscriberOption {
+	return func(s *Subscriber) { s.maxMessages = n }
+}
+
+// SubscriberVisibilityBackoff sets the visibility timeouts of failed
+// messages, after which they're received again: the nth failure of a
+// message, by its ApproximateReceiveCount, sets the nth timeout, or the
+// last, if there are fewer. By default, the visibility timeout of the queue
+// applies.
+func SubscriberVisibilityBackoff(timeouts ...time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.visibility = timeouts }
+}
+
+// SubscriberDeadLetterQueue sends messages which fail on their maxReceives
+// receive, and messages which can't be decoded, to the dead letter queue,
+// and deletes them from the queue. By default, as without a redrive policy,
+// failed messages are received again until they expire.
+func SubscriberDeadLetterQueue(queueURL string, maxReceives int) SubscriberOption {
+	return func(s *Subscriber) { s.dlqURL, s.maxReceives = queueURL, maxReceives }
+}
+
+// SubscriberPollBackoff sets the backoff of receives after they fail, which
+// doubles from min to max. By default, it's from one second to a minute.
+func SubscriberPollBackoff(min, max time.Duration) SubscriberOption {
+	return func(s *Subscriber) { s.minBackoff, s.maxBackoff = min, max }
+}
+
+// Serve long-polls the queue, serving its messages with ServeMessage, until
+// the context is done, and returns its error, once the messages in flight
+// are served. At most as many messages are received at once as are served
+// concurrently, so that they're not kept invisible long while they wait.
+func (s Subscriber) Serve(ctx context.Context) error {
+	concurrency := s.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	msgs := make(chan *sqs.Message)
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				// Messages in flight are served to completion.
+				s.ServeMessage(context.Background(), msg)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(msgs)
+
+	backoff := s.minBackoff
+	for {
+		maxMessages := s.maxMessages
+		if int64(concurrency) < maxMessages {
+			maxMessages = int64(concurrency)
+		}
+		out, err := s.svc.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(s.queueURL),
+			MaxNumberOfMessages:   aws.Int64(maxMessages),
+			WaitTimeSeconds:       aws.Int64(int64(s.waitTime / time.Second)),
+			AttributeNames:        aws.StringSlice([]string{sqs.MessageSystemAttributeNameApproximateReceiveCount}),
+			MessageAttributeNames: aws.StringSlice([]string{"All"}),
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			s.logger.Log("err", err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+			continue
+		}
+		backoff = s.minBackoff
+
+		for _, msg := range out.Messages {
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// ServeMessage serves the message with the endpoint, and deletes it from
+// the queue once it's served. If it fails, in the decoder or the endpoint,
+// its visibility timeout is changed, by the visibility backoff, or it's
+// sent to the dead letter queue, if either is set. A response implementing
+// endpoint.Failer, whose Failed method returns an error, fails like an
+// error of the endpoint.
+func (s Subscriber) ServeMessage(ctx context.Context, msg *sqs.Message) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var err error
+	if s.finalizer != nil {
+		defer func() { s.finalizer(ctx, msg, err) }()
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := s.dec(ctx, msg)
+	if err != nil {
+		s.logger.Log("err", err)
+		s.fail(ctx, msg, true)
+		return
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		s.fail(ctx, msg, false)
+		return
+	}
+
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		err = f.Failed()
+		s.fail(ctx, msg, false)
+		return
+	}
+
+	if deleteErr := s.delete(ctx, msg); deleteErr != nil {
+		s.logger.Log("err", deleteErr)
+	}
+}
+
+// fail handles a failed message. Messages which can't be decoded are sent
+// to the dead letter queue on their first receive.
+func (s Subscriber) fail(ctx context.Context, msg *sqs.Message, undecodable bool) {
+	receives, _ := strconv.Atoi(aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+	if s.dlqURL != "" && (undecodable || receives >= s.maxReceives) {
+		if err := s.deadLetter(ctx, msg); err != nil {
+			s.logger.Log("err", err)
+		}
+		return
+	}
+
+	if len(s.visibility) == 0 {
+		return
+	}
+	if receives < 1 {
+		receives = 1
+	}
+	if receives > len(s.visibility) {
+		receives = len(s.visibility)
+	}
+	if _, err := s.svc.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(s.queueURL),
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: aws.Int64(int64(s.visibility[receives-1] / time.Second)),
+	}); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+func (s Subscriber) deadLetter(ctx context.Context, msg *sqs.Message) error {
+	if msg.Body == nil {
+		return errors.New("message has no body")
+	}
+	if _, err := s.svc.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.dlqURL),
+		MessageBody:       msg.Body,
+		MessageAttributes: msg.MessageAttributes,
+	}); err != nil {
+		return err
+	}
+	return s.delete(ctx, msg)
+}
+
+func (s Subscriber) delete(ctx context.Context, msg *sqs.Message) error {
+	_, err := s.svc.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(s.queueURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	})
+	return err
+}
+
+// SubscriberFinalizerFunc can be used to perform work at the end of a
+// message, after it's deleted, given the error with which it failed, if
+// any. The principal intended use is for request logging.
+type SubscriberFinalizerFunc func(ctx context.Context, msg *sqs.Message, err error)
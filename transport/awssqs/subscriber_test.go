@@ -0,0 +1,199 @@
+package awssqs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/go-kit/kit/transport/awssqs"
+)
+
+type testRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func decodeTestRequest(_ context.Context, msg *sqs.Message) (interface{}, error) {
+	var req testRequest
+	err := json.Unmarshal([]byte(aws.StringValue(msg.Body)), &req)
+	return req, err
+}
+
+func failNegative(_ context.Context, request interface{}) (interface{}, error) {
+	if request.(testRequest).A < 0 {
+		return nil, errors.New("negative")
+	}
+	return nil, nil
+}
+
+// mockSQS is an SQS queue of messages, recording the calls on them.
+type mockSQS struct {
+	sqsiface.SQSAPI
+	mtx   sync.Mutex
+	msgs  []*sqs.Message
+	calls []string
+}
+
+func (m *mockSQS) ReceiveMessageWithContext(ctx aws.Context, in *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	m.mtx.Lock()
+	n := int(aws.Int64Value(in.MaxNumberOfMessages))
+	if n > len(m.msgs) {
+		n = len(m.msgs)
+	}
+	msgs := m.msgs[:n]
+	m.msgs = m.msgs[n:]
+	m.mtx.Unlock()
+	if n == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (m *mockSQS) DeleteMessageWithContext(_ aws.Context, in *sqs.DeleteMessageInput, _ ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	m.record("delete " + aws.StringValue(in.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (m *mockSQS) ChangeMessageVisibilityWithContext(_ aws.Context, in *sqs.ChangeMessageVisibilityInput, _ ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	m.record("visibility " + aws.StringValue(in.ReceiptHandle) + " " + (time.Duration(aws.Int64Value(in.VisibilityTimeout)) * time.Second).String())
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (m *mockSQS) SendMessageWithContext(_ aws.Context, in *sqs.SendMessageInput, _ ...request.Option) (*sqs.SendMessageOutput, error) {
+	m.record("send " + aws.StringValue(in.QueueUrl) + " " + aws.StringValue(in.MessageBody))
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (m *mockSQS) record(call string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.calls = append(m.calls, call)
+}
+
+func newMessage(handle, body string, receives string) *sqs.Message {
+	return &sqs.Message{
+		ReceiptHandle: aws.String(handle),
+		Body:          aws.String(body),
+		Attributes:    map[string]*string{sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(receives)},
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			"trace": {DataType: aws.String("String"), StringValue: aws.String("abc")},
+		},
+	}
+}
+
+func TestSubscriberServe(t *testing.T) {
+	svc := &mockSQS{msgs: []*sqs.Message{
+		newMessage("1", `{"a":1}`, "1"),
+		newMessage("2", `{"a":2}`, "1"),
+		newMessage("3", `{"a":3}`, "1"),
+	}}
+	var (
+		mtx    sync.Mutex
+		traces []string
+		served = make(chan struct{}, 3)
+	)
+	s := awssqs.NewSubscriber(svc, "queue", failNegative, decodeTestRequest,
+		awssqs.SubscriberConcurrency(2),
+		awssqs.SubscriberBefore(func(ctx context.Context, msg *sqs.Message) context.Context {
+			mtx.Lock()
+			traces = append(traces, awssqs.MessageAttribute(msg, "trace"))
+			mtx.Unlock()
+			return ctx
+		}),
+		awssqs.SubscriberFinalizer(func(context.Context, *sqs.Message, error) { served <- struct{}{} }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() { errc <- s.Serve(ctx) }()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-served:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for messages to be served")
+		}
+	}
+	cancel()
+	if want, have := context.Canceled, <-errc; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	svc.mtx.Lock()
+	calls := svc.calls
+	svc.mtx.Unlock()
+	if want, have := 3, len(calls); want != have {
+		t.Errorf("want %d calls, have %v", want, calls)
+	}
+	if want, have := []string{"abc", "abc", "abc"}, traces; !reflect.DeepEqual(want, have) {
+		t.Errorf("traces: want %v, have %v", want, have)
+	}
+}
+
+func TestSubscriberFailure(t *testing.T) {
+	for _, testcase := range []struct {
+		name    string
+		msg     *sqs.Message
+		options []awssqs.SubscriberOption
+		want    []string
+	}{
+		{
+			name: "served",
+			msg:  newMessage("h", `{"a":1}`, "1"),
+			want: []string{"delete h"},
+		},
+		{
+			name: "no backoff",
+			msg:  newMessage("h", `{"a":-1}`, "1"),
+			want: nil,
+		},
+		{
+			name:    "visibility backoff",
+			msg:     newMessage("h", `{"a":-1}`, "2"),
+			options: []awssqs.SubscriberOption{awssqs.SubscriberVisibilityBackoff(time.Second, 10*time.Second)},
+			want:    []string{"visibility h 10s"},
+		},
+		{
+			name:    "visibility backoff exhausted",
+			msg:     newMessage("h", `{"a":-1}`, "5"),
+			options: []awssqs.SubscriberOption{awssqs.SubscriberVisibilityBackoff(time.Second, 10*time.Second)},
+			want:    []string{"visibility h 10s"},
+		},
+		{
+			name:    "dead letter",
+			msg:     newMessage("h", `{"a":-1}`, "3"),
+			options: []awssqs.SubscriberOption{awssqs.SubscriberDeadLetterQueue("dlq", 3)},
+			want:    []string{"send dlq {\"a\":-1}", "delete h"},
+		},
+		{
+			name:    "dead letter before max receives",
+			msg:     newMessage("h", `{"a":-1}`, "2"),
+			options: []awssqs.SubscriberOption{awssqs.SubscriberDeadLetterQueue("dlq", 3), awssqs.SubscriberVisibilityBackoff(time.Minute)},
+			want:    []string{"visibility h 1m0s"},
+		},
+		{
+			name:    "undecodable",
+			msg:     newMessage("h", `x`, "1"),
+			options: []awssqs.SubscriberOption{awssqs.SubscriberDeadLetterQueue("dlq", 3)},
+			want:    []string{"send dlq x", "delete h"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			svc := &mockSQS{}
+			s := awssqs.NewSubscriber(svc, "queue", failNegative, decodeTestRequest, testcase.options...)
+			s.ServeMessage(context.Background(), testcase.msg)
+			if want, have := testcase.want, svc.calls; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Package graphql provides a GraphQL binding for endpoints, over the
+// schemas of package github.com/graph-gophers/graphql-go.
+//
+// A Resolver serves a field of a schema, a query or a mutation, with an
+// endpoint, and its codecs, from the arguments of the field to the request,
+// and from the response to the result. The methods of the root resolver of
+// the schema call Resolvers, e.g.
+//
+//	func (r *root) Sum(ctx context.Context, args struct{ A, B int32 }) (int32, error) {
+//	    v, err := r.sum.Resolve(ctx, args)
+//	    if err != nil {
+//	        return 0, err
+//	    }
+//	    return v.(int32), nil
+//	}
+//
+// Errors are formatted as GraphQL errors, with extensions, e.g. their code.
+// Each Resolver may have endpoint middlewares of its own, so that the
+// instrumentation of endpoints applies to each field. A Handler serves the
+// schema over HTTP.
+package graphql
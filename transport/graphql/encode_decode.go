@@ -0,0 +1,20 @@
+package graphql
+
+import "context"
+
+// DecodeRequestFunc extracts a user-domain request object from the
+// arguments of a field, as they're passed to the method of the resolver,
+// typically a struct. One straightforward DecodeRequestFunc could be
+// something that converts the arguments to the concrete request type.
+type DecodeRequestFunc func(ctx context.Context, args interface{}) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the result of a
+// field, as it's returned by the method of the resolver, of the type the
+// schema expects.
+type EncodeResponseFunc func(ctx context.Context, response interface{}) (result interface{}, err error)
+
+// ErrorFormatter formats an error of a resolver as the error of the field,
+// which is a GraphQL error, with the extensions of the error, if it has an
+// Extensions method, as Error has. Users are encouraged to use custom
+// ErrorFormatters to add codes and data to their errors.
+type ErrorFormatter func(ctx context.Context, err error) error
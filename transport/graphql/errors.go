@@ -0,0 +1,71 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+)
+
+// Codes of errors, in the "code" extension of GraphQL errors, by the
+// convention of many GraphQL servers.
+const (
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeNotFound        = "NOT_FOUND"
+	CodeInternal        = "INTERNAL_SERVER_ERROR"
+)
+
+// Error is an error of a resolver, which is formatted as a GraphQL error
+// with the extensions of its code, if any, and data.
+type Error struct {
+	Err  error
+	Code string
+	Data map[string]interface{}
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Extensions returns the extensions of the GraphQL error: the data, and
+// the code, if there is one.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := make(map[string]interface{}, len(e.Data)+1)
+	for k, v := range e.Data {
+		extensions[k] = v
+	}
+	if e.Code != "" {
+		extensions["code"] = e.Code
+	}
+	return extensions
+}
+
+// ErrorCoder is checked by DefaultErrorFormatter. If an error implements
+// it, the GraphQL error has its code.
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// DefaultErrorFormatter formats errors implementing ErrorCoder, and errors
+// wrapping an Error, as Errors, with their code. Other errors are left as
+// they are, so that the GraphQL error has only their message, unless they
+// have extensions of their own.
+func DefaultErrorFormatter(_ context.Context, err error) error {
+	if _, ok := err.(interface{ Extensions() map[string]interface{} }); ok {
+		return err
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return &Error{Err: err, Code: e.Code, Data: e.Data}
+	}
+	if coder, ok := err.(ErrorCoder); ok {
+		return &Error{Err: err, Code: coder.ErrorCode()}
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graphgophers "github.com/graph-gophers/graphql-go"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport/graphql"
+)
+
+const schema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+type Query {
+	sum(a: Int!, b: Int!): Int!
+	fail(code: String!): String
+}
+type Mutation {
+	concat(a: String!, b: String!): String!
+}
+`
+
+type sumArgs struct{ A, B int32 }
+
+type codedError struct{ code string }
+
+func (e codedError) Error() string     { return "coded" }
+func (e codedError) ErrorCode() string { return e.code }
+
+type root struct {
+	sum, fail, concat *graphql.Resolver
+}
+
+func (r *root) Sum(ctx context.Context, args sumArgs) (int32, error) {
+	v, err := r.sum.Resolve(ctx, args)
+	if err != nil {
+		return 0, err
+	}
+	return v.(int32), nil
+}
+
+func (r *root) Fail(ctx context.Context, args struct{ Code string }) (*string, error) {
+	_, err := r.fail.Resolve(ctx, args)
+	return nil, err
+}
+
+func (r *root) Concat(ctx context.Context, args struct{ A, B string }) (string, error) {
+	v, err := r.concat.Resolve(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+func passthrough(_ context.Context, v interface{}) (interface{}, error) { return v, nil }
+
+func newServer(t *testing.T) (*httptest.Server, *[]string) {
+	var calls []string
+	record := func(name string) endpoint.Middleware {
+		return func(next endpoint.Endpoint) endpoint.Endpoint {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				calls = append(calls, name)
+				return next(ctx, request)
+			}
+		}
+	}
+	r := &root{
+		sum: graphql.NewResolver(
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(sumArgs)
+				return req.A + req.B, nil
+			},
+			passthrough,
+			passthrough,
+			graphql.ResolverMiddleware(record("outer"), record("inner")),
+		),
+		fail: graphql.NewResolver(
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				code := request.(struct{ Code string }).Code
+				if code == "" {
+					return nil, errors.New("plain")
+				}
+				return nil, codedError{code}
+			},
+			passthrough,
+			passthrough,
+		),
+		concat: graphql.NewResolver(
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return request.(string), nil
+			},
+			func(_ context.Context, args interface{}) (interface{}, error) {
+				a := args.(struct{ A, B string })
+				if a.A == "" {
+					return nil, errors.New("a is empty")
+				}
+				return a.A + a.B, nil
+			},
+			passthrough,
+		),
+	}
+	s, err := graphgophers.ParseSchema(schema, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(graphql.NewHandler(s)), &calls
+}
+
+func post(t *testing.T, url, body string) (int, string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestHandler(t *testing.T) {
+	server, calls := newServer(t)
+	defer server.Close()
+
+	for _, testcase := range []struct {
+		name, body, want string
+	}{
+		{
+			"query",
+			`{"query":"query($b: Int!) { sum(a: 1, b: $b) }","variables":{"b":2}}`,
+			`{"data":{"sum":3}}`,
+		},
+		{
+			"mutation",
+			`{"query":"mutation { concat(a: \"x\", b: \"y\") }"}`,
+			`{"data":{"concat":"xy"}}`,
+		},
+		{
+			"decode error",
+			`{"query":"mutation { concat(a: \"\", b: \"y\") }"}`,
+			`{"errors":[{"message":"a is empty","path":["concat"],"extensions":{"code":"BAD_USER_INPUT"}}],"data":null}`,
+		},
+		{
+			"coded error",
+			`{"query":"{ fail(code: \"NOT_FOUND\") }"}`,
+			`{"errors":[{"message":"coded","path":["fail"],"extensions":{"code":"NOT_FOUND"}}],"data":{"fail":null}}`,
+		},
+		{
+			"plain error",
+			`{"query":"{ fail(code: \"\") }"}`,
+			`{"errors":[{"message":"plain","path":["fail"]}],"data":{"fail":null}}`,
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			code, have := post(t, server.URL, testcase.body)
+			if want, have := http.StatusOK, code; want != have {
+				t.Errorf("status: want %d, have %d", want, have)
+			}
+			if want := testcase.want; want != have {
+				t.Errorf("want %s, have %s", want, have)
+			}
+		})
+	}
+
+	if want, have := []string{"outer", "inner"}, *calls; strings.Join(want, ",") != strings.Join(have, ",") {
+		t.Errorf("middleware: want %v, have %v", want, have)
+	}
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	server, _ := newServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusMethodNotAllowed, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestDefaultErrorFormatter(t *testing.T) {
+	inner := &graphql.Error{Err: errors.New("dang"), Code: graphql.CodeForbidden, Data: map[string]interface{}{"reason": "nope"}}
+	err := graphql.DefaultErrorFormatter(context.Background(), wrapped{inner})
+	e, ok := err.(*graphql.Error)
+	if !ok {
+		t.Fatalf("want *Error, have %T", err)
+	}
+	if want, have := "wrapped: dang", e.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	extensions := e.Extensions()
+	if want, have := graphql.CodeForbidden, extensions["code"]; want != have {
+		t.Errorf("code: want %v, have %v", want, have)
+	}
+	if want, have := "nope", extensions["reason"]; want != have {
+		t.Errorf("reason: want %v, have %v", want, have)
+	}
+}
+
+type wrapped struct{ err error }
+
+func (w wrapped) Error() string { return "wrapped: " + w.err.Error() }
+func (w wrapped) Unwrap() error { return w.err }
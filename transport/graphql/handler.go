@@ -0,0 +1,109 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Handler serves a GraphQL schema over HTTP, and implements http.Handler.
+// Requests are POSTed, with the query, operation name, and variables in a
+// JSON object, as most GraphQL clients send them, and responses are JSON
+// objects of the data and errors.
+type Handler struct {
+	schema       *graphql.Schema
+	before       []httptransport.RequestFunc
+	after        []httptransport.ServerResponseFunc
+	maxBodyBytes int64
+	logger       log.Logger
+}
+
+// NewHandler constructs a new handler, which serves the schema, whose root
+// resolver is typically backed by Resolvers.
+func NewHandler(schema *graphql.Schema, options ...HandlerOption) *Handler {
+	h := &Handler{
+		schema: schema,
+		logger: log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// HandlerOption sets an optional parameter for handlers.
+type HandlerOption func(*Handler)
+
+// HandlerBefore functions are executed on the HTTP request object before
+// the query is executed, so that the context of the resolvers has the
+// information they put into it.
+func HandlerBefore(before ...httptransport.RequestFunc) HandlerOption {
+	return func(h *Handler) { h.before = append(h.before, before...) }
+}
+
+// HandlerAfter functions are executed on the HTTP response writer after the
+// query is executed, but before the response is written.
+func HandlerAfter(after ...httptransport.ServerResponseFunc) HandlerOption {
+	return func(h *Handler) { h.after = append(h.after, after...) }
+}
+
+// HandlerMaxBodyBytes sets the maximum size of request bodies. By default,
+// there's no limit.
+func HandlerMaxBodyBytes(n int64) HandlerOption {
+	return func(h *Handler) { h.maxBodyBytes = n }
+}
+
+// HandlerErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func HandlerErrorLogger(logger log.Logger) HandlerOption {
+	return func(h *Handler) { h.logger = logger }
+}
+
+// ServeHTTP implements http.Handler.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "GraphQL requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.maxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBodyBytes)
+	}
+
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		h.logger.Log("err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	for _, f := range h.before {
+		ctx = f(ctx, r)
+	}
+
+	response := h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+
+	for _, f := range h.after {
+		ctx = f(ctx, w)
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		h.logger.Log("err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(b); err != nil {
+		h.logger.Log("err", err)
+	}
+}
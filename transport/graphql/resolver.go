@@ -0,0 +1,113 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Resolver wraps an endpoint, and resolves a field of a GraphQL schema.
+type Resolver struct {
+	e              endpoint.Endpoint
+	dec            DecodeRequestFunc
+	enc            EncodeResponseFunc
+	middleware     []endpoint.Middleware
+	errorFormatter ErrorFormatter
+	finalizer      ResolverFinalizerFunc
+	logger         log.Logger
+}
+
+// NewResolver constructs a new resolver, which resolves a field with the
+// provided endpoint.
+func NewResolver(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...ResolverOption,
+) *Resolver {
+	r := &Resolver{
+		e:              e,
+		dec:            dec,
+		enc:            enc,
+		errorFormatter: DefaultErrorFormatter,
+		logger:         log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if len(r.middleware) > 0 {
+		r.e = endpoint.Chain(r.middleware[0], r.middleware[1:]...)(r.e)
+	}
+	return r
+}
+
+// ResolverOption sets an optional parameter for resolvers.
+type ResolverOption func(*Resolver)
+
+// ResolverMiddleware wraps the endpoint of the resolver with the
+// middlewares, e.g. of instrumentation, so that they apply to this field
+// alone. As with endpoint.Chain, the first is the outermost.
+func ResolverMiddleware(middleware ...endpoint.Middleware) ResolverOption {
+	return func(r *Resolver) { r.middleware = append(r.middleware, middleware...) }
+}
+
+// ResolverErrorFormatter is used to format errors of the decoder, the
+// endpoint, and the encoder, as the errors of the field. By default, errors
+// are formatted with DefaultErrorFormatter.
+func ResolverErrorFormatter(f ErrorFormatter) ResolverOption {
+	return func(r *Resolver) { r.errorFormatter = f }
+}
+
+// ResolverErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure.
+func ResolverErrorLogger(logger log.Logger) ResolverOption {
+	return func(r *Resolver) { r.logger = logger }
+}
+
+// ResolverFinalizer is executed at the end of every resolution of the
+// field, with the error with which it failed, if any. By default, no
+// finalizer is registered.
+func ResolverFinalizer(f ResolverFinalizerFunc) ResolverOption {
+	return func(r *Resolver) { r.finalizer = f }
+}
+
+// Resolve resolves the field, given the arguments of the method of the
+// resolver, and returns the result, or the formatted error. Errors of the
+// decoder are Errors with CodeBadUserInput, unless they have a code of
+// their own. A response implementing endpoint.Failer, whose Failed method
+// returns an error, fails like an error of the endpoint.
+func (r Resolver) Resolve(ctx context.Context, args interface{}) (result interface{}, err error) {
+	if r.finalizer != nil {
+		defer func() { r.finalizer(ctx, err) }()
+	}
+
+	request, err := r.dec(ctx, args)
+	if err != nil {
+		r.logger.Log("err", err)
+		if _, ok := err.(ErrorCoder); !ok {
+			err = &Error{Err: err, Code: CodeBadUserInput}
+		}
+		return nil, r.errorFormatter(ctx, err)
+	}
+
+	response, err := r.e(ctx, request)
+	if err != nil {
+		r.logger.Log("err", err)
+		return nil, r.errorFormatter(ctx, err)
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return nil, r.errorFormatter(ctx, f.Failed())
+	}
+
+	result, err = r.enc(ctx, response)
+	if err != nil {
+		r.logger.Log("err", err)
+		return nil, r.errorFormatter(ctx, err)
+	}
+	return result, nil
+}
+
+// ResolverFinalizerFunc can be used to perform work at the end of the
+// resolution of a field, given the error with which it failed, if any.
+type ResolverFinalizerFunc func(ctx context.Context, err error)
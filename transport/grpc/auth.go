@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// APIKeyMetadataKey is the conventional metadata key of API keys.
+const APIKeyMetadataKey = "x-api-key"
+
+// authorizationKey is the metadata key of bearer tokens. It's lowercase, as
+// HTTP/2 requires.
+const authorizationKey = "authorization"
+
+// ServerBearerToken returns a ServerRequestFunc which moves the bearer token
+// of the authorization metadata, "Bearer <token>", to the context under the
+// given key, as a string. It's symmetrical with ClientBearerToken. To feed
+// the middlewares of package auth/jwt, use jwt.JWTTokenContextKey.
+func ServerBearerToken(ctxKey interface{}) ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		for _, v := range md[authorizationKey] {
+			if token, ok := bearerToken(v); ok {
+				return context.WithValue(ctx, ctxKey, token)
+			}
+		}
+		return ctx
+	}
+}
+
+// ClientBearerToken returns a ClientRequestFunc which moves the string under
+// the given key of the context to the authorization metadata, as a bearer
+// token, replacing any other. If there's no token in the context, the
+// metadata is unchanged.
+func ClientBearerToken(ctxKey interface{}) ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if token, ok := ctx.Value(ctxKey).(string); ok && token != "" {
+			(*md)[authorizationKey] = []string{"Bearer " + token}
+		}
+		return ctx
+	}
+}
+
+// SetBearerToken returns a ClientRequestFunc which sends the fixed bearer
+// token in the authorization metadata, e.g. for service accounts.
+func SetBearerToken(token string) ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		(*md)[authorizationKey] = []string{"Bearer " + token}
+		return ctx
+	}
+}
+
+// ServerCredential returns a ServerRequestFunc which moves the first value
+// of the metadata key, e.g. APIKeyMetadataKey, to the context under the
+// given key, as a string. Keys are case-insensitive.
+func ServerCredential(mdKey string, ctxKey interface{}) ServerRequestFunc {
+	mdKey = strings.ToLower(mdKey)
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		if v := md[mdKey]; len(v) > 0 && v[0] != "" {
+			return context.WithValue(ctx, ctxKey, v[0])
+		}
+		return ctx
+	}
+}
+
+// ClientCredential returns a ClientRequestFunc which moves the string under
+// the given key of the context to the metadata key, e.g. APIKeyMetadataKey,
+// replacing any other value. If there's no credential in the context, the
+// metadata is unchanged.
+func ClientCredential(mdKey string, ctxKey interface{}) ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		if v, ok := ctx.Value(ctxKey).(string); ok && v != "" {
+			key, val := EncodeKeyValue(mdKey, v)
+			(*md)[key] = []string{val}
+		}
+		return ctx
+	}
+}
+
+// ClientResponseCredential returns a ClientResponseFunc which moves the
+// first value of the metadata key, from the response's trailer or else its
+// header, to the context under the given key, e.g. to pick up a token
+// refreshed by the server. It's symmetrical with SetResponseHeader and
+// SetResponseTrailer.
+func ClientResponseCredential(mdKey string, ctxKey interface{}) ClientResponseFunc {
+	mdKey = strings.ToLower(mdKey)
+	return func(ctx context.Context, header metadata.MD, trailer metadata.MD) context.Context {
+		for _, md := range []metadata.MD{trailer, header} {
+			if v := md[mdKey]; len(v) > 0 && v[0] != "" {
+				return context.WithValue(ctx, ctxKey, v[0])
+			}
+		}
+		return ctx
+	}
+}
+
+// bearerToken returns the token of an authorization value of the bearer
+// scheme, which is case-insensitive.
+func bearerToken(v string) (string, bool) {
+	const prefix = "bearer "
+	if len(v) <= len(prefix) || !strings.EqualFold(v[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(v[len(prefix):])
+	return token, token != ""
+}
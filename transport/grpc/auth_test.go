@@ -0,0 +1,69 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/auth/jwt"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+type credentialKey struct{}
+
+func TestBearerToken(t *testing.T) {
+	ctx := context.WithValue(context.Background(), jwt.JWTTokenContextKey, "abc")
+	md := metadata.MD{}
+	grpctransport.ClientBearerToken(jwt.JWTTokenContextKey)(ctx, &md)
+	if want, have := []string{"Bearer abc"}, md["authorization"]; len(have) != 1 || want[0] != have[0] {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	ctx = grpctransport.ServerBearerToken(jwt.JWTTokenContextKey)(context.Background(), md)
+	if want, have := "abc", ctx.Value(jwt.JWTTokenContextKey); want != have {
+		t.Errorf("want %q, have %v", want, have)
+	}
+
+	for _, v := range []string{"Basic abc", "Bearer ", "bearer"} {
+		ctx = grpctransport.ServerBearerToken(jwt.JWTTokenContextKey)(context.Background(), metadata.MD{"authorization": {v}})
+		if have := ctx.Value(jwt.JWTTokenContextKey); have != nil {
+			t.Errorf("%q: want no token, have %v", v, have)
+		}
+	}
+
+	md = metadata.MD{}
+	grpctransport.ClientBearerToken(jwt.JWTTokenContextKey)(context.Background(), &md)
+	if len(md) != 0 {
+		t.Errorf("want no metadata without a token, have %v", md)
+	}
+	grpctransport.SetBearerToken("xyz")(context.Background(), &md)
+	ctx = grpctransport.ServerBearerToken(jwt.JWTTokenContextKey)(context.Background(), md)
+	if want, have := "xyz", ctx.Value(jwt.JWTTokenContextKey); want != have {
+		t.Errorf("want %q, have %v", want, have)
+	}
+}
+
+func TestCredential(t *testing.T) {
+	ctx := context.WithValue(context.Background(), credentialKey{}, "secret")
+	md := metadata.MD{}
+	grpctransport.ClientCredential("X-API-Key", credentialKey{})(ctx, &md)
+	if want, have := "secret", md[grpctransport.APIKeyMetadataKey]; len(have) != 1 || want != have[0] {
+		t.Fatalf("want %q, have %v", want, have)
+	}
+
+	ctx = grpctransport.ServerCredential("X-API-Key", credentialKey{})(context.Background(), md)
+	if want, have := "secret", ctx.Value(credentialKey{}); want != have {
+		t.Errorf("want %q, have %v", want, have)
+	}
+
+	header, trailer := metadata.MD{"x-token": {"old"}}, metadata.MD{"x-token": {"new"}}
+	ctx = grpctransport.ClientResponseCredential("x-token", credentialKey{})(context.Background(), header, trailer)
+	if want, have := "new", ctx.Value(credentialKey{}); want != have {
+		t.Errorf("want %q, have %v", want, have)
+	}
+	ctx = grpctransport.ClientResponseCredential("x-token", credentialKey{})(context.Background(), header, metadata.MD{})
+	if want, have := "old", ctx.Value(credentialKey{}); want != have {
+		t.Errorf("want %q, have %v", want, have)
+	}
+}
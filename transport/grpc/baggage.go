@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// BaggageMetadataKey carries the endpoint.Baggage of requests, in the W3C
+// baggage format.
+const BaggageMetadataKey = "baggage"
+
+// ContextToBaggageMetadata is a ClientRequestFunc which sets
+// BaggageMetadataKey to the baggage of the context, if any, so that it travels
+// on to the server.
+func ContextToBaggageMetadata(ctx context.Context, md *metadata.MD) context.Context {
+	if b := endpoint.BaggageFromContext(ctx); len(b) > 0 {
+		(*md)[BaggageMetadataKey] = []string{b.String()}
+	}
+	return ctx
+}
+
+// BaggageMetadataToContext is a ServerRequestFunc which passes the baggage in
+// BaggageMetadataKey, if any, to endpoint.ContextWithBaggage. Combined with
+// ContextToBaggageMetadata in the clients the server calls, the baggage is
+// propagated through every service.
+func BaggageMetadataToContext(ctx context.Context, md metadata.MD) context.Context {
+	values := md[BaggageMetadataKey]
+	if len(values) == 0 {
+		return ctx
+	}
+	if b := endpoint.ParseBaggage(strings.Join(values, ",")); len(b) > 0 {
+		ctx = endpoint.ContextWithBaggage(ctx, b)
+	}
+	return ctx
+}
@@ -0,0 +1,25 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+func TestBaggageMetadata(t *testing.T) {
+	md := metadata.MD{}
+	ctx := endpoint.ContextWithBaggageItem(context.Background(), "tenant", "acme")
+	grpctransport.ContextToBaggageMetadata(ctx, &md)
+	if want, have := []string{"tenant=acme"}, md[grpctransport.BaggageMetadataKey]; len(have) != 1 || want[0] != have[0] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	ctx = grpctransport.BaggageMetadataToContext(context.Background(), md)
+	if have, _ := endpoint.BaggageItem(ctx, "tenant"); have != "acme" {
+		t.Errorf("want acme, have %q", have)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -22,11 +24,17 @@ type Client struct {
 	grpcReply   reflect.Type
 	before      []ClientRequestFunc
 	after       []ClientResponseFunc
+	timeout     time.Duration
+	propagate   []string
+	callOptions []grpc.CallOption
+	errorDec    func(error) error
 }
 
 // NewClient constructs a usable Client for a single remote endpoint.
 // Pass an zero-value protobuf message of the RPC response type as
 // the grpcReply argument.
+// Keepalive, connection backoff, and retry policies are properties of the
+// connection; see NewClientConn.
 func NewClient(
 	cc *grpc.ClientConn,
 	serviceName string,
@@ -75,11 +83,53 @@ func ClientAfter(after ...ClientResponseFunc) ClientOption {
 	return func(c *Client) { c.after = append(c.after, after...) }
 }
 
+// ClientTimeout sets a default timeout for each call. The deadline of the
+// request context, e.g. that of an incoming RPC, which gRPC propagates to the
+// server, applies if it's sooner. By default, calls have only the deadline of
+// the request context.
+func ClientTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = d }
+}
+
+// ClientPropagateMetadata copies the values of the keys, e.g.
+// "x-request-id", from the incoming metadata of the request context, i.e. of
+// an RPC served by this service, to the outgoing metadata, so that
+// correlation metadata is preserved over many hops. The values are copied
+// before the ClientBefore funcs are applied.
+func ClientPropagateMetadata(keys ...string) ClientOption {
+	return func(c *Client) {
+		for _, key := range keys {
+			c.propagate = append(c.propagate, strings.ToLower(key))
+		}
+	}
+}
+
+// ClientWaitForReady makes calls wait until the connection is ready, or the
+// request context is done, rather than failing immediately when it's in a
+// transient failure, e.g. while the server restarts.
+func ClientWaitForReady() ClientOption {
+	return func(c *Client) { c.callOptions = append(c.callOptions, grpc.WaitForReady(true)) }
+}
+
+// ClientErrorDecoder sets a function which decodes the errors of failed
+// calls, e.g. DecodeError, so that callers see structured errors. By default,
+// the gRPC status errors are returned.
+func ClientErrorDecoder(dec func(error) error) ClientOption {
+	return func(c *Client) { c.errorDec = dec }
+}
+
 // Endpoint returns a usable endpoint that will invoke the gRPC specified by the
-// client.
+// client. Outgoing metadata already in the context, e.g. set by an
+// interceptor adapted with ClientInterceptorMiddleware, is sent along with
+// the metadata of the ClientBefore funcs.
 func (c Client) Endpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		ctx, cancel := context.WithCancel(ctx)
+		var cancel context.CancelFunc
+		if c.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
 		defer cancel()
 
 		req, err := c.enc(ctx, request)
@@ -88,17 +138,30 @@ func (c Client) Endpoint() endpoint.Endpoint {
 		}
 
 		md := &metadata.MD{}
+		if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+			for _, key := range c.propagate {
+				if v, ok := incoming[key]; ok {
+					(*md)[key] = append((*md)[key], v...)
+				}
+			}
+		}
 		for _, f := range c.before {
 			ctx = f(ctx, md)
 		}
-		ctx = metadata.NewContext(ctx, *md)
+		if outgoing, ok := metadata.FromOutgoingContext(ctx); ok {
+			*md = metadata.Join(outgoing, *md)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, *md)
 
 		var header, trailer metadata.MD
 		grpcReply := reflect.New(c.grpcReply).Interface()
 		if err = grpc.Invoke(
 			ctx, c.method, req, grpcReply, c.client,
-			grpc.Header(&header), grpc.Trailer(&trailer),
+			append([]grpc.CallOption{grpc.Header(&header), grpc.Trailer(&trailer)}, c.callOptions...)...,
 		); err != nil {
+			if c.errorDec != nil {
+				err = c.errorDec(err)
+			}
 			return nil, err
 		}
 
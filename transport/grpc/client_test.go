@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
+	grpctransport "github.com/go-kit/kit/transport/grpc"
 	test "github.com/go-kit/kit/transport/grpc/_grpc_test"
 	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
 )
@@ -59,3 +64,77 @@ func TestGRPCClient(t *testing.T) {
 		t.Fatalf("want %q, have %q", want, have)
 	}
 }
+
+func TestGRPCClientOptions(t *testing.T) {
+	cc, stop := startInterceptedServer(t)
+	defer stop()
+
+	var deadline time.Time
+	client := grpctransport.NewClient(cc, "pb.Intercepted", "Echo",
+		func(_ context.Context, request interface{}) (interface{}, error) { return &pb.TestRequest{A: request.(string)}, nil },
+		func(_ context.Context, response interface{}) (interface{}, error) { return response.(*pb.TestResponse).V, nil },
+		pb.TestResponse{},
+		grpctransport.ClientTimeout(time.Minute),
+		grpctransport.ClientPropagateMetadata("X-User"),
+		grpctransport.ClientBefore(func(ctx context.Context, _ *metadata.MD) context.Context {
+			deadline, _ = ctx.Deadline()
+			return ctx
+		}),
+	)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-user", "dave", "x-other", "no"))
+	response, err := client.Endpoint()(ctx, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hello dave", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if remaining := time.Until(deadline); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("want a deadline within a minute, have %v", remaining)
+	}
+
+	// A sooner deadline of the request context applies.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.Endpoint()(ctx, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if remaining := time.Until(deadline); remaining > time.Second {
+		t.Errorf("want the deadline of the request context, have %v", remaining)
+	}
+}
+
+func TestGRPCClientWaitForReady(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens
+
+	cc, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	for _, tc := range []struct {
+		options []grpctransport.ClientOption
+		want    codes.Code
+	}{
+		{nil, codes.Unavailable},
+		{[]grpctransport.ClientOption{grpctransport.ClientWaitForReady()}, codes.DeadlineExceeded},
+	} {
+		client := grpctransport.NewClient(cc, "pb.Intercepted", "Echo",
+			func(_ context.Context, request interface{}) (interface{}, error) { return &pb.TestRequest{}, nil },
+			func(_ context.Context, response interface{}) (interface{}, error) { return response, nil },
+			pb.TestResponse{},
+			append(tc.options, grpctransport.ClientTimeout(200*time.Millisecond))...,
+		)
+		_, err := client.Endpoint()(context.Background(), nil)
+		if want, have := tc.want, status.Code(err); want != have {
+			t.Errorf("want %s, have %s (%v)", want, have, err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+)
+
+// RetryPolicy is the policy with which gRPC retries failed calls, without
+// them reaching the endpoint, including kit Client endpoints. Zero fields
+// have the defaults of DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts       int           // attempts, including the first, at most 5
+	InitialBackoff    time.Duration // backoff of the first retry
+	MaxBackoff        time.Duration // maximum backoff
+	BackoffMultiplier float64       // growth of the backoff of each retry
+	RetryableCodes    []codes.Code  // codes of failures which may be retried
+	Services          []string      // services it applies to, e.g. "pb.Add"; all, if empty
+}
+
+// DefaultRetryPolicy retries failures with code Unavailable twice.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        time.Second,
+	BackoffMultiplier: 2,
+	RetryableCodes:    []codes.Code{codes.Unavailable},
+}
+
+// ClientConnOption sets an optional parameter for NewClientConn.
+type ClientConnOption func(*clientConnConfig)
+
+// ClientConnInsecure dials without transport security, e.g. within a
+// service mesh which provides it. Otherwise, transport credentials must be
+// given with ClientConnOptions.
+func ClientConnInsecure() ClientConnOption {
+	return func(c *clientConnConfig) { c.options = append(c.options, grpc.WithInsecure()) }
+}
+
+// ClientConnKeepalive sets the keepalive parameters of the connection, so
+// that broken connections are detected while idle. Servers close the
+// connections of clients pinging more often than they allow; see
+// GRPCServerKeepaliveEnforcement. By default, no keepalive pings are sent.
+func ClientConnKeepalive(p keepalive.ClientParameters) ClientConnOption {
+	return func(c *clientConnConfig) { c.options = append(c.options, grpc.WithKeepaliveParams(p)) }
+}
+
+// ClientConnBackoff sets the backoff between attempts to connect, and the
+// minimum time given to each attempt. By default, grpc-go's defaults, of
+// backoff.DefaultConfig and 20 seconds, are used.
+func ClientConnBackoff(b backoff.Config, minConnectTimeout time.Duration) ClientConnOption {
+	return func(c *clientConnConfig) {
+		c.options = append(c.options, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           b,
+			MinConnectTimeout: minConnectTimeout,
+		}))
+	}
+}
+
+// ClientConnRetryPolicy sets the policy with which gRPC retries failed
+// calls. It may be given more than once, for different services. By
+// default, calls aren't retried, unless the service config of the resolver
+// provides a policy.
+func ClientConnRetryPolicy(p RetryPolicy) ClientConnOption {
+	return func(c *clientConnConfig) { c.retryPolicies = append(c.retryPolicies, p) }
+}
+
+// ClientConnBalancer sets the load balancing policy, e.g. "round_robin". By
+// default, the first address is picked.
+func ClientConnBalancer(policy string) ClientConnOption {
+	return func(c *clientConnConfig) { c.balancer = policy }
+}
+
+// ClientConnOptions adds other options of the grpc.ClientConn, e.g.
+// credentials, or grpc.WithResolvers with a Resolver.
+func ClientConnOptions(options ...grpc.DialOption) ClientConnOption {
+	return func(c *clientConnConfig) { c.options = append(c.options, options...) }
+}
+
+type clientConnConfig struct {
+	options       []grpc.DialOption
+	retryPolicies []RetryPolicy
+	balancer      string
+}
+
+// NewClientConn dials the target, with the options, returning a ClientConn
+// for kit Clients of NewClient and NewStreamClient. Retry policies and the
+// load balancing policy are given to gRPC as the default service config,
+// which a service config of the resolver overrides.
+func NewClientConn(target string, options ...ClientConnOption) (*grpc.ClientConn, error) {
+	c := &clientConnConfig{}
+	for _, option := range options {
+		option(c)
+	}
+	dialOptions := c.options
+	if len(c.retryPolicies) > 0 || c.balancer != "" {
+		dialOptions = append(dialOptions, grpc.WithDefaultServiceConfig(c.serviceConfig()))
+	}
+	return grpc.Dial(target, dialOptions...)
+}
+
+// serviceConfig returns the JSON service config of the retry and load
+// balancing policies.
+func (c *clientConnConfig) serviceConfig() string {
+	type name struct {
+		Service string `json:"service,omitempty"`
+	}
+	type retryPolicy struct {
+		MaxAttempts          int      `json:"maxAttempts"`
+		InitialBackoff       string   `json:"initialBackoff"`
+		MaxBackoff           string   `json:"maxBackoff"`
+		BackoffMultiplier    float64  `json:"backoffMultiplier"`
+		RetryableStatusCodes []string `json:"retryableStatusCodes"`
+	}
+	type methodConfig struct {
+		Name        []name      `json:"name"`
+		RetryPolicy retryPolicy `json:"retryPolicy"`
+	}
+	var config struct {
+		LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig,omitempty"`
+		MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	}
+	if c.balancer != "" {
+		config.LoadBalancingConfig = []map[string]struct{}{{c.balancer: {}}}
+	}
+	for _, p := range c.retryPolicies {
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+		}
+		if p.InitialBackoff == 0 {
+			p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+		}
+		if p.MaxBackoff == 0 {
+			p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+		}
+		if p.BackoffMultiplier == 0 {
+			p.BackoffMultiplier = DefaultRetryPolicy.BackoffMultiplier
+		}
+		if len(p.RetryableCodes) == 0 {
+			p.RetryableCodes = DefaultRetryPolicy.RetryableCodes
+		}
+		mc := methodConfig{RetryPolicy: retryPolicy{
+			MaxAttempts:       p.MaxAttempts,
+			InitialBackoff:    durationJSON(p.InitialBackoff),
+			MaxBackoff:        durationJSON(p.MaxBackoff),
+			BackoffMultiplier: p.BackoffMultiplier,
+		}}
+		for _, code := range p.RetryableCodes {
+			mc.RetryPolicy.RetryableStatusCodes = append(mc.RetryPolicy.RetryableStatusCodes, codeJSON(code))
+		}
+		for _, service := range p.Services {
+			mc.Name = append(mc.Name, name{service})
+		}
+		if len(mc.Name) == 0 {
+			mc.Name = []name{{}} // all services
+		}
+		config.MethodConfig = append(config.MethodConfig, mc)
+	}
+	buf, _ := json.Marshal(config)
+	return string(buf)
+}
+
+// durationJSON formats the duration as a service config does, e.g. "0.1s".
+func durationJSON(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// codeJSON formats the code as a service config does, e.g. "UNAVAILABLE" or
+// "DEADLINE_EXCEEDED".
+func codeJSON(code codes.Code) string {
+	s := code.String()
+	var b bytes.Buffer
+	for i, r := range s {
+		if i > 0 && r >= 'A' && r <= 'Z' && s[i-1] >= 'a' && s[i-1] <= 'z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToUpper(b.String())
+}
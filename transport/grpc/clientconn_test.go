@@ -0,0 +1,87 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+func TestNewClientConnRetryPolicy(t *testing.T) {
+	var calls int32
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Flaky",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Call",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				if err := dec(&pb.TestRequest{}); err != nil {
+					return nil, err
+				}
+				if n := atomic.AddInt32(&calls, 1); n%3 != 0 {
+					return nil, status.Error(codes.Unavailable, "try again")
+				}
+				return &pb.TestResponse{V: "ok"}, nil
+			},
+		}},
+	}, struct{}{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Stop()
+
+	call := func(options ...grpctransport.ClientConnOption) error {
+		cc, err := grpctransport.NewClientConn(ln.Addr().String(), append(options,
+			grpctransport.ClientConnInsecure(),
+			grpctransport.ClientConnKeepalive(keepalive.ClientParameters{Time: time.Minute}),
+			grpctransport.ClientConnBackoff(backoff.DefaultConfig, time.Second),
+			grpctransport.ClientConnBalancer("round_robin"),
+		)...)
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		_, err = grpctransport.NewClient(cc, "pb.Flaky", "Call",
+			func(context.Context, interface{}) (interface{}, error) { return &pb.TestRequest{}, nil },
+			func(_ context.Context, response interface{}) (interface{}, error) { return response, nil },
+			pb.TestResponse{},
+		).Endpoint()(context.Background(), nil)
+		return err
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := call(); status.Code(err) != codes.Unavailable {
+		t.Errorf("without retries: want Unavailable, have %v", err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := call(grpctransport.ClientConnRetryPolicy(grpctransport.RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		Services:       []string{"pb.Flaky"},
+	})); err != nil {
+		t.Errorf("with retries: want success, have %v", err)
+	}
+	if want, have := int32(3), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := call(grpctransport.ClientConnRetryPolicy(grpctransport.RetryPolicy{
+		RetryableCodes: []codes.Code{codes.DeadlineExceeded},
+	})); status.Code(err) != codes.Unavailable {
+		t.Errorf("with other retryable codes: want Unavailable, have %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/go-kit/kit/sd"
+)
+
+// DrainOption sets an optional parameter for Drain.
+type DrainOption func(*drainConfig)
+
+// DrainHealth reports the services of the Health as not serving, first, so
+// that load balancers and health probes stop routing to the server. Its
+// registrar, if any, is deregistered too.
+func DrainHealth(h *Health) DrainOption {
+	return func(c *drainConfig) { c.health = h }
+}
+
+// DrainRegistrar deregisters the instance from service discovery, after the
+// health service reports it as not serving.
+func DrainRegistrar(r sd.Registrar) DrainOption {
+	return func(c *drainConfig) { c.registrars = append(c.registrars, r) }
+}
+
+// DrainDelay sets how long Drain waits, once the instance is not serving and
+// deregistered, before it stops accepting connections, giving clients time
+// to notice. By default, there's no delay.
+func DrainDelay(d time.Duration) DrainOption {
+	return func(c *drainConfig) { c.delay = d }
+}
+
+// DrainTimeout sets the maximum duration of the drain, including the delay,
+// after which the server is stopped, canceling the RPCs still in flight. It
+// applies if it's sooner than the deadline of the context given to Drain.
+// By default, there's only the context's deadline.
+func DrainTimeout(d time.Duration) DrainOption {
+	return func(c *drainConfig) { c.timeout = d }
+}
+
+type drainConfig struct {
+	health     *Health
+	registrars []sd.Registrar
+	delay      time.Duration
+	timeout    time.Duration
+}
+
+// Drain gracefully stops the server, in order: it reports the services of
+// the Health as not serving, deregisters the instance, waits for the drain
+// delay, then stops accepting connections, and waits for the RPCs in flight
+// to complete. If the drain timeout elapses, or the context is done, first,
+// the server is stopped immediately, canceling the remaining RPCs, and the
+// context's error is returned. It mirrors the Drainer of package
+// transport/http.
+func Drain(ctx context.Context, s *grpc.Server, options ...DrainOption) error {
+	c := &drainConfig{}
+	for _, option := range options {
+		option(c)
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.health != nil {
+		c.health.setServing(false)
+		if c.health.registrar != nil {
+			c.health.registrar.Deregister()
+		}
+	}
+	for _, r := range c.registrars {
+		r.Deregister()
+	}
+
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+			s.Stop()
+			return ctx.Err()
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,106 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+// startSlowServer starts a server whose pb.Slow/Wait method waits for B
+// milliseconds, and returns a function calling it in the background.
+func startSlowServer(t *testing.T) (*grpc.Server, func(ms int64) <-chan error) {
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Slow",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &pb.TestRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				select {
+				case <-time.After(time.Duration(req.B) * time.Millisecond):
+					return &pb.TestResponse{}, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			},
+		}},
+	}, struct{}{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	cc, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(ms int64) <-chan error {
+		errc := make(chan error, 1)
+		go func() {
+			errc <- cc.Invoke(context.Background(), "/pb.Slow/Wait", &pb.TestRequest{B: ms}, &pb.TestResponse{})
+		}()
+		time.Sleep(50 * time.Millisecond) // let the call begin
+		return errc
+	}
+	return server, call
+}
+
+func TestDrain(t *testing.T) {
+	server, call := startSlowServer(t)
+
+	registrar := &recordingRegistrar{}
+	h := grpctransport.NewHealth(nil, nil)
+	registrar.health = h
+	h.Register()
+
+	errc := call(200)
+	begin := time.Now()
+	if err := grpctransport.Drain(context.Background(), server,
+		grpctransport.DrainHealth(h),
+		grpctransport.DrainRegistrar(registrar),
+		grpctransport.DrainDelay(10*time.Millisecond),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("want the call in flight to complete, have %v", err)
+	}
+	if took := time.Since(begin); took < 100*time.Millisecond {
+		t.Errorf("want Drain to wait for the call in flight, took %v", took)
+	}
+	if h.Serving() {
+		t.Error("want not serving after Drain")
+	}
+	if want, have := []string{"deregister", "deregistered while not serving"}, registrar.events; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	server, call := startSlowServer(t)
+
+	errc := call(10000)
+	if want, have := context.DeadlineExceeded, grpctransport.Drain(context.Background(), server, grpctransport.DrainTimeout(100*time.Millisecond)); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("want the call in flight to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call in flight wasn't canceled")
+	}
+}
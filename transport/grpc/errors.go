@@ -0,0 +1,223 @@
+package grpc
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ErrorDetailsFunc returns the google.rpc error details of an error, e.g. an
+// errdetails.BadRequest, which servers send with the status of the RPC.
+type ErrorDetailsFunc func(err error) []proto.Message
+
+// DefaultErrorDetails is the ErrorDetailsFunc of servers, unless it's set
+// with ServerErrorDetails. It looks through the error, and those it wraps,
+// for
+//
+//   - an endpoint.ValidationError, sent as a BadRequest of its fields,
+//   - an error with a RetryAfter() time.Duration method, sent as RetryInfo,
+//   - an error with a Reason() string method, and optionally Domain() string
+//     and Metadata() map[string]string methods, sent as ErrorInfo.
+//
+// A StatusError, decoded by DecodeError, is sent with its details unchanged.
+func DefaultErrorDetails(err error) []proto.Message {
+	if e, ok := err.(StatusError); ok {
+		var details []proto.Message
+		for _, d := range e.Status.Details() {
+			if m, ok := d.(proto.Message); ok {
+				details = append(details, m)
+			}
+		}
+		return details
+	}
+
+	var (
+		details                      []proto.Message
+		validation, retry, errorInfo bool
+	)
+	for ; err != nil; err = unwrap(err) {
+		if e, ok := err.(endpoint.ValidationError); ok && !validation && len(e.Fields) > 0 {
+			validation = true
+			br := &errdetails.BadRequest{}
+			for _, f := range e.Fields {
+				br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       f.Field,
+					Description: f.Message,
+				})
+			}
+			details = append(details, br)
+		}
+		if e, ok := err.(interface {
+			RetryAfter() time.Duration
+		}); ok && !retry {
+			retry = true
+			details = append(details, &errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(e.RetryAfter())})
+		}
+		if e, ok := err.(interface {
+			Reason() string
+		}); ok && !errorInfo {
+			errorInfo = true
+			info := &errdetails.ErrorInfo{Reason: e.Reason()}
+			if d, ok := err.(interface {
+				Domain() string
+			}); ok {
+				info.Domain = d.Domain()
+			}
+			if m, ok := err.(interface {
+				Metadata() map[string]string
+			}); ok {
+				info.Metadata = m.Metadata()
+			}
+			details = append(details, info)
+		}
+	}
+	return details
+}
+
+// endpointError maps errors of a known endpoint.Kind to gRPC statuses, with
+// the error details returned by the ErrorDetailsFunc. Errors of unknown kind
+// with details have code Unknown; others are returned unchanged.
+func endpointError(err error, detailsFunc ErrorDetailsFunc) error {
+	if e, ok := err.(StatusError); ok {
+		return e.Status.Err()
+	}
+	var details []proto.Message
+	if detailsFunc != nil {
+		details = detailsFunc(err)
+	}
+	code, ok := kindCodes[endpoint.KindOf(err)]
+	if !ok {
+		if len(details) == 0 {
+			return err
+		}
+		code = codes.Unknown
+	}
+	s := status.New(code, err.Error())
+	if len(details) > 0 {
+		if withDetails, detailsErr := s.WithDetails(details...); detailsErr == nil {
+			s = withDetails
+		}
+	}
+	return s.Err()
+}
+
+var kindCodes = map[endpoint.Kind]codes.Code{
+	endpoint.KindInvalidArgument:    codes.InvalidArgument,
+	endpoint.KindNotFound:           codes.NotFound,
+	endpoint.KindAlreadyExists:      codes.AlreadyExists,
+	endpoint.KindPermissionDenied:   codes.PermissionDenied,
+	endpoint.KindUnauthenticated:    codes.Unauthenticated,
+	endpoint.KindFailedPrecondition: codes.FailedPrecondition,
+	endpoint.KindResourceExhausted:  codes.ResourceExhausted,
+	endpoint.KindCanceled:           codes.Canceled,
+	endpoint.KindDeadlineExceeded:   codes.DeadlineExceeded,
+	endpoint.KindUnimplemented:      codes.Unimplemented,
+	endpoint.KindUnavailable:        codes.Unavailable,
+	endpoint.KindInternal:           codes.Internal,
+}
+
+// StatusError is a gRPC status error, as decoded by DecodeError. It has the
+// endpoint.Kind of its code, and the RetryAfter, Reason, Domain, and
+// Metadata methods recognized by DefaultErrorDetails, from the RetryInfo and
+// ErrorInfo details of the status, so that it's sent on unchanged if it's
+// returned by a server.
+type StatusError struct {
+	Status *status.Status
+}
+
+// Error implements error.
+func (e StatusError) Error() string {
+	return e.Status.Message()
+}
+
+// GRPCStatus returns the status, so that status.FromError and status.Code
+// work with StatusErrors.
+func (e StatusError) GRPCStatus() *status.Status {
+	return e.Status
+}
+
+// Kind returns the endpoint.Kind of the code of the status.
+func (e StatusError) Kind() endpoint.Kind {
+	for kind, code := range kindCodes {
+		if code == e.Status.Code() {
+			return kind
+		}
+	}
+	return endpoint.KindUnknown
+}
+
+// RetryAfter returns the retry delay of the RetryInfo details, or zero.
+func (e StatusError) RetryAfter() time.Duration {
+	for _, d := range e.Status.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+			delay, _ := ptypes.Duration(ri.RetryDelay)
+			return delay
+		}
+	}
+	return 0
+}
+
+// Reason returns the reason of the ErrorInfo details, or "".
+func (e StatusError) Reason() string { return e.errorInfo().GetReason() }
+
+// Domain returns the domain of the ErrorInfo details, or "".
+func (e StatusError) Domain() string { return e.errorInfo().GetDomain() }
+
+// Metadata returns the metadata of the ErrorInfo details, or nil.
+func (e StatusError) Metadata() map[string]string { return e.errorInfo().GetMetadata() }
+
+func (e StatusError) errorInfo() *errdetails.ErrorInfo {
+	for _, d := range e.Status.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// DecodeError may be used with ClientErrorDecoder. It decodes gRPC status
+// errors of code InvalidArgument with BadRequest details as
+// endpoint.ValidationErrors, and other status errors as StatusErrors, so
+// that clients see the structured errors of the server. Other errors are
+// returned unchanged.
+func DecodeError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() == codes.OK {
+		return err
+	}
+	if s.Code() == codes.InvalidArgument {
+		for _, d := range s.Details() {
+			br, ok := d.(*errdetails.BadRequest)
+			if !ok {
+				continue
+			}
+			v := endpoint.ValidationError{}
+			details := make([]string, len(br.FieldViolations))
+			for i, f := range br.FieldViolations {
+				v.Fields = append(v.Fields, endpoint.FieldError{Field: f.Field, Message: f.Description})
+				details[i] = f.Field + ": " + f.Description
+			}
+			// The message of the status is the Error of the ValidationError,
+			// which ends with its fields.
+			v.Message = strings.TrimSuffix(s.Message(), ": "+strings.Join(details, "; "))
+			return v
+		}
+	}
+	return StatusError{Status: s}
+}
+
+func unwrap(err error) error {
+	if u, ok := err.(interface {
+		Unwrap() error
+	}); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+// busyError has the methods recognized by DefaultErrorDetails.
+type busyError struct{}
+
+func (busyError) Error() string               { return "busy" }
+func (busyError) RetryAfter() time.Duration   { return 3 * time.Second }
+func (busyError) Reason() string              { return "OVERLOADED" }
+func (busyError) Domain() string              { return "example.com" }
+func (busyError) Metadata() map[string]string { return map[string]string{"shard": "7"} }
+
+func TestErrorDetails(t *testing.T) {
+	failures := map[string]error{
+		"invalid": endpoint.ValidationError{Message: "bad test", Fields: []endpoint.FieldError{{Field: "a", Message: "is required"}}},
+		"busy":    endpoint.Unavailable(busyError{}),
+		"plain":   errors.New("plain"),
+	}
+	handler := grpctransport.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			return nil, failures[request.(string)]
+		},
+		func(_ context.Context, req interface{}) (interface{}, error) { return req.(*pb.TestRequest).A, nil },
+		func(_ context.Context, response interface{}) (interface{}, error) { return response, nil },
+	)
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Errors",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Fail",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &pb.TestRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				_, resp, err := handler.ServeGRPC(ctx, req)
+				return resp, err
+			},
+		}},
+	}, struct{}{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Stop()
+	cc, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	e := grpctransport.NewClient(cc, "pb.Errors", "Fail",
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			return &pb.TestRequest{A: request.(string)}, nil
+		},
+		func(_ context.Context, response interface{}) (interface{}, error) { return response, nil },
+		pb.TestResponse{},
+		grpctransport.ClientErrorDecoder(grpctransport.DecodeError),
+	).Endpoint()
+
+	_, err = e(context.Background(), "invalid")
+	if want, have := failures["invalid"], err; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+
+	_, err = e(context.Background(), "busy")
+	se, ok := err.(grpctransport.StatusError)
+	if !ok {
+		t.Fatalf("want StatusError, have %T: %v", err, err)
+	}
+	if want, have := codes.Unavailable, status.Code(err); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := endpoint.KindUnavailable, endpoint.KindOf(err); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 3*time.Second, se.RetryAfter(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if se.Reason() != "OVERLOADED" || se.Domain() != "example.com" || se.Metadata()["shard"] != "7" {
+		t.Errorf("want the ErrorInfo of busyError, have %q %q %v", se.Reason(), se.Domain(), se.Metadata())
+	}
+	if want, have := "busy", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// A StatusError returned by a server is sent with its details unchanged.
+	if want, have := 2, len(grpctransport.DefaultErrorDetails(se)); want != have {
+		t.Errorf("want %d details, have %d", want, have)
+	}
+
+	_, err = e(context.Background(), "plain")
+	if want, have := codes.Unknown, status.Code(err); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 0, len(err.(grpctransport.StatusError).Status.Details()); want != have {
+		t.Errorf("want %d details, have %d", want, have)
+	}
+}
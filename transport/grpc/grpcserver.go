@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/stats"
+)
+
+// GRPCServerOption sets an optional parameter for NewGRPCServer.
+type GRPCServerOption func(*grpcServerConfig)
+
+// GRPCServerReflection registers the server reflection service, so that
+// tools such as grpcurl can list and call the services without their
+// protobuf definitions. By default, it isn't registered.
+func GRPCServerReflection() GRPCServerOption {
+	return func(c *grpcServerConfig) { c.reflection = true }
+}
+
+// GRPCServerChannelz registers the channelz service, which reports the
+// state of the server's connections and calls for debugging. By default, it
+// isn't registered.
+func GRPCServerChannelz() GRPCServerOption {
+	return func(c *grpcServerConfig) { c.channelz = true }
+}
+
+// GRPCServerHealth registers the health service of the Health with the
+// server. By default, no health service is registered.
+func GRPCServerHealth(h *Health) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.health = h }
+}
+
+// GRPCServerKeepalive sets the keepalive parameters of the server, e.g. to
+// close idle connections, or cycle long-lived ones so that clients
+// rebalance. By default, grpc-go's defaults are used.
+func GRPCServerKeepalive(p keepalive.ServerParameters) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.options = append(c.options, grpc.KeepaliveParams(p)) }
+}
+
+// GRPCServerKeepaliveEnforcement sets the policy with which the server
+// enforces the keepalive pings of clients, closing the connections of those
+// pinging too often. By default, grpc-go's defaults are used, which allow a
+// ping every five minutes, and only with active calls.
+func GRPCServerKeepaliveEnforcement(p keepalive.EnforcementPolicy) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.options = append(c.options, grpc.KeepaliveEnforcementPolicy(p)) }
+}
+
+// GRPCServerStatsHandler sets a handler of the server's stats, e.g. of
+// package tracing. It may be given more than once.
+func GRPCServerStatsHandler(h stats.Handler) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.options = append(c.options, grpc.StatsHandler(h)) }
+}
+
+// GRPCServerInterceptors adds unary interceptors, e.g. those returned by
+// UnaryServerInterceptor and MiddlewareServerInterceptor, which are chained
+// in the order given.
+func GRPCServerInterceptors(interceptors ...grpc.UnaryServerInterceptor) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.unary = append(c.unary, interceptors...) }
+}
+
+// GRPCServerStreamInterceptors adds stream interceptors, e.g. those returned
+// by StreamServerInterceptor, which are chained in the order given.
+func GRPCServerStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.stream = append(c.stream, interceptors...) }
+}
+
+// GRPCServerOptions adds other options of the grpc.Server, e.g. credentials
+// or message size limits.
+func GRPCServerOptions(options ...grpc.ServerOption) GRPCServerOption {
+	return func(c *grpcServerConfig) { c.options = append(c.options, options...) }
+}
+
+type grpcServerConfig struct {
+	options    []grpc.ServerOption
+	unary      []grpc.UnaryServerInterceptor
+	stream     []grpc.StreamServerInterceptor
+	reflection bool
+	channelz   bool
+	health     *Health
+}
+
+// NewGRPCServer returns a grpc.Server configured by the options, on which
+// register registers the services, typically with the RegisterXxxServer
+// function generated for each service, given a binding of kit Servers. The
+// reflection, channelz, and health services are registered after, if
+// they're enabled, so that reflection lists the services.
+func NewGRPCServer(register func(*grpc.Server), options ...GRPCServerOption) *grpc.Server {
+	c := &grpcServerConfig{}
+	for _, option := range options {
+		option(c)
+	}
+	serverOptions := c.options
+	if len(c.unary) > 0 {
+		serverOptions = append(serverOptions, grpc.ChainUnaryInterceptor(c.unary...))
+	}
+	if len(c.stream) > 0 {
+		serverOptions = append(serverOptions, grpc.ChainStreamInterceptor(c.stream...))
+	}
+
+	s := grpc.NewServer(serverOptions...)
+	if register != nil {
+		register(s)
+	}
+	if c.health != nil {
+		c.health.RegisterHealthServer(s)
+	}
+	if c.channelz {
+		channelz.RegisterChannelzServiceToServer(s)
+	}
+	if c.reflection {
+		reflection.Register(s)
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+func TestNewGRPCServer(t *testing.T) {
+	var calls []string
+	intercept := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name+" "+info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+	health := grpctransport.NewHealth(nil, nil)
+	server := grpctransport.NewGRPCServer(
+		func(s *grpc.Server) {
+			s.RegisterService(&grpc.ServiceDesc{ServiceName: "pb.Empty", HandlerType: (*interface{})(nil)}, struct{}{})
+		},
+		grpctransport.GRPCServerReflection(),
+		grpctransport.GRPCServerChannelz(),
+		grpctransport.GRPCServerHealth(health),
+		grpctransport.GRPCServerInterceptors(intercept("first"), intercept("second")),
+	)
+
+	var services []string
+	for name := range server.GetServiceInfo() {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	for _, want := range []string{"grpc.channelz.v1.Channelz", "grpc.health.v1.Health", "grpc.reflection.v1.ServerReflection", "pb.Empty"} {
+		if i := sort.SearchStrings(services, want); i == len(services) || services[i] != want {
+			t.Errorf("want %s registered, have %v", want, services)
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Stop()
+	cc, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	health.Register()
+	resp, err := healthpb.NewHealthClient(cc).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := healthpb.HealthCheckResponse_SERVING, resp.Status; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	want := []string{"first /grpc.health.v1.Health/Check", "second /grpc.health.v1.Health/Check"}
+	if !reflect.DeepEqual(want, calls) {
+		t.Errorf("want %v, have %v", want, calls)
+	}
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/go-kit/kit/sd"
+)
+
+// HealthOption sets an optional parameter for Healths.
+type HealthOption func(*Health)
+
+// HealthDrainDelay sets how long Shutdown reports the services as not
+// serving before it stops the server, giving load balancers time to stop
+// routing to it. By default, there's no delay.
+func HealthDrainDelay(d time.Duration) HealthOption {
+	return func(h *Health) { h.delay = d }
+}
+
+// Health runs the standard grpc.health.v1 health service, and keeps its
+// serving status in lockstep with the registration of the instance, so that
+// service discovery, load balancers, and health probes agree on whether the
+// instance is serving.
+//
+// Health implements sd.Registrar: Register reports the services as serving,
+// then registers the instance, and Deregister deregisters the instance, then
+// reports the services as not serving. Use it in place of the wrapped
+// Registrar.
+type Health struct {
+	registrar sd.Registrar
+	services  []string
+	server    *health.Server
+	delay     time.Duration
+
+	mtx     sync.Mutex
+	serving bool
+}
+
+// NewHealth returns a Health reporting the overall health of the server,
+// with the empty service name, and the health of each of the named services,
+// which are not serving until Register is called. The registrar may be nil,
+// if the instance isn't registered with a service discovery system.
+func NewHealth(registrar sd.Registrar, services []string, options ...HealthOption) *Health {
+	h := &Health{
+		registrar: registrar,
+		services:  append([]string{""}, services...),
+		server:    health.NewServer(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	h.setServing(false)
+	return h
+}
+
+// RegisterHealthServer registers the health service with the gRPC server.
+func (h *Health) RegisterHealthServer(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, h.server)
+}
+
+// Serving returns true if the services are reported as serving.
+func (h *Health) Serving() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.serving
+}
+
+// Register implements sd.Registrar. It reports the services as serving, so
+// that they pass health checks by the time they're discovered, then
+// registers the instance.
+func (h *Health) Register() {
+	h.setServing(true)
+	if h.registrar != nil {
+		h.registrar.Register()
+	}
+}
+
+// Deregister implements sd.Registrar. It deregisters the instance, so that
+// it's no longer discovered, then reports the services as not serving.
+func (h *Health) Deregister() {
+	if h.registrar != nil {
+		h.registrar.Deregister()
+	}
+	h.setServing(false)
+}
+
+// Shutdown drains the server with Drain, given the Health and its drain
+// delay: the services are reported as not serving, then the instance is
+// deregistered, and the server gracefully stopped. If the context is done
+// first, the server is stopped immediately, and the context's error is
+// returned.
+func (h *Health) Shutdown(ctx context.Context, s *grpc.Server) error {
+	return Drain(ctx, s, DrainHealth(h), DrainDelay(h.delay))
+}
+
+func (h *Health) setServing(serving bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	for _, service := range h.services {
+		h.server.SetServingStatus(service, status)
+	}
+	h.serving = serving
+}
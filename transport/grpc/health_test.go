@@ -0,0 +1,93 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+type recordingRegistrar struct {
+	health *grpctransport.Health
+	events []string
+}
+
+func (r *recordingRegistrar) Register() {
+	r.events = append(r.events, "register")
+	if !r.health.Serving() {
+		r.events = append(r.events, "registered while not serving")
+	}
+}
+
+func (r *recordingRegistrar) Deregister() {
+	r.events = append(r.events, "deregister")
+	if !r.health.Serving() {
+		r.events = append(r.events, "deregistered while not serving")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	registrar := &recordingRegistrar{}
+	h := grpctransport.NewHealth(registrar, []string{"pb.Test"})
+	registrar.health = h
+
+	server := grpc.NewServer()
+	h.RegisterHealthServer(server)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	cc, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	client := healthpb.NewHealthClient(cc)
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+
+	for _, service := range []string{"", "pb.Test"} {
+		if want, have := healthpb.HealthCheckResponse_NOT_SERVING, check(service); want != have {
+			t.Errorf("%q before Register: want %s, have %s", service, want, have)
+		}
+	}
+	h.Register()
+	for _, service := range []string{"", "pb.Test"} {
+		if want, have := healthpb.HealthCheckResponse_SERVING, check(service); want != have {
+			t.Errorf("%q after Register: want %s, have %s", service, want, have)
+		}
+	}
+	h.Deregister()
+	if want, have := healthpb.HealthCheckResponse_NOT_SERVING, check("pb.Test"); want != have {
+		t.Errorf("after Deregister: want %s, have %s", want, have)
+	}
+	h.Register()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+	if h.Serving() {
+		t.Error("want not serving after Shutdown")
+	}
+
+	// Shutdown reports the services as not serving before deregistering.
+	want := []string{"register", "deregister", "register", "deregister", "deregistered while not serving"}
+	if have := registrar.events; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+	oldcontext "golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// MiddlewareServerInterceptor returns a gRPC interceptor which runs unary RPCs
+// through the middleware, so that kit middlewares may be used with servers
+// which don't use package transport/grpc. The endpoint wrapped by the
+// middleware invokes the RPC's handler, with the gRPC request and response
+// messages; the incoming metadata is in the context, as usual.
+func MiddlewareServerInterceptor(m endpoint.Middleware) grpc.UnaryServerInterceptor {
+	return func(
+		ctx oldcontext.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		next := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return handler(ctx, request)
+		}
+		return m(next)(ctx, req)
+	}
+}
+
+// MiddlewareClientInterceptor returns a gRPC interceptor which runs unary RPCs
+// through the middleware, so that kit middlewares may be used with clients
+// which don't use package transport/grpc. The endpoint wrapped by the
+// middleware invokes the RPC, with the gRPC request message, and returns the
+// reply message. Outgoing metadata set in the context by the middleware is
+// sent with the RPC.
+//
+// If the middleware returns a response other than the reply, e.g. from a
+// cache, it must be a protobuf message of the reply's type, and it's copied
+// into the reply.
+func MiddlewareClientInterceptor(m endpoint.Middleware) grpc.UnaryClientInterceptor {
+	return func(
+		ctx oldcontext.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		next := func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := invoker(ctx, method, request, reply, cc, opts...); err != nil {
+				return nil, err
+			}
+			return reply, nil
+		}
+		response, err := m(next)(ctx, req)
+		if err != nil || response == reply {
+			return err
+		}
+		dst, ok := reply.(proto.Message)
+		src, ok2 := response.(proto.Message)
+		if !ok || !ok2 || reflect.TypeOf(dst) != reflect.TypeOf(src) {
+			return fmt.Errorf("middleware returned %T for a reply of %T", response, reply)
+		}
+		dst.Reset()
+		proto.Merge(dst, src)
+		return nil
+	}
+}
+
+// ServerInterceptorMiddleware returns a middleware which runs requests
+// through the gRPC server interceptor, so that interceptors, e.g. for
+// authentication, recovery, or validation, may be used in kit stacks. The
+// interceptor is given the fullMethod, e.g. "/pb.Add/Sum", and a handler
+// which invokes the next endpoint. Note that it sees the endpoint's request
+// and response, which, behind a Server of this package, are the decoded
+// domain types rather than protobuf messages. The incoming metadata of the
+// Server's RPC is in the context.
+func ServerInterceptorMiddleware(i grpc.UnaryServerInterceptor, fullMethod string) endpoint.Middleware {
+	info := &grpc.UnaryServerInfo{FullMethod: fullMethod}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return i(ctx, request, info, func(ctx context.Context, request interface{}) (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
+
+// ClientInterceptorMiddleware returns a middleware which runs requests
+// through the gRPC client interceptor, so that interceptors, e.g. for
+// authentication or retries, may be used in kit stacks. The interceptor is
+// given the method, e.g. "/pb.Add/Sum", and an invoker which invokes the
+// next endpoint, which may happen more than once; the response of the last
+// invocation is returned. The reply the interceptor sees is a pointer to the
+// response, as an *interface{}, and its ClientConn is nil. Outgoing metadata
+// set in the context by the interceptor is sent by a Client of this package.
+func ClientInterceptorMiddleware(i grpc.UnaryClientInterceptor, method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			var response interface{}
+			invoker := func(ctx context.Context, _ string, req, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				resp, err := next(ctx, req)
+				if err != nil {
+					return err
+				}
+				response = resp
+				return nil
+			}
+			if err := i(ctx, method, request, &response, nil, invoker); err != nil {
+				return nil, err
+			}
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+// userMiddleware appends the x-user of the incoming metadata to the response.
+func userMiddleware(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		response, err := next(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		r := response.(*pb.TestResponse)
+		return &pb.TestResponse{V: r.V + " " + strings.Join(md["x-user"], ",")}, nil
+	}
+}
+
+func startInterceptedServer(t *testing.T, options ...grpc.DialOption) (*grpc.ClientConn, func()) {
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpctransport.MiddlewareServerInterceptor(userMiddleware)))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Intercepted",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &pb.TestRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				handler := func(_ context.Context, request interface{}) (interface{}, error) {
+					return &pb.TestResponse{V: request.(*pb.TestRequest).A}, nil
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/pb.Intercepted/Echo"}, handler)
+			},
+		}},
+	}, struct{}{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	cc, err := grpc.Dial(ln.Addr().String(), append(options, grpc.WithInsecure())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cc, func() { cc.Close(); server.Stop() }
+}
+
+func TestClientInterceptorMiddleware(t *testing.T) {
+	cc, stop := startInterceptedServer(t)
+	defer stop()
+
+	var method string
+	authenticate := func(ctx context.Context, m string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		method = m
+		return invoker(metadata.AppendToOutgoingContext(ctx, "x-user", "alice"), m, req, reply, cc, opts...)
+	}
+	client := grpctransport.NewClient(cc, "pb.Intercepted", "Echo",
+		func(_ context.Context, request interface{}) (interface{}, error) { return &pb.TestRequest{A: request.(string)}, nil },
+		func(_ context.Context, response interface{}) (interface{}, error) { return response.(*pb.TestResponse).V, nil },
+		pb.TestResponse{},
+		grpctransport.ClientBefore(grpctransport.SetRequestHeader("x-user", "bob")),
+	)
+	e := grpctransport.ClientInterceptorMiddleware(authenticate, "/pb.Intercepted/Echo")(client.Endpoint())
+
+	response, err := e(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hello alice,bob", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "/pb.Intercepted/Echo", method; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestMiddlewareClientInterceptor(t *testing.T) {
+	cached := func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if request.(*pb.TestRequest).A == "cached" {
+				return &pb.TestResponse{V: "from cache"}, nil
+			}
+			return next(metadata.AppendToOutgoingContext(ctx, "x-user", "carol"), request)
+		}
+	}
+	cc, stop := startInterceptedServer(t, grpc.WithUnaryInterceptor(grpctransport.MiddlewareClientInterceptor(cached)))
+	defer stop()
+
+	for _, tc := range []struct{ a, want string }{
+		{"cached", "from cache"},
+		{"hello", "hello carol"},
+	} {
+		reply := &pb.TestResponse{}
+		if err := cc.Invoke(context.Background(), "/pb.Intercepted/Echo", &pb.TestRequest{A: tc.a}, reply); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, reply.V; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestServerInterceptorMiddleware(t *testing.T) {
+	errDenied := errors.New("denied")
+	var fullMethod string
+	deny := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		fullMethod = info.FullMethod
+		if req == "bad" {
+			return nil, errDenied
+		}
+		return handler(ctx, req)
+	}
+	e := grpctransport.ServerInterceptorMiddleware(deny, "/pb.Test/Test")(func(_ context.Context, request interface{}) (interface{}, error) {
+		return request, nil
+	})
+
+	if response, err := e(context.Background(), "good"); err != nil || response != "good" {
+		t.Errorf("want good, have %v, %v", response, err)
+	}
+	if _, err := e(context.Background(), "bad"); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+	if want, have := "/pb.Test/Test", fullMethod; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,120 @@
+package grpc
+
+import (
+	"strings"
+	"time"
+
+	oldcontext "golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// ServerMetrics are the metrics recorded by the server interceptors. Any of
+// them may be nil, in which case it is skipped.
+//
+// Every metric is labeled with "service" and "method", parsed from the full
+// gRPC method name, and "type", which is one of "unary", "client_stream",
+// "server_stream", or "bidi_stream". Handled and Duration are additionally
+// labeled with "code", the gRPC status code of the result, e.g. "OK" or
+// "NotFound".
+type ServerMetrics struct {
+	Started     metrics.Counter   // RPCs started
+	Handled     metrics.Counter   // RPCs completed
+	Duration    metrics.Histogram // seconds taken to handle each RPC
+	MsgReceived metrics.Counter   // stream messages received from clients
+	MsgSent     metrics.Counter   // stream messages sent to clients
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that records the passed
+// metrics for every unary RPC. Install it with grpc.UnaryInterceptor.
+func UnaryServerInterceptor(m ServerMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx oldcontext.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done := m.start(info.FullMethod, "unary")
+		resp, err := handler(ctx, req)
+		done(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that records the passed
+// metrics for every streaming RPC. Install it with grpc.StreamInterceptor.
+func StreamServerInterceptor(m ServerMetrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		typ := streamType(info)
+		done := m.start(info.FullMethod, typ)
+		err := handler(srv, &monitoredStream{
+			ServerStream: ss,
+			labelValues:  methodLabelValues(info.FullMethod, typ),
+			m:            m,
+		})
+		done(err)
+		return err
+	}
+}
+
+// start records the start of an RPC, and returns a function to record its
+// completion.
+func (m ServerMetrics) start(fullMethod, typ string) func(error) {
+	var (
+		begin       = time.Now()
+		labelValues = methodLabelValues(fullMethod, typ)
+	)
+	if m.Started != nil {
+		m.Started.With(labelValues...).Add(1)
+	}
+	return func(err error) {
+		s, _ := status.FromError(err)
+		labelValues := append(labelValues, "code", s.Code().String())
+		if m.Handled != nil {
+			m.Handled.With(labelValues...).Add(1)
+		}
+		if m.Duration != nil {
+			m.Duration.With(labelValues...).Observe(time.Since(begin).Seconds())
+		}
+	}
+}
+
+type monitoredStream struct {
+	grpc.ServerStream
+	labelValues []string
+	m           ServerMetrics
+}
+
+func (s *monitoredStream) SendMsg(msg interface{}) error {
+	err := s.ServerStream.SendMsg(msg)
+	if err == nil && s.m.MsgSent != nil {
+		s.m.MsgSent.With(s.labelValues...).Add(1)
+	}
+	return err
+}
+
+func (s *monitoredStream) RecvMsg(msg interface{}) error {
+	err := s.ServerStream.RecvMsg(msg)
+	if err == nil && s.m.MsgReceived != nil {
+		s.m.MsgReceived.With(s.labelValues...).Add(1)
+	}
+	return err
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return "bidi_stream"
+	case info.IsClientStream:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
+
+// methodLabelValues splits a full method name, e.g. "/pkg.Service/Method",
+// into service and method labels.
+func methodLabelValues(fullMethod, typ string) []string {
+	service, method := "unknown", "unknown"
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		service, method = strings.TrimPrefix(fullMethod[:i], "/"), fullMethod[i+1:]
+	}
+	return []string{"service", service, "method", method, "type", typ}
+}
@@ -0,0 +1,156 @@
+package grpc_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-kit/kit/metrics"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var (
+		started  = &counter{}
+		handled  = &counter{}
+		duration = &histogram{}
+		m        = grpctransport.ServerMetrics{Started: started, Handled: handled, Duration: duration}
+		info     = &grpc.UnaryServerInfo{FullMethod: "/pb.Add/Sum"}
+	)
+	interceptor := grpctransport.UnaryServerInterceptor(m)
+	for _, err := range []error{nil, status.Error(codes.NotFound, "nope"), errors.New("plain")} {
+		_, have := interceptor(context.Background(), "req", info, func(context.Context, interface{}) (interface{}, error) {
+			return "resp", err
+		})
+		if want := err; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+
+	labels := "service=pb.Add,method=Sum,type=unary"
+	if want, have := fmt.Sprintf("[%s %s %s]", labels, labels, labels), started.String(); want != have {
+		t.Errorf("started: want %s, have %s", want, have)
+	}
+	want := fmt.Sprintf("[%s,code=NotFound %s,code=OK %s,code=Unknown]", labels, labels, labels)
+	if have := handled.String(); want != have {
+		t.Errorf("handled: want %s, have %s", want, have)
+	}
+	if have := duration.String(); want != have {
+		t.Errorf("duration: want %s, have %s", want, have)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	var (
+		handled  = &counter{}
+		received = &counter{}
+		sent     = &counter{}
+		m        = grpctransport.ServerMetrics{Handled: handled, MsgReceived: received, MsgSent: sent}
+		info     = &grpc.StreamServerInfo{FullMethod: "/pb.Add/Stream", IsClientStream: true, IsServerStream: true}
+	)
+	interceptor := grpctransport.StreamServerInterceptor(m)
+	err := interceptor(nil, &fakeStream{msgs: 2}, info, func(_ interface{}, ss grpc.ServerStream) error {
+		for {
+			if err := ss.RecvMsg(nil); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			ss.SendMsg(nil)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := "service=pb.Add,method=Stream,type=bidi_stream"
+	if want, have := fmt.Sprintf("[%s,code=OK]", labels), handled.String(); want != have {
+		t.Errorf("handled: want %s, have %s", want, have)
+	}
+	if want, have := fmt.Sprintf("[%s %s]", labels, labels), received.String(); want != have {
+		t.Errorf("received: want %s, have %s", want, have)
+	}
+	if want, have := fmt.Sprintf("[%s %s]", labels, labels), sent.String(); want != have {
+		t.Errorf("sent: want %s, have %s", want, have)
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	msgs int
+}
+
+func (s *fakeStream) Context() context.Context  { return context.Background() }
+func (s *fakeStream) SendMsg(interface{}) error { return nil }
+func (s *fakeStream) RecvMsg(interface{}) error {
+	if s.msgs == 0 {
+		return io.EOF
+	}
+	s.msgs--
+	return nil
+}
+
+// observations records the label values of each observation, in sorted order.
+type observations struct {
+	mtx sync.Mutex
+	obs []string
+}
+
+func (o *observations) record(labelValues []string) {
+	var pairs []string
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		pairs = append(pairs, labelValues[i]+"="+labelValues[i+1])
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.obs = append(o.obs, strings.Join(pairs, ","))
+}
+
+func (o *observations) String() string {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	obs := append([]string{}, o.obs...)
+	sort.Strings(obs)
+	return fmt.Sprint(obs)
+}
+
+type counter struct {
+	observations
+	root *counter
+	lvs  []string
+}
+
+func (c *counter) With(labelValues ...string) metrics.Counter {
+	root := c
+	if c.root != nil {
+		root = c.root
+	}
+	return &counter{root: root, lvs: append(append([]string{}, c.lvs...), labelValues...)}
+}
+
+func (c *counter) Add(float64) { c.root.record(c.lvs) }
+
+type histogram struct {
+	observations
+	root *histogram
+	lvs  []string
+}
+
+func (h *histogram) With(labelValues ...string) metrics.Histogram {
+	root := h
+	if h.root != nil {
+		root = h.root
+	}
+	return &histogram{root: root, lvs: append(append([]string{}, h.lvs...), labelValues...)}
+}
+
+func (h *histogram) Observe(float64) { h.root.record(h.lvs) }
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"google.golang.org/grpc/resolver"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+)
+
+// DefaultResolverScheme is the scheme of Resolvers, unless it's set with
+// ResolverScheme.
+const DefaultResolverScheme = "kit"
+
+// ResolverOption sets an optional parameter for Resolvers.
+type ResolverOption func(*Resolver)
+
+// ResolverScheme sets the scheme of the Resolver, by default
+// DefaultResolverScheme. Resolvers which are registered globally, with
+// resolver.Register, must have distinct schemes.
+func ResolverScheme(scheme string) ResolverOption {
+	return func(r *Resolver) { r.scheme = scheme }
+}
+
+// ResolverLogger is used to log errors from the Instancer. By default, no
+// errors are logged.
+func ResolverLogger(logger log.Logger) ResolverOption {
+	return func(r *Resolver) { r.logger = logger }
+}
+
+// Resolver is a gRPC name resolver backed by an sd.Instancer, so that a
+// single ClientConn balances its RPCs over the instances discovered by
+// consul, etcd, and so on, instead of constructing a Client per instance
+// with an sd.Endpointer. Instances must be addresses, e.g. host:port.
+//
+// Dial any target of the Resolver's scheme, e.g. "kit:///addsvc", with the
+// grpc.WithResolvers option. The ClientConn picks the first instance, unless
+// a balancer is configured with its service config, e.g.
+//
+//	grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin":{}}]}`)
+//
+// When the Instancer reports an error, the ClientConn keeps the last known
+// instances.
+type Resolver struct {
+	instancer sd.Instancer
+	scheme    string
+	logger    log.Logger
+}
+
+// NewResolver returns a Resolver of the instances from the Instancer.
+func NewResolver(instancer sd.Instancer, options ...ResolverOption) *Resolver {
+	r := &Resolver{
+		instancer: instancer,
+		scheme:    DefaultResolverScheme,
+		logger:    log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Scheme implements resolver.Builder.
+func (r *Resolver) Scheme() string {
+	return r.scheme
+}
+
+// Build implements resolver.Builder. The ClientConn is updated with the
+// instances until it closes the returned resolver.
+func (r *Resolver) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	w := &instanceWatcher{
+		instancer: r.instancer,
+		cc:        cc,
+		logger:    r.logger,
+		ch:        make(chan sd.Event),
+	}
+	go w.receive()
+	r.instancer.Register(w.ch)
+	return w, nil
+}
+
+// instanceWatcher updates a ClientConn with the instances from an Instancer.
+type instanceWatcher struct {
+	instancer sd.Instancer
+	cc        resolver.ClientConn
+	logger    log.Logger
+	ch        chan sd.Event
+}
+
+func (w *instanceWatcher) receive() {
+	for event := range w.ch {
+		if event.Err != nil {
+			w.logger.Log("err", event.Err)
+			w.cc.ReportError(event.Err)
+			continue
+		}
+		addresses := make([]resolver.Address, len(event.Instances))
+		for i, instance := range event.Instances {
+			addresses[i] = resolver.Address{Addr: instance}
+		}
+		w.cc.UpdateState(resolver.State{Addresses: addresses})
+	}
+}
+
+// ResolveNow implements resolver.Resolver. Instancers push their updates, so
+// there's nothing to do.
+func (w *instanceWatcher) ResolveNow(resolver.ResolveNowOptions) {}
+
+// Close implements resolver.Resolver.
+func (w *instanceWatcher) Close() {
+	w.instancer.Deregister(w.ch)
+	close(w.ch)
+}
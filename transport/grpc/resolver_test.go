@@ -0,0 +1,125 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/go-kit/kit/sd"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+// startNamedServer starts a server whose pb.Named/Name method returns its
+// address.
+func startNamedServer(t *testing.T) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Named",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Name",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				if err := dec(&pb.TestRequest{}); err != nil {
+					return nil, err
+				}
+				return &pb.TestResponse{V: addr}, nil
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(ln)
+	return addr, server.Stop
+}
+
+// pushInstancer sends events to its one registered channel on demand.
+type pushInstancer struct {
+	mtx   sync.Mutex
+	ch    chan<- sd.Event
+	first sd.Event
+}
+
+func (p *pushInstancer) Register(ch chan<- sd.Event) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.ch = ch
+	ch <- p.first
+}
+
+func (p *pushInstancer) Deregister(chan<- sd.Event) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.ch = nil
+}
+
+func (p *pushInstancer) push(event sd.Event) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.ch != nil {
+		p.ch <- event
+	}
+}
+
+func TestResolver(t *testing.T) {
+	addr1, stop1 := startNamedServer(t)
+	defer stop1()
+	addr2, stop2 := startNamedServer(t)
+	defer stop2()
+
+	instancer := &pushInstancer{first: sd.Event{Instances: []string{addr1, addr2}}}
+	cc, err := grpc.Dial("kit:///named",
+		grpc.WithInsecure(),
+		grpc.WithResolvers(grpctransport.NewResolver(instancer)),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin":{}}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	e := grpctransport.NewClient(cc, "pb.Named", "Name",
+		func(context.Context, interface{}) (interface{}, error) { return &pb.TestRequest{}, nil },
+		func(_ context.Context, response interface{}) (interface{}, error) { return response.(*pb.TestResponse).V, nil },
+		pb.TestResponse{},
+	).Endpoint()
+
+	// serves calls the endpoint until it's answered by each address, and only
+	// those addresses.
+	serves := func(addrs ...string) {
+		want := map[string]bool{}
+		for _, addr := range addrs {
+			want[addr] = true
+		}
+		have := map[string]bool{}
+		for deadline := time.Now().Add(5 * time.Second); len(have) < len(want); {
+			if time.Now().After(deadline) {
+				t.Fatalf("want responses from %v, have %v", addrs, have)
+			}
+			response, err := e(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have[response.(string)] = true
+			if !want[response.(string)] {
+				have = map[string]bool{} // not yet updated
+			}
+		}
+	}
+	serves(addr1, addr2)
+
+	instancer.push(sd.Event{Instances: []string{addr2}})
+	serves(addr2)
+	for i := 0; i < 10; i++ {
+		if response, err := e(context.Background(), nil); err != nil || response != addr2 {
+			t.Fatalf("want %s, have %v, %v", addr2, response, err)
+		}
+	}
+}
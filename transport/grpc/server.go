@@ -18,12 +18,13 @@ type Handler interface {
 
 // Server wraps an endpoint and implements grpc.Handler.
 type Server struct {
-	e      endpoint.Endpoint
-	dec    DecodeRequestFunc
-	enc    EncodeResponseFunc
-	before []ServerRequestFunc
-	after  []ServerResponseFunc
-	logger log.Logger
+	e       endpoint.Endpoint
+	dec     DecodeRequestFunc
+	enc     EncodeResponseFunc
+	before  []ServerRequestFunc
+	after   []ServerResponseFunc
+	details ErrorDetailsFunc
+	logger  log.Logger
 }
 
 // NewServer constructs a new server, which implements wraps the provided
@@ -38,10 +39,11 @@ func NewServer(
 	options ...ServerOption,
 ) *Server {
 	s := &Server{
-		e:      e,
-		dec:    dec,
-		enc:    enc,
-		logger: log.NewNopLogger(),
+		e:       e,
+		dec:     dec,
+		enc:     enc,
+		details: DefaultErrorDetails,
+		logger:  log.NewNopLogger(),
 	}
 	for _, option := range options {
 		option(s)
@@ -64,16 +66,27 @@ func ServerAfter(after ...ServerResponseFunc) ServerOption {
 	return func(s *Server) { s.after = append(s.after, after...) }
 }
 
+// ServerErrorDetails sets the function returning the google.rpc error
+// details sent with the status of errors from the endpoint. By default,
+// DefaultErrorDetails is used.
+func ServerErrorDetails(f ErrorDetailsFunc) ServerOption {
+	return func(s *Server) { s.details = f }
+}
+
 // ServerErrorLogger is used to log non-terminal errors. By default, no errors
 // are logged.
 func ServerErrorLogger(logger log.Logger) ServerOption {
 	return func(s *Server) { s.logger = logger }
 }
 
-// ServeGRPC implements the Handler interface.
+// ServeGRPC implements the Handler interface. Errors from the endpoint, and
+// those of responses implementing endpoint.Failer, are returned as gRPC
+// statuses with the code of their endpoint.Kind, if known, such as
+// endpoint.TimeoutError with DeadlineExceeded, and their error details. Other
+// errors without details are returned unchanged.
 func (s Server) ServeGRPC(ctx oldcontext.Context, req interface{}) (oldcontext.Context, interface{}, error) {
 	// Retrieve gRPC metadata.
-	md, ok := metadata.FromContext(ctx)
+	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		md = metadata.MD{}
 	}
@@ -91,7 +104,10 @@ func (s Server) ServeGRPC(ctx oldcontext.Context, req interface{}) (oldcontext.C
 	response, err := s.e(ctx, request)
 	if err != nil {
 		s.logger.Log("err", err)
-		return ctx, nil, err
+		return ctx, nil, endpointError(err, s.details)
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return ctx, nil, endpointError(f.Failed(), s.details)
 	}
 
 	var mdHeader, mdTrailer metadata.MD
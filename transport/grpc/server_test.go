@@ -0,0 +1,57 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+func TestServerEndpointErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"timeout", endpoint.TimeoutError{Duration: time.Second}, codes.DeadlineExceeded},
+		{"validation", endpoint.ValidationError{Fields: []endpoint.FieldError{{Field: "name", Message: "is required"}}}, codes.InvalidArgument},
+		{"not found", endpoint.NotFound(errors.New("no such user")), codes.NotFound},
+		{"unavailable", endpoint.Unavailable(errors.New("try later")), codes.Unavailable},
+		{"canceled", context.Canceled, codes.Canceled},
+		{"other", errors.New("dang"), codes.Unknown},
+	} {
+		server := grpctransport.NewServer(
+			func(context.Context, interface{}) (interface{}, error) { return nil, tc.err },
+			func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil },
+			func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil },
+		)
+		_, _, err := server.ServeGRPC(context.Background(), struct{}{})
+		if want, have := tc.want, status.Code(err); want != have {
+			t.Errorf("%s: want %s, have %s", tc.name, want, have)
+		}
+	}
+}
+
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
+
+func TestServerFailer(t *testing.T) {
+	server := grpctransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) {
+			return failedResponse{endpoint.PermissionDenied(errors.New("nope"))}, nil
+		},
+		func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil },
+	)
+	_, _, err := server.ServeGRPC(context.Background(), struct{}{})
+	if want, have := codes.PermissionDenied, status.Code(err); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,235 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// StreamClient wraps a gRPC connection and provides a method that implements
+// endpoint.Endpoint, for client-streaming, server-streaming, and
+// bidirectional-streaming RPCs.
+type StreamClient struct {
+	client    *grpc.ClientConn
+	method    string
+	desc      grpc.StreamDesc
+	enc       EncodeRequestFunc
+	dec       DecodeResponseFunc
+	grpcReply reflect.Type
+	before    []ClientRequestFunc
+	after     []ClientResponseFunc
+}
+
+// NewStreamClient constructs a usable StreamClient for a single remote
+// streaming method. The desc says which sides of the RPC stream, e.g.
+// grpc.StreamDesc{ClientStreams: true, ServerStreams: true} for a
+// bidirectional RPC. Pass a zero-value protobuf message of the RPC response
+// type as the grpcReply argument.
+func NewStreamClient(
+	cc *grpc.ClientConn,
+	serviceName string,
+	method string,
+	desc grpc.StreamDesc,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	grpcReply interface{},
+	options ...StreamClientOption,
+) *StreamClient {
+	c := &StreamClient{
+		client:    cc,
+		method:    fmt.Sprintf("/%s/%s", serviceName, method),
+		desc:      desc,
+		enc:       enc,
+		dec:       dec,
+		grpcReply: reflect.TypeOf(reflect.Indirect(reflect.ValueOf(grpcReply)).Interface()),
+	}
+	c.desc.StreamName = method
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// StreamClientOption sets an optional parameter for stream clients.
+type StreamClientOption func(*StreamClient)
+
+// StreamClientBefore sets the RequestFuncs that are applied to the outgoing
+// gRPC metadata before the stream is opened.
+func StreamClientBefore(before ...ClientRequestFunc) StreamClientOption {
+	return func(c *StreamClient) { c.before = append(c.before, before...) }
+}
+
+// StreamClientAfter sets the ClientResponseFuncs that are applied to the
+// response metadata. For RPCs with a single response, they're applied prior
+// to it being decoded, with the header and trailer. For RPCs streaming
+// responses, they're applied once the header is received, with an empty
+// trailer.
+func StreamClientAfter(after ...ClientResponseFunc) StreamClientOption {
+	return func(c *StreamClient) { c.after = append(c.after, after...) }
+}
+
+// Endpoint returns a usable endpoint that invokes the streaming RPC. If the
+// client streams, the request must be a channel of requests, <-chan
+// interface{}, each of which is encoded and sent, until the channel is
+// closed; otherwise, it's a single request. If the server streams, the
+// response is a channel of decoded responses, <-chan interface{}, which is
+// closed when the stream ends; an error ending the stream early is
+// delivered as the last value. Otherwise, the endpoint returns the single
+// decoded response, once the requests are sent.
+//
+// Streams last until they end, or the request context is canceled. Callers
+// receiving a channel of responses must drain it, or cancel the context.
+func (c StreamClient) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		parent := ctx
+		ctx, cancel := context.WithCancel(ctx)
+
+		md := &metadata.MD{}
+		for _, f := range c.before {
+			ctx = f(ctx, md)
+		}
+		if outgoing, ok := metadata.FromOutgoingContext(ctx); ok {
+			*md = metadata.Join(outgoing, *md)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, *md)
+
+		stream, err := grpc.NewClientStream(ctx, &c.desc, c.client, c.method)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		if !c.desc.ServerStreams {
+			defer cancel()
+			if err := c.send(ctx, stream, request); err != nil {
+				return nil, err
+			}
+			grpcReply := reflect.New(c.grpcReply).Interface()
+			if err := stream.RecvMsg(grpcReply); err != nil {
+				return nil, err
+			}
+			header, _ := stream.Header()
+			for _, f := range c.after {
+				ctx = f(ctx, header, stream.Trailer())
+			}
+			return c.dec(ctx, grpcReply)
+		}
+
+		sendErr := make(chan error, 1)
+		if c.desc.ClientStreams {
+			if _, ok := request.(<-chan interface{}); !ok {
+				cancel()
+				return nil, fmt.Errorf("stream request must be a channel, not %T", request)
+			}
+			go func() {
+				if err := c.send(ctx, stream, request); err != nil {
+					sendErr <- err
+					cancel() // abort the stream, so that RecvMsg returns
+				}
+			}()
+		} else if err := c.send(ctx, stream, request); err != nil {
+			cancel()
+			return nil, err
+		}
+
+		responses := make(chan interface{})
+		go func() {
+			defer cancel()
+			defer close(responses)
+			// Deliver until the caller cancels; a failed send cancels the
+			// stream, but its error must still be delivered.
+			deliver := func(v interface{}) bool {
+				select {
+				case responses <- v:
+					return true
+				case <-parent.Done():
+					return false
+				}
+			}
+			fail := func(err error) {
+				select {
+				case err = <-sendErr:
+				default:
+				}
+				deliver(err)
+			}
+
+			header, err := stream.Header()
+			if err != nil {
+				fail(err)
+				return
+			}
+			ctx := ctx
+			for _, f := range c.after {
+				ctx = f(ctx, header, metadata.MD{})
+			}
+			for {
+				grpcReply := reflect.New(c.grpcReply).Interface()
+				if err := stream.RecvMsg(grpcReply); err != nil {
+					if err == io.EOF {
+						return
+					}
+					fail(err)
+					return
+				}
+				response, err := c.dec(ctx, grpcReply)
+				if err != nil {
+					deliver(err)
+					return
+				}
+				if !deliver(response) {
+					return
+				}
+			}
+		}()
+		return (<-chan interface{})(responses), nil
+	}
+}
+
+// send encodes and sends the request, or each request from a channel, if the
+// client streams, then closes the sending side of the stream.
+func (c StreamClient) send(ctx context.Context, stream grpc.ClientStream, request interface{}) error {
+	sendOne := func(v interface{}) error {
+		req, err := c.enc(ctx, v)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(req)
+	}
+
+	if !c.desc.ClientStreams {
+		if err := sendOne(request); err != nil {
+			return err
+		}
+		return stream.CloseSend()
+	}
+
+	requests, ok := request.(<-chan interface{})
+	if !ok {
+		return fmt.Errorf("stream request must be a channel, not %T", request)
+	}
+	for {
+		select {
+		case v, ok := <-requests:
+			if !ok {
+				return stream.CloseSend()
+			}
+			if err := sendOne(v); err != nil {
+				if err == io.EOF {
+					// The server ended the stream; its status is returned by
+					// RecvMsg.
+					return nil
+				}
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package grpc_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+func startStreamServer(t *testing.T) (*grpc.ClientConn, func()) {
+	count := grpctransport.NewStreamServer(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			c := make(chan interface{})
+			go func() {
+				defer close(c)
+				for i := int64(0); i < request.(int64); i++ {
+					select {
+					case c <- i:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return (<-chan interface{})(c), nil
+		},
+		func(_ context.Context, req interface{}) (interface{}, error) { return req.(*pb.TestRequest).B, nil },
+		func(_ context.Context, response interface{}) (interface{}, error) {
+			return &pb.TestResponse{V: fmt.Sprint(response)}, nil
+		},
+	)
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Streams",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Count",
+				ServerStreams: true,
+				Handler: func(_ interface{}, stream grpc.ServerStream) error {
+					req := &pb.TestRequest{}
+					if err := stream.RecvMsg(req); err != nil {
+						return err
+					}
+					return count.ServeGRPCStream(req, stream)
+				},
+			},
+			{
+				StreamName:    "Join",
+				ClientStreams: true,
+				Handler: func(_ interface{}, stream grpc.ServerStream) error {
+					var parts []string
+					for {
+						req := &pb.TestRequest{}
+						if err := stream.RecvMsg(req); err == io.EOF {
+							break
+						} else if err != nil {
+							return err
+						}
+						parts = append(parts, req.A)
+					}
+					return stream.SendMsg(&pb.TestResponse{V: strings.Join(parts, ",")})
+				},
+			},
+			{
+				StreamName:    "Echo",
+				ClientStreams: true,
+				ServerStreams: true,
+				Handler: func(_ interface{}, stream grpc.ServerStream) error {
+					for {
+						req := &pb.TestRequest{}
+						if err := stream.RecvMsg(req); err == io.EOF {
+							return nil
+						} else if err != nil {
+							return err
+						}
+						if err := stream.SendMsg(&pb.TestResponse{V: strings.ToUpper(req.A)}); err != nil {
+							return err
+						}
+					}
+				},
+			},
+		},
+	}, struct{}{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	cc, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cc, func() { cc.Close(); server.Stop() }
+}
+
+func TestStreamClient(t *testing.T) {
+	cc, stop := startStreamServer(t)
+	defer stop()
+
+	encode := func(_ context.Context, request interface{}) (interface{}, error) {
+		switch v := request.(type) {
+		case string:
+			return &pb.TestRequest{A: v}, nil
+		case int64:
+			return &pb.TestRequest{B: v}, nil
+		}
+		return nil, fmt.Errorf("bad request %T", request)
+	}
+	decode := func(_ context.Context, response interface{}) (interface{}, error) {
+		return response.(*pb.TestResponse).V, nil
+	}
+	requests := func(values ...interface{}) <-chan interface{} {
+		c := make(chan interface{}, len(values))
+		for _, v := range values {
+			c <- v
+		}
+		close(c)
+		return c
+	}
+	collect := func(response interface{}) []interface{} {
+		var all []interface{}
+		for v := range response.(<-chan interface{}) {
+			all = append(all, v)
+		}
+		return all
+	}
+
+	// Server streaming.
+	count := grpctransport.NewStreamClient(cc, "pb.Streams", "Count", grpc.StreamDesc{ServerStreams: true}, encode, decode, pb.TestResponse{})
+	response, err := count.Endpoint()(context.Background(), int64(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []interface{}{"0", "1", "2"}, collect(response); !reflect.DeepEqual(want, have) {
+		t.Errorf("Count: want %v, have %v", want, have)
+	}
+
+	// Client streaming.
+	join := grpctransport.NewStreamClient(cc, "pb.Streams", "Join", grpc.StreamDesc{ClientStreams: true}, encode, decode, pb.TestResponse{})
+	response, err = join.Endpoint()(context.Background(), requests("a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a,b,c", response; want != have {
+		t.Errorf("Join: want %q, have %q", want, have)
+	}
+
+	// Bidirectional streaming.
+	echo := grpctransport.NewStreamClient(cc, "pb.Streams", "Echo", grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, encode, decode, pb.TestResponse{})
+	response, err = echo.Endpoint()(context.Background(), requests("x", "y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []interface{}{"X", "Y"}, collect(response); !reflect.DeepEqual(want, have) {
+		t.Errorf("Echo: want %v, have %v", want, have)
+	}
+
+	// An encoding error ends a bidirectional stream with that error.
+	response, err = echo.Endpoint()(context.Background(), requests("x", 1.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := collect(response)
+	if len(all) == 0 {
+		t.Fatal("want an error, have nothing")
+	}
+	if err, ok := all[len(all)-1].(error); !ok || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("Echo: want the encoding error last, have %v", all)
+	}
+}
@@ -0,0 +1,187 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// StreamHandler should be called from the gRPC binding of a server-streaming
+// method of the service implementation, with the request message and the
+// stream. The messages sent are gRPC types, not user-domain.
+type StreamHandler interface {
+	ServeGRPCStream(request interface{}, stream grpc.ServerStream) error
+}
+
+// MessageIterator is a source of response messages, which may be returned by
+// an endpoint instead of a channel. Next blocks until the next message is
+// available, or the context is done. It returns io.EOF after the last
+// message; any other error ends the stream with that error.
+type MessageIterator interface {
+	Next(ctx context.Context) (interface{}, error)
+}
+
+// StreamServer wraps an endpoint and implements StreamHandler, for
+// server-streaming RPCs.
+type StreamServer struct {
+	e       endpoint.Endpoint
+	dec     DecodeRequestFunc
+	enc     EncodeResponseFunc
+	before  []ServerRequestFunc
+	after   []ServerResponseFunc
+	details ErrorDetailsFunc
+	logger  log.Logger
+}
+
+// NewStreamServer constructs a new stream server, which wraps the provided
+// endpoint and implements the StreamHandler interface. The endpoint must
+// return a channel of responses, <-chan interface{}, or a MessageIterator.
+// Each response is encoded with enc, and sent as a message.
+func NewStreamServer(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...StreamServerOption,
+) *StreamServer {
+	s := &StreamServer{
+		e:       e,
+		dec:     dec,
+		enc:     enc,
+		details: DefaultErrorDetails,
+		logger:  log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// StreamServerOption sets an optional parameter for stream servers.
+type StreamServerOption func(*StreamServer)
+
+// StreamServerBefore functions are executed on the gRPC metadata of the
+// request, before the request is decoded.
+func StreamServerBefore(before ...ServerRequestFunc) StreamServerOption {
+	return func(s *StreamServer) { s.before = append(s.before, before...) }
+}
+
+// StreamServerAfter functions are executed after the endpoint is invoked,
+// but before the first message is sent. The header they set is sent before
+// the first message, and the trailer after the last.
+func StreamServerAfter(after ...ServerResponseFunc) StreamServerOption {
+	return func(s *StreamServer) { s.after = append(s.after, after...) }
+}
+
+// StreamServerErrorDetails sets the function returning the google.rpc error
+// details sent with the status of errors from the endpoint, or ending the
+// stream. By default, DefaultErrorDetails is used.
+func StreamServerErrorDetails(f ErrorDetailsFunc) StreamServerOption {
+	return func(s *StreamServer) { s.details = f }
+}
+
+// StreamServerErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func StreamServerErrorLogger(logger log.Logger) StreamServerOption {
+	return func(s *StreamServer) { s.logger = logger }
+}
+
+// ServeGRPCStream implements the StreamHandler interface. The endpoint is
+// invoked with the context of the stream, which is canceled when the client
+// goes away, so endpoints should produce responses with it, and stop when
+// it's done. Messages are sent as they're produced, as fast as gRPC flow
+// control allows. The stream ends when the channel is closed, or the iterator
+// returns io.EOF. Errors, whether returned by the endpoint, the iterator, or
+// received as a value from the channel, end the stream, and are mapped to
+// gRPC statuses as by Server.
+func (s StreamServer) ServeGRPCStream(req interface{}, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, md)
+	}
+
+	request, err := s.dec(ctx, req)
+	if err != nil {
+		s.logger.Log("err", err)
+		return err
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		return endpointError(err, s.details)
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return endpointError(f.Failed(), s.details)
+	}
+
+	var next func() (interface{}, error)
+	switch r := response.(type) {
+	case <-chan interface{}:
+		next = func() (interface{}, error) {
+			select {
+			case v, ok := <-r:
+				if !ok {
+					return nil, io.EOF
+				}
+				if err, ok := v.(error); ok {
+					return nil, err
+				}
+				return v, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	case MessageIterator:
+		next = func() (interface{}, error) { return r.Next(ctx) }
+	default:
+		return fmt.Errorf("stream response must be a channel or a MessageIterator, not %T", response)
+	}
+
+	var mdHeader, mdTrailer metadata.MD
+	for _, f := range s.after {
+		ctx = f(ctx, &mdHeader, &mdTrailer)
+	}
+	if len(mdHeader) > 0 {
+		if err := stream.SendHeader(mdHeader); err != nil {
+			s.logger.Log("err", err)
+			return err
+		}
+	}
+	defer func() {
+		if len(mdTrailer) > 0 {
+			stream.SetTrailer(mdTrailer)
+		}
+	}()
+
+	for {
+		v, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			s.logger.Log("err", err)
+			return endpointError(err, s.details)
+		}
+		msg, err := s.enc(ctx, v)
+		if err != nil {
+			s.logger.Log("err", err)
+			return err
+		}
+		if err := stream.SendMsg(msg); err != nil {
+			s.logger.Log("err", err)
+			return err
+		}
+	}
+}
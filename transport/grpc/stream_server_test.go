@@ -0,0 +1,115 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-kit/kit/endpoint"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+)
+
+// fakeServerStream records the messages and metadata sent on it.
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	header  metadata.MD
+	trailer metadata.MD
+	sent    []interface{}
+}
+
+func (s *fakeServerStream) Context() context.Context        { return s.ctx }
+func (s *fakeServerStream) SendHeader(md metadata.MD) error { s.header = md; return nil }
+func (s *fakeServerStream) SetTrailer(md metadata.MD)       { s.trailer = md }
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+type countdown struct{ n int }
+
+func (c *countdown) Next(context.Context) (interface{}, error) {
+	if c.n == 0 {
+		return nil, io.EOF
+	}
+	c.n--
+	return c.n, nil
+}
+
+func TestStreamServer(t *testing.T) {
+	encode := func(_ context.Context, response interface{}) (interface{}, error) {
+		return fmt.Sprint(response), nil
+	}
+	decode := func(_ context.Context, request interface{}) (interface{}, error) { return request, nil }
+
+	for _, tc := range []struct {
+		name     string
+		response func(n int) interface{}
+		want     []interface{}
+		code     codes.Code
+	}{
+		{"channel", func(n int) interface{} {
+			c := make(chan interface{}, n)
+			for i := 0; i < n; i++ {
+				c <- i
+			}
+			close(c)
+			return (<-chan interface{})(c)
+		}, []interface{}{"0", "1", "2"}, codes.OK},
+		{"iterator", func(n int) interface{} { return &countdown{n} }, []interface{}{"2", "1", "0"}, codes.OK},
+		{"error value", func(n int) interface{} {
+			c := make(chan interface{}, 2)
+			c <- 0
+			c <- endpoint.NotFound(errors.New("gone"))
+			return (<-chan interface{})(c)
+		}, []interface{}{"0"}, codes.NotFound},
+	} {
+		server := grpctransport.NewStreamServer(
+			func(_ context.Context, request interface{}) (interface{}, error) { return tc.response(request.(int)), nil },
+			decode, encode,
+			grpctransport.StreamServerAfter(func(ctx context.Context, header *metadata.MD, trailer *metadata.MD) context.Context {
+				*header = metadata.Pairs("h", "1")
+				*trailer = metadata.Pairs("t", "1")
+				return ctx
+			}),
+		)
+		stream := &fakeServerStream{ctx: context.Background()}
+		err := server.ServeGRPCStream(3, stream)
+		if want, have := tc.code, status.Code(err); want != have {
+			t.Errorf("%s: want %s, have %s (%v)", tc.name, want, have, err)
+		}
+		if !reflect.DeepEqual(tc.want, stream.sent) {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, stream.sent)
+		}
+		if want, have := []string{"1"}, stream.header["h"]; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: header: want %v, have %v", tc.name, want, have)
+		}
+		if want, have := []string{"1"}, stream.trailer["t"]; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: trailer: want %v, have %v", tc.name, want, have)
+		}
+	}
+}
+
+func TestStreamServerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := grpctransport.NewStreamServer(
+		func(context.Context, interface{}) (interface{}, error) {
+			return (<-chan interface{})(make(chan interface{})), nil
+		},
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil },
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil },
+	)
+	cancel()
+	err := server.ServeGRPCStream(nil, &fakeServerStream{ctx: ctx})
+	if want, have := codes.Canceled, status.Code(err); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,250 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// WebOption sets an optional parameter for WebHandlers.
+type WebOption func(*WebHandler)
+
+// WebAllowedOrigins allows browsers to make gRPC-Web requests from pages of
+// other origins, e.g. "https://app.example.com", answering their CORS
+// preflight requests. "*" allows any origin. By default, only requests from
+// the same origin are allowed.
+func WebAllowedOrigins(origins ...string) WebOption {
+	return func(h *WebHandler) { h.origins = append(h.origins, origins...) }
+}
+
+// WebFallback sets the handler of requests which are neither gRPC-Web nor
+// native gRPC, e.g. to serve the HTTP API or the pages of the site from the
+// same port. By default, they're rejected with 415 Unsupported Media Type.
+func WebFallback(next http.Handler) WebOption {
+	return func(h *WebHandler) { h.fallback = next }
+}
+
+// WebHandler serves gRPC-Web, alongside native gRPC, so that browsers may
+// reach the gRPC services without a translating proxy such as Envoy.
+// gRPC-Web requests, of the content types application/grpc-web and
+// application/grpc-web-text, are translated into native gRPC for the wrapped
+// handler, and its responses translated back, with the trailers in the body.
+// gRPC-Web over WebSockets isn't supported.
+//
+// The wrapped handler is usually a *grpc.Server, which implements
+// http.Handler, on which the kit Servers of this package are registered.
+// Native gRPC requires HTTP/2: serve the WebHandler with TLS, or, in
+// plaintext, with package transport/http's HTTPServerH2C option.
+type WebHandler struct {
+	grpc     http.Handler
+	origins  []string
+	fallback http.Handler
+}
+
+// NewWebHandler returns a WebHandler serving gRPC-Web and native gRPC with
+// the handler.
+func NewWebHandler(grpc http.Handler, options ...WebOption) *WebHandler {
+	h := &WebHandler{
+		grpc: grpc,
+		fallback: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		}),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.preflight(w, r) {
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case r.ProtoMajor == 2 && strings.HasPrefix(contentType, "application/grpc") && !strings.HasPrefix(contentType, "application/grpc-web"):
+		h.grpc.ServeHTTP(w, r)
+		return
+	case !strings.HasPrefix(contentType, "application/grpc-web"):
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" && h.allowed(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+
+	// application/grpc-web[-text][+subtype] becomes application/grpc[+subtype].
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+	subtype := ""
+	if i := strings.Index(contentType, "+"); i >= 0 {
+		subtype = contentType[i:]
+	}
+
+	req := r.WithContext(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/grpc"+subtype)
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	if text {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{base64.NewDecoder(base64.StdEncoding, r.Body), r.Body}
+	}
+
+	ww := &webWriter{
+		w:           w,
+		header:      http.Header{},
+		contentType: "application/grpc-web" + subtype,
+		text:        text,
+	}
+	if text {
+		ww.contentType = "application/grpc-web-text" + subtype
+	}
+	h.grpc.ServeHTTP(ww, req)
+	ww.finish()
+}
+
+// preflight answers CORS preflight requests for gRPC-Web, and returns true if
+// the request was one.
+func (h *WebHandler) preflight(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if r.Method != "OPTIONS" || origin == "" || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	w.Header().Add("Vary", "Origin")
+	if h.allowed(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func (h *WebHandler) allowed(origin string) bool {
+	for _, o := range h.origins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// webWriter translates a native gRPC response into gRPC-Web. Headers set
+// once the response is written are trailers, which are written at the end
+// of the body, as a frame flagged 0x80.
+type webWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+	wroteHeader bool
+	trailers    []string // declared with the Trailer header
+}
+
+func (w *webWriter) Header() http.Header { return w.header }
+
+func (w *webWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.w.Header()
+	var exposed []string
+	for k, v := range w.header {
+		if k == "Trailer" {
+			for _, t := range v {
+				w.trailers = append(w.trailers, strings.Split(t, ",")...)
+			}
+			continue
+		}
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		h[k] = v
+		exposed = append(exposed, k)
+	}
+	h.Set("Content-Type", w.contentType)
+	h.Del("Content-Length")
+	if h.Get("Access-Control-Allow-Origin") != "" {
+		exposed = append(exposed, "Grpc-Status", "Grpc-Message")
+		sort.Strings(exposed)
+		h.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+	}
+	w.w.WriteHeader(code)
+}
+
+func (w *webWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.text {
+		return w.w.Write(p)
+	}
+	// Each write is encoded, and padded, on its own, as gRPC-Web allows.
+	if _, err := io.WriteString(w.w, base64.StdEncoding.EncodeToString(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *webWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailers frame.
+func (w *webWriter) finish() {
+	trailer := http.Header{}
+	for _, k := range w.trailers {
+		k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+		if v, ok := w.header[k]; ok {
+			trailer[k] = v
+		}
+	}
+	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			k = http.CanonicalHeaderKey(strings.TrimPrefix(k, http.TrailerPrefix))
+			trailer[k] = append(trailer[k], v...)
+		}
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var buf bytes.Buffer
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			buf.WriteString(strings.ToLower(k) + ": " + v + "\r\n")
+		}
+	}
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = 0x80
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	w.Write(append(frame, buf.Bytes()...))
+	w.Flush()
+}
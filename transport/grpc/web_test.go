@@ -0,0 +1,178 @@
+package grpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+func newWebServer(t *testing.T, options ...grpctransport.WebOption) *httptest.Server {
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "pb.Web",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &pb.TestRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				grpc.SetTrailer(ctx, map[string][]string{"x-echoed": {"yes"}})
+				return &pb.TestResponse{V: strings.Repeat(req.A, int(req.B))}, nil
+			},
+		}},
+	}, struct{}{})
+	return httptest.NewServer(grpctransport.NewWebHandler(server, options...))
+}
+
+// webFrames splits a gRPC-Web body into its messages and trailers.
+func webFrames(t *testing.T, body []byte) (messages [][]byte, trailers string) {
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("short frame %q", body)
+		}
+		n := binary.BigEndian.Uint32(body[1:5])
+		if flag := body[0]; flag&0x80 != 0 {
+			trailers += string(body[5 : 5+n])
+		} else {
+			messages = append(messages, body[5:5+n])
+		}
+		body = body[5+n:]
+	}
+	return messages, trailers
+}
+
+func TestWebHandler(t *testing.T) {
+	server := newWebServer(t)
+	defer server.Close()
+
+	msg, _ := proto.Marshal(&pb.TestRequest{A: "ab", B: 2})
+	frame := append([]byte{0, 0, 0, 0, byte(len(msg))}, msg...)
+
+	for _, tc := range []struct {
+		contentType string
+		text        bool
+	}{
+		{"application/grpc-web+proto", false},
+		{"application/grpc-web", false},
+		{"application/grpc-web-text", true},
+	} {
+		body := frame
+		if tc.text {
+			body = []byte(base64.StdEncoding.EncodeToString(frame))
+		}
+		req, _ := http.NewRequest("POST", server.URL+"/pb.Web/Echo", bytes.NewReader(body))
+		req.Header.Set("Content-Type", tc.contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if want, have := tc.contentType, resp.Header.Get("Content-Type"); want != have {
+			t.Errorf("%s: want Content-Type %q, have %q", tc.contentType, want, have)
+		}
+		if tc.text {
+			// Each write is padded on its own, so decode chunk by chunk.
+			var decoded []byte
+			for s := string(body); s != ""; {
+				i := strings.Index(s, "=")
+				for i >= 0 && i+1 < len(s) && s[i+1] == '=' {
+					i++
+				}
+				chunk := s
+				if i >= 0 {
+					chunk, s = s[:i+1], s[i+1:]
+				} else {
+					s = ""
+				}
+				b, err := base64.StdEncoding.DecodeString(chunk)
+				if err != nil {
+					t.Fatalf("%s: %v", tc.contentType, err)
+				}
+				decoded = append(decoded, b...)
+			}
+			body = decoded
+		}
+
+		messages, trailers := webFrames(t, body)
+		if len(messages) != 1 {
+			t.Fatalf("%s: want 1 message, have %d", tc.contentType, len(messages))
+		}
+		var response pb.TestResponse
+		if err := proto.Unmarshal(messages[0], &response); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "abab", response.V; want != have {
+			t.Errorf("%s: want %q, have %q", tc.contentType, want, have)
+		}
+		for _, want := range []string{"grpc-status: 0\r\n", "x-echoed: yes\r\n"} {
+			if !strings.Contains(trailers, want) {
+				t.Errorf("%s: want trailer %q, have %q", tc.contentType, want, trailers)
+			}
+		}
+	}
+}
+
+func TestWebHandlerErrors(t *testing.T) {
+	server := newWebServer(t, grpctransport.WebAllowedOrigins("https://app.example.com"))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/pb.Web/Missing", bytes.NewReader([]byte{0, 0, 0, 0, 0}))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if _, trailers := webFrames(t, body); !strings.Contains(trailers, "grpc-status: 12\r\n") {
+		t.Errorf("want Unimplemented, have %q", trailers)
+	}
+	if want, have := "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if have := resp.Header.Get("Access-Control-Expose-Headers"); !strings.Contains(have, "Grpc-Status") {
+		t.Errorf("want Grpc-Status exposed, have %q", have)
+	}
+
+	req, _ = http.NewRequest("OPTIONS", server.URL+"/pb.Web/Echo", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusNoContent, resp.StatusCode; want != have {
+		t.Errorf("preflight: want %d, have %d", want, have)
+	}
+	if want, have := "content-type,x-grpc-web", resp.Header.Get("Access-Control-Allow-Headers"); want != have {
+		t.Errorf("preflight: want %q, have %q", want, have)
+	}
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusUnsupportedMediaType, resp.StatusCode; want != have {
+		t.Errorf("fallback: want %d, have %d", want, have)
+	}
+}
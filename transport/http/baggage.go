@@ -0,0 +1,37 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// BaggageHeader carries the endpoint.Baggage of requests, in the W3C baggage
+// format.
+const BaggageHeader = "baggage"
+
+// ContextToBaggageHeader is a client RequestFunc which sets BaggageHeader to
+// the baggage of the context, if any, so that it travels on to the server.
+func ContextToBaggageHeader(ctx context.Context, r *http.Request) context.Context {
+	if b := endpoint.BaggageFromContext(ctx); len(b) > 0 {
+		r.Header.Set(BaggageHeader, b.String())
+	}
+	return ctx
+}
+
+// BaggageHeaderToContext is a server RequestFunc which passes the baggage in
+// BaggageHeader, if any, to endpoint.ContextWithBaggage. Combined with
+// ContextToBaggageHeader in the clients the server calls, the baggage is
+// propagated through every service.
+func BaggageHeaderToContext(ctx context.Context, r *http.Request) context.Context {
+	values := r.Header[http.CanonicalHeaderKey(BaggageHeader)]
+	if len(values) == 0 {
+		return ctx
+	}
+	if b := endpoint.ParseBaggage(strings.Join(values, ",")); len(b) > 0 {
+		ctx = endpoint.ContextWithBaggage(ctx, b)
+	}
+	return ctx
+}
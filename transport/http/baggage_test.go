@@ -0,0 +1,32 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestBaggageHeader(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://example.com", nil)
+	ctx := endpoint.ContextWithBaggageItem(context.Background(), "tenant", "acme")
+	httptransport.ContextToBaggageHeader(ctx, r)
+	if want, have := "tenant=acme", r.Header.Get(httptransport.BaggageHeader); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	r.Header.Add(httptransport.BaggageHeader, "route=canary")
+	ctx = httptransport.BaggageHeaderToContext(context.Background(), r)
+	for k, want := range map[string]string{"tenant": "acme", "route": "canary"} {
+		if have, _ := endpoint.BaggageItem(ctx, k); want != have {
+			t.Errorf("%s: want %q, have %q", k, want, have)
+		}
+	}
+
+	r.Header.Del(httptransport.BaggageHeader)
+	if b := endpoint.BaggageFromContext(httptransport.BaggageHeaderToContext(context.Background(), r)); b != nil {
+		t.Errorf("want no baggage, have %v", b)
+	}
+}
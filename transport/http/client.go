@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 )
@@ -23,6 +24,12 @@ type Client struct {
 	after          []ClientResponseFunc
 	finalizer      ClientFinalizerFunc
 	bufferedStream bool
+	compression    *compression
+	retry          *clientRetry
+	transport      *transportConfig
+	timeout        time.Duration
+	middleware     []ClientMiddleware
+	do             Doer
 }
 
 // NewClient constructs a usable Client for a single remote method.
@@ -46,6 +53,10 @@ func NewClient(
 	for _, option := range options {
 		option(c)
 	}
+	if c.transport != nil {
+		c.client = c.transport.client(c.client)
+	}
+	c.do = c.doer()
 	return c
 }
 
@@ -86,7 +97,12 @@ func BufferedStream(buffered bool) ClientOption {
 // Endpoint returns a usable endpoint that invokes the remote endpoint.
 func (c Client) Endpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		ctx, cancel := context.WithCancel(ctx)
+		var cancel context.CancelFunc
+		if c.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
 		defer cancel()
 
 		var (
@@ -116,7 +132,17 @@ func (c Client) Endpoint() endpoint.Endpoint {
 			ctx = f(ctx, req)
 		}
 
-		resp, err = c.client.Do(req.WithContext(ctx))
+		if c.compression != nil {
+			req.Header.Set("Accept-Encoding", c.compression.acceptEncoding())
+		}
+
+		if c.retry != nil {
+			var done context.CancelFunc
+			resp, done, err = c.retry.do(ctx, c.do, req)
+			defer done()
+		} else {
+			resp, err = c.do.Do(req.WithContext(ctx))
+		}
 
 		if err != nil {
 			return nil, err
@@ -126,6 +152,12 @@ func (c Client) Endpoint() endpoint.Endpoint {
 			defer resp.Body.Close()
 		}
 
+		if c.compression != nil {
+			if err = c.compression.decompressResponse(resp); err != nil {
+				return nil, err
+			}
+		}
+
 		for _, f := range c.after {
 			ctx = f(ctx, resp)
 		}
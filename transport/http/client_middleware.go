@@ -0,0 +1,58 @@
+package http
+
+import (
+	"net/http"
+)
+
+// Doer sends HTTP requests and returns their responses, as *http.Client does.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doers.
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+// Do implements Doer.
+func (f DoerFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// ClientMiddleware wraps the sending of a client's requests. Unlike
+// ClientBefore and ClientAfter functions, it may act on the request and
+// response together, replace either, or send the request again, e.g. to
+// refresh expired credentials.
+type ClientMiddleware func(Doer) Doer
+
+// ClientChain is a helper function for composing client middlewares.
+// Requests pass through them in the order they're given, so the first is
+// outermost.
+func ClientChain(outer ClientMiddleware, others ...ClientMiddleware) ClientMiddleware {
+	return func(next Doer) Doer {
+		for i := len(others) - 1; i >= 0; i-- {
+			next = others[i](next)
+		}
+		return outer(next)
+	}
+}
+
+// ClientWrap sets middlewares around the HTTP client, which requests pass
+// through in the order they're given, so the first is outermost. Repeated
+// options append middlewares, inside those already set.
+//
+// A client processes each request in this order: the EncodeRequestFunc; the
+// ClientBefore functions, including those propagating traces; the
+// middlewares, which see each attempt of ClientRetry; and the HTTP client.
+// Then, the response passes back out through the middlewares, and is given to
+// the ClientAfter functions, the DecodeResponseFunc, and the finalizer.
+func ClientWrap(mw ...ClientMiddleware) ClientOption {
+	return func(c *Client) { c.middleware = append(c.middleware, mw...) }
+}
+
+// doer returns the client's HTTP client, wrapped by its middlewares.
+func (c *Client) doer() Doer {
+	var d Doer = c.client
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		d = c.middleware[i](d)
+	}
+	return d
+}
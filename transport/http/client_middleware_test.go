@@ -0,0 +1,66 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestClientWrap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var log []string
+	trace := func(name string) httptransport.ClientMiddleware {
+		return func(next httptransport.Doer) httptransport.Doer {
+			return httptransport.DoerFunc(func(r *http.Request) (*http.Response, error) {
+				log = append(log, name+" "+r.Header.Get("Authorization"))
+				return next.Do(r)
+			})
+		}
+	}
+	refresh := func(next httptransport.Doer) httptransport.Doer {
+		return httptransport.DoerFunc(func(r *http.Request) (*http.Response, error) {
+			resp, err := next.Do(r)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			resp.Body.Close()
+			r.Header.Set("Authorization", "Bearer fresh")
+			return next.Do(r)
+		})
+	}
+
+	u, _ := url.Parse(server.URL)
+	client := httptransport.NewClient(
+		"GET", u,
+		func(_ context.Context, r *http.Request, _ interface{}) error {
+			r.Header.Set("Authorization", "Bearer stale")
+			return nil
+		},
+		func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+		httptransport.ClientWrap(trace("outer")),
+		httptransport.ClientWrap(httptransport.ClientChain(refresh, trace("inner"))),
+	)
+	code, err := client.Endpoint()(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := http.StatusOK, code; want != have {
+		t.Errorf("want %d, have %v", want, have)
+	}
+	if want, have := "[outer Bearer stale inner Bearer stale inner Bearer fresh]", fmt.Sprint(log); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,278 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionThreshold is the size, in bytes, below which responses
+// aren't compressed, if no other threshold is given.
+const DefaultCompressionThreshold = 1024
+
+// ErrUnsupportedEncoding is returned for requests whose Content-Encoding
+// isn't supported. It implements StatusCoder, so DefaultErrorEncoder responds
+// with 415 Unsupported Media Type.
+var ErrUnsupportedEncoding error = statusError{"unsupported content encoding", http.StatusUnsupportedMediaType}
+
+// ContentEncoding is an HTTP content coding, such as gzip. Others, such as
+// br, may be supported by defining a ContentEncoding with an implementation
+// from another package.
+type ContentEncoding struct {
+	Name      string // as in Accept-Encoding and Content-Encoding
+	NewWriter func(io.Writer) io.WriteCloser
+	NewReader func(io.Reader) (io.ReadCloser, error)
+}
+
+// Content encodings supported by the standard library.
+var (
+	Gzip = ContentEncoding{
+		Name:      "gzip",
+		NewWriter: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	}
+	Deflate = ContentEncoding{
+		Name:      "deflate", // which, in HTTP, is the zlib format
+		NewWriter: func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return zlib.NewReader(r) },
+	}
+)
+
+// ServerCompression enables compression for a server, with the encodings in
+// order of preference, or Gzip and Deflate if none are given. Request bodies
+// with one of the encodings are decompressed before they're decoded; those
+// with another fail with ErrUnsupportedEncoding. Responses, of any content
+// type, are compressed with the encoding the client prefers from its
+// Accept-Encoding header, once their body exceeds threshold bytes. Responses
+// which already have a Content-Encoding are sent as they are.
+func ServerCompression(threshold int, encodings ...ContentEncoding) ServerOption {
+	if len(encodings) == 0 {
+		encodings = []ContentEncoding{Gzip, Deflate}
+	}
+	return func(s *Server) { s.compression = &compression{threshold, encodings} }
+}
+
+// ClientCompression sets the Accept-Encoding header of requests to the
+// encodings, or Gzip and Deflate if none are given, and decompresses
+// response bodies in any of them before they're decoded.
+func ClientCompression(encodings ...ContentEncoding) ClientOption {
+	if len(encodings) == 0 {
+		encodings = []ContentEncoding{Gzip, Deflate}
+	}
+	return func(c *Client) { c.compression = &compression{encodings: encodings} }
+}
+
+type compression struct {
+	threshold int
+	encodings []ContentEncoding
+}
+
+func (c *compression) find(name string) (ContentEncoding, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, e := range c.encodings {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return ContentEncoding{}, false
+}
+
+// acceptEncoding returns the value of an Accept-Encoding header listing the
+// encodings.
+func (c *compression) acceptEncoding() string {
+	names := make([]string, len(c.encodings))
+	for i, e := range c.encodings {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// negotiate returns the encoding with the highest quality in the
+// Accept-Encoding header, breaking ties by order of preference.
+func (c *compression) negotiate(accept string) (ContentEncoding, bool) {
+	var (
+		best      ContentEncoding
+		bestQ     float64
+		qualities = map[string]float64{}
+	)
+	for _, spec := range strings.Split(accept, ",") {
+		fields := strings.Split(spec, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			if kv := strings.SplitN(strings.TrimSpace(param), "=", 2); len(kv) == 2 && kv[0] == "q" {
+				if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if name != "" {
+			qualities[name] = q
+		}
+	}
+	for _, e := range c.encodings {
+		q, ok := qualities[e.Name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// decompressRequest replaces the body of the request with its decompressed
+// content, according to its Content-Encoding.
+func (c *compression) decompressRequest(r *http.Request) error {
+	name := r.Header.Get("Content-Encoding")
+	if name == "" || strings.EqualFold(name, "identity") {
+		return nil
+	}
+	e, ok := c.find(name)
+	if !ok {
+		return ErrUnsupportedEncoding
+	}
+	body, err := e.NewReader(r.Body)
+	if err != nil {
+		return statusError{"invalid " + e.Name + " request body", http.StatusBadRequest}
+	}
+	r.Body = readCloser{body, r.Body}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+// decompressResponse replaces the body of the response with its decompressed
+// content, according to its Content-Encoding.
+func (c *compression) decompressResponse(resp *http.Response) error {
+	e, ok := c.find(resp.Header.Get("Content-Encoding"))
+	if !ok {
+		return nil
+	}
+	body, err := e.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body = readCloser{body, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// readCloser reads decompressed content, and closes both the decompressor
+// and the underlying body.
+type readCloser struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (r readCloser) Close() error {
+	r.ReadCloser.Close()
+	return r.body.Close()
+}
+
+// compressWriter buffers the response until it exceeds the threshold, and
+// then compresses it. Responses which end below the threshold are sent
+// uncompressed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  ContentEncoding
+	threshold int
+
+	code    int
+	buf     bytes.Buffer
+	started bool           // the header is written
+	w       io.WriteCloser // compressor, if compressing
+}
+
+func newCompressWriter(w http.ResponseWriter, r *http.Request, c *compression) (*compressWriter, bool) {
+	e, ok := c.negotiate(r.Header.Get("Accept-Encoding"))
+	if !ok || r.Method == "HEAD" {
+		return nil, false
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &compressWriter{ResponseWriter: w, encoding: e, threshold: c.threshold, code: http.StatusOK}, true
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.started {
+		w.code = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.started {
+		if w.w != nil {
+			return w.w.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() > w.threshold {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher, compressing what's buffered, so that
+// streaming responses are sent as they're written.
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start(w.buf.Len() > 0)
+	}
+	if f, ok := w.w.(interface {
+		Flush() error
+	}); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response.
+func (w *compressWriter) Close() error {
+	if !w.started {
+		if err := w.start(false); err != nil {
+			return err
+		}
+	}
+	if w.w != nil {
+		return w.w.Close()
+	}
+	return nil
+}
+
+// start writes the header, and the buffered body, compressing it if compress
+// is true, the response has a body, and it isn't already encoded.
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	h := w.Header()
+	if compress && h.Get("Content-Encoding") == "" && w.code != http.StatusNoContent && w.code != http.StatusNotModified {
+		h.Set("Content-Encoding", w.encoding.Name)
+		h.Del("Content-Length")
+		w.w = w.encoding.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.w != nil {
+		_, err = w.w.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
@@ -0,0 +1,139 @@
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func echoServer(options ...httptransport.ServerOption) *httptest.Server {
+	return httptest.NewServer(httptransport.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+		func(_ context.Context, r *http.Request) (interface{}, error) {
+			buf, err := ioutil.ReadAll(r.Body)
+			return string(buf), err
+		},
+		func(_ context.Context, w http.ResponseWriter, response interface{}) error {
+			w.Header().Set("Content-Type", "text/plain")
+			_, err := w.Write([]byte(response.(string)))
+			return err
+		},
+		options...,
+	))
+}
+
+func TestServerCompression(t *testing.T) {
+	server := echoServer(httptransport.ServerCompression(10))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name, accept, body, want string
+	}{
+		{"preferred", "deflate;q=0.5, gzip", strings.Repeat("a", 100), "gzip"},
+		{"quality", "gzip;q=0.1, deflate", strings.Repeat("a", 100), "deflate"},
+		{"wildcard", "*", strings.Repeat("a", 100), "gzip"},
+		{"refused", "gzip;q=0, br", strings.Repeat("a", 100), ""},
+		{"small", "gzip", "short", ""},
+		{"none", "", strings.Repeat("a", 100), ""},
+	} {
+		req, _ := http.NewRequest("POST", server.URL, strings.NewReader(tc.body))
+		req.Header.Set("Accept-Encoding", tc.accept)
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if want, have := tc.want, resp.Header.Get("Content-Encoding"); want != have {
+			t.Errorf("%s: want %q, have %q", tc.name, want, have)
+			continue
+		}
+		var r = bytes.NewReader(body)
+		switch tc.want {
+		case "gzip":
+			zr, _ := gzip.NewReader(r)
+			body, _ = ioutil.ReadAll(zr)
+		case "deflate":
+			zr, _ := zlib.NewReader(r)
+			body, _ = ioutil.ReadAll(zr)
+		}
+		if want, have := tc.body, string(body); want != have {
+			t.Errorf("%s: want %q, have %q", tc.name, want, have)
+		}
+	}
+}
+
+func TestServerCompressionRequest(t *testing.T) {
+	server := echoServer(httptransport.ServerCompression(1 << 20))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("compressed request"))
+	zw.Close()
+	req, _ := http.NewRequest("POST", server.URL, &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want, have := "compressed request", string(body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	req, _ = http.NewRequest("POST", server.URL, strings.NewReader("x"))
+	req.Header.Set("Content-Encoding", "br")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusUnsupportedMediaType, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestClientCompression(t *testing.T) {
+	body := strings.Repeat("b", 100)
+	server := echoServer(httptransport.ServerCompression(10))
+	defer server.Close()
+
+	var (
+		encoding     string
+		uncompressed bool
+	)
+	u, _ := url.Parse(server.URL)
+	client := httptransport.NewClient("POST", u,
+		func(_ context.Context, r *http.Request, request interface{}) error {
+			r.Body = ioutil.NopCloser(strings.NewReader(request.(string)))
+			return nil
+		},
+		func(_ context.Context, resp *http.Response) (interface{}, error) {
+			encoding, uncompressed = resp.Header.Get("Content-Encoding"), resp.Uncompressed
+			buf, err := ioutil.ReadAll(resp.Body)
+			return string(buf), err
+		},
+		httptransport.ClientCompression(httptransport.Deflate),
+	)
+	response, err := client.Endpoint()(context.Background(), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := body, response.(string); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if encoding != "" || !uncompressed {
+		t.Errorf("want decompressed response, have Content-Encoding %q", encoding)
+	}
+}
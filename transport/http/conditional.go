@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagger may be implemented by responses which know their entity tag, e.g.
+// a version or content hash, as a quoted string, optionally prefixed by W/ if
+// weak. See EncodeConditionalResponse.
+type ETagger interface {
+	ETag() string
+}
+
+// LastModifier may be implemented by responses which know when their content
+// last changed. See EncodeConditionalResponse.
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+// CacheController may be implemented by responses to set the Cache-Control
+// header, e.g. "max-age=60" or "no-cache". See EncodeConditionalResponse.
+type CacheController interface {
+	CacheControl() string
+}
+
+// EncodeConditionalResponse wraps an EncodeResponseFunc to support
+// conditional requests, so that clients may revalidate cached responses
+// cheaply. The ETag header is set from the response, if it implements
+// ETagger, or else computed by hashing the body which the wrapped encoder
+// writes. The Last-Modified and Cache-Control headers are set from the
+// response, if it implements LastModifier or CacheController.
+//
+// If a GET or HEAD request carries an If-None-Match header matching the ETag,
+// or, lacking one, an If-Modified-Since header no earlier than the
+// modification time, the response is 304 Not Modified, without a body. The
+// request headers are read from the context, so the server must populate it
+// with PopulateRequestContext in a ServerBefore.
+//
+// Computing the ETag buffers the body, so responses which are large, or
+// streamed, should implement ETagger. Only 200 OK responses are made
+// conditional.
+func EncodeConditionalResponse(next EncodeResponseFunc) EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		var (
+			etag    string
+			modTime time.Time
+		)
+		if e, ok := response.(ETagger); ok {
+			etag = e.ETag()
+		}
+		if lm, ok := response.(LastModifier); ok {
+			modTime = lm.LastModified()
+		}
+		if cc, ok := response.(CacheController); ok {
+			if v := cc.CacheControl(); v != "" {
+				w.Header().Set("Cache-Control", v)
+			}
+		}
+
+		var buffered *bufferedWriter
+		if etag == "" {
+			buffered = &bufferedWriter{header: http.Header{}, code: http.StatusOK}
+			if err := next(ctx, buffered, response); err != nil {
+				return err
+			}
+			if buffered.code == http.StatusOK {
+				sum := sha1.Sum(buffered.body.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:]) + `"`
+			}
+		}
+
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		if !modTime.IsZero() {
+			w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+		}
+		if (buffered == nil || buffered.code == http.StatusOK) && notModified(ctx, etag, modTime) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+
+		if buffered == nil {
+			return next(ctx, w, response)
+		}
+		for k, v := range buffered.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(buffered.code)
+		_, err := w.Write(buffered.body.Bytes())
+		return err
+	}
+}
+
+// notModified returns true if the conditional request headers in the context
+// show the client's copy is current.
+func notModified(ctx context.Context, etag string, modTime time.Time) bool {
+	method, _ := ctx.Value(ContextKeyRequestMethod).(string)
+	if method != "GET" && method != "HEAD" {
+		return false
+	}
+	if inm, _ := ctx.Value(ContextKeyRequestIfNoneMatch).(string); inm != "" {
+		return etag != "" && etagMatch(inm, etag)
+	}
+	ims, _ := ctx.Value(ContextKeyRequestIfModifiedSince).(string)
+	if ims == "" || modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(t)
+}
+
+// etagMatch reports whether the If-None-Match header matches the ETag, using
+// the weak comparison.
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter is a ResponseWriter which records the response.
+type bufferedWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header         { return w.header }
+func (w *bufferedWriter) WriteHeader(code int)        { w.code = code }
+func (w *bufferedWriter) Write(p []byte) (int, error) { return w.body.Write(p) }
@@ -0,0 +1,101 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+type article struct {
+	Title string `json:"title"`
+}
+
+type versionedArticle struct {
+	article
+	version  string
+	modified time.Time
+}
+
+func (a versionedArticle) ETag() string            { return a.version }
+func (a versionedArticle) LastModified() time.Time { return a.modified }
+func (a versionedArticle) CacheControl() string    { return "max-age=60" }
+
+func TestEncodeConditionalResponse(t *testing.T) {
+	modified := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	newServer := func(response interface{}) http.Handler {
+		return httptransport.NewServer(
+			func(context.Context, interface{}) (interface{}, error) { return response, nil },
+			func(context.Context, *http.Request) (interface{}, error) { return nil, nil },
+			httptransport.EncodeConditionalResponse(httptransport.EncodeJSONResponse),
+			httptransport.ServerBefore(httptransport.PopulateRequestContext),
+		)
+	}
+	do := func(h http.Handler, method string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Computed ETags.
+	h := newServer(article{"hello"})
+	rec := do(h, "GET", nil)
+	etag := rec.Header().Get("ETag")
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if etag == "" || rec.Body.Len() == 0 {
+		t.Fatalf("want ETag and body, have %q and %q", etag, rec.Body.String())
+	}
+	rec = do(h, "GET", http.Header{"If-None-Match": {`"other", ` + etag}})
+	if want, have := http.StatusNotModified, rec.Code; want != have {
+		t.Errorf("If-None-Match: want %d, have %d", want, have)
+	}
+	if want, have := 0, rec.Body.Len(); want != have {
+		t.Errorf("If-None-Match: want empty body, have %q", rec.Body.String())
+	}
+	rec = do(h, "POST", http.Header{"If-None-Match": {etag}})
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Errorf("POST: want %d, have %d", want, have)
+	}
+
+	// Response metadata.
+	h = newServer(versionedArticle{article{"hello"}, `W/"v2"`, modified})
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"none", nil, http.StatusOK},
+		{"strong match", http.Header{"If-None-Match": {`"v2"`}}, http.StatusNotModified},
+		{"mismatch", http.Header{"If-None-Match": {`"v1"`}}, http.StatusOK},
+		{"star", http.Header{"If-None-Match": {`*`}}, http.StatusNotModified},
+		{"not modified since", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {modified.Add(-time.Hour).Format(http.TimeFormat)}}, http.StatusOK},
+		{"If-None-Match wins", http.Header{
+			"If-None-Match":     {`"v1"`},
+			"If-Modified-Since": {modified.Format(http.TimeFormat)},
+		}, http.StatusOK},
+	} {
+		rec := do(h, "GET", tc.header)
+		if want, have := tc.want, rec.Code; want != have {
+			t.Errorf("%s: want %d, have %d", tc.name, want, have)
+		}
+		if want, have := `W/"v2"`, rec.Header().Get("ETag"); want != have {
+			t.Errorf("%s: ETag: want %q, have %q", tc.name, want, have)
+		}
+		if want, have := "max-age=60", rec.Header().Get("Cache-Control"); want != have {
+			t.Errorf("%s: Cache-Control: want %q, have %q", tc.name, want, have)
+		}
+		if want, have := modified.Format(http.TimeFormat), rec.Header().Get("Last-Modified"); want != have {
+			t.Errorf("%s: Last-Modified: want %q, have %q", tc.name, want, have)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOption sets an optional parameter for ServerCORS.
+type CORSOption func(*cors)
+
+// CORSAllowedOrigins sets the origins, e.g. https://example.com, allowed to
+// make cross-origin requests. "*" allows any origin. By default, any origin is
+// allowed.
+func CORSAllowedOrigins(origins ...string) CORSOption {
+	return func(c *cors) { c.origins = origins }
+}
+
+// CORSAllowedMethods sets the methods allowed in cross-origin requests. By
+// default, GET, HEAD, and POST are allowed.
+func CORSAllowedMethods(methods ...string) CORSOption {
+	return func(c *cors) { c.methods = methods }
+}
+
+// CORSAllowedHeaders sets the request headers allowed in cross-origin
+// requests, besides those browsers always allow. "*" allows any header. By
+// default, Content-Type is allowed.
+func CORSAllowedHeaders(headers ...string) CORSOption {
+	return func(c *cors) { c.headers = headers }
+}
+
+// CORSExposedHeaders sets the response headers exposed to cross-origin
+// requests, besides those browsers always expose. By default, none are.
+func CORSExposedHeaders(headers ...string) CORSOption {
+	return func(c *cors) { c.exposed = headers }
+}
+
+// CORSAllowCredentials allows cross-origin requests to include credentials,
+// such as cookies. Allowed origins are then reflected, rather than allowed
+// with "*", as browsers require.
+func CORSAllowCredentials() CORSOption {
+	return func(c *cors) { c.credentials = true }
+}
+
+// CORSMaxAge sets how long browsers may cache the result of a preflight
+// request. By default, browsers choose.
+func CORSMaxAge(d time.Duration) CORSOption {
+	return func(c *cors) { c.maxAge = d }
+}
+
+// ServerCORS enables Cross-Origin Resource Sharing, so that the server may be
+// called by browsers from other origins. The CORS headers are set before the
+// request is decoded, so they apply to every response, including errors.
+// Preflight requests, i.e. OPTIONS requests with an
+// Access-Control-Request-Method header, are answered with 204 No Content,
+// without invoking the endpoint.
+func ServerCORS(options ...CORSOption) ServerOption {
+	c := &cors{
+		origins: []string{"*"},
+		methods: []string{"GET", "HEAD", "POST"},
+		headers: []string{"Content-Type"},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return func(s *Server) { s.cors = c }
+}
+
+type cors struct {
+	origins     []string
+	methods     []string
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+// handle sets the CORS headers of the response, and returns true if the
+// request was a preflight request, which has been answered.
+func (c *cors) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if origin == "" {
+		return false
+	}
+	if !c.allowOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return preflight
+	}
+
+	if c.credentials || !contains(c.origins, "*") {
+		h.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		h.Set("Access-Control-Allow-Origin", "*")
+	}
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(c.exposed) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(c.exposed, ", "))
+		}
+		return false
+	}
+
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if method := r.Header.Get("Access-Control-Request-Method"); containsFold(c.methods, method) {
+		h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+	}
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		if contains(c.headers, "*") {
+			h.Set("Access-Control-Allow-Headers", requested)
+		} else {
+			h.Set("Access-Control-Allow-Headers", strings.Join(c.headers, ", "))
+		}
+	}
+	if c.maxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func (c *cors) allowOrigin(origin string) bool {
+	return contains(c.origins, "*") || containsFold(c.origins, origin)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
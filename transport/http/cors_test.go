@@ -0,0 +1,104 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestServerCORS(t *testing.T) {
+	server := echoServer(httptransport.ServerCORS(
+		httptransport.CORSAllowedOrigins("https://example.com"),
+		httptransport.CORSAllowedMethods("GET", "PUT"),
+		httptransport.CORSAllowedHeaders("Content-Type", "X-Token"),
+		httptransport.CORSExposedHeaders("X-Id"),
+		httptransport.CORSAllowCredentials(),
+		httptransport.CORSMaxAge(time.Minute),
+	))
+	defer server.Close()
+
+	do := func(method, origin, requestMethod string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+			req.Header.Set("Access-Control-Request-Headers", "x-token")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Preflight from an allowed origin.
+	resp := do("OPTIONS", "https://example.com", "PUT")
+	if want, have := http.StatusNoContent, resp.StatusCode; want != have {
+		t.Errorf("preflight: want %d, have %d", want, have)
+	}
+	for k, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "Content-Type, X-Token",
+		"Access-Control-Max-Age":           "60",
+	} {
+		if have := resp.Header.Get(k); want != have {
+			t.Errorf("preflight %s: want %q, have %q", k, want, have)
+		}
+	}
+
+	// Preflight for a method that isn't allowed.
+	resp = do("OPTIONS", "https://example.com", "DELETE")
+	if want, have := "", resp.Header.Get("Access-Control-Allow-Methods"); want != have {
+		t.Errorf("disallowed method: want %q, have %q", want, have)
+	}
+
+	// Preflight from an origin that isn't allowed.
+	resp = do("OPTIONS", "https://evil.com", "PUT")
+	if want, have := "", resp.Header.Get("Access-Control-Allow-Origin"); want != have {
+		t.Errorf("disallowed origin: want %q, have %q", want, have)
+	}
+
+	// Actual request from an allowed origin reaches the endpoint.
+	resp = do("GET", "https://example.com", "")
+	if want, have := http.StatusOK, resp.StatusCode; want != have {
+		t.Errorf("request: want %d, have %d", want, have)
+	}
+	if want, have := "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"); want != have {
+		t.Errorf("request: want %q, have %q", want, have)
+	}
+	if want, have := "X-Id", resp.Header.Get("Access-Control-Expose-Headers"); want != have {
+		t.Errorf("request: want %q, have %q", want, have)
+	}
+	if want, have := "Origin", resp.Header.Get("Vary"); want != have {
+		t.Errorf("request Vary: want %q, have %q", want, have)
+	}
+
+	// Same-origin requests are untouched.
+	resp = do("GET", "", "")
+	if want, have := "", resp.Header.Get("Access-Control-Allow-Origin"); want != have {
+		t.Errorf("no origin: want %q, have %q", want, have)
+	}
+}
+
+func TestServerCORSAnyOrigin(t *testing.T) {
+	server := echoServer(httptransport.ServerCORS())
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, nil)
+	req.Header.Set("Origin", "https://anywhere.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := "*", resp.Header.Get("Access-Control-Allow-Origin"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// TimeoutHeader carries the time remaining before the client's deadline, in
+// whole milliseconds.
+const TimeoutHeader = "X-Request-Timeout"
+
+// ContextToTimeoutHeader is a client RequestFunc which sets TimeoutHeader to
+// the time remaining before the deadline of the context, if it has one.
+// Combined with endpoint.DeadlineBudget, HTTP services propagate their
+// timeout budgets like gRPC ones do.
+func ContextToTimeoutHeader(ctx context.Context, r *http.Request) context.Context {
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := int64(time.Until(deadline) / time.Millisecond)
+		if ms < 0 {
+			ms = 0
+		}
+		r.Header.Set(TimeoutHeader, strconv.FormatInt(ms, 10))
+	}
+	return ctx
+}
+
+// TimeoutHeaderToContext is a server RequestFunc which passes the timeout in
+// TimeoutHeader, if any, to endpoint.ContextWithTimeout. It takes effect in an
+// endpoint.Timeout middleware wrapping the server's endpoint.
+func TimeoutHeaderToContext(ctx context.Context, r *http.Request) context.Context {
+	ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+	if err != nil || ms < 0 {
+		return ctx
+	}
+	return endpoint.ContextWithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
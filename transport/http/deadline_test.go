@@ -0,0 +1,38 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestTimeoutHeader(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://example.com", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	httptransport.ContextToTimeoutHeader(ctx, r)
+	ms, err := strconv.Atoi(r.Header.Get(httptransport.TimeoutHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms > 2000 || ms < 1900 {
+		t.Errorf("want about 2000ms, have %dms", ms)
+	}
+
+	r.Header.Set(httptransport.TimeoutHeader, "1500")
+	d, ok := endpoint.TimeoutFromContext(httptransport.TimeoutHeaderToContext(context.Background(), r))
+	if want, have := 1500*time.Millisecond, d; !ok || want != have {
+		t.Errorf("want %s, have %s (%v)", want, have, ok)
+	}
+
+	r.Header.Del(httptransport.TimeoutHeader)
+	httptransport.ContextToTimeoutHeader(context.Background(), r)
+	if _, ok := endpoint.TimeoutFromContext(httptransport.TimeoutHeaderToContext(context.Background(), r)); ok {
+		t.Error("want no timeout without a deadline")
+	}
+}
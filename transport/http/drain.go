@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDraining is returned to requests which arrive while the server is
+// draining. It implements the StatusCoder interface, so the default error
+// encoder responds with 503 Service Unavailable.
+var ErrDraining error = statusError{"server is draining", http.StatusServiceUnavailable}
+
+// DrainerOption sets an optional parameter for Drainers.
+type DrainerOption func(*Drainer)
+
+// DrainerDelay sets how long Shutdown fails readiness checks before it stops
+// accepting connections, giving load balancers time to stop routing to the
+// server. Requests which arrive during the delay are still served. By
+// default, there's no delay.
+func DrainerDelay(d time.Duration) DrainerOption {
+	return func(dr *Drainer) { dr.delay = d }
+}
+
+// Drainer tracks the in-flight requests of the servers it's given to with
+// ServerDrainer, so that they may be shut down gracefully. A Drainer may be
+// shared by many servers, and should be used for one shutdown only.
+type Drainer struct {
+	delay time.Duration
+
+	mtx      sync.Mutex
+	unready  bool // set by Shutdown before its delay, and by draining
+	draining bool
+	next     uint64
+	active   map[uint64]context.CancelFunc
+	idle     chan struct{} // closed when draining with no active requests
+}
+
+// NewDrainer returns a Drainer with no requests in flight.
+func NewDrainer(options ...DrainerOption) *Drainer {
+	d := &Drainer{
+		active: map[uint64]context.CancelFunc{},
+		idle:   make(chan struct{}),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// ServerDrainer tracks the requests of the server with the Drainer. Once the
+// Drainer is draining, new requests are rejected with ErrDraining and the
+// Connection: close header. Requests still in flight when the drain times out
+// have their contexts canceled.
+func ServerDrainer(d *Drainer) ServerOption {
+	return func(s *Server) { s.drainer = d }
+}
+
+// Draining returns true once the Drainer has begun to drain.
+func (d *Drainer) Draining() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.draining
+}
+
+// Active returns the number of requests in flight.
+func (d *Drainer) Active() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return len(d.active)
+}
+
+// ReadinessHandler returns a handler for readiness checks, which responds
+// with 200 OK until Shutdown is called or the Drainer begins to drain, and
+// 503 Service Unavailable after.
+func (d *Drainer) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.ready() {
+			http.Error(w, "draining", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// Drain rejects new requests, and waits for those in flight to complete. If
+// the context is done first, the contexts of the remaining requests are
+// canceled, and the context's error is returned.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.startDrain()
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		d.cancelActive()
+		return ctx.Err()
+	}
+}
+
+// Shutdown gracefully shuts down the http.Server. It fails readiness checks
+// for the delay set by DrainerDelay, then stops the server accepting
+// connections, and waits for the requests in flight to complete. If the
+// context is done first, the contexts of the remaining requests are canceled,
+// the server's connections are closed, and the context's error is returned.
+func (d *Drainer) Shutdown(ctx context.Context, srv *http.Server) error {
+	d.mtx.Lock()
+	d.unready = true
+	d.mtx.Unlock()
+	if d.delay > 0 {
+		select {
+		case <-time.After(d.delay):
+		case <-ctx.Done():
+		}
+	}
+
+	d.startDrain()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Shutdown(ctx) }()
+	if err := d.Drain(ctx); err != nil {
+		srv.Close()
+		return err
+	}
+	if err := <-errc; err != nil {
+		srv.Close()
+		return err
+	}
+	return nil
+}
+
+// ready reports whether readiness checks pass.
+func (d *Drainer) ready() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return !d.unready
+}
+
+func (d *Drainer) startDrain() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.draining {
+		return
+	}
+	d.unready, d.draining = true, true
+	if len(d.active) == 0 {
+		close(d.idle)
+	}
+}
+
+func (d *Drainer) cancelActive() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for _, cancel := range d.active {
+		cancel()
+	}
+}
+
+// begin tracks a request, returning its context and a function to call when
+// it completes, or false if the Drainer is draining.
+func (d *Drainer) begin(ctx context.Context) (context.Context, func(), bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.draining {
+		return ctx, nil, false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	id := d.next
+	d.next++
+	d.active[id] = cancel
+	return ctx, func() {
+		cancel()
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		delete(d.active, id)
+		if d.draining && len(d.active) == 0 {
+			close(d.idle)
+		}
+	}, true
+}
@@ -0,0 +1,152 @@
+package http_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestDrainer(t *testing.T) {
+	var (
+		d       = httptransport.NewDrainer()
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+	)
+	handler := httptransport.NewServer(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-release
+			return struct{}{}, nil
+		},
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+		httptransport.ServerDrainer(d),
+	)
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-started
+	if want, have := 1, d.Active(); want != have {
+		t.Fatalf("Active: want %d, have %d", want, have)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- d.Drain(context.Background()) }()
+	for !d.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec := httptest.NewRecorder()
+	d.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if want, have := http.StatusServiceUnavailable, rec.Code; want != have {
+		t.Errorf("readiness: want %d, have %d", want, have)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if want, have := http.StatusServiceUnavailable, rec.Code; want != have {
+		t.Errorf("new request: want %d, have %d", want, have)
+	}
+
+	select {
+	case <-drained:
+		t.Fatal("drained with a request in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrainerShutdownTimeout(t *testing.T) {
+	var (
+		d        = httptransport.NewDrainer()
+		started  = make(chan struct{}, 1)
+		canceled = make(chan struct{})
+	)
+	handler := httptransport.NewServer(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		},
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+		httptransport.ServerDrainer(d),
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(ln)
+	go http.Get("http://" + ln.Addr().String())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, d.Shutdown(ctx, srv); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("request context wasn't canceled")
+	}
+}
+
+func TestDrainerShutdownDelay(t *testing.T) {
+	d := httptransport.NewDrainer(httptransport.DrainerDelay(100 * time.Millisecond))
+	handler := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+		httptransport.ServerDrainer(d),
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(ln)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- d.Shutdown(context.Background(), srv) }()
+	for {
+		rec := httptest.NewRecorder()
+		d.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+		if rec.Code == http.StatusServiceUnavailable {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// During the delay, load balancers may still route requests, which are
+	// served.
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusOK, resp.StatusCode; want != have {
+		t.Errorf("request during the delay: want %d, have %d", want, have)
+	}
+	if d.Draining() {
+		t.Error("draining during the delay")
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if !d.Draining() {
+		t.Error("not draining after Shutdown")
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrUnsupportedContentType is returned for requests whose Content-Type isn't
+// allowed by ServerAllowedContentTypes. It implements StatusCoder, so
+// DefaultErrorEncoder responds with 415 Unsupported Media Type.
+var ErrUnsupportedContentType error = statusError{"unsupported content type", http.StatusUnsupportedMediaType}
+
+// ServerMaxBodySize limits request bodies to n bytes. Requests declaring a
+// larger Content-Length fail with ErrRequestTooLarge before they're decoded,
+// and reading beyond n bytes of other bodies fails with ErrRequestTooLarge, so
+// DefaultErrorEncoder responds with 413 Request Entity Too Large. The limit
+// applies after any decompression by ServerCompression. By default, bodies
+// aren't limited.
+func ServerMaxBodySize(n int64) ServerOption {
+	return func(s *Server) { s.maxBodySize = n }
+}
+
+// ServerAllowedContentTypes restricts the media types of request bodies, e.g.
+// to "application/json". Parameters, such as charset, are ignored. Requests
+// with a body of another type fail with ErrUnsupportedContentType. Requests
+// without a body are always allowed. By default, any type is.
+func ServerAllowedContentTypes(types ...string) ServerOption {
+	return func(s *Server) { s.contentTypes = types }
+}
+
+// guard checks the request against the server's limits, wrapping its body
+// if needed.
+func (s Server) guard(r *http.Request) error {
+	hasBody := r.Body != nil && r.Body != http.NoBody && (r.ContentLength != 0 || len(r.TransferEncoding) > 0)
+	if len(s.contentTypes) > 0 && hasBody {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !containsFold(s.contentTypes, mediaType) {
+			return ErrUnsupportedContentType
+		}
+	}
+	if s.maxBodySize > 0 && r.Body != nil {
+		if r.ContentLength > s.maxBodySize {
+			return ErrRequestTooLarge
+		}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{&limitedReader{r: r.Body, n: s.maxBodySize, err: ErrRequestTooLarge}, r.Body}
+	}
+	return nil
+}
+
+// DecodeJSONRequest returns a DecodeRequestFunc which decodes the JSON body of
+// the request into the value returned by newRequest, which should be a
+// pointer, and returns it. Unlike json.Unmarshal, it rejects fields unknown to
+// the request type, and any data after the JSON value. Such errors describe
+// the problem, and implement StatusCoder, so DefaultErrorEncoder responds
+// with 400 Bad Request. Errors from the body, such as ErrRequestTooLarge, are
+// returned as they are.
+func DecodeJSONRequest(newRequest func() interface{}) DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		request := newRequest()
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(request); err != nil {
+			return nil, jsonError(err)
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			if err == nil {
+				return nil, statusError{"invalid JSON: unexpected data after value", http.StatusBadRequest}
+			}
+			return nil, jsonError(err)
+		}
+		return request, nil
+	}
+}
+
+// jsonError returns errors from the body unchanged, and describes others as
+// invalid JSON.
+func jsonError(err error) error {
+	if _, ok := err.(statusError); ok {
+		return err
+	}
+	msg := err.Error()
+	if err == io.EOF {
+		msg = "empty body"
+	}
+	return statusError{"invalid JSON: " + strings.TrimPrefix(msg, "json: "), http.StatusBadRequest}
+}
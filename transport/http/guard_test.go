@@ -0,0 +1,60 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+type createRequest struct {
+	Name string `json:"name"`
+}
+
+func TestServerGuards(t *testing.T) {
+	handler := httptransport.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+		httptransport.DecodeJSONRequest(func() interface{} { return &createRequest{} }),
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerMaxBodySize(32),
+		httptransport.ServerAllowedContentTypes("application/json"),
+	)
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		chunked     bool
+		want        int
+		wantBody    string
+	}{
+		{"ok", "application/json; charset=utf-8", `{"name":"x"}`, false, 200, `{"name":"x"}`},
+		{"no body", "", "", false, 400, "invalid JSON: empty body"},
+		{"wrong type", "text/plain", `{"name":"x"}`, false, 415, "unsupported content type"},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("x", 40) + `"}`, false, 413, "request body too large"},
+		{"too large chunked", "application/json", `{"name":"` + strings.Repeat("x", 40) + `"}`, true, 413, "request body too large"},
+		{"unknown field", "application/json", `{"nam":"x"}`, false, 400, `invalid JSON: unknown field "nam"`},
+		{"trailing data", "application/json", `{"name":"x"} {}`, false, 400, "invalid JSON: unexpected data after value"},
+		{"malformed", "application/json", `{"name":`, false, 400, "invalid JSON: unexpected EOF"},
+	} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if want, have := tc.want, rec.Code; want != have {
+			t.Errorf("%s: want %d, have %d", tc.name, want, have)
+		}
+		body, _ := ioutil.ReadAll(rec.Body)
+		if want, have := tc.wantBody, strings.TrimSpace(string(body)); want != have {
+			t.Errorf("%s: want %q, have %q", tc.name, want, have)
+		}
+	}
+}
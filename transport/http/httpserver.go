@@ -0,0 +1,95 @@
+package http
+
+import (
+	stdlog "log"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Defaults for NewHTTPServer.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+)
+
+// HTTPServerOption sets an optional parameter for NewHTTPServer.
+type HTTPServerOption func(*httpServerConfig)
+
+// HTTPServerReadTimeout sets the maximum time to read each request, including
+// its body. By default, there's no limit, so long uploads aren't cut off.
+func HTTPServerReadTimeout(d time.Duration) HTTPServerOption {
+	return func(c *httpServerConfig) { c.srv.ReadTimeout = d }
+}
+
+// HTTPServerReadHeaderTimeout sets the maximum time to read the headers of
+// each request. By default, DefaultReadHeaderTimeout is used.
+func HTTPServerReadHeaderTimeout(d time.Duration) HTTPServerOption {
+	return func(c *httpServerConfig) { c.srv.ReadHeaderTimeout = d }
+}
+
+// HTTPServerWriteTimeout sets the maximum time to write each response, from
+// the end of reading the request headers. By default, there's no limit, so
+// streamed responses aren't cut off.
+func HTTPServerWriteTimeout(d time.Duration) HTTPServerOption {
+	return func(c *httpServerConfig) { c.srv.WriteTimeout = d }
+}
+
+// HTTPServerIdleTimeout sets the maximum time to wait for the next request on
+// a keep-alive connection. By default, DefaultIdleTimeout is used.
+func HTTPServerIdleTimeout(d time.Duration) HTTPServerOption {
+	return func(c *httpServerConfig) { c.srv.IdleTimeout = d }
+}
+
+// HTTPServerMaxHeaderBytes sets the maximum size of request headers. By
+// default, http.DefaultMaxHeaderBytes is used.
+func HTTPServerMaxHeaderBytes(n int) HTTPServerOption {
+	return func(c *httpServerConfig) { c.srv.MaxHeaderBytes = n }
+}
+
+// HTTPServerErrorLogger logs errors accepting connections, and unexpected
+// behavior of handlers, to the logger. By default, they're logged by the
+// standard library logger.
+func HTTPServerErrorLogger(logger log.Logger) HTTPServerOption {
+	return func(c *httpServerConfig) {
+		c.srv.ErrorLog = stdlog.New(log.NewStdlibAdapter(logger), "", 0)
+	}
+}
+
+// HTTPServerH2C serves HTTP/2 over cleartext connections, both with prior
+// knowledge and by upgrade from HTTP/1.1, as well as HTTP/1.1. It's intended
+// for servers behind proxies which terminate TLS, but speak HTTP/2 to
+// backends. Servers using TLS negotiate HTTP/2 regardless.
+func HTTPServerH2C() HTTPServerOption {
+	return func(c *httpServerConfig) { c.h2c = true }
+}
+
+type httpServerConfig struct {
+	srv *http.Server
+	h2c bool
+}
+
+// NewHTTPServer returns an http.Server serving the handler, typically a
+// Server or Router, at the address, configured by the options. Unlike the
+// zero http.Server, it bounds the time to read request headers and the time
+// idle connections are kept, so that slow or abandoned clients don't hold
+// resources indefinitely.
+func NewHTTPServer(addr string, handler http.Handler, options ...HTTPServerOption) *http.Server {
+	c := &httpServerConfig{srv: &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+	}}
+	for _, option := range options {
+		option(c)
+	}
+	if c.h2c {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: c.srv.IdleTimeout})
+	}
+	c.srv.Handler = handler
+	return c.srv
+}
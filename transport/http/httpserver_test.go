@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestNewHTTPServer(t *testing.T) {
+	srv := httptransport.NewHTTPServer(":0", http.NotFoundHandler(),
+		httptransport.HTTPServerWriteTimeout(time.Minute),
+		httptransport.HTTPServerMaxHeaderBytes(4096),
+	)
+	if want, have := httptransport.DefaultReadHeaderTimeout, srv.ReadHeaderTimeout; want != have {
+		t.Errorf("ReadHeaderTimeout: want %s, have %s", want, have)
+	}
+	if want, have := time.Minute, srv.WriteTimeout; want != have {
+		t.Errorf("WriteTimeout: want %s, have %s", want, have)
+	}
+	if want, have := 4096, srv.MaxHeaderBytes; want != have {
+		t.Errorf("MaxHeaderBytes: want %d, have %d", want, have)
+	}
+}
+
+func TestHTTPServerH2C(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptransport.NewHTTPServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), httptransport.HTTPServerH2C())
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if want, have := 2, resp.ProtoMajor; want != have {
+		t.Errorf("want HTTP/%d, have HTTP/%d", want, have)
+	}
+}
@@ -0,0 +1,11 @@
+// Package jsonrpc provides a JSON-RPC 2.0 binding for endpoints, over HTTP.
+// A Server serves the methods of an EndpointCodecMap, each an endpoint with
+// its codecs, including batch requests, and notifications, as the spec
+// requires. See https://www.jsonrpc.org/specification.
+//
+// A WebSocketServer serves the same methods over WebSocket connections, on
+// which the server may also push notifications to clients. Endpoints find
+// the connection of their request in the context, by ContextKeyConn, and may
+// subscribe it to the topics of a Registry, to whose subscribers
+// notifications are published.
+package jsonrpc
@@ -0,0 +1,32 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from the params
+// of a JSON-RPC request. It's designed to be used in JSON-RPC servers, for
+// server-side endpoints. One straightforward DecodeRequestFunc could be
+// something that JSON decodes the params to the concrete request type.
+type DecodeRequestFunc func(context.Context, json.RawMessage) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to the result of a
+// JSON-RPC response. It's designed to be used in JSON-RPC servers, for
+// server-side endpoints. One straightforward EncodeResponseFunc could be
+// something that JSON encodes the object directly.
+type EncodeResponseFunc func(context.Context, interface{}) (response json.RawMessage, err error)
+
+// EndpointCodec is an endpoint, and the codecs of its requests and
+// responses, which serves a JSON-RPC method.
+type EndpointCodec struct {
+	Endpoint endpoint.Endpoint
+	Decode   DecodeRequestFunc
+	Encode   EncodeResponseFunc
+}
+
+// EndpointCodecMap maps the names of JSON-RPC methods to the EndpointCodecs
+// serving them.
+type EndpointCodecMap map[string]EndpointCodec
@@ -0,0 +1,109 @@
+package jsonrpc
+
+import (
+	"sync"
+)
+
+// Registry records the subscriptions of connections to topics, so that
+// notifications may be published to the subscribers of a topic. Connections
+// are unsubscribed from every topic once they're closed. It's safe for
+// concurrent use.
+type Registry struct {
+	mtx    sync.Mutex
+	topics map[string]map[*Conn]struct{}
+	conns  map[*Conn]map[string]struct{}
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		topics: map[string]map[*Conn]struct{}{},
+		conns:  map[*Conn]map[string]struct{}{},
+	}
+}
+
+// Subscribe subscribes the connection to the topic. Subscribing it again
+// has no effect.
+func (r *Registry) Subscribe(topic string, conn *Conn) {
+	r.mtx.Lock()
+	topics, known := r.conns[conn]
+	if !known {
+		topics = map[string]struct{}{}
+		r.conns[conn] = topics
+	}
+	topics[topic] = struct{}{}
+	conns, ok := r.topics[topic]
+	if !ok {
+		conns = map[*Conn]struct{}{}
+		r.topics[topic] = conns
+	}
+	conns[conn] = struct{}{}
+	r.mtx.Unlock()
+
+	if !known {
+		conn.afterClose(func() { r.remove(conn) })
+	}
+}
+
+// Unsubscribe unsubscribes the connection from the topic.
+func (r *Registry) Unsubscribe(topic string, conn *Conn) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.unsubscribe(topic, conn)
+}
+
+// Subscribers returns the number of connections subscribed to the topic.
+func (r *Registry) Subscribers(topic string) int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.topics[topic])
+}
+
+// Publish sends a notification of the method, with the params, to every
+// connection subscribed to the topic, and returns the number of
+// connections to which it's sent. Connections to which it can't be sent are
+// closed, and so unsubscribed. The params are JSON encoded once, or omitted,
+// if nil.
+func (r *Registry) Publish(topic, method string, params interface{}) (int, error) {
+	message, err := notification(method, params)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mtx.Lock()
+	conns := make([]*Conn, 0, len(r.topics[topic]))
+	for conn := range r.topics[topic] {
+		conns = append(conns, conn)
+	}
+	r.mtx.Unlock()
+
+	var n int
+	for _, conn := range conns {
+		if err := conn.write(message); err == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *Registry) unsubscribe(topic string, conn *Conn) {
+	if conns, ok := r.topics[topic]; ok {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(r.topics, topic)
+		}
+	}
+	if topics, ok := r.conns[conn]; ok {
+		delete(topics, topic)
+	}
+}
+
+// remove unsubscribes the closed connection from every topic.
+func (r *Registry) remove(conn *Conn) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for topic := range r.conns[conn] {
+		r.unsubscribe(topic, conn)
+	}
+	delete(r.conns, conn)
+}
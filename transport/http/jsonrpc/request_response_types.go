@@ -0,0 +1,69 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+)
+
+// Version is the version of JSON-RPC of requests and responses.
+const Version = "2.0"
+
+// ContentType is the content type of JSON-RPC responses.
+const ContentType = "application/json; charset=utf-8"
+
+// Request is a JSON-RPC request. A request without an ID is a
+// notification, to which there's no response.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification returns true if the request has no ID. A request with a
+// null ID isn't a notification.
+func (r Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is a JSON-RPC response, with either a result or an error.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// The error codes defined by the spec. Codes from -32000 to -32099 are
+// reserved for implementation-defined server errors.
+const (
+	ParseError          = -32700
+	InvalidRequestError = -32600
+	MethodNotFoundError = -32601
+	InvalidParamsError  = -32602
+	InternalError       = -32603
+)
+
+// Error is the error of a JSON-RPC response. It may be returned by
+// endpoints and decoders, to respond with its code and data.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements error.
+func (e Error) Error() string {
+	return e.Message
+}
+
+// ErrorCode returns the code of the error.
+func (e Error) ErrorCode() int {
+	return e.Code
+}
+
+// ErrorCoder is checked by servers for errors of endpoints and decoders.
+// If an error implements it, the JSON-RPC error has its code; otherwise,
+// the code is InternalError, or InvalidParamsError, for errors of decoders.
+type ErrorCoder interface {
+	ErrorCode() int
+}
@@ -0,0 +1,256 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Server serves the methods of an EndpointCodecMap, and implements
+// http.Handler.
+type Server struct {
+	ecm        EndpointCodecMap
+	before     []httptransport.RequestFunc
+	after      []httptransport.ServerResponseFunc
+	finalizer  httptransport.ServerFinalizerFunc
+	logger     log.Logger
+	batchLimit int
+}
+
+// NewServer constructs a new server, which implements http.Handler and
+// serves the methods of the EndpointCodecMap.
+func NewServer(
+	ecm EndpointCodecMap,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		ecm:    ecm,
+		logger: log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the HTTP request object before the
+// JSON-RPC requests are decoded.
+func ServerBefore(before ...httptransport.RequestFunc) ServerOption {
+	return func(s *Server) { s.before = append(s.before, before...) }
+}
+
+// ServerAfter functions are executed on the HTTP response writer after the
+// endpoints are invoked, but before anything is written to the client.
+func ServerAfter(after ...httptransport.ServerResponseFunc) ServerOption {
+	return func(s *Server) { s.after = append(s.after, after...) }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// ServerFinalizer is executed at the end of every HTTP request.
+// By default, no finalizer is registered.
+func ServerFinalizer(f httptransport.ServerFinalizerFunc) ServerOption {
+	return func(s *Server) { s.finalizer = f }
+}
+
+// ServerBatchLimit sets the maximum number of requests of batches. Larger
+// batches are rejected with an InvalidRequestError. By default, there's no
+// limit.
+func ServerBatchLimit(n int) ServerOption {
+	return func(s *Server) { s.batchLimit = n }
+}
+
+// ServeHTTP implements http.Handler. The body of the request may be a
+// single JSON-RPC request, or a batch of them, in an array. The requests of
+// a batch are served concurrently, and their responses are written in the
+// order of the requests. Notifications are served, but not responded to;
+// if there are only notifications, the status of the response is 204 No
+// Content, without a body.
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	code := http.StatusOK
+	if s.finalizer != nil {
+		defer func() { s.finalizer(ctx, code, r) }()
+	}
+
+	if r.Method != http.MethodPost {
+		code = http.StatusMethodNotAllowed
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "JSON-RPC requests must be POSTed", code)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.logger.Log("err", err)
+		code = http.StatusBadRequest
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, r)
+	}
+
+	response, ok := s.serve(ctx, body)
+
+	for _, f := range s.after {
+		ctx = f(ctx, w)
+	}
+
+	if !ok {
+		code = http.StatusNoContent
+		w.WriteHeader(code)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(code)
+	if _, err := w.Write(response); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+// serve serves the single or batch request of the body, and returns its
+// response, or false if there's none.
+func (s Server) serve(ctx context.Context, body []byte) ([]byte, bool) {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		response := s.call(ctx, body)
+		if response == nil {
+			return nil, false
+		}
+		return s.marshal(response), true
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return s.marshal(errorResponse(nil, ParseError, err.Error())), true
+	}
+	if len(batch) == 0 {
+		return s.marshal(errorResponse(nil, InvalidRequestError, "empty batch")), true
+	}
+	if s.batchLimit > 0 && len(batch) > s.batchLimit {
+		return s.marshal(errorResponse(nil, InvalidRequestError, fmt.Sprintf("batch of more than %d requests", s.batchLimit))), true
+	}
+
+	responses := make([]*Response, len(batch))
+	var wg sync.WaitGroup
+	wg.Add(len(batch))
+	for i := range batch {
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = s.call(ctx, batch[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var results []*Response
+	for _, response := range responses {
+		if response != nil {
+			results = append(results, response)
+		}
+	}
+	if len(results) == 0 {
+		return nil, false
+	}
+	return s.marshal(results), true
+}
+
+// call serves a single request, and returns its response, or nil for
+// notifications.
+func (s Server) call(ctx context.Context, raw json.RawMessage) *Response {
+	if !json.Valid(raw) {
+		return errorResponse(nil, ParseError, "invalid JSON")
+	}
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return errorResponse(nil, InvalidRequestError, err.Error())
+	}
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, InvalidRequestError, "invalid JSON-RPC 2.0 request")
+	}
+
+	ec, ok := s.ecm[req.Method]
+	if !ok {
+		return s.respond(req, errorResponse(req.ID, MethodNotFoundError, "method not found: "+req.Method))
+	}
+
+	request, err := ec.Decode(ctx, req.Params)
+	if err != nil {
+		s.logger.Log("method", req.Method, "err", err)
+		return s.respond(req, endpointError(req.ID, err, InvalidParamsError))
+	}
+
+	response, err := ec.Endpoint(ctx, request)
+	if err != nil {
+		s.logger.Log("method", req.Method, "err", err)
+		return s.respond(req, endpointError(req.ID, err, InternalError))
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return s.respond(req, endpointError(req.ID, f.Failed(), InternalError))
+	}
+
+	result, err := ec.Encode(ctx, response)
+	if err != nil {
+		s.logger.Log("method", req.Method, "err", err)
+		return s.respond(req, endpointError(req.ID, err, InternalError))
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return s.respond(req, &Response{JSONRPC: Version, Result: result, ID: req.ID})
+}
+
+// respond returns the response, unless the request is a notification.
+func (s Server) respond(req Request, response *Response) *Response {
+	if req.IsNotification() {
+		return nil
+	}
+	return response
+}
+
+func (s Server) marshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Log("err", err)
+		b, _ = json.Marshal(errorResponse(nil, InternalError, err.Error()))
+	}
+	return b
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *Response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: Version, Error: &Error{Code: code, Message: message}, ID: id}
+}
+
+// endpointError returns the response of an error, with the code of the
+// error, if it's an ErrorCoder, or the default code.
+func endpointError(id json.RawMessage, err error, code int) *Response {
+	if e, ok := err.(Error); ok {
+		response := errorResponse(id, e.Code, e.Message)
+		response.Error.Data = e.Data
+		return response
+	}
+	if e, ok := err.(ErrorCoder); ok {
+		code = e.ErrorCode()
+	}
+	return errorResponse(id, code, err.Error())
+}
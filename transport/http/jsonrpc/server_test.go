@@ -0,0 +1,207 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+type addRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func decodeAddRequest(_ context.Context, params json.RawMessage) (interface{}, error) {
+	var req addRequest
+	err := json.Unmarshal(params, &req)
+	return req, err
+}
+
+func encodeJSON(_ context.Context, response interface{}) (json.RawMessage, error) {
+	return json.Marshal(response)
+}
+
+func newTestServer(options ...jsonrpc.ServerOption) (*httptest.Server, *[]int) {
+	var (
+		mtx      sync.Mutex
+		notified []int
+	)
+	ecm := jsonrpc.EndpointCodecMap{
+		"add": {
+			Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(addRequest)
+				// Later requests of batches complete first.
+				time.Sleep(time.Duration(10-req.A) * time.Millisecond)
+				return req.A + req.B, nil
+			},
+			Decode: decodeAddRequest,
+			Encode: encodeJSON,
+		},
+		"fail": {
+			Endpoint: func(context.Context, interface{}) (interface{}, error) {
+				return nil, jsonrpc.Error{Code: -32000, Message: "dang", Data: "details"}
+			},
+			Decode: decodeAddRequest,
+			Encode: encodeJSON,
+		},
+		"notify": {
+			Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+				mtx.Lock()
+				notified = append(notified, request.(addRequest).A)
+				mtx.Unlock()
+				return nil, nil
+			},
+			Decode: decodeAddRequest,
+			Encode: encodeJSON,
+		},
+	}
+	return httptest.NewServer(jsonrpc.NewServer(ecm, options...)), &notified
+}
+
+func post(t *testing.T, url, body string) (int, string) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, strings.TrimSpace(string(b))
+}
+
+func TestServer(t *testing.T) {
+	server, _ := newTestServer()
+	defer server.Close()
+
+	for _, testcase := range []struct {
+		name, body, want string
+	}{
+		{
+			"result",
+			`{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`,
+			`{"jsonrpc":"2.0","result":3,"id":1}`,
+		},
+		{
+			"string id",
+			`{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":"x"}`,
+			`{"jsonrpc":"2.0","result":3,"id":"x"}`,
+		},
+		{
+			"error",
+			`{"jsonrpc":"2.0","method":"fail","params":{},"id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"dang","data":"details"},"id":1}`,
+		},
+		{
+			"method not found",
+			`{"jsonrpc":"2.0","method":"nope","id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: nope"},"id":1}`,
+		},
+		{
+			"invalid params",
+			`{"jsonrpc":"2.0","method":"add","params":[1],"id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"json: cannot unmarshal array into Go value of type jsonrpc_test.addRequest"},"id":1}`,
+		},
+		{
+			"invalid request",
+			`{"jsonrpc":"1.0","method":"add","id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid JSON-RPC 2.0 request"},"id":1}`,
+		},
+		{
+			"parse error",
+			`{"jsonrpc":`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"invalid JSON"},"id":null}`,
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			code, have := post(t, server.URL, testcase.body)
+			if want, have := http.StatusOK, code; want != have {
+				t.Errorf("status: want %d, have %d", want, have)
+			}
+			if want := testcase.want; want != have {
+				t.Errorf("want %s, have %s", want, have)
+			}
+		})
+	}
+}
+
+func TestServerBatch(t *testing.T) {
+	server, notified := newTestServer()
+	defer server.Close()
+
+	_, have := post(t, server.URL, `[
+		{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":1},"id":1},
+		{"jsonrpc":"2.0","method":"notify","params":{"a":7}},
+		{"jsonrpc":"2.0","method":"add","params":{"a":2,"b":2},"id":2},
+		1,
+		{"jsonrpc":"2.0","method":"add","params":{"a":3,"b":3},"id":3}
+	]`)
+	want := `[` +
+		`{"jsonrpc":"2.0","result":2,"id":1},` +
+		`{"jsonrpc":"2.0","result":4,"id":2},` +
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"json: cannot unmarshal number into Go value of type jsonrpc.Request"},"id":null},` +
+		`{"jsonrpc":"2.0","result":6,"id":3}` +
+		`]`
+	if want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := []int{7}, *notified; len(have) != 1 || want[0] != have[0] {
+		t.Errorf("notified: want %v, have %v", want, have)
+	}
+
+	_, have = post(t, server.URL, `[]`)
+	if want := `{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestServerNotifications(t *testing.T) {
+	server, notified := newTestServer()
+	defer server.Close()
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"notify","params":{"a":1}}`,
+		`[{"jsonrpc":"2.0","method":"notify","params":{"a":2}},{"jsonrpc":"2.0","method":"nope"}]`,
+	} {
+		code, have := post(t, server.URL, body)
+		if want, have := http.StatusNoContent, code; want != have {
+			t.Errorf("status: want %d, have %d", want, have)
+		}
+		if have != "" {
+			t.Errorf("want no body, have %s", have)
+		}
+	}
+	if want, have := 2, len(*notified); want != have {
+		t.Errorf("notified: want %d, have %d", want, have)
+	}
+}
+
+func TestServerBatchLimit(t *testing.T) {
+	server, _ := newTestServer(jsonrpc.ServerBatchLimit(1))
+	defer server.Close()
+
+	_, have := post(t, server.URL, `[{"jsonrpc":"2.0","method":"notify"},{"jsonrpc":"2.0","method":"notify"}]`)
+	if want := `{"jsonrpc":"2.0","error":{"code":-32600,"message":"batch of more than 1 requests"},"id":null}`; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestServerMethodNotAllowed(t *testing.T) {
+	server, _ := newTestServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusMethodNotAllowed, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
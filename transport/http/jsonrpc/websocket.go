@@ -0,0 +1,279 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	kitws "github.com/go-kit/kit/transport/websocket"
+)
+
+type contextKey int
+
+const (
+	// ContextKeyConn is populated in the context of the requests of
+	// WebSocketServers with the *Conn on which they're received, so that
+	// endpoints may subscribe it to a Registry, or notify it.
+	ContextKeyConn contextKey = iota
+)
+
+// ErrConnClosed is returned by the methods of closed connections.
+var ErrConnClosed = errors.New("connection closed")
+
+// WebSocketServer serves the methods of an EndpointCodecMap over WebSocket
+// connections, and implements http.Handler, upgrading requests. Each
+// message received is a single or batch request, served as by Server, and
+// the response, if there is one, is sent as a message. Messages are served
+// concurrently, so that responses may be sent out of order, to be matched
+// to their requests by ID, as the spec allows.
+//
+// The server may push notifications to clients on their connections, with
+// Conn.Notify, or Registry.Publish.
+type WebSocketServer struct {
+	server     Server
+	before     []httptransport.RequestFunc
+	handshake  func(*websocket.Config, *http.Request) error
+	connect    []ConnectFunc
+	disconnect []DisconnectFunc
+	logger     log.Logger
+}
+
+// NewWebSocketServer constructs a new WebSocket server, which implements
+// http.Handler and serves the methods of the EndpointCodecMap.
+func NewWebSocketServer(
+	ecm EndpointCodecMap,
+	options ...WebSocketServerOption,
+) *WebSocketServer {
+	s := &WebSocketServer{
+		server:    Server{ecm: ecm, logger: log.NewNopLogger()},
+		handshake: kitws.SameOrigin,
+		logger:    log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// WebSocketServerOption sets an optional parameter for WebSocket servers.
+type WebSocketServerOption func(*WebSocketServer)
+
+// WebSocketServerBefore functions are executed on the HTTP request which
+// opens each connection, before any message is received. The context is
+// shared by every request on the connection.
+func WebSocketServerBefore(before ...httptransport.RequestFunc) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.before = append(s.before, before...) }
+}
+
+// WebSocketServerHandshake sets the function which accepts or rejects the
+// opening handshake of each connection. By default, the SameOrigin
+// handshake of package transport/websocket is used.
+func WebSocketServerHandshake(f func(*websocket.Config, *http.Request) error) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.handshake = f }
+}
+
+// WebSocketServerConnect functions are executed when each connection is
+// opened, after the before functions, and before any message is received.
+// If one returns an error, the connection is closed.
+func WebSocketServerConnect(connect ...ConnectFunc) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.connect = append(s.connect, connect...) }
+}
+
+// WebSocketServerDisconnect functions are executed when each connection is
+// closed, once the requests in flight on it are served, and it's removed
+// from the Registries it was subscribed to.
+func WebSocketServerDisconnect(disconnect ...DisconnectFunc) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.disconnect = append(s.disconnect, disconnect...) }
+}
+
+// WebSocketServerErrorLogger is used to log non-terminal errors. By
+// default, no errors are logged.
+func WebSocketServerErrorLogger(logger log.Logger) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.logger, s.server.logger = logger, logger }
+}
+
+// WebSocketServerBatchLimit sets the maximum number of requests of
+// batches, as ServerBatchLimit. By default, there's no limit.
+func WebSocketServerBatchLimit(n int) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.server.batchLimit = n }
+}
+
+// ConnectFunc is executed when a connection is opened, and may put
+// information into the context of its requests, or reject it, by returning
+// an error.
+type ConnectFunc func(ctx context.Context, conn *Conn) (context.Context, error)
+
+// DisconnectFunc is executed when a connection is closed.
+type DisconnectFunc func(ctx context.Context, conn *Conn)
+
+// ServeHTTP implements http.Handler.
+func (s WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	websocket.Server{Handshake: s.handshake, Handler: s.serve}.ServeHTTP(w, r)
+}
+
+func (s WebSocketServer) serve(ws *websocket.Conn) {
+	conn := newConn(ws)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	ctx = context.WithValue(ctx, ContextKeyConn, conn)
+	for _, f := range s.before {
+		ctx = f(ctx, ws.Request())
+	}
+	for _, f := range s.connect {
+		var err error
+		if ctx, err = f(ctx, conn); err != nil {
+			s.logger.Log("during", "connect", "err", err)
+			return
+		}
+	}
+
+	// Closing the connection, from either side, cancels the context of the
+	// requests in flight, and the disconnect functions are executed once
+	// they're served, and the connection is closed.
+	var wg sync.WaitGroup
+	defer func() {
+		for _, f := range s.disconnect {
+			f(ctx, conn)
+		}
+	}()
+	defer conn.Close()
+	defer wg.Wait()
+	defer cancel()
+
+	go func() {
+		select {
+		case <-conn.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(ws, &message); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				s.logger.Log("during", "Receive", "err", err)
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, ok := s.server.serve(ctx, message)
+			if !ok {
+				return
+			}
+			if err := conn.write(response); err != nil && err != ErrConnClosed {
+				s.logger.Log("during", "Write", "err", err)
+			}
+		}()
+	}
+}
+
+// Conn is a WebSocket connection of a WebSocketServer, on which the server
+// may push notifications. It's safe for concurrent use.
+type Conn struct {
+	ws *websocket.Conn
+
+	writeMtx sync.Mutex
+
+	mtx     sync.Mutex
+	closed  bool
+	done    chan struct{}
+	onClose []func()
+}
+
+func newConn(ws *websocket.Conn) *Conn {
+	return &Conn{ws: ws, done: make(chan struct{})}
+}
+
+// Request returns the HTTP request which opened the connection.
+func (c *Conn) Request() *http.Request {
+	return c.ws.Request()
+}
+
+// Notify sends a notification, a request without an ID, to the client, of
+// the method, with the params, which are JSON encoded, or omitted, if nil.
+func (c *Conn) Notify(method string, params interface{}) error {
+	message, err := notification(method, params)
+	if err != nil {
+		return err
+	}
+	return c.write(message)
+}
+
+// Done returns a channel which is closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection, which cancels the context of its requests
+// in flight.
+func (c *Conn) Close() error {
+	c.mtx.Lock()
+	if c.closed {
+		c.mtx.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	onClose := c.onClose
+	c.onClose = nil
+	c.mtx.Unlock()
+
+	for _, f := range onClose {
+		f()
+	}
+	return c.ws.Close()
+}
+
+// afterClose registers f to be executed when the connection is closed, or
+// executes it, if it's closed already.
+func (c *Conn) afterClose(f func()) {
+	c.mtx.Lock()
+	if !c.closed {
+		c.onClose = append(c.onClose, f)
+		c.mtx.Unlock()
+		return
+	}
+	c.mtx.Unlock()
+	f()
+}
+
+// write sends the message, closing the connection if it fails.
+func (c *Conn) write(message []byte) error {
+	select {
+	case <-c.done:
+		return ErrConnClosed
+	default:
+	}
+
+	c.writeMtx.Lock()
+	_, err := c.ws.Write(message)
+	c.writeMtx.Unlock()
+	if err != nil {
+		c.Close()
+	}
+	return err
+}
+
+func notification(method string, params interface{}) ([]byte, error) {
+	req := Request{JSONRPC: Version, Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		req.Params = b
+	}
+	return json.Marshal(req)
+}
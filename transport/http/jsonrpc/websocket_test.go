@@ -0,0 +1,160 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+func dialWebSocket(t *testing.T, server *httptest.Server) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func receive(t *testing.T, ws *websocket.Conn) string {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var message string
+	if err := websocket.Message.Receive(ws, &message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestWebSocketServer(t *testing.T) {
+	registry := jsonrpc.NewRegistry()
+	var (
+		connected    = make(chan *jsonrpc.Conn, 1)
+		disconnected = make(chan *jsonrpc.Conn, 1)
+	)
+	ecm := jsonrpc.EndpointCodecMap{
+		"add": {
+			Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(addRequest)
+				return req.A + req.B, nil
+			},
+			Decode: decodeAddRequest,
+			Encode: encodeJSON,
+		},
+		"subscribe": {
+			Endpoint: func(ctx context.Context, request interface{}) (interface{}, error) {
+				conn, ok := ctx.Value(jsonrpc.ContextKeyConn).(*jsonrpc.Conn)
+				if !ok {
+					return nil, errors.New("no connection")
+				}
+				registry.Subscribe(request.(string), conn)
+				return true, nil
+			},
+			Decode: func(_ context.Context, params json.RawMessage) (interface{}, error) {
+				var topic string
+				err := json.Unmarshal(params, &topic)
+				return topic, err
+			},
+			Encode: encodeJSON,
+		},
+	}
+	handler := jsonrpc.NewWebSocketServer(
+		ecm,
+		jsonrpc.WebSocketServerConnect(func(ctx context.Context, conn *jsonrpc.Conn) (context.Context, error) {
+			connected <- conn
+			return ctx, nil
+		}),
+		jsonrpc.WebSocketServerDisconnect(func(_ context.Context, conn *jsonrpc.Conn) {
+			disconnected <- conn
+		}),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ws := dialWebSocket(t, server)
+	conn := <-connected
+
+	websocket.Message.Send(ws, `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`)
+	if want, have := `{"jsonrpc":"2.0","result":3,"id":1}`, receive(t, ws); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	websocket.Message.Send(ws, `[{"jsonrpc":"2.0","method":"subscribe","params":"news","id":2},{"jsonrpc":"2.0","method":"subscribe","params":"sports"}]`)
+	if want, have := `[{"jsonrpc":"2.0","result":true,"id":2}]`, receive(t, ws); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 1, registry.Subscribers("news"); want != have {
+		t.Errorf("subscribers: want %d, have %d", want, have)
+	}
+
+	n, err := registry.Publish("news", "headline", map[string]string{"title": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, n; want != have {
+		t.Errorf("published: want %d, have %d", want, have)
+	}
+	if want, have := `{"jsonrpc":"2.0","method":"headline","params":{"title":"hello"}}`, receive(t, ws); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	if err := conn.Notify("ping", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"jsonrpc":"2.0","method":"ping"}`, receive(t, ws); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	ws.Close()
+	select {
+	case have := <-disconnected:
+		if have != conn {
+			t.Errorf("disconnected another connection")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for disconnect")
+	}
+	for _, topic := range []string{"news", "sports"} {
+		if want, have := 0, registry.Subscribers(topic); want != have {
+			t.Errorf("%s subscribers: want %d, have %d", topic, want, have)
+		}
+	}
+	if want, have := jsonrpc.ErrConnClosed, conn.Notify("ping", nil); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestWebSocketServerConnectRejected(t *testing.T) {
+	handler := jsonrpc.NewWebSocketServer(
+		jsonrpc.EndpointCodecMap{},
+		jsonrpc.WebSocketServerConnect(func(ctx context.Context, _ *jsonrpc.Conn) (context.Context, error) {
+			return ctx, errors.New("go away")
+		}),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ws := dialWebSocket(t, server)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var message string
+	if err := websocket.Message.Receive(ws, &message); err == nil {
+		t.Errorf("want error, have message %s", message)
+	}
+}
+
+func TestWebSocketServerCrossOrigin(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewWebSocketServer(jsonrpc.EndpointCodecMap{}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	if _, err := websocket.Dial(url, "", "http://example.com"); err == nil {
+		t.Error("want error, have none")
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// ServerMetrics are the metrics recorded by InstrumentHandler. Any of them may
+// be nil, in which case it is skipped.
+//
+// InFlight is labeled with "route" and "method", the HTTP method. The others
+// are additionally labeled with "code", the status class of the response,
+// e.g. "2xx".
+type ServerMetrics struct {
+	Requests     metrics.Counter   // number of requests served
+	InFlight     metrics.Gauge     // number of requests being served
+	Duration     metrics.Histogram // seconds taken to serve each request
+	ResponseSize metrics.Histogram // bytes written in each response body
+}
+
+// InstrumentHandler wraps an http.Handler, typically a Server, recording the
+// passed metrics for every request it serves. The route is used as the value
+// of the "route" label, and should be a fixed, low-cardinality name or
+// pattern, e.g. "/profiles/{id}", rather than the request path.
+func InstrumentHandler(route string, m ServerMetrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.InFlight != nil {
+			inFlight := m.InFlight.With("route", route, "method", r.Method)
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+		}
+
+		iw := &interceptingWriter{w, http.StatusOK, 0}
+		defer func(begin time.Time) {
+			labelValues := []string{"route", route, "method", r.Method, "code", statusClass(iw.code)}
+			if m.Requests != nil {
+				m.Requests.With(labelValues...).Add(1)
+			}
+			if m.Duration != nil {
+				m.Duration.With(labelValues...).Observe(time.Since(begin).Seconds())
+			}
+			if m.ResponseSize != nil {
+				m.ResponseSize.With(labelValues...).Observe(float64(iw.written))
+			}
+		}(time.Now())
+
+		next.ServeHTTP(iw, r)
+	})
+}
+
+// statusClass returns the class of an HTTP status code, e.g. "4xx" for 404.
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return strconv.Itoa(code)
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,137 @@
+package http_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	var (
+		requests     = recCounter{&recorder{}}
+		inFlight     = recGauge{&recorder{}}
+		duration     = recHistogram{&recorder{}}
+		responseSize = recHistogram{&recorder{}}
+		m            = httptransport.ServerMetrics{
+			Requests:     requests,
+			InFlight:     inFlight,
+			Duration:     duration,
+			ResponseSize: responseSize,
+		}
+	)
+	handler := httptransport.InstrumentHandler("/things/{id}", m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := "route=/things/{id},method=GET 1", inFlight.Last(); want != have {
+			t.Errorf("in flight: want %s, have %s", want, have)
+		}
+		if r.URL.Path == "/things/missing" {
+			http.Error(w, "nope", http.StatusNotFound) // 5 bytes
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, path := range []string{"/things/1", "/things/2", "/things/missing"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	inc, dec := "route=/things/{id},method=GET 1", "route=/things/{id},method=GET -1"
+	if want, have := fmt.Sprintf("[%s %s %s %s %s %s]", dec, dec, dec, inc, inc, inc), inFlight.Sorted(); want != have {
+		t.Errorf("in flight: want %s, have %s", want, have)
+	}
+	ok, notFound := "route=/things/{id},method=GET,code=2xx", "route=/things/{id},method=GET,code=4xx"
+	if want, have := fmt.Sprintf("[%s 1 %s 1 %s 1]", ok, ok, notFound), requests.Sorted(); want != have {
+		t.Errorf("requests: want %s, have %s", want, have)
+	}
+	if want, have := fmt.Sprintf("[%s 5 %s 5 %s 5]", ok, ok, notFound), responseSize.Sorted(); want != have {
+		t.Errorf("response size: want %s, have %s", want, have)
+	}
+	if want, have := 3, duration.Len(); want != have {
+		t.Errorf("duration: want %d observations, have %d", want, have)
+	}
+}
+
+// recorder records each observation along with its label values.
+type recorder struct {
+	mtx sync.Mutex
+	obs []string
+	lvs []string
+	rec *recorder // root, shared by descendants
+}
+
+func (r *recorder) root() *recorder {
+	if r.rec == nil {
+		return r
+	}
+	return r.rec
+}
+
+func (r *recorder) with(labelValues []string) *recorder {
+	return &recorder{lvs: append(append([]string{}, r.lvs...), labelValues...), rec: r.root()}
+}
+
+func (r *recorder) record(value float64) {
+	var pairs []string
+	for i := 0; i+1 < len(r.lvs); i += 2 {
+		pairs = append(pairs, r.lvs[i]+"="+r.lvs[i+1])
+	}
+	root := r.root()
+	root.mtx.Lock()
+	defer root.mtx.Unlock()
+	root.obs = append(root.obs, fmt.Sprintf("%s %v", strings.Join(pairs, ","), value))
+}
+
+type recCounter struct{ *recorder }
+
+func (c recCounter) With(labelValues ...string) metrics.Counter {
+	return recCounter{c.with(labelValues)}
+}
+func (c recCounter) Add(delta float64) { c.record(delta) }
+
+type recGauge struct{ *recorder }
+
+func (g recGauge) With(labelValues ...string) metrics.Gauge { return recGauge{g.with(labelValues)} }
+func (g recGauge) Set(value float64)                        { g.record(value) }
+func (g recGauge) Add(delta float64)                        { g.record(delta) }
+
+type recHistogram struct{ *recorder }
+
+func (h recHistogram) With(labelValues ...string) metrics.Histogram {
+	return recHistogram{h.with(labelValues)}
+}
+func (h recHistogram) Observe(value float64) { h.record(value) }
+
+func (r *recorder) Last() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.obs) == 0 {
+		return ""
+	}
+	return r.obs[len(r.obs)-1]
+}
+
+func (r *recorder) Sorted() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	obs := append([]string{}, r.obs...)
+	sort.Strings(obs)
+	return fmt.Sprint(obs)
+}
+
+func (r *recorder) Len() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.obs)
+}
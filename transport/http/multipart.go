@@ -0,0 +1,182 @@
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// Defaults for a MultipartReader.
+const (
+	DefaultMultipartMaxSize  = 32 << 20 // bytes
+	DefaultMultipartMaxParts = 1000
+)
+
+// Errors returned by a MultipartReader, and its parts. They implement
+// StatusCoder, so DefaultErrorEncoder responds with 415 Unsupported Media
+// Type, 400 Bad Request, or 413 Request Entity Too Large, respectively.
+var (
+	ErrNotMultipart     error = statusError{"request isn't multipart/form-data", http.StatusUnsupportedMediaType}
+	ErrMultipartInvalid error = statusError{"invalid multipart body", http.StatusBadRequest}
+	ErrRequestTooLarge  error = statusError{"request body too large", http.StatusRequestEntityTooLarge}
+	ErrPartTooLarge     error = statusError{"multipart part too large", http.StatusRequestEntityTooLarge}
+	ErrTooManyParts     error = statusError{"too many multipart parts", http.StatusRequestEntityTooLarge}
+)
+
+// MultipartOption sets an optional parameter for a MultipartReader.
+type MultipartOption func(*MultipartReader)
+
+// MultipartMaxSize sets the maximum size of the request body, in bytes.
+// Reading beyond it fails with ErrRequestTooLarge. By default,
+// DefaultMultipartMaxSize is used.
+func MultipartMaxSize(n int64) MultipartOption {
+	return func(m *MultipartReader) { m.maxSize = n }
+}
+
+// MultipartMaxPartSize sets the maximum size of each part, in bytes. Reading
+// beyond it fails with ErrPartTooLarge. By default, parts are only limited by
+// the size of the request body.
+func MultipartMaxPartSize(n int64) MultipartOption {
+	return func(m *MultipartReader) { m.maxPartSize = n }
+}
+
+// MultipartMaxParts sets the maximum number of parts. Reading more fails with
+// ErrTooManyParts. By default, DefaultMultipartMaxParts is used.
+func MultipartMaxParts(n int) MultipartOption {
+	return func(m *MultipartReader) { m.maxParts = n }
+}
+
+// MultipartReader gives streaming access to the parts of a multipart/form-data
+// request body, with limits on their sizes. Unlike r.ParseMultipartForm, it
+// neither buffers files in memory nor spills them to temporary files, so
+// the endpoint may copy each file straight to its destination. As the body
+// is read during the endpoint, the reader should be returned in the request
+// from a DecodeRequestFunc, and consumed by the endpoint before it returns.
+//
+//	func decodeUploadRequest(_ context.Context, r *http.Request) (interface{}, error) {
+//	    parts, err := httptransport.NewMultipartReader(r, httptransport.MultipartMaxPartSize(10<<20))
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return uploadRequest{Parts: parts}, nil
+//	}
+type MultipartReader struct {
+	maxSize     int64
+	maxPartSize int64
+	maxParts    int
+
+	body  *limitedReader
+	r     *multipart.Reader
+	parts int
+}
+
+// NewMultipartReader returns a MultipartReader for the body of the request,
+// or ErrNotMultipart if it isn't multipart/form-data.
+func NewMultipartReader(r *http.Request, options ...MultipartOption) (*MultipartReader, error) {
+	m := &MultipartReader{
+		maxSize:  DefaultMultipartMaxSize,
+		maxParts: DefaultMultipartMaxParts,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, ErrNotMultipart
+	}
+	if r.ContentLength > m.maxSize {
+		return nil, ErrRequestTooLarge
+	}
+	m.body = &limitedReader{r: r.Body, n: m.maxSize, err: ErrRequestTooLarge}
+	m.r = multipart.NewReader(m.body, params["boundary"])
+	return m, nil
+}
+
+// NextPart returns the next part of the body, or io.EOF after the last one.
+// Reading a part invalidates those before it.
+func (m *MultipartReader) NextPart() (*Part, error) {
+	p, err := m.r.NextPart()
+	switch {
+	case m.body.n < 0:
+		return nil, ErrRequestTooLarge
+	case err == io.EOF:
+		return nil, err
+	case err != nil:
+		return nil, ErrMultipartInvalid
+	}
+	m.parts++
+	if m.maxParts > 0 && m.parts > m.maxParts {
+		return nil, ErrTooManyParts
+	}
+	part := &Part{
+		FormName: p.FormName(),
+		FileName: p.FileName(),
+		Header:   p.Header,
+		r:        p,
+	}
+	if m.maxPartSize > 0 {
+		part.r = &limitedReader{r: p, n: m.maxPartSize, err: ErrPartTooLarge}
+	}
+	return part, nil
+}
+
+// Part is a single part of a multipart/form-data body: a form field, or a
+// file. Read it to access its content.
+type Part struct {
+	FormName string // name of the form field
+	FileName string // name of the file, if the part is one
+	Header   textproto.MIMEHeader
+
+	r io.Reader
+}
+
+// IsFile returns true if the part is a file, rather than a plain form field.
+func (p *Part) IsFile() bool {
+	return p.FileName != ""
+}
+
+// ContentType returns the content type of the part, which defaults to
+// text/plain for form fields.
+func (p *Part) ContentType() string {
+	if ct := p.Header.Get("Content-Type"); ct != "" {
+		return ct
+	}
+	return "text/plain"
+}
+
+// Read implements io.Reader.
+func (p *Part) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// Value reads the whole part, e.g. a form field, as a string.
+func (p *Part) Value() (string, error) {
+	buf, err := ioutil.ReadAll(p)
+	return string(buf), err
+}
+
+// limitedReader reads from r, and returns err once more than n bytes are
+// read.
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if l.n < 0 {
+		return 0, l.err
+	}
+	if int64(len(b)) > l.n+1 {
+		b = b[:l.n+1]
+	}
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), l.err
+	}
+	return n, err
+}
@@ -0,0 +1,110 @@
+package http_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func multipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	for name, content := range files {
+		fw, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, content)
+	}
+	w.Close()
+	r, _ := http.NewRequest("POST", "/upload", &buf)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestMultipartReader(t *testing.T) {
+	r := multipartRequest(t, map[string]string{"title": "report"}, map[string]string{"data.csv": "a,b\n1,2\n"})
+	m, err := httptransport.NewMultipartReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := m.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IsFile() || p.FormName != "title" || p.ContentType() != "text/plain" {
+		t.Errorf("want the title field, have %+v", p)
+	}
+	if v, err := p.Value(); err != nil || v != "report" {
+		t.Errorf("want report, have %q, %v", v, err)
+	}
+
+	p, err = m.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsFile() || p.FileName != "data.csv" || p.ContentType() != "application/octet-stream" {
+		t.Errorf("want the data.csv file, have %+v", p)
+	}
+	if buf, err := ioutil.ReadAll(p); err != nil || string(buf) != "a,b\n1,2\n" {
+		t.Errorf("want the file content, have %q, %v", buf, err)
+	}
+
+	if _, err := m.NextPart(); err != io.EOF {
+		t.Errorf("want %v, have %v", io.EOF, err)
+	}
+}
+
+func TestMultipartReaderLimits(t *testing.T) {
+	big := strings.Repeat("x", 1000)
+
+	m, _ := httptransport.NewMultipartReader(multipartRequest(t, nil, map[string]string{"big": big}), httptransport.MultipartMaxPartSize(100))
+	p, _ := m.NextPart()
+	if _, err := ioutil.ReadAll(p); err != httptransport.ErrPartTooLarge {
+		t.Errorf("part: want %v, have %v", httptransport.ErrPartTooLarge, err)
+	}
+
+	r := multipartRequest(t, nil, map[string]string{"big": big})
+	r.ContentLength = -1
+	m, _ = httptransport.NewMultipartReader(r, httptransport.MultipartMaxSize(500))
+	p, err := m.NextPart()
+	if err == nil {
+		_, err = ioutil.ReadAll(p)
+	}
+	if err != httptransport.ErrRequestTooLarge {
+		t.Errorf("body: want %v, have %v", httptransport.ErrRequestTooLarge, err)
+	}
+
+	r = multipartRequest(t, nil, map[string]string{"big": big})
+	if _, err := httptransport.NewMultipartReader(r, httptransport.MultipartMaxSize(500)); err != httptransport.ErrRequestTooLarge {
+		t.Errorf("content length: want %v, have %v", httptransport.ErrRequestTooLarge, err)
+	}
+
+	m, _ = httptransport.NewMultipartReader(multipartRequest(t, map[string]string{"a": "1", "b": "2"}, nil), httptransport.MultipartMaxParts(1))
+	m.NextPart()
+	if _, err := m.NextPart(); err != httptransport.ErrTooManyParts {
+		t.Errorf("parts: want %v, have %v", httptransport.ErrTooManyParts, err)
+	}
+}
+
+func TestMultipartReaderNotMultipart(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/upload", strings.NewReader("{}"))
+	r.Header.Set("Content-Type", "application/json")
+	_, err := httptransport.NewMultipartReader(r)
+	if err != httptransport.ErrNotMultipart {
+		t.Fatalf("want %v, have %v", httptransport.ErrNotMultipart, err)
+	}
+	if want, have := http.StatusUnsupportedMediaType, err.(httptransport.StatusCoder).StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,6 @@
+// Package openapi generates OpenAPI 3 documents describing kit HTTP servers.
+// Operations are annotated with their request and response types where
+// they're registered, so the document can't drift from the endpoints it
+// describes. Schemas are derived from the types by reflection, honoring json
+// tags, and the validate tags of endpoint.ValidateStruct.
+package openapi
@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Operation describes an endpoint served at a method and path.
+type Operation struct {
+	ID          string   // unique identifier, e.g. "getProfile"
+	Summary     string   // short summary
+	Description string   // longer description
+	Tags        []string // for grouping operations
+
+	// Request and Response are values of the request and response types,
+	// e.g. getProfileRequest{}, from which their schemas are derived. Either
+	// may be nil, if the operation has no body. Requests of GET, HEAD, and
+	// DELETE operations are never described as bodies.
+	Request  interface{}
+	Response interface{}
+
+	// Errors describes the error responses, by status code.
+	Errors map[int]string
+}
+
+// Spec accumulates operations, and generates an OpenAPI 3 document
+// describing them. It's safe for concurrent use.
+type Spec struct {
+	title   string
+	version string
+
+	mtx        sync.Mutex
+	operations []operation
+}
+
+type operation struct {
+	method, path string
+	Operation
+}
+
+// NewSpec returns an empty Spec for the API with the title and version.
+func NewSpec(title, version string) *Spec {
+	return &Spec{title: title, version: version}
+}
+
+// Add describes the operation served at the method and path. The path may
+// contain variables, written {name} or {name...}, as for httptransport.Router.
+func (s *Spec) Add(method, path string, op Operation) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.operations = append(s.operations, operation{method, path, op})
+}
+
+// Handle registers the endpoint with the router, as Router.Handle does, and
+// adds the operation describing it to the spec.
+func (s *Spec) Handle(
+	rt *httptransport.Router,
+	method, path string,
+	e endpoint.Endpoint,
+	dec httptransport.DecodeRequestFunc,
+	enc httptransport.EncodeResponseFunc,
+	op Operation,
+	options ...httptransport.RouteOption,
+) {
+	rt.Handle(method, path, e, dec, enc, options...)
+	s.Add(method, path, op)
+}
+
+// MarshalJSON implements json.Marshaler, generating the OpenAPI document.
+func (s *Spec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Document())
+}
+
+// Document returns the OpenAPI document, as a tree of maps and slices which
+// may be further edited, e.g. to add servers or security schemes, before
+// it's marshaled.
+func (s *Spec) Document() map[string]interface{} {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	g := newGenerator()
+	paths := map[string]interface{}{}
+	for _, op := range s.operations {
+		path, params := convertPath(op.path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = g.operation(op, params)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   s.title,
+			"version": s.version,
+		},
+		"paths": paths,
+	}
+	if len(g.schemas) > 0 {
+		doc["components"] = map[string]interface{}{"schemas": g.schemas}
+	}
+	return doc
+}
+
+// Handler returns a handler serving the document as JSON.
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, err := s.MarshalJSON()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(buf)
+	})
+}
+
+func (g *generator) operation(op operation, params []string) map[string]interface{} {
+	o := map[string]interface{}{}
+	for k, v := range map[string]string{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"description": op.Description,
+	} {
+		if v != "" {
+			o[k] = v
+		}
+	}
+	if len(op.Tags) > 0 {
+		o["tags"] = op.Tags
+	}
+
+	if len(params) > 0 {
+		parameters := make([]interface{}, len(params))
+		for i, name := range params {
+			parameters[i] = map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			}
+		}
+		o["parameters"] = parameters
+	}
+
+	switch op.method {
+	case "GET", "HEAD", "DELETE":
+	default:
+		if op.Request != nil {
+			o["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(g.schema(op.Request)),
+			}
+		}
+	}
+
+	response := map[string]interface{}{"description": http.StatusText(http.StatusOK)}
+	if op.Response != nil {
+		response["content"] = jsonContent(g.schema(op.Response))
+	}
+	responses := map[string]interface{}{"200": response}
+	codes := make([]int, 0, len(op.Errors))
+	for code := range op.Errors {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		description := op.Errors[code]
+		if description == "" {
+			description = http.StatusText(code)
+		}
+		responses[strconv.Itoa(code)] = map[string]interface{}{"description": description}
+	}
+	o["responses"] = responses
+	return o
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// convertPath converts a Router pattern to an OpenAPI path, returning the
+// names of its variables.
+func convertPath(pattern string) (string, []string) {
+	var params []string
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			name := strings.TrimSuffix(seg[1:len(seg)-1], "...")
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,111 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/kit/transport/http/openapi"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type profile struct {
+	ID        string    `json:"id" validate:"required,max=64"`
+	Name      string    `json:"name,omitempty"`
+	Age       int       `json:"age" validate:"min=0"`
+	Addresses []address `json:"addresses,omitempty"`
+	Friends   []profile `json:"friends,omitempty"`
+	Created   time.Time `json:"created"`
+	secret    string
+	Ignored   string `json:"-"`
+}
+
+type postProfileRequest struct {
+	Profile profile `json:"profile" validate:"required"`
+}
+
+type postProfileResponse struct {
+	Err string `json:"err,omitempty"`
+}
+
+func TestSpec(t *testing.T) {
+	var (
+		spec = openapi.NewSpec("Profiles", "1.0")
+		rt   = httptransport.NewRouter()
+		dec  = func(context.Context, *http.Request) (interface{}, error) { return nil, nil }
+	)
+	spec.Handle(rt, "POST", "/profiles", endpoint.Nop, dec, httptransport.EncodeJSONResponse, openapi.Operation{
+		ID:       "postProfile",
+		Tags:     []string{"profiles"},
+		Request:  postProfileRequest{},
+		Response: postProfileResponse{},
+		Errors:   map[int]string{400: "", 409: "Profile exists"},
+	})
+	spec.Handle(rt, "GET", "/profiles/{id}", endpoint.Nop, dec, httptransport.EncodeJSONResponse, openapi.Operation{
+		ID:       "getProfile",
+		Request:  struct{ ID string }{},
+		Response: profile{},
+	})
+
+	rec := httptest.NewRecorder()
+	spec.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path ...string) interface{} {
+		var v interface{} = doc
+		for _, p := range path {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				t.Fatalf("%v: not an object", path)
+			}
+			v = m[p]
+		}
+		return v
+	}
+	for _, tc := range []struct {
+		path []string
+		want interface{}
+	}{
+		{[]string{"openapi"}, "3.0.3"},
+		{[]string{"info", "title"}, "Profiles"},
+		{[]string{"paths", "/profiles", "post", "operationId"}, "postProfile"},
+		{[]string{"paths", "/profiles", "post", "requestBody", "content", "application/json", "schema", "$ref"}, "#/components/schemas/postProfileRequest"},
+		{[]string{"paths", "/profiles", "post", "responses", "400", "description"}, "Bad Request"},
+		{[]string{"paths", "/profiles", "post", "responses", "409", "description"}, "Profile exists"},
+		{[]string{"paths", "/profiles/{id}", "get", "requestBody"}, nil},
+		{[]string{"paths", "/profiles/{id}", "get", "parameters"}, []interface{}{map[string]interface{}{
+			"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		}}},
+		{[]string{"components", "schemas", "postProfileRequest", "required"}, []interface{}{"profile"}},
+		{[]string{"components", "schemas", "profile", "required"}, []interface{}{"id"}},
+		{[]string{"components", "schemas", "profile", "properties", "id"}, map[string]interface{}{"type": "string", "maxLength": 64.0}},
+		{[]string{"components", "schemas", "profile", "properties", "age"}, map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0.0}},
+		{[]string{"components", "schemas", "profile", "properties", "created"}, map[string]interface{}{"type": "string", "format": "date-time"}},
+		{[]string{"components", "schemas", "profile", "properties", "friends", "items", "$ref"}, "#/components/schemas/profile"},
+		{[]string{"components", "schemas", "profile", "properties", "addresses", "items", "$ref"}, "#/components/schemas/address"},
+		{[]string{"components", "schemas", "profile", "properties", "secret"}, nil},
+		{[]string{"components", "schemas", "profile", "properties", "Ignored"}, nil},
+	} {
+		if have := get(tc.path...); !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("%v: want %v, have %v", tc.path, tc.want, have)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest("GET", "/profiles/1", nil))
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Errorf("route: want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// generator derives schemas from types, collecting those of named structs as
+// components, so that recursive types terminate.
+type generator struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: map[string]interface{}{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+func (g *generator) schema(v interface{}) map[string]interface{} {
+	return g.typeSchema(reflect.TypeOf(v))
+}
+
+func (g *generator) typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + g.component(t)}
+	}
+	return map[string]interface{}{}
+}
+
+// component returns the name of the component for the named struct type,
+// generating its schema the first time it's seen.
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+	g.names[t] = name
+	g.schemas[name] = map[string]interface{}{} // placeholder, for recursion
+	g.schemas[name] = g.structSchema(t)
+	return name
+}
+
+func (g *generator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	g.addFields(t, properties, &required)
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (g *generator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && tag[0] == "" && ft.Kind() == reflect.Struct {
+			g.addFields(ft, properties, required)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag[0] != "" {
+			name = tag[0]
+		}
+
+		schema := g.typeSchema(f.Type)
+		for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "required" {
+				*required = append(*required, name)
+				continue
+			}
+			if i := strings.Index(rule, "="); i > 0 {
+				applyLimit(schema, rule[:i], rule[i+1:])
+			}
+		}
+		properties[name] = schema
+	}
+}
+
+// applyLimit adds a min or max rule of endpoint.ValidateStruct to the schema.
+func applyLimit(schema map[string]interface{}, rule, arg string) {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+	var key string
+	switch schema["type"] {
+	case "integer", "number":
+		key = map[string]string{"min": "minimum", "max": "maximum"}[rule]
+	case "string":
+		key = map[string]string{"min": "minLength", "max": "maxLength"}[rule]
+	case "array":
+		key = map[string]string{"min": "minItems", "max": "maxItems"}[rule]
+	case "object":
+		key = map[string]string{"min": "minProperties", "max": "maxProperties"}[rule]
+	}
+	if key != "" {
+		schema[key] = n
+	}
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Problem is an RFC 7807 problem details object, which describes an error in
+// a machine-readable way. See ProblemErrorEncoder.
+type Problem struct {
+	Type     string // URI identifying the problem type, or "about:blank"
+	Title    string // short summary of the problem type
+	Status   int    // HTTP status code
+	Detail   string // explanation of this occurrence of the problem
+	Instance string // URI identifying this occurrence of the problem
+
+	// Extensions are additional members of the problem object.
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler, encoding the extensions as members
+// of the object.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for k, v := range map[string]string{
+		"type":     p.Type,
+		"title":    p.Title,
+		"detail":   p.Detail,
+		"instance": p.Instance,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	return json.Marshal(m)
+}
+
+// Problemer may be implemented by errors which describe themselves as
+// problems. It takes precedence over a ProblemRegistry.
+type Problemer interface {
+	Problem() Problem
+}
+
+// ProblemRegistry maps errors to problem types, so that errors of the
+// business domain needn't depend on the transport. It's safe for concurrent
+// use, though it's typically populated at startup.
+type ProblemRegistry struct {
+	mtx   sync.RWMutex
+	rules []problemRule
+}
+
+type problemRule struct {
+	match   func(error) bool
+	problem Problem
+}
+
+// NewProblemRegistry returns an empty ProblemRegistry.
+func NewProblemRegistry() *ProblemRegistry {
+	return &ProblemRegistry{}
+}
+
+// Register maps the error, typically a sentinel like sql.ErrNoRows, and
+// errors wrapping it, to the problem. Zero fields of the problem are filled
+// as described by ProblemErrorEncoder.
+func (r *ProblemRegistry) Register(target error, p Problem) {
+	r.RegisterFunc(func(err error) bool {
+		for err != nil {
+			if err == target {
+				return true
+			}
+			u, ok := err.(interface {
+				Unwrap() error
+			})
+			if !ok {
+				return false
+			}
+			err = u.Unwrap()
+		}
+		return false
+	}, p)
+}
+
+// RegisterFunc maps errors for which match returns true, e.g. errors of a
+// type, to the problem. Rules are tried in the order they're registered.
+func (r *ProblemRegistry) RegisterFunc(match func(error) bool, p Problem) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.rules = append(r.rules, problemRule{match, p})
+}
+
+// Problem returns the problem describing the error. Zero fields are filled as
+// described by ProblemErrorEncoder, except Instance. A nil registry describes
+// errors by their own Problem method, if any, or else by their status code.
+func (r *ProblemRegistry) Problem(err error) Problem {
+	var p Problem
+	if pe, ok := err.(Problemer); ok {
+		p = pe.Problem()
+	} else if r != nil {
+		r.mtx.RLock()
+		for _, rule := range r.rules {
+			if rule.match(err) {
+				p = rule.problem
+				break
+			}
+		}
+		r.mtx.RUnlock()
+	}
+
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+		if sc, ok := err.(StatusCoder); ok {
+			p.Status = sc.StatusCode()
+		}
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		p.Detail = err.Error()
+	}
+	if ve, ok := err.(endpoint.ValidationError); ok && len(ve.Fields) > 0 {
+		ext := map[string]interface{}{"fields": ve.Fields}
+		for k, v := range p.Extensions {
+			ext[k] = v
+		}
+		p.Extensions = ext
+	}
+	return p
+}
+
+// ProblemErrorEncoder returns an ErrorEncoder which writes errors as RFC 7807
+// problem details, with content type application/problem+json. Errors are
+// described by their Problem method, if they implement Problemer, or else by
+// the first matching rule of the registry, which may be nil. Zero fields are
+// filled in: the type is "about:blank"; the status is the error's StatusCode,
+// if it implements StatusCoder, or 500; the title is the text of the status;
+// the detail is the error's message; and the instance is the request path, if
+// the context was populated by PopulateRequestContext. Fields of an
+// endpoint.ValidationError are included in a "fields" member. If the error
+// hints when to retry the request, as for DefaultErrorEncoder, the
+// Retry-After header is set, and if it implements Headerer, the provided
+// headers are applied to the response.
+func ProblemErrorEncoder(registry *ProblemRegistry) ErrorEncoder {
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		p := registry.Problem(err)
+		if p.Instance == "" {
+			p.Instance, _ = ctx.Value(ContextKeyRequestPath).(string)
+		}
+		body, marshalErr := json.Marshal(p)
+		if marshalErr != nil {
+			body, _ = json.Marshal(Problem{Type: p.Type, Title: p.Title, Status: p.Status, Detail: p.Detail, Instance: p.Instance})
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		setRetryAfter(w, err)
+		if headerer, ok := err.(Headerer); ok {
+			for k := range headerer.Headers() {
+				w.Header().Set(k, headerer.Headers().Get(k))
+			}
+		}
+		w.WriteHeader(p.Status)
+		w.Write(body)
+	}
+}
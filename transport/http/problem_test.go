@@ -0,0 +1,90 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+var errOutOfStock = errors.New("out of stock")
+
+type quotaError struct{ limit int }
+
+func (e quotaError) Error() string { return "quota exceeded" }
+
+type selfDescribing struct{}
+
+func (selfDescribing) Error() string { return "self" }
+func (selfDescribing) Problem() httptransport.Problem {
+	return httptransport.Problem{Type: "https://example.com/self", Status: 418}
+}
+
+func TestProblemErrorEncoder(t *testing.T) {
+	registry := httptransport.NewProblemRegistry()
+	registry.Register(errOutOfStock, httptransport.Problem{
+		Type:   "https://example.com/out-of-stock",
+		Title:  "Out of stock",
+		Status: 409,
+	})
+	registry.RegisterFunc(func(err error) bool { _, ok := err.(quotaError); return ok }, httptransport.Problem{
+		Type:       "https://example.com/quota",
+		Status:     429,
+		Extensions: map[string]interface{}{"retryable": true},
+	})
+	encode := httptransport.ProblemErrorEncoder(registry)
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want map[string]interface{}
+	}{
+		{"sentinel", errOutOfStock, map[string]interface{}{
+			"type": "https://example.com/out-of-stock", "title": "Out of stock", "status": 409.0,
+			"detail": "out of stock", "instance": "/orders",
+		}},
+		{"wrapped", endpoint.FailedPrecondition(errOutOfStock), map[string]interface{}{
+			"type": "https://example.com/out-of-stock", "title": "Out of stock", "status": 409.0,
+			"detail": "out of stock", "instance": "/orders",
+		}},
+		{"typed", quotaError{10}, map[string]interface{}{
+			"type": "https://example.com/quota", "title": "Too Many Requests", "status": 429.0,
+			"detail": "quota exceeded", "instance": "/orders", "retryable": true,
+		}},
+		{"self", selfDescribing{}, map[string]interface{}{
+			"type": "https://example.com/self", "title": "I'm a teapot", "status": 418.0,
+			"detail": "self", "instance": "/orders",
+		}},
+		{"kind", endpoint.NotFound(errors.New("no such order")), map[string]interface{}{
+			"type": "about:blank", "title": "Not Found", "status": 404.0,
+			"detail": "no such order", "instance": "/orders",
+		}},
+		{"validation", endpoint.ValidationError{Fields: []endpoint.FieldError{{Field: "id", Message: "is required"}}}, map[string]interface{}{
+			"type": "about:blank", "title": "Bad Request", "status": 400.0,
+			"detail": "invalid request: id: is required", "instance": "/orders",
+			"fields": []interface{}{map[string]interface{}{"field": "id", "message": "is required"}},
+		}},
+	} {
+		ctx := httptransport.PopulateRequestContext(context.Background(), httptest.NewRequest("POST", "/orders", nil))
+		rec := httptest.NewRecorder()
+		encode(ctx, tc.err, rec)
+		if want, have := "application/problem+json", rec.Header().Get("Content-Type"); want != have {
+			t.Errorf("%s: Content-Type: want %q, have %q", tc.name, want, have)
+		}
+		if want, have := int(tc.want["status"].(float64)), rec.Code; want != have {
+			t.Errorf("%s: status: want %d, have %d", tc.name, want, have)
+		}
+		var have map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &have); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, have)
+		}
+	}
+}
@@ -61,6 +61,8 @@ func PopulateRequestContext(ctx context.Context, r *http.Request) context.Contex
 		ContextKeyRequestUserAgent:       r.Header.Get("User-Agent"),
 		ContextKeyRequestXRequestID:      r.Header.Get("X-Request-Id"),
 		ContextKeyRequestAccept:          r.Header.Get("Accept"),
+		ContextKeyRequestIfNoneMatch:     r.Header.Get("If-None-Match"),
+		ContextKeyRequestIfModifiedSince: r.Header.Get("If-Modified-Since"),
 	} {
 		ctx = context.WithValue(ctx, k, v)
 	}
@@ -130,4 +132,21 @@ const (
 	// ContextKeyResponseSize is populated in the context whenever a
 	// ServerFinalizerFunc is specified. Its value is of type int64.
 	ContextKeyResponseSize
+
+	// ContextKeyRequestPathVars is populated in the context by Router. Its
+	// value is of type map[string]string, holding the path variables of the
+	// matched route.
+	ContextKeyRequestPathVars
+
+	// ContextKeyRequestRoute is populated in the context by Router. Its value
+	// is the pattern of the matched route, e.g. "/users/{id}".
+	ContextKeyRequestRoute
+
+	// ContextKeyRequestIfNoneMatch is populated in the context by
+	// PopulateRequestContext. Its value is r.Header.Get("If-None-Match").
+	ContextKeyRequestIfNoneMatch
+
+	// ContextKeyRequestIfModifiedSince is populated in the context by
+	// PopulateRequestContext. Its value is r.Header.Get("If-Modified-Since").
+	ContextKeyRequestIfModifiedSince
 )
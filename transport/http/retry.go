@@ -0,0 +1,218 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ClientRetryOption sets an optional parameter for ClientRetry.
+type ClientRetryOption func(*clientRetry)
+
+// ClientRetryAttempts sets the maximum number of times a request is sent,
+// including the first. By default, endpoint.DefaultRetryAttempts is used.
+func ClientRetryAttempts(n int) ClientRetryOption {
+	return func(r *clientRetry) { r.attempts = n }
+}
+
+// ClientRetryBackoff sets the delay between attempts, as for
+// endpoint.RetryBackoff. By default, endpoint.DefaultRetryBaseDelay and
+// endpoint.DefaultRetryMaxDelay are used.
+func ClientRetryBackoff(base, max time.Duration) ClientRetryOption {
+	return func(r *clientRetry) { r.base, r.max = base, max }
+}
+
+// ClientRetryMethods sets the methods whose requests may be retried. Requests
+// with an Idempotency-Key header may always be retried. By default, the
+// idempotent methods GET, HEAD, OPTIONS, TRACE, PUT, and DELETE are.
+func ClientRetryMethods(methods ...string) ClientRetryOption {
+	return func(r *clientRetry) {
+		r.methods = map[string]bool{}
+		for _, m := range methods {
+			r.methods[m] = true
+		}
+	}
+}
+
+// ClientRetryIf sets the predicate which decides whether the outcome of an
+// attempt is retryable. Exactly one of resp and err is non-nil. By default,
+// RetryableResponse is used.
+func ClientRetryIf(retryable func(resp *http.Response, err error) bool) ClientRetryOption {
+	return func(r *clientRetry) { r.retryable = retryable }
+}
+
+// ClientRetryAttemptTimeout bounds the time each attempt may take, including
+// reading the response body of the last. By default, attempts are only bounded
+// by the request context.
+func ClientRetryAttemptTimeout(d time.Duration) ClientRetryOption {
+	return func(r *clientRetry) { r.timeout = d }
+}
+
+// ClientRetryMaxRetryAfter sets the longest Retry-After the client will wait
+// for. If a response asks for longer, it's returned without retrying. By
+// default, endpoint.DefaultRetryMaxDelay is used.
+func ClientRetryMaxRetryAfter(d time.Duration) ClientRetryOption {
+	return func(r *clientRetry) { r.maxRetryAfter = d }
+}
+
+// ClientRetryBudget limits retries by the budget, which may be shared by many
+// clients, so that retries don't multiply the load on a struggling service.
+// By default, retries are only limited per request by attempts.
+func ClientRetryBudget(b *endpoint.RetryBudget) ClientRetryOption {
+	return func(r *clientRetry) { r.budget = b }
+}
+
+// ClientRetry resends requests whose attempts fail retryably, with
+// exponential backoff and full jitter between attempts. Only requests with
+// retryable methods are retried. If a response carries a Retry-After header,
+// in seconds or as an HTTP date, the client waits that long instead. Request
+// bodies are resent with the request's GetBody, if it's set, as by
+// EncodeStreamRequest, and are otherwise buffered, so that they may be
+// resent. The response of the last attempt is decoded; if the request context
+// is done while waiting to retry, its error is returned instead.
+func ClientRetry(options ...ClientRetryOption) ClientOption {
+	r := &clientRetry{
+		attempts:      endpoint.DefaultRetryAttempts,
+		base:          endpoint.DefaultRetryBaseDelay,
+		max:           endpoint.DefaultRetryMaxDelay,
+		maxRetryAfter: endpoint.DefaultRetryMaxDelay,
+		retryable:     RetryableResponse,
+		jitter:        rand.Int63n,
+	}
+	ClientRetryMethods("GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE")(r)
+	for _, option := range options {
+		option(r)
+	}
+	return func(c *Client) { c.retry = r }
+}
+
+// RetryableResponse returns true for transport errors, and for responses with
+// status 429 Too Many Requests, 502 Bad Gateway, 503 Service Unavailable, or
+// 504 Gateway Timeout.
+func RetryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+type clientRetry struct {
+	attempts      int
+	base, max     time.Duration
+	methods       map[string]bool
+	retryable     func(*http.Response, error) bool
+	timeout       time.Duration
+	maxRetryAfter time.Duration
+	budget        *endpoint.RetryBudget
+	jitter        func(n int64) int64
+}
+
+// do sends the request, retrying as configured. The returned function must be
+// called once the response has been consumed.
+func (r *clientRetry) do(ctx context.Context, client Doer, req *http.Request) (*http.Response, context.CancelFunc, error) {
+	retryable := r.methods[req.Method] || req.Header.Get("Idempotency-Key") != ""
+	getBody := req.GetBody
+	if _, ok := req.Body.(unreplayableBody); ok {
+		retryable = false
+	}
+	if retryable && req.Body != nil && getBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		getBody = func() (io.ReadCloser, error) { return ioutil.NopCloser(bytes.NewReader(body)), nil }
+		req.ContentLength = int64(len(body))
+		req.Body, _ = getBody()
+	}
+	if retryable && r.budget != nil {
+		r.budget.Deposit()
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && getBody != nil {
+			body, err := getBody()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			req.Body = body
+		}
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if r.timeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, r.timeout)
+		}
+		resp, err := client.Do(req.WithContext(actx))
+		if !retryable || attempt >= r.attempts || ctx.Err() != nil || !r.retryable(resp, err) {
+			return resp, cancel, err
+		}
+
+		wait := r.delay(attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if d > r.maxRetryAfter {
+					return resp, cancel, err
+				}
+				wait = d
+			}
+		}
+		if r.budget != nil && !r.budget.Withdraw() {
+			return resp, cancel, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096)) // allow connection reuse
+			resp.Body.Close()
+		}
+		cancel()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, func() {}, ctx.Err()
+		}
+	}
+}
+
+// delay returns the time to wait after the given attempt.
+func (r *clientRetry) delay(attempt int) time.Duration {
+	d := r.max
+	if shift := uint(attempt - 1); shift < 63 && r.base < r.max>>shift {
+		d = r.base << shift
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(r.jitter(int64(d) + 1))
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,118 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestClientRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		method    string
+		key       string
+		wantCalls int32
+		wantCode  int
+	}{
+		{"PUT", "", 3, http.StatusOK},
+		{"POST", "", 1, http.StatusServiceUnavailable},
+		{"POST", "abc", 3, http.StatusOK},
+	} {
+		atomic.StoreInt32(&calls, 0)
+		u, _ := url.Parse(server.URL)
+		client := httptransport.NewClient(
+			tc.method, u,
+			func(_ context.Context, r *http.Request, request interface{}) error {
+				if tc.key != "" {
+					r.Header.Set("Idempotency-Key", tc.key)
+				}
+				r.Body = ioutil.NopCloser(strings.NewReader(request.(string)))
+				return nil
+			},
+			func(_ context.Context, resp *http.Response) (interface{}, error) {
+				body, err := ioutil.ReadAll(resp.Body)
+				return [2]interface{}{resp.StatusCode, string(body)}, err
+			},
+			httptransport.ClientRetry(httptransport.ClientRetryBackoff(time.Millisecond, time.Millisecond)),
+		)
+		response, err := client.Endpoint()(context.Background(), "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := response.([2]interface{})
+		if want, have := tc.wantCalls, atomic.LoadInt32(&calls); want != have {
+			t.Errorf("%s %q: calls: want %d, have %d", tc.method, tc.key, want, have)
+		}
+		if want, have := tc.wantCode, result[0].(int); want != have {
+			t.Errorf("%s %q: code: want %d, have %d", tc.method, tc.key, want, have)
+		}
+		if tc.wantCode == http.StatusOK {
+			if want, have := "hello", result[1].(string); want != have {
+				t.Errorf("%s %q: body: want %q, have %q", tc.method, tc.key, want, have)
+			}
+		}
+	}
+}
+
+func TestClientRetryLimits(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if r.URL.Path == "/later" {
+			w.Header().Set("Retry-After", "3600")
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	newClient := func(path string, options ...httptransport.ClientRetryOption) endpoint.Endpoint {
+		u, _ := url.Parse(server.URL + path)
+		options = append(options, httptransport.ClientRetryBackoff(time.Millisecond, time.Millisecond))
+		return httptransport.NewClient(
+			"GET", u,
+			func(context.Context, *http.Request, interface{}) error { return nil },
+			func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+			httptransport.ClientRetry(options...),
+		).Endpoint()
+	}
+
+	for _, tc := range []struct {
+		name      string
+		e         endpoint.Endpoint
+		wantCalls int32
+	}{
+		{"attempts", newClient("/", httptransport.ClientRetryAttempts(4)), 4},
+		{"retry-after too long", newClient("/later"), 1},
+		{"budget", newClient("/", httptransport.ClientRetryBudget(endpoint.NewRetryBudget(0, 1))), 2},
+		{"attempt timeout", newClient("/slow", httptransport.ClientRetryAttemptTimeout(10*time.Millisecond)), 3},
+	} {
+		atomic.StoreInt32(&calls, 0)
+		tc.e(context.Background(), nil)
+		if want, have := tc.wantCalls, atomic.LoadInt32(&calls); want != have {
+			t.Errorf("%s: calls: want %d, have %d", tc.name, want, have)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// RouterOption sets an optional parameter for routers.
+type RouterOption func(*Router)
+
+// RouterServerOptions sets ServerOptions applied to every route, before the
+// route's own.
+func RouterServerOptions(options ...ServerOption) RouterOption {
+	return func(rt *Router) { rt.options = append(rt.options, options...) }
+}
+
+// RouterMiddleware sets middlewares wrapping the endpoint of every route,
+// outside the route's own. The first middleware is outermost.
+func RouterMiddleware(mw ...endpoint.Middleware) RouterOption {
+	return func(rt *Router) { rt.middleware = append(rt.middleware, mw...) }
+}
+
+// RouterNotFound sets the handler for requests matching no route. By default,
+// http.NotFoundHandler is used.
+func RouterNotFound(h http.Handler) RouterOption {
+	return func(rt *Router) { rt.notFound = h }
+}
+
+// RouteOption sets an optional parameter for a single route.
+type RouteOption func(*routeConfig)
+
+// RouteServerOptions sets ServerOptions for the route.
+func RouteServerOptions(options ...ServerOption) RouteOption {
+	return func(c *routeConfig) { c.options = append(c.options, options...) }
+}
+
+// RouteMiddleware sets middlewares wrapping the endpoint of the route. The
+// first middleware is outermost.
+func RouteMiddleware(mw ...endpoint.Middleware) RouteOption {
+	return func(c *routeConfig) { c.middleware = append(c.middleware, mw...) }
+}
+
+type routeConfig struct {
+	options    []ServerOption
+	middleware []endpoint.Middleware
+}
+
+// Router is an http.Handler which dispatches requests to Servers by method and
+// path. Patterns are paths whose segments may be variables, written {name},
+// which match any one segment. If the last segment is written {name...}, it
+// matches the rest of the path, including slashes. Where several routes
+// match, the one with the most literal segments wins.
+//
+// The variables of the matched route are populated in the request context
+// under ContextKeyRequestPathVars, and may be read with PathVar. The pattern is
+// populated under ContextKeyRequestRoute, so it may be used as a low
+// cardinality label, e.g. with InstrumentHandler.
+//
+// Requests whose path matches a route but not its method receive 405 Method
+// Not Allowed, with the Allow header listing the methods routed.
+type Router struct {
+	options    []ServerOption
+	middleware []endpoint.Middleware
+	notFound   http.Handler
+	routes     []*route
+}
+
+// NewRouter returns a Router with no routes.
+func NewRouter(options ...RouterOption) *Router {
+	rt := &Router{notFound: http.NotFoundHandler()}
+	for _, option := range options {
+		option(rt)
+	}
+	return rt
+}
+
+// Handle routes requests with the method and pattern to a Server constructed
+// from the endpoint, decoder, and encoder, with the router's options and
+// middlewares, then the route's. An empty method matches any method. It panics
+// if the pattern is invalid.
+func (rt *Router) Handle(
+	method, pattern string,
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...RouteOption,
+) {
+	c := routeConfig{}
+	for _, option := range options {
+		option(&c)
+	}
+	mw := append(append([]endpoint.Middleware{}, rt.middleware...), c.middleware...)
+	for i := len(mw) - 1; i >= 0; i-- {
+		e = mw[i](e)
+	}
+	serverOptions := append(append([]ServerOption{}, rt.options...), c.options...)
+	rt.Handler(method, pattern, NewServer(e, dec, enc, serverOptions...))
+}
+
+// Handler routes requests with the method and pattern to the handler. An
+// empty method matches any method. It panics if the pattern is invalid.
+func (rt *Router) Handler(method, pattern string, h http.Handler) {
+	rt.routes = append(rt.routes, &route{
+		method:   method,
+		pattern:  pattern,
+		segments: parsePattern(pattern),
+		handler:  h,
+	})
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := splitPath(r.URL.EscapedPath())
+	var (
+		best      *route
+		bestVars  map[string]string
+		bestScore = -1
+		allowed   []string
+	)
+	for _, rr := range rt.routes {
+		vars, score, ok := rr.match(path)
+		if !ok {
+			continue
+		}
+		if rr.method != "" && rr.method != r.Method {
+			if !contains(allowed, rr.method) {
+				allowed = append(allowed, rr.method)
+			}
+			continue
+		}
+		if score > bestScore {
+			best, bestVars, bestScore = rr, vars, score
+		}
+	}
+
+	if best == nil {
+		if len(allowed) > 0 {
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		rt.notFound.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), ContextKeyRequestPathVars, bestVars)
+	ctx = context.WithValue(ctx, ContextKeyRequestRoute, best.pattern)
+	best.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PathVar returns the value of the named path variable of the route matched
+// by Router, or the empty string.
+func PathVar(ctx context.Context, name string) string {
+	vars, _ := ctx.Value(ContextKeyRequestPathVars).(map[string]string)
+	return vars[name]
+}
+
+type route struct {
+	method   string
+	pattern  string
+	segments []segment
+	handler  http.Handler
+}
+
+type segment struct {
+	literal string
+	name    string // variable name, if not a literal
+	rest    bool   // matches the rest of the path
+}
+
+// match returns the variables of the path, and the number of literal
+// segments matched.
+func (rr *route) match(path []string) (map[string]string, int, bool) {
+	vars := map[string]string{}
+	score := 0
+	for i, seg := range rr.segments {
+		if seg.rest {
+			value, err := url.PathUnescape(strings.Join(path[i:], "/"))
+			if err != nil {
+				return nil, 0, false
+			}
+			vars[seg.name] = value
+			return vars, score, true
+		}
+		if i >= len(path) {
+			return nil, 0, false
+		}
+		if seg.name == "" {
+			if seg.literal != path[i] {
+				return nil, 0, false
+			}
+			score++
+			continue
+		}
+		value, err := url.PathUnescape(path[i])
+		if err != nil || value == "" {
+			return nil, 0, false
+		}
+		vars[seg.name] = value
+	}
+	if len(path) != len(rr.segments) {
+		return nil, 0, false
+	}
+	return vars, score, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+func parsePattern(pattern string) []segment {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("pattern must begin with /: " + pattern)
+	}
+	parts := splitPath(pattern)
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			segments[i] = segment{literal: part}
+			continue
+		}
+		name := part[1 : len(part)-1]
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 {
+				panic("{name...} must be the last segment: " + pattern)
+			}
+			segments[i] = segment{name: strings.TrimSuffix(name, "..."), rest: true}
+		} else {
+			segments[i] = segment{name: name}
+		}
+		if segments[i].name == "" {
+			panic("empty variable name: " + pattern)
+		}
+	}
+	return segments
+}
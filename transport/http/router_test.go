@@ -0,0 +1,76 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestRouter(t *testing.T) {
+	var log []string
+	mark := func(name string) endpoint.Middleware {
+		return func(next endpoint.Endpoint) endpoint.Endpoint {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				log = append(log, name)
+				return next(ctx, request)
+			}
+		}
+	}
+	echo := func(_ context.Context, request interface{}) (interface{}, error) { return request, nil }
+	decodeVars := func(ctx context.Context, r *http.Request) (interface{}, error) {
+		route, _ := ctx.Value(httptransport.ContextKeyRequestRoute).(string)
+		return route + " id=" + httptransport.PathVar(ctx, "id") + " rest=" + httptransport.PathVar(ctx, "rest"), nil
+	}
+	encode := func(_ context.Context, w http.ResponseWriter, response interface{}) error {
+		_, err := w.Write([]byte(response.(string)))
+		return err
+	}
+
+	rt := httptransport.NewRouter(httptransport.RouterMiddleware(mark("router")))
+	rt.Handle("GET", "/users/{id}", echo, decodeVars, encode, httptransport.RouteMiddleware(mark("route")))
+	rt.Handle("DELETE", "/users/{id}", echo, decodeVars, encode)
+	rt.Handle("GET", "/users/me", echo, decodeVars, encode)
+	rt.Handle("GET", "/files/{rest...}", echo, decodeVars, encode)
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+		body, allow  string
+	}{
+		{"GET", "/users/42", 200, "/users/{id} id=42 rest=", ""},
+		{"GET", "/users/a%2Fb", 200, "/users/{id} id=a/b rest=", ""},
+		{"GET", "/users/me", 200, "/users/me id= rest=", ""},
+		{"GET", "/files/a/b/c", 200, "/files/{rest...} id= rest=a/b/c", ""},
+		{"GET", "/users", 404, "", ""},
+		{"GET", "/users/42/x", 404, "", ""},
+		{"PUT", "/users/42", 405, "", "DELETE, GET"},
+	} {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if want, have := tc.code, rec.Code; want != have {
+			t.Errorf("%s %s: want %d, have %d", tc.method, tc.path, want, have)
+			continue
+		}
+		body, _ := ioutil.ReadAll(rec.Body)
+		if tc.code == 200 {
+			if want, have := tc.body, string(body); want != have {
+				t.Errorf("%s %s: want %q, have %q", tc.method, tc.path, want, have)
+			}
+		}
+		if want, have := tc.allow, rec.Header().Get("Allow"); want != have {
+			t.Errorf("%s %s Allow: want %q, have %q", tc.method, tc.path, want, have)
+		}
+	}
+
+	log = nil
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+	if want, have := "[router route]", fmt.Sprint(log); want != have {
+		t.Errorf("middleware order: want %s, have %s", want, have)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
@@ -18,6 +20,12 @@ type Server struct {
 	after        []ServerResponseFunc
 	errorEncoder ErrorEncoder
 	finalizer    ServerFinalizerFunc
+	compression  *compression
+	cors         *cors
+	drainer      *Drainer
+	accessLogger log.Logger
+	maxBodySize  int64
+	contentTypes []string
 	logger       log.Logger
 }
 
@@ -74,16 +82,45 @@ func ServerErrorLogger(logger log.Logger) ServerOption {
 	return func(s *Server) { s.logger = logger }
 }
 
+// ServerAccessLog logs one record per request to the logger, after the
+// response is written, with the keys method, path, status, bytes (of the
+// response body), took, request_id (the X-Request-Id header of the request,
+// or of the response), and remote_addr. By default, requests aren't logged.
+func ServerAccessLog(logger log.Logger) ServerOption {
+	return func(s *Server) { s.accessLogger = logger }
+}
+
 // ServerFinalizer is executed at the end of every HTTP request.
 // By default, no finalizer is registered.
 func ServerFinalizer(f ServerFinalizerFunc) ServerOption {
 	return func(s *Server) { s.finalizer = f }
 }
 
-// ServeHTTP implements http.Handler.
+// ServeHTTP implements http.Handler. A response implementing endpoint.Failer,
+// whose Failed method returns an error, is encoded with the error encoder.
 func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if s.accessLogger != nil {
+		iw := &interceptingWriter{w, http.StatusOK, 0}
+		defer func(begin time.Time) {
+			requestID := r.Header.Get("X-Request-Id")
+			if requestID == "" {
+				requestID = iw.Header().Get("X-Request-Id")
+			}
+			s.accessLogger.Log(
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", iw.code,
+				"bytes", iw.written,
+				"took", time.Since(begin),
+				"request_id", requestID,
+				"remote_addr", r.RemoteAddr,
+			)
+		}(time.Now())
+		w = iw
+	}
+
 	if s.finalizer != nil {
 		iw := &interceptingWriter{w, http.StatusOK, 0}
 		defer func() {
@@ -94,6 +131,39 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w = iw
 	}
 
+	if s.drainer != nil {
+		var done func()
+		var ok bool
+		if ctx, done, ok = s.drainer.begin(ctx); !ok {
+			w.Header().Set("Connection", "close")
+			s.errorEncoder(ctx, ErrDraining, w)
+			return
+		}
+		defer done()
+	}
+
+	if s.cors != nil && s.cors.handle(w, r) {
+		return
+	}
+
+	if s.compression != nil {
+		if cw, ok := newCompressWriter(w, r, s.compression); ok {
+			defer cw.Close()
+			w = cw
+		}
+		if err := s.compression.decompressRequest(r); err != nil {
+			s.logger.Log("err", err)
+			s.errorEncoder(ctx, err, w)
+			return
+		}
+	}
+
+	if err := s.guard(r); err != nil {
+		s.logger.Log("err", err)
+		s.errorEncoder(ctx, err, w)
+		return
+	}
+
 	for _, f := range s.before {
 		ctx = f(ctx, r)
 	}
@@ -112,6 +182,11 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		s.errorEncoder(ctx, f.Failed(), w)
+		return
+	}
+
 	for _, f := range s.after {
 		ctx = f(ctx, w)
 	}
@@ -165,7 +240,9 @@ func EncodeJSONResponse(_ context.Context, w http.ResponseWriter, response inter
 // will be applied to the response. If the error implements json.Marshaler, and
 // the marshaling succeeds, a content type of application/json and the JSON
 // encoded form of the error will be used. If the error implements StatusCoder,
-// the provided StatusCode will be used instead of 500.
+// the provided StatusCode will be used instead of 500. If the error has a
+// RetryAfter() time.Duration method, such as endpoint.OverloadError, the
+// Retry-After header is set to its hint.
 func DefaultErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 	contentType, body := "text/plain; charset=utf-8", []byte(err.Error())
 	if marshaler, ok := err.(json.Marshaler); ok {
@@ -174,6 +251,7 @@ func DefaultErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 		}
 	}
 	w.Header().Set("Content-Type", contentType)
+	setRetryAfter(w, err)
 	if headerer, ok := err.(Headerer); ok {
 		for k := range headerer.Headers() {
 			w.Header().Set(k, headerer.Headers().Get(k))
@@ -194,6 +272,17 @@ type StatusCoder interface {
 	StatusCode() int
 }
 
+// setRetryAfter sets the Retry-After header, in whole seconds rounded up, if
+// the error hints when to retry the request.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	e, ok := err.(interface{ RetryAfter() time.Duration })
+	if !ok || e.RetryAfter() <= 0 {
+		return
+	}
+	seconds := (e.RetryAfter() + time.Second - 1) / time.Second
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+}
+
 // Headerer is checked by DefaultErrorEncoder. If an error value implements
 // Headerer, the provided headers will be applied to the response writer, after
 // the Content-Type is set.
@@ -201,6 +290,15 @@ type Headerer interface {
 	Headers() http.Header
 }
 
+// statusError is an error with a fixed status code.
+type statusError struct {
+	msg  string
+	code int
+}
+
+func (e statusError) Error() string   { return e.msg }
+func (e statusError) StatusCode() int { return e.code }
+
 type interceptingWriter struct {
 	http.ResponseWriter
 	code    int
@@ -219,3 +317,11 @@ func (w *interceptingWriter) Write(p []byte) (int, error) {
 	w.written += int64(n)
 	return n, err
 }
+
+// Flush implements http.Flusher, if the underlying writer does, so that
+// streaming responses work with a finalizer.
+func (w *interceptingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
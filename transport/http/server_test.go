@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
 	httptransport "github.com/go-kit/kit/transport/http"
 )
 
@@ -42,6 +43,43 @@ func TestServerBadEndpoint(t *testing.T) {
 	}
 }
 
+func TestServerTimeout(t *testing.T) {
+	handler := httptransport.NewServer(
+		endpoint.Timeout(time.Millisecond)(func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	resp, _ := http.Get(server.URL)
+	if want, have := http.StatusGatewayTimeout, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
+
+func TestServerFailer(t *testing.T) {
+	handler := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) {
+			return failedResponse{endpoint.NotFound(errors.New("no such user"))}, nil
+		},
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	resp, _ := http.Get(server.URL)
+	if want, have := http.StatusNotFound, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
 func TestServerBadEncode(t *testing.T) {
 	handler := httptransport.NewServer(
 		func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil },
@@ -228,6 +266,47 @@ func TestServerFinalizer(t *testing.T) {
 	}
 }
 
+func TestServerAccessLog(t *testing.T) {
+	var keyvals []interface{}
+	handler := httptransport.NewServer(
+		endpoint.Nop,
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(_ context.Context, w http.ResponseWriter, _ interface{}) error {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("hello"))
+			return nil
+		},
+		httptransport.ServerAccessLog(log.LoggerFunc(func(kv ...interface{}) error {
+			keyvals = kv
+			return nil
+		})),
+	)
+	req := httptest.NewRequest("POST", "/things", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.RemoteAddr = "10.0.0.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	have := map[interface{}]interface{}{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		have[keyvals[i]] = keyvals[i+1]
+	}
+	for k, want := range map[string]interface{}{
+		"method":      "POST",
+		"path":        "/things",
+		"status":      http.StatusAccepted,
+		"bytes":       int64(5),
+		"request_id":  "abc",
+		"remote_addr": "10.0.0.1:1234",
+	} {
+		if have := have[k]; want != have {
+			t.Errorf("%s: want %v, have %v", k, want, have)
+		}
+	}
+	if _, ok := have["took"].(time.Duration); !ok {
+		t.Errorf("took: want a time.Duration, have %v", have["took"])
+	}
+}
+
 type enhancedResponse struct {
 	Foo string `json:"foo"`
 }
@@ -322,6 +401,37 @@ func TestEnhancedError(t *testing.T) {
 	}
 }
 
+func TestServerOverload(t *testing.T) {
+	// Every request is rejected, as the only slot is taken.
+	var (
+		limit   = endpoint.MaxConcurrent(1, false, endpoint.ConcurrencyOverload(endpoint.KindResourceExhausted, 1500*time.Millisecond))
+		taken   = make(chan struct{})
+		release = make(chan struct{})
+	)
+	defer close(release)
+	go limit(func(context.Context, interface{}) (interface{}, error) {
+		close(taken)
+		<-release
+		return nil, nil
+	})(context.Background(), struct{}{})
+	<-taken
+	e := limit(endpoint.Nop)
+	handler := httptransport.NewServer(
+		e,
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if want, have := http.StatusTooManyRequests, rec.Code; want != have {
+		t.Errorf("StatusCode: want %d, have %d", want, have)
+	}
+	if want, have := "2", rec.Header().Get("Retry-After"); want != have {
+		t.Errorf("Retry-After: want %q, have %q", want, have)
+	}
+}
+
 func testServer(t *testing.T) (step func(), resp <-chan *http.Response) {
 	var (
 		stepch   = make(chan bool)
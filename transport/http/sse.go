@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSSEHeartbeat is the default interval between heartbeats of
+// EncodeSSEResponse.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// ErrStreamingUnsupported is returned by EncodeSSEResponse when the
+// ResponseWriter can't be flushed.
+var ErrStreamingUnsupported = errors.New("response writer doesn't support streaming")
+
+// Event is a Server-Sent Event. Only Data is required.
+type Event struct {
+	ID    string        // sets the client's last event ID, if not empty
+	Event string        // type of the event; the client's default is "message"
+	Data  string        // payload; may contain newlines
+	Retry time.Duration // sets the client's reconnection delay, if positive
+}
+
+// EventIterator is a source of events, which may be returned by an endpoint
+// instead of a channel. Next blocks until the next event is available, or the
+// context is done. It returns io.EOF after the last event; any other error
+// also ends the stream.
+type EventIterator interface {
+	Next(ctx context.Context) (Event, error)
+}
+
+// SSEOption sets an optional parameter for EncodeSSEResponse.
+type SSEOption func(*sseEncoder)
+
+// SSEHeartbeat sets the interval at which a comment is sent while no events
+// are, so that proxies don't close idle streams. Zero disables heartbeats. By
+// default, DefaultSSEHeartbeat is used.
+func SSEHeartbeat(d time.Duration) SSEOption {
+	return func(e *sseEncoder) { e.heartbeat = d }
+}
+
+// EncodeSSEResponse returns an EncodeResponseFunc which streams Server-Sent
+// Events to the client. The endpoint's response must be a <-chan Event, a chan
+// Event, or an EventIterator. Each event is flushed as soon as it's written.
+// The stream ends when the channel is closed, the iterator returns io.EOF, or
+// the client disconnects, which cancels the request context. Endpoints should
+// produce events with that context, so that they stop with the stream.
+//
+//	server := httptransport.NewServer(
+//	    subscribeEndpoint, decodeSubscribeRequest,
+//	    httptransport.EncodeSSEResponse(),
+//	)
+func EncodeSSEResponse(options ...SSEOption) EncodeResponseFunc {
+	e := &sseEncoder{heartbeat: DefaultSSEHeartbeat}
+	for _, option := range options {
+		option(e)
+	}
+	return e.encode
+}
+
+type sseEncoder struct {
+	heartbeat time.Duration
+}
+
+func (e *sseEncoder) encode(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var events <-chan Event
+	switch r := response.(type) {
+	case <-chan Event:
+		events = r
+	case chan Event:
+		events = r
+	case EventIterator:
+		events = iterate(ctx, r)
+	default:
+		return fmt.Errorf("SSE response must be a channel of Events or an EventIterator, not %T", response)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable buffering by nginx
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if e.heartbeat > 0 {
+		ticker := time.NewTicker(e.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		var buf []byte
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			buf = event.encode()
+		case <-heartbeat:
+			buf = []byte(":\n\n")
+		case <-ctx.Done():
+			return nil
+		}
+		if _, err := w.Write(buf); err != nil {
+			if ctx.Err() != nil {
+				return nil // the client disconnected
+			}
+			return err
+		}
+		flusher.Flush()
+	}
+}
+
+// iterate returns a channel of the events of the iterator, which is closed
+// after the last one, or when the context is done.
+func iterate(ctx context.Context, it EventIterator) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for {
+			event, err := it.Next(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+func (e Event) encode() []byte {
+	var buf bytes.Buffer
+	if e.ID != "" {
+		fmt.Fprintf(&buf, "id: %s\n", oneLine(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", oneLine(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %s\n", strconv.FormatInt(int64(e.Retry/time.Millisecond), 10))
+	}
+	for _, line := range strings.Split(strings.Replace(e.Data, "\r\n", "\n", -1), "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// oneLine removes line breaks, which would corrupt a field.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
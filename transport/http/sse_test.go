@@ -0,0 +1,99 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestEncodeSSEResponse(t *testing.T) {
+	handler := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) {
+			events := make(chan httptransport.Event, 2)
+			events <- httptransport.Event{ID: "1", Event: "greeting", Data: "hello\nworld"}
+			events <- httptransport.Event{Data: "bye", Retry: 3 * time.Second}
+			close(events)
+			return events, nil
+		},
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.EncodeSSEResponse(),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if want, have := "text/event-stream", resp.Header.Get("Content-Type"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	want := "id: 1\nevent: greeting\ndata: hello\ndata: world\n\nretry: 3000\ndata: bye\n\n"
+	if have := string(body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type iterator struct {
+	n int
+}
+
+func (it *iterator) Next(ctx context.Context) (httptransport.Event, error) {
+	if it.n == 0 {
+		<-ctx.Done()
+		return httptransport.Event{}, ctx.Err()
+	}
+	it.n--
+	return httptransport.Event{Data: "tick"}, nil
+}
+
+func TestEncodeSSEResponseDisconnect(t *testing.T) {
+	done := make(chan struct{})
+	handler := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return &iterator{n: 1}, nil },
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.EncodeSSEResponse(httptransport.SSEHeartbeat(5*time.Millisecond)),
+		httptransport.ServerFinalizer(func(context.Context, int, *http.Request) { close(done) }),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for _, want := range []string{"data: tick\n", "\n", ":\n", "\n"} {
+		have, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		if want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+	resp.Body.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream didn't end after the client disconnected")
+	}
+}
+
+func TestEncodeSSEResponseBadResponse(t *testing.T) {
+	err := httptransport.EncodeSSEResponse()(context.Background(), httptest.NewRecorder(), "nope")
+	if err == nil || !strings.Contains(err.Error(), "string") {
+		t.Errorf("want error, have %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// DefaultStreamBufferSize is the default size of the buffer with which
+// EncodeStreamResponse copies responses.
+const DefaultStreamBufferSize = 32 * 1024
+
+// Streamer may be implemented by responses which write their own body, e.g.
+// by rendering a large report row by row. See EncodeStreamResponse.
+type Streamer interface {
+	Stream(w io.Writer) error
+}
+
+// EncodeStreamResponse returns an EncodeResponseFunc which streams the body
+// of the response to the ResponseWriter, rather than buffering it in memory.
+// The response must be a Streamer or an io.Reader, which is copied through a
+// buffer of bufferSize bytes, or DefaultStreamBufferSize if bufferSize isn't
+// positive. Responses which are io.Closers are closed once they're written,
+// or fail.
+//
+// The content type is application/octet-stream, unless the response
+// implements Headerer, whose headers are applied, e.g. to set the content
+// type, disposition, or length. If it doesn't set the length, and the
+// response has a Len method, as bytes.Reader and strings.Reader do, the
+// length is set from it. If the response implements StatusCoder, the
+// provided StatusCode is used instead of 200.
+func EncodeStreamResponse(bufferSize int) EncodeResponseFunc {
+	if bufferSize <= 0 {
+		bufferSize = DefaultStreamBufferSize
+	}
+	return func(_ context.Context, w http.ResponseWriter, response interface{}) error {
+		if c, ok := response.(io.Closer); ok {
+			defer c.Close()
+		}
+		streamer, isStreamer := response.(Streamer)
+		reader, isReader := response.(io.Reader)
+		if !isStreamer && !isReader {
+			return fmt.Errorf("stream response must be a Streamer or an io.Reader, not %T", response)
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if headerer, ok := response.(Headerer); ok {
+			for k := range headerer.Headers() {
+				w.Header().Set(k, headerer.Headers().Get(k))
+			}
+		}
+		if l, ok := response.(interface {
+			Len() int
+		}); ok && w.Header().Get("Content-Length") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(l.Len()))
+		}
+		code := http.StatusOK
+		if sc, ok := response.(StatusCoder); ok {
+			code = sc.StatusCode()
+		}
+		w.WriteHeader(code)
+
+		if isStreamer {
+			return streamer.Stream(w)
+		}
+		// Hide any ReadFrom method of the writer, and WriteTo method of the
+		// reader, so that the buffer size is respected.
+		_, err := io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{reader}, make([]byte, bufferSize))
+		return err
+	}
+}
+
+// EncodeStreamRequest is an EncodeRequestFunc which streams the body of the
+// request to the server, rather than buffering it in memory, e.g. to upload
+// large files. The request must be a Streamer, whose Stream method is called
+// in another goroutine, or an io.Reader.
+//
+// The content type is application/octet-stream, unless the request
+// implements Headerer, whose headers are applied. If the request has a Len
+// method, as bytes.Reader and strings.Reader do, or its headers set
+// Content-Length, the body is sent with that length. Otherwise, it's sent
+// with chunked encoding.
+//
+// Streamers, and readers which are also io.Seekers, such as *os.File, can be
+// resent from the start, so they may be retried with ClientRetry. Other
+// readers are never retried. Requests which are io.Closers aren't closed, so
+// that they may be resent; callers should close them once the endpoint
+// returns.
+func EncodeStreamRequest(_ context.Context, r *http.Request, request interface{}) error {
+	r.Header.Set("Content-Type", "application/octet-stream")
+	if headerer, ok := request.(Headerer); ok {
+		for k := range headerer.Headers() {
+			r.Header.Set(k, headerer.Headers().Get(k))
+		}
+	}
+	r.ContentLength = -1
+	if l, ok := request.(interface {
+		Len() int
+	}); ok {
+		r.ContentLength = int64(l.Len())
+	}
+	if v := r.Header.Get("Content-Length"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid Content-Length %q", v)
+		}
+		r.ContentLength = n
+		r.Header.Del("Content-Length")
+	}
+
+	switch v := request.(type) {
+	case Streamer:
+		r.GetBody = func() (io.ReadCloser, error) { return streamBody(v), nil }
+	case io.ReadSeeker:
+		start, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		r.GetBody = func() (io.ReadCloser, error) {
+			if _, err := v.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(v), nil
+		}
+	case io.Reader:
+		r.Body = unreplayableBody{v}
+		return nil
+	default:
+		return fmt.Errorf("stream request must be a Streamer or an io.Reader, not %T", request)
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return err
+	}
+	r.Body = body
+	return nil
+}
+
+// streamBody returns a reader of what the Streamer writes.
+func streamBody(s Streamer) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(s.Stream(pw)) }()
+	return pr
+}
+
+// unreplayableBody marks a request body which can't be resent, so that
+// ClientRetry doesn't buffer it.
+type unreplayableBody struct {
+	io.Reader
+}
+
+func (unreplayableBody) Close() error { return nil }
@@ -0,0 +1,140 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+type download struct {
+	*strings.Reader
+	closed bool
+}
+
+func (d *download) Close() error { d.closed = true; return nil }
+
+func (d *download) Headers() http.Header {
+	return http.Header{"Content-Type": []string{"text/csv"}}
+}
+
+type report struct{ rows int }
+
+func (r report) Stream(w io.Writer) error {
+	for i := 0; i < r.rows; i++ {
+		if _, err := io.WriteString(w, "row\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r report) StatusCode() int { return http.StatusPartialContent }
+
+func TestEncodeStreamResponse(t *testing.T) {
+	encode := httptransport.EncodeStreamResponse(4)
+
+	d := &download{Reader: strings.NewReader("a,b\n1,2\n")}
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, d); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a,b\n1,2\n", rec.Body.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "text/csv", rec.Header().Get("Content-Type"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "8", rec.Header().Get("Content-Length"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !d.closed {
+		t.Error("want response closed")
+	}
+
+	rec = httptest.NewRecorder()
+	if err := encode(context.Background(), rec, report{rows: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := http.StatusPartialContent, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "application/octet-stream", rec.Header().Get("Content-Type"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "row\nrow\nrow\n", rec.Body.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if err := encode(context.Background(), httptest.NewRecorder(), 42); err == nil {
+		t.Error("want error for unsupported response")
+	}
+}
+
+func TestEncodeStreamRequest(t *testing.T) {
+	type received struct {
+		body          string
+		contentLength int64
+		chunked       bool
+	}
+	var (
+		calls   int32
+		results = make(chan received, 10)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		chunked := len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
+		results <- received{string(body), r.ContentLength, chunked}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	for _, tc := range []struct {
+		name          string
+		request       interface{}
+		contentLength int64
+		chunked       bool
+		wantCalls     int32
+	}{
+		{"sized reader", strings.NewReader("hello"), 5, false, 2},
+		{"streamer", report{3}, -1, true, 2},
+		{"plain reader", struct{ io.Reader }{strings.NewReader("hello")}, -1, true, 1},
+	} {
+		atomic.StoreInt32(&calls, 0)
+		client := httptransport.NewClient(
+			"PUT", u,
+			httptransport.EncodeStreamRequest,
+			func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+			httptransport.ClientRetry(httptransport.ClientRetryBackoff(time.Millisecond, time.Millisecond)),
+		)
+		if _, err := client.Endpoint()(context.Background(), tc.request); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if want, have := tc.wantCalls, atomic.LoadInt32(&calls); want != have {
+			t.Errorf("%s: calls: want %d, have %d", tc.name, want, have)
+		}
+		for i := int32(0); i < tc.wantCalls; i++ {
+			r := <-results
+			if want, have := tc.contentLength, r.contentLength; want != have {
+				t.Errorf("%s: content length: want %d, have %d", tc.name, want, have)
+			}
+			if want, have := tc.chunked, r.chunked; want != have {
+				t.Errorf("%s: chunked: want %v, have %v", tc.name, want, have)
+			}
+			if r.body == "" {
+				t.Errorf("%s: empty body", tc.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Headers of the W3C Trace Context.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// ErrInvalidTraceParent is returned by ParseTraceParent for values which
+// aren't valid traceparent headers.
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// TraceContext is the W3C Trace Context of a request, as propagated by the
+// traceparent and tracestate headers, which Envoy and OpenTelemetry speak.
+// It's independent of any tracer.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte // of the caller, the parent-id of the header
+	Flags   byte    // the trace-flags; the lowest bit is sampled
+	State   string  // the tracestate header, passed on unchanged
+}
+
+// Valid reports whether the trace and span IDs are set.
+func (tc TraceContext) Valid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the caller may have recorded the trace.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// TraceParent returns the value of the traceparent header, in version 00.
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%x-%x-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// ParseTraceParent parses the value of a traceparent header. Values of
+// versions after 00 are parsed as version 00, ignoring fields they add.
+func ParseTraceParent(s string) (TraceContext, error) {
+	var tc TraceContext
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tc, ErrInvalidTraceParent
+	}
+	version := s[:2]
+	if version == "ff" || (len(s) > 55 && (version == "00" || s[55] != '-')) {
+		return tc, ErrInvalidTraceParent
+	}
+	var v, flags [1]byte
+	if !decodeHex(v[:], version) ||
+		!decodeHex(tc.TraceID[:], s[3:35]) ||
+		!decodeHex(tc.SpanID[:], s[36:52]) ||
+		!decodeHex(flags[:], s[53:55]) ||
+		!tc.Valid() {
+		return TraceContext{}, ErrInvalidTraceParent
+	}
+	tc.Flags = flags[0]
+	return tc, nil
+}
+
+// decodeHex decodes the lowercase hex src into dst.
+func decodeHex(dst []byte, src string) bool {
+	if strings.ToLower(src) != src {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(src))
+	return err == nil
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a context carrying the trace context.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context set by
+// ContextWithTraceContext or TraceContextHeadersToContext, if any.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// TraceContextHeadersToContext is a server RequestFunc which extracts the
+// trace context of the traceparent and tracestate headers, if they're valid,
+// into the context. Requests without one start no trace.
+func TraceContextHeadersToContext(ctx context.Context, r *http.Request) context.Context {
+	tc, err := ParseTraceParent(r.Header.Get(TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	tc.State = strings.Join(r.Header[http.CanonicalHeaderKey(TraceStateHeader)], ",")
+	return ContextWithTraceContext(ctx, tc)
+}
+
+// ContextToTraceContextHeaders is a client RequestFunc which sets the
+// traceparent and tracestate headers to the trace context of the context, if
+// any, so that the trace continues through the peer. Each request is a new
+// span of the trace, with a random span ID; the flags and state are passed
+// on unchanged. It shouldn't be combined with a tracer which sets the same
+// headers.
+func ContextToTraceContextHeaders(ctx context.Context, r *http.Request) context.Context {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if _, err := rand.Read(tc.SpanID[:]); err != nil {
+		return ctx
+	}
+	r.Header.Set(TraceParentHeader, tc.TraceParent())
+	if tc.State != "" {
+		r.Header.Set(TraceStateHeader, tc.State)
+	} else {
+		r.Header.Del(TraceStateHeader)
+	}
+	return ctx
+}
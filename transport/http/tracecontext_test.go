@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	} {
+		_, err := httptransport.ParseTraceParent(tc.header)
+		if want, have := tc.valid, err == nil; want != have {
+			t.Errorf("%q: want valid %v, have %v (%v)", tc.header, want, have, err)
+		}
+	}
+}
+
+func TestTraceContextHeaders(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	in, _ := http.NewRequest("GET", "http://example.com", nil)
+	in.Header.Set("traceparent", traceParent)
+	in.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	ctx := httptransport.TraceContextHeadersToContext(context.Background(), in)
+
+	tc, ok := httptransport.TraceContextFromContext(ctx)
+	if !ok {
+		t.Fatal("want a trace context")
+	}
+	if want, have := traceParent, tc.TraceParent(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !tc.Sampled() {
+		t.Error("want sampled")
+	}
+
+	// The outgoing request continues the trace, as a new span.
+	out, _ := http.NewRequest("GET", "http://example.com", nil)
+	httptransport.ContextToTraceContextHeaders(ctx, out)
+	next, err := httptransport.ParseTraceParent(out.Header.Get("traceparent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := tc.TraceID, next.TraceID; want != have {
+		t.Errorf("want trace ID %x, have %x", want, have)
+	}
+	if next.SpanID == tc.SpanID {
+		t.Error("want a new span ID")
+	}
+	if want, have := "congo=t61rcWkgMzE", out.Header.Get("tracestate"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Without a trace context, no headers are set.
+	out, _ = http.NewRequest("GET", "http://example.com", nil)
+	httptransport.ContextToTraceContextHeaders(context.Background(), out)
+	if have := out.Header.Get("traceparent"); have != "" {
+		t.Errorf("want no traceparent, have %q", have)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientMaxIdleConnsPerHost sets the maximum number of idle connections kept
+// per host. Clients making many concurrent requests to one service should
+// raise it from the default of http.DefaultMaxIdleConnsPerHost (2), to avoid
+// churning connections.
+//
+// This, and the other transport options, replace the transport of the HTTP
+// client with a new http.Transport, configured as http.DefaultTransport is,
+// except as the options set. The timeout, redirect policy, and cookie jar of
+// the HTTP client are preserved.
+func ClientMaxIdleConnsPerHost(n int) ClientOption {
+	return func(c *Client) { c.transportConfig().maxIdleConnsPerHost = n }
+}
+
+// ClientDialTimeout sets the maximum time to establish a connection. By
+// default, it's 30 seconds.
+func ClientDialTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.transportConfig().dialTimeout = d }
+}
+
+// ClientTLSHandshakeTimeout sets the maximum time to perform the TLS
+// handshake. By default, it's 10 seconds.
+func ClientTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.transportConfig().tlsHandshakeTimeout = d }
+}
+
+// ClientTLSConfig sets the TLS configuration, e.g. for client certificates or
+// private certificate authorities.
+func ClientTLSConfig(config *tls.Config) ClientOption {
+	return func(c *Client) { c.transportConfig().tlsConfig = config }
+}
+
+// ClientProxy sets the function returning the proxy for each request, e.g.
+// http.ProxyURL. A nil proxy function, or nil URL, means no proxy. By default,
+// http.ProxyFromEnvironment is used.
+func ClientProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(c *Client) {
+		t := c.transportConfig()
+		t.proxy, t.proxySet = proxy, true
+	}
+}
+
+// ClientRequestTimeout bounds the time of each request, from encoding the
+// request to decoding the response, unless the request context has an
+// earlier deadline. Unlike the timeout of an http.Client, it applies to each
+// kit Client separately. By default, requests are only bounded by their
+// context.
+func ClientRequestTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = d }
+}
+
+type transportConfig struct {
+	maxIdleConnsPerHost int
+	dialTimeout         time.Duration
+	tlsHandshakeTimeout time.Duration
+	tlsConfig           *tls.Config
+	proxy               func(*http.Request) (*url.URL, error)
+	proxySet            bool
+}
+
+func (c *Client) transportConfig() *transportConfig {
+	if c.transport == nil {
+		c.transport = &transportConfig{
+			dialTimeout:         30 * time.Second,
+			tlsHandshakeTimeout: 10 * time.Second,
+		}
+	}
+	return c.transport
+}
+
+// client returns an HTTP client like base, with a transport configured by t.
+func (t *transportConfig) client(base *http.Client) *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if t.proxySet {
+		proxy = t.proxy
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   t.dialTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig:       t.tlsConfig,
+			TLSHandshakeTimeout:   t.tlsHandshakeTimeout,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   t.maxIdleConnsPerHost,
+			IdleConnTimeout:       90 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		CheckRedirect: base.CheckRedirect,
+		Jar:           base.Jar,
+		Timeout:       base.Timeout,
+	}
+}
@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	tgt, _ := url.Parse("http://service.invalid/path")
+	client := httptransport.NewClient(
+		"GET", tgt,
+		func(context.Context, *http.Request, interface{}) error { return nil },
+		func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+		httptransport.ClientProxy(http.ProxyURL(proxyURL)),
+		httptransport.ClientMaxIdleConnsPerHost(16),
+		httptransport.ClientDialTimeout(time.Second),
+		httptransport.ClientTLSHandshakeTimeout(time.Second),
+	)
+	if _, err := client.Endpoint()(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := tgt.String(), proxied; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestClientRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tgt, _ := url.Parse(server.URL)
+	client := httptransport.NewClient(
+		"GET", tgt,
+		func(context.Context, *http.Request, interface{}) error { return nil },
+		func(_ context.Context, resp *http.Response) (interface{}, error) { return resp.StatusCode, nil },
+		httptransport.ClientRequestTimeout(10*time.Millisecond),
+	)
+	begin := time.Now()
+	if _, err := client.Endpoint()(context.Background(), nil); err == nil {
+		t.Fatal("want error, have none")
+	}
+	if have := time.Since(begin); have > time.Second {
+		t.Errorf("request took %s", have)
+	}
+}
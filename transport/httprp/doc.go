@@ -1,4 +1,5 @@
 // Package httprp provides an HTTP reverse-proxy transport. HTTP handlers that
 // need to proxy requests to another HTTP service can do so with this package by
-// specifying the URL to forward the request to.
+// specifying the URL to forward the request to. To proxy through a middleware
+// stack, use NewProxyEndpoint with a transport/http Server.
 package httprp
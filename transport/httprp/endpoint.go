@@ -0,0 +1,259 @@
+package httprp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// hopHeaders are the hop-by-hop headers, which apply to a single connection,
+// and so aren't forwarded.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// ProxyOption sets an optional parameter for proxy endpoints.
+type ProxyOption func(*proxy)
+
+// ProxyClient sets the HTTP client with which requests are forwarded. By
+// default, a client using http.DefaultTransport, which doesn't follow
+// redirects, so that they're passed through, is used.
+func ProxyClient(client *http.Client) ProxyOption {
+	return func(p *proxy) { p.client = client }
+}
+
+// ProxyBefore sets functions which may modify each outgoing request, e.g. to
+// add credentials for the upstream, before it's sent.
+func ProxyBefore(before ...func(context.Context, *http.Request) context.Context) ProxyOption {
+	return func(p *proxy) { p.before = append(p.before, before...) }
+}
+
+type proxy struct {
+	target *url.URL
+	client *http.Client
+	before []func(context.Context, *http.Request) context.Context
+}
+
+// BadGatewayError is returned by proxy endpoints when the upstream can't be
+// reached. It implements the StatusCoder interface of package transport/http,
+// so the default error encoder responds with 502 Bad Gateway.
+type BadGatewayError struct {
+	Err error
+}
+
+// Error implements error.
+func (e BadGatewayError) Error() string {
+	return fmt.Sprintf("upstream unavailable: %v", e.Err)
+}
+
+// StatusCode returns http.StatusBadGateway.
+func (e BadGatewayError) StatusCode() int {
+	return http.StatusBadGateway
+}
+
+// NewProxyEndpoint returns an endpoint which forwards requests to the target
+// URL, like Server, but which may be wrapped by middlewares, e.g. for rate
+// limiting or authentication, and served by a transport/http Server with
+// DecodeProxyRequest and EncodeProxyResponse. The request is the incoming
+// *http.Request, and the response is the upstream's *http.Response, whose
+// body the encoder streams to the client and closes.
+//
+// The method, headers, and body of the request are passed through, streaming
+// the body. The path is joined to the target's path, as for Server, and the
+// query is merged with the target's. Hop-by-hop headers, including those
+// listed in the Connection header, are removed, and X-Forwarded-For is
+// appended. The upstream's responses, of any status, are passed through;
+// only failures to reach it return an error, a BadGatewayError.
+//
+// The endpoint's context, e.g. the deadline of a Timeout middleware, bounds
+// the time until the upstream's response headers arrive. The response body
+// outlives it, so that the encoder may stream it after the endpoint returns;
+// the upstream request is canceled once the body is closed, or once the
+// incoming request's context is done.
+func NewProxyEndpoint(target *url.URL, options ...ProxyOption) endpoint.Endpoint {
+	p := &proxy{
+		target: target,
+		client: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		in, ok := request.(*http.Request)
+		if !ok {
+			return nil, fmt.Errorf("proxy request must be an *http.Request, not %T", request)
+		}
+
+		u := *p.target
+		u.Path = singleJoiningSlash(p.target.Path, in.URL.Path)
+		u.RawPath = ""
+		switch {
+		case p.target.RawQuery == "":
+			u.RawQuery = in.URL.RawQuery
+		case in.URL.RawQuery != "":
+			u.RawQuery = p.target.RawQuery + "&" + in.URL.RawQuery
+		}
+
+		body := in.Body
+		if in.ContentLength == 0 {
+			body = nil
+		}
+		out, err := http.NewRequest(in.Method, u.String(), body)
+		if err != nil {
+			return nil, err
+		}
+		out.ContentLength = in.ContentLength
+		out.Header = make(http.Header, len(in.Header))
+		copyHeader(out.Header, in.Header)
+		if clientIP, _, err := net.SplitHostPort(in.RemoteAddr); err == nil {
+			if prior := out.Header.Get("X-Forwarded-For"); prior != "" {
+				clientIP = prior + ", " + clientIP
+			}
+			out.Header.Set("X-Forwarded-For", clientIP)
+		}
+		for _, f := range p.before {
+			ctx = f(ctx, out)
+		}
+
+		uctx, cancel := context.WithCancel(detachedContext{ctx})
+		headers := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				select {
+				case <-headers:
+				default:
+					cancel()
+					return
+				}
+			case <-headers:
+			}
+			select {
+			case <-in.Context().Done():
+				cancel()
+			case <-uctx.Done():
+			}
+		}()
+		resp, err := p.client.Do(out.WithContext(uctx))
+		close(headers)
+		if err != nil {
+			cancel()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, BadGatewayError{err}
+		}
+		if uctx.Err() != nil {
+			// The endpoint's context was done before the response was.
+			resp.Body.Close()
+			return nil, ctx.Err()
+		}
+		resp.Body = cancelBody{resp.Body, cancel}
+		return resp, nil
+	}
+}
+
+// detachedContext carries the values of a context, but not its deadline or
+// cancellation.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// cancelBody cancels the upstream request once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// DecodeProxyRequest is a DecodeRequestFunc for transport/http Servers, which
+// returns the incoming request for a proxy endpoint.
+func DecodeProxyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return r, nil
+}
+
+// EncodeProxyResponse is an EncodeResponseFunc for transport/http Servers,
+// which writes the upstream response of a proxy endpoint, less its hop-by-hop
+// headers, and closes its body. Bodies of unknown length, as of streamed
+// responses, are flushed as they're read.
+func EncodeProxyResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(*http.Response)
+	if !ok {
+		return fmt.Errorf("proxy response must be an *http.Response, not %T", response)
+	}
+	defer resp.Body.Close()
+
+	copyHeader(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+
+	var dst io.Writer = w
+	if f, ok := w.(http.Flusher); ok && resp.ContentLength < 0 {
+		dst = flushWriter{w, f}
+	}
+	_, err := io.Copy(dst, resp.Body)
+	return err
+}
+
+// copyHeader copies the end-to-end headers of src to dst.
+func copyHeader(dst, src http.Header) {
+	for k, vv := range src {
+		dst[k] = append([]string(nil), vv...)
+	}
+	for _, f := range src["Connection"] {
+		for _, k := range strings.Split(f, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				dst.Del(k)
+			}
+		}
+	}
+	for _, k := range hopHeaders {
+		dst.Del(k)
+	}
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+type flushWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.f.Flush()
+	return n, err
+}
@@ -0,0 +1,126 @@
+package httprp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/kit/transport/httprp"
+)
+
+func TestProxyEndpoint(t *testing.T) {
+	originServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Connection", "X-Hop")
+		w.Header().Set("X-Hop", "1")
+		w.Header().Set("X-Upstream", r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		w.Header().Set("X-Forwarded-For-Seen", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Token-Seen", r.Header.Get("X-Token"))
+		w.Header().Set("X-Hop-Seen", r.Header.Get("X-Client-Hop"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+	defer originServer.Close()
+	originURL, _ := url.Parse(originServer.URL + "/base?key=1")
+
+	var middlewareCalled bool
+	e := httprp.NewProxyEndpoint(originURL, httprp.ProxyBefore(func(ctx context.Context, r *http.Request) context.Context {
+		r.Header.Set("X-Token", "upstream")
+		return ctx
+	}))
+	e = func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			middlewareCalled = true
+			return next(ctx, request)
+		}
+	}(e)
+	proxyServer := httptest.NewServer(httptransport.NewServer(e, httprp.DecodeProxyRequest, httprp.EncodeProxyResponse))
+	defer proxyServer.Close()
+
+	req, _ := http.NewRequest("PUT", proxyServer.URL+"/items/1?x=2", strings.NewReader("payload"))
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if !middlewareCalled {
+		t.Error("middleware wasn't called")
+	}
+	if want, have := http.StatusCreated, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "created", string(body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	for k, want := range map[string]string{
+		"X-Upstream":           "PUT /base/items/1?key=1&x=2 payload",
+		"X-Forwarded-For-Seen": "127.0.0.1",
+		"X-Token-Seen":         "upstream",
+		"X-Hop-Seen":           "",
+		"X-Hop":                "",
+	} {
+		if have := resp.Header.Get(k); want != have {
+			t.Errorf("%s: want %q, have %q", k, want, have)
+		}
+	}
+}
+
+func TestProxyEndpointTimeout(t *testing.T) {
+	originServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("streamed "))
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("body"))
+	}))
+	defer originServer.Close()
+	originURL, _ := url.Parse(originServer.URL)
+
+	// The timeout's context is canceled once the endpoint returns, before the
+	// body is streamed, which mustn't cut it short.
+	e := endpoint.Timeout(time.Second)(httprp.NewProxyEndpoint(originURL))
+	proxyServer := httptest.NewServer(httptransport.NewServer(e, httprp.DecodeProxyRequest, httprp.EncodeProxyResponse))
+	defer proxyServer.Close()
+
+	resp, err := http.Get(proxyServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "streamed body", string(body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestProxyEndpointBadGateway(t *testing.T) {
+	originURL, _ := url.Parse("http://127.0.0.1:1")
+	proxyServer := httptest.NewServer(httptransport.NewServer(
+		httprp.NewProxyEndpoint(originURL),
+		httprp.DecodeProxyRequest,
+		httprp.EncodeProxyResponse,
+	))
+	defer proxyServer.Close()
+
+	resp, err := http.Get(proxyServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusBadGateway, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
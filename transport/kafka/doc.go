@@ -0,0 +1,5 @@
+// Package kafka provides a Kafka binding for endpoints. Servers serve
+// endpoints with the records of the topics of a consumer group, committing
+// their offsets once they're served, and Producers call them by producing
+// records.
+package kafka
@@ -0,0 +1,19 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from a Kafka
+// record. It's designed to be used in Kafka servers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// JSON decodes from the record value to the concrete request type.
+type DecodeRequestFunc func(context.Context, *sarama.ConsumerMessage) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the Kafka
+// record. It's designed to be used in Kafka producers, for producer-side
+// endpoints. One straightforward EncodeRequestFunc could be something that
+// JSON encodes the object directly to the record value.
+type EncodeRequestFunc func(context.Context, *sarama.ProducerMessage, interface{}) error
@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Producer wraps a topic, and provides methods that implement
+// endpoint.Endpoint, by producing records to the topic.
+type Producer struct {
+	producer  sarama.SyncProducer
+	topic     string
+	enc       EncodeRequestFunc
+	key       KeyFunc
+	partition PartitionFunc
+	before    []ProducerRequestFunc
+	after     []ProducerResponseFunc
+}
+
+// NewProducer constructs a usable Producer for a single topic. The producer
+// must be configured with Producer.Return.Successes, as sarama requires of
+// SyncProducers.
+func NewProducer(
+	producer sarama.SyncProducer,
+	topic string,
+	enc EncodeRequestFunc,
+	options ...ProducerOption,
+) *Producer {
+	p := &Producer{
+		producer: producer,
+		topic:    topic,
+		enc:      enc,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// ProducerOption sets an optional parameter for producers.
+type ProducerOption func(*Producer)
+
+// KeyFunc returns the key of the record of a request. Records with the same
+// key are produced to the same partition by the default partitioner, so
+// that they're consumed in order.
+type KeyFunc func(ctx context.Context, request interface{}) []byte
+
+// PartitionFunc returns the partition of the record of a request.
+type PartitionFunc func(ctx context.Context, request interface{}) int32
+
+// ProducerKey sets the KeyFunc of the records. By default, records have no
+// key, and the default partitioner picks a random partition.
+func ProducerKey(f KeyFunc) ProducerOption {
+	return func(p *Producer) { p.key = f }
+}
+
+// ProducerPartition sets the PartitionFunc of the records. The partition is
+// only used by producers configured with sarama.NewManualPartitioner.
+func ProducerPartition(f PartitionFunc) ProducerOption {
+	return func(p *Producer) { p.partition = f }
+}
+
+// ProducerBefore sets the ProducerRequestFuncs that are applied to the
+// outgoing record before it's produced, e.g. to propagate a trace in its
+// headers.
+func ProducerBefore(before ...ProducerRequestFunc) ProducerOption {
+	return func(p *Producer) { p.before = append(p.before, before...) }
+}
+
+// ProducerAfter sets the ProducerResponseFuncs applied to the record after
+// it's produced.
+func ProducerAfter(after ...ProducerResponseFunc) ProducerOption {
+	return func(p *Producer) { p.after = append(p.after, after...) }
+}
+
+// ProducerResponse is the response of the endpoints of Producers: the
+// partition and offset a record was produced to.
+type ProducerResponse struct {
+	Partition int32
+	Offset    int64
+}
+
+// Endpoint returns a usable endpoint that produces the request as a record,
+// and returns the ProducerResponse of the record once the producer is
+// acknowledged by the brokers, as set by Producer.RequiredAcks. Records of
+// concurrent requests are batched by the producer, as set by
+// Producer.Flush.
+func (p Producer) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, msg, err := p.message(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, _, err := p.producer.SendMessage(msg); err != nil {
+			return nil, err
+		}
+
+		for _, f := range p.after {
+			ctx = f(ctx, msg)
+		}
+		return ProducerResponse{Partition: msg.Partition, Offset: msg.Offset}, nil
+	}
+}
+
+// BatchEndpoint returns a usable endpoint that produces a batch of requests,
+// given as a []interface{}, as records, in a single call to the producer,
+// and returns a []ProducerResponse of the records. If any record fails, the
+// sarama.ProducerErrors of the records which failed are returned; the
+// others were produced.
+func (p Producer) BatchEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		requests, ok := request.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("batch request must be a []interface{}, not %T", request)
+		}
+
+		msgs := make([]*sarama.ProducerMessage, len(requests))
+		for i, request := range requests {
+			var err error
+			if ctx, msgs[i], err = p.message(ctx, request); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := p.producer.SendMessages(msgs); err != nil {
+			return nil, err
+		}
+
+		responses := make([]ProducerResponse, len(msgs))
+		for i, msg := range msgs {
+			for _, f := range p.after {
+				ctx = f(ctx, msg)
+			}
+			responses[i] = ProducerResponse{Partition: msg.Partition, Offset: msg.Offset}
+		}
+		return responses, nil
+	}
+}
+
+// message encodes the request as a record.
+func (p Producer) message(ctx context.Context, request interface{}) (context.Context, *sarama.ProducerMessage, error) {
+	msg := &sarama.ProducerMessage{Topic: p.topic}
+	if p.key != nil {
+		if key := p.key(ctx, request); key != nil {
+			msg.Key = sarama.ByteEncoder(key)
+		}
+	}
+	if p.partition != nil {
+		msg.Partition = p.partition(ctx, request)
+	}
+	if err := p.enc(ctx, msg, request); err != nil {
+		return ctx, nil, err
+	}
+
+	for _, f := range p.before {
+		ctx = f(ctx, msg)
+	}
+	return ctx, msg, nil
+}
+
+// EncodeJSONRequest is an EncodeRequestFunc that serializes the request as
+// a JSON object to the value of the record. Many JSON-over-Kafka services
+// can use it as a sensible default.
+func EncodeJSONRequest(_ context.Context, msg *sarama.ProducerMessage, request interface{}) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	msg.Value = sarama.ByteEncoder(b)
+	return nil
+}
@@ -0,0 +1,80 @@
+package kafka_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+
+	kafkatransport "github.com/go-kit/kit/transport/kafka"
+)
+
+func TestProducer(t *testing.T) {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewSyncProducer(t, config)
+	defer producer.Close()
+
+	var produced *sarama.ProducerMessage
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		produced = msg
+		return nil
+	})
+
+	p := kafkatransport.NewProducer(producer, "sums", kafkatransport.EncodeJSONRequest,
+		kafkatransport.ProducerKey(func(_ context.Context, request interface{}) []byte {
+			return []byte("key")
+		}),
+		kafkatransport.ProducerBefore(kafkatransport.SetHeader("x-trace", "abc")),
+	)
+	response, err := p.Endpoint()(context.Background(), testRequest{A: 1, B: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := response.(kafkatransport.ProducerResponse); !ok {
+		t.Errorf("want ProducerResponse, have %T", response)
+	}
+
+	if want, have := "sums", produced.Topic; want != have {
+		t.Errorf("topic: want %q, have %q", want, have)
+	}
+	if want, have := sarama.ByteEncoder("key"), produced.Key; !reflect.DeepEqual(want, have) {
+		t.Errorf("key: want %v, have %v", want, have)
+	}
+	if want, have := sarama.ByteEncoder(`{"a":1,"b":2}`), produced.Value; !reflect.DeepEqual(want, have) {
+		t.Errorf("value: want %v, have %v", want, have)
+	}
+	wantHeaders := []sarama.RecordHeader{{Key: []byte("x-trace"), Value: []byte("abc")}}
+	if want, have := wantHeaders, produced.Headers; !reflect.DeepEqual(want, have) {
+		t.Errorf("headers: want %v, have %v", want, have)
+	}
+}
+
+func TestProducerBatch(t *testing.T) {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewSyncProducer(t, config)
+	defer producer.Close()
+	producer.ExpectSendMessageAndSucceed()
+	producer.ExpectSendMessageAndSucceed()
+	producer.ExpectSendMessageAndSucceed()
+
+	p := kafkatransport.NewProducer(producer, "sums", kafkatransport.EncodeJSONRequest)
+	response, err := p.BatchEndpoint()(context.Background(), []interface{}{
+		testRequest{A: 1},
+		testRequest{A: 2},
+		testRequest{A: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, len(response.([]kafkatransport.ProducerResponse)); want != have {
+		t.Errorf("want %d responses, have %d", want, have)
+	}
+
+	if _, err := p.BatchEndpoint()(context.Background(), testRequest{}); err == nil {
+		t.Error("want error for a request which isn't a batch, have none")
+	}
+}
@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+)
+
+// RequestFunc may take information from a Kafka record and put it into a
+// request context. RequestFuncs are executed in servers, prior to decoding
+// the request.
+type RequestFunc func(context.Context, *sarama.ConsumerMessage) context.Context
+
+// ProducerRequestFunc may take information from a request context and use
+// it to manipulate the record, e.g. its headers. ProducerRequestFuncs are
+// executed in producers, after encoding the request, but prior to
+// producing it.
+type ProducerRequestFunc func(context.Context, *sarama.ProducerMessage) context.Context
+
+// ProducerResponseFunc may take information from a produced record, e.g.
+// its partition and offset, and put it into the context. ProducerResponseFuncs
+// are executed in producers, after the record was produced.
+type ProducerResponseFunc func(context.Context, *sarama.ProducerMessage) context.Context
+
+// SetHeader returns a ProducerRequestFunc that sets the given header of the
+// record.
+func SetHeader(key, val string) ProducerRequestFunc {
+	return func(ctx context.Context, msg *sarama.ProducerMessage) context.Context {
+		for i, h := range msg.Headers {
+			if string(h.Key) == key {
+				msg.Headers[i].Value = []byte(val)
+				return ctx
+			}
+		}
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(val)})
+		return ctx
+	}
+}
+
+// Header returns the value of the given header of the record, or "".
+func Header(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Server wraps an endpoint and implements sarama.ConsumerGroupHandler, so
+// that it serves the records of the topics of a consumer group.
+type Server struct {
+	e          endpoint.Endpoint
+	dec        DecodeRequestFunc
+	before     []RequestFunc
+	finalizer  ServerFinalizerFunc
+	logger     log.Logger
+	markFailed bool
+}
+
+// NewServer constructs a new server, which serves records with the provided
+// endpoint.
+func NewServer(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		e:      e,
+		dec:    dec,
+		logger: log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the Kafka record before the
+// request is decoded.
+func ServerBefore(before ...RequestFunc) ServerOption {
+	return func(s *Server) { s.before = append(s.before, before...) }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure. Finer-grained
+// control of error handling, including logging in more detail, should be
+// performed in a custom ServerFinalizer, which has access to the context.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// ServerFinalizer is executed at the end of every record, with the error
+// with which it failed, if any. By default, no finalizer is registered.
+func ServerFinalizer(f ServerFinalizerFunc) ServerOption {
+	return func(s *Server) { s.finalizer = f }
+}
+
+// ServerMarkFailed marks the offsets of records which fail as consumed, as
+// those of records which are served, so that they're not consumed again,
+// e.g. when the finalizer produces them to a dead letter topic. By default,
+// a failure ends the session; see ConsumeClaim.
+func ServerMarkFailed() ServerOption {
+	return func(s *Server) { s.markFailed = true }
+}
+
+// Serve consumes the topics as a member of the consumer group, serving
+// their records, until the context is done, and returns its error. The
+// group's sessions are joined again after rebalances, and after sessions
+// end with a failure. If the group is closed, sarama.ErrClosedConsumerGroup
+// is returned.
+func (s Server) Serve(ctx context.Context, group sarama.ConsumerGroup, topics []string) error {
+	for {
+		if err := group.Consume(ctx, topics, s); err == sarama.ErrClosedConsumerGroup {
+			return err
+		} else if err != nil {
+			s.logger.Log("err", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (s Server) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (s Server) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler. It serves the records
+// of the claim in order, marking the offset of each once it's served, so
+// that it's committed. If a record fails, in the decoder or the endpoint,
+// the failure is returned, without marking it, which ends the session, so
+// that the record is consumed again, from the last committed offset, once
+// the group is joined again. A response implementing endpoint.Failer, whose
+// Failed method returns an error, fails like an error of the endpoint.
+func (s Server) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := s.serve(sess.Context(), msg); err != nil && !s.markFailed {
+				return err
+			}
+			sess.MarkMessage(msg, "")
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s Server) serve(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if s.finalizer != nil {
+		defer func() { s.finalizer(ctx, msg, err) }()
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := s.dec(ctx, msg)
+	if err != nil {
+		s.logger.Log("err", err)
+		return err
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		return err
+	}
+
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return f.Failed()
+	}
+	return nil
+}
+
+// ServerFinalizerFunc can be used to perform work at the end of a record,
+// before its offset is marked, given the error with which it failed, if
+// any. The principal intended use is for request logging.
+type ServerFinalizerFunc func(ctx context.Context, msg *sarama.ConsumerMessage, err error)
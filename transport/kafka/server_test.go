@@ -0,0 +1,132 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+
+	kafkatransport "github.com/go-kit/kit/transport/kafka"
+)
+
+type testRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func decodeTestRequest(_ context.Context, msg *sarama.ConsumerMessage) (interface{}, error) {
+	var req testRequest
+	err := json.Unmarshal(msg.Value, &req)
+	return req, err
+}
+
+// testSession is a sarama.ConsumerGroupSession recording marked offsets.
+type testSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	marked []int64
+}
+
+func (s *testSession) Context() context.Context { return s.ctx }
+
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+// testClaim is a sarama.ConsumerGroupClaim of the given records.
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	msgs chan *sarama.ConsumerMessage
+}
+
+func newTestClaim(values ...string) testClaim {
+	c := testClaim{msgs: make(chan *sarama.ConsumerMessage, len(values))}
+	for i, v := range values {
+		c.msgs <- &sarama.ConsumerMessage{
+			Offset:  int64(i),
+			Value:   []byte(v),
+			Headers: []*sarama.RecordHeader{{Key: []byte("x-trace"), Value: []byte("abc")}},
+		}
+	}
+	close(c.msgs)
+	return c
+}
+
+func (c testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgs }
+
+func TestServerMarksServed(t *testing.T) {
+	var (
+		sums   []int
+		traces []string
+	)
+	s := kafkatransport.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			req := request.(testRequest)
+			sums = append(sums, req.A+req.B)
+			return nil, nil
+		},
+		decodeTestRequest,
+		kafkatransport.ServerBefore(func(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+			traces = append(traces, kafkatransport.Header(msg, "x-trace"))
+			return ctx
+		}),
+	)
+
+	sess := &testSession{ctx: context.Background()}
+	if err := s.ConsumeClaim(sess, newTestClaim(`{"a":1,"b":2}`, `{"a":3,"b":4}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []int{3, 7}, sums; !reflect.DeepEqual(want, have) {
+		t.Errorf("sums: want %v, have %v", want, have)
+	}
+	if want, have := []string{"abc", "abc"}, traces; !reflect.DeepEqual(want, have) {
+		t.Errorf("traces: want %v, have %v", want, have)
+	}
+	if want, have := []int64{0, 1}, sess.marked; !reflect.DeepEqual(want, have) {
+		t.Errorf("marked: want %v, have %v", want, have)
+	}
+}
+
+func TestServerFailure(t *testing.T) {
+	failing := func(_ context.Context, request interface{}) (interface{}, error) {
+		if request.(testRequest).A < 0 {
+			return nil, errors.New("dang")
+		}
+		return nil, nil
+	}
+	values := []string{`{"a":1}`, `{"a":-1}`, `{"a":2}`, `x`}
+
+	t.Run("ends the session", func(t *testing.T) {
+		var finalized []error
+		s := kafkatransport.NewServer(failing, decodeTestRequest,
+			kafkatransport.ServerFinalizer(func(_ context.Context, _ *sarama.ConsumerMessage, err error) {
+				finalized = append(finalized, err)
+			}),
+		)
+		sess := &testSession{ctx: context.Background()}
+		err := s.ConsumeClaim(sess, newTestClaim(values...))
+		if want, have := errors.New("dang"), err; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+		if want, have := []int64{0}, sess.marked; !reflect.DeepEqual(want, have) {
+			t.Errorf("marked: want %v, have %v", want, have)
+		}
+		if want, have := []error{nil, errors.New("dang")}, finalized; !reflect.DeepEqual(want, have) {
+			t.Errorf("finalized: want %v, have %v", want, have)
+		}
+	})
+
+	t.Run("marks failed", func(t *testing.T) {
+		s := kafkatransport.NewServer(failing, decodeTestRequest, kafkatransport.ServerMarkFailed())
+		sess := &testSession{ctx: context.Background()}
+		if err := s.ConsumeClaim(sess, newTestClaim(values...)); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := []int64{0, 1, 2, 3}, sess.marked; !reflect.DeepEqual(want, have) {
+			t.Errorf("marked: want %v, have %v", want, have)
+		}
+	})
+}
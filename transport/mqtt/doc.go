@@ -0,0 +1,4 @@
+// Package mqtt provides an MQTT binding for endpoints, e.g. for services
+// facing IoT devices. Subscribers serve endpoints with the messages of
+// topics, and Publishers call them by publishing messages.
+package mqtt
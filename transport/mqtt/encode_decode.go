@@ -0,0 +1,29 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from an MQTT
+// message. It's designed to be used in MQTT subscribers, for
+// subscriber-side endpoints. One straightforward DecodeRequestFunc could be
+// something that JSON decodes from the message payload to the concrete
+// request type.
+type DecodeRequestFunc func(context.Context, mqtt.Message) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the MQTT
+// publication, and may set its topic, e.g. to that of a device. It's
+// designed to be used in MQTT publishers, for publisher-side endpoints. One
+// straightforward EncodeRequestFunc could be something that JSON encodes
+// the object directly to the publication payload.
+type EncodeRequestFunc func(context.Context, *Publication, interface{}) error
+
+// Publication is a message published by a Publisher.
+type Publication struct {
+	Topic    string
+	QoS      byte
+	Retained bool
+	Payload  []byte
+}
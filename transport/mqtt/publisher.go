@@ -0,0 +1,116 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DefaultPublisherTimeout is the timeout of publications, unless it's set
+// with PublisherTimeout.
+const DefaultPublisherTimeout = 10 * time.Second
+
+// Publisher wraps a topic, and provides a method that implements
+// endpoint.Endpoint, by publishing requests to the topic.
+type Publisher struct {
+	client   mqtt.Client
+	topic    string
+	enc      EncodeRequestFunc
+	before   []PublisherRequestFunc
+	qos      byte
+	retained bool
+	timeout  time.Duration
+}
+
+// NewPublisher constructs a usable Publisher for a single remote method.
+// The topic may be empty, if the encoder sets the topic of each
+// publication.
+func NewPublisher(
+	client mqtt.Client,
+	topic string,
+	enc EncodeRequestFunc,
+	options ...PublisherOption,
+) *Publisher {
+	p := &Publisher{
+		client:  client,
+		topic:   topic,
+		enc:     enc,
+		timeout: DefaultPublisherTimeout,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// PublisherOption sets an optional parameter for publishers.
+type PublisherOption func(*Publisher)
+
+// PublisherBefore sets the PublisherRequestFuncs that are applied to the
+// publication before it's published.
+func PublisherBefore(before ...PublisherRequestFunc) PublisherOption {
+	return func(p *Publisher) { p.before = append(p.before, before...) }
+}
+
+// PublisherQoS sets the QoS of publications: 0, at most once, 1, at least
+// once, or 2, exactly once. By default, it's 0.
+func PublisherQoS(qos byte) PublisherOption {
+	return func(p *Publisher) { p.qos = qos }
+}
+
+// PublisherRetained makes the broker retain the publications, as the last
+// known message of their topic, which it sends to later subscribers. By
+// default, publications aren't retained.
+func PublisherRetained() PublisherOption {
+	return func(p *Publisher) { p.retained = true }
+}
+
+// PublisherTimeout sets how long to wait for publications to complete, by
+// default DefaultPublisherTimeout. The deadline of the request context
+// applies if it's sooner.
+func PublisherTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) { p.timeout = timeout }
+}
+
+// Endpoint returns a usable endpoint that publishes the request, and
+// returns a nil response once the publication completes: once it's sent,
+// for QoS 0, or once the broker acknowledges it, for QoS 1 and 2.
+func (p Publisher) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+
+		pub := &Publication{Topic: p.topic, QoS: p.qos, Retained: p.retained}
+		if err := p.enc(ctx, pub, request); err != nil {
+			return nil, err
+		}
+
+		for _, f := range p.before {
+			ctx = f(ctx, pub)
+		}
+
+		token := p.client.Publish(pub.Topic, pub.QoS, pub.Retained, pub.Payload)
+		select {
+		case <-token.Done():
+			return nil, token.Error()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// EncodeJSONRequest is an EncodeRequestFunc that serializes the request as
+// a JSON object to the payload of the publication. Many JSON-over-MQTT
+// services can use it as a sensible default.
+func EncodeJSONRequest(_ context.Context, pub *Publication, request interface{}) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	pub.Payload = b
+	return nil
+}
@@ -0,0 +1,36 @@
+package mqtt_test
+
+import (
+	"context"
+	"testing"
+
+	mqtttransport "github.com/go-kit/kit/transport/mqtt"
+)
+
+func TestPublisher(t *testing.T) {
+	client := newTestClient()
+	p := mqtttransport.NewPublisher(client, "devices/a/config", mqtttransport.EncodeJSONRequest,
+		mqtttransport.PublisherQoS(2),
+		mqtttransport.PublisherRetained(),
+	)
+	if _, err := p.Endpoint()(context.Background(), map[string]int{"interval": 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 1, len(client.published); want != have {
+		t.Fatalf("want %d publications, have %d", want, have)
+	}
+	msg := client.published[0]
+	if want, have := "devices/a/config", msg.topic; want != have {
+		t.Errorf("topic: want %q, have %q", want, have)
+	}
+	if want, have := byte(2), msg.qos; want != have {
+		t.Errorf("QoS: want %d, have %d", want, have)
+	}
+	if !msg.retained {
+		t.Error("publication wasn't retained")
+	}
+	if want, have := `{"interval":60}`, string(msg.payload); want != have {
+		t.Errorf("payload: want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,47 @@
+package mqtt
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+// RequestFunc may take information from an MQTT message and put it into a
+// request context. RequestFuncs are executed in subscribers, prior to
+// decoding the request.
+type RequestFunc func(context.Context, mqtt.Message) context.Context
+
+// PublisherRequestFunc may take information from a request context and use
+// it to manipulate the publication. PublisherRequestFuncs are executed in
+// publishers, after encoding the request, but prior to publishing it.
+type PublisherRequestFunc func(context.Context, *Publication) context.Context
+
+// TopicWildcards returns the levels of the topic matched by the wildcards of
+// the topic filter, in order, and whether the topic matches the filter.
+// The multi-level wildcard, #, matches the remaining levels, joined by "/".
+// For example, the levels matched by the filter "devices/+/events/#" in the
+// topic "devices/42/events/door/open" are "42" and "door/open".
+func TopicWildcards(filter, topic string) ([]string, bool) {
+	var (
+		filterLevels = strings.Split(filter, "/")
+		topicLevels  = strings.Split(topic, "/")
+		wildcards    = []string{}
+	)
+	for i, f := range filterLevels {
+		switch {
+		case f == "#":
+			return append(wildcards, strings.Join(topicLevels[i:], "/")), true
+		case i >= len(topicLevels):
+			return nil, false
+		case f == "+":
+			wildcards = append(wildcards, topicLevels[i])
+		case f != topicLevels[i]:
+			return nil, false
+		}
+	}
+	if len(topicLevels) != len(filterLevels) {
+		return nil, false
+	}
+	return wildcards, true
+}
@@ -0,0 +1,132 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and provides an mqtt.MessageHandler.
+type Subscriber struct {
+	e              endpoint.Endpoint
+	dec            DecodeRequestFunc
+	before         []RequestFunc
+	finalizer      SubscriberFinalizerFunc
+	logger         log.Logger
+	ignoreRetained bool
+}
+
+// NewSubscriber constructs a new subscriber, which provides an
+// mqtt.MessageHandler and wraps the provided endpoint.
+func NewSubscriber(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		e:      e,
+		dec:    dec,
+		logger: log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the MQTT message before the
+// request is decoded.
+func SubscriberBefore(before ...RequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = append(s.before, before...) }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure. Finer-grained
+// control of error handling, including logging in more detail, should be
+// performed in a custom SubscriberFinalizer, which has access to the
+// context.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// SubscriberFinalizer is executed at the end of every message, with the
+// error with which it failed, if any. By default, no finalizer is
+// registered.
+func SubscriberFinalizer(f SubscriberFinalizerFunc) SubscriberOption {
+	return func(s *Subscriber) { s.finalizer = f }
+}
+
+// SubscriberIgnoreRetained ignores retained messages, which the broker
+// sends on subscription as the last known message of each matching topic,
+// so that only messages published after the subscription are served. By
+// default, retained messages are served too; decoders may tell them apart
+// by their Retained method.
+func SubscriberIgnoreRetained() SubscriberOption {
+	return func(s *Subscriber) { s.ignoreRetained = true }
+}
+
+// Subscribe subscribes the client to the topic filter, which may have
+// wildcards, with the QoS, serving the messages with ServeMessage, and
+// waits for the broker to acknowledge the subscription.
+func (s Subscriber) Subscribe(client mqtt.Client, filter string, qos byte) error {
+	token := client.Subscribe(filter, qos, s.ServeMessage)
+	token.Wait()
+	return token.Error()
+}
+
+// ServeMessage implements mqtt.MessageHandler. Messages of QoS 1 and 2 are
+// acknowledged once they're served, or if they can't be decoded, but not
+// if they fail in the endpoint, so that the broker sends them again when
+// the session is resumed. For that, the client must be configured with
+// SetAutoAckDisabled(true), and SetCleanSession(false); otherwise, messages
+// are acknowledged once they're received. A response implementing
+// endpoint.Failer, whose Failed method returns an error, fails like an
+// error of the endpoint.
+func (s Subscriber) ServeMessage(_ mqtt.Client, msg mqtt.Message) {
+	if s.ignoreRetained && msg.Retained() {
+		msg.Ack()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var err error
+	if s.finalizer != nil {
+		defer func() { s.finalizer(ctx, msg, err) }()
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := s.dec(ctx, msg)
+	if err != nil {
+		s.logger.Log("err", err)
+		msg.Ack() // it would fail again
+		return
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		return
+	}
+
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		err = f.Failed()
+		return
+	}
+	msg.Ack()
+}
+
+// SubscriberFinalizerFunc can be used to perform work at the end of a
+// message, given the error with which it failed, if any. The principal
+// intended use is for request logging.
+type SubscriberFinalizerFunc func(ctx context.Context, msg mqtt.Message, err error)
@@ -0,0 +1,186 @@
+package mqtt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang"
+
+	mqtttransport "github.com/go-kit/kit/transport/mqtt"
+)
+
+type testRequest struct {
+	Device string `json:"-"`
+	Value  int    `json:"value"`
+}
+
+// decodeTestRequest takes the device from the topic, of the filter
+// "devices/+/temperature".
+func decodeTestRequest(_ context.Context, msg mqtt.Message) (interface{}, error) {
+	var req testRequest
+	wildcards, ok := mqtttransport.TopicWildcards("devices/+/temperature", msg.Topic())
+	if !ok {
+		return nil, errors.New("unexpected topic")
+	}
+	req.Device = wildcards[0]
+	err := json.Unmarshal(msg.Payload(), &req)
+	return req, err
+}
+
+// testToken is a completed mqtt.Token.
+type testToken struct {
+	err error
+}
+
+func (t testToken) Wait() bool                     { return true }
+func (t testToken) WaitTimeout(time.Duration) bool { return true }
+func (t testToken) Error() error                   { return t.err }
+
+func (t testToken) Done() <-chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// testMessage is an mqtt.Message recording whether it's acked.
+type testMessage struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+	acked    bool
+}
+
+func (m *testMessage) Duplicate() bool   { return false }
+func (m *testMessage) Qos() byte         { return m.qos }
+func (m *testMessage) Retained() bool    { return m.retained }
+func (m *testMessage) Topic() string     { return m.topic }
+func (m *testMessage) MessageID() uint16 { return 1 }
+func (m *testMessage) Payload() []byte   { return m.payload }
+func (m *testMessage) Ack()              { m.acked = true }
+
+// testClient is an mqtt.Client which delivers publications to its
+// subscriptions synchronously, as a broker would.
+type testClient struct {
+	mqtt.Client
+	mtx           sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler
+	published     []*testMessage
+}
+
+func newTestClient() *testClient {
+	return &testClient{subscriptions: map[string]mqtt.MessageHandler{}}
+}
+
+func (c *testClient) Subscribe(filter string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.subscriptions[filter] = callback
+	return testToken{}
+}
+
+func (c *testClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	msg := &testMessage{topic: topic, qos: qos, retained: retained, payload: payload.([]byte)}
+	c.mtx.Lock()
+	c.published = append(c.published, msg)
+	var handlers []mqtt.MessageHandler
+	for filter, h := range c.subscriptions {
+		if _, ok := mqtttransport.TopicWildcards(filter, topic); ok {
+			handlers = append(handlers, h)
+		}
+	}
+	c.mtx.Unlock()
+	for _, h := range handlers {
+		h(c, msg)
+	}
+	return testToken{}
+}
+
+func TestSubscriber(t *testing.T) {
+	var served []testRequest
+	s := mqtttransport.NewSubscriber(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			req := request.(testRequest)
+			if req.Value < 0 {
+				return nil, errors.New("negative")
+			}
+			served = append(served, req)
+			return nil, nil
+		},
+		decodeTestRequest,
+	)
+
+	client := newTestClient()
+	if err := s.Subscribe(client, "devices/+/temperature", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	p := mqtttransport.NewPublisher(client, "", func(ctx context.Context, pub *mqtttransport.Publication, request interface{}) error {
+		req := request.(testRequest)
+		pub.Topic = "devices/" + req.Device + "/temperature"
+		return mqtttransport.EncodeJSONRequest(ctx, pub, request)
+	}, mqtttransport.PublisherQoS(1))
+	for _, req := range []testRequest{{"a", 20}, {"b", -1}, {"c", 21}} {
+		if _, err := p.Endpoint()(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want, have := []testRequest{{"a", 20}, {"c", 21}}, served; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	var acked []bool
+	for _, msg := range client.published {
+		acked = append(acked, msg.acked)
+	}
+	if want, have := []bool{true, false, true}, acked; !reflect.DeepEqual(want, have) {
+		t.Errorf("acked: want %v, have %v", want, have)
+	}
+}
+
+func TestSubscriberIgnoreRetained(t *testing.T) {
+	var served int
+	s := mqtttransport.NewSubscriber(
+		func(context.Context, interface{}) (interface{}, error) {
+			served++
+			return nil, nil
+		},
+		decodeTestRequest,
+		mqtttransport.SubscriberIgnoreRetained(),
+	)
+	retained := &testMessage{topic: "devices/a/temperature", retained: true, payload: []byte(`{}`)}
+	s.ServeMessage(nil, retained)
+	s.ServeMessage(nil, &testMessage{topic: "devices/a/temperature", payload: []byte(`{}`)})
+	if want, have := 1, served; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if !retained.acked {
+		t.Error("retained message wasn't acked")
+	}
+}
+
+func TestTopicWildcards(t *testing.T) {
+	for _, testcase := range []struct {
+		filter, topic string
+		want          []string
+		match         bool
+	}{
+		{"devices/+/temperature", "devices/42/temperature", []string{"42"}, true},
+		{"devices/+/temperature", "devices/42/humidity", nil, false},
+		{"devices/+/temperature", "devices/42/temperature/max", nil, false},
+		{"devices/+/events/#", "devices/42/events/door/open", []string{"42", "door/open"}, true},
+		{"devices/#", "devices", []string{""}, true},
+		{"devices/status", "devices/status", []string{}, true},
+		{"devices/status", "devices", nil, false},
+	} {
+		have, match := mqtttransport.TopicWildcards(testcase.filter, testcase.topic)
+		if match != testcase.match || !reflect.DeepEqual(testcase.want, have) {
+			t.Errorf("%s %s: want %v %v, have %v %v", testcase.filter, testcase.topic, testcase.want, testcase.match, have, match)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package nats provides a NATS binding for endpoints. Subscribers serve
+// endpoints on subjects, and Publishers call them with NATS request/reply.
+// Consumers serve endpoints with the messages of JetStream consumers, with
+// at-least-once semantics.
+package nats
@@ -0,0 +1,34 @@
+package nats
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from a NATS
+// message. It's designed to be used in NATS subscribers, for
+// subscriber-side endpoints. One straightforward DecodeRequestFunc could be
+// something that JSON decodes from the message data to the concrete request
+// type.
+type DecodeRequestFunc func(context.Context, *nats.Msg) (request interface{}, err error)
+
+// EncodeRequestFunc encodes the passed request object into the NATS message
+// object. It's designed to be used in NATS publishers, for publisher-side
+// endpoints. One straightforward EncodeRequestFunc could be something that
+// JSON encodes the object directly to the message data.
+type EncodeRequestFunc func(context.Context, *nats.Msg, interface{}) error
+
+// EncodeResponseFunc encodes the passed response object into the NATS reply
+// message, which is then published to the reply subject of the request.
+// It's designed to be used in NATS subscribers, for subscriber-side
+// endpoints. One straightforward EncodeResponseFunc could be something that
+// JSON encodes the object directly to the message data.
+type EncodeResponseFunc func(context.Context, *nats.Msg, interface{}) error
+
+// DecodeResponseFunc extracts a user-domain response object from the NATS
+// reply message. It's designed to be used in NATS publishers, for
+// publisher-side endpoints. One straightforward DecodeResponseFunc could be
+// something that JSON decodes from the message data to the concrete
+// response type.
+type DecodeResponseFunc func(context.Context, *nats.Msg) (response interface{}, err error)
@@ -0,0 +1,192 @@
+package nats
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Defaults for Consumers.
+const (
+	DefaultConsumerBatch   = 10
+	DefaultConsumerMaxWait = 5 * time.Second
+)
+
+// DefaultConsumerBackoff is the delay before the redeliveries of failed
+// messages, unless it's set with ConsumerBackoff. The last delay is repeated
+// for later redeliveries.
+var DefaultConsumerBackoff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+// Consumer serves the messages of a JetStream consumer with an endpoint,
+// with at-least-once semantics: each message is acknowledged once its
+// request succeeds, and negatively acknowledged, after a backoff delay, if
+// it fails, so that it's redelivered. Messages which fail to decode are
+// terminated, since they'd never succeed. The consumer, typically durable,
+// must use explicit acknowledgement, e.g.
+//
+//	sub, err := js.PullSubscribe("orders.*", "orders-worker", nats.ManualAck())
+//
+// The consumer's MaxDeliver bounds the number of deliveries of a message.
+// Endpoints should be idempotent, since a message may be delivered again,
+// e.g. if the acknowledgement is lost.
+type Consumer struct {
+	e         endpoint.Endpoint
+	dec       DecodeRequestFunc
+	before    []RequestFunc
+	finalizer ConsumerFinalizerFunc
+	batch     int
+	maxWait   time.Duration
+	backoff   []time.Duration
+	logger    log.Logger
+}
+
+// NewConsumer constructs a new consumer, which wraps the provided endpoint.
+// Its responses are discarded.
+func NewConsumer(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	options ...ConsumerOption,
+) *Consumer {
+	c := &Consumer{
+		e:       e,
+		dec:     dec,
+		batch:   DefaultConsumerBatch,
+		maxWait: DefaultConsumerMaxWait,
+		backoff: DefaultConsumerBackoff,
+		logger:  log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ConsumerOption sets an optional parameter for consumers.
+type ConsumerOption func(*Consumer)
+
+// ConsumerBefore functions are executed on the NATS message before the
+// request is decoded.
+func ConsumerBefore(before ...RequestFunc) ConsumerOption {
+	return func(c *Consumer) { c.before = append(c.before, before...) }
+}
+
+// ConsumerBatch sets the maximum number of messages Serve fetches at once,
+// by default DefaultConsumerBatch. They're served in order.
+func ConsumerBatch(n int) ConsumerOption {
+	return func(c *Consumer) { c.batch = n }
+}
+
+// ConsumerMaxWait sets how long each fetch of Serve waits for messages, by
+// default DefaultConsumerMaxWait.
+func ConsumerMaxWait(d time.Duration) ConsumerOption {
+	return func(c *Consumer) { c.maxWait = d }
+}
+
+// ConsumerBackoff sets the delays before the redeliveries of failed
+// messages, by their number of deliveries so far, by default
+// DefaultConsumerBackoff. The last delay is repeated for later
+// redeliveries.
+func ConsumerBackoff(delays ...time.Duration) ConsumerOption {
+	return func(c *Consumer) { c.backoff = delays }
+}
+
+// ConsumerErrorLogger is used to log errors, including those of the
+// endpoint. By default, no errors are logged.
+func ConsumerErrorLogger(logger log.Logger) ConsumerOption {
+	return func(c *Consumer) { c.logger = logger }
+}
+
+// ConsumerFinalizer is executed once each message is acknowledged, or not,
+// with the error the request failed with, if any.
+func ConsumerFinalizer(f ConsumerFinalizerFunc) ConsumerOption {
+	return func(c *Consumer) { c.finalizer = f }
+}
+
+// ConsumerFinalizerFunc can be used to perform work at the end of a
+// message, e.g. logging or metrics.
+type ConsumerFinalizerFunc func(ctx context.Context, msg *nats.Msg, err error)
+
+// Serve fetches messages from the pull subscription, and serves them, until
+// the context is done, when it returns the context's error, or fetching
+// fails otherwise, e.g. because the subscription is closed.
+func (c Consumer) Serve(ctx context.Context, sub *nats.Subscription) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fetchCtx, cancel := context.WithTimeout(ctx, c.maxWait)
+		msgs, err := sub.Fetch(c.batch, nats.Context(fetchCtx))
+		cancel()
+		if err != nil {
+			if err == context.DeadlineExceeded || err == nats.ErrTimeout {
+				continue // no messages
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		for _, msg := range msgs {
+			c.ServeMsg(ctx, msg)
+		}
+	}
+}
+
+// ServeMsg serves a single message, acknowledging it if its request
+// succeeds. It may be used with push subscriptions, with nats.ManualAck.
+func (c Consumer) ServeMsg(ctx context.Context, msg *nats.Msg) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, f := range c.before {
+		ctx = f(ctx, msg)
+	}
+
+	request, err := c.dec(ctx, msg)
+	if err != nil {
+		c.logger.Log("err", err)
+		c.finish(ctx, msg, err, msg.Term())
+		return
+	}
+
+	response, err := c.e(ctx, request)
+	if err == nil {
+		if f, ok := response.(endpoint.Failer); ok {
+			err = f.Failed()
+		}
+	}
+	if err != nil {
+		c.logger.Log("err", err)
+		c.finish(ctx, msg, err, msg.NakWithDelay(c.delay(msg)))
+		return
+	}
+	c.finish(ctx, msg, nil, msg.Ack())
+}
+
+func (c Consumer) finish(ctx context.Context, msg *nats.Msg, err, ackErr error) {
+	if ackErr != nil {
+		c.logger.Log("err", ackErr)
+	}
+	if c.finalizer != nil {
+		c.finalizer(ctx, msg, err)
+	}
+}
+
+// delay returns the backoff before the redelivery of the message.
+func (c Consumer) delay(msg *nats.Msg) time.Duration {
+	if len(c.backoff) == 0 {
+		return 0
+	}
+	i := 0
+	if meta, err := msg.Metadata(); err == nil && meta.NumDelivered > 0 {
+		i = int(meta.NumDelivered) - 1
+	}
+	if i >= len(c.backoff) {
+		i = len(c.backoff) - 1
+	}
+	return c.backoff[i]
+}
@@ -0,0 +1,118 @@
+package nats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	natstransport "github.com/go-kit/kit/transport/nats"
+)
+
+func TestConsumer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jetstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := natsserver.NewServer(&natsserver.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	defer s.Shutdown()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{`{"A":1}`, `{"A":2}`, `not json`, `{"A":3}`} {
+		if _, err := js.Publish("orders.new", []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub, err := js.PullSubscribe("orders.>", "worker", nats.ManualAck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mtx      sync.Mutex
+		attempts = map[int]int{}
+		outcomes = map[string]int{}
+		done     = make(chan struct{})
+	)
+	consumer := natstransport.NewConsumer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			a := request.(testRequest).A
+			attempts[a]++
+			if a == 2 && attempts[a] == 1 {
+				return nil, errors.New("transient")
+			}
+			return nil, nil
+		},
+		func(_ context.Context, msg *nats.Msg) (interface{}, error) {
+			var req testRequest
+			err := json.Unmarshal(msg.Data, &req)
+			return req, err
+		},
+		natstransport.ConsumerBatch(2),
+		natstransport.ConsumerMaxWait(50*time.Millisecond),
+		natstransport.ConsumerBackoff(10*time.Millisecond),
+		natstransport.ConsumerFinalizer(func(_ context.Context, msg *nats.Msg, err error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			outcome := "ok"
+			if err != nil {
+				outcome = "failed"
+			}
+			outcomes[outcome]++
+			if outcomes["ok"] == 3 {
+				close(done)
+			}
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- consumer.Serve(ctx, sub) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	time.Sleep(100 * time.Millisecond) // nothing else is redelivered
+	cancel()
+	if want, have := context.Canceled, <-errc; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want, have := (map[int]int{1: 1, 2: 2, 3: 1}), attempts; !reflect.DeepEqual(want, have) {
+		t.Errorf("want attempts %v, have %v", want, have)
+	}
+	if want, have := (map[string]int{"ok": 3, "failed": 2}), outcomes; !reflect.DeepEqual(want, have) {
+		t.Errorf("want outcomes %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,126 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// DefaultPublisherTimeout is the timeout of requests, unless it's set with
+// PublisherTimeout.
+const DefaultPublisherTimeout = 10 * time.Second
+
+// Publisher wraps a subject, and provides a method that implements
+// endpoint.Endpoint, by NATS request/reply.
+type Publisher struct {
+	publisher *nats.Conn
+	subject   string
+	enc       EncodeRequestFunc
+	dec       DecodeResponseFunc
+	before    []RequestFunc
+	after     []PublisherResponseFunc
+	timeout   time.Duration
+}
+
+// NewPublisher constructs a usable Publisher for a single remote method.
+func NewPublisher(
+	publisher *nats.Conn,
+	subject string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...PublisherOption,
+) *Publisher {
+	p := &Publisher{
+		publisher: publisher,
+		subject:   subject,
+		enc:       enc,
+		dec:       dec,
+		timeout:   DefaultPublisherTimeout,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// PublisherOption sets an optional parameter for publishers.
+type PublisherOption func(*Publisher)
+
+// PublisherBefore sets the RequestFuncs that are applied to the outgoing
+// NATS request before it's published.
+func PublisherBefore(before ...RequestFunc) PublisherOption {
+	return func(p *Publisher) { p.before = append(p.before, before...) }
+}
+
+// PublisherAfter sets the PublisherResponseFuncs applied to the reply
+// before it's decoded.
+func PublisherAfter(after ...PublisherResponseFunc) PublisherOption {
+	return func(p *Publisher) { p.after = append(p.after, after...) }
+}
+
+// PublisherTimeout sets how long to wait for the reply, by default
+// DefaultPublisherTimeout. The deadline of the request context applies if
+// it's sooner.
+func PublisherTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) { p.timeout = timeout }
+}
+
+// Endpoint returns a usable endpoint that invokes the remote endpoint. If
+// the reply has the ErrorHeader, set by DefaultErrorEncoder, a
+// ResponseError is returned, rather than the reply being decoded.
+func (p Publisher) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+
+		msg := &nats.Msg{Subject: p.subject}
+		if err := p.enc(ctx, msg, request); err != nil {
+			return nil, err
+		}
+
+		for _, f := range p.before {
+			ctx = f(ctx, msg)
+		}
+
+		resp, err := p.publisher.RequestMsgWithContext(ctx, msg)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range p.after {
+			ctx = f(ctx, resp)
+		}
+
+		if msg := resp.Header.Get(ErrorHeader); msg != "" {
+			return nil, ResponseError{Message: msg}
+		}
+		return p.dec(ctx, resp)
+	}
+}
+
+// ResponseError is returned by Publishers for replies encoded by
+// DefaultErrorEncoder.
+type ResponseError struct {
+	Message string
+}
+
+// Error implements error.
+func (e ResponseError) Error() string {
+	return e.Message
+}
+
+// EncodeJSONRequest is an EncodeRequestFunc that serializes the request as
+// a JSON object to the data of the message. Many JSON-over-NATS services
+// can use it as a sensible default.
+func EncodeJSONRequest(_ context.Context, msg *nats.Msg, request interface{}) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	msg.Data = b
+	return nil
+}
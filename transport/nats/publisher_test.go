@@ -0,0 +1,57 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	natstransport "github.com/go-kit/kit/transport/nats"
+)
+
+func TestPublisher(t *testing.T) {
+	nc, stop := newConn(t)
+	defer stop()
+
+	sub, err := nc.Subscribe("sum", natstransport.NewSubscriber(sum, decodeTestRequest, natstransport.EncodeJSONResponse).ServeMsg(nc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	var header string
+	e := natstransport.NewPublisher(nc, "sum",
+		natstransport.EncodeJSONRequest,
+		decodeTestResponse,
+		natstransport.PublisherBefore(natstransport.SetRequestHeader("X-Trace", "abc")),
+		natstransport.PublisherAfter(func(ctx context.Context, reply *nats.Msg) context.Context {
+			header = reply.Header.Get(natstransport.ErrorHeader)
+			return ctx
+		}),
+	).Endpoint()
+
+	response, err := e(context.Background(), testRequest{A: 2, B: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := (testResponse{5}), response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	_, err = e(context.Background(), testRequest{A: -1})
+	if want, have := (natstransport.ResponseError{Message: "negative"}), err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "negative", header; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Nothing replies on another subject.
+	e = natstransport.NewPublisher(nc, "nobody", natstransport.EncodeJSONRequest, decodeTestResponse,
+		natstransport.PublisherTimeout(100*time.Millisecond),
+	).Endpoint()
+	if _, err := e(context.Background(), testRequest{}); err == nil {
+		t.Error("want an error, have none")
+	}
+}
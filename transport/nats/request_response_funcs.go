@@ -0,0 +1,49 @@
+package nats
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RequestFunc may take information from a NATS message and put it into a
+// request context. In Subscribers, RequestFuncs are executed prior to
+// invoking the endpoint. In Publishers, RequestFuncs are executed after
+// creating the request but prior to publishing it, e.g. to set headers.
+type RequestFunc func(context.Context, *nats.Msg) context.Context
+
+// SubscriberResponseFunc may take information from a request context and
+// use it to manipulate the reply, e.g. its headers. SubscriberResponseFuncs
+// are only executed in subscribers, after invoking the endpoint but prior
+// to publishing a reply.
+type SubscriberResponseFunc func(context.Context, *nats.Msg) context.Context
+
+// PublisherResponseFunc may take information from a NATS reply and make
+// the response available for consumption. PublisherResponseFuncs are only
+// executed in publishers, after a reply has been received, but prior to it
+// being decoded.
+type PublisherResponseFunc func(context.Context, *nats.Msg) context.Context
+
+// SetRequestHeader returns a RequestFunc that sets the given header of the
+// request message.
+func SetRequestHeader(key, val string) RequestFunc {
+	return func(ctx context.Context, msg *nats.Msg) context.Context {
+		if msg.Header == nil {
+			msg.Header = nats.Header{}
+		}
+		msg.Header.Set(key, val)
+		return ctx
+	}
+}
+
+// SetResponseHeader returns a SubscriberResponseFunc that sets the given
+// header of the reply message.
+func SetResponseHeader(key, val string) SubscriberResponseFunc {
+	return func(ctx context.Context, msg *nats.Msg) context.Context {
+		if msg.Header == nil {
+			msg.Header = nats.Header{}
+		}
+		msg.Header.Set(key, val)
+		return ctx
+	}
+}
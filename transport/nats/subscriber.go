@@ -0,0 +1,200 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Subscriber wraps an endpoint and provides a nats.MsgHandler.
+type Subscriber struct {
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []RequestFunc
+	after        []SubscriberResponseFunc
+	errorEncoder ErrorEncoder
+	finalizer    SubscriberFinalizerFunc
+	logger       log.Logger
+}
+
+// NewSubscriber constructs a new subscriber, which provides a
+// nats.MsgHandler and wraps the provided endpoint.
+func NewSubscriber(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...SubscriberOption,
+) *Subscriber {
+	s := &Subscriber{
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore functions are executed on the NATS request message
+// before the request is decoded.
+func SubscriberBefore(before ...RequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = append(s.before, before...) }
+}
+
+// SubscriberAfter functions are executed on the NATS reply message after
+// the endpoint is invoked, but before the reply is published.
+func SubscriberAfter(after ...SubscriberResponseFunc) SubscriberOption {
+	return func(s *Subscriber) { s.after = append(s.after, after...) }
+}
+
+// SubscriberErrorEncoder is used to encode errors to the reply message
+// whenever they're encountered in the processing of a request. Clients can
+// use this to provide custom error formatting. By default, errors will be
+// encoded with the DefaultErrorEncoder.
+func SubscriberErrorEncoder(ee ErrorEncoder) SubscriberOption {
+	return func(s *Subscriber) { s.errorEncoder = ee }
+}
+
+// SubscriberErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged. This is intended as a diagnostic measure. Finer-grained
+// control of error handling, including logging in more detail, should be
+// performed in a custom SubscriberErrorEncoder or SubscriberFinalizer, both
+// of which have access to the context.
+func SubscriberErrorLogger(logger log.Logger) SubscriberOption {
+	return func(s *Subscriber) { s.logger = logger }
+}
+
+// SubscriberFinalizer is executed at the end of every request.
+// By default, no finalizer is registered.
+func SubscriberFinalizer(f SubscriberFinalizerFunc) SubscriberOption {
+	return func(s *Subscriber) { s.finalizer = f }
+}
+
+// ServeMsg returns a nats.MsgHandler which serves the messages of a
+// subscription with the endpoint, publishing replies on the connection. Use
+// it with nc.Subscribe, or with nc.QueueSubscribe, to balance the messages
+// of a subject over the members of a queue group:
+//
+//	nc.QueueSubscribe("addsvc.sum", "addsvc", subscriber.ServeMsg(nc))
+//
+// Messages without a reply subject are served, but not replied to. A
+// response implementing endpoint.Failer, whose Failed method returns an
+// error, is encoded with the error encoder.
+func (s Subscriber) ServeMsg(nc *nats.Conn) func(msg *nats.Msg) {
+	return func(msg *nats.Msg) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		reply := &nats.Msg{Subject: msg.Reply, Header: nats.Header{}}
+		if s.finalizer != nil {
+			defer func() { s.finalizer(ctx, msg) }()
+		}
+
+		for _, f := range s.before {
+			ctx = f(ctx, msg)
+		}
+
+		request, err := s.dec(ctx, msg)
+		if err != nil {
+			s.logger.Log("err", err)
+			s.replyError(ctx, err, nc, reply)
+			return
+		}
+
+		response, err := s.e(ctx, request)
+		if err != nil {
+			s.logger.Log("err", err)
+			s.replyError(ctx, err, nc, reply)
+			return
+		}
+
+		if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+			s.replyError(ctx, f.Failed(), nc, reply)
+			return
+		}
+
+		for _, f := range s.after {
+			ctx = f(ctx, reply)
+		}
+
+		if err := s.enc(ctx, reply, response); err != nil {
+			s.logger.Log("err", err)
+			s.replyError(ctx, err, nc, reply)
+			return
+		}
+		s.publish(nc, reply)
+	}
+}
+
+func (s Subscriber) replyError(ctx context.Context, err error, nc *nats.Conn, reply *nats.Msg) {
+	s.errorEncoder(ctx, err, reply)
+	s.publish(nc, reply)
+}
+
+func (s Subscriber) publish(nc *nats.Conn, reply *nats.Msg) {
+	if reply.Subject == "" {
+		return
+	}
+	if len(reply.Header) == 0 {
+		reply.Header = nil // servers without header support reject them
+	}
+	if err := nc.PublishMsg(reply); err != nil {
+		s.logger.Log("err", err)
+	}
+}
+
+// ErrorEncoder is responsible for encoding an error to the reply message.
+// Users are encouraged to use custom ErrorEncoders to encode errors to
+// their replies, and will likely want to pass and check for their own error
+// types.
+type ErrorEncoder func(ctx context.Context, err error, reply *nats.Msg)
+
+// SubscriberFinalizerFunc can be used to perform work at the end of a
+// request, after the reply has been published. The principal intended use
+// is for request logging.
+type SubscriberFinalizerFunc func(ctx context.Context, msg *nats.Msg)
+
+// EncodeJSONResponse is an EncodeResponseFunc that serializes the response
+// as a JSON object to the data of the reply. Many JSON-over-NATS services
+// can use it as a sensible default.
+func EncodeJSONResponse(_ context.Context, reply *nats.Msg, response interface{}) error {
+	b, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	reply.Data = b
+	return nil
+}
+
+// ErrorHeader is the header of replies which carry an error, set by
+// DefaultErrorEncoder, and checked by Publishers.
+const ErrorHeader = "Kit-Error"
+
+// DefaultErrorEncoder writes the error to the reply, as a JSON object of
+// the form {"error": "..."}, and sets the ErrorHeader of the reply to the
+// plain text of the error, so that Publishers return it as a
+// ResponseError.
+func DefaultErrorEncoder(_ context.Context, err error, reply *nats.Msg) {
+	b, marshalErr := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	if marshalErr != nil {
+		return
+	}
+	reply.Data = b
+	if reply.Header == nil {
+		reply.Header = nats.Header{}
+	}
+	reply.Header.Set(ErrorHeader, err.Error())
+}
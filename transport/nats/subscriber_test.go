@@ -0,0 +1,120 @@
+package nats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	natstransport "github.com/go-kit/kit/transport/nats"
+)
+
+type traceKey struct{}
+
+type testRequest struct {
+	A, B int
+}
+
+type testResponse struct {
+	Sum int `json:"sum"`
+}
+
+// newConn starts a NATS server, and returns a connection to it, and a
+// function to close both.
+func newConn(t *testing.T) (*nats.Conn, func()) {
+	s, err := natsserver.NewServer(&natsserver.Options{Host: "127.0.0.1", Port: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nc, func() { nc.Close(); s.Shutdown() }
+}
+
+func decodeTestRequest(_ context.Context, msg *nats.Msg) (interface{}, error) {
+	var req testRequest
+	err := json.Unmarshal(msg.Data, &req)
+	return req, err
+}
+
+func decodeTestResponse(_ context.Context, msg *nats.Msg) (interface{}, error) {
+	var resp testResponse
+	err := json.Unmarshal(msg.Data, &resp)
+	return resp, err
+}
+
+func sum(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(testRequest)
+	if req.A < 0 {
+		return nil, errors.New("negative")
+	}
+	return testResponse{req.A + req.B}, nil
+}
+
+func TestSubscriber(t *testing.T) {
+	nc, stop := newConn(t)
+	defer stop()
+
+	var finalized = make(chan string, 1)
+	sub, err := nc.QueueSubscribe("sum", "workers", natstransport.NewSubscriber(
+		sum,
+		decodeTestRequest,
+		natstransport.EncodeJSONResponse,
+		natstransport.SubscriberBefore(func(ctx context.Context, msg *nats.Msg) context.Context {
+			return context.WithValue(ctx, traceKey{}, msg.Header.Get("X-Trace"))
+		}),
+		natstransport.SubscriberAfter(func(ctx context.Context, reply *nats.Msg) context.Context {
+			reply.Header.Set("X-Trace", ctx.Value(traceKey{}).(string))
+			return ctx
+		}),
+		natstransport.SubscriberFinalizer(func(ctx context.Context, msg *nats.Msg) {
+			finalized <- msg.Subject
+		}),
+	).ServeMsg(nc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	msg := nats.NewMsg("sum")
+	msg.Data = []byte(`{"A":1,"B":2}`)
+	msg.Header.Set("X-Trace", "abc")
+	reply, err := nc.RequestMsg(msg, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"sum":3}`, string(reply.Data); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := "abc", reply.Header.Get("X-Trace"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "sum", <-finalized; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	for _, data := range []string{`{"A":-1}`, `not json`} {
+		reply, err = nc.Request("sum", []byte(data), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-finalized
+		if have := reply.Header.Get(natstransport.ErrorHeader); have == "" {
+			t.Errorf("%s: want %s header", data, natstransport.ErrorHeader)
+		}
+		if have := string(reply.Data); !strings.HasPrefix(have, `{"error":`) {
+			t.Errorf("%s: want JSON error, have %s", data, have)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package stdio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Client wraps a remote endpoint, served over a pair of streams, e.g. the
+// standard input and output of a subprocess, and provides a method that
+// implements endpoint.Endpoint. Calls are made one at a time, in order, as
+// the Server serves them.
+type Client struct {
+	w       io.Writer
+	scanner *bufio.Scanner
+	enc     EncodeRequestFunc
+	dec     DecodeResponseFunc
+	mtx     *sync.Mutex
+}
+
+// NewClient constructs a usable Client, which writes requests to w, e.g.
+// the standard input of a subprocess, and reads responses from r, e.g. its
+// standard output.
+func NewClient(
+	w io.Writer,
+	r io.Reader,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...ClientOption,
+) *Client {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, DefaultMaxLineSize)
+	c := &Client{
+		w:       w,
+		scanner: scanner,
+		enc:     enc,
+		dec:     dec,
+		mtx:     &sync.Mutex{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// ClientMaxLineSize sets the maximum size of response lines, in bytes. By
+// default, it's DefaultMaxLineSize.
+func ClientMaxLineSize(n int) ClientOption {
+	return func(c *Client) { c.scanner.Buffer(nil, n) }
+}
+
+// Endpoint returns a usable endpoint that invokes the remote endpoint. As
+// the streams can't be interrupted, the context doesn't bound the call,
+// once it's made.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		line, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := c.w.Write(append(line, '\n')); err != nil {
+			return nil, err
+		}
+		if !c.scanner.Scan() {
+			if err := c.scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+		return c.dec(ctx, append([]byte(nil), c.scanner.Bytes()...))
+	}
+}
@@ -0,0 +1,10 @@
+// Package stdio provides a binding for endpoints over streams of
+// newline-delimited messages, e.g. JSON, such as the standard input and
+// output of a process, for CLIs, subprocess plugins, and golden-file tests
+// of services.
+//
+// A Server reads each line of an io.Reader as a request, and writes the
+// response, or the error, as a line to an io.Writer, in order. A Client
+// speaks to a Server over the pipes of a subprocess, or any other pair of
+// streams.
+package stdio
@@ -0,0 +1,58 @@
+package stdio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from a line,
+// without its newline. It's designed to be used in servers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// JSON decodes the line to the concrete request type.
+type DecodeRequestFunc func(context.Context, []byte) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to a line, without
+// a newline, which must not contain one. It's designed to be used in
+// servers, for server-side endpoints. EncodeJSON may be used.
+type EncodeResponseFunc func(context.Context, interface{}) (line []byte, err error)
+
+// EncodeRequestFunc encodes the passed request object to a line, without a
+// newline, which must not contain one. It's designed to be used in clients,
+// for client-side endpoints. EncodeJSON may be used.
+type EncodeRequestFunc func(context.Context, interface{}) (line []byte, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from a line,
+// without its newline. It's designed to be used in clients, for client-side
+// endpoints.
+type DecodeResponseFunc func(context.Context, []byte) (response interface{}, err error)
+
+// ErrorEncoder is responsible for encoding an error as a line, which is
+// written in place of the response. Users are encouraged to use custom
+// ErrorEncoders to encode errors in the same envelope as their responses.
+type ErrorEncoder func(ctx context.Context, err error) (line []byte)
+
+// EncodeJSON is an EncodeResponseFunc and EncodeRequestFunc that serializes
+// the object as JSON, which has no newlines.
+func EncodeJSON(_ context.Context, v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DefaultErrorEncoder encodes the error as a JSON object with the error
+// message in its "error" field. If the error implements json.Marshaler, and
+// the marshaling succeeds, its JSON encoding is used instead.
+func DefaultErrorEncoder(_ context.Context, err error) []byte {
+	if marshaler, ok := err.(json.Marshaler); ok {
+		if line, marshalErr := marshaler.MarshalJSON(); marshalErr == nil {
+			// Compact it, as MarshalJSON may indent.
+			var buf bytes.Buffer
+			if json.Compact(&buf, line) == nil {
+				return buf.Bytes()
+			}
+		}
+	}
+	line, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	return line
+}
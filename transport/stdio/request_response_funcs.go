@@ -0,0 +1,7 @@
+package stdio
+
+import "context"
+
+// RequestFunc may take information from a request line and put it into a
+// request context. RequestFuncs are executed prior to decoding the request.
+type RequestFunc func(context.Context, []byte) context.Context
@@ -0,0 +1,185 @@
+package stdio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// DefaultMaxLineSize is the maximum size of lines, unless it's set with
+// ServerMaxLineSize or ClientMaxLineSize.
+const DefaultMaxLineSize = 1024 * 1024
+
+// Server wraps an endpoint, and serves requests read as lines.
+type Server struct {
+	e            endpoint.Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []RequestFunc
+	errorEncoder ErrorEncoder
+	finalizer    ServerFinalizerFunc
+	maxLineSize  int
+	logger       log.Logger
+}
+
+// NewServer constructs a new server, which serves requests with the
+// provided endpoint.
+func NewServer(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
+		maxLineSize:  DefaultMaxLineSize,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on each line before the request is
+// decoded.
+func ServerBefore(before ...RequestFunc) ServerOption {
+	return func(s *Server) { s.before = append(s.before, before...) }
+}
+
+// ServerErrorEncoder is used to encode errors from decoding requests, from
+// the endpoint, and from encoding responses, as lines written in place of
+// the response. By default, errors are encoded with DefaultErrorEncoder.
+func ServerErrorEncoder(ee ErrorEncoder) ServerOption {
+	return func(s *Server) { s.errorEncoder = ee }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no
+// errors are logged.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// ServerFinalizer is executed at the end of every request, with the error
+// with which it failed, if any. By default, no finalizer is registered.
+func ServerFinalizer(f ServerFinalizerFunc) ServerOption {
+	return func(s *Server) { s.finalizer = f }
+}
+
+// ServerMaxLineSize sets the maximum size of request lines, in bytes. Serve
+// fails with bufio.ErrTooLong on longer lines. By default, it's
+// DefaultMaxLineSize.
+func ServerMaxLineSize(n int) ServerOption {
+	return func(s *Server) { s.maxLineSize = n }
+}
+
+// Serve reads requests from r, one per line, and writes their responses,
+// or errors, to w, one per line, in order, until r is exhausted, when it
+// returns nil, or the context is done, when it returns its error. Blank
+// lines are skipped. If reading or writing fails, its error is returned.
+//
+// Reads of r aren't interrupted by the context; if the context is done
+// while a read blocks, the read is abandoned, to return with r, e.g. when
+// it's closed.
+func (s Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := make(chan []byte)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, s.maxLineSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-done:
+				return
+			}
+		}
+		errc <- scanner.Err()
+	}()
+
+	bw := bufio.NewWriter(w)
+	for {
+		select {
+		case line := <-lines:
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if err := s.write(bw, s.handle(ctx, line)); err != nil {
+				return err
+			}
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// handle serves a request, and returns the line of its response, or error.
+func (s Server) handle(ctx context.Context, line []byte) []byte {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var err error
+	if s.finalizer != nil {
+		defer func() { s.finalizer(ctx, err) }()
+	}
+
+	for _, f := range s.before {
+		ctx = f(ctx, line)
+	}
+
+	request, err := s.dec(ctx, line)
+	if err != nil {
+		s.logger.Log("err", err)
+		return s.errorEncoder(ctx, err)
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		return s.errorEncoder(ctx, err)
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		err = f.Failed()
+		return s.errorEncoder(ctx, err)
+	}
+
+	out, err := s.enc(ctx, response)
+	if err != nil {
+		s.logger.Log("err", err)
+		return s.errorEncoder(ctx, err)
+	}
+	return out
+}
+
+// write writes the line, and flushes it, so that each response is written
+// as soon as it's served, e.g. to the parent of a subprocess.
+func (s Server) write(w *bufio.Writer, line []byte) error {
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+	if err := w.WriteByte('\n'); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// ServerFinalizerFunc can be used to perform work at the end of a request,
+// after the response is encoded, but before it's written, given the error
+// with which it failed, if any. The principal intended use is for request
+// logging.
+type ServerFinalizerFunc func(ctx context.Context, err error)
@@ -0,0 +1,130 @@
+package stdio_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/transport/stdio"
+)
+
+type sumRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type sumResponse struct {
+	V int `json:"v"`
+}
+
+func decodeSumRequest(_ context.Context, line []byte) (interface{}, error) {
+	var req sumRequest
+	err := json.Unmarshal(line, &req)
+	return req, err
+}
+
+func decodeSumResponse(_ context.Context, line []byte) (interface{}, error) {
+	var resp sumResponse
+	err := json.Unmarshal(line, &resp)
+	return resp, err
+}
+
+func sum(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(sumRequest)
+	if req.A < 0 {
+		return nil, errors.New("negative")
+	}
+	return sumResponse{V: req.A + req.B}, nil
+}
+
+func TestServer(t *testing.T) {
+	var finalized []error
+	server := stdio.NewServer(sum, decodeSumRequest, stdio.EncodeJSON, stdio.ServerFinalizer(func(_ context.Context, err error) {
+		finalized = append(finalized, err)
+	}))
+
+	in := strings.NewReader(`{"a":1,"b":2}
+
+{"a":-1,"b":2}
+not json
+{"a":3,"b":4}
+`)
+	var out bytes.Buffer
+	if err := server.Serve(context.Background(), in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"v":3}
+{"error":"negative"}
+{"error":"invalid character 'o' in literal null (expecting 'u')"}
+{"v":7}
+`
+	if have := out.String(); want != have {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+	if want, have := 4, len(finalized); want != have {
+		t.Fatalf("finalized: want %d, have %d", want, have)
+	}
+	if finalized[0] != nil || finalized[1] == nil {
+		t.Errorf("finalized: have %v", finalized)
+	}
+}
+
+func TestServerContext(t *testing.T) {
+	server := stdio.NewServer(sum, decodeSumRequest, stdio.EncodeJSON)
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(ctx, r, ioutil.Discard) }()
+	cancel()
+	select {
+	case err := <-errc:
+		if want, have := context.Canceled, err; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestServerMaxLineSize(t *testing.T) {
+	server := stdio.NewServer(sum, decodeSumRequest, stdio.EncodeJSON, stdio.ServerMaxLineSize(8))
+	err := server.Serve(context.Background(), strings.NewReader(`{"a":1,"b":2}`+"\n"), ioutil.Discard)
+	if err == nil {
+		t.Error("want error, have none")
+	}
+}
+
+func TestClient(t *testing.T) {
+	requestR, requestW := io.Pipe()
+	responseR, responseW := io.Pipe()
+	server := stdio.NewServer(sum, decodeSumRequest, stdio.EncodeJSON)
+	go func() {
+		server.Serve(context.Background(), requestR, responseW)
+		responseW.Close()
+	}()
+
+	e := stdio.NewClient(requestW, responseR, stdio.EncodeJSON, decodeSumResponse).Endpoint()
+	for i := 0; i < 3; i++ {
+		response, err := e(context.Background(), sumRequest{A: i, B: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := i+10, response.(sumResponse).V; want != have {
+			t.Errorf("want %d, have %d", want, have)
+		}
+	}
+
+	requestW.Close()
+	if _, err := e(context.Background(), sumRequest{}); err == nil {
+		t.Error("want error after close, have none")
+	}
+}
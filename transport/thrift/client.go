@@ -0,0 +1,82 @@
+package thrift
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// CallFunc calls the server with a generated client, constructed over the
+// thrift.TClient, and returns the response, e.g.
+//
+//	func(ctx context.Context, c thrift.TClient, request interface{}) (interface{}, error) {
+//	    req := request.(sumRequest)
+//	    return addsvc.NewAddServiceClient(c).Sum(ctx, req.A, req.B)
+//	}
+//
+// It's the binding of the request and response of an endpoint to those of
+// the generated client, as a pair of encoder and decoder is elsewhere.
+type CallFunc func(ctx context.Context, c thrift.TClient, request interface{}) (response interface{}, err error)
+
+// Client wraps a call of a Thrift server, over the connections of a Pool,
+// and provides a method that implements endpoint.Endpoint.
+type Client struct {
+	pool      *Pool
+	call      CallFunc
+	finalizer ClientFinalizerFunc
+}
+
+// NewClient constructs a usable Client for a single remote method, made by
+// the call, over a connection of the pool.
+func NewClient(
+	pool *Pool,
+	call CallFunc,
+	options ...ClientOption,
+) *Client {
+	c := &Client{
+		pool: pool,
+		call: call,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// ClientFinalizer is executed at the end of every call, with the error with
+// which it failed, if any. By default, no finalizer is registered.
+func ClientFinalizer(f ClientFinalizerFunc) ClientOption {
+	return func(c *Client) { c.finalizer = f }
+}
+
+// Endpoint returns a usable endpoint that invokes the remote method. The
+// context of the endpoint bounds the wait for a connection of the pool, as
+// well as the call.
+func (c Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		if c.finalizer != nil {
+			defer func() { c.finalizer(ctx, err) }()
+		}
+
+		conn, err := c.pool.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		response, err = c.call(ctx, conn.client, request)
+		c.pool.put(conn, err)
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+}
+
+// ClientFinalizerFunc can be used to perform work at the end of a client
+// call, given the error with which it failed, if any. The principal intended
+// use is for error logging.
+type ClientFinalizerFunc func(ctx context.Context, err error)
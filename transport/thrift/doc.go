@@ -0,0 +1,11 @@
+// Package thrift provides a Thrift binding for endpoints, over the
+// processors and clients generated by the Thrift compiler, for versions of
+// Apache Thrift with context support.
+//
+// A Server serves a processor, e.g. one generated for a handwritten binding
+// of endpoints to the service definition, or several, multiplexed with
+// Multiplex, with the Protocol and transport of its options. A Client wraps
+// a call of a generated client as an endpoint, over the connections of a
+// Pool, which dials the server with the same Protocol and transport, and,
+// for multiplexed servers, the name of the service.
+package thrift
@@ -0,0 +1,210 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// ErrPoolClosed is returned by the Clients of closed Pools.
+var ErrPoolClosed = errors.New("thrift: pool closed")
+
+// DefaultPoolMaxIdle is how many idle connections Pools keep, unless it's
+// set with PoolMaxIdle.
+const DefaultPoolMaxIdle = 2
+
+// Pool is a pool of connections to a Thrift server, over which Clients
+// call it. As generated clients aren't safe for concurrent use, each call
+// has a connection of its own, which is returned to the pool after the
+// call, unless it failed in the transport or the protocol. It's safe for
+// concurrent use.
+type Pool struct {
+	addr    string
+	codec   codec
+	service string
+	maxIdle int
+	active  chan struct{}
+
+	mtx    sync.Mutex
+	idle   []*poolConn
+	closed bool
+}
+
+type poolConn struct {
+	transport thrift.TTransport
+	client    thrift.TClient
+}
+
+// NewPool returns a pool of connections to the server at the address,
+// which are dialed as they're needed.
+func NewPool(addr string, options ...PoolOption) *Pool {
+	p := &Pool{
+		addr:    addr,
+		maxIdle: DefaultPoolMaxIdle,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// PoolOption sets an optional parameter for pools.
+type PoolOption func(*Pool)
+
+// PoolProtocol sets the protocol of messages, which must be that of the
+// server. By default, it's BinaryProtocol.
+func PoolProtocol(pr Protocol) PoolOption {
+	return func(p *Pool) { p.codec.protocol = pr }
+}
+
+// PoolFramed frames messages, for servers which frame them. By default,
+// messages aren't framed.
+func PoolFramed() PoolOption {
+	return func(p *Pool) { p.codec.framed = true }
+}
+
+// PoolBuffered buffers the transport of connections, with buffers of the
+// size. By default, it's unbuffered.
+func PoolBuffered(size int) PoolOption {
+	return func(p *Pool) { p.codec.bufferSize = size }
+}
+
+// PoolConfiguration sets the configuration of protocols and transports,
+// including the connect and socket timeouts of connections. By default,
+// Thrift's defaults are used.
+func PoolConfiguration(conf *thrift.TConfiguration) PoolOption {
+	return func(p *Pool) { p.codec.conf = conf }
+}
+
+// PoolService sets the name of the service of calls, for multiplexed
+// servers, serving it among others. By default, calls aren't multiplexed.
+func PoolService(name string) PoolOption {
+	return func(p *Pool) { p.service = name }
+}
+
+// PoolMaxIdle sets how many idle connections are kept, to be reused by
+// later calls. By default, it's DefaultPoolMaxIdle.
+func PoolMaxIdle(n int) PoolOption {
+	return func(p *Pool) { p.maxIdle = n }
+}
+
+// PoolMaxActive sets how many connections may be open at once, so that
+// calls wait for one, or their context to be done, beyond it. By default,
+// there's no limit.
+func PoolMaxActive(n int) PoolOption {
+	return func(p *Pool) { p.active = make(chan struct{}, n) }
+}
+
+// Close closes the idle connections of the pool, and those of calls in
+// flight once they're done. Calls with a closed pool return ErrPoolClosed.
+func (p *Pool) Close() error {
+	p.mtx.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.mtx.Unlock()
+
+	var err error
+	for _, c := range idle {
+		if closeErr := c.transport.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// get returns an idle connection, or dials a new one.
+func (p *Pool) get(ctx context.Context) (*poolConn, error) {
+	if p.active != nil {
+		select {
+		case p.active <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		p.release()
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mtx.Unlock()
+		return c, nil
+	}
+	p.mtx.Unlock()
+
+	c, err := p.dial()
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	return c, nil
+}
+
+// put returns the connection of a call to the pool, or closes it, if the
+// call failed in the transport or the protocol, or the pool is full, or
+// closed.
+func (p *Pool) put(c *poolConn, err error) {
+	defer p.release()
+
+	if !broken(err) {
+		p.mtx.Lock()
+		if !p.closed && len(p.idle) < p.maxIdle {
+			p.idle = append(p.idle, c)
+			p.mtx.Unlock()
+			return
+		}
+		p.mtx.Unlock()
+	}
+	c.transport.Close()
+}
+
+func (p *Pool) release() {
+	if p.active != nil {
+		<-p.active
+	}
+}
+
+func (p *Pool) dial() (*poolConn, error) {
+	socket := thrift.NewTSocketConf(p.addr, p.codec.conf)
+	transport, err := p.codec.transportFactory().GetTransport(socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := transport.Open(); err != nil {
+		return nil, err
+	}
+	protocol := p.codec.protocolFactory().GetProtocol(transport)
+	if p.service != "" {
+		protocol = thrift.NewTMultiplexedProtocol(protocol, p.service)
+	}
+	return &poolConn{
+		transport: transport,
+		client:    thrift.NewTStandardClient(protocol, protocol),
+	}, nil
+}
+
+// broken returns true if the call failed in a way which may leave the
+// connection in an unknown state: any error which isn't an exception of
+// the service, or a TApplicationException, which the server may return
+// in place of a reply.
+func broken(err error) bool {
+	if err == nil {
+		return false
+	}
+	var te thrift.TException
+	if !errors.As(err, &te) {
+		return true
+	}
+	switch te.TExceptionType() {
+	case thrift.TExceptionTypeCompiled, thrift.TExceptionTypeApplication:
+		return false
+	default:
+		return true
+	}
+}
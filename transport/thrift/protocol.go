@@ -0,0 +1,63 @@
+package thrift
+
+import (
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// Protocol is the protocol with which Thrift messages are serialized.
+// Servers and clients must use the same protocol.
+type Protocol int
+
+// The protocols of package github.com/apache/thrift/lib/go/thrift.
+const (
+	BinaryProtocol Protocol = iota
+	CompactProtocol
+	JSONProtocol
+)
+
+// String implements fmt.Stringer.
+func (p Protocol) String() string {
+	switch p {
+	case BinaryProtocol:
+		return "binary"
+	case CompactProtocol:
+		return "compact"
+	case JSONProtocol:
+		return "json"
+	default:
+		return fmt.Sprintf("Protocol(%d)", int(p))
+	}
+}
+
+// codec is the protocol and transport options, shared by servers and
+// pools, which must agree on them.
+type codec struct {
+	protocol   Protocol
+	framed     bool
+	bufferSize int
+	conf       *thrift.TConfiguration
+}
+
+func (c codec) protocolFactory() thrift.TProtocolFactory {
+	switch c.protocol {
+	case CompactProtocol:
+		return thrift.NewTCompactProtocolFactoryConf(c.conf)
+	case JSONProtocol:
+		return thrift.NewTJSONProtocolFactory()
+	default:
+		return thrift.NewTBinaryProtocolFactoryConf(c.conf)
+	}
+}
+
+func (c codec) transportFactory() thrift.TTransportFactory {
+	factory := thrift.NewTTransportFactory()
+	if c.bufferSize > 0 {
+		factory = thrift.NewTBufferedTransportFactory(c.bufferSize)
+	}
+	if c.framed {
+		factory = thrift.NewTFramedTransportFactoryConf(factory, c.conf)
+	}
+	return factory
+}
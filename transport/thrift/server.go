@@ -0,0 +1,118 @@
+package thrift
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Server serves a Thrift processor, with the protocol and transport of its
+// options.
+type Server struct {
+	processor thrift.TProcessor
+	codec     codec
+	logger    log.Logger
+}
+
+// NewServer constructs a new server, which serves the processor, e.g. one
+// generated by the Thrift compiler, or returned by Multiplex.
+func NewServer(
+	processor thrift.TProcessor,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		processor: processor,
+		logger:    log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerProtocol sets the protocol of messages. By default, it's
+// BinaryProtocol.
+func ServerProtocol(p Protocol) ServerOption {
+	return func(s *Server) { s.codec.protocol = p }
+}
+
+// ServerFramed frames messages, as non-blocking Thrift servers and clients
+// require. By default, messages aren't framed.
+func ServerFramed() ServerOption {
+	return func(s *Server) { s.codec.framed = true }
+}
+
+// ServerBuffered buffers the transport of connections, with buffers of the
+// size. By default, it's unbuffered.
+func ServerBuffered(size int) ServerOption {
+	return func(s *Server) { s.codec.bufferSize = size }
+}
+
+// ServerConfiguration sets the configuration of protocols and transports,
+// e.g. the maximum size of messages and frames. By default, Thrift's
+// defaults are used.
+func ServerConfiguration(conf *thrift.TConfiguration) ServerOption {
+	return func(s *Server) { s.codec.conf = conf }
+}
+
+// ServerErrorLogger is used to log the exceptions with which the processor
+// fails. By default, no errors are logged. This is intended as a diagnostic
+// measure.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// Serve accepts connections on the server transport, e.g. a
+// thrift.TServerSocket, and serves their requests, until the context is
+// done, and returns its error, once the connections are closed. If the
+// transport fails to listen, its error is returned.
+func (s Server) Serve(ctx context.Context, transport thrift.TServerTransport) error {
+	server := thrift.NewTSimpleServer4(
+		loggingProcessor{s.processor, s.logger},
+		transport,
+		s.codec.transportFactory(),
+		s.codec.protocolFactory(),
+	)
+	if err := server.Listen(); err != nil {
+		return err
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.AcceptLoop() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		server.Stop()
+		<-errc
+		return ctx.Err()
+	}
+}
+
+// Multiplex returns a processor serving each processor as the service of
+// its name, for clients whose Pools have the option PoolService.
+func Multiplex(services map[string]thrift.TProcessor) thrift.TProcessor {
+	processor := thrift.NewTMultiplexedProcessor()
+	for name, p := range services {
+		processor.RegisterProcessor(name, p)
+	}
+	return processor
+}
+
+type loggingProcessor struct {
+	thrift.TProcessor
+	logger log.Logger
+}
+
+func (p loggingProcessor) Process(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+	ok, err := p.TProcessor.Process(ctx, in, out)
+	if err != nil {
+		p.logger.Log("err", err)
+	}
+	return ok, err
+}
@@ -0,0 +1,254 @@
+package thrift_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	kitthrift "github.com/go-kit/kit/transport/thrift"
+)
+
+// stringStruct is a struct of a single string field of the ID, as are the
+// arguments and results of the echo method.
+type stringStruct struct {
+	id int16
+	s  string
+}
+
+func (s *stringStruct) Write(ctx context.Context, p thrift.TProtocol) error {
+	if err := p.WriteStructBegin(ctx, "s"); err != nil {
+		return err
+	}
+	if err := p.WriteFieldBegin(ctx, "s", thrift.STRING, s.id); err != nil {
+		return err
+	}
+	if err := p.WriteString(ctx, s.s); err != nil {
+		return err
+	}
+	if err := p.WriteFieldEnd(ctx); err != nil {
+		return err
+	}
+	if err := p.WriteFieldStop(ctx); err != nil {
+		return err
+	}
+	return p.WriteStructEnd(ctx)
+}
+
+func (s *stringStruct) Read(ctx context.Context, p thrift.TProtocol) error {
+	if _, err := p.ReadStructBegin(ctx); err != nil {
+		return err
+	}
+	for {
+		_, typ, id, err := p.ReadFieldBegin(ctx)
+		if err != nil {
+			return err
+		}
+		if typ == thrift.STOP {
+			break
+		}
+		if typ == thrift.STRING && id == s.id {
+			if s.s, err = p.ReadString(ctx); err != nil {
+				return err
+			}
+		} else if err := p.Skip(ctx, typ); err != nil {
+			return err
+		}
+		if err := p.ReadFieldEnd(ctx); err != nil {
+			return err
+		}
+	}
+	return p.ReadStructEnd(ctx)
+}
+
+// echoProcessor serves the echo method, replying with the prefixed argument,
+// or an application exception, if it's "fail".
+type echoProcessor struct {
+	prefix string
+}
+
+func (p echoProcessor) Process(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+	name, _, seqID, err := in.ReadMessageBegin(ctx)
+	if err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	args := &stringStruct{id: 1}
+	if err := args.Read(ctx, in); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := in.ReadMessageEnd(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+
+	if args.s == "fail" {
+		exception := thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "dang")
+		out.WriteMessageBegin(ctx, name, thrift.EXCEPTION, seqID)
+		exception.Write(ctx, out)
+		out.WriteMessageEnd(ctx)
+		out.Flush(ctx)
+		return true, exception
+	}
+	out.WriteMessageBegin(ctx, name, thrift.REPLY, seqID)
+	(&stringStruct{id: 0, s: p.prefix + args.s}).Write(ctx, out)
+	out.WriteMessageEnd(ctx)
+	return true, thrift.WrapTException(out.Flush(ctx))
+}
+
+func (p echoProcessor) ProcessorMap() map[string]thrift.TProcessorFunction { return nil }
+
+func (p echoProcessor) AddToProcessorMap(string, thrift.TProcessorFunction) {}
+
+func echo(ctx context.Context, c thrift.TClient, request interface{}) (interface{}, error) {
+	result := &stringStruct{id: 0}
+	if _, err := c.Call(ctx, "echo", &stringStruct{id: 1, s: request.(string)}, result); err != nil {
+		return nil, err
+	}
+	return result.s, nil
+}
+
+// serve serves the processor on a local port, until the returned function
+// is called, and returns its address.
+func serve(t *testing.T, processor thrift.TProcessor, options ...kitthrift.ServerOption) (string, func()) {
+	socket, err := thrift.NewTServerSocket("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := socket.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- kitthrift.NewServer(processor, options...).Serve(ctx, socket) }()
+	return socket.Addr().String(), func() {
+		cancel()
+		if want, have := context.Canceled, <-errc; want != have {
+			t.Errorf("Serve: want %v, have %v", want, have)
+		}
+	}
+}
+
+func TestProtocols(t *testing.T) {
+	for _, testcase := range []struct {
+		name   string
+		server []kitthrift.ServerOption
+		pool   []kitthrift.PoolOption
+	}{
+		{"binary", nil, nil},
+		{
+			"compact framed",
+			[]kitthrift.ServerOption{kitthrift.ServerProtocol(kitthrift.CompactProtocol), kitthrift.ServerFramed()},
+			[]kitthrift.PoolOption{kitthrift.PoolProtocol(kitthrift.CompactProtocol), kitthrift.PoolFramed()},
+		},
+		{
+			"json buffered",
+			[]kitthrift.ServerOption{kitthrift.ServerProtocol(kitthrift.JSONProtocol), kitthrift.ServerBuffered(1024)},
+			[]kitthrift.PoolOption{kitthrift.PoolProtocol(kitthrift.JSONProtocol), kitthrift.PoolBuffered(1024)},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			addr, stop := serve(t, echoProcessor{prefix: "echo: "}, testcase.server...)
+			defer stop()
+			pool := kitthrift.NewPool(addr, testcase.pool...)
+			defer pool.Close()
+
+			e := kitthrift.NewClient(pool, echo).Endpoint()
+			for i := 0; i < 3; i++ {
+				response, err := e(context.Background(), "hello")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want, have := "echo: hello", response.(string); want != have {
+					t.Errorf("want %q, have %q", want, have)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiplex(t *testing.T) {
+	addr, stop := serve(t, kitthrift.Multiplex(map[string]thrift.TProcessor{
+		"a": echoProcessor{prefix: "a: "},
+		"b": echoProcessor{prefix: "b: "},
+	}))
+	defer stop()
+
+	for _, service := range []string{"a", "b"} {
+		pool := kitthrift.NewPool(addr, kitthrift.PoolService(service))
+		response, err := kitthrift.NewClient(pool, echo).Endpoint()(context.Background(), "hello")
+		pool.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := service+": hello", response.(string); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestClientApplicationException(t *testing.T) {
+	addr, stop := serve(t, echoProcessor{})
+	defer stop()
+	pool := kitthrift.NewPool(addr, kitthrift.PoolMaxActive(1))
+	defer pool.Close()
+
+	var finalized error
+	e := kitthrift.NewClient(pool, echo, kitthrift.ClientFinalizer(func(_ context.Context, err error) {
+		finalized = err
+	})).Endpoint()
+
+	_, err := e(context.Background(), "fail")
+	var ae thrift.TApplicationException
+	if !errors.As(err, &ae) {
+		t.Fatalf("want TApplicationException, have %v", err)
+	}
+	if want, have := "dang", ae.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if finalized != err {
+		t.Errorf("finalizer: want %v, have %v", err, finalized)
+	}
+
+	// The connection is reused, and the single active slot released.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if response, err := e(ctx, "ok"); err != nil || response != "ok" {
+		t.Errorf("want ok, have %v, %v", response, err)
+	}
+}
+
+func TestPoolClosed(t *testing.T) {
+	addr, stop := serve(t, echoProcessor{})
+	defer stop()
+	pool := kitthrift.NewPool(addr)
+	pool.Close()
+
+	if _, err := kitthrift.NewClient(pool, echo).Endpoint()(context.Background(), "hello"); err != kitthrift.ErrPoolClosed {
+		t.Errorf("want %v, have %v", kitthrift.ErrPoolClosed, err)
+	}
+}
+
+func TestPoolMaxActive(t *testing.T) {
+	addr, stop := serve(t, echoProcessor{})
+	defer stop()
+	pool := kitthrift.NewPool(addr, kitthrift.PoolMaxActive(1))
+	defer pool.Close()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	blocking := kitthrift.NewClient(pool, func(ctx context.Context, c thrift.TClient, request interface{}) (interface{}, error) {
+		close(started)
+		<-block
+		return echo(ctx, c, request)
+	}).Endpoint()
+	go blocking(context.Background(), "hello")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := kitthrift.NewClient(pool, echo).Endpoint()(ctx, "hello"); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	close(block)
+}
@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Client wraps a WebSocket URL and provides methods that implement
+// endpoint.Endpoint.
+type Client struct {
+	url    string
+	origin string
+	enc    EncodeRequestFunc
+	dec    DecodeResponseFunc
+	before []ClientRequestFunc
+	binary bool
+
+	mtx  sync.Mutex
+	conn *websocket.Conn
+}
+
+// NewClient constructs a usable Client for a single remote endpoint, at the
+// ws or wss URL.
+func NewClient(
+	url string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	options ...ClientOption,
+) *Client {
+	c := &Client{
+		url:    url,
+		origin: "http" + strings.TrimPrefix(url, "ws"),
+		enc:    enc,
+		dec:    dec,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ClientOption sets an optional parameter for clients.
+type ClientOption func(*Client)
+
+// ClientBefore sets the ClientRequestFuncs that are applied to the headers of
+// the HTTP request which opens each connection.
+func ClientBefore(before ...ClientRequestFunc) ClientOption {
+	return func(c *Client) { c.before = append(c.before, before...) }
+}
+
+// ClientOrigin sets the Origin header sent when opening connections. By
+// default, the http or https equivalent of the URL is used, which satisfies
+// the default handshake of a Server.
+func ClientOrigin(origin string) ClientOption {
+	return func(c *Client) { c.origin = origin }
+}
+
+// ClientBinaryFrames causes requests to be sent as binary frames. By default,
+// they're sent as text frames.
+func ClientBinaryFrames() ClientOption {
+	return func(c *Client) { c.binary = true }
+}
+
+// Endpoint returns a usable endpoint that sends each request as a message on
+// a shared connection, and decodes the next message received as its response.
+// Requests are sent one at a time, in the per-message mode of a Server. The
+// connection is opened by the first request, and reopened by the next request
+// after any error. If the request context is done before the response is
+// received, the connection is closed.
+func (c *Client) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		message, err := c.enc(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		if c.conn == nil {
+			conn, err := c.dial(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.conn = conn
+		}
+
+		message, err = exchange(ctx, c.conn, message)
+		if err != nil {
+			c.conn.Close()
+			c.conn = nil
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		return c.dec(ctx, message)
+	}
+}
+
+// StreamEndpoint returns a usable endpoint that opens a connection for each
+// request, sends the request as a message, and returns a <-chan interface{}
+// of the decoded messages the server responds with, in the streaming mode of
+// a Server. The channel is closed when the server closes the connection, or
+// the request context is done, which closes the connection. If a message
+// can't be decoded, the error is sent on the channel, and the stream ends.
+func (c *Client) StreamEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		conn, err := c.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		message, err := c.enc(ctx, request)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if _, err := conn.Write(message); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		stop := closeOnDone(ctx, conn)
+		responses := make(chan interface{})
+		go func() {
+			defer close(responses)
+			defer conn.Close()
+			defer stop()
+			for {
+				var message []byte
+				if err := websocket.Message.Receive(conn, &message); err != nil {
+					return
+				}
+				response, err := c.dec(ctx, message)
+				if err != nil {
+					response = err
+				}
+				select {
+				case responses <- response:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		return (<-chan interface{})(responses), nil
+	}
+}
+
+// Close closes the shared connection of the Endpoint, if it's open.
+func (c *Client) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(c.url, c.origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Header = http.Header{}
+	for _, f := range c.before {
+		ctx = f(ctx, config.Header)
+	}
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.binary {
+		conn.PayloadType = websocket.BinaryFrame
+	}
+	return conn, nil
+}
+
+// exchange sends the message, and returns the next message received. It
+// closes the connection if the context is done first.
+func exchange(ctx context.Context, conn *websocket.Conn, message []byte) ([]byte, error) {
+	defer closeOnDone(ctx, conn)()
+	if _, err := conn.Write(message); err != nil {
+		return nil, err
+	}
+	var response []byte
+	if err := websocket.Message.Receive(conn, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// closeOnDone closes the connection if the context is done before the
+// returned function is called.
+func closeOnDone(ctx context.Context, conn *websocket.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
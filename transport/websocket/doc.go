@@ -0,0 +1,13 @@
+// Package websocket provides a WebSocket binding for endpoints.
+//
+// In the default, per-message mode, each message received by a Server is
+// decoded as a request, and the endpoint's response is encoded and sent as a
+// message, so that a connection carries any number of exchanges, in order. If
+// the endpoint's response is a <-chan interface{}, the Server is in streaming
+// mode for that request: every value received from the channel is encoded and
+// sent as a message, and the connection is closed after the channel is. A
+// Client's Endpoint and StreamEndpoint methods speak each mode.
+//
+// Closing the connection, from either side, cancels the context of the
+// requests in flight on it.
+package websocket
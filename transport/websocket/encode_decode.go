@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// DecodeRequestFunc extracts a user-domain request object from a WebSocket
+// message. It's designed to be used in WebSocket servers, for server-side
+// endpoints. One straightforward DecodeRequestFunc could be something that
+// JSON decodes the message to the concrete request type.
+type DecodeRequestFunc func(context.Context, []byte) (request interface{}, err error)
+
+// EncodeResponseFunc encodes the passed response object to a WebSocket
+// message. It's designed to be used in WebSocket servers, for server-side
+// endpoints. One straightforward EncodeResponseFunc could be something that
+// JSON encodes the object. EncodeJSON may be used.
+type EncodeResponseFunc func(context.Context, interface{}) (message []byte, err error)
+
+// EncodeRequestFunc encodes the passed request object to a WebSocket message.
+// It's designed to be used in WebSocket clients, for client-side endpoints.
+// EncodeJSON may be used.
+type EncodeRequestFunc func(context.Context, interface{}) (message []byte, err error)
+
+// DecodeResponseFunc extracts a user-domain response object from a WebSocket
+// message. It's designed to be used in WebSocket clients, for client-side
+// endpoints.
+type DecodeResponseFunc func(context.Context, []byte) (response interface{}, err error)
+
+// ErrorEncoder is responsible for encoding an error as a WebSocket message,
+// which is sent in place of the response. Users are encouraged to use custom
+// ErrorEncoders to encode errors in the same envelope as their responses.
+type ErrorEncoder func(ctx context.Context, err error) (message []byte)
+
+// EncodeJSON is an EncodeResponseFunc and EncodeRequestFunc that serializes
+// the object as JSON.
+func EncodeJSON(_ context.Context, v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// DefaultErrorEncoder encodes the error as a JSON object with the error
+// message in its "error" field. If the error implements json.Marshaler, and
+// the marshaling succeeds, its JSON encoding is used instead.
+func DefaultErrorEncoder(_ context.Context, err error) []byte {
+	if marshaler, ok := err.(json.Marshaler); ok {
+		if message, marshalErr := marshaler.MarshalJSON(); marshalErr == nil {
+			return message
+		}
+	}
+	message, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	return message
+}
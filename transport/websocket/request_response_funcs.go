@@ -0,0 +1,18 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+)
+
+// ServerRequestFunc may take information from the HTTP request which opened a
+// WebSocket connection and put it into the context, which is shared by every
+// request on the connection. ServerRequestFuncs are executed once per
+// connection, before any message is received.
+type ServerRequestFunc func(context.Context, *http.Request) context.Context
+
+// ClientRequestFunc may take information from a request context and use it to
+// set headers of the HTTP request which opens a WebSocket connection.
+// ClientRequestFuncs are executed once per connection, with the context of
+// the request which causes it to be opened.
+type ClientRequestFunc func(context.Context, http.Header) context.Context
@@ -0,0 +1,213 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+)
+
+// Server wraps an endpoint and implements http.Handler, upgrading requests to
+// WebSocket connections.
+type Server struct {
+	e              endpoint.Endpoint
+	dec            DecodeRequestFunc
+	enc            EncodeResponseFunc
+	before         []ServerRequestFunc
+	errorEncoder   ErrorEncoder
+	handshake      func(*websocket.Config, *http.Request) error
+	maxMessageSize int
+	binary         bool
+	logger         log.Logger
+}
+
+// NewServer constructs a new server, which implements http.Handler and wraps
+// the provided endpoint.
+func NewServer(
+	e endpoint.Endpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	options ...ServerOption,
+) *Server {
+	s := &Server{
+		e:            e,
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
+		handshake:    SameOrigin,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerBefore functions are executed on the HTTP request which opens each
+// connection, before any message is received.
+func ServerBefore(before ...ServerRequestFunc) ServerOption {
+	return func(s *Server) { s.before = append(s.before, before...) }
+}
+
+// ServerErrorEncoder is used to encode errors from decoding requests, from
+// the endpoint, and from encoding responses, as messages sent in place of the
+// response. By default, errors are encoded with DefaultErrorEncoder.
+func ServerErrorEncoder(ee ErrorEncoder) ServerOption {
+	return func(s *Server) { s.errorEncoder = ee }
+}
+
+// ServerHandshake sets the function which accepts or rejects the opening
+// handshake of each connection, e.g. to check its origin against a list. By
+// default, SameOrigin is used.
+func ServerHandshake(f func(*websocket.Config, *http.Request) error) ServerOption {
+	return func(s *Server) { s.handshake = f }
+}
+
+// ServerMaxMessageSize sets the maximum size of a received message, in bytes.
+// Connections receiving larger messages are closed. By default, the limit of
+// package golang.org/x/net/websocket is used.
+func ServerMaxMessageSize(n int) ServerOption {
+	return func(s *Server) { s.maxMessageSize = n }
+}
+
+// ServerBinaryFrames causes responses to be sent as binary frames. By
+// default, they're sent as text frames.
+func ServerBinaryFrames() ServerOption {
+	return func(s *Server) { s.binary = true }
+}
+
+// ServerErrorLogger is used to log non-terminal errors. By default, no errors
+// are logged.
+func ServerErrorLogger(logger log.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// SameOrigin is the default handshake of a Server. It rejects connections
+// whose Origin header, if any, doesn't match the host of the request, so that
+// other sites can't open connections with the credentials of browsers
+// visiting them.
+func SameOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin != nil && origin.Host != r.Host {
+		return fmt.Errorf("origin %s not allowed", origin)
+	}
+	config.Origin = origin
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	websocket.Server{Handshake: s.handshake, Handler: s.serve}.ServeHTTP(w, r)
+}
+
+func (s Server) serve(conn *websocket.Conn) {
+	defer conn.Close()
+	if s.maxMessageSize > 0 {
+		conn.MaxPayloadBytes = s.maxMessageSize
+	}
+	if s.binary {
+		conn.PayloadType = websocket.BinaryFrame
+	}
+
+	ctx, cancel := context.WithCancel(conn.Request().Context())
+	defer cancel()
+	for _, f := range s.before {
+		ctx = f(ctx, conn.Request())
+	}
+
+	// Receive messages concurrently, so that the connection closing cancels
+	// the context of the request in flight.
+	messages := make(chan []byte)
+	go func() {
+		defer cancel()
+		for {
+			var message []byte
+			if err := websocket.Message.Receive(conn, &message); err != nil {
+				if err != io.EOF {
+					s.logger.Log("during", "Receive", "err", err)
+				}
+				return
+			}
+			select {
+			case messages <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case message := <-messages:
+			if !s.handle(ctx, conn, message) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handle serves a request, and returns false if the connection should be
+// closed.
+func (s Server) handle(ctx context.Context, conn *websocket.Conn, message []byte) bool {
+	request, err := s.dec(ctx, message)
+	if err != nil {
+		s.logger.Log("err", err)
+		return s.send(conn, s.errorEncoder(ctx, err))
+	}
+
+	response, err := s.e(ctx, request)
+	if err != nil {
+		s.logger.Log("err", err)
+		return s.send(conn, s.errorEncoder(ctx, err))
+	}
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		return s.send(conn, s.errorEncoder(ctx, f.Failed()))
+	}
+
+	if stream, ok := response.(<-chan interface{}); ok {
+		for {
+			select {
+			case v, ok := <-stream:
+				if !ok {
+					return false
+				}
+				if !s.encode(ctx, conn, v) {
+					return false
+				}
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	return s.encode(ctx, conn, response)
+}
+
+func (s Server) encode(ctx context.Context, conn *websocket.Conn, response interface{}) bool {
+	message, err := s.enc(ctx, response)
+	if err != nil {
+		s.logger.Log("err", err)
+		message = s.errorEncoder(ctx, err)
+	}
+	return s.send(conn, message)
+}
+
+func (s Server) send(conn *websocket.Conn, message []byte) bool {
+	if _, err := conn.Write(message); err != nil {
+		s.logger.Log("during", "Write", "err", err)
+		return false
+	}
+	return true
+}
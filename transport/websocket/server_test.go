@@ -0,0 +1,168 @@
+package websocket_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	kitws "github.com/go-kit/kit/transport/websocket"
+)
+
+func decodeString(_ context.Context, message []byte) (interface{}, error) {
+	return string(message), nil
+}
+
+func encodeString(_ context.Context, v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestServerPerMessage(t *testing.T) {
+	handler := kitws.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			if request == "fail" {
+				return nil, errors.New("dang")
+			}
+			return strings.ToUpper(request.(string)), nil
+		},
+		decodeString,
+		encodeString,
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := kitws.NewClient(wsURL(server), encodeString, decodeString)
+	defer client.Close()
+	e := client.Endpoint()
+	for request, want := range map[string]string{
+		"hello": "HELLO",
+		"fail":  `{"error":"dang"}`,
+		"bye":   "BYE",
+	} {
+		response, err := e(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := response.(string); want != have {
+			t.Errorf("%s: want %q, have %q", request, want, have)
+		}
+	}
+}
+
+func TestServerStreaming(t *testing.T) {
+	handler := kitws.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			n, _ := strconv.Atoi(request.(string))
+			stream := make(chan interface{})
+			go func() {
+				defer close(stream)
+				for i := 0; i < n; i++ {
+					stream <- strconv.Itoa(i)
+				}
+			}()
+			return (<-chan interface{})(stream), nil
+		},
+		decodeString,
+		encodeString,
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := kitws.NewClient(wsURL(server), encodeString, decodeString).StreamEndpoint()(context.Background(), "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for v := range response.(<-chan interface{}) {
+		have = append(have, v.(string))
+	}
+	if want := "0 1 2"; want != strings.Join(have, " ") {
+		t.Errorf("want %q, have %q", want, strings.Join(have, " "))
+	}
+}
+
+func TestServerCloseCancelsContext(t *testing.T) {
+	canceled := make(chan struct{})
+	handler := kitws.NewServer(
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		},
+		decodeString,
+		encodeString,
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	client := kitws.NewClient(wsURL(server), encodeString, decodeString)
+	if _, err := client.Endpoint()(ctx, "block"); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("server request context wasn't canceled by the client closing")
+	}
+}
+
+func TestServerBefore(t *testing.T) {
+	type key struct{}
+	handler := kitws.NewServer(
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return ctx.Value(key{}).(string), nil
+		},
+		decodeString,
+		encodeString,
+		kitws.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+			return context.WithValue(ctx, key{}, r.Header.Get("X-Tenant"))
+		}),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := kitws.NewClient(wsURL(server), encodeString, decodeString,
+		kitws.ClientBefore(func(ctx context.Context, h http.Header) context.Context {
+			h.Set("X-Tenant", "acme")
+			return ctx
+		}),
+	)
+	defer client.Close()
+	response, err := client.Endpoint()(context.Background(), "who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "acme", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	server := httptest.NewServer(kitws.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return "", nil },
+		decodeString,
+		encodeString,
+	))
+	defer server.Close()
+
+	if _, err := websocket.Dial(wsURL(server), "", "http://evil.example.com"); err == nil {
+		t.Error("want cross-origin connection rejected")
+	}
+	conn, err := websocket.Dial(wsURL(server), "", server.URL)
+	if err != nil {
+		t.Fatalf("same-origin connection: %v", err)
+	}
+	conn.Close()
+}
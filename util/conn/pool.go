@@ -0,0 +1,245 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ErrPoolClosed is returned by the Pool's Take method once it's closed.
+var ErrPoolClosed = errors.New("connection pool closed")
+
+// Pool manages a number of net.Conns to each of a set of addresses.
+//
+// Unlike a Manager, whose single connection is shared, a connection of a
+// Pool is yielded to one client at a time, so that a connection which wedges
+// stalls only its client, rather than the whole service. Clients should Take
+// a connection when they want to use it, and Put it back with whatever error
+// they receive from its use. When a non-nil error is Put, the connection is
+// closed, and a new connection to its address is established. Connection
+// failures are retried after an exponential backoff with jitter. Idle
+// connections may be probed periodically, and those which fail their probes
+// are replaced.
+type Pool struct {
+	dialer        Dialer
+	network       string
+	logger        log.Logger
+	size          int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	probe         func(net.Conn) error
+	probeInterval time.Duration
+
+	idle chan *pooledConn
+	done chan struct{}
+	wg   sync.WaitGroup // of the goroutines dialing and probing
+
+	mtx    sync.Mutex
+	closed bool
+}
+
+// PoolOption sets an optional parameter for pools.
+type PoolOption func(*Pool)
+
+// PoolSize sets the number of connections to each address. By default, it's
+// 2.
+func PoolSize(n int) PoolOption {
+	return func(p *Pool) { p.size = n }
+}
+
+// PoolBackoff sets the bounds of the backoff between failed connection
+// attempts, which doubles from min up to max, with a random jitter of up to
+// half of it. By default, it's from 100ms to a minute.
+func PoolBackoff(min, max time.Duration) PoolOption {
+	return func(p *Pool) { p.minBackoff, p.maxBackoff = min, max }
+}
+
+// PoolHealthCheck sets the probe with which idle connections are checked,
+// every interval, e.g. by writing a ping and reading the reply. Connections
+// whose probes fail are closed and replaced. The connection's deadline is set
+// to the interval during the probe. By default, connections aren't probed.
+func PoolHealthCheck(interval time.Duration, probe func(net.Conn) error) PoolOption {
+	return func(p *Pool) { p.probeInterval, p.probe = interval, probe }
+}
+
+// NewPool returns a connection pool using the passed Dialer, network, and
+// addresses. Connections are established in the background; Take waits for
+// them. The logger is used to log errors; pass a log.NopLogger if you don't
+// care to receive them.
+func NewPool(d Dialer, network string, addresses []string, logger log.Logger, options ...PoolOption) *Pool {
+	p := &Pool{
+		dialer:     d,
+		network:    network,
+		logger:     logger,
+		size:       2,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: time.Minute,
+		done:       make(chan struct{}),
+	}
+	for _, option := range options {
+		option(p)
+	}
+	p.idle = make(chan *pooledConn, p.size*len(addresses))
+	for _, address := range addresses {
+		for i := 0; i < p.size; i++ {
+			p.redial(address)
+		}
+	}
+	if p.probe != nil && p.probeInterval > 0 {
+		p.wg.Add(1) // before the pool may be closed
+		go p.probeLoop()
+	}
+	return p
+}
+
+// Take yields an idle connection, waiting for one until the context is done.
+// If the context has a deadline, it's set as the connection's deadline, so
+// that a wedged connection fails rather than blocking past it. The
+// connection must be Put back.
+func (p *Pool) Take(ctx context.Context) (net.Conn, error) {
+	select {
+	case <-p.done:
+		return nil, ErrPoolClosed
+	default:
+	}
+	select {
+	case c := <-p.idle:
+		if deadline, ok := ctx.Deadline(); ok {
+			c.SetDeadline(deadline)
+		}
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, ErrPoolClosed
+	}
+}
+
+// Put returns a connection yielded by Take to the pool, with the error that
+// came from its use. If the error is non-nil, the connection is closed, and
+// the pool reconnects to its address, with exponential backoff.
+func (p *Pool) Put(conn net.Conn, err error) {
+	c, ok := conn.(*pooledConn)
+	if !ok {
+		conn.Close()
+		return
+	}
+	if err != nil {
+		p.logger.Log("address", c.address, "err", err)
+		c.Close()
+		p.redial(c.address)
+		return
+	}
+	c.SetDeadline(time.Time{})
+	p.release(c)
+}
+
+// Close closes the pool and its idle connections. Connections which are
+// taken are closed as they're Put back.
+func (p *Pool) Close() error {
+	p.mtx.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.mtx.Unlock()
+	p.wg.Wait()
+	for {
+		select {
+		case c := <-p.idle:
+			c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// release makes the connection idle, unless the pool is closed.
+func (p *Pool) release(c *pooledConn) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.closed {
+		c.Close()
+		return
+	}
+	p.idle <- c // it has room for every connection of the pool
+}
+
+// redial establishes a connection to the address in the background.
+func (p *Pool) redial(address string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.closed {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		backoff := p.minBackoff
+		for {
+			conn, err := p.dialer(p.network, address)
+			if err == nil {
+				p.release(&pooledConn{Conn: conn, address: address})
+				return
+			}
+			p.logger.Log("address", address, "err", err)
+			select {
+			case <-time.After(jitter(backoff)):
+			case <-p.done:
+				return
+			}
+			if backoff *= 2; backoff > p.maxBackoff {
+				backoff = p.maxBackoff
+			}
+		}
+	}()
+}
+
+// probeLoop probes the idle connections every interval.
+func (p *Pool) probeLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.done:
+			return
+		}
+		for n := len(p.idle); n > 0; n-- {
+			var c *pooledConn
+			select {
+			case c = <-p.idle:
+			default:
+			}
+			if c == nil {
+				break
+			}
+			c.SetDeadline(time.Now().Add(p.probeInterval))
+			err := p.probe(c.Conn)
+			if err != nil {
+				err = errors.New("health check failed: " + err.Error())
+			}
+			p.Put(c, err)
+		}
+	}
+}
+
+// jitter returns a random duration between half of d and d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// pooledConn is a connection of a Pool, with the address it was dialed to.
+type pooledConn struct {
+	net.Conn
+	address string
+}
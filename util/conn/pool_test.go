@@ -0,0 +1,119 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestPool(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		dials  = map[string]int{}
+		dialer = func(_, address string) (net.Conn, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			dials[address]++
+			return &mockConn{}, nil
+		}
+		pool = NewPool(dialer, "netw", []string{"a", "b"}, log.NewNopLogger(), PoolSize(2))
+	)
+	defer pool.Close()
+
+	// All four connections may be taken at once.
+	var conns []net.Conn
+	for i := 0; i < 4; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		conn, err := pool.Take(ctx)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+
+	// A fifth waits until its deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Take(ctx); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+
+	// A connection which is Put with an error is replaced.
+	address := conns[0].(*pooledConn).address
+	pool.Put(conns[0], errors.New("broken pipe"))
+	for _, conn := range conns[1:] {
+		pool.Put(conn, nil)
+	}
+	if !within(time.Second, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return dials[address] == 3
+	}) {
+		t.Errorf("want %s redialed, have %v", address, dials)
+	}
+	if !within(time.Second, func() bool { return len(pool.idle) == 4 }) {
+		t.Errorf("want 4 idle connections, have %d", len(pool.idle))
+	}
+}
+
+func TestPoolBackoff(t *testing.T) {
+	var (
+		attempts uint64
+		dialer   = func(string, string) (net.Conn, error) {
+			if atomic.AddUint64(&attempts, 1) < 3 {
+				return nil, errors.New("connection refused")
+			}
+			return &mockConn{}, nil
+		}
+		pool = NewPool(dialer, "netw", []string{"a"}, log.NewNopLogger(), PoolSize(1), PoolBackoff(time.Millisecond, 2*time.Millisecond))
+	)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := pool.Take(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(conn, nil)
+	if want, have := uint64(3), atomic.LoadUint64(&attempts); want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	var (
+		dials  uint64
+		dialer = func(string, string) (net.Conn, error) {
+			atomic.AddUint64(&dials, 1)
+			return &mockConn{}, nil
+		}
+		probe = func(conn net.Conn) error {
+			// The first connection fails its probe.
+			if _, err := conn.Write([]byte{1}); err != nil {
+				return err
+			}
+			if atomic.LoadUint64(&conn.(*mockConn).wr) == 1 && atomic.LoadUint64(&dials) == 1 {
+				return errors.New("no pong")
+			}
+			return nil
+		}
+		pool = NewPool(dialer, "netw", []string{"a"}, log.NewNopLogger(), PoolSize(1), PoolHealthCheck(time.Millisecond, probe))
+	)
+
+	if !within(time.Second, func() bool { return atomic.LoadUint64(&dials) == 2 }) {
+		t.Errorf("want the connection replaced, have %d dials", atomic.LoadUint64(&dials))
+	}
+
+	pool.Close()
+	if _, err := pool.Take(context.Background()); err != ErrPoolClosed {
+		t.Errorf("want %v, have %v", ErrPoolClosed, err)
+	}
+}